@@ -0,0 +1,17 @@
+package main
+
+import (
+	"log"
+
+	"github.com/nikitamishagin/corebgp/internal/corebgpctl"
+)
+
+// main is the entry point of corebgpctl, the command line client of the CoreBGP API server.
+func main() {
+	// Print errors without a timestamp, like other command line tools
+	log.SetFlags(0)
+	err := corebgpctl.RootCmd().Execute()
+	if err != nil {
+		log.Fatalf("corebgpctl: %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	fieldSeparator  = "\x1f" // fieldSeparator separates commit fields in the git log output.
+	recordSeparator = "\x1e" // recordSeparator separates commits in the git log output.
+	changelogHeader = "# Changelog\n"
+)
+
+// defaultTemplate is the built-in template used to render a version section.
+const defaultTemplate = `## {{ .Version }} ({{ .Date }})
+{{ range .Groups }}
+### {{ .Title }}
+{{ range .Entries }}
+- {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Subject }}{{ if .Breaking }} (BREAKING){{ end }} ({{ .ShortHash }})
+{{- end }}
+{{ end }}`
+
+// conventionalCommit matches the conventional commit subject format: type(scope)!: subject.
+var conventionalCommit = regexp.MustCompile(`^(\w+)(?:\(([^)]+)\))?(!)?:\s*(.+)$`)
+
+// groupTitles defines the order and the titles of the changelog groups.
+var groupTitles = []struct {
+	Type  string
+	Title string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"refactor", "Refactoring"},
+	{"other", "Other Changes"},
+}
+
+// Entry represents a single changelog line produced from a commit.
+type Entry struct {
+	Hash      string // Hash is the full commit hash.
+	ShortHash string // ShortHash is the abbreviated commit hash.
+	Type      string // Type is the conventional commit type, e.g., feat or fix.
+	Scope     string // Scope is the optional conventional commit scope.
+	Subject   string // Subject is the commit description without the type prefix.
+	Breaking  bool   // Breaking reports whether the commit is marked as a breaking change.
+}
+
+// Group is a set of changelog entries of the same commit type.
+type Group struct {
+	Title   string  // Title is the human-readable group heading.
+	Entries []Entry // Entries are the changelog lines of the group.
+}
+
+// Release is the data passed to the changelog template.
+type Release struct {
+	Version string  // Version is the name of the released version.
+	Date    string  // Date is the release date in YYYY-MM-DD format.
+	Groups  []Group // Groups contains the non-empty changelog groups in display order.
+}
+
+// rootCmd initializes and returns the root command of the changelog generator.
+func rootCmd() *cobra.Command {
+	var (
+		version      string
+		since        string
+		output       string
+		templatePath string
+		skipOther    bool
+	)
+	var cmd = &cobra.Command{
+		Use:   "gen-changelog",
+		Short: "Add a section of the conventional commit history to the top of CHANGELOG.md, newest version first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if version == "" {
+				version = os.Getenv("CHANGELOG_VERSION")
+			}
+			if version == "" {
+				version = "Unreleased"
+			}
+
+			// Use the latest tag as the lower bound unless a revision is given explicitly
+			if since == "" {
+				since = latestTag()
+			}
+
+			entries, err := readCommits(since)
+			if err != nil {
+				return err
+			}
+
+			tmpl, err := loadTemplate(templatePath)
+			if err != nil {
+				return err
+			}
+
+			release := Release{
+				Version: version,
+				Date:    time.Now().Format("2006-01-02"),
+				Groups:  groupEntries(entries, skipOther),
+			}
+
+			var section bytes.Buffer
+			if err := tmpl.Execute(&section, release); err != nil {
+				return fmt.Errorf("failed to render changelog template: %w", err)
+			}
+
+			return prependSection(output, section.String())
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Version name of the new section (defaults to $CHANGELOG_VERSION or Unreleased)")
+	cmd.Flags().StringVar(&since, "since", "", "Revision to start from (defaults to the latest tag)")
+	cmd.Flags().StringVarP(&output, "output", "o", "CHANGELOG.md", "Path to the changelog file the new section is inserted into below its header")
+	cmd.Flags().StringVarP(&templatePath, "template", "t", "", "Path to a custom text/template file for the version section")
+	cmd.Flags().BoolVar(&skipOther, "skip-other", false, "Skip commits that do not follow the conventional commit format")
+
+	return cmd
+}
+
+// latestTag returns the most recent tag reachable from HEAD or an empty string if there are no tags.
+func latestTag() string {
+	out, err := exec.Command("git", "describe", "--tags", "--abbrev=0").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// readCommits reads commits from the git history starting after the since revision and parses them into entries.
+func readCommits(since string) ([]Entry, error) {
+	args := []string{"log", "--no-merges", "--format=%H" + fieldSeparator + "%h" + fieldSeparator + "%s" + fieldSeparator + "%b" + recordSeparator}
+	if since != "" {
+		args = append(args, since+"..HEAD")
+	}
+
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git log: %w", err)
+	}
+
+	var entries []Entry
+	for _, record := range strings.Split(string(out), recordSeparator) {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, fieldSeparator, 4)
+		if len(fields) < 3 {
+			continue
+		}
+
+		var body string
+		if len(fields) == 4 {
+			body = fields[3]
+		}
+		entries = append(entries, parseCommit(fields[0], fields[1], fields[2], body))
+	}
+
+	return entries, nil
+}
+
+// parseCommit converts a commit subject and body into a changelog entry using the conventional commit format.
+func parseCommit(hash, shortHash, subject, body string) Entry {
+	entry := Entry{
+		Hash:      hash,
+		ShortHash: shortHash,
+		Type:      "other",
+		Subject:   subject,
+		Breaking:  strings.Contains(body, "BREAKING CHANGE"),
+	}
+
+	match := conventionalCommit.FindStringSubmatch(subject)
+	if match == nil {
+		return entry
+	}
+
+	entry.Type = strings.ToLower(match[1])
+	entry.Scope = match[2]
+	entry.Breaking = entry.Breaking || match[3] == "!"
+	entry.Subject = match[4]
+	return entry
+}
+
+// groupEntries groups the entries by commit type in the display order; unknown types go to the "other" group.
+func groupEntries(entries []Entry, skipOther bool) []Group {
+	byType := make(map[string][]Entry)
+	for _, entry := range entries {
+		entryType := entry.Type
+		if !knownType(entryType) {
+			entryType = "other"
+		}
+		byType[entryType] = append(byType[entryType], entry)
+	}
+
+	var groups []Group
+	for _, group := range groupTitles {
+		if group.Type == "other" && skipOther {
+			continue
+		}
+		if len(byType[group.Type]) == 0 {
+			continue
+		}
+		groups = append(groups, Group{Title: group.Title, Entries: byType[group.Type]})
+	}
+	return groups
+}
+
+// knownType reports whether the commit type has a dedicated changelog group.
+func knownType(entryType string) bool {
+	for _, group := range groupTitles {
+		if group.Type == entryType && entryType != "other" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTemplate parses the custom template file or falls back to the default template.
+func loadTemplate(path string) (*template.Template, error) {
+	text := defaultTemplate
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read template: %w", err)
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New("changelog").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// prependSection inserts the new version section right after the changelog header, creating the file if needed. The
+// section is added above the existing ones rather than at the end of the file, so that the newest version comes first
+// as readers of a changelog expect.
+func prependSection(path, section string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not read changelog: %w", err)
+	}
+
+	content := strings.TrimPrefix(string(existing), changelogHeader)
+	content = strings.TrimLeft(content, "\n")
+
+	var result strings.Builder
+	result.WriteString(changelogHeader)
+	result.WriteString("\n")
+	result.WriteString(strings.TrimRight(section, "\n"))
+	result.WriteString("\n")
+	if content != "" {
+		result.WriteString("\n")
+		result.WriteString(content)
+	}
+
+	if err := os.WriteFile(path, []byte(result.String()), 0644); err != nil {
+		return fmt.Errorf("could not write changelog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseCommit checks the parsing of the conventional commit subjects.
+func TestParseCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		body    string
+		want    Entry
+	}{
+		{name: "type", subject: "feat: add pools", want: Entry{Type: "feat", Subject: "add pools"}},
+		{name: "scope", subject: "fix(apiserver): keep the status", want: Entry{Type: "fix", Scope: "apiserver", Subject: "keep the status"}},
+		{name: "breaking marker", subject: "refactor(model)!: rename fields", want: Entry{Type: "refactor", Scope: "model", Subject: "rename fields", Breaking: true}},
+		{name: "breaking footer", subject: "feat: drop v0", body: "BREAKING CHANGE: v0 is removed", want: Entry{Type: "feat", Subject: "drop v0", Breaking: true}},
+		{name: "upper case type", subject: "Fix: typo", want: Entry{Type: "fix", Subject: "typo"}},
+		{name: "not conventional", subject: "Update README", want: Entry{Type: "other", Subject: "Update README"}},
+		{name: "not conventional breaking", subject: "Remove v0", body: "BREAKING CHANGE", want: Entry{Type: "other", Subject: "Remove v0", Breaking: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Hash, tt.want.ShortHash = "0123456789abcdef", "0123456"
+			if got := parseCommit("0123456789abcdef", "0123456", tt.subject, tt.body); got != tt.want {
+				t.Fatalf("parseCommit = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGroupEntries checks the order of the groups and the grouping of the unknown commit types.
+func TestGroupEntries(t *testing.T) {
+	entries := []Entry{
+		{Type: "fix", Subject: "a"},
+		{Type: "docs", Subject: "b"},
+		{Type: "feat", Subject: "c"},
+		{Type: "fix", Subject: "d"},
+		{Type: "other", Subject: "e"},
+	}
+
+	tests := []struct {
+		name      string
+		skipOther bool
+		want      []Group
+	}{
+		{name: "all", want: []Group{
+			{Title: "Features", Entries: []Entry{entries[2]}},
+			{Title: "Bug Fixes", Entries: []Entry{entries[0], entries[3]}},
+			{Title: "Other Changes", Entries: []Entry{entries[1], entries[4]}},
+		}},
+		{name: "skip other", skipOther: true, want: []Group{
+			{Title: "Features", Entries: []Entry{entries[2]}},
+			{Title: "Bug Fixes", Entries: []Entry{entries[0], entries[3]}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupEntries(entries, tt.skipOther); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("groupEntries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPrependSection checks that the new section is inserted below the header, above the sections of the previous
+// versions.
+func TestPrependSection(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *string
+		want     string
+	}{
+		{name: "new file", want: "# Changelog\n\n## v1.1.0\n"},
+		{name: "header only", existing: ptr("# Changelog\n"), want: "# Changelog\n\n## v1.1.0\n"},
+		{name: "previous versions", existing: ptr("# Changelog\n\n## v1.0.0\n\n- first\n"), want: "# Changelog\n\n## v1.1.0\n\n## v1.0.0\n\n- first\n"},
+		{name: "without header", existing: ptr("## v1.0.0\n"), want: "# Changelog\n\n## v1.1.0\n\n## v1.0.0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if tt.existing != nil {
+				if err := os.WriteFile(path, []byte(*tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := prependSection(path, "## v1.1.0\n\n\n"); err != nil {
+				t.Fatalf("prependSection: %v", err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("changelog = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDefaultTemplate checks the rendering of a version section with the default template.
+func TestDefaultTemplate(t *testing.T) {
+	tmpl, err := loadTemplate("")
+	if err != nil {
+		t.Fatalf("loadTemplate: %v", err)
+	}
+	release := Release{Version: "v1.1.0", Date: "2026-10-15", Groups: []Group{
+		{Title: "Features", Entries: []Entry{{ShortHash: "abc1234", Scope: "apiserver", Subject: "add pools", Breaking: true}}},
+		{Title: "Bug Fixes", Entries: []Entry{{ShortHash: "def5678", Subject: "keep the status"}}},
+	}}
+
+	var section strings.Builder
+	if err := tmpl.Execute(&section, release); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	want := "## v1.1.0 (2026-10-15)\n\n### Features\n\n- **apiserver:** add pools (BREAKING) (abc1234)\n\n### Bug Fixes\n\n- keep the status (def5678)\n"
+	if section.String() != want {
+		t.Fatalf("section = %q, want %q", section.String(), want)
+	}
+}
+
+// ptr returns a pointer to the string.
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"log"
+)
+
+//go:generate go run . --output ../../CHANGELOG.md
+
+// main is the entry point of the tool that generates CHANGELOG.md entries from the git history.
+func main() {
+	err := rootCmd().Execute()
+	if err != nil {
+		log.Fatalf("failed to generate changelog: %v", err)
+	}
+}
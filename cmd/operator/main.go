@@ -0,0 +1,15 @@
+package main
+
+import (
+	"log"
+
+	"github.com/nikitamishagin/corebgp/internal/operator"
+)
+
+// main is the entry point of the application that starts the CoreBGP Kubernetes operator.
+func main() {
+	err := operator.RootCmd().Execute()
+	if err != nil {
+		log.Fatalf("failed to run operator: %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+const (
+	defaultAdmissionTimeout = 10 * time.Second // defaultAdmissionTimeout bounds the reviews of the webhooks without a timeout.
+	maxAdmissionTimeout     = 30 * time.Second // maxAdmissionTimeout is the longest timeout a webhook may set.
+	admissionResponseLimit  = 1 << 20          // admissionResponseLimit is the maximum size of a review response in bytes.
+)
+
+// AdmissionWebhooks reviews the created and updated announcements with the configured admission webhooks before they
+// are stored. Mutating webhooks may change an announcement, e.g., to inject default communities, and any webhook may
+// reject it. A nil value admits every change.
+type AdmissionWebhooks struct {
+	hooks []*admissionWebhook // hooks holds the mutating webhooks followed by the validating ones.
+}
+
+// admissionWebhook is a configured admission webhook with its HTTP client.
+type admissionWebhook struct {
+	model.AdmissionWebhook
+	client  *http.Client
+	timeout time.Duration
+}
+
+// LoadAdmissionWebhooks reads the JSON admission webhooks file. It returns nil when no file is configured.
+func LoadAdmissionWebhooks(path string) (*AdmissionWebhooks, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read admission webhooks file: %w", err)
+	}
+	var file model.AdmissionConfig
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse admission webhooks file: %w", err)
+	}
+	return newAdmissionWebhooks(file.Webhooks)
+}
+
+// newAdmissionWebhooks checks the webhooks and sets up their clients.
+func newAdmissionWebhooks(hooks []model.AdmissionWebhook) (*AdmissionWebhooks, error) {
+	var mutating, validating []*admissionWebhook
+	names := make(map[string]struct{}, len(hooks))
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			return nil, fmt.Errorf("admission webhook %d: name must not be empty", i)
+		}
+		if _, ok := names[hook.Name]; ok {
+			return nil, fmt.Errorf("admission webhook %s: duplicate name", hook.Name)
+		}
+		names[hook.Name] = struct{}{}
+
+		if u, err := url.Parse(hook.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("admission webhook %s: %q is not an HTTPS URL", hook.Name, hook.URL)
+		}
+		if hook.Type != model.AdmissionMutating && hook.Type != model.AdmissionValidating {
+			return nil, fmt.Errorf("admission webhook %s: unknown type %q, must be %s or %s", hook.Name, hook.Type, model.AdmissionMutating, model.AdmissionValidating)
+		}
+		for _, operation := range hook.Operations {
+			if operation != model.AdmissionCreate && operation != model.AdmissionUpdate {
+				return nil, fmt.Errorf("admission webhook %s: unknown operation %q", hook.Name, operation)
+			}
+		}
+		switch hook.FailurePolicy {
+		case "":
+			hook.FailurePolicy = model.AdmissionFailClosed
+		case model.AdmissionFailClosed, model.AdmissionFailOpen:
+		default:
+			return nil, fmt.Errorf("admission webhook %s: unknown failure policy %q, must be %s or %s", hook.Name, hook.FailurePolicy, model.AdmissionFailClosed, model.AdmissionFailOpen)
+		}
+		timeout := time.Duration(hook.Timeout) * time.Second
+		if timeout < 0 || timeout > maxAdmissionTimeout {
+			return nil, fmt.Errorf("admission webhook %s: timeout must be between 0 and %d seconds", hook.Name, int(maxAdmissionTimeout.Seconds()))
+		}
+		if timeout == 0 {
+			timeout = defaultAdmissionTimeout
+		}
+
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if hook.CACert != "" {
+			caCert, err := os.ReadFile(hook.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("admission webhook %s: could not read CA certificate: %w", hook.Name, err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+				return nil, fmt.Errorf("admission webhook %s: failed to append CA certificate", hook.Name)
+			}
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+
+		configured := &admissionWebhook{
+			AdmissionWebhook: hook,
+			client:           &http.Client{Transport: transport},
+			timeout:          timeout,
+		}
+		if hook.Type == model.AdmissionMutating {
+			mutating = append(mutating, configured)
+		} else {
+			validating = append(validating, configured)
+		}
+	}
+
+	// The validating webhooks review the announcement as changed by all mutating webhooks
+	return &AdmissionWebhooks{hooks: append(mutating, validating...)}, nil
+}
+
+// admit reviews the change of the announcement with the webhooks subscribed to it; previous is the stored
+// announcement, nil for a creation. The changes of the mutating webhooks are applied to the announcement, except for
+// its identity and the fields maintained by the server, and the changed announcement is validated again. A rejection
+// is returned as a validation error, while a failed call rejects the change only with the fail-closed policy.
+func (a *AdmissionWebhooks) admit(c *gin.Context, dryRun bool, previous, announcement *model.Announcement) error {
+	if a == nil {
+		return nil
+	}
+
+	operation := model.AdmissionUpdate
+	if previous == nil {
+		operation = model.AdmissionCreate
+	}
+	mutated := false
+	for _, hook := range a.hooks {
+		if !hook.subscribed(operation, announcement.Meta.Project) {
+			continue
+		}
+
+		review := model.AdmissionReview{
+			ID:           randomID(),
+			Operation:    operation,
+			DryRun:       dryRun,
+			Actor:        c.GetString(actorContextKey),
+			Announcement: announcement,
+			Previous:     previous,
+		}
+		response, err := hook.review(c.Request.Context(), &review)
+		if err == nil && response.Allowed && response.Announcement != nil && hook.Type == model.AdmissionMutating {
+			if response.Announcement.Meta.Project != announcement.Meta.Project || response.Announcement.Meta.Name != announcement.Meta.Name {
+				err = fmt.Errorf("webhook changed the project or the name of the announcement")
+			}
+		}
+		if err != nil {
+			if hook.FailurePolicy == model.AdmissionFailOpen {
+				admissionReviews.WithLabelValues(hook.Name, "ignored").Inc()
+				slog.Warn("admission webhook failed, admitting the change", "webhook", hook.Name, "review_id", review.ID, "error", err)
+				continue
+			}
+			admissionReviews.WithLabelValues(hook.Name, "error").Inc()
+			return fmt.Errorf("admission webhook %s failed: %w", hook.Name, err)
+		}
+
+		if !response.Allowed {
+			admissionReviews.WithLabelValues(hook.Name, "rejected").Inc()
+			return &invalidAnnouncementError{errors: admissionErrors(hook.Name, response)}
+		}
+		admissionReviews.WithLabelValues(hook.Name, "allowed").Inc()
+
+		// The validating webhooks can not change the announcement, their changes are ignored
+		if response.Announcement != nil && hook.Type == model.AdmissionMutating {
+			changed := *response.Announcement
+			changed.Meta.ResourceVersion = announcement.Meta.ResourceVersion
+			changed.Status = announcement.Status
+			changed.LastApplied = announcement.LastApplied
+			*announcement = changed
+			mutated = true
+		}
+	}
+
+	if mutated {
+		if err := validateAnnouncement(announcement); err != nil {
+			return fmt.Errorf("announcement changed by the admission webhooks: %w", err)
+		}
+	}
+	return nil
+}
+
+// subscribed reports whether the webhook reviews the operation on the announcements of the project.
+func (h *admissionWebhook) subscribed(operation model.AdmissionOperation, project string) bool {
+	return (len(h.Operations) == 0 || slices.Contains(h.Operations, operation)) &&
+		(len(h.Projects) == 0 || slices.Contains(h.Projects, project))
+}
+
+// review posts the review to the webhook and returns its response. Responses other than 2xx are errors.
+func (h *admissionWebhook) review(ctx context.Context, review *model.AdmissionReview) (*model.AdmissionResponse, error) {
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "corebgp-apiserver")
+
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	admissionDuration.WithLabelValues(h.Name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	var response model.AdmissionResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, admissionResponseLimit)).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook response: %w", err)
+	}
+	return &response, nil
+}
+
+// admissionErrors returns the errors of a rejection, attributed to the webhook.
+func admissionErrors(name string, response *model.AdmissionResponse) []model.ValidationError {
+	prefix := "rejected by admission webhook " + name
+	if len(response.Errors) == 0 {
+		message := prefix
+		if response.Message != "" {
+			message += ": " + response.Message
+		}
+		return []model.ValidationError{{Message: message}}
+	}
+
+	errs := make([]model.ValidationError, 0, len(response.Errors))
+	for _, validationError := range response.Errors {
+		errs = append(errs, model.ValidationError{Field: validationError.Field, Message: prefix + ": " + validationError.Message})
+	}
+	return errs
+}
+
+// randomID returns a new random 128-bit ID in hex, e.g., of an admission review.
+func randomID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
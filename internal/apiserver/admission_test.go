@@ -0,0 +1,204 @@
+package apiserver
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// TestNewAdmissionWebhooks checks that the invalid admission webhook configurations are rejected.
+func TestNewAdmissionWebhooks(t *testing.T) {
+	valid := model.AdmissionWebhook{Name: "a", URL: "https://admission.example.com/review", Type: model.AdmissionValidating}
+	with := func(change func(hook *model.AdmissionWebhook)) []model.AdmissionWebhook {
+		hook := valid
+		change(&hook)
+		return []model.AdmissionWebhook{hook}
+	}
+
+	tests := []struct {
+		name      string
+		hooks     []model.AdmissionWebhook
+		wantError string
+	}{
+		{name: "valid", hooks: with(func(hook *model.AdmissionWebhook) { hook.FailurePolicy = model.AdmissionFailOpen; hook.Timeout = 30 })},
+		{name: "no name", hooks: with(func(hook *model.AdmissionWebhook) { hook.Name = "" }), wantError: "name must not be empty"},
+		{name: "duplicate name", hooks: []model.AdmissionWebhook{valid, valid}, wantError: "duplicate name"},
+		{name: "plain HTTP", hooks: with(func(hook *model.AdmissionWebhook) { hook.URL = "http://admission.example.com/review" }), wantError: "not an HTTPS URL"},
+		{name: "unknown type", hooks: with(func(hook *model.AdmissionWebhook) { hook.Type = "auditing" }), wantError: "unknown type"},
+		{name: "unknown operation", hooks: with(func(hook *model.AdmissionWebhook) { hook.Operations = []model.AdmissionOperation{"delete"} }), wantError: "unknown operation"},
+		{name: "unknown failure policy", hooks: with(func(hook *model.AdmissionWebhook) { hook.FailurePolicy = "ignore" }), wantError: "unknown failure policy"},
+		{name: "timeout too long", hooks: with(func(hook *model.AdmissionWebhook) { hook.Timeout = 31 }), wantError: "timeout must be between"},
+		{name: "missing CA certificate", hooks: with(func(hook *model.AdmissionWebhook) { hook.CACert = "/nonexistent/ca.pem" }), wantError: "could not read CA certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAdmissionWebhooks(tt.hooks)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("newAdmissionWebhooks: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Fatalf("newAdmissionWebhooks returned %v, want an error containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+// TestAdmissionMutation checks that the changes of the mutating webhooks are stored and reviewed by the validating
+// webhooks, while the changes of the validating webhooks are ignored.
+func TestAdmissionMutation(t *testing.T) {
+	var validated atomic.Value
+	validating := newAdmissionWebhook(t, "validate", model.AdmissionValidating, func(review *model.AdmissionReview) model.AdmissionResponse {
+		validated.Store(slices.Clone(review.Announcement.Communities))
+		changed := *review.Announcement
+		changed.Communities = []string{"65000:666"}
+		return model.AdmissionResponse{Allowed: true, Announcement: &changed}
+	})
+	mutating := newAdmissionWebhook(t, "defaults", model.AdmissionMutating, func(review *model.AdmissionReview) model.AdmissionResponse {
+		changed := *review.Announcement
+		if !slices.Contains(changed.Communities, "65000:100") {
+			changed.Communities = append(changed.Communities, "65000:100")
+		}
+		return model.AdmissionResponse{Allowed: true, Announcement: &changed}
+	})
+	// The validating webhook is listed first and still reviews the mutated announcement
+	server := newTestServer(t, func(config *model.APIConfig) {
+		config.AdmissionWebhooksFile = writeAdmissionWebhooks(t, validating, mutating)
+	})
+
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/", testAnnouncement("alpha", "web", "192.0.2.1")); code != http.StatusCreated {
+		t.Fatalf("create answered %d: %s", code, response.Message)
+	}
+	if got := server.stored(t, "alpha", "web").Communities; !slices.Equal(got, []string{"65000:100"}) {
+		t.Fatalf("stored communities %v, want the injected 65000:100", got)
+	}
+	if got, _ := validated.Load().([]string); !slices.Equal(got, []string{"65000:100"}) {
+		t.Fatalf("validating webhook reviewed communities %v, want the injected 65000:100", got)
+	}
+}
+
+// TestAdmissionRejections checks the changes rejected by the admission webhooks and the failures admitted by the
+// fail-open policy.
+func TestAdmissionRejections(t *testing.T) {
+	hook := func(name, hookType string, change func(hook *model.AdmissionWebhook), review func(review *model.AdmissionReview) model.AdmissionResponse) model.AdmissionWebhook {
+		webhook := newAdmissionWebhook(t, name, hookType, review)
+		if change != nil {
+			change(&webhook)
+		}
+		return webhook
+	}
+	unreachable := func(policy string) func(hook *model.AdmissionWebhook) {
+		return func(hook *model.AdmissionWebhook) {
+			hook.URL = "https://127.0.0.1:1/review"
+			hook.FailurePolicy = policy
+		}
+	}
+	mutate := func(change func(announcement *model.Announcement)) func(review *model.AdmissionReview) model.AdmissionResponse {
+		return func(review *model.AdmissionReview) model.AdmissionResponse {
+			changed := *review.Announcement
+			change(&changed)
+			return model.AdmissionResponse{Allowed: true, Announcement: &changed}
+		}
+	}
+	allow := func(*model.AdmissionReview) model.AdmissionResponse { return model.AdmissionResponse{Allowed: true} }
+
+	tests := []struct {
+		name      string
+		hook      model.AdmissionWebhook
+		wantCode  int
+		wantError string
+	}{
+		{name: "rejected", hook: hook("deny", model.AdmissionValidating, nil, func(*model.AdmissionReview) model.AdmissionResponse {
+			return model.AdmissionResponse{Message: "change freeze"}
+		}), wantCode: http.StatusBadRequest, wantError: "rejected by admission webhook deny: change freeze"},
+		{name: "rejected field", hook: hook("fields", model.AdmissionValidating, nil, func(*model.AdmissionReview) model.AdmissionResponse {
+			return model.AdmissionResponse{Errors: []model.ValidationError{{Field: "communities", Message: "a site community is required"}}}
+		}), wantCode: http.StatusBadRequest, wantError: "communities: rejected by admission webhook fields: a site community is required"},
+		{name: "fail-closed", hook: hook("closed", model.AdmissionValidating, unreachable(model.AdmissionFailClosed), allow), wantCode: http.StatusInternalServerError, wantError: "admission webhook closed failed"},
+		{name: "fail-open", hook: hook("open", model.AdmissionValidating, unreachable(model.AdmissionFailOpen), allow), wantCode: http.StatusCreated},
+		{name: "timeout", hook: hook("slow", model.AdmissionValidating, func(hook *model.AdmissionWebhook) { hook.Timeout = 1 }, func(*model.AdmissionReview) model.AdmissionResponse {
+			time.Sleep(1500 * time.Millisecond)
+			return model.AdmissionResponse{Allowed: true}
+		}), wantCode: http.StatusInternalServerError, wantError: "admission webhook slow failed"},
+		{name: "identity changed", hook: hook("rename", model.AdmissionMutating, nil, mutate(func(announcement *model.Announcement) {
+			announcement.Meta.Name = "renamed"
+		})), wantCode: http.StatusInternalServerError, wantError: "changed the project or the name"},
+		{name: "identity changed with fail-open", hook: hook("rename-open", model.AdmissionMutating, func(hook *model.AdmissionWebhook) { hook.FailurePolicy = model.AdmissionFailOpen }, mutate(func(announcement *model.Announcement) {
+			announcement.Meta.Name = "renamed"
+		})), wantCode: http.StatusCreated},
+		{name: "mutated into an invalid announcement", hook: hook("broken", model.AdmissionMutating, nil, mutate(func(announcement *model.Announcement) {
+			announcement.Addresses.AnnouncedIP = "not-an-ip"
+		})), wantCode: http.StatusBadRequest, wantError: "changed by the admission webhooks"},
+		{name: "other project", hook: hook("beta-only", model.AdmissionValidating, func(hook *model.AdmissionWebhook) { hook.Projects = []string{"beta"} }, func(*model.AdmissionReview) model.AdmissionResponse {
+			return model.AdmissionResponse{}
+		}), wantCode: http.StatusCreated},
+		{name: "updates only", hook: hook("updates", model.AdmissionValidating, func(hook *model.AdmissionWebhook) {
+			hook.Operations = []model.AdmissionOperation{model.AdmissionUpdate}
+		}, func(*model.AdmissionReview) model.AdmissionResponse {
+			return model.AdmissionResponse{}
+		}), wantCode: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(config *model.APIConfig) {
+				config.AdmissionWebhooksFile = writeAdmissionWebhooks(t, tt.hook)
+			})
+			code, response := server.do(t, http.MethodPost, "/v1/announcements/", testAnnouncement("alpha", "web", "192.0.2.1"))
+			if code != tt.wantCode || !strings.Contains(response.Message, tt.wantError) {
+				t.Fatalf("create answered %d: %s, want %d with %q", code, response.Message, tt.wantCode, tt.wantError)
+			}
+			if _, err := server.Store.Get(announcementsPrefix + "alpha/web"); (err == nil) != (tt.wantCode == http.StatusCreated) {
+				t.Fatalf("announcement stored: %t, want %t", err == nil, tt.wantCode == http.StatusCreated)
+			}
+		})
+	}
+}
+
+// TestAdmissionReview checks the review of a create and of an update, and the dry run of a create.
+func TestAdmissionReview(t *testing.T) {
+	reviews := make(chan model.AdmissionReview, 3)
+	hook := newAdmissionWebhook(t, "record", model.AdmissionValidating, func(review *model.AdmissionReview) model.AdmissionResponse {
+		reviews <- *review
+		return model.AdmissionResponse{Allowed: true}
+	})
+	server := newTestServer(t, func(config *model.APIConfig) {
+		config.AdmissionWebhooksFile = writeAdmissionWebhooks(t, hook)
+	})
+
+	announcement := testAnnouncement("alpha", "web", "192.0.2.1")
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/?dryRun=true", announcement); code != http.StatusCreated {
+		t.Fatalf("dry-run create answered %d: %s", code, response.Message)
+	}
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/", announcement); code != http.StatusCreated {
+		t.Fatalf("create answered %d: %s", code, response.Message)
+	}
+	announcement.Addresses.AnnouncedIP = "192.0.2.2"
+	if code, response := server.do(t, http.MethodPatch, "/v1/announcements/", announcement); code != http.StatusOK {
+		t.Fatalf("update answered %d: %s", code, response.Message)
+	}
+
+	for i, want := range []struct {
+		operation model.AdmissionOperation
+		dryRun    bool
+		previous  string
+	}{
+		{operation: model.AdmissionCreate, dryRun: true},
+		{operation: model.AdmissionCreate},
+		{operation: model.AdmissionUpdate, previous: "192.0.2.1"},
+	} {
+		review := <-reviews
+		if review.Operation != want.operation || review.DryRun != want.dryRun || review.ID == "" {
+			t.Errorf("review %d = %s (dry run %t), want %s (dry run %t)", i, review.Operation, review.DryRun, want.operation, want.dryRun)
+		}
+		if (review.Previous == nil) != (want.previous == "") || (review.Previous != nil && review.Previous.Addresses.AnnouncedIP != want.previous) {
+			t.Errorf("review %d has previous announcement %+v, want announced IP %q", i, review.Previous, want.previous)
+		}
+	}
+}
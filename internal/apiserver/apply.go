@@ -0,0 +1,279 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// serverManagedFields lists the top-level announcement fields that can not be set through the apply endpoint.
+var serverManagedFields = []string{"status", "last-applied"}
+
+// registerApplyRoutes adds the route that creates or updates an announcement with declarative apply semantics.
+func registerApplyRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog, policy *PrefixPolicy, admission *AdmissionWebhooks, config *model.APIConfig, clk clock.Clock) {
+	v1.POST("/apply", func(c *gin.Context) {
+		var request model.ApplyRequest
+		if err := bindAnnouncementJSON(c, config.StrictDecoding, &request); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if request.Manager == "" {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "manager is required",
+				Data:    nil,
+			})
+			return
+		}
+
+		var desired map[string]interface{}
+		var partial model.Announcement
+		if err := json.Unmarshal(request.Announcement, &desired); err != nil || json.Unmarshal(request.Announcement, &partial) != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "announcement must be a JSON object",
+				Data:    nil,
+			})
+			return
+		}
+		if config.StrictDecoding {
+			if err := decodeStrict(bytes.NewReader(request.Announcement), &model.Announcement{}); err != nil {
+				c.JSON(http.StatusBadRequest, model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    nil,
+				})
+				return
+			}
+		}
+		for _, field := range serverManagedFields {
+			delete(desired, field)
+		}
+
+		project, name := partial.Meta.Project, partial.Meta.Name
+		if project == "" || name == "" {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "meta.project and meta.name are required",
+				Data:    nil,
+			})
+			return
+		}
+
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		key := announcementsPrefix + project + "/" + name
+		value, err := db.Get(key)
+		exists := err == nil
+		if err != nil && err.Error() != "key not found" {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to check announcement existence: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var stored model.Announcement
+		current := map[string]interface{}{}
+		if exists {
+			if err := json.Unmarshal([]byte(value), &stored); err != nil || json.Unmarshal([]byte(value), &current) != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+		}
+
+		var lastApplied map[string]interface{}
+		if raw, ok := stored.LastApplied[request.Manager]; ok {
+			_ = json.Unmarshal(raw, &lastApplied)
+		}
+
+		merged, err := mergeAnnouncement(current, lastApplied, desired)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		// Record the applied configuration of the manager for the next three-way diff
+		merged.LastApplied = maps.Clone(stored.LastApplied)
+		if merged.LastApplied == nil {
+			merged.LastApplied = make(map[string]json.RawMessage)
+		}
+		appliedConfig, err := json.Marshal(desired)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		merged.LastApplied[request.Manager] = appliedConfig
+
+		if err := checkNameLength(merged.Meta.Name, config.MaxAnnouncementNameLength); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		if err := validateAnnouncement(merged); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		var previous *model.Announcement
+		if exists {
+			previous = &stored
+		}
+		if err := admission.admit(c, false, previous, merged); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		// The allocation is recorded by the announcement itself and released when it is deleted
+		if !exists {
+			if err := allocatePoolAddress(db, merged, nil); err != nil {
+				c.JSON(validationErrorStatus(err), model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    validationErrors(err),
+				})
+				return
+			}
+		}
+
+		unlockConflicts := lockConflicts(serializer, policy)
+		defer unlockConflicts()
+
+		if err := checkProjectAdmission(db, policy, merged, nil); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		// An update re-activates a cancelled announcement
+		if merged.Status.Status == model.StatusCancelled {
+			merged.Status.Status = model.StatusPending
+		}
+		pruneDrainedNextHops(merged)
+		now := clk.Now()
+		resolveSchedule(merged, now)
+		stampGeneration(c, previous, merged, now)
+
+		newValue, err := json.Marshal(merged)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := db.Put(key, string(newValue)); err != nil {
+			c.JSON(writeErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to write announcement: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+		refreshResourceVersion(db, key, merged)
+
+		eventType, status := model.EventAdded, http.StatusCreated
+		if exists {
+			eventType, status = model.EventUpdated, http.StatusOK
+			changes.record(c, &stored, merged)
+		} else {
+			changes.record(c, nil, merged)
+		}
+		c.JSON(status, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement applied successfully",
+			Data: model.Event{
+				Type:         eventType,
+				Announcement: *merged,
+			},
+		})
+	})
+}
+
+// mergeAnnouncement performs a three-way merge of the stored announcement with the configuration applied by a manager.
+// Fields present in desired are set, fields present in lastApplied but missing from desired are removed, and all
+// other fields, owned by other managers or by the server, are kept as stored.
+func mergeAnnouncement(current, lastApplied, desired map[string]interface{}) (*model.Announcement, error) {
+	merged := mergeObjects(current, lastApplied, desired)
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	var announcement model.Announcement
+	if err := json.Unmarshal(data, &announcement); err != nil {
+		return nil, fmt.Errorf("merged announcement is invalid: %w", err)
+	}
+	return &announcement, nil
+}
+
+// mergeObjects merges JSON objects recursively. Arrays and scalar values are replaced as a whole.
+func mergeObjects(current, lastApplied, desired map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(current))
+	for key, value := range current {
+		result[key] = value
+	}
+
+	// Remove the fields the manager no longer manages
+	for key := range lastApplied {
+		if _, ok := desired[key]; !ok {
+			delete(result, key)
+		}
+	}
+
+	for key, desiredValue := range desired {
+		desiredObject, desiredIsObject := desiredValue.(map[string]interface{})
+		currentObject, currentIsObject := result[key].(map[string]interface{})
+		if desiredIsObject && currentIsObject {
+			lastObject, _ := lastApplied[key].(map[string]interface{})
+			result[key] = mergeObjects(currentObject, lastObject, desiredObject)
+			continue
+		}
+		result[key] = desiredValue
+	}
+
+	return result
+}
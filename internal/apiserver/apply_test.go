@@ -0,0 +1,164 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// TestMergeObjects checks the three-way merge of the stored object, the configuration last applied by the manager
+// and the configuration it applies.
+func TestMergeObjects(t *testing.T) {
+	decode := func(data string) map[string]interface{} {
+		if data == "" {
+			return nil
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &object); err != nil {
+			t.Fatalf("malformed test object %s: %v", data, err)
+		}
+		return object
+	}
+
+	tests := []struct {
+		name        string
+		current     string
+		lastApplied string
+		desired     string
+		want        string
+	}{
+		{
+			name:    "create",
+			desired: `{"med": 10}`,
+			want:    `{"med": 10}`,
+		},
+		{
+			name:        "field changed",
+			current:     `{"med": 10}`,
+			lastApplied: `{"med": 10}`,
+			desired:     `{"med": 20}`,
+			want:        `{"med": 20}`,
+		},
+		{
+			name:        "field no longer managed",
+			current:     `{"med": 10, "local-pref": 200}`,
+			lastApplied: `{"med": 10, "local-pref": 200}`,
+			desired:     `{"med": 10}`,
+			want:        `{"med": 10}`,
+		},
+		{
+			name:        "field of another manager kept",
+			current:     `{"med": 10, "communities": ["65000:1"]}`,
+			lastApplied: `{"med": 10}`,
+			desired:     `{"med": 20}`,
+			want:        `{"med": 20, "communities": ["65000:1"]}`,
+		},
+		{
+			name:        "field of another manager taken over",
+			current:     `{"med": 10, "communities": ["65000:1"]}`,
+			lastApplied: `{"med": 10}`,
+			desired:     `{"med": 10, "communities": ["65000:2"]}`,
+			want:        `{"med": 10, "communities": ["65000:2"]}`,
+		},
+		{
+			name:        "nested objects merged",
+			current:     `{"health-check": {"type": "tcp", "port": 80, "interval": 5}}`,
+			lastApplied: `{"health-check": {"type": "tcp", "port": 80}}`,
+			desired:     `{"health-check": {"type": "http", "path": "/healthz"}}`,
+			want:        `{"health-check": {"type": "http", "path": "/healthz", "interval": 5}}`,
+		},
+		{
+			name:        "arrays replaced as a whole",
+			current:     `{"communities": ["65000:1", "65000:2"]}`,
+			lastApplied: `{"communities": ["65000:1", "65000:2"]}`,
+			desired:     `{"communities": ["65000:3"]}`,
+			want:        `{"communities": ["65000:3"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := decode(tt.current)
+			if current == nil {
+				current = map[string]interface{}{}
+			}
+			got := mergeObjects(current, decode(tt.lastApplied), decode(tt.desired))
+			if want := decode(tt.want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("merged = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestApplyCoOwnership checks that two managers applying different fields of the same announcement keep each other's
+// fields, and that a manager removes the fields it stops applying.
+func TestApplyCoOwnership(t *testing.T) {
+	server := newTestServer(t, nil)
+	client := server.client()
+	ctx := context.Background()
+
+	// The service controller owns the addresses and the next hops
+	base := testAnnouncement("alpha", "web", "192.0.2.1")
+	base.MED = 10
+	if _, err := client.V1ApplyAnnouncement(ctx, "service-controller", base); err != nil {
+		t.Fatalf("V1ApplyAnnouncement of the service controller: %v", err)
+	}
+
+	// The policy controller owns the communities
+	communities := &model.Announcement{Meta: base.Meta, Communities: []string{"65000:100"}}
+	if _, err := client.V1ApplyAnnouncement(ctx, "policy-controller", communities); err != nil {
+		t.Fatalf("V1ApplyAnnouncement of the policy controller: %v", err)
+	}
+
+	// The service controller stops setting the MED and applies its configuration again
+	base.MED = 0
+	applied, err := client.V1ApplyAnnouncement(ctx, "service-controller", base)
+	if err != nil {
+		t.Fatalf("V1ApplyAnnouncement of the service controller: %v", err)
+	}
+	if applied.MED != 0 {
+		t.Errorf("MED = %d, want it removed", applied.MED)
+	}
+	if !slices.Equal(applied.Communities, []string{"65000:100"}) {
+		t.Errorf("communities = %v, want those of the policy controller", applied.Communities)
+	}
+	if applied.Addresses.AnnouncedIP != "192.0.2.1" || len(applied.NextHops) != 1 {
+		t.Errorf("applied announcement lost the fields of the service controller: %+v", applied)
+	}
+
+	stored := server.stored(t, "alpha", "web")
+	for _, manager := range []string{"service-controller", "policy-controller"} {
+		if _, ok := stored.LastApplied[manager]; !ok {
+			t.Errorf("the configuration last applied by %s is not recorded", manager)
+		}
+	}
+}
+
+// TestApplyServerManagedFields checks that the status and the recorded configurations can not be applied.
+func TestApplyServerManagedFields(t *testing.T) {
+	server := newTestServer(t, nil)
+	stored := testAnnouncement("alpha", "web", "192.0.2.1")
+	stored.Status = model.Status{Status: model.StatusFailed}
+	server.put(t, stored)
+
+	raw := json.RawMessage(`{"meta": {"project": "alpha", "name": "web"}, "med": 5, "status": {"status": "programmed"}, "last-applied": {"other": {"med": 1}}}`)
+	code, response := server.do(t, http.MethodPost, "/v1/apply", model.ApplyRequest{Manager: "test", Announcement: raw})
+	if code != http.StatusOK {
+		t.Fatalf("apply answered %d: %s", code, response.Message)
+	}
+
+	announcement := server.stored(t, "alpha", "web")
+	if announcement.Status.Status != model.StatusFailed {
+		t.Errorf("status = %q, want the stored one", announcement.Status.Status)
+	}
+	if _, ok := announcement.LastApplied["other"]; ok {
+		t.Error("the recorded configuration of another manager was applied")
+	}
+	if announcement.MED != 5 {
+		t.Errorf("MED = %d, want 5", announcement.MED)
+	}
+}
@@ -0,0 +1,281 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// auditPrefix is the key prefix of the audit log. The keys end with the zero-padded Unix time in nanoseconds, so
+// they are ordered by time.
+const auditPrefix = "v1/audit/"
+
+// defaultAuditPageSize is the number of audit entries returned when the request has no limit.
+const defaultAuditPageSize = 100
+
+// changeLog records the changes of the announcements in the database: it appends them to the audit log, whose
+// entries are written once under a new key and never modified, keeps the revision history and the events of the
+// announcements and notifies the webhooks.
+type changeLog struct {
+	db        model.DatabaseAdapter
+	clock     clock.Clock
+	revisions int              // revisions is the number of revisions kept in the history of every announcement; zero disables the history.
+	events    int              // events is the number of events kept for every announcement; zero disables the events.
+	notifier  *WebhookNotifier // notifier sends the changes to the webhooks; nil disables the notifications.
+}
+
+// newChangeLog creates the change log stored in the database.
+func newChangeLog(db model.DatabaseAdapter, clk clock.Clock, revisions, events int, notifier *WebhookNotifier) *changeLog {
+	return &changeLog{db: db, clock: clk, revisions: revisions, events: events, notifier: notifier}
+}
+
+// record appends the change made by the request. The action is derived from the announcements: before is nil for
+// a creation, after is nil for a deletion.
+func (l *changeLog) record(c *gin.Context, before, after *model.Announcement) {
+	entry := model.AuditEntry{
+		Actor:      c.GetString(actorContextKey),
+		RemoteAddr: c.ClientIP(),
+		RequestID:  c.GetHeader("X-Request-ID"),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+		entry.Token = tokenFingerprint(token)
+	}
+	l.write(entry, before, after)
+}
+
+// write completes the entry with the identity and the changes of the announcement, appends it, adds the new
+// revision to the history, records the events of the change and notifies the webhooks. Failed writes are logged only, since the change they record is
+// already stored.
+func (l *changeLog) write(entry model.AuditEntry, before, after *model.Announcement) {
+	now := l.clock.Now().UTC()
+	entry.ID = auditID(now)
+	entry.Timestamp = now
+
+	switch {
+	case before == nil:
+		entry.Action = model.AuditCreate
+	case after == nil:
+		entry.Action = model.AuditDelete
+	default:
+		entry.Action = model.AuditUpdate
+	}
+	if after != nil {
+		entry.Project, entry.Name, entry.ResourceVersion = after.Meta.Project, after.Meta.Name, after.Meta.ResourceVersion
+	} else if before != nil {
+		entry.Project, entry.Name = before.Meta.Project, before.Meta.Name
+	}
+	entry.Changes = diffAnnouncements(before, after)
+
+	value, err := json.Marshal(entry)
+	if err == nil {
+		err = l.db.Put(auditPrefix+entry.ID, string(value))
+	}
+	if err != nil {
+		slog.Error("failed to write audit entry", "project", entry.Project, "name", entry.Name, "action", entry.Action, "actor", entry.Actor, "error", err)
+	}
+
+	if after != nil {
+		l.addRevision(entry, before, after)
+	}
+	l.addEvents(entry, before, after)
+	l.notifier.notify(entry, before, after)
+}
+
+// auditID returns a new entry ID: the zero-padded Unix time in nanoseconds followed by a random suffix that keeps
+// the entries of concurrent API server replicas apart.
+func auditID(t time.Time) string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%020d-%s", t.UnixNano(), hex.EncodeToString(suffix))
+}
+
+// tokenFingerprint identifies the bearer token without revealing it: the first 8 bytes of its SHA-256 hash.
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// diffAnnouncements returns the changed fields of the announcement sorted by path. The resource version is not
+// compared, since it changes with every write.
+func diffAnnouncements(before, after *model.Announcement) []model.AuditChange {
+	changes := diffObjects("", announcementFields(before), announcementFields(after), []model.AuditChange{})
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// announcementFields returns the JSON object of the announcement without the resource version, or nil for nil.
+func announcementFields(announcement *model.Announcement) map[string]interface{} {
+	if announcement == nil {
+		return nil
+	}
+	copied := *announcement
+	copied.Meta.ResourceVersion = ""
+
+	var fields map[string]interface{}
+	data, err := json.Marshal(copied)
+	if err != nil || json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+	return fields
+}
+
+// diffObjects appends the differences of the JSON objects to changes. Objects present on both sides are compared
+// recursively, all other values as a whole.
+func diffObjects(path string, before, after map[string]interface{}, changes []model.AuditChange) []model.AuditChange {
+	keys := make(map[string]struct{}, len(before)+len(after))
+	for key := range before {
+		keys[key] = struct{}{}
+	}
+	for key := range after {
+		keys[key] = struct{}{}
+	}
+
+	for key := range keys {
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+
+		beforeValue, inBefore := before[key]
+		afterValue, inAfter := after[key]
+		beforeObject, beforeIsObject := beforeValue.(map[string]interface{})
+		afterObject, afterIsObject := afterValue.(map[string]interface{})
+		if beforeIsObject && afterIsObject {
+			changes = diffObjects(field, beforeObject, afterObject, changes)
+			continue
+		}
+		if inBefore && inAfter && reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+
+		change := model.AuditChange{Field: field}
+		if inBefore {
+			change.Before, _ = json.Marshal(beforeValue)
+		}
+		if inAfter {
+			change.After, _ = json.Marshal(afterValue)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// registerAuditRoutes adds the route that lists the audit log.
+func registerAuditRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter) {
+	v1.GET("/audit", func(c *gin.Context) {
+		limit, startKey, since, until, err := parseAuditQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		list, err := listAudit(db, c.Query("project"), startKey, since, until, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Audit entries retrieved successfully",
+			Data:    list,
+		})
+	})
+}
+
+// parseAuditQuery reads the limit, continue, since and until query parameters. The times are in RFC 3339 format,
+// a zero until means no upper bound. The returned start key is that of the continue token, or the first key at
+// since.
+func parseAuditQuery(c *gin.Context) (int64, string, time.Time, time.Time, error) {
+	var since, until time.Time
+	limit := int64(defaultAuditPageSize)
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.ParseInt(value, 10, 64); err != nil || limit < 1 {
+			return 0, "", since, until, fmt.Errorf("limit must be a positive integer")
+		}
+		limit = min(limit, maxPageSize)
+	}
+
+	for name, t := range map[string]*time.Time{"since": &since, "until": &until} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return 0, "", since, until, fmt.Errorf("%s must be a time in RFC 3339 format", name)
+		}
+		*t = parsed
+	}
+
+	startKey, err := decodeContinueToken(c.Query("continue"), auditPrefix)
+	if err != nil {
+		return 0, "", since, until, err
+	}
+	if startKey == "" && !since.IsZero() {
+		startKey = fmt.Sprintf("%s%020d", auditPrefix, since.UnixNano())
+	}
+	return limit, startKey, since, until, nil
+}
+
+// listAudit returns up to limit entries of the project, or of all projects if empty, starting at the key and
+// written before until. The pages of the database are read until the result is full, so that a selective project
+// filter still returns complete pages.
+func listAudit(db model.DatabaseAdapter, project, startKey string, since, until time.Time, limit int64) (*model.AuditList, error) {
+	list := &model.AuditList{Items: make([]model.AuditEntry, 0)}
+	for {
+		values, next, err := db.GetObjectsPage(auditPrefix, startKey, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range values {
+			var entry model.AuditEntry
+			if err := json.Unmarshal([]byte(value), &entry); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal audit entry")
+			}
+			if !until.IsZero() && entry.Timestamp.After(until) {
+				return list, nil
+			}
+			if project != "" && entry.Project != project || entry.Timestamp.Before(since) {
+				continue
+			}
+
+			list.Items = append(list.Items, entry)
+			if int64(len(list.Items)) == limit {
+				// The smallest key greater than that of the entry
+				list.Continue = encodeContinueToken(auditPrefix + entry.ID + "\x00")
+				return list, nil
+			}
+		}
+
+		if next == "" {
+			return list, nil
+		}
+		startKey = next
+	}
+}
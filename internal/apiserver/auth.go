@@ -0,0 +1,228 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// Authenticator authenticates the v1 API requests with static API tokens or OIDC bearer tokens and authorizes them
+// against per-project role bindings.
+type Authenticator struct {
+	tokens       []model.StaticToken
+	bindings     map[string]map[string]model.Role // bindings maps a subject to the roles granted per project.
+	verifier     *oidc.IDTokenVerifier            // verifier validates OIDC tokens; nil disables OIDC.
+	subjectClaim string                           // subjectClaim is the OIDC claim used as the subject.
+}
+
+// NewAuthenticator creates the authenticator from the policy file and the OIDC settings of the config. It returns
+// nil when neither a policy file nor an OIDC issuer is configured, i.e. authentication is disabled.
+func NewAuthenticator(ctx context.Context, config *model.APIConfig) (*Authenticator, error) {
+	if config.AuthPolicyFile == "" && config.OIDCIssuerURL == "" {
+		return nil, nil
+	}
+
+	a := &Authenticator{
+		bindings:     make(map[string]map[string]model.Role),
+		subjectClaim: config.OIDCSubjectClaim,
+	}
+
+	if config.AuthPolicyFile != "" {
+		policy, err := readAuthPolicy(config.AuthPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.tokens = policy.Tokens
+		for _, binding := range policy.Bindings {
+			if binding.Role != model.RoleRead && binding.Role != model.RoleWrite && binding.Role != model.RoleController {
+				return nil, fmt.Errorf("unknown role %q bound to subject %q", binding.Role, binding.Subject)
+			}
+			if a.bindings[binding.Subject] == nil {
+				a.bindings[binding.Subject] = make(map[string]model.Role)
+			}
+			// Keep the highest role when a subject is bound several times in the same project
+			if current := a.bindings[binding.Subject][binding.Project]; !current.Allows(binding.Role) {
+				a.bindings[binding.Subject][binding.Project] = binding.Role
+			}
+		}
+	}
+
+	if config.OIDCIssuerURL != "" {
+		provider, err := oidc.NewProvider(ctx, config.OIDCIssuerURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+		}
+		a.verifier = provider.Verifier(&oidc.Config{ClientID: config.OIDCClientID})
+	}
+
+	return a, nil
+}
+
+// readAuthPolicy reads the JSON authorization policy file.
+func readAuthPolicy(path string) (*model.AuthPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read auth policy file: %w", err)
+	}
+
+	var policy model.AuthPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse auth policy file: %w", err)
+	}
+	return &policy, nil
+}
+
+// Middleware returns the gin middleware that rejects unauthenticated requests with 401 and requests without
+// a sufficient role in the target project with 403. The subject is stored in the context for the access log.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, err := a.authenticate(c)
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		c.Set(actorContextKey, subject)
+
+		project, err := requestProject(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		role := requiredRole(c)
+		if !a.authorized(subject, project, role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("%s has no %s access to project %s", subject, role, project),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authenticate returns the subject of the bearer token of the request. Static tokens are checked first.
+func (a *Authenticator) authenticate(c *gin.Context) (string, error) {
+	header := c.GetHeader("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", fmt.Errorf("bearer token is required")
+	}
+
+	for _, staticToken := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(staticToken.Token), []byte(token)) == 1 {
+			return staticToken.Subject, nil
+		}
+	}
+
+	if a.verifier == nil {
+		return "", fmt.Errorf("invalid token")
+	}
+
+	idToken, err := a.verifier.Verify(c.Request.Context(), token)
+	if err != nil {
+		return "", fmt.Errorf("invalid token")
+	}
+	if a.subjectClaim == "" || a.subjectClaim == "sub" {
+		return idToken.Subject, nil
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", fmt.Errorf("invalid token claims")
+	}
+	subject, ok := claims[a.subjectClaim].(string)
+	if !ok || subject == "" {
+		return "", fmt.Errorf("token has no %s claim", a.subjectClaim)
+	}
+	return subject, nil
+}
+
+// authorized reports whether the subject has the required role in the project or in all projects.
+func (a *Authenticator) authorized(subject, project string, required model.Role) bool {
+	roles := a.bindings[subject]
+	if roles[model.AllProjects].Allows(required) {
+		return true
+	}
+	return project != model.AllProjects && roles[project].Allows(required)
+}
+
+// requestProject returns the project targeted by the request: the project path parameter, the project filter of the
+// audit log, the export or the conflicts report, the project of the announcement in the request body, or
+// model.AllProjects for the cluster-wide endpoints. The project resources and the pools are changed with cluster-wide
+// access only, so that the writers of a project can not raise its quota or claim more addresses.
+func requestProject(c *gin.Context) (string, error) {
+	if (strings.HasPrefix(c.FullPath(), "/v1/projects") || strings.HasPrefix(c.FullPath(), "/v1/pools")) && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return model.AllProjects, nil
+	}
+	if project := c.Param("project"); project != "" {
+		return project, nil
+	}
+	if project := c.Query("project"); project != "" && (c.FullPath() == "/v1/audit" || c.FullPath() == "/v1/export" || c.FullPath() == "/v1/conflicts" || c.FullPath() == "/v1/watch/announcements/") {
+		return project, nil
+	}
+
+	fullPath := c.FullPath()
+	if c.Request.Body == nil || c.Request.ContentLength == 0 || !strings.HasPrefix(fullPath, "/v1/announcements/") && fullPath != "/v1/apply" {
+		return model.AllProjects, nil
+	}
+
+	// Peek at the announcement in the body and restore it for the handler
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read request body: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	// The apply endpoint wraps the announcement into the request envelope
+	if fullPath == "/v1/apply" {
+		var request model.ApplyRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return model.AllProjects, nil
+		}
+		body = request.Announcement
+	}
+
+	var announcement model.Announcement
+	if err := json.Unmarshal(body, &announcement); err != nil || announcement.Meta.Project == "" {
+		// Let the handler report the malformed body, cluster-wide access is required meanwhile
+		return model.AllProjects, nil
+	}
+	return announcement.Meta.Project, nil
+}
+
+// requiredRole returns the role needed for the request. Reads, queries and validations need RoleRead, status
+// reports and updater registrations need RoleController, everything else needs RoleWrite.
+func requiredRole(c *gin.Context) model.Role {
+	switch {
+	case c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead:
+		return model.RoleRead
+	case strings.HasSuffix(c.FullPath(), "/status") || strings.HasPrefix(c.FullPath(), "/v1/sites/"):
+		return model.RoleController
+	case strings.HasSuffix(c.FullPath(), "/query") || strings.HasSuffix(c.FullPath(), "/validate"):
+		return model.RoleRead
+	default:
+		return model.RoleWrite
+	}
+}
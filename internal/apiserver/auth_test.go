@@ -0,0 +1,160 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// TestRequestProjectAndRole checks the project and the role the middleware authorizes each kind of request against.
+func TestRequestProjectAndRole(t *testing.T) {
+	announcement := func(project string) string {
+		data, _ := json.Marshal(testAnnouncement(project, "web", "192.0.2.1"))
+		return string(data)
+	}
+	apply := func(project string) string {
+		data, _ := json.Marshal(model.ApplyRequest{Manager: "test", Announcement: json.RawMessage(announcement(project))})
+		return string(data)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		route       string
+		url         string
+		body        string
+		wantProject string
+		wantRole    model.Role
+	}{
+		{name: "get by path", method: http.MethodGet, route: "/v1/announcements/:project/:name", url: "/v1/announcements/alpha/web", wantProject: "alpha", wantRole: model.RoleRead},
+		{name: "delete by path", method: http.MethodDelete, route: "/v1/announcements/:project/:name", url: "/v1/announcements/alpha/web", wantProject: "alpha", wantRole: model.RoleWrite},
+		{name: "create from body", method: http.MethodPost, route: "/v1/announcements/", url: "/v1/announcements/", body: announcement("beta"), wantProject: "beta", wantRole: model.RoleWrite},
+		{name: "update from body", method: http.MethodPatch, route: "/v1/announcements/", url: "/v1/announcements/", body: announcement("beta"), wantProject: "beta", wantRole: model.RoleWrite},
+		{name: "apply from envelope", method: http.MethodPost, route: "/v1/apply", url: "/v1/apply", body: apply("beta"), wantProject: "beta", wantRole: model.RoleWrite},
+		{name: "malformed body", method: http.MethodPost, route: "/v1/announcements/", url: "/v1/announcements/", body: "{", wantProject: model.AllProjects, wantRole: model.RoleWrite},
+		{name: "body without project", method: http.MethodPost, route: "/v1/announcements/", url: "/v1/announcements/", body: announcement(""), wantProject: model.AllProjects, wantRole: model.RoleWrite},
+		{name: "batch", method: http.MethodPost, route: "/v1/announcements/batch", url: "/v1/announcements/batch", body: `{"operations":[]}`, wantProject: model.AllProjects, wantRole: model.RoleWrite},
+		{name: "validate", method: http.MethodPost, route: "/v1/announcements/validate", url: "/v1/announcements/validate", body: announcement("beta"), wantProject: "beta", wantRole: model.RoleRead},
+		{name: "query", method: http.MethodPost, route: "/v1/announcements/:project/query", url: "/v1/announcements/alpha/query", body: `{}`, wantProject: "alpha", wantRole: model.RoleRead},
+		{name: "status report", method: http.MethodPatch, route: "/v1/announcements/:project/:name/status", url: "/v1/announcements/alpha/web/status", body: `{}`, wantProject: "alpha", wantRole: model.RoleController},
+		{name: "updater registration", method: http.MethodPut, route: "/v1/sites/:site/updaters/:identity", url: "/v1/sites/dc1/updaters/u1", body: `{}`, wantProject: model.AllProjects, wantRole: model.RoleController},
+		{name: "audit filter", method: http.MethodGet, route: "/v1/audit", url: "/v1/audit?project=alpha", wantProject: "alpha", wantRole: model.RoleRead},
+		{name: "export filter", method: http.MethodGet, route: "/v1/export", url: "/v1/export?project=alpha", wantProject: "alpha", wantRole: model.RoleRead},
+		{name: "export of all projects", method: http.MethodGet, route: "/v1/export", url: "/v1/export", wantProject: model.AllProjects, wantRole: model.RoleRead},
+		{name: "filter ignored elsewhere", method: http.MethodGet, route: "/v1/peers", url: "/v1/peers?project=alpha", wantProject: model.AllProjects, wantRole: model.RoleRead},
+		{name: "project quota change", method: http.MethodPatch, route: "/v1/projects/:project", url: "/v1/projects/alpha", body: `{}`, wantProject: model.AllProjects, wantRole: model.RoleWrite},
+		{name: "project read", method: http.MethodGet, route: "/v1/projects/:project", url: "/v1/projects/alpha", wantProject: "alpha", wantRole: model.RoleRead},
+		{name: "pool change", method: http.MethodPost, route: "/v1/pools/:project", url: "/v1/pools/alpha", body: `{}`, wantProject: model.AllProjects, wantRole: model.RoleWrite},
+		{name: "import", method: http.MethodPost, route: "/v1/import", url: "/v1/import", body: `{}`, wantProject: model.AllProjects, wantRole: model.RoleWrite},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var project string
+			var role model.Role
+			var body []byte
+			router := gin.New()
+			router.Handle(tt.method, tt.route, func(c *gin.Context) {
+				var err error
+				if project, err = requestProject(c); err != nil {
+					t.Fatalf("requestProject: %v", err)
+				}
+				role = requiredRole(c)
+				body, _ = c.GetRawData()
+			})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body)))
+			if project != tt.wantProject {
+				t.Errorf("project = %q, want %q", project, tt.wantProject)
+			}
+			if role != tt.wantRole {
+				t.Errorf("role = %q, want %q", role, tt.wantRole)
+			}
+			// The body is restored for the handler after the project is read from it
+			if string(body) != tt.body {
+				t.Errorf("handler read body %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+// TestAuthMiddleware checks the authentication and the authorization of the requests against the role bindings,
+// including the requests to the projects the subject has no role in.
+func TestAuthMiddleware(t *testing.T) {
+	policy := model.AuthPolicy{
+		Tokens: []model.StaticToken{
+			{Token: "reader-token", Subject: "reader"},
+			{Token: "writer-token", Subject: "writer"},
+			{Token: "controller-token", Subject: "controller"},
+			{Token: "admin-token", Subject: "admin"},
+		},
+		Bindings: []model.RoleBinding{
+			{Subject: "reader", Project: "alpha", Role: model.RoleRead},
+			{Subject: "writer", Project: "alpha", Role: model.RoleRead},
+			{Subject: "writer", Project: "alpha", Role: model.RoleWrite},
+			{Subject: "controller", Project: "alpha", Role: model.RoleController},
+			{Subject: "admin", Project: model.AllProjects, Role: model.RoleWrite},
+		},
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policyFile := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(policyFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, func(config *model.APIConfig) {
+		config.AuthPolicyFile = policyFile
+	})
+	server.put(t, testAnnouncement("alpha", "web", "192.0.2.1"))
+	server.put(t, testAnnouncement("beta", "web", "192.0.2.2"))
+
+	raw, _ := json.Marshal(testAnnouncement("beta", "api", "192.0.2.3"))
+	tests := []struct {
+		name     string
+		token    string
+		method   string
+		path     string
+		body     any
+		wantCode int
+	}{
+		{name: "no token", method: http.MethodGet, path: "/v1/announcements/alpha/web", wantCode: http.StatusUnauthorized},
+		{name: "unknown token", token: "forged", method: http.MethodGet, path: "/v1/announcements/alpha/web", wantCode: http.StatusUnauthorized},
+		{name: "reader reads", token: "reader-token", method: http.MethodGet, path: "/v1/announcements/alpha/web", wantCode: http.StatusOK},
+		{name: "reader validates", token: "reader-token", method: http.MethodPost, path: "/v1/announcements/validate", body: testAnnouncement("alpha", "api", "192.0.2.4"), wantCode: http.StatusOK},
+		{name: "reader can not create", token: "reader-token", method: http.MethodPost, path: "/v1/announcements/", body: testAnnouncement("alpha", "api", "192.0.2.4"), wantCode: http.StatusForbidden},
+		{name: "reader can not read another project", token: "reader-token", method: http.MethodGet, path: "/v1/announcements/beta/web", wantCode: http.StatusForbidden},
+		{name: "writer creates", token: "writer-token", method: http.MethodPost, path: "/v1/announcements/", body: testAnnouncement("alpha", "api", "192.0.2.4"), wantCode: http.StatusCreated},
+		{name: "writer can not create in another project", token: "writer-token", method: http.MethodPost, path: "/v1/announcements/", body: testAnnouncement("beta", "api", "192.0.2.3"), wantCode: http.StatusForbidden},
+		{name: "writer can not apply to another project", token: "writer-token", method: http.MethodPost, path: "/v1/apply", body: model.ApplyRequest{Manager: "test", Announcement: raw}, wantCode: http.StatusForbidden},
+		{name: "writer can not delete in another project", token: "writer-token", method: http.MethodDelete, path: "/v1/announcements/beta/web", wantCode: http.StatusForbidden},
+		{name: "writer can not batch", token: "writer-token", method: http.MethodPost, path: "/v1/announcements/batch", body: model.BatchRequest{Operations: []model.BatchOperation{{Action: model.BatchApply, Announcement: *testAnnouncement("alpha", "db", "192.0.2.5")}}}, wantCode: http.StatusForbidden},
+		{name: "writer can not report the status", token: "writer-token", method: http.MethodPatch, path: "/v1/announcements/alpha/web/status", body: model.Status{}, wantCode: http.StatusForbidden},
+		{name: "writer can not change the quota", token: "writer-token", method: http.MethodPost, path: "/v1/projects", body: model.Project{Name: "alpha"}, wantCode: http.StatusForbidden},
+		{name: "controller reports the status", token: "controller-token", method: http.MethodPatch, path: "/v1/announcements/alpha/web/status", body: model.Status{Status: model.StatusProgrammed}, wantCode: http.StatusOK},
+		{name: "controller can not report in another project", token: "controller-token", method: http.MethodPatch, path: "/v1/announcements/beta/web/status", body: model.Status{Status: model.StatusProgrammed}, wantCode: http.StatusForbidden},
+		{name: "admin creates in any project", token: "admin-token", method: http.MethodPost, path: "/v1/announcements/", body: testAnnouncement("beta", "api", "192.0.2.3"), wantCode: http.StatusCreated},
+		{name: "admin can not report the status", token: "admin-token", method: http.MethodPatch, path: "/v1/announcements/beta/web/status", body: model.Status{}, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header []string
+			if tt.token != "" {
+				header = []string{"Authorization", "Bearer " + tt.token}
+			}
+			code, response := server.do(t, tt.method, tt.path, tt.body, header...)
+			if code != tt.wantCode {
+				t.Fatalf("answered %d: %s, want %d", code, response.Message, tt.wantCode)
+			}
+		})
+	}
+}
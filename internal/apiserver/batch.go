@@ -0,0 +1,241 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// maxBatchSize is the maximum number of operations in a single batch. It keeps the transaction, including the
+// next-hop index updates, within the etcd limit of operations per transaction.
+const maxBatchSize = 25
+
+// registerBatchRoutes adds the route that applies a set of announcement changes atomically.
+func registerBatchRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog, policy *PrefixPolicy, admission *AdmissionWebhooks, config *model.APIConfig, clk clock.Clock) {
+	v1.POST("/announcements/batch", func(c *gin.Context) {
+		var request model.BatchRequest
+		if err := bindAnnouncementJSON(c, config.StrictDecoding, &request); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if len(request.Operations) == 0 || len(request.Operations) > maxBatchSize {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("batch must contain between 1 and %d operations", maxBatchSize),
+				Data:    nil,
+			})
+			return
+		}
+
+		results := make([]model.BatchItemResult, len(request.Operations))
+		failed := checkBatch(request.Operations, results, config)
+
+		// Lock the projects in a fixed order to avoid deadlocks with concurrent batches
+		projects := batchProjects(request.Operations)
+		for _, project := range projects {
+			unlock := serializer.Lock(project)
+			defer unlock()
+		}
+
+		// The prefixes of the other projects are checked and claimed by the whole batch at once
+		unlockConflicts := lockConflicts(serializer, policy)
+		defer unlockConflicts()
+
+		// The announcements are admitted together: the deletions free their quota and prefixes for all applies, and
+		// every apply is checked against those before it
+		pending := newPendingWrites()
+		for i, operation := range request.Operations {
+			if results[i].Error == "" && operation.Action == model.BatchDelete {
+				pending.deleteAnnouncement(operation.Announcement.Meta.Project, operation.Announcement.Meta.Name)
+			}
+		}
+
+		writes := make([]model.BatchWrite, 0, len(request.Operations))
+		previous := make([]*model.Announcement, 0, len(request.Operations))
+		for i := range request.Operations {
+			if results[i].Error != "" {
+				continue
+			}
+
+			write, stored, eventType, err := prepareBatchWrite(c, db, policy, admission, pending, &request.Operations[i], clk.Now())
+			if err != nil {
+				results[i].Error = err.Error()
+				failed = true
+				continue
+			}
+			results[i].Type = eventType
+			writes = append(writes, write)
+			previous = append(previous, stored)
+		}
+
+		// Nothing is written unless every operation is valid
+		if failed {
+			for i := range results {
+				results[i].Type = ""
+			}
+			c.JSON(http.StatusUnprocessableEntity, model.APIResponse{
+				Status:  "error",
+				Message: "batch rejected, no changes were applied",
+				Data:    results,
+			})
+			return
+		}
+
+		if err := db.Batch(writes); err != nil {
+			c.JSON(writeErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to apply batch: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+		for i, write := range writes {
+			var written *model.Announcement
+			if !write.Delete {
+				written = &model.Announcement{}
+				if err := json.Unmarshal([]byte(write.Value), written); err != nil {
+					continue
+				}
+				refreshResourceVersion(db, write.Key, written)
+			}
+			changes.record(c, previous[i], written)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Batch applied successfully",
+			Data:    results,
+		})
+	})
+}
+
+// checkBatch validates every operation and fills in the identity and the validation error of each result.
+// It reports whether any operation is invalid.
+func checkBatch(operations []model.BatchOperation, results []model.BatchItemResult, config *model.APIConfig) bool {
+	failed := false
+	seen := make(map[string]struct{}, len(operations))
+
+	for i := range operations {
+		operation := &operations[i]
+		meta := operation.Announcement.Meta
+		results[i] = model.BatchItemResult{Project: meta.Project, Name: meta.Name}
+
+		var err error
+		switch operation.Action {
+		case model.BatchApply:
+			if err = checkNameLength(meta.Name, config.MaxAnnouncementNameLength); err == nil {
+				err = validateAnnouncement(&operation.Announcement)
+			}
+		case model.BatchDelete:
+			if meta.Project == "" || meta.Name == "" {
+				err = fmt.Errorf("project and name are required")
+			}
+		default:
+			err = fmt.Errorf("unknown action %q", operation.Action)
+		}
+
+		key := meta.Project + "/" + meta.Name
+		if _, ok := seen[key]; ok && err == nil {
+			err = fmt.Errorf("announcement appears more than once in the batch")
+		}
+		seen[key] = struct{}{}
+
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+		}
+	}
+
+	return failed
+}
+
+// batchProjects returns the sorted list of distinct projects affected by the operations.
+func batchProjects(operations []model.BatchOperation) []string {
+	set := make(map[string]struct{})
+	for _, operation := range operations {
+		set[operation.Announcement.Meta.Project] = struct{}{}
+	}
+
+	projects := make([]string, 0, len(set))
+	for project := range set {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// prepareBatchWrite reads the stored announcement and builds the write for the operation together with the
+// stored announcement, nil if it does not exist, and the resulting event type. Deleting a missing announcement is
+// an error. The applied announcement is reviewed by the admission webhooks, a created one is allocated the address of
+// its pool, and its schedule is resolved and its generation stamped at the time of the batch. It is admitted
+// together with the pending writes of the batch, which it is added to.
+func prepareBatchWrite(c *gin.Context, db model.DatabaseAdapter, policy *PrefixPolicy, admission *AdmissionWebhooks, pending *pendingWrites, operation *model.BatchOperation, now time.Time) (model.BatchWrite, *model.Announcement, model.EventType, error) {
+	announcement := operation.Announcement
+	key := announcementsPrefix + announcement.Meta.Project + "/" + announcement.Meta.Name
+
+	value, err := db.Get(key)
+	exists := err == nil
+	if err != nil && err.Error() != "key not found" {
+		return model.BatchWrite{}, nil, "", fmt.Errorf("failed to check announcement existence: %w", err)
+	}
+
+	var stored *model.Announcement
+	if exists {
+		// A malformed announcement is audited by its identity only
+		stored = &model.Announcement{}
+		if err := json.Unmarshal([]byte(value), stored); err != nil {
+			stored = &model.Announcement{Meta: model.Meta{Project: announcement.Meta.Project, Name: announcement.Meta.Name}}
+		}
+	}
+
+	if operation.Action == model.BatchDelete {
+		if !exists {
+			return model.BatchWrite{}, nil, "", fmt.Errorf("announcement not found")
+		}
+		return model.BatchWrite{Key: key, Delete: true}, stored, model.EventDeleted, nil
+	}
+
+	if err := admission.admit(c, false, stored, &announcement); err != nil {
+		return model.BatchWrite{}, nil, "", err
+	}
+	if !exists {
+		if err := allocatePoolAddress(db, &announcement, pending); err != nil {
+			return model.BatchWrite{}, nil, "", err
+		}
+	}
+	if err := checkProjectAdmission(db, policy, &announcement, pending); err != nil {
+		return model.BatchWrite{}, nil, "", err
+	}
+	pending.putAnnouncement(&announcement)
+
+	// The status is reported by the controllers through the status subresource, so a create starts without one and
+	// an update keeps the stored one. An update re-activates a cancelled announcement
+	eventType := model.EventAdded
+	announcement.Status = model.Status{}
+	if exists {
+		eventType = model.EventUpdated
+		announcement.Status = stored.Status
+		if announcement.Status.Status == model.StatusCancelled {
+			announcement.Status.Status = model.StatusPending
+		}
+	}
+	resolveSchedule(&announcement, now)
+	stampGeneration(c, stored, &announcement, now)
+
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		return model.BatchWrite{}, nil, "", err
+	}
+	return model.BatchWrite{Key: key, Value: string(data)}, stored, eventType, nil
+}
@@ -0,0 +1,128 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+// TestBatchApplied checks that a valid batch creates, updates and deletes the announcements and reports the change
+// made to each.
+func TestBatchApplied(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.put(t, testAnnouncement("alpha", "update", "192.0.2.2"))
+	server.put(t, testAnnouncement("beta", "delete", "192.0.2.3"))
+
+	updated := testAnnouncement("alpha", "update", "192.0.2.2")
+	updated.NextHops = append(updated.NextHops, model.Subnet{IP: "10.0.0.2", Mask: 32})
+	results, err := server.client().V1Batch(context.Background(), []model.BatchOperation{
+		{Action: model.BatchApply, Announcement: *testAnnouncement("alpha", "create", "192.0.2.1")},
+		{Action: model.BatchApply, Announcement: *updated},
+		{Action: model.BatchDelete, Announcement: model.Announcement{Meta: model.Meta{Project: "beta", Name: "delete"}}},
+	})
+	if err != nil {
+		t.Fatalf("V1Batch: %v", err)
+	}
+
+	want := []model.EventType{model.EventAdded, model.EventUpdated, model.EventDeleted}
+	for i, result := range results {
+		if result.Type != want[i] || result.Error != "" {
+			t.Errorf("result %d = %+v, want %s", i, result, want[i])
+		}
+	}
+	server.stored(t, "alpha", "create")
+	if hops := server.stored(t, "alpha", "update").NextHops; len(hops) != 2 {
+		t.Errorf("updated announcement has %d next hops, want 2", len(hops))
+	}
+	if _, err := server.Store.Get(announcementsPrefix + "beta/delete"); err == nil {
+		t.Error("deleted announcement is still stored")
+	}
+}
+
+// TestBatchAllOrNothing checks that a batch with an invalid operation is rejected as a whole, and that the results
+// point at the invalid operations only.
+func TestBatchAllOrNothing(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation model.BatchOperation
+		wantError string
+	}{
+		{
+			name:      "invalid announcement",
+			operation: model.BatchOperation{Action: model.BatchApply, Announcement: *testAnnouncement("alpha", "invalid", "not-an-ip")},
+			wantError: "not a valid IP address",
+		},
+		{
+			name:      "overlap with another operation",
+			operation: model.BatchOperation{Action: model.BatchApply, Announcement: *testAnnouncement("alpha", "overlap", "192.0.2.1")},
+			wantError: "overlaps",
+		},
+		{
+			name:      "delete of a missing announcement",
+			operation: model.BatchOperation{Action: model.BatchDelete, Announcement: model.Announcement{Meta: model.Meta{Project: "alpha", Name: "missing"}}},
+			wantError: "not found",
+		},
+		{
+			name:      "operation repeated",
+			operation: model.BatchOperation{Action: model.BatchApply, Announcement: *testAnnouncement("alpha", "create", "192.0.2.1")},
+			wantError: "more than once",
+		},
+		{
+			name:      "unknown action",
+			operation: model.BatchOperation{Action: "replace", Announcement: *testAnnouncement("alpha", "replace", "192.0.2.9")},
+			wantError: "unknown action",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			server.put(t, testAnnouncement("beta", "delete", "192.0.2.3"))
+
+			results, err := server.client().V1Batch(context.Background(), []model.BatchOperation{
+				{Action: model.BatchApply, Announcement: *testAnnouncement("alpha", "create", "192.0.2.1")},
+				{Action: model.BatchDelete, Announcement: model.Announcement{Meta: model.Meta{Project: "beta", Name: "delete"}}},
+				tt.operation,
+			})
+			var apiErr *v1.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity {
+				t.Fatalf("V1Batch error = %v, want a rejected batch", err)
+			}
+			if len(results) != 3 {
+				t.Fatalf("got %d results, want 3", len(results))
+			}
+			for i, result := range results {
+				if result.Type != "" {
+					t.Errorf("result %d reports the change %s of a rejected batch", i, result.Type)
+				}
+			}
+			if !strings.Contains(results[2].Error, tt.wantError) {
+				t.Errorf("error of the invalid operation = %q, want %q", results[2].Error, tt.wantError)
+			}
+
+			// Nothing is written, the valid operations included
+			if _, err := server.Store.Get(announcementsPrefix + "alpha/create"); err == nil {
+				t.Error("the create of a rejected batch was stored")
+			}
+			server.stored(t, "beta", "delete")
+		})
+	}
+}
+
+// TestBatchSize checks that empty and oversized batches are rejected.
+func TestBatchSize(t *testing.T) {
+	server := newTestServer(t, nil)
+	for _, size := range []int{0, maxBatchSize + 1} {
+		operations := make([]model.BatchOperation, size)
+		for i := range operations {
+			operations[i] = model.BatchOperation{Action: model.BatchDelete, Announcement: model.Announcement{Meta: model.Meta{Project: "alpha", Name: "missing"}}}
+		}
+		if code, _ := server.do(t, http.MethodPost, "/v1/announcements/batch", model.BatchRequest{Operations: operations}); code != http.StatusBadRequest {
+			t.Errorf("batch of %d operations answered %d, want %d", size, code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltWatchHistory is the number of changes kept in memory to resume the watches of the BoltStore. Watches resuming
+// from an older revision get a resync event.
+const boltWatchHistory = 10000
+
+var (
+	boltKeysBucket  = []byte("keys") // boltKeysBucket holds the keys, each value prefixed with its mod revision.
+	boltMetaBucket  = []byte("meta") // boltMetaBucket holds the revision of the store.
+	boltRevisionKey = []byte("revision")
+)
+
+// BoltStore is the embedded database adapter keeping all keys in a single bbolt file. It serves single-node
+// deployments that do not need an etcd cluster. Like etcd, it keeps a revision increased by every write, so resource
+// versions and resumable watches work the same with both backends.
+type BoltStore struct {
+	db *bolt.DB
+
+	mu        sync.Mutex         // mu serializes the writes and guards the fields below.
+	revision  int64              // revision is the revision of the last write.
+	history   []model.WatchEvent // history holds the latest changes in revision order.
+	compacted int64              // compacted is the latest revision whose changes are no longer in the history.
+	notify    chan struct{}      // notify is closed and replaced after every write to wake up the watches.
+}
+
+// NewBoltStore opens or creates the bbolt database file at the path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database: %w", err)
+	}
+
+	var revision int64
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltKeysBucket); err != nil {
+			return err
+		}
+		meta, err := tx.CreateBucketIfNotExists(boltMetaBucket)
+		if err != nil {
+			return err
+		}
+		if value := meta.Get(boltRevisionKey); len(value) == 8 {
+			revision = int64(binary.BigEndian.Uint64(value))
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize bolt database: %w", err)
+	}
+
+	return &BoltStore{
+		db:        db,
+		revision:  revision,
+		compacted: revision,
+		notify:    make(chan struct{}),
+	}, nil
+}
+
+// encodeBoltValue prefixes the value with its mod revision.
+func encodeBoltValue(value string, modRevision int64) []byte {
+	data := make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(data, uint64(modRevision))
+	copy(data[8:], value)
+	return data
+}
+
+// decodeBoltValue returns the value and the mod revision of a stored key.
+func decodeBoltValue(data []byte) (string, int64) {
+	if len(data) < 8 {
+		return "", 0
+	}
+	return string(data[8:]), int64(binary.BigEndian.Uint64(data))
+}
+
+// Close closes the database file.
+func (b *BoltStore) Close() {
+	_ = b.db.Close()
+}
+
+// HealthCheck verifies that the database file is open and readable.
+func (b *BoltStore) HealthCheck() error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltKeysBucket) == nil {
+			return fmt.Errorf("bolt health check failed: bucket %s is missing", boltKeysBucket)
+		}
+		return nil
+	})
+}
+
+func (b *BoltStore) Get(key string) (string, error) {
+	var value string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltKeysBucket).Get([]byte(key))
+		if data == nil {
+			return fmt.Errorf("key not found")
+		}
+		var modRevision int64
+		value, modRevision = decodeBoltValue(data)
+		value = withResourceVersion(key, value, modRevision)
+		return nil
+	})
+	return value, err
+}
+
+// scanPrefix calls fn for every key under the prefix, starting from startKey if it is greater, until fn returns false.
+func scanPrefix(tx *bolt.Tx, prefix, startKey string, fn func(key string, data []byte) bool) {
+	seek := prefix
+	if startKey > prefix {
+		seek = startKey
+	}
+	cursor := tx.Bucket(boltKeysBucket).Cursor()
+	for k, v := cursor.Seek([]byte(seek)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = cursor.Next() {
+		if !fn(string(k), v) {
+			return
+		}
+	}
+}
+
+func (b *BoltStore) List(prefix string) ([]string, error) {
+	keys := []string{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		scanPrefix(tx, prefix, "", func(key string, _ []byte) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return nil
+	})
+	return keys, err
+}
+
+func (b *BoltStore) GetObjects(prefix string) ([]string, error) {
+	values := []string{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		scanPrefix(tx, prefix, "", func(key string, data []byte) bool {
+			value, modRevision := decodeBoltValue(data)
+			values = append(values, withResourceVersion(key, value, modRevision))
+			return true
+		})
+		return nil
+	})
+	return values, err
+}
+
+// GetObjectsPage returns up to limit values stored under the prefix, starting from startKey (inclusive).
+// The second return value is the key to start the next page from, or an empty string if there are no more keys.
+func (b *BoltStore) GetObjectsPage(prefix, startKey string, limit int64) ([]string, string, error) {
+	values := []string{}
+	var next string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var lastKey string
+		scanPrefix(tx, prefix, startKey, func(key string, data []byte) bool {
+			if limit > 0 && int64(len(values)) == limit {
+				// The smallest key greater than the last returned one
+				next = lastKey + "\x00"
+				return false
+			}
+			value, modRevision := decodeBoltValue(data)
+			values = append(values, withResourceVersion(key, value, modRevision))
+			lastKey = key
+			return true
+		})
+		return nil
+	})
+	return values, next, err
+}
+
+// ListByNextHop returns the serialized announcements that use the specified next-hop address.
+// The lookup is served from the secondary next-hop index maintained on every announcement write.
+func (b *BoltStore) ListByNextHop(nextHop string) ([]string, error) {
+	values := []string{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(boltKeysBucket)
+		scanPrefix(tx, nextHopIndexPrefix+nextHop+"/", "", func(_ string, data []byte) bool {
+			announcementKey, _ := decodeBoltValue(data)
+			if announcement := keys.Get([]byte(announcementKey)); announcement != nil {
+				value, modRevision := decodeBoltValue(announcement)
+				values = append(values, withResourceVersion(announcementKey, value, modRevision))
+			}
+			return true
+		})
+		return nil
+	})
+	return values, err
+}
+
+func (b *BoltStore) Put(key, value string) error {
+	if err := b.write([]model.BatchWrite{{Key: key, Value: value}}); err != nil {
+		return fmt.Errorf("failed to put data to bolt: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltStore) Patch(key, value string) error {
+	if err := b.write([]model.BatchWrite{{Key: key, Value: value}}); err != nil {
+		return fmt.Errorf("failed to patch data to bolt: %w", err)
+	}
+	return nil
+}
+
+func (b *BoltStore) Delete(key string) error {
+	if err := b.write([]model.BatchWrite{{Key: key, Delete: true}}); err != nil {
+		return fmt.Errorf("failed to delete data from bolt: %w", err)
+	}
+	return nil
+}
+
+// Batch applies all writes in a single transaction, so that either all of them or none are stored.
+// The next-hop index is updated in the same transaction.
+func (b *BoltStore) Batch(writes []model.BatchWrite) error {
+	if err := b.write(writes); err != nil {
+		return fmt.Errorf("failed to apply batch to bolt: %w", err)
+	}
+	return nil
+}
+
+// write applies the writes and the resulting next-hop index changes in a single transaction at the next revision
+// and passes the changes to the watches. Announcements carrying a resource version are written only if they were
+// not modified since that version, otherwise model.ErrResourceVersionConflict is returned.
+func (b *BoltStore) write(writes []model.BatchWrite) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	revision := b.revision + 1
+	var events []model.WatchEvent
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(boltKeysBucket)
+
+		// put stores the value of the key, or deletes the key if deleteKey is set, and records the change
+		put := func(key, value string, deleteKey bool) error {
+			prevData := keys.Get([]byte(key))
+			prevValue, _ := decodeBoltValue(prevData)
+			event := model.WatchEvent{Type: model.WatchEventPut, Key: key, Value: value, PrevValue: prevValue, ModRevision: revision, Created: prevData == nil}
+			if deleteKey {
+				if prevData == nil {
+					return nil
+				}
+				event.Type, event.Value, event.Created = model.WatchEventDelete, "", false
+				if err := keys.Delete([]byte(key)); err != nil {
+					return err
+				}
+			} else if err := keys.Put([]byte(key), encodeBoltValue(value, revision)); err != nil {
+				return err
+			}
+			events = append(events, event)
+			return nil
+		}
+
+		for _, write := range writes {
+			var expectedRevision int64
+			if isAnnouncementKey(write.Key) && !write.Delete {
+				var err error
+				if expectedRevision, write.Value, err = splitResourceVersion(write.Value); err != nil {
+					return err
+				}
+			}
+
+			prevValue, prevRevision := decodeBoltValue(keys.Get([]byte(write.Key)))
+			if expectedRevision != 0 && expectedRevision != prevRevision {
+				return fmt.Errorf("%w: %s was modified at revision %d", model.ErrResourceVersionConflict, write.Key, prevRevision)
+			}
+			if err := put(write.Key, write.Value, write.Delete); err != nil {
+				return err
+			}
+
+			if !isAnnouncementKey(write.Key) {
+				continue
+			}
+
+			var newIndexKeys []string
+			if !write.Delete {
+				newIndexKeys = nextHopIndexKeys(write.Key, write.Value)
+			}
+			stale, fresh := diffIndexKeys(nextHopIndexKeys(write.Key, prevValue), newIndexKeys)
+			for _, indexKey := range stale {
+				if err := put(indexKey, "", true); err != nil {
+					return err
+				}
+			}
+			for _, indexKey := range fresh {
+				if err := put(indexKey, write.Key, false); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+		data := make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(revision))
+		return tx.Bucket(boltMetaBucket).Put(boltRevisionKey, data)
+	})
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	b.revision = revision
+	b.history = append(b.history, events...)
+	if excess := len(b.history) - boltWatchHistory; excess > 0 {
+		// Drop whole revisions only, so that a watch never gets part of the changes of a revision
+		for excess < len(b.history) && b.history[excess].ModRevision == b.history[excess-1].ModRevision {
+			excess++
+		}
+		b.compacted = b.history[excess-1].ModRevision
+		b.history = append([]model.WatchEvent(nil), b.history[excess:]...)
+	}
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return nil
+}
+
+// Watch streams the changes of the keys under the prefix through a channel until the stop signal is received.
+// When revision is greater than zero the watch resumes right after that revision, otherwise it starts from the
+// current state. If the changes after that revision are no longer kept, a resync response (with CompactRevision
+// set) is sent first and the watch continues from the current revision. A response is sent at the start and after
+// every write, also without changes of the watched keys, to report the progress of the watch.
+func (b *BoltStore) Watch(prefix string, revision int64, stopChan <-chan struct{}) (<-chan model.WatchResponse, error) {
+	events := make(chan model.WatchResponse)
+
+	go func() {
+		defer close(events)
+
+		b.mu.Lock()
+		last := revision
+		if last <= 0 {
+			last = b.revision
+		}
+		b.mu.Unlock()
+
+		for {
+			b.mu.Lock()
+			resp := model.WatchResponse{Revision: b.revision}
+			if last < b.compacted {
+				resp.CompactRevision = b.compacted
+			} else {
+				first := sort.Search(len(b.history), func(i int) bool { return b.history[i].ModRevision > last })
+				for _, event := range b.history[first:] {
+					if strings.HasPrefix(event.Key, prefix) {
+						resp.Events = append(resp.Events, event)
+					}
+				}
+			}
+			last = b.revision
+			notify := b.notify
+			b.mu.Unlock()
+
+			// A response without changes of the watched keys reports the progress of the watch
+			select {
+			case events <- resp:
+			case <-stopChan:
+				return
+			}
+
+			select {
+			case <-notify:
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
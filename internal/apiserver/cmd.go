@@ -1,29 +1,90 @@
 package apiserver
 
 import (
+	"context"
 	"fmt"
+	"github.com/nikitamishagin/corebgp/internal/configfile"
+	"github.com/nikitamishagin/corebgp/internal/logging"
 	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log/slog"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // RootCmd initializes and returns the root command for the CoreBGP API server application.
 func RootCmd() *cobra.Command {
 	var (
 		endpointsList string
+		configPath    string
 		config        model.APIConfig
+		logLevel      slog.LevelVar
 	)
 	var cmd = &cobra.Command{
 		Use:   "apiserver",
 		Short: "CoreBGP API server",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Parse endpoints from the provided CLI argument
-			endpoints, err := parseEndpoints(endpointsList)
+			if err := configfile.Load(cmd.Flags(), "config", &config); err != nil {
+				return err
+			}
+
+			logger, logFile, err := logging.New(logging.Config{
+				Path:       config.LogPath,
+				Format:     config.LogFormat,
+				Verbose:    config.Verbose,
+				MaxSize:    config.LogMaxSize,
+				MaxBackups: config.LogMaxBackups,
+				LevelVar:   &logLevel,
+			})
+			if err != nil {
+				return err
+			}
+			defer logFile.Close()
+			slog.SetDefault(logger)
+
+			shutdownTracing, err := tracing.Setup(cmd.Context(), config.OTLPEndpoint, "corebgp-apiserver")
 			if err != nil {
 				return err
 			}
-			config.Endpoints = endpoints
+			defer func() {
+				if err := shutdownTracing(context.Background()); err != nil {
+					slog.Error("failed to flush traces", "error", err)
+				}
+			}()
+
+			// Re-read the log verbosity from the configuration file on SIGHUP
+			reloadSignals := make(chan os.Signal, 1)
+			signal.Notify(reloadSignals, syscall.SIGHUP)
+			defer signal.Stop(reloadSignals)
+			go func() {
+				for range reloadSignals {
+					if err := reloadLogLevel(cmd.Flags(), configPath, config, &logLevel); err != nil {
+						slog.Error("failed to reload configuration", "error", err)
+						continue
+					}
+					slog.Info("configuration reloaded")
+				}
+			}()
+
+			// Parse endpoints from the provided CLI argument, only etcd is reached over the network. The endpoints
+			// of the configuration file apply unless the flag or its environment variable is set.
+			if len(config.Endpoints) > 0 && !configfile.Overridden(cmd.Flags(), "endpoints") {
+				endpointsList = strings.Join(config.Endpoints, ",")
+			}
+			if config.DBType == "etcd" {
+				endpoints, err := parseEndpoints(endpointsList)
+				if err != nil {
+					return err
+				}
+				config.Endpoints = endpoints
+			}
 
 			// Initialize the database adapter
 			databaseAdapter, err := initializeDatabaseAdapter(&config)
@@ -33,38 +94,120 @@ func RootCmd() *cobra.Command {
 			defer databaseAdapter.Close()
 
 			// Start the API server
-			if err := NewAPIServer(databaseAdapter); err != nil {
+			if err := NewAPIServer(databaseAdapter, &config); err != nil {
 				return err
 			}
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&config.DBType, "db-type", "etcd", "Database type")
+	cmd.Flags().StringVar(&configPath, "config", "", "Path to the YAML or TOML (.toml) configuration file; flags and COREBGP_* environment variables take precedence, "+
+		"the log verbosity is re-read on SIGHUP")
+	cmd.Flags().StringVar(&config.DBType, "db-type", "etcd", "Database type: etcd, bolt for an embedded database file on a single node, or memory for tests and demonstrations (the data is lost on exit)")
+	cmd.Flags().StringVar(&config.DBPath, "db-path", "corebgp.db", "Path to the database file of the bolt database type")
 	cmd.Flags().StringVar(&endpointsList, "endpoints", "http://localhost:2379", "Comma separated list of database endpoints")
 	//cmd.Flags().StringSlice(&config.Endpoints, []string{"http://localhost:2379"}, "Comma separated list of database endpoints")
-	cmd.Flags().StringVar(&config.Etcd.CACert, "etcd-ca", "", "Path to etcd CA certificate")
-	cmd.Flags().StringVar(&config.Etcd.ClientCert, "etcd-cert", "", "Path to etcd client certificate")
+	cmd.Flags().StringVar(&config.Etcd.CACert, "etcd-ca", "", "Path to etcd CA certificate (TLS is disabled if empty)")
+	cmd.Flags().StringVar(&config.Etcd.ClientCert, "etcd-cert", "", "Path to etcd client certificate (optional with TLS)")
 	cmd.Flags().StringVar(&config.Etcd.ClientKey, "etcd-key", "", "Path to etcd client key")
+	cmd.Flags().StringVar(&config.Etcd.Username, "etcd-username", "", "Name of the etcd user (authentication is disabled if empty)")
+	cmd.Flags().StringVar(&config.Etcd.PasswordFile, "etcd-password-file", "", "Path to the file with the password of the etcd user")
+	cmd.Flags().StringVar(&config.Etcd.KeyPrefix, "etcd-key-prefix", "", "Prefix of all etcd keys, e.g. corebgp-eu/, so that several CoreBGP instances can share an etcd cluster")
+	cmd.Flags().DurationVar(&config.Etcd.RequestTimeout, "etcd-request-timeout", 10*time.Second, "Deadline of every etcd request")
+	cmd.Flags().IntVar(&config.Etcd.MaxRetries, "etcd-max-retries", 3, "Number of retries of an etcd request failing with a transient error, e.g. a leader change (0 disables retries)")
 	cmd.Flags().StringVar(&config.TLSCert, "tls-cert", "", "Path to TLS certificate")
 	cmd.Flags().StringVar(&config.TLSKey, "tls-key", "", "Path to TLS key")
-	cmd.Flags().StringVarP(&config.LogPath, "log-path", "l", "/var/log/corebgp/apiserver.log", "Path to log file")
-	cmd.Flags().Int8VarP(&config.Verbose, "verbose", "v", 0, "Verbosity level")
+	cmd.Flags().StringVar(&config.PrefixPolicyFile, "prefix-policy-file", "", "Path to the JSON file with the global and per-project allowed prefixes, allowed next hops and forbidden prefix lengths (any prefix is admitted if empty)")
+	cmd.Flags().StringVar(&config.CrossProjectConflicts, "cross-project-conflicts", model.ConflictsWarn, "Admission of announcements whose prefixes equal or overlap those of another project: off, warn (logs a warning) or reject")
+	cmd.Flags().StringVar(&config.AdmissionWebhooksFile, "admission-webhooks-file", "", "Path to the JSON file with the HTTPS webhooks mutating or rejecting the created and updated announcements (disabled if empty)")
+	cmd.Flags().StringVar(&config.WebhooksFile, "webhooks-file", "", "Path to the JSON file with the webhooks notified of created, updated, deleted and health-state-changed announcements (disabled if empty)")
+	cmd.Flags().StringVar(&config.AuthPolicyFile, "auth-policy-file", "", "Path to the JSON file with static API tokens and per-project role bindings (authentication is disabled if empty and no OIDC issuer is set)")
+	cmd.Flags().StringVar(&config.OIDCIssuerURL, "oidc-issuer-url", "", "Issuer URL of accepted OIDC bearer tokens (disabled if empty)")
+	cmd.Flags().StringVar(&config.OIDCClientID, "oidc-client-id", "", "Expected audience of OIDC bearer tokens")
+	cmd.Flags().StringVar(&config.OIDCSubjectClaim, "oidc-subject-claim", "sub", "OIDC token claim used as the subject in role bindings")
+	cmd.Flags().StringVar(&config.TLSClientCA, "tls-client-ca", "", "Path to CA certificate used to verify client certificates (enables mutual TLS)")
+	cmd.Flags().StringVarP(&config.LogPath, "log-path", "l", "stderr", "Path to log file (rotated by size), stdout or stderr")
+	cmd.Flags().Int8VarP(&config.Verbose, "verbose", "v", 0, "Verbosity level (1 enables debug logs, -1 logs only warnings and errors)")
+	cmd.Flags().StringVar(&config.LogFormat, "log-format", logging.FormatJSON, "Log output format: json or console")
+	cmd.Flags().IntVar(&config.LogMaxSize, "log-max-size", 100, "Size in megabytes at which the log file is rotated")
+	cmd.Flags().IntVar(&config.LogMaxBackups, "log-max-backups", 10, "Number of rotated log files to keep")
+	cmd.Flags().StringVar(&config.GoBGPEndpoint, "gobgp-endpoint", "", "GoBGP gRPC endpoint used for the BGP session endpoints (disabled if empty)")
+	cmd.Flags().StringVar(&config.GoBGPCACert, "gobgp-ca-cert", "", "Path to GoBGP CA certificate")
+	cmd.Flags().StringVar(&config.GoBGPClientCert, "gobgp-client-cert", "", "Path to GoBGP client certificate")
+	cmd.Flags().StringVar(&config.GoBGPClientKey, "gobgp-client-key", "", "Path to GoBGP client key")
+	cmd.Flags().IntVar(&config.HistoryRevisions, "history-revisions", 10, "Number of revisions kept in the history of every announcement for rollbacks, also after its deletion (0 disables the history)")
+	cmd.Flags().IntVar(&config.AnnouncementEvents, "announcement-events", 50, "Number of events, e.g. failed health checks or programming errors, kept for every announcement, also after its deletion (0 disables the events)")
+	cmd.Flags().DurationVar(&config.WithdrawnRetention, "withdrawn-retention", 7*24*time.Hour, "How long soft-deleted announcements are kept before garbage collection (0 keeps them forever)")
+	cmd.Flags().StringVar(&config.AccessLogPath, "access-log-path", "", "Destination of the JSON access log: a file path (rotated automatically), stdout or stderr (disabled if empty)")
+	cmd.Flags().StringVar(&config.OTLPEndpoint, "otlp-endpoint", "", "URL of the OTLP/HTTP collector receiving the traces, e.g. http://localhost:4318 (disabled if empty)")
+	cmd.Flags().DurationVar(&config.QueryTimeout, "query-timeout", 2*time.Second, "Maximum evaluation time of an announcement query expression")
+	cmd.Flags().IntVar(&config.MaxAnnouncementNameLength, "max-announcement-name-length", 253, "Maximum length of an announcement name (0 disables the limit)")
+	cmd.Flags().StringSliceVar(&config.TrustedProxies, "trusted-proxies", nil, "Comma separated list of the addresses and CIDR ranges of reverse proxies whose X-Forwarded-For headers identify the clients (none if empty, the peer address is used)")
+	cmd.Flags().Float64Var(&config.RateLimit, "rate-limit", 0, "Requests per second allowed per client, identified by its authenticated subject or IP address (0 disables the limit)")
+	cmd.Flags().IntVar(&config.RateLimitBurst, "rate-limit-burst", 50, "Number of requests a client may send at once above the rate limit")
+	cmd.Flags().Float64Var(&config.IPRateLimit, "ip-rate-limit", 0, "Requests per second allowed per IP address before authentication (0 disables the limit)")
+	cmd.Flags().IntVar(&config.IPRateLimitBurst, "ip-rate-limit-burst", 100, "Number of requests an IP address may send at once above the IP rate limit")
+	cmd.Flags().IntVar(&config.MaxInflightRequests, "max-inflight-requests", 400, "Maximum number of requests served concurrently, watches excluded (0 disables the limit)")
+	cmd.Flags().Int64Var(&config.MaxRequestBodySize, "max-request-body-size", 10<<20, "Maximum size of a request body in bytes (0 disables the limit)")
+	cmd.Flags().IntVar(&config.MaxListSize, "max-list-size", 0, "Maximum number of announcements in an unpaginated list response, larger lists must be requested in pages (0 disables the limit)")
+	cmd.Flags().DurationVar(&config.WatchHeartbeatInterval, "watch-heartbeat-interval", 15*time.Second, "Interval of the bookmark events carrying the current revision, and of the pings, sent on watches to keep them alive and detect dead clients (0 disables them)")
+	cmd.Flags().BoolVar(&config.StrictDecoding, "strict-decoding", false, "Reject announcement request bodies with unknown fields, e.g. misspelled ones, instead of ignoring them")
+	cmd.Flags().StringVar(&config.GRPCAddr, "grpc-addr", "", "Address of a separate listener serving the gRPC AnnouncementService, e.g. :9090 (disabled if empty)")
+	cmd.Flags().BoolVar(&config.SwaggerUI, "swagger-ui", false, "Serve the Swagger UI page rendering the OpenAPI document of the v1 API at /openapi/ui")
+	cmd.Flags().StringVar(&config.PprofAddr, "pprof-addr", "", "Address of a separate listener serving pprof handlers, e.g. 127.0.0.1:6060 (disabled if empty). "+
+		"WARNING: it exposes sensitive profiling data and must never be reachable publicly")
 
 	return cmd
 }
 
+// reloadLogLevel re-reads the configuration file and applies its log verbosity, unless a flag or an environment
+// variable overrides it.
+func reloadLogLevel(flags *pflag.FlagSet, path string, config model.APIConfig, logLevel *slog.LevelVar) error {
+	if path == "" || configfile.Overridden(flags, "verbose") {
+		return nil
+	}
+	if err := configfile.ReadFile(path, &config); err != nil {
+		return err
+	}
+	logLevel.Set(logging.Level(config.Verbose))
+	return nil
+}
+
 // initializeDatabaseAdapter initializes the appropriate database adapter based on the config.DBType value
 func initializeDatabaseAdapter(config *model.APIConfig) (model.DatabaseAdapter, error) {
 	switch config.DBType {
 	case "etcd":
 		// Initialize Etcd adapter
-		etcdClient, err := NewEtcdClient(config.Endpoints, config.Etcd.CACert, config.Etcd.ClientCert, config.Etcd.ClientKey)
+		opts := []EtcdOption{
+			WithEtcdRequestTimeout(config.Etcd.RequestTimeout),
+			WithEtcdKeyPrefix(config.Etcd.KeyPrefix),
+			WithEtcdRetries(config.Etcd.MaxRetries),
+		}
+		if config.Etcd.Username != "" {
+			password, err := os.ReadFile(config.Etcd.PasswordFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not read etcd password: %w", err)
+			}
+			opts = append(opts, WithEtcdAuth(config.Etcd.Username, strings.TrimSpace(string(password))))
+		}
+		etcdClient, err := NewEtcdClient(config.Endpoints, config.Etcd.CACert, config.Etcd.ClientCert, config.Etcd.ClientKey, clock.RealClock{}, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize etcd adapter: %w", err)
 		}
 		return etcdClient, nil
 
+	case "bolt":
+		// Initialize the embedded bolt adapter
+		boltStore, err := NewBoltStore(config.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize bolt adapter: %w", err)
+		}
+		return boltStore, nil
+
+	case "memory":
+		// Initialize the in-memory adapter, whose data is lost on exit
+		return NewMemoryStore(), nil
+
 	default:
 		// Return an error if DBType is unknown
 		return nil, fmt.Errorf("unsupported db type: %s", config.DBType)
@@ -0,0 +1,99 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// registerCollectionRoutes adds the route that deletes the announcements of a project selected by their labels.
+func registerCollectionRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog) {
+	v1.DELETE("/announcements/:project/", func(c *gin.Context) {
+		project := c.Param("project")
+
+		// A selector is required, so that a request without one does not wipe out the whole project
+		if c.Query("labelSelector") == "" {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "labelSelector query parameter is required",
+				Data:    nil,
+			})
+			return
+		}
+		selector, err := querySelector(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		data, err := db.GetObjects(announcementsPrefix + project + "/")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var selected []model.Announcement
+		for _, value := range data {
+			var announcement model.Announcement
+			if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+			if selector.matches(announcement.Meta.Labels) {
+				selected = append(selected, announcement)
+			}
+		}
+
+		events := make([]model.Event, 0, len(selected))
+		for _, announcement := range selected {
+			events = append(events, model.Event{Type: model.EventDeleted, Announcement: announcement})
+		}
+
+		if isDryRun(c) {
+			c.JSON(http.StatusOK, model.APIResponse{
+				Status:  "success",
+				Message: fmt.Sprintf("%d announcements would be deleted (dry run)", len(events)),
+				Data:    events,
+			})
+			return
+		}
+
+		// The announcements are deleted one by one, a failure leaves the ones deleted before it deleted
+		for i := range selected {
+			announcement := &selected[i]
+			if err := db.Delete(announcementsPrefix + project + "/" + announcement.Meta.Name); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: fmt.Errorf("failed to delete announcement %s after deleting %d: %w", announcement.Meta.Name, i, err).Error(),
+					Data:    events[:i],
+				})
+				return
+			}
+			changes.record(c, announcement, nil)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: fmt.Sprintf("%d announcements deleted successfully", len(events)),
+			Data:    events,
+		})
+	})
+}
@@ -0,0 +1,171 @@
+package apiserver
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// registerConflictRoutes adds the route that reports the announcements of different projects whose prefixes overlap,
+// including those admitted before the conflict admission was enabled or in warn mode.
+func registerConflictRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter) {
+	v1.GET("/conflicts", func(c *gin.Context) {
+		announcements, err := conflictCandidates(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		// The project filter keeps the conflicts of the project, which comes first in every pair
+		project := c.Query("project")
+		conflicts := make([]model.Conflict, 0)
+		for i := range announcements {
+			for j := i + 1; j < len(announcements); j++ {
+				first, second := &announcements[i], &announcements[j]
+				if project != "" && second.Meta.Project == project {
+					first, second = second, first
+				}
+				if project != "" && first.Meta.Project != project {
+					continue
+				}
+				conflicts = append(conflicts, prefixConflicts(first, second)...)
+			}
+		}
+		sortConflicts(conflicts)
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Conflicts retrieved successfully",
+			Data:    conflicts,
+		})
+	})
+}
+
+// conflictCandidates returns the announcements whose prefixes are programmed now or may be programmed later. Withdrawn,
+// cancelled and expired announcements are skipped.
+func conflictCandidates(db model.DatabaseAdapter) ([]model.Announcement, error) {
+	values, err := db.GetObjects(announcementsPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list announcements: %w", err)
+	}
+
+	announcements := make([]model.Announcement, 0, len(values))
+	for _, value := range values {
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			continue
+		}
+		switch announcement.Status.Status {
+		case model.StatusWithdrawn, model.StatusCancelled, model.StatusExpired:
+			continue
+		}
+		announcements = append(announcements, announcement)
+	}
+	return announcements, nil
+}
+
+// lockConflicts serializes the writes rejected on conflicts with the other projects, from their admission to their
+// write, so that two projects never claim the same prefix concurrently. It returns the function releasing the lock;
+// the other conflict modes only warn and take no lock.
+func lockConflicts(serializer *PerProjectSerializer, policy *PrefixPolicy) func() {
+	if policy.conflictMode() != model.ConflictsReject {
+		return func() {}
+	}
+	return serializer.LockGlobal()
+}
+
+// crossProjectConflicts returns the conflicts of the announcement with the announcements of the other projects. The
+// announcement comes first in every conflict.
+func crossProjectConflicts(db model.DatabaseAdapter, announcement *model.Announcement) ([]model.Conflict, error) {
+	others, err := conflictCandidates(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []model.Conflict
+	for i := range others {
+		if others[i].Meta.Project != announcement.Meta.Project {
+			conflicts = append(conflicts, prefixConflicts(announcement, &others[i])...)
+		}
+	}
+	sortConflicts(conflicts)
+	return conflicts, nil
+}
+
+// prefixConflicts returns a conflict for every pair of overlapping prefixes of two announcements of different projects.
+func prefixConflicts(first, second *model.Announcement) []model.Conflict {
+	if first.Meta.Project == second.Meta.Project {
+		return nil
+	}
+
+	var conflicts []model.Conflict
+	secondPrefixes := announcedPrefixes(second)
+	for field, prefix := range announcedPrefixes(first) {
+		for otherField, otherPrefix := range secondPrefixes {
+			if !prefix.Overlaps(otherPrefix) {
+				continue
+			}
+			conflicts = append(conflicts, model.Conflict{
+				Announcements: [2]model.ConflictingAnnouncement{
+					{Project: first.Meta.Project, Name: first.Meta.Name, Field: field, Prefix: prefix.String()},
+					{Project: second.Meta.Project, Name: second.Meta.Name, Field: otherField, Prefix: otherPrefix.String()},
+				},
+				Equal: prefix == otherPrefix,
+			})
+		}
+	}
+	return conflicts
+}
+
+// sortConflicts sorts the conflicts by their announcements, so that they are reported in a stable order.
+func sortConflicts(conflicts []model.Conflict) {
+	slices.SortFunc(conflicts, func(a, b model.Conflict) int {
+		for i := range a.Announcements {
+			x, y := a.Announcements[i], b.Announcements[i]
+			if c := cmp.Or(cmp.Compare(x.Project, y.Project), cmp.Compare(x.Name, y.Name), cmp.Compare(x.Field, y.Field)); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// conflictMessage describes the conflict from the point of view of its first announcement.
+func conflictMessage(conflict model.Conflict) string {
+	first, second := conflict.Announcements[0], conflict.Announcements[1]
+	verb := "overlaps"
+	if conflict.Equal {
+		verb = "equals"
+	}
+	return fmt.Sprintf("%s %s %s announced by %s/%s", first.Prefix, verb, second.Prefix, second.Project, second.Name)
+}
+
+// conflictErrors returns the validation errors of the conflicts of a rejected announcement.
+func conflictErrors(conflicts []model.Conflict) []model.ValidationError {
+	errs := make([]model.ValidationError, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		errs = append(errs, model.ValidationError{
+			Field:   conflict.Announcements[0].Field,
+			Message: conflictMessage(conflict),
+		})
+	}
+	return errs
+}
+
+// warnConflicts logs the conflicts of an admitted announcement.
+func warnConflicts(conflicts []model.Conflict) {
+	for _, conflict := range conflicts {
+		announcement := conflict.Announcements[0]
+		slog.Warn("announced prefix conflicts with another project", "project", announcement.Project, "name", announcement.Name, "conflict", conflictMessage(conflict))
+	}
+}
@@ -0,0 +1,69 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// slowStore delays the writes of the announcements, widening the window between their admission and their write.
+type slowStore struct {
+	model.DatabaseAdapter
+}
+
+func (s slowStore) Put(key, value string) error {
+	if strings.HasPrefix(key, announcementsPrefix) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return s.DatabaseAdapter.Put(key, value)
+}
+
+func (s slowStore) Batch(writes []model.BatchWrite) error {
+	time.Sleep(10 * time.Millisecond)
+	return s.DatabaseAdapter.Batch(writes)
+}
+
+// TestConcurrentConflictingCreates checks that in reject mode only one of the concurrent creates of the same address
+// in different projects is admitted, whatever the path of the write.
+func TestConcurrentConflictingCreates(t *testing.T) {
+	server := newTestServerOn(t, slowStore{NewMemoryStore()}, func(config *model.APIConfig) {
+		config.CrossProjectConflicts = model.ConflictsReject
+	})
+
+	const writers = 12
+	codes := make([]int, writers)
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			announcement := testAnnouncement(fmt.Sprintf("team-%d", i), "web", "192.0.2.10")
+			if i%2 == 0 {
+				codes[i], _ = server.do(t, http.MethodPost, "/v1/announcements/", announcement)
+				return
+			}
+			batch := model.BatchRequest{Operations: []model.BatchOperation{{Action: model.BatchApply, Announcement: *announcement}}}
+			codes[i], _ = server.do(t, http.MethodPost, "/v1/announcements/batch", batch)
+		}()
+	}
+	wg.Wait()
+
+	admitted := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusCreated, http.StatusOK:
+			admitted++
+		case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		default:
+			t.Errorf("writer %d answered %d", i, code)
+		}
+	}
+	if admitted != 1 {
+		t.Fatalf("%d projects were admitted to announce 192.0.2.10, want 1", admitted)
+	}
+}
@@ -0,0 +1,159 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// registerDrainRoutes adds the routes that drain a single next hop of an announcement for maintenance and restore
+// it. The paths via a draining next hop are withdrawn by the updater while its health checks continue; the draining
+// next hops are listed in the status.
+func registerDrainRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog, clk clock.Clock) {
+	v1.POST("/announcements/:project/:name/next-hops/:ip/drain", setNextHopDraining(db, serializer, changes, clk, true))
+	v1.POST("/announcements/:project/:name/next-hops/:ip/undrain", setNextHopDraining(db, serializer, changes, clk, false))
+}
+
+// setNextHopDraining returns the handler that adds the next hop to the draining next hops of the announcement or
+// removes it. The announcement is not written when the next hop is already in the requested state.
+func setNextHopDraining(db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog, clk clock.Clock, draining bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		project := c.Param("project")
+		name := c.Param("name")
+
+		if _, err := netip.ParseAddr(c.Param("ip")); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "next hop must be an IP address",
+				Data:    nil,
+			})
+			return
+		}
+
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		key := announcementsPrefix + project + "/" + name
+		value, err := db.Get(key)
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "announcement not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to check announcement existence: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+
+		// The next hop is recorded as written in the specification, so that it matches the paths of the updater
+		index := slices.IndexFunc(announcement.NextHopAddresses(), func(address string) bool {
+			return model.SameAddress(address, c.Param("ip"))
+		})
+		if index < 0 {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "next hop not found",
+				Data:    nil,
+			})
+			return
+		}
+		nextHop := announcement.NextHopAddresses()[index]
+
+		message := "Next hop drained successfully"
+		if !draining {
+			message = "Next hop restored successfully"
+		}
+		if announcement.Status.Drained(nextHop) == draining {
+			c.JSON(http.StatusOK, model.APIResponse{
+				Status:  "success",
+				Message: message,
+				Data: model.Event{
+					Type:         model.EventUpdated,
+					Announcement: announcement,
+				},
+			})
+			return
+		}
+
+		previous := announcement
+		if draining {
+			announcement.Status.Draining = append(slices.Clone(announcement.Status.Draining), nextHop)
+		} else {
+			announcement.Status.Draining = slices.DeleteFunc(slices.Clone(announcement.Status.Draining), func(address string) bool {
+				return model.SameAddress(address, nextHop)
+			})
+		}
+		announcement.Status.Timestamp = clk.Now().UTC().Format(time.RFC3339)
+
+		newValue, err := json.Marshal(announcement)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := db.Put(key, string(newValue)); err != nil {
+			c.JSON(writeErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to update announcement: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+		refreshResourceVersion(db, key, &announcement)
+		changes.record(c, &previous, &announcement)
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: message,
+			Data: model.Event{
+				Type:         model.EventUpdated,
+				Announcement: announcement,
+			},
+		})
+	}
+}
+
+// pruneDrainedNextHops removes the next hops that the announcement no longer has from its draining next hops, so
+// that a next hop removed by an update and added again later is not drained.
+func pruneDrainedNextHops(announcement *model.Announcement) {
+	if len(announcement.Status.Draining) == 0 {
+		return
+	}
+	announcement.Status.Draining = slices.DeleteFunc(slices.Clone(announcement.Status.Draining), func(address string) bool {
+		return !slices.ContainsFunc(announcement.NextHopAddresses(), func(nextHop string) bool {
+			return model.SameAddress(address, nextHop)
+		})
+	})
+	if len(announcement.Status.Draining) == 0 {
+		announcement.Status.Draining = nil
+	}
+}
@@ -0,0 +1,9 @@
+package apiserver
+
+import "github.com/gin-gonic/gin"
+
+// isDryRun reports whether the request has the dryRun=true query parameter. A dry run performs all checks of the
+// write and responds with the resulting change, but persists nothing.
+func isDryRun(c *gin.Context) bool {
+	return c.Query("dryRun") == "true"
+}
@@ -0,0 +1,124 @@
+package apiserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipMinSize is the size from which the response bodies are compressed; smaller ones gain too little.
+const gzipMinSize = 1024
+
+// responseEncoding returns the middleware that makes the GET requests conditional and compresses the responses. A
+// successful GET response carries an ETag, the resource version of a single announcement or a hash of the body
+// otherwise, and is answered with 304 Not Modified when the If-None-Match header of the request lists it. Responses
+// of at least gzipMinSize bytes are compressed with gzip when the client accepts it. The watch streams are passed
+// through, since they are never complete.
+func responseEncoding() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.FullPath(), "/v1/watch/") || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedResponseWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		// Responses written without a body, e.g., aborted with a status only, are sent already
+		if original.Written() {
+			return
+		}
+
+		header := original.Header()
+		body := writer.body.Bytes()
+		if (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) && original.Status() == http.StatusOK {
+			etag := header.Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(body)
+				etag = `W/"` + hex.EncodeToString(sum[:16]) + `"`
+				header.Set("ETag", etag)
+			}
+			if etagMatches(c.GetHeader("If-None-Match"), etag) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				original.WriteHeader(http.StatusNotModified)
+				original.WriteHeaderNow()
+				return
+			}
+		}
+
+		if len(body) < gzipMinSize || header.Get("Content-Encoding") != "" || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			_, _ = original.Write(body)
+			return
+		}
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		header.Del("Content-Length")
+		gz := gzip.NewWriter(original)
+		_, _ = gz.Write(body)
+		_ = gz.Close()
+	}
+}
+
+// bufferedResponseWriter keeps the response body until the handler has finished, so that the ETag can be computed
+// and the body compressed. The status and the headers are kept by the wrapped writer.
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write appends the data to the buffered body.
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString appends the string to the buffered body.
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Written reports whether the handler has written a body or sent the status.
+func (w *bufferedResponseWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Size returns the number of buffered body bytes.
+func (w *bufferedResponseWriter) Size() int {
+	return w.body.Len()
+}
+
+// etagMatches reports whether the If-None-Match header lists the ETag or is "*". The weak comparison is used, as
+// defined for If-None-Match by RFC 9110.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether the Accept-Encoding header accepts gzip, i.e., lists it without a zero quality.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
@@ -4,45 +4,141 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
 	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+	"google.golang.org/grpc"
+	"log/slog"
 	"os"
 	"time"
 )
 
+// defaultEtcdRequestTimeout is the deadline of a single etcd request unless overridden with WithEtcdRequestTimeout.
+const defaultEtcdRequestTimeout = 10 * time.Second
+
 type EtcdClient struct {
-	client *clientv3.Client
+	client         *clientv3.Client
+	clock          clock.Clock   // clock is used to wait between watch restarts.
+	requestTimeout time.Duration // requestTimeout bounds every etcd request, so that an unresponsive etcd never blocks forever.
+	username       string        // username authenticates the connection together with the password; empty disables authentication.
+	password       string
+	keyPrefix      string // keyPrefix is prepended to all keys transparently, so that several instances can share a cluster.
+	maxRetries     int    // maxRetries is the number of retries of a request failing with a transient error.
 }
 
-func NewEtcdClient(endpoints []string, caFile, certFile, keyFile string) (*EtcdClient, error) {
-	caCert, err := os.ReadFile(caFile)
-	if err != nil {
-		return nil, fmt.Errorf("could not read CA certificate: %w", err)
+// EtcdOption configures optional behaviour of the EtcdClient.
+type EtcdOption func(*EtcdClient)
+
+// WithEtcdRequestTimeout sets the deadline of every etcd request. Non-positive values keep the default of 10 seconds.
+func WithEtcdRequestTimeout(d time.Duration) EtcdOption {
+	return func(e *EtcdClient) {
+		if d > 0 {
+			e.requestTimeout = d
+		}
 	}
-	caPool := x509.NewCertPool()
-	if !caPool.AppendCertsFromPEM(caCert) {
-		return nil, fmt.Errorf("failed to append CA certificate")
+}
+
+// WithEtcdAuth authenticates the connection as the etcd user with the password.
+func WithEtcdAuth(username, password string) EtcdOption {
+	return func(e *EtcdClient) {
+		e.username = username
+		e.password = password
 	}
+}
 
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-	if err != nil {
-		return nil, fmt.Errorf("could not load client certificate and key: %w", err)
+// WithEtcdKeyPrefix stores all keys under the prefix, e.g., "corebgp-eu/". The prefix is invisible to the API
+// server: the keys it reads, writes and watches never contain it.
+func WithEtcdKeyPrefix(prefix string) EtcdOption {
+	return func(e *EtcdClient) {
+		e.keyPrefix = prefix
+	}
+}
+
+// WithEtcdRetries retries the requests failing with a transient error, e.g., a leader change, up to maxRetries
+// times within the request timeout.
+func WithEtcdRetries(maxRetries int) EtcdOption {
+	return func(e *EtcdClient) {
+		e.maxRetries = maxRetries
+	}
+}
+
+// NewEtcdClient connects to the etcd cluster. TLS is enabled by the CA certificate, the client certificate is
+// optional. The clock times the restarts of the watches.
+func NewEtcdClient(endpoints []string, caFile, certFile, keyFile string, clk clock.Clock, opts ...EtcdOption) (*EtcdClient, error) {
+	e := &EtcdClient{clock: clk, requestTimeout: defaultEtcdRequestTimeout}
+	for _, opt := range opts {
+		opt(e)
 	}
 
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		RootCAs:      caPool,
+	var tlsConfig *tls.Config
+	if caFile != "" {
+		var err error
+		if tlsConfig, err = etcdTLSConfig(caFile, certFile, keyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	interceptors := []grpc.UnaryClientInterceptor{etcdMetricsInterceptor, tracing.UnaryClientInterceptor("etcd")}
+	if e.maxRetries > 0 {
+		interceptors = append(interceptors, etcdRetryInterceptor(e.maxRetries))
 	}
 
 	cli, err := clientv3.New(clientv3.Config{
 		Endpoints:   endpoints,
 		DialTimeout: 3 * time.Second,
 		TLS:         tlsConfig,
+		Username:    e.username,
+		Password:    e.password,
+		DialOptions: []grpc.DialOption{grpc.WithChainUnaryInterceptor(interceptors...)},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create etcd client: %w", err)
 	}
-	return &EtcdClient{client: cli}, nil
+	if e.keyPrefix != "" {
+		cli.KV = namespace.NewKV(cli.KV, e.keyPrefix)
+		cli.Watcher = namespace.NewWatcher(cli.Watcher, e.keyPrefix)
+		cli.Lease = namespace.NewLease(cli.Lease, e.keyPrefix)
+	}
+	e.client = cli
+	return e, nil
+}
+
+// etcdTLSConfig loads the TLS configuration of the etcd connection. The client certificate is optional.
+func etcdTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	caCert, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificate: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to append CA certificate")
+	}
+
+	tlsConfig := &tls.Config{RootCAs: caPool}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate and key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// requestContext returns a context bounded by the configured request timeout.
+func (e *EtcdClient) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), e.requestTimeout)
+}
+
+// warnOnTimeout logs a warning when the request failed because the request timeout was hit.
+func (e *EtcdClient) warnOnTimeout(ctx context.Context, operation, key string) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.Warn("etcd request timed out", "operation", operation, "key", key, "timeout", e.requestTimeout)
+	}
 }
 
 // Close gracefully closes the underlying etcd client connection and releases associated resources.
@@ -53,34 +149,42 @@ func (e *EtcdClient) Close() {
 // HealthCheck verifies the health status of the etcd client by querying the status of the first endpoint.
 // It returns an error if the health check fails, indicating that the etcd client may be unreachable.
 func (e *EtcdClient) HealthCheck() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := e.requestContext()
 	defer cancel()
 
 	// Simple status check via the shortest operation
 	_, err := e.client.Status(ctx, e.client.Endpoints()[0])
 	if err != nil {
+		e.warnOnTimeout(ctx, "status", e.client.Endpoints()[0])
 		return fmt.Errorf("etcd health check failed: %w", err)
 	}
 	return nil
 }
 
 func (e *EtcdClient) Put(key, value string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := e.requestContext()
 	defer cancel()
 
-	_, err := e.client.Put(ctx, key, value)
+	var err error
+	if isAnnouncementKey(key) {
+		err = e.writeIndexed(ctx, key, value, false)
+	} else {
+		_, err = e.client.Put(ctx, key, value)
+	}
 	if err != nil {
+		e.warnOnTimeout(ctx, "put", key)
 		return fmt.Errorf("failed to put data to etcd: %w", err)
 	}
 	return nil
 }
 
 func (e *EtcdClient) Get(key string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := e.requestContext()
 	defer cancel()
 
 	resp, err := e.client.Get(ctx, key)
 	if err != nil {
+		e.warnOnTimeout(ctx, "get", key)
 		return "", fmt.Errorf("failed to get data from etcd: %w", err)
 	}
 
@@ -88,16 +192,16 @@ func (e *EtcdClient) Get(key string) (string, error) {
 		return "", fmt.Errorf("key not found")
 	}
 
-	value := string(resp.Kvs[0].Value)
-	return value, nil
+	return kvValue(resp.Kvs[0]), nil
 }
 
 func (e *EtcdClient) List(prefix string) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := e.requestContext()
 	defer cancel()
 
 	resp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
+		e.warnOnTimeout(ctx, "list", prefix)
 		return nil, fmt.Errorf("failed to get data from etcd: %w", err)
 	}
 
@@ -109,47 +213,228 @@ func (e *EtcdClient) List(prefix string) ([]string, error) {
 }
 
 func (e *EtcdClient) GetObjects(prefix string) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := e.requestContext()
 	defer cancel()
 
 	resp, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
 	if err != nil {
+		e.warnOnTimeout(ctx, "list", prefix)
 		return nil, fmt.Errorf("failed to get data from etcd: %w", err)
 	}
 
 	values := make([]string, 0, len(resp.Kvs))
 	for _, kv := range resp.Kvs {
-		values = append(values, string(kv.Value))
+		values = append(values, kvValue(kv))
 	}
 
 	return values, nil
 }
 
+// GetObjectsPage returns up to limit values stored under the prefix, starting from startKey (inclusive).
+// The second return value is the key to start the next page from, or an empty string if there are no more keys.
+func (e *EtcdClient) GetObjectsPage(prefix, startKey string, limit int64) ([]string, string, error) {
+	ctx, cancel := e.requestContext()
+	defer cancel()
+
+	key := prefix
+	if startKey != "" {
+		key = startKey
+	}
+
+	resp, err := e.client.Get(ctx, key,
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)),
+		clientv3.WithLimit(limit),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+	)
+	if err != nil {
+		e.warnOnTimeout(ctx, "list", prefix)
+		return nil, "", fmt.Errorf("failed to get data from etcd: %w", err)
+	}
+
+	values := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		values = append(values, kvValue(kv))
+	}
+
+	var next string
+	if resp.More && len(resp.Kvs) > 0 {
+		// The smallest key greater than the last returned one
+		next = string(resp.Kvs[len(resp.Kvs)-1].Key) + "\x00"
+	}
+
+	return values, next, nil
+}
+
 func (e *EtcdClient) Delete(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := e.requestContext()
 	defer cancel()
 
-	_, err := e.client.Delete(ctx, key)
+	var err error
+	if isAnnouncementKey(key) {
+		err = e.writeIndexed(ctx, key, "", true)
+	} else {
+		_, err = e.client.Delete(ctx, key)
+	}
 	if err != nil {
+		e.warnOnTimeout(ctx, "delete", key)
 		return fmt.Errorf("failed to delete data from etcd: %w", err)
 	}
 	return nil
 }
 
 func (e *EtcdClient) Patch(key, value string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := e.requestContext()
 	defer cancel()
 
-	_, err := e.client.Put(ctx, key, value)
+	var err error
+	if isAnnouncementKey(key) {
+		err = e.writeIndexed(ctx, key, value, false)
+	} else {
+		_, err = e.client.Put(ctx, key, value)
+	}
 	if err != nil {
+		e.warnOnTimeout(ctx, "patch", key)
 		return fmt.Errorf("failed to patch data to etcd: %w", err)
 	}
 	return nil
 }
 
+// ListByNextHop returns the serialized announcements that use the specified next-hop address.
+// The lookup is served from the secondary next-hop index maintained on every announcement write.
+func (e *EtcdClient) ListByNextHop(nextHop string) ([]string, error) {
+	ctx, cancel := e.requestContext()
+	defer cancel()
+
+	resp, err := e.client.Get(ctx, nextHopIndexPrefix+nextHop+"/", clientv3.WithPrefix())
+	if err != nil {
+		e.warnOnTimeout(ctx, "list", nextHopIndexPrefix+nextHop)
+		return nil, fmt.Errorf("failed to get index from etcd: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return []string{}, nil
+	}
+
+	// Read all referenced announcements at the index revision to get a consistent snapshot
+	ops := make([]clientv3.Op, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		ops = append(ops, clientv3.OpGet(string(kv.Value), clientv3.WithRev(resp.Header.Revision)))
+	}
+	txnResp, err := e.client.Txn(ctx).Then(ops...).Commit()
+	if err != nil {
+		e.warnOnTimeout(ctx, "get", nextHopIndexPrefix+nextHop)
+		return nil, fmt.Errorf("failed to get data from etcd: %w", err)
+	}
+
+	values := make([]string, 0, len(txnResp.Responses))
+	for _, r := range txnResp.Responses {
+		for _, kv := range r.GetResponseRange().Kvs {
+			values = append(values, kvValue(kv))
+		}
+	}
+	return values, nil
+}
+
+// Batch applies all writes in a single transaction, so that either all of them or none are stored.
+// The next-hop index is updated in the same transaction.
+func (e *EtcdClient) Batch(writes []model.BatchWrite) error {
+	ctx, cancel := e.requestContext()
+	defer cancel()
+
+	if err := e.writeIndexedBatch(ctx, writes); err != nil {
+		e.warnOnTimeout(ctx, "batch", announcementsPrefix)
+		return fmt.Errorf("failed to apply batch to etcd: %w", err)
+	}
+	return nil
+}
+
+// writeIndexed puts or deletes the announcement key and updates the next-hop index in a single transaction.
+func (e *EtcdClient) writeIndexed(ctx context.Context, key, value string, deleteKey bool) error {
+	return e.writeIndexedBatch(ctx, []model.BatchWrite{{Key: key, Value: value, Delete: deleteKey}})
+}
+
+// writeIndexedBatch puts or deletes the keys and updates the next-hop index of the announcement keys in a single
+// transaction. The transaction is retried when any of the keys was modified concurrently between reading and writing.
+// Announcements carrying a resource version are written only if they were not modified since that version, otherwise
+// model.ErrResourceVersionConflict is returned without retrying.
+func (e *EtcdClient) writeIndexedBatch(ctx context.Context, writes []model.BatchWrite) error {
+	const maxAttempts = 5
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		compares := make([]clientv3.Cmp, 0, len(writes))
+		ops := make([]clientv3.Op, 0, len(writes))
+
+		for _, write := range writes {
+			var expectedRevision int64
+			if isAnnouncementKey(write.Key) && !write.Delete {
+				var err error
+				if expectedRevision, write.Value, err = splitResourceVersion(write.Value); err != nil {
+					return err
+				}
+			}
+
+			prev, err := e.client.Get(ctx, write.Key)
+			if err != nil {
+				return err
+			}
+
+			var prevValue string
+			var prevRevision int64
+			if len(prev.Kvs) > 0 {
+				prevValue = string(prev.Kvs[0].Value)
+				prevRevision = prev.Kvs[0].ModRevision
+			}
+			if expectedRevision != 0 && expectedRevision != prevRevision {
+				return fmt.Errorf("%w: %s was modified at revision %d", model.ErrResourceVersionConflict, write.Key, prevRevision)
+			}
+			compares = append(compares, clientv3.Compare(clientv3.ModRevision(write.Key), "=", prevRevision))
+
+			if write.Delete {
+				ops = append(ops, clientv3.OpDelete(write.Key))
+			} else {
+				ops = append(ops, clientv3.OpPut(write.Key, write.Value))
+			}
+
+			if !isAnnouncementKey(write.Key) {
+				continue
+			}
+
+			var newIndexKeys []string
+			if !write.Delete {
+				newIndexKeys = nextHopIndexKeys(write.Key, write.Value)
+			}
+			stale, fresh := diffIndexKeys(nextHopIndexKeys(write.Key, prevValue), newIndexKeys)
+			for _, indexKey := range stale {
+				ops = append(ops, clientv3.OpDelete(indexKey))
+			}
+			for _, indexKey := range fresh {
+				ops = append(ops, clientv3.OpPut(indexKey, write.Key))
+			}
+		}
+
+		// Apply the changes only if none of the keys changed since they were read
+		resp, err := e.client.Txn(ctx).
+			If(compares...).
+			Then(ops...).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if resp.Succeeded {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("keys were modified concurrently")
+}
+
 // Watch sets up a watch operation on a specified key and streams events through a channel until the stop signal is received.
+// When revision is greater than zero the watch resumes right after that revision, otherwise it starts from the current state.
+// If the underlying etcd watch channel closes unexpectedly (e.g., leader election), the watch is restarted from the last
+// seen revision. If that revision has been compacted, a full list is issued to obtain the current revision, a resync event
+// (a response with CompactRevision set) is emitted so consumers know about the gap, and the watch restarts from there.
+// The progress notifications of etcd are passed on as responses without events.
 // The stopChan is used to terminate the watch operation by canceling the associated context.
-func (e *EtcdClient) Watch(key string, stopChan <-chan struct{}) (<-chan clientv3.WatchResponse, error) {
+func (e *EtcdClient) Watch(key string, revision int64, stopChan <-chan struct{}) (<-chan model.WatchResponse, error) {
 	// Create a context that can be canceled to stop the watch operation
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -164,7 +449,103 @@ func (e *EtcdClient) Watch(key string, stopChan <-chan struct{}) (<-chan clientv
 		}
 	}()
 
-	// Start watching the specified key with a prefix
+	events := make(chan model.WatchResponse)
+
+	go func() {
+		defer cancel()
+		defer close(events)
+
+		// send forwards the response to the consumer unless the watch is stopped
+		send := func(resp model.WatchResponse) bool {
+			select {
+			case events <- resp:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		lastRevision := revision
+		for {
+			// Progress notifications report the revision of idle watches, for the watch bookmarks
+			opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithProgressNotify()}
+			if lastRevision > 0 {
+				opts = append(opts, clientv3.WithRev(lastRevision+1))
+			}
+
+			// Require a leader so that the watch is closed and restarted when the cluster loses it
+			watchChan := e.client.Watch(clientv3.WithRequireLeader(ctx), key, opts...)
+			for resp := range watchChan {
+				if resp.CompactRevision != 0 {
+					// The history is compacted, obtain the current revision with a full list and signal the gap
+					listCtx, listCancel := context.WithTimeout(ctx, e.requestTimeout)
+					listResp, err := e.client.Get(listCtx, key, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+					listCancel()
+					if err != nil {
+						e.warnOnTimeout(listCtx, "list", key)
+						slog.Error("failed to list keys after watch compaction", "error", err)
+						break
+					}
+					lastRevision = listResp.Header.Revision
+					if !send(model.WatchResponse{Revision: listResp.Header.Revision, CompactRevision: resp.CompactRevision}) {
+						return
+					}
+					break
+				}
+
+				if resp.Err() != nil {
+					// The watch was canceled by the server, restart it from the last seen revision
+					break
+				}
+
+				if n := len(resp.Events); n > 0 {
+					lastRevision = resp.Events[n-1].Kv.ModRevision
+				} else if resp.IsProgressNotify() {
+					lastRevision = resp.Header.Revision
+				}
+				if !send(watchResponse(resp)) {
+					return
+				}
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Wait before restarting the watch to avoid a busy loop while etcd is unavailable
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.clock.After(time.Second):
+			}
+		}
+	}()
+
 	// The returned channel streams events; the caller is responsible for processing them
-	return e.client.Watch(ctx, key, clientv3.WithPrefix(), clientv3.WithPrevKV()), nil
+	return events, nil
+}
+
+// watchResponse converts the etcd watch response to the one of the database adapter.
+func watchResponse(resp clientv3.WatchResponse) model.WatchResponse {
+	converted := model.WatchResponse{
+		Revision: resp.Header.Revision,
+		Events:   make([]model.WatchEvent, 0, len(resp.Events)),
+	}
+	for _, event := range resp.Events {
+		watchEvent := model.WatchEvent{
+			Type:        model.WatchEventPut,
+			Key:         string(event.Kv.Key),
+			Value:       string(event.Kv.Value),
+			ModRevision: event.Kv.ModRevision,
+			Created:     event.IsCreate(),
+		}
+		if event.Type == clientv3.EventTypeDelete {
+			watchEvent.Type = model.WatchEventDelete
+		}
+		if event.PrevKv != nil {
+			watchEvent.PrevValue = string(event.PrevKv.Value)
+		}
+		converted.Events = append(converted.Events, watchEvent)
+	}
+	return converted
 }
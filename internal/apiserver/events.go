@@ -0,0 +1,174 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// eventsPrefix is the key prefix of the announcement events. The keys end with the ID of the audit entry of the
+// change and the index of the event within it, so the events of an announcement are ordered by time.
+const eventsPrefix = "v1/events/"
+
+// statusEventReasons maps the statuses set by a change to the reasons of their events. The programming results are
+// reported by the Programmed condition instead.
+var statusEventReasons = map[string]string{
+	model.StatusWithdrawn:                "Withdrawn",
+	model.StatusExpired:                  "Expired",
+	model.StatusCancelled:                "Cancelled",
+	model.StatusUnsupportedAddressFamily: "UnsupportedAddressFamily",
+}
+
+// eventsKeyPrefix returns the key prefix of the events of the announcement.
+func eventsKeyPrefix(project, name string) string {
+	return eventsPrefix + project + "/" + name + "/"
+}
+
+// addEvents stores the events of the change and removes the oldest events exceeding the limit. Like the history, the
+// events are kept when the announcement is deleted.
+func (l *changeLog) addEvents(entry model.AuditEntry, before, after *model.Announcement) {
+	events := announcementEvents(entry, before, after)
+	if l.events <= 0 || len(events) == 0 {
+		return
+	}
+
+	prefix := eventsKeyPrefix(entry.Project, entry.Name)
+	for i, event := range events {
+		value, err := json.Marshal(event)
+		if err == nil {
+			err = l.db.Put(fmt.Sprintf("%s%s-%02d", prefix, entry.ID, i), string(value))
+		}
+		if err != nil {
+			slog.Error("failed to write announcement event", "project", entry.Project, "name", entry.Name, "reason", event.Reason, "error", err)
+			return
+		}
+	}
+
+	keys, err := l.db.List(prefix)
+	if err != nil {
+		slog.Error("failed to list announcement events", "project", entry.Project, "name", entry.Name, "error", err)
+		return
+	}
+	for len(keys) > l.events {
+		if err := l.db.Delete(keys[0]); err != nil {
+			slog.Error("failed to delete announcement event", "key", keys[0], "error", err)
+			return
+		}
+		keys = keys[1:]
+	}
+}
+
+// announcementEvents returns the events of the change: the creation, the update or the deletion of the announcement,
+// the statuses it entered, the transitions of its conditions, e.g., failed health checks or programming errors
+// reported by the updater, and the next hops that started or stopped draining.
+func announcementEvents(entry model.AuditEntry, before, after *model.Announcement) []model.AnnouncementEvent {
+	var events []model.AnnouncementEvent
+	add := func(eventType, reason, message string) {
+		events = append(events, model.AnnouncementEvent{
+			Timestamp:       entry.Timestamp,
+			Type:            eventType,
+			Reason:          reason,
+			Message:         message,
+			Actor:           entry.Actor,
+			ResourceVersion: entry.ResourceVersion,
+		})
+	}
+
+	switch {
+	case after == nil:
+		add(model.EventNormal, "Deleted", "announcement deleted")
+		return events
+	case before == nil:
+		add(model.EventNormal, "Created", "announcement created")
+		before = &model.Announcement{}
+	default:
+		var fields []string
+		for _, change := range entry.Changes {
+			if field, _, _ := strings.Cut(change.Field, "."); field != "status" && !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			add(model.EventNormal, "Updated", "changed "+strings.Join(fields, ", "))
+		}
+	}
+
+	if reason, ok := statusEventReasons[after.Status.Status]; ok && after.Status.Status != before.Status.Status {
+		eventType := model.EventNormal
+		if after.Status.Status == model.StatusUnsupportedAddressFamily {
+			eventType = model.EventWarning
+		}
+		add(eventType, reason, "status changed to "+after.Status.Status)
+	}
+
+	for _, condition := range after.Status.Conditions {
+		previous := before.Status.Condition(condition.Type)
+		// A failing condition is reported again when its message changes, e.g., when another next hop fails
+		if previous != nil && previous.Status == condition.Status && previous.Reason == condition.Reason &&
+			(condition.Status != model.ConditionFalse || previous.Message == condition.Message) {
+			continue
+		}
+		eventType := model.EventNormal
+		if condition.Status == model.ConditionFalse {
+			eventType = model.EventWarning
+		}
+		reason := condition.Reason
+		if reason == "" {
+			reason = condition.Type + condition.Status
+		}
+		add(eventType, reason, condition.Message)
+	}
+
+	for _, nextHop := range after.Status.Draining {
+		if !slices.Contains(before.Status.Draining, nextHop) {
+			add(model.EventNormal, "NextHopDraining", "next hop "+nextHop+" is draining")
+		}
+	}
+	for _, nextHop := range before.Status.Draining {
+		if !slices.Contains(after.Status.Draining, nextHop) {
+			add(model.EventNormal, "NextHopUndrained", "next hop "+nextHop+" is no longer draining")
+		}
+	}
+	return events
+}
+
+// registerEventRoutes adds the route that lists the events of an announcement.
+func registerEventRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter) {
+	v1.GET("/announcements/:project/:name/events", func(c *gin.Context) {
+		data, err := db.GetObjects(eventsKeyPrefix(c.Param("project"), c.Param("name")))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		events := make([]model.AnnouncementEvent, 0, len(data))
+		for _, value := range data {
+			var event model.AnnouncementEvent
+			if err := json.Unmarshal([]byte(value), &event); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement event",
+					Data:    nil,
+				})
+				return
+			}
+			events = append(events, event)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement events retrieved successfully",
+			Data:    events,
+		})
+	})
+}
@@ -0,0 +1,405 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// registerExportRoutes adds the routes that export the resources as a bundle and import a bundle, e.g., to restore
+// the control plane after the loss of the datastore.
+func registerExportRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog, policy *PrefixPolicy, config *model.APIConfig, clk clock.Clock) {
+	v1.GET("/export", func(c *gin.Context) {
+		project := c.Query("project")
+		if strings.Contains(project, "/") {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "project must not contain '/'",
+				Data:    nil,
+			})
+			return
+		}
+
+		bundle, err := exportBundle(db, project)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		bundle.ExportedAt = clk.Now().UTC()
+
+		// The bundle is sent as it is, so that the response can be saved and imported later
+		if c.Query("format") == "yaml" || strings.Contains(c.GetHeader("Accept"), "yaml") {
+			data, err := model.MarshalYAML(bundle)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    nil,
+				})
+				return
+			}
+			c.Data(http.StatusOK, "application/yaml", data)
+			return
+		}
+		c.JSON(http.StatusOK, bundle)
+	})
+
+	v1.POST("/import", func(c *gin.Context) {
+		strategy := model.ImportStrategy(c.DefaultQuery("strategy", string(model.ImportFail)))
+		if !strategy.Valid() {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("strategy must be %s, %s or %s", model.ImportSkip, model.ImportOverwrite, model.ImportFail),
+				Data:    nil,
+			})
+			return
+		}
+
+		var bundle model.Bundle
+		if err := bindAnnouncementJSON(c, config.StrictDecoding, &bundle); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		if bundle.Version != model.BundleVersion {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("unsupported bundle version %q, must be %s", bundle.Version, model.BundleVersion),
+				Data:    nil,
+			})
+			return
+		}
+
+		items, errs := importItems(&bundle, config)
+		if len(errs) > 0 {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("invalid bundle: %d invalid resources, nothing was imported", len(errs)),
+				Data:    errs,
+			})
+			return
+		}
+
+		// Lock the projects in a fixed order to avoid deadlocks with concurrent batches
+		for _, project := range bundleProjects(&bundle) {
+			unlock := serializer.Lock(project)
+			defer unlock()
+		}
+
+		report := model.ImportReport{Created: []string{}, Overwritten: []string{}, Skipped: []string{}, Conflicts: []string{}}
+		for i := range items {
+			if err := items[i].read(db); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    nil,
+				})
+				return
+			}
+			if items[i].exists {
+				report.Conflicts = append(report.Conflicts, items[i].id)
+			}
+		}
+		if strategy == model.ImportFail && len(report.Conflicts) > 0 {
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("%d resources already exist, nothing was imported", len(report.Conflicts)),
+				Data:    report,
+			})
+			return
+		}
+		report.Conflicts = []string{}
+
+		unlockConflicts := lockConflicts(serializer, policy)
+		defer unlockConflicts()
+
+		if errs, err := admitImport(db, policy, items, strategy); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		} else if len(errs) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("bundle rejected: %d admission errors, nothing was imported", len(errs)),
+				Data:    errs,
+			})
+			return
+		}
+
+		for i := range items {
+			item := &items[i]
+			switch {
+			case item.exists && strategy == model.ImportSkip:
+				report.Skipped = append(report.Skipped, item.id)
+				continue
+			case isDryRun(c):
+			default:
+				if err := db.Put(item.key, item.value); err != nil {
+					c.JSON(http.StatusInternalServerError, model.APIResponse{
+						Status:  "error",
+						Message: fmt.Errorf("failed to import %s, the resources before it were imported: %w", item.id, err).Error(),
+						Data:    report,
+					})
+					return
+				}
+				if item.announcement != nil {
+					refreshResourceVersion(db, item.key, item.announcement)
+					changes.record(c, item.previous, item.announcement)
+				}
+			}
+			if item.exists {
+				report.Overwritten = append(report.Overwritten, item.id)
+			} else {
+				report.Created = append(report.Created, item.id)
+			}
+		}
+
+		message := "Bundle imported successfully"
+		if isDryRun(c) {
+			message = "Bundle would be imported (dry run)"
+		}
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: message,
+			Data:    report,
+		})
+	})
+}
+
+// exportBundle reads the announcements and the project resources of the project, or of all projects together with
+// the peers if the project is empty.
+func exportBundle(db model.DatabaseAdapter, project string) (*model.Bundle, error) {
+	bundle := &model.Bundle{
+		Version:       model.BundleVersion,
+		Project:       project,
+		Announcements: []model.Announcement{},
+	}
+
+	prefix := announcementsPrefix
+	if project != "" {
+		prefix += project + "/"
+	}
+	values, err := db.GetObjects(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list announcements: %w", err)
+	}
+	for _, value := range values {
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal announcement")
+		}
+		if revision, err := strconv.ParseInt(announcement.Meta.ResourceVersion, 10, 64); err == nil {
+			bundle.Revision = max(bundle.Revision, revision)
+		}
+		bundle.Announcements = append(bundle.Announcements, announcement)
+	}
+
+	if project != "" {
+		resource, err := getProject(db, project)
+		if err != nil {
+			return nil, err
+		}
+		if resource != nil {
+			bundle.Projects = append(bundle.Projects, *resource)
+		}
+		return bundle, nil
+	}
+
+	if err := decodeObjects(db, projectsPrefix, &bundle.Projects); err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	if err := decodeObjects(db, peersPrefix, &bundle.Peers); err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	return bundle, nil
+}
+
+// decodeObjects appends the values stored under the prefix to the list.
+func decodeObjects[T any](db model.DatabaseAdapter, prefix string, list *[]T) error {
+	values, err := db.GetObjects(prefix)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		var object T
+		if err := json.Unmarshal([]byte(value), &object); err != nil {
+			return fmt.Errorf("failed to unmarshal %s", strings.TrimSuffix(strings.TrimPrefix(prefix, "v1/"), "/"))
+		}
+		*list = append(*list, object)
+	}
+	return nil
+}
+
+// importItem is a resource of an imported bundle.
+type importItem struct {
+	id    string // id identifies the resource in the import report.
+	key   string // key is the storage key of the resource.
+	value string // value is the resource to store.
+
+	announcement *model.Announcement // announcement is the imported announcement; nil for the other resources.
+	previous     *model.Announcement // previous is the stored announcement replaced by the import, if any.
+	exists       bool                // exists reports whether the resource is stored.
+}
+
+// read checks whether the resource is stored, and reads the replaced announcement.
+func (i *importItem) read(db model.DatabaseAdapter) error {
+	value, err := db.Get(i.key)
+	if err != nil && err.Error() == "key not found" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check %s existence: %w", i.id, err)
+	}
+	i.exists = true
+	if i.announcement != nil {
+		i.previous = &model.Announcement{}
+		if err := json.Unmarshal([]byte(value), i.previous); err != nil {
+			// A malformed announcement is audited by its identity only
+			i.previous = &model.Announcement{Meta: model.Meta{Project: i.announcement.Meta.Project, Name: i.announcement.Meta.Name}}
+		}
+	}
+	return nil
+}
+
+// importItems validates the resources of the bundle and returns them in the order they are imported: the projects,
+// the peers and the announcements. The announcements are stored without their resource version, which is assigned
+// by the storage, and keep their status.
+func importItems(bundle *model.Bundle, config *model.APIConfig) ([]importItem, []model.ValidationError) {
+	var items []importItem
+	var errs []model.ValidationError
+	seen := make(map[string]struct{})
+	add := func(field, id, key string, object interface{}, announcement *model.Announcement) {
+		if _, ok := seen[id]; ok {
+			errs = append(errs, model.ValidationError{Field: field, Message: fmt.Sprintf("duplicate %s", id)})
+			return
+		}
+		seen[id] = struct{}{}
+		value, err := json.Marshal(object)
+		if err != nil {
+			errs = append(errs, model.ValidationError{Field: field, Message: err.Error()})
+			return
+		}
+		items = append(items, importItem{id: id, key: key, value: string(value), announcement: announcement})
+	}
+
+	for i := range bundle.Projects {
+		project := &bundle.Projects[i]
+		field := fmt.Sprintf("projects[%d]", i)
+		if err := validateProject(project); err != nil {
+			errs = append(errs, model.ValidationError{Field: field, Message: err.Error()})
+			continue
+		}
+		add(field, "projects/"+project.Name, projectsPrefix+project.Name, project, nil)
+	}
+
+	for i := range bundle.Peers {
+		peer := &bundle.Peers[i]
+		field := fmt.Sprintf("peers[%d]", i)
+		if err := validatePeer(peer); err != nil {
+			errs = append(errs, model.ValidationError{Field: field, Message: err.Error()})
+			continue
+		}
+		add(field, "peers/"+peer.Address, peersPrefix+peer.Address, peer, nil)
+	}
+
+	for i := range bundle.Announcements {
+		announcement := &bundle.Announcements[i]
+		field := fmt.Sprintf("announcements[%d]", i)
+		err := checkNameLength(announcement.Meta.Name, config.MaxAnnouncementNameLength)
+		if err == nil {
+			err = validateAnnouncement(announcement)
+		}
+		if err != nil {
+			for _, invalid := range validationErrors(err) {
+				errs = append(errs, model.ValidationError{Field: field + "." + invalid.Field, Message: invalid.Message})
+			}
+			continue
+		}
+		announcement.Meta.ResourceVersion = ""
+		id := announcement.Meta.Project + "/" + announcement.Meta.Name
+		add(field, "announcements/"+id, announcementsPrefix+id, announcement, announcement)
+	}
+	return items, errs
+}
+
+// admitImport checks the imported announcements against the project quotas, the overlaps within their project, the
+// prefix policy and the pools, admitting them together with the projects and the announcements imported before them.
+// The created announcements of a pool without an announced IP are allocated an address first. The skipped resources
+// are not imported and are admitted as they are stored. The errors are prefixed with the announcements they belong to.
+func admitImport(db model.DatabaseAdapter, policy *PrefixPolicy, items []importItem, strategy model.ImportStrategy) ([]model.ValidationError, error) {
+	pending := newPendingWrites()
+	for _, item := range items {
+		var project model.Project
+		if item.announcement == nil && strings.HasPrefix(item.key, projectsPrefix) && !(item.exists && strategy == model.ImportSkip) {
+			if err := json.Unmarshal([]byte(item.value), &project); err == nil {
+				pending.putProject(&project)
+			}
+		}
+	}
+
+	var errs []model.ValidationError
+	for i := range items {
+		item := &items[i]
+		if item.announcement == nil || item.exists && strategy == model.ImportSkip {
+			continue
+		}
+
+		// The created announcements are allocated the addresses of their pools like the other creates
+		if !item.exists && item.announcement.Addresses.Pool != "" && item.announcement.Addresses.AnnouncedIP == "" {
+			if err := allocatePoolAddress(db, item.announcement, pending); err != nil {
+				fields := validationErrors(err)
+				if fields == nil {
+					return nil, err
+				}
+				for _, field := range fields {
+					errs = append(errs, model.ValidationError{Field: item.id + "." + field.Field, Message: field.Message})
+				}
+				continue
+			}
+			value, err := json.Marshal(item.announcement)
+			if err != nil {
+				return nil, err
+			}
+			item.value = string(value)
+		}
+
+		admissionErrs, err := projectAdmissionErrors(db, policy, item.announcement, pending)
+		if err != nil {
+			return nil, err
+		}
+		for _, admissionErr := range admissionErrs {
+			errs = append(errs, model.ValidationError{Field: item.id + "." + admissionErr.Field, Message: admissionErr.Message})
+		}
+		pending.putAnnouncement(item.announcement)
+	}
+	return errs, nil
+}
+
+// bundleProjects returns the sorted projects of the announcements of the bundle.
+func bundleProjects(bundle *model.Bundle) []string {
+	var projects []string
+	for _, announcement := range bundle.Announcements {
+		if !slices.Contains(projects, announcement.Meta.Project) {
+			projects = append(projects, announcement.Meta.Project)
+		}
+	}
+	slices.Sort(projects)
+	return projects
+}
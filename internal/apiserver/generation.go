@@ -0,0 +1,62 @@
+package apiserver
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// stampGeneration sets the creation time and the generation of the announcement written by the request; previous is
+// the stored announcement, nil for a creation. A creation records its time, which the updates keep, and starts at
+// generation 1, and every later change other than of the status increments the generation and records the change ID
+// and time, which the propagation latency is measured from. Writes that leave the announcement as it is keep the
+// generation of the stored announcement.
+func stampGeneration(c *gin.Context, previous, announcement *model.Announcement, now time.Time) {
+	if previous == nil {
+		announcement.Meta.CreatedAt = now.UTC().Format(time.RFC3339Nano)
+		announcement.Meta.Generation = 0
+	} else {
+		announcement.Meta.CreatedAt = previous.Meta.CreatedAt
+		announcement.Meta.Generation = previous.Meta.Generation
+		announcement.Meta.ChangeID = previous.Meta.ChangeID
+		announcement.Meta.ChangedAt = previous.Meta.ChangedAt
+		if reflect.DeepEqual(specFields(previous), specFields(announcement)) {
+			return
+		}
+	}
+
+	announcement.Meta.Generation++
+	announcement.Meta.ChangeID = changeID(c)
+	announcement.Meta.ChangedAt = now.UTC().Format(time.RFC3339Nano)
+}
+
+// changeID returns the ID of the change made by the request: the ID of its trace when the request is traced, so that
+// the change can be followed from the client to the updaters, or a random ID.
+func changeID(c *gin.Context) string {
+	if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return randomID()
+}
+
+// observeGeneration records in the status of the announcement that its current generation is programmed once all
+// reported routers have programmed it, together with the time it took since the write. It reports whether the
+// generation was observed by the status just now, so that its propagation time is recorded once it is stored.
+func observeGeneration(announcement *model.Announcement, now time.Time) bool {
+	status := &announcement.Status
+	generation := status.ProgrammedGeneration()
+	if generation == 0 || generation != announcement.Meta.Generation || generation == status.ObservedGeneration {
+		return false
+	}
+
+	status.ObservedGeneration = generation
+	status.ProgrammedAt = now.UTC().Format(time.RFC3339Nano)
+	status.PropagationSeconds = 0
+	if changedAt, err := time.Parse(time.RFC3339Nano, announcement.Meta.ChangedAt); err == nil {
+		status.PropagationSeconds = max(now.Sub(changedAt).Seconds(), 0)
+	}
+	return true
+}
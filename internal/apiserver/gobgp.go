@@ -0,0 +1,166 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/model"
+
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+// registerGoBGPRoutes adds the routes that expose the state of the GoBGP instance. When the GoBGP client is not
+// configured, the routes respond with 503 Service Unavailable.
+func registerGoBGPRoutes(v1 *gin.RouterGroup, goBGP *gobgp.Client) {
+	group := v1.Group("/gobgp")
+	group.Use(requireGoBGP(goBGP))
+
+	group.GET("/summary", func(c *gin.Context) {
+		global, err := goBGP.GetGlobal()
+		if err != nil {
+			c.JSON(http.StatusBadGateway, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		peers, err := goBGP.ListPeers()
+		if err != nil {
+			c.JSON(http.StatusBadGateway, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "BGP session summary retrieved successfully",
+			Data:    summarizeSessions(global, peers),
+		})
+	})
+
+	group.GET("/global", func(c *gin.Context) {
+		global, err := goBGP.GetGlobal()
+		if err != nil {
+			c.JSON(http.StatusBadGateway, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		config := model.GoBGPGlobalConfig{}
+		if global != nil {
+			config.ASN = global.Asn
+			config.RouterID = global.RouterId
+			config.ListenPort = global.ListenPort
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "GoBGP global configuration retrieved successfully",
+			Data:    config,
+		})
+	})
+
+	group.PUT("/global", func(c *gin.Context) {
+		var config model.GoBGPGlobalConfig
+		if err := c.ShouldBindJSON(&config); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := validateGoBGPGlobalConfig(&config); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := goBGP.StartBGP(config); err != nil {
+			c.JSON(http.StatusBadGateway, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "GoBGP global configuration applied successfully",
+			Data:    config,
+		})
+	})
+}
+
+// requireGoBGP rejects the requests with 503 Service Unavailable when the GoBGP client is not configured.
+func requireGoBGP(goBGP *gobgp.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if goBGP == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, model.APIResponse{
+				Status:  "error",
+				Message: "GoBGP connection is not configured",
+				Data:    nil,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// validateGoBGPGlobalConfig checks that the global configuration can be passed to GoBGP.
+func validateGoBGPGlobalConfig(config *model.GoBGPGlobalConfig) error {
+	if config.ASN == 0 {
+		return fmt.Errorf("asn is required")
+	}
+	routerID, err := netip.ParseAddr(config.RouterID)
+	if err != nil || !routerID.Is4() {
+		return fmt.Errorf("router-id must be a valid IPv4 address")
+	}
+	if config.ListenPort < -1 || config.ListenPort > 65535 {
+		return fmt.Errorf("listen-port must be in range 0-65535 or -1 to disable listening")
+	}
+	return nil
+}
+
+// summarizeSessions aggregates the session states and prefix counters of all peers.
+func summarizeSessions(global *api.Global, peers []*api.Peer) model.BGPSessionSummary {
+	summary := model.BGPSessionSummary{
+		TotalPeers: len(peers),
+	}
+	if global != nil {
+		summary.ASN = global.Asn
+		summary.RouterID = global.RouterId
+	}
+
+	for _, peer := range peers {
+		switch peer.GetState().GetSessionState() {
+		case api.PeerState_ESTABLISHED:
+			summary.EstablishedPeers++
+		case api.PeerState_IDLE:
+			summary.IdlePeers++
+		}
+
+		for _, afiSafi := range peer.GetAfiSafis() {
+			summary.TotalPrefixesReceived += int64(afiSafi.GetState().GetReceived())
+			summary.TotalPrefixesSent += int64(afiSafi.GetState().GetAdvertised())
+		}
+	}
+
+	return summary
+}
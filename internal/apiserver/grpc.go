@@ -0,0 +1,381 @@
+package apiserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	corebgpv1 "github.com/nikitamishagin/corebgp/pkg/client/grpc/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer implements the gRPC AnnouncementService by passing every call to the handlers of the REST router, so
+// that both APIs share the authentication, validation, admission, audit log and resource version checks. The
+// metadata of a call is passed as the request headers, e.g., the authorization and the trace context.
+type grpcServer struct {
+	corebgpv1.UnimplementedAnnouncementServiceServer
+	router http.Handler
+}
+
+// startGRPCServer serves the AnnouncementService on the address in the background. The TLS configuration is nil for
+// a plaintext listener.
+func startGRPCServer(addr string, router http.Handler, tlsConfig *tls.Config) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+	corebgpv1.RegisterAnnouncementServiceServer(server, &grpcServer{router: router})
+
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			slog.Error("gRPC server failed", "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// Get returns an announcement.
+func (s *grpcServer) Get(ctx context.Context, req *corebgpv1.GetAnnouncementRequest) (*corebgpv1.Announcement, error) {
+	path, err := announcementPath(req.GetProject(), req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	var announcement model.Announcement
+	if err := s.call(ctx, http.MethodGet, path, nil, nil, &announcement); err != nil {
+		return nil, err
+	}
+	return corebgpv1.AnnouncementFromModel(&announcement), nil
+}
+
+// List returns a page of the announcements of a project.
+func (s *grpcServer) List(ctx context.Context, req *corebgpv1.ListAnnouncementsRequest) (*corebgpv1.ListAnnouncementsResponse, error) {
+	path, err := announcementPath(req.GetProject(), "all")
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	if req.GetLimit() > 0 {
+		query.Set("limit", strconv.Itoa(int(req.GetLimit())))
+	}
+	if req.GetContinue() != "" {
+		query.Set("continue", req.GetContinue())
+	}
+	if req.GetNamePrefix() != "" {
+		query.Set("namePrefix", req.GetNamePrefix())
+	}
+	if req.GetLabelSelector() != "" {
+		query.Set("labelSelector", req.GetLabelSelector())
+	}
+	if req.GetIncludeWithdrawn() {
+		query.Set("includeWithdrawn", "true")
+	}
+
+	var list model.AnnouncementList
+	if err := s.call(ctx, http.MethodGet, path, query, nil, &list); err != nil {
+		return nil, err
+	}
+	resp := &corebgpv1.ListAnnouncementsResponse{Continue: list.Continue}
+	for i := range list.Items {
+		resp.Items = append(resp.Items, corebgpv1.AnnouncementFromModel(&list.Items[i]))
+	}
+	return resp, nil
+}
+
+// Create creates an announcement.
+func (s *grpcServer) Create(ctx context.Context, req *corebgpv1.CreateAnnouncementRequest) (*corebgpv1.Announcement, error) {
+	var event model.Event
+	err := s.call(ctx, http.MethodPost, "/v1/announcements/", dryRunQuery(req.GetDryRun()), req.GetAnnouncement().ToModel(), &event)
+	if status.Code(err) == codes.Aborted {
+		return nil, status.Error(codes.AlreadyExists, status.Convert(err).Message())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return corebgpv1.AnnouncementFromModel(&event.Announcement), nil
+}
+
+// Update replaces an announcement.
+func (s *grpcServer) Update(ctx context.Context, req *corebgpv1.UpdateAnnouncementRequest) (*corebgpv1.Announcement, error) {
+	var event model.Event
+	if err := s.call(ctx, http.MethodPatch, "/v1/announcements/", dryRunQuery(req.GetDryRun()), req.GetAnnouncement().ToModel(), &event); err != nil {
+		return nil, err
+	}
+	return corebgpv1.AnnouncementFromModel(&event.Announcement), nil
+}
+
+// Delete deletes an announcement and returns its last state.
+func (s *grpcServer) Delete(ctx context.Context, req *corebgpv1.DeleteAnnouncementRequest) (*corebgpv1.Announcement, error) {
+	path, err := announcementPath(req.GetProject(), req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	var event model.Event
+	if err := s.call(ctx, http.MethodDelete, path, dryRunQuery(req.GetDryRun()), nil, &event); err != nil {
+		return nil, err
+	}
+	return corebgpv1.AnnouncementFromModel(&event.Announcement), nil
+}
+
+// Watch streams the changes of the announcements. It reads the Server-Sent Events of the REST watch through a pipe.
+func (s *grpcServer) Watch(req *corebgpv1.WatchAnnouncementsRequest, stream corebgpv1.AnnouncementService_WatchServer) error {
+	query := url.Values{}
+	if req.GetProject() != "" {
+		query.Set("project", req.GetProject())
+	}
+	if req.GetNamePrefix() != "" {
+		query.Set("namePrefix", req.GetNamePrefix())
+	}
+	if req.GetLabelSelector() != "" {
+		query.Set("labelSelector", req.GetLabelSelector())
+	}
+	if len(req.GetTypes()) > 0 {
+		query.Set("types", strings.Join(req.GetTypes(), ","))
+	}
+	httpReq, err := s.newRequest(stream.Context(), http.MethodGet, "/v1/watch/announcements/", query, nil)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if req.GetRevision() > 0 {
+		httpReq.Header.Set("Last-Event-ID", strconv.FormatInt(req.GetRevision(), 10))
+	}
+
+	reader, writer := io.Pipe()
+	defer reader.Close()
+	response := &pipeResponseWriter{header: http.Header{}, body: writer}
+	go func() {
+		s.router.ServeHTTP(response, httpReq)
+		_ = writer.Close()
+	}()
+
+	lines := bufio.NewReader(reader)
+	var data strings.Builder
+	for {
+		line, err := lines.ReadString('\n')
+		if code := response.statusCode(); code != http.StatusOK {
+			// The watch was rejected, the rest of the body is the error response
+			rest, _ := io.ReadAll(lines)
+			return grpcError(code, append([]byte(line), rest...))
+		}
+		if err != nil {
+			return nil
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var event model.Event
+		if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+			return status.Errorf(codes.Internal, "failed to decode watch event: %v", err)
+		}
+		data.Reset()
+		if err := stream.Send(corebgpv1.WatchEventFromModel(event)); err != nil {
+			return err
+		}
+	}
+}
+
+// call serves the REST request of the call and decodes the data of the response into out. A failed request is
+// returned as a gRPC status error.
+func (s *grpcServer) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := s.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	return s.do(req, out)
+}
+
+// do serves the REST request and decodes the data of the response into out.
+func (s *grpcServer) do(req *http.Request, out interface{}) error {
+	response := &bufferResponseWriter{header: http.Header{}}
+	s.router.ServeHTTP(response, req)
+	if response.status >= http.StatusMultipleChoices {
+		return grpcError(response.status, response.body.Bytes())
+	}
+
+	var apiResponse struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(response.body.Bytes(), &apiResponse); err != nil {
+		return status.Errorf(codes.Internal, "failed to decode response: %v", err)
+	}
+	if err := json.Unmarshal(apiResponse.Data, out); err != nil {
+		return status.Errorf(codes.Internal, "failed to decode response data: %v", err)
+	}
+	return nil
+}
+
+// newRequest creates the REST request of a call. The metadata of the call becomes the headers and the address of
+// the peer the remote address.
+func (s *grpcServer) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := &url.URL{Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create request: %v", err)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		// Skip the pseudo headers and the headers of the gRPC protocol
+		if strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") || key == "content-type" || key == "te" {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+	}
+	return req, nil
+}
+
+// announcementPath returns the REST path of the announcement after checking the project and the name.
+func announcementPath(project, name string) (string, error) {
+	if project == "" || name == "" || strings.Contains(project, "/") || strings.Contains(name, "/") {
+		return "", status.Error(codes.InvalidArgument, "project and name must not be empty nor contain a slash")
+	}
+	return "/v1/announcements/" + project + "/" + name, nil
+}
+
+// dryRunQuery returns the query parameters of a dry run, or nil.
+func dryRunQuery(dryRun bool) url.Values {
+	if !dryRun {
+		return nil
+	}
+	return url.Values{"dryRun": {"true"}}
+}
+
+// grpcError converts the REST error response to a gRPC status error.
+func grpcError(httpStatus int, body []byte) error {
+	message := http.StatusText(httpStatus)
+	var apiResponse model.APIResponse
+	if err := json.Unmarshal(body, &apiResponse); err == nil && apiResponse.Message != "" {
+		message = apiResponse.Message
+	}
+
+	code := codes.Unknown
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		code = codes.Aborted
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	default:
+		if httpStatus >= http.StatusInternalServerError {
+			code = codes.Internal
+		}
+	}
+	return status.Error(code, message)
+}
+
+// bufferResponseWriter is an http.ResponseWriter keeping the response in memory.
+type bufferResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferResponseWriter) Header() http.Header { return w.header }
+
+func (w *bufferResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+// pipeResponseWriter is an http.ResponseWriter streaming the response body through a pipe. It implements
+// http.Flusher, which the event stream requires; the pipe is unbuffered, so there is nothing to flush.
+type pipeResponseWriter struct {
+	header http.Header
+	body   *io.PipeWriter
+
+	mu     sync.Mutex
+	status int
+}
+
+func (w *pipeResponseWriter) Header() http.Header { return w.header }
+
+func (w *pipeResponseWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *pipeResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+func (w *pipeResponseWriter) Flush() {}
+
+// statusCode returns the status of the response, or 200 if none was written yet.
+func (w *pipeResponseWriter) statusCode() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,79 @@
+package apiserver
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/version"
+)
+
+// registerHealthRoutes registers the probes of the API server and its build information outside the v1 API, so that
+// they are reachable without authentication. A nil GoBGP client is not checked for readiness.
+func registerHealthRoutes(router *gin.Engine, db model.DatabaseAdapter, goBGP *gobgp.Client) {
+	router.GET("/healthz", func(c *gin.Context) {
+		// Check connection to etcd
+		if err := db.HealthCheck(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	// The process serves requests, its dependencies are not checked so that their outage does not restart it
+	router.GET("/livez", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	router.GET("/readyz", func(c *gin.Context) {
+		readiness := checkReadiness(db, goBGP)
+		if !readiness.Ready {
+			var failed []string
+			for _, name := range slices.Sorted(maps.Keys(readiness.Checks)) {
+				if result := readiness.Checks[name]; result != "ok" {
+					failed = append(failed, name+": "+result)
+				}
+			}
+			c.JSON(http.StatusServiceUnavailable, model.APIResponse{
+				Status:  "error",
+				Message: "not ready: " + strings.Join(failed, "; "),
+				Data:    readiness,
+			})
+			return
+		}
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "ready",
+			Data:    readiness,
+		})
+	})
+
+	router.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "build information retrieved successfully",
+			Data:    version.Info(),
+		})
+	})
+}
+
+// checkReadiness checks that the datastore and, if configured, GoBGP are reachable.
+func checkReadiness(db model.DatabaseAdapter, goBGP *gobgp.Client) model.Readiness {
+	readiness := model.Readiness{Ready: true, Checks: map[string]string{"datastore": "ok"}}
+	if err := db.HealthCheck(); err != nil {
+		readiness.Ready = false
+		readiness.Checks["datastore"] = err.Error()
+	}
+	if goBGP != nil {
+		readiness.Checks["gobgp"] = "ok"
+		if _, err := goBGP.GetGlobal(); err != nil {
+			readiness.Ready = false
+			readiness.Checks["gobgp"] = err.Error()
+		}
+	}
+	return readiness
+}
@@ -0,0 +1,300 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// historyPrefix is the key prefix of the announcement revisions. The keys end with the zero-padded resource
+// version, so the revisions of an announcement are ordered from the oldest to the newest.
+const historyPrefix = "v1/history/"
+
+// historyKeyPrefix returns the key prefix of the revisions of the announcement.
+func historyKeyPrefix(project, name string) string {
+	return historyPrefix + project + "/" + name + "/"
+}
+
+// historyKey returns the key of the revision of the announcement.
+func historyKey(project, name string, revision int64) string {
+	return fmt.Sprintf("%s%020d", historyKeyPrefix(project, name), revision)
+}
+
+// addRevision adds the announcement written by the change to its history and removes the revisions exceeding the
+// limit. Changes of the status only, e.g., reports of the updater or withdrawals, add no revision, so that they do
+// not push the configuration changes out of the history. The history is kept when the announcement is deleted.
+func (l *changeLog) addRevision(entry model.AuditEntry, before, after *model.Announcement) {
+	if l.revisions <= 0 || before != nil && reflect.DeepEqual(specFields(before), specFields(after)) {
+		return
+	}
+	revision, err := strconv.ParseInt(after.Meta.ResourceVersion, 10, 64)
+	if err != nil {
+		slog.Warn("announcement revision is not recorded, its resource version is unknown", "project", entry.Project, "name", entry.Name)
+		return
+	}
+
+	value, err := json.Marshal(model.AnnouncementRevision{
+		Revision:     after.Meta.ResourceVersion,
+		Timestamp:    entry.Timestamp,
+		Actor:        entry.Actor,
+		Announcement: *after,
+	})
+	if err == nil {
+		err = l.db.Put(historyKey(entry.Project, entry.Name, revision), string(value))
+	}
+	if err != nil {
+		slog.Error("failed to write announcement revision", "project", entry.Project, "name", entry.Name, "revision", revision, "error", err)
+		return
+	}
+
+	keys, err := l.db.List(historyKeyPrefix(entry.Project, entry.Name))
+	if err != nil {
+		slog.Error("failed to list announcement revisions", "project", entry.Project, "name", entry.Name, "error", err)
+		return
+	}
+	for len(keys) > l.revisions {
+		if err := l.db.Delete(keys[0]); err != nil {
+			slog.Error("failed to delete announcement revision", "key", keys[0], "error", err)
+			return
+		}
+		keys = keys[1:]
+	}
+}
+
+// specFields returns the JSON object of the announcement without the resource version and the status.
+func specFields(announcement *model.Announcement) map[string]interface{} {
+	fields := announcementFields(announcement)
+	delete(fields, "status")
+	return fields
+}
+
+// registerHistoryRoutes adds the routes that list the revisions of an announcement and restore one of them.
+func registerHistoryRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog, policy *PrefixPolicy, admission *AdmissionWebhooks, clk clock.Clock) {
+	v1.GET("/announcements/:project/:name/history", func(c *gin.Context) {
+		data, err := db.GetObjects(historyKeyPrefix(c.Param("project"), c.Param("name")))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		revisions := make([]model.AnnouncementRevision, 0, len(data))
+		for _, value := range data {
+			var revision model.AnnouncementRevision
+			if err := json.Unmarshal([]byte(value), &revision); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement revision",
+					Data:    nil,
+				})
+				return
+			}
+			revisions = append(revisions, revision)
+		}
+		// The newest revision comes first
+		slices.Reverse(revisions)
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement history retrieved successfully",
+			Data:    revisions,
+		})
+	})
+
+	v1.POST("/announcements/:project/:name/rollback", func(c *gin.Context) {
+		project := c.Param("project")
+		name := c.Param("name")
+
+		var request model.RollbackRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		revision, err := strconv.ParseInt(request.Revision, 10, 64)
+		if err != nil || revision <= 0 {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("invalid revision %q", request.Revision),
+				Data:    nil,
+			})
+			return
+		}
+
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		value, err := db.Get(historyKey(project, name, revision))
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "revision not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var stored model.AnnouncementRevision
+		if err := json.Unmarshal([]byte(value), &stored); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement revision",
+				Data:    nil,
+			})
+			return
+		}
+
+		key := announcementsPrefix + project + "/" + name
+		current, err := db.Get(key)
+		if err != nil && err.Error() != "key not found" {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to check announcement existence: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		// The configuration of the revision is restored, the status is kept as stored. A deleted announcement is
+		// created again and starts pending
+		restored := stored.Announcement
+		restored.Status = model.Status{}
+		var previous *model.Announcement
+		if err == nil {
+			previous = &model.Announcement{}
+			if err := json.Unmarshal([]byte(current), previous); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+			restored.Status = previous.Status
+		}
+
+		// A rollback re-activates a withdrawn or cancelled announcement
+		if restored.Status.Status == model.StatusWithdrawn || restored.Status.Status == model.StatusCancelled {
+			restored.Status.Status = model.StatusPending
+		}
+		pruneDrainedNextHops(&restored)
+		now := clk.Now()
+		resolveSchedule(&restored, now)
+		stampGeneration(c, previous, &restored, now)
+
+		// The rollback is conditional if the client passes the resource version of the current announcement
+		restored.Meta.ResourceVersion = parseIfMatch(c.GetHeader("If-Match"))
+		if version := restored.Meta.ResourceVersion; version != "" {
+			if revision, err := strconv.ParseInt(version, 10, 64); err != nil || revision <= 0 {
+				c.JSON(http.StatusBadRequest, model.APIResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("invalid resource version %q", version),
+					Data:    nil,
+				})
+				return
+			}
+		}
+
+		if err := validateAnnouncement(&restored); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		if err := admission.admit(c, isDryRun(c), previous, &restored); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		unlockConflicts := lockConflicts(serializer, policy)
+		defer unlockConflicts()
+
+		if err := checkProjectAdmission(db, policy, &restored, nil); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		eventType, status := model.EventAdded, http.StatusCreated
+		if previous != nil {
+			eventType, status = model.EventUpdated, http.StatusOK
+		}
+
+		if isDryRun(c) {
+			c.JSON(status, model.APIResponse{
+				Status:  "success",
+				Message: "Announcement would be rolled back (dry run)",
+				Data: model.Event{
+					Type:         eventType,
+					Announcement: restored,
+					Previous:     previous,
+				},
+			})
+			return
+		}
+
+		newValue, err := json.Marshal(restored)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := db.Put(key, string(newValue)); err != nil {
+			c.JSON(writeErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to roll back announcement: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+		refreshResourceVersion(db, key, &restored)
+		changes.record(c, previous, &restored)
+
+		c.JSON(status, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement rolled back successfully",
+			Data: model.Event{
+				Type:         eventType,
+				Announcement: restored,
+				Previous:     previous,
+			},
+		})
+	})
+}
@@ -0,0 +1,193 @@
+package apiserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+const (
+	idempotencyPrefix          = "v1/idempotency/" // idempotencyPrefix is the key prefix of the stored responses of idempotent requests.
+	idempotencyTTL             = 24 * time.Hour    // idempotencyTTL is how long the response of an idempotent request is replayed.
+	idempotencyCollectInterval = 10 * time.Minute  // idempotencyCollectInterval is the interval at which expired responses are deleted.
+	maxIdempotencyKeyLength    = 255               // maxIdempotencyKeyLength is the maximum length of an Idempotency-Key header.
+)
+
+// idempotencyRecord is the stored response of a POST request with an Idempotency-Key header.
+type idempotencyRecord struct {
+	Fingerprint string          `json:"fingerprint"` // Fingerprint is the hash of the URL and the body of the request.
+	Status      int             `json:"status"`      // Status is the status code of the response.
+	Body        json.RawMessage `json:"body"`        // Body is the JSON body of the response.
+	Expires     time.Time       `json:"expires"`     // Expires is the time after which the record is no longer replayed.
+}
+
+// idempotency makes the POST requests with an Idempotency-Key header safe to retry: the response of the first
+// request is stored and replayed to the requests of the same subject with the same key, so that, e.g., a create
+// retried after a lost response does not fail with a conflict. Reusing a key for a different request is rejected.
+// Server errors are not stored, so that the request can be retried, and neither are dry runs.
+func idempotency(db model.DatabaseAdapter, clk clock.Clock) gin.HandlerFunc {
+	var inProgress sync.Map
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if c.Request.Method != http.MethodPost || key == "" || isDryRun(c) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "Idempotency-Key must not be longer than 255 characters",
+				Data:    nil,
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "failed to read request body",
+				Data:    nil,
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The keys are scoped to the authenticated subject, so that clients cannot replay the responses of each other,
+		// while a client that rotated its token still gets its responses replayed
+		recordKey := idempotencyPrefix + sha256Hex([]byte(subjectKey(c)+"\n"+key))
+		fingerprint := sha256Hex(append([]byte(c.Request.URL.RequestURI()+"\n"), body...))
+
+		if _, loaded := inProgress.LoadOrStore(recordKey, struct{}{}); loaded {
+			c.AbortWithStatusJSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: "a request with the same Idempotency-Key is in progress",
+				Data:    nil,
+			})
+			return
+		}
+		defer inProgress.Delete(recordKey)
+
+		value, err := db.Get(recordKey)
+		if err != nil && err.Error() != "key not found" {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		if err == nil {
+			var record idempotencyRecord
+			if err := json.Unmarshal([]byte(value), &record); err == nil && clk.Now().Before(record.Expires) {
+				if record.Fingerprint != fingerprint {
+					c.AbortWithStatusJSON(http.StatusUnprocessableEntity, model.APIResponse{
+						Status:  "error",
+						Message: "Idempotency-Key was already used for a different request",
+						Data:    nil,
+					})
+					return
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.Status, "application/json; charset=utf-8", record.Body)
+				c.Abort()
+				return
+			}
+		}
+
+		writer := &recordingResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if writer.Status() >= http.StatusInternalServerError || !json.Valid(writer.body.Bytes()) {
+			return
+		}
+		record, err := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      writer.Status(),
+			Body:        writer.body.Bytes(),
+			Expires:     clk.Now().Add(idempotencyTTL),
+		})
+		if err == nil {
+			err = db.Put(recordKey, string(record))
+		}
+		if err != nil {
+			slog.Error("failed to store idempotent response", "path", c.Request.URL.Path, "error", err)
+		}
+	}
+}
+
+// recordingResponseWriter keeps a copy of the response body.
+type recordingResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes the data to the response and the copy.
+func (w *recordingResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the string to the response and the copy.
+func (w *recordingResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// sha256Hex returns the hex encoded SHA-256 hash of the data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// runIdempotencyCollector periodically deletes the expired idempotent responses until stopChan is closed.
+func runIdempotencyCollector(db model.DatabaseAdapter, clk clock.Clock, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(idempotencyCollectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			if err := collectIdempotencyRecords(db, clk); err != nil {
+				slog.Error("failed to collect expired idempotent responses", "error", err)
+			}
+		}
+	}
+}
+
+// collectIdempotencyRecords deletes the idempotent responses that are no longer replayed.
+func collectIdempotencyRecords(db model.DatabaseAdapter, clk clock.Clock) error {
+	keys, err := db.List(idempotencyPrefix)
+	if err != nil {
+		return err
+	}
+
+	now := clk.Now()
+	for _, key := range keys {
+		value, err := db.Get(key)
+		if err != nil {
+			continue
+		}
+		var record idempotencyRecord
+		if err := json.Unmarshal([]byte(value), &record); err == nil && now.Before(record.Expires) {
+			continue
+		}
+		if err := db.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
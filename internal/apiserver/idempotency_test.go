@@ -0,0 +1,127 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// TestIdempotency checks the replay of the responses of the POST requests with an Idempotency-Key header.
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := NewMemoryStore()
+	defer db.Close()
+	clk := clock.NewFakeClock(time.Unix(0, 0))
+
+	calls, status := 0, http.StatusCreated
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(actorContextKey, c.GetHeader("X-Subject"))
+	}, idempotency(db, clk))
+	router.POST("/items", func(c *gin.Context) {
+		calls++
+		c.JSON(status, gin.H{"call": calls})
+	})
+	router.GET("/items", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"call": calls})
+	})
+
+	send := func(method, subject, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/items", strings.NewReader(body))
+		req.Header.Set("X-Subject", subject)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		subject    string
+		key        string
+		body       string
+		advance    time.Duration
+		status     int
+		wantCode   int
+		wantCalls  int
+		wantReplay bool
+	}{
+		{name: "first request", method: http.MethodPost, subject: "a", key: "k1", body: `{"n":1}`, wantCode: http.StatusCreated, wantCalls: 1},
+		{name: "retry replayed", method: http.MethodPost, subject: "a", key: "k1", body: `{"n":1}`, status: http.StatusConflict, wantCode: http.StatusCreated, wantCalls: 1, wantReplay: true},
+		{name: "key reused for another request", method: http.MethodPost, subject: "a", key: "k1", body: `{"n":2}`, wantCode: http.StatusUnprocessableEntity, wantCalls: 1},
+		{name: "key of another subject", method: http.MethodPost, subject: "b", key: "k1", body: `{"n":1}`, status: http.StatusConflict, wantCode: http.StatusConflict, wantCalls: 2},
+		{name: "without a key", method: http.MethodPost, subject: "a", body: `{"n":1}`, status: http.StatusConflict, wantCode: http.StatusConflict, wantCalls: 3},
+		{name: "GET not recorded", method: http.MethodGet, subject: "a", key: "k2", wantCode: http.StatusOK, wantCalls: 4},
+		{name: "server error not recorded", method: http.MethodPost, subject: "a", key: "k3", status: http.StatusServiceUnavailable, wantCode: http.StatusServiceUnavailable, wantCalls: 5},
+		{name: "retry after a server error", method: http.MethodPost, subject: "a", key: "k3", wantCode: http.StatusCreated, wantCalls: 6},
+		{name: "key too long", method: http.MethodPost, subject: "a", key: strings.Repeat("k", maxIdempotencyKeyLength+1), wantCode: http.StatusBadRequest, wantCalls: 6},
+		{name: "expired response not replayed", method: http.MethodPost, subject: "a", key: "k1", body: `{"n":1}`, advance: idempotencyTTL, status: http.StatusConflict, wantCode: http.StatusConflict, wantCalls: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk.Advance(tt.advance)
+			status = http.StatusCreated
+			if tt.status != 0 {
+				status = tt.status
+			}
+
+			recorder := send(tt.method, tt.subject, tt.key, tt.body)
+			if recorder.Code != tt.wantCode {
+				t.Fatalf("answered %d: %s, want %d", recorder.Code, recorder.Body, tt.wantCode)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("handler called %d times, want %d", calls, tt.wantCalls)
+			}
+			if replayed := recorder.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.wantReplay {
+				t.Fatalf("replayed = %t, want %t", replayed, tt.wantReplay)
+			}
+		})
+	}
+}
+
+// TestIdempotencyCollector checks that the expired responses are deleted and the others kept.
+func TestIdempotencyCollector(t *testing.T) {
+	db := NewMemoryStore()
+	defer db.Close()
+	clk := clock.NewFakeClock(time.Unix(0, 0))
+	for key, expires := range map[string]time.Time{"old": clk.Now(), "new": clk.Now().Add(time.Hour)} {
+		if err := db.Put(idempotencyPrefix+key, `{"expires":"`+expires.Format(time.RFC3339)+`"}`); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := collectIdempotencyRecords(db, clk); err != nil {
+		t.Fatalf("collectIdempotencyRecords: %v", err)
+	}
+	if _, err := db.Get(idempotencyPrefix + "old"); err == nil {
+		t.Error("expired response was not deleted")
+	}
+	if _, err := db.Get(idempotencyPrefix + "new"); err != nil {
+		t.Error("response still replayed was deleted")
+	}
+}
+
+// TestIdempotentCreate checks that a create retried with the same Idempotency-Key is answered like the first one
+// instead of failing with a conflict.
+func TestIdempotentCreate(t *testing.T) {
+	server := newTestServer(t, nil)
+	announcement := testAnnouncement("alpha", "web", "192.0.2.1")
+
+	for i := range 2 {
+		if code, response := server.do(t, http.MethodPost, "/v1/announcements/", announcement, "Idempotency-Key", "create-web"); code != http.StatusCreated {
+			t.Fatalf("attempt %d answered %d: %s", i, code, response.Message)
+		}
+	}
+	if code, _ := server.do(t, http.MethodPost, "/v1/announcements/", announcement); code != http.StatusConflict {
+		t.Fatalf("create without the key answered %d, want %d", code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,82 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+const (
+	announcementsPrefix = "v1/announcements/"   // announcementsPrefix is the key prefix under which announcements are stored.
+	nextHopIndexPrefix  = "v1/index/next-hops/" // nextHopIndexPrefix is the key prefix of the secondary index by next-hop address.
+)
+
+// isAnnouncementKey reports whether the key belongs to the announcements keyspace.
+func isAnnouncementKey(key string) bool {
+	return strings.HasPrefix(key, announcementsPrefix)
+}
+
+// nextHopIndexKey builds the index key that links the next-hop address with the announcement key.
+func nextHopIndexKey(nextHop, announcementKey string) string {
+	return nextHopIndexPrefix + nextHop + "/" + strings.TrimPrefix(announcementKey, announcementsPrefix)
+}
+
+// nextHopIndexKeys returns the list of index keys for every next hop of the serialized announcement.
+// An empty or malformed value produces no index keys.
+func nextHopIndexKeys(announcementKey, value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var announcement model.Announcement
+	if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(announcement.NextHops))
+	seen := make(map[string]struct{}, len(announcement.NextHops))
+	for _, nextHop := range announcement.NextHops {
+		if nextHop.IP == "" {
+			continue
+		}
+		if _, ok := seen[nextHop.IP]; ok {
+			continue
+		}
+		seen[nextHop.IP] = struct{}{}
+		keys = append(keys, nextHopIndexKey(nextHop.IP, announcementKey))
+	}
+	for _, nextHop := range announcement.WeightedNextHops {
+		if nextHop.Address == "" {
+			continue
+		}
+		if _, ok := seen[nextHop.Address]; ok {
+			continue
+		}
+		seen[nextHop.Address] = struct{}{}
+		keys = append(keys, nextHopIndexKey(nextHop.Address, announcementKey))
+	}
+	return keys
+}
+
+// diffIndexKeys returns the index keys that must be removed and the ones that must be added
+// to move the index from the old set of keys to the new one.
+func diffIndexKeys(oldKeys, newKeys []string) (stale, fresh []string) {
+	oldSet := make(map[string]struct{}, len(oldKeys))
+	for _, key := range oldKeys {
+		oldSet[key] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(newKeys))
+	for _, key := range newKeys {
+		newSet[key] = struct{}{}
+		if _, ok := oldSet[key]; !ok {
+			fresh = append(fresh, key)
+		}
+	}
+	for _, key := range oldKeys {
+		if _, ok := newSet[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+	return stale, fresh
+}
@@ -0,0 +1,156 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// TestNextHopIndexConsistency checks that every backend keeps the next-hop index in line with the announcements
+// through a create, an update that replaces next hops, a batch and a delete.
+func TestNextHopIndexConsistency(t *testing.T) {
+	backends := map[string]func(t *testing.T) model.DatabaseAdapter{
+		"memory": func(t *testing.T) model.DatabaseAdapter {
+			return NewMemoryStore()
+		},
+		"bolt": func(t *testing.T) model.DatabaseAdapter {
+			db, err := NewBoltStore(filepath.Join(t.TempDir(), "corebgp.db"))
+			if err != nil {
+				t.Fatalf("NewBoltStore: %v", err)
+			}
+			return db
+		},
+		"etcd": func(t *testing.T) model.DatabaseAdapter {
+			return newTestEtcdClient(t)
+		},
+		"etcd with key prefix": func(t *testing.T) model.DatabaseAdapter {
+			return newTestEtcdClient(t, WithEtcdKeyPrefix("/tenant"))
+		},
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			defer db.Close()
+
+			const key = announcementsPrefix + "project/web"
+			put := func(nextHops []model.Subnet, weighted []model.WeightedNextHop) {
+				t.Helper()
+				data, _ := json.Marshal(model.Announcement{
+					Meta:             model.Meta{Name: "web", Project: "project"},
+					NextHops:         nextHops,
+					WeightedNextHops: weighted,
+				})
+				if err := db.Put(key, string(data)); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+			}
+
+			put([]model.Subnet{{IP: "10.0.0.1", Mask: 32}, {IP: "10.0.0.2", Mask: 32}}, nil)
+			expectIndexed(t, db, "10.0.0.1", 1)
+			expectIndexed(t, db, "10.0.0.2", 1)
+			expectIndexKeys(t, db, nextHopIndexKey("10.0.0.1", key), nextHopIndexKey("10.0.0.2", key))
+
+			// The update drops 10.0.0.1 and adds a weighted next hop, the duplicate of 10.0.0.2 is indexed once
+			put([]model.Subnet{{IP: "10.0.0.2", Mask: 32}}, []model.WeightedNextHop{{Address: "10.0.0.2", Weight: 1}, {Address: "10.0.0.3", Weight: 1}})
+			expectIndexed(t, db, "10.0.0.1", 0)
+			expectIndexed(t, db, "10.0.0.2", 1)
+			expectIndexed(t, db, "10.0.0.3", 1)
+			expectIndexKeys(t, db, nextHopIndexKey("10.0.0.2", key), nextHopIndexKey("10.0.0.3", key))
+
+			// The batch moves 10.0.0.3 to another announcement and deletes the first one in the same write
+			const other = announcementsPrefix + "project/api"
+			data, _ := json.Marshal(model.Announcement{
+				Meta:     model.Meta{Name: "api", Project: "project"},
+				NextHops: []model.Subnet{{IP: "10.0.0.3", Mask: 32}},
+			})
+			if err := db.Batch([]model.BatchWrite{{Key: other, Value: string(data)}, {Key: key, Delete: true}}); err != nil {
+				t.Fatalf("Batch: %v", err)
+			}
+			expectIndexed(t, db, "10.0.0.2", 0)
+			expectIndexed(t, db, "10.0.0.3", 1)
+			expectIndexKeys(t, db, nextHopIndexKey("10.0.0.3", other))
+
+			if err := db.Delete(other); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			expectIndexed(t, db, "10.0.0.3", 0)
+			expectIndexKeys(t, db)
+		})
+	}
+}
+
+// expectIndexed checks the number of announcements the lookup by the next hop returns.
+func expectIndexed(t *testing.T, db model.DatabaseAdapter, nextHop string, want int) {
+	t.Helper()
+	values, err := db.ListByNextHop(nextHop)
+	if err != nil {
+		t.Fatalf("ListByNextHop(%q): %v", nextHop, err)
+	}
+	if len(values) != want {
+		t.Fatalf("ListByNextHop(%q) returned %d announcements, want %d", nextHop, len(values), want)
+	}
+}
+
+// expectIndexKeys checks that the index holds exactly the keys, so that no stale entry is left behind.
+func expectIndexKeys(t *testing.T, db model.DatabaseAdapter, want ...string) {
+	t.Helper()
+	keys, err := db.List(nextHopIndexPrefix)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	slices.Sort(want)
+	if !slices.Equal(keys, want) && !(len(keys) == 0 && len(want) == 0) {
+		t.Fatalf("index keys = %v, want %v", keys, want)
+	}
+}
+
+// newTestEtcdClient starts a single-member etcd server in the test directory and returns a client connected to it.
+// The client and the server are stopped when the test completes.
+func newTestEtcdClient(t *testing.T, opts ...EtcdOption) *EtcdClient {
+	t.Helper()
+	config := embed.NewConfig()
+	config.Dir = t.TempDir()
+	config.LogLevel = "error"
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	config.ListenClientUrls, config.AdvertiseClientUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	config.ListenPeerUrls, config.AdvertisePeerUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	config.InitialCluster = config.InitialClusterFromName(config.Name)
+
+	server, err := embed.StartEtcd(config)
+	if err != nil {
+		t.Fatalf("StartEtcd: %v", err)
+	}
+	t.Cleanup(server.Close)
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd did not start")
+	}
+
+	client, err := NewEtcdClient([]string{clientURL.String()}, "", "", "", clock.RealClock{}, opts...)
+	if err != nil {
+		t.Fatalf("NewEtcdClient: %v", err)
+	}
+	return client
+}
+
+// freeURL returns an HTTP URL of a free port of the loopback interface.
+func freeURL(t *testing.T) url.URL {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return url.URL{Scheme: "http", Host: fmt.Sprintf("127.0.0.1:%d", listener.Addr().(*net.TCPAddr).Port)}
+}
@@ -0,0 +1,310 @@
+package apiserver
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// memoryWatchHistory is the number of changes kept to resume the watches of the MemoryStore. Watches resuming from an
+// older revision get a resync event.
+const memoryWatchHistory = 10000
+
+// MemoryStore is the database adapter keeping all keys in memory, for tests and demonstrations; the data is lost when
+// the process exits. Like the other adapters, it keeps a revision increased by every write, so resource versions and
+// resumable watches work the same with all backends.
+type MemoryStore struct {
+	mu        sync.Mutex             // mu guards all fields.
+	keys      map[string]memoryValue // keys holds the stored values by key.
+	revision  int64                  // revision is the revision of the last write.
+	history   []model.WatchEvent     // history holds the latest changes in revision order.
+	compacted int64                  // compacted is the latest revision whose changes are no longer in the history.
+	notify    chan struct{}          // notify is closed and replaced after every write to wake up the watches.
+}
+
+// memoryValue is a value of the MemoryStore with the revision of its last modification.
+type memoryValue struct {
+	value       string
+	modRevision int64
+}
+
+// NewMemoryStore creates an empty in-memory database.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys:   make(map[string]memoryValue),
+		notify: make(chan struct{}),
+	}
+}
+
+// Close does nothing, the data is kept until the store is garbage collected.
+func (m *MemoryStore) Close() {}
+
+// HealthCheck always succeeds.
+func (m *MemoryStore) HealthCheck() error {
+	return nil
+}
+
+func (m *MemoryStore) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.keys[key]
+	if !ok {
+		return "", fmt.Errorf("key not found")
+	}
+	return withResourceVersion(key, stored.value, stored.modRevision), nil
+}
+
+// sortedKeys returns the keys under the prefix that are not less than startKey, in order. The caller must hold mu.
+func (m *MemoryStore) sortedKeys(prefix, startKey string) []string {
+	var keys []string
+	for key := range m.keys {
+		if strings.HasPrefix(key, prefix) && key >= startKey {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (m *MemoryStore) List(prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := m.sortedKeys(prefix, "")
+	if keys == nil {
+		keys = []string{}
+	}
+	return keys, nil
+}
+
+func (m *MemoryStore) GetObjects(prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := []string{}
+	for _, key := range m.sortedKeys(prefix, "") {
+		stored := m.keys[key]
+		values = append(values, withResourceVersion(key, stored.value, stored.modRevision))
+	}
+	return values, nil
+}
+
+// GetObjectsPage returns up to limit values stored under the prefix, starting from startKey (inclusive).
+// The second return value is the key to start the next page from, or an empty string if there are no more keys.
+func (m *MemoryStore) GetObjectsPage(prefix, startKey string, limit int64) ([]string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := []string{}
+	keys := m.sortedKeys(prefix, startKey)
+	var next string
+	if limit > 0 && int64(len(keys)) > limit {
+		// The smallest key greater than the last returned one
+		keys, next = keys[:limit], keys[limit-1]+"\x00"
+	}
+	for _, key := range keys {
+		stored := m.keys[key]
+		values = append(values, withResourceVersion(key, stored.value, stored.modRevision))
+	}
+	return values, next, nil
+}
+
+// ListByNextHop returns the serialized announcements that use the specified next-hop address.
+// The lookup is served from the secondary next-hop index maintained on every announcement write.
+func (m *MemoryStore) ListByNextHop(nextHop string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := []string{}
+	for _, indexKey := range m.sortedKeys(nextHopIndexPrefix+nextHop+"/", "") {
+		announcementKey := m.keys[indexKey].value
+		if stored, ok := m.keys[announcementKey]; ok {
+			values = append(values, withResourceVersion(announcementKey, stored.value, stored.modRevision))
+		}
+	}
+	return values, nil
+}
+
+func (m *MemoryStore) Put(key, value string) error {
+	if err := m.write([]model.BatchWrite{{Key: key, Value: value}}); err != nil {
+		return fmt.Errorf("failed to put data to memory: %w", err)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Patch(key, value string) error {
+	if err := m.write([]model.BatchWrite{{Key: key, Value: value}}); err != nil {
+		return fmt.Errorf("failed to patch data to memory: %w", err)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	if err := m.write([]model.BatchWrite{{Key: key, Delete: true}}); err != nil {
+		return fmt.Errorf("failed to delete data from memory: %w", err)
+	}
+	return nil
+}
+
+// Batch applies all writes at once, so that either all of them or none are stored. The next-hop index is updated
+// together with them.
+func (m *MemoryStore) Batch(writes []model.BatchWrite) error {
+	if err := m.write(writes); err != nil {
+		return fmt.Errorf("failed to apply batch to memory: %w", err)
+	}
+	return nil
+}
+
+// write applies the writes and the resulting next-hop index changes at the next revision and passes the changes to
+// the watches. The writes are applied to a copy of the changed keys first, so that a failing write leaves the store
+// unchanged. Announcements carrying a resource version are written only if they were not modified since that version,
+// otherwise model.ErrResourceVersionConflict is returned.
+func (m *MemoryStore) write(writes []model.BatchWrite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revision := m.revision + 1
+	changed := make(map[string]*memoryValue) // changed holds the new values of the written keys, nil for deleted keys.
+	lookup := func(key string) (memoryValue, bool) {
+		if value, ok := changed[key]; ok {
+			if value == nil {
+				return memoryValue{}, false
+			}
+			return *value, true
+		}
+		value, ok := m.keys[key]
+		return value, ok
+	}
+
+	// put stores the value of the key, or deletes the key if deleteKey is set, and records the change
+	var events []model.WatchEvent
+	put := func(key, value string, deleteKey bool) {
+		prev, existed := lookup(key)
+		event := model.WatchEvent{Type: model.WatchEventPut, Key: key, Value: value, PrevValue: prev.value, ModRevision: revision, Created: !existed}
+		if deleteKey {
+			if !existed {
+				return
+			}
+			event.Type, event.Value, event.Created = model.WatchEventDelete, "", false
+			changed[key] = nil
+		} else {
+			changed[key] = &memoryValue{value: value, modRevision: revision}
+		}
+		events = append(events, event)
+	}
+
+	for _, write := range writes {
+		var expectedRevision int64
+		if isAnnouncementKey(write.Key) && !write.Delete {
+			var err error
+			if expectedRevision, write.Value, err = splitResourceVersion(write.Value); err != nil {
+				return err
+			}
+		}
+
+		prev, _ := lookup(write.Key)
+		if expectedRevision != 0 && expectedRevision != prev.modRevision {
+			return fmt.Errorf("%w: %s was modified at revision %d", model.ErrResourceVersionConflict, write.Key, prev.modRevision)
+		}
+		put(write.Key, write.Value, write.Delete)
+
+		if !isAnnouncementKey(write.Key) {
+			continue
+		}
+
+		var newIndexKeys []string
+		if !write.Delete {
+			newIndexKeys = nextHopIndexKeys(write.Key, write.Value)
+		}
+		stale, fresh := diffIndexKeys(nextHopIndexKeys(write.Key, prev.value), newIndexKeys)
+		for _, indexKey := range stale {
+			put(indexKey, "", true)
+		}
+		for _, indexKey := range fresh {
+			put(indexKey, write.Key, false)
+		}
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+	for key, value := range changed {
+		if value == nil {
+			delete(m.keys, key)
+		} else {
+			m.keys[key] = *value
+		}
+	}
+
+	m.revision = revision
+	m.history = append(m.history, events...)
+	if excess := len(m.history) - memoryWatchHistory; excess > 0 {
+		// Drop whole revisions only, so that a watch never gets part of the changes of a revision
+		for excess < len(m.history) && m.history[excess].ModRevision == m.history[excess-1].ModRevision {
+			excess++
+		}
+		m.compacted = m.history[excess-1].ModRevision
+		m.history = append([]model.WatchEvent(nil), m.history[excess:]...)
+	}
+	close(m.notify)
+	m.notify = make(chan struct{})
+	return nil
+}
+
+// Watch streams the changes of the keys under the prefix through a channel until the stop signal is received.
+// When revision is greater than zero the watch resumes right after that revision, otherwise it starts from the
+// current state. If the changes after that revision are no longer kept, a resync response (with CompactRevision
+// set) is sent first and the watch continues from the current revision. A response is sent at the start and after
+// every write, also without changes of the watched keys, to report the progress of the watch.
+func (m *MemoryStore) Watch(prefix string, revision int64, stopChan <-chan struct{}) (<-chan model.WatchResponse, error) {
+	events := make(chan model.WatchResponse)
+
+	go func() {
+		defer close(events)
+
+		m.mu.Lock()
+		last := revision
+		if last <= 0 {
+			last = m.revision
+		}
+		m.mu.Unlock()
+
+		for {
+			m.mu.Lock()
+			resp := model.WatchResponse{Revision: m.revision}
+			if last < m.compacted {
+				resp.CompactRevision = m.compacted
+			} else {
+				first := sort.Search(len(m.history), func(i int) bool { return m.history[i].ModRevision > last })
+				for _, event := range m.history[first:] {
+					if strings.HasPrefix(event.Key, prefix) {
+						resp.Events = append(resp.Events, event)
+					}
+				}
+			}
+			last = m.revision
+			notify := m.notify
+			m.mu.Unlock()
+
+			// A response without changes of the watched keys reports the progress of the watch
+			select {
+			case events <- resp:
+			case <-stopChan:
+				return
+			}
+
+			select {
+			case <-notify:
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,180 @@
+package apiserver
+
+import (
+	"context"
+	"io"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+)
+
+// sizeBuckets spans from 64 bytes to 64 MB, growing by a factor of 4.
+var sizeBuckets = prometheus.ExponentialBuckets(64, 4, 11)
+
+var (
+	// requestSize is the distribution of request body sizes per endpoint and method.
+	requestSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "corebgp_request_size_bytes",
+		Help:    "Size of HTTP request bodies in bytes.",
+		Buckets: sizeBuckets,
+	}, []string{"endpoint", "method"})
+
+	// responseSize is the distribution of response body sizes per endpoint and method.
+	responseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "corebgp_response_size_bytes",
+		Help:    "Size of HTTP response bodies in bytes.",
+		Buckets: sizeBuckets,
+	}, []string{"endpoint", "method"})
+
+	// requestDuration is the distribution of request latencies per endpoint, method and status code.
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "corebgp_request_duration_seconds",
+		Help:    "Latency of HTTP requests in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "method", "code"})
+
+	// watchConnections is the number of open watch connections.
+	watchConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "corebgp_watch_connections",
+		Help: "Number of open announcement watch connections.",
+	})
+
+	// etcdDuration is the distribution of etcd request latencies per operation.
+	etcdDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "corebgp_etcd_operation_duration_seconds",
+		Help:    "Latency of etcd operations in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+
+	// webhookDeliveries is the number of webhook delivery attempts per webhook and result.
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "corebgp_webhook_deliveries_total",
+		Help: "Number of webhook delivery attempts.",
+	}, []string{"webhook", "result"})
+
+	// webhookDeadLetters is the number of webhook events given up on per webhook and reason.
+	webhookDeadLetters = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "corebgp_webhook_dead_letters_total",
+		Help: "Number of webhook events that could not be delivered.",
+	}, []string{"webhook", "reason"})
+
+	// admissionReviews is the number of admission webhook reviews per webhook and result.
+	admissionReviews = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "corebgp_admission_webhook_reviews_total",
+		Help: "Number of announcement changes reviewed by the admission webhooks.",
+	}, []string{"webhook", "result"})
+
+	// admissionDuration is the time the admission webhooks take to review a change.
+	admissionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "corebgp_admission_webhook_duration_seconds",
+		Help:    "Duration of the admission webhook calls in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"webhook"})
+
+	// propagationDuration is the time from the write of an announcement generation until it is programmed on all
+	// reported routers.
+	propagationDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "corebgp_announcement_propagation_seconds",
+		Help:    "Time from the write of an announcement change until it is programmed on all routers in seconds.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	})
+
+	// rejectedRequests is the number of requests rejected with 429 Too Many Requests per reason.
+	rejectedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "corebgp_rejected_requests_total",
+		Help: "Number of requests rejected by the rate or inflight limits.",
+	}, []string{"reason"})
+
+	// inflightRequests is the number of requests counted against the inflight limit.
+	inflightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "corebgp_inflight_requests",
+		Help: "Number of requests being served, watches excluded.",
+	})
+)
+
+// countingReader counts the bytes read from the underlying request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+// Read reads from the underlying body and adds the number of read bytes to the counter.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// durationMetrics records the latency of every request. Watch requests, over a WebSocket or as an event stream, are
+// skipped, as they last for the lifetime of the connection and are counted by the watch connection gauge instead.
+func durationMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.IsWebsocket() || acceptsEventStream(c) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = "unmatched"
+		}
+		requestDuration.WithLabelValues(endpoint, c.Request.Method, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
+// etcdMetricsInterceptor records the latency and the result of every unary etcd request. The operation is the name
+// of the gRPC method, e.g., "Range" or "Txn".
+func etcdMetricsInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	etcdDuration.WithLabelValues(path.Base(method), result).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// sizeMetrics records the request and response body sizes of every request. The declared Content-Length is used
+// when available, otherwise the number of bytes actually read by the handler.
+func sizeMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body *countingReader
+		if c.Request.Body != nil {
+			body = &countingReader{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+		}
+
+		c.Next()
+
+		// Use the route template as the label to keep the cardinality bounded
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = "unmatched"
+		}
+
+		size := c.Request.ContentLength
+		if size < 0 && body != nil {
+			size = body.n
+		}
+		if size < 0 {
+			size = 0
+		}
+		requestSize.WithLabelValues(endpoint, c.Request.Method).Observe(float64(size))
+
+		written := c.Writer.Size()
+		if written < 0 {
+			written = 0
+		}
+		responseSize.WithLabelValues(endpoint, c.Request.Method).Observe(float64(written))
+	}
+}
@@ -0,0 +1,597 @@
+package apiserver
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// swaggerUI is the page rendering the OpenAPI document with Swagger UI.
+//
+//go:embed swagger-ui.html
+var swaggerUI []byte
+
+// openAPIParam is a query or header parameter of an operation.
+type openAPIParam struct {
+	in          string // in is the location of the parameter: query or header.
+	name        string // name is the name of the parameter.
+	schemaType  string // schemaType is the JSON schema type of the value, e.g., integer.
+	description string // description explains the parameter.
+	required    bool   // required marks parameters without which the request is rejected.
+}
+
+// openAPIOneOf lists the alternative types of a response that depends on the request.
+type openAPIOneOf []interface{}
+
+// openAPIOperation describes a v1 route in the OpenAPI document. The body types are given as values, whose JSON
+// schemas are derived from the model types.
+type openAPIOperation struct {
+	id       string         // id is the operationId, the name of the method of generated clients.
+	tag      string         // tag groups the operations in the document.
+	summary  string         // summary is the one-line description of the operation.
+	params   []openAPIParam // params lists the query and header parameters; the path parameters are taken from the route.
+	request  interface{}    // request is a value of the body type; nil for requests without a body.
+	status   int            // status is the status code of a successful response; zero means 200.
+	response interface{}    // response is a value of the type of the data field of the response envelope.
+	stream   bool           // stream marks responses streaming events rather than a single envelope.
+	raw      bool           // raw marks responses sent without the envelope, in JSON or YAML.
+}
+
+// Parameters shared by several operations.
+var (
+	dryRunParam           = openAPIParam{in: "query", name: "dryRun", schemaType: "boolean", description: "Perform all checks and return the resulting change without persisting it"}
+	includeWithdrawnParam = openAPIParam{in: "query", name: "includeWithdrawn", schemaType: "boolean", description: "Include the soft-deleted announcements"}
+	namePrefixParam       = openAPIParam{in: "query", name: "namePrefix", schemaType: "string", description: "Select only the announcements whose names start with the prefix"}
+	limitParam            = openAPIParam{in: "query", name: "limit", schemaType: "integer", description: "Maximum number of items of the page"}
+	continueParam         = openAPIParam{in: "query", name: "continue", schemaType: "string", description: "Token of the next page returned by the previous one"}
+	labelSelectorParam    = openAPIParam{in: "query", name: "labelSelector", schemaType: "string", description: "Select only the announcements whose labels meet the requirements, e.g., env=staging,tier in (web,db)"}
+	ifMatchParam          = openAPIParam{in: "header", name: "If-Match", schemaType: "string", description: "Resource version the change is conditional on"}
+	idempotencyKeyParam   = openAPIParam{in: "header", name: "Idempotency-Key", schemaType: "string", description: "Key under which the response is stored and replayed to retries of the request for 24 hours"}
+)
+
+// openAPIOperations describes the v1 routes, keyed by the method and the path of the route. Routes missing from it
+// are still listed in the document, with a response of any data.
+var openAPIOperations = map[string]openAPIOperation{
+	"GET /v1/announcements": {
+		id: "listAnnouncementsByNextHop", tag: "announcements", summary: "List the announcements routed via a next hop",
+		params:   []openAPIParam{{in: "query", name: "nextHop", schemaType: "string", description: "IP address of the next hop", required: true}, includeWithdrawnParam, labelSelectorParam},
+		response: []model.Announcement{},
+	},
+	"GET /v1/announcements/": {
+		id: "listAnnouncementsByProject", tag: "announcements", summary: "List the announcements of all projects grouped by project",
+		params:   []openAPIParam{includeWithdrawnParam, labelSelectorParam},
+		response: map[string][]model.Announcement{},
+	},
+	"GET /v1/announcements/all": {
+		id: "listAllAnnouncements", tag: "announcements", summary: "List the announcements of all projects",
+		params:   []openAPIParam{includeWithdrawnParam, labelSelectorParam},
+		response: []model.Announcement{},
+	},
+	"GET /v1/announcements/:project/": {
+		id: "listAnnouncementKeys", tag: "announcements", summary: "List the keys of the announcements of a project",
+		params:   []openAPIParam{includeWithdrawnParam, labelSelectorParam},
+		response: []string{},
+	},
+	"GET /v1/announcements/:project/all": {
+		id: "listAnnouncements", tag: "announcements", summary: "List the announcements of a project, a single page of them when a limit is given",
+		params:   []openAPIParam{namePrefixParam, labelSelectorParam, limitParam, continueParam, includeWithdrawnParam},
+		response: openAPIOneOf{[]model.Announcement{}, model.AnnouncementList{}},
+	},
+	"GET /v1/announcements/:project/:name": {
+		id: "getAnnouncement", tag: "announcements", summary: "Get an announcement",
+		response: model.Announcement{},
+	},
+	"POST /v1/announcements/": {
+		id: "createAnnouncement", tag: "announcements", summary: "Create an announcement",
+		params:  []openAPIParam{dryRunParam},
+		request: model.Announcement{}, status: http.StatusCreated, response: model.Event{},
+	},
+	"PATCH /v1/announcements/": {
+		id: "updateAnnouncement", tag: "announcements", summary: "Update an announcement, conditionally when the resource version is given",
+		params:  []openAPIParam{dryRunParam, ifMatchParam},
+		request: model.Announcement{}, response: model.Event{},
+	},
+	"DELETE /v1/announcements/:project/": {
+		id: "deleteAnnouncementCollection", tag: "announcements", summary: "Delete the announcements of a project selected by their labels",
+		params:   []openAPIParam{{in: "query", name: "labelSelector", schemaType: "string", description: "Requirements the labels of the deleted announcements meet, e.g., env=staging", required: true}, dryRunParam},
+		response: []model.Event{},
+	},
+	"DELETE /v1/announcements/:project/:name": {
+		id: "deleteAnnouncement", tag: "announcements", summary: "Delete an announcement",
+		params:   []openAPIParam{dryRunParam},
+		response: model.Event{},
+	},
+	"POST /v1/announcements/validate": {
+		id: "validateAnnouncement", tag: "announcements", summary: "Check an announcement against all server-side policies without storing it",
+		request: model.Announcement{}, response: model.ValidationReport{},
+	},
+	"POST /v1/announcements/:project/:name/withdraw": {
+		id: "withdrawAnnouncement", tag: "announcements", summary: "Soft-delete an announcement, which is kept for the retention period",
+		response: model.Event{},
+	},
+	"POST /v1/announcements/:project/:name/cancel": {
+		id: "cancelAnnouncement", tag: "announcements", summary: "Cancel a pending announcement",
+		response: model.Event{},
+	},
+	"POST /v1/announcements/:project/:name/next-hops/:ip/drain": {
+		id: "drainNextHop", tag: "announcements", summary: "Withdraw the paths via a next hop while its health checks continue",
+		response: model.Event{},
+	},
+	"POST /v1/announcements/:project/:name/next-hops/:ip/undrain": {
+		id: "undrainNextHop", tag: "announcements", summary: "Program the paths via a drained next hop again",
+		response: model.Event{},
+	},
+	"POST /v1/announcements/batch": {
+		id: "batchAnnouncements", tag: "announcements", summary: "Apply several announcement changes atomically",
+		request: model.BatchRequest{}, response: []model.BatchItemResult{},
+	},
+	"POST /v1/announcements/:project/query": {
+		id: "queryAnnouncements", tag: "announcements", summary: "Select the announcements of a project with a JMESPath expression",
+		params:  []openAPIParam{includeWithdrawnParam},
+		request: model.AnnouncementQuery{}, response: []model.Announcement{},
+	},
+	"POST /v1/apply": {
+		id: "applyAnnouncement", tag: "announcements", summary: "Apply the fields of an announcement managed by a manager",
+		request: model.ApplyRequest{}, response: model.Event{},
+	},
+	"GET /v1/announcements/:project/:name/status": {
+		id: "getAnnouncementStatus", tag: "status", summary: "Get the status of an announcement",
+		response: model.Status{},
+	},
+	"GET /v1/announcements/:project/:name/health": {
+		id: "getAnnouncementHealth", tag: "status", summary: "Get the health check results of the next hops of an announcement",
+		response: model.AnnouncementHealth{},
+	},
+	"PATCH /v1/announcements/:project/:name/status": {
+		id: "updateAnnouncementStatus", tag: "status", summary: "Update the status of an announcement",
+		request: model.Status{}, response: model.Event{},
+	},
+	"GET /v1/announcements/:project/:name/history": {
+		id: "getAnnouncementHistory", tag: "history", summary: "List the revisions of an announcement from the newest",
+		response: []model.AnnouncementRevision{},
+	},
+	"POST /v1/announcements/:project/:name/rollback": {
+		id: "rollbackAnnouncement", tag: "history", summary: "Restore a previous revision of an announcement",
+		params:  []openAPIParam{dryRunParam, ifMatchParam},
+		request: model.RollbackRequest{}, response: model.Event{},
+	},
+	"GET /v1/announcements/:project/:name/events": {
+		id: "listAnnouncementEvents", tag: "history", summary: "List the events of an announcement from the oldest",
+		response: []model.AnnouncementEvent{},
+	},
+	"GET /v1/watch/announcements/": {
+		id: "watchAnnouncements", tag: "announcements", summary: "Stream the changes of the announcements over a WebSocket or as Server-Sent Events",
+		params: []openAPIParam{
+			{in: "query", name: "revision", schemaType: "integer", description: "Revision to resume the watch from"},
+			{in: "query", name: "project", schemaType: "string", description: "Select only the announcements of the project"},
+			namePrefixParam,
+			labelSelectorParam,
+			{in: "query", name: "types", schemaType: "string", description: "Comma separated list of the selected event types: added, updated and deleted"},
+			{in: "header", name: "Last-Event-ID", schemaType: "string", description: "Revision to resume an event stream from; takes precedence over revision"},
+		},
+		response: model.Event{}, stream: true,
+	},
+	"GET /v1/audit": {
+		id: "listAuditEntries", tag: "audit", summary: "List the audit log entries from the oldest",
+		params: []openAPIParam{
+			{in: "query", name: "project", schemaType: "string", description: "Select only the entries of the project"},
+			{in: "query", name: "since", schemaType: "string", description: "Select only the entries written at or after the time in RFC 3339 format"},
+			{in: "query", name: "until", schemaType: "string", description: "Select only the entries written before the time in RFC 3339 format"},
+			limitParam, continueParam,
+		},
+		response: model.AuditList{},
+	},
+	"GET /v1/export": {
+		id: "exportBundle", tag: "export", summary: "Export the resources of all projects or of a project as a bundle",
+		params: []openAPIParam{
+			{in: "query", name: "project", schemaType: "string", description: "Export only the announcements and the project resource of the project"},
+			{in: "query", name: "format", schemaType: "string", description: "Format of the bundle: json (default) or yaml"},
+		},
+		response: model.Bundle{}, raw: true,
+	},
+	"GET /v1/conflicts": {
+		id: "listConflicts", tag: "conflicts", summary: "List the announcements of different projects whose prefixes equal or overlap",
+		params: []openAPIParam{
+			{in: "query", name: "project", schemaType: "string", description: "Select only the conflicts of the project, whose announcement comes first"},
+		},
+		response: []model.Conflict{},
+	},
+	"POST /v1/import": {
+		id: "importBundle", tag: "export", summary: "Import the resources of a bundle",
+		params: []openAPIParam{
+			{in: "query", name: "strategy", schemaType: "string", description: "Treatment of the existing resources: fail (default), skip or overwrite"},
+			dryRunParam,
+		},
+		request: model.Bundle{}, response: model.ImportReport{},
+	},
+	"GET /v1/projects": {
+		id: "listProjects", tag: "projects", summary: "List the projects",
+		response: []model.Project{},
+	},
+	"GET /v1/projects/:project": {
+		id: "getProject", tag: "projects", summary: "Get a project",
+		response: model.Project{},
+	},
+	"POST /v1/projects": {
+		id: "createProject", tag: "projects", summary: "Create a project",
+		request: model.Project{}, status: http.StatusCreated, response: model.Project{},
+	},
+	"PATCH /v1/projects/:project": {
+		id: "updateProject", tag: "projects", summary: "Update a project",
+		request: model.Project{}, response: model.Project{},
+	},
+	"DELETE /v1/projects/:project": {
+		id: "deleteProject", tag: "projects", summary: "Delete a project",
+		response: model.Project{},
+	},
+	"GET /v1/projects/:project/summary": {
+		id: "getProjectSummary", tag: "projects", summary: "Get the statistics of the announcements of a project",
+		params:   []openAPIParam{includeWithdrawnParam},
+		response: model.ProjectSummary{},
+	},
+	"GET /v1/pools/:project": {
+		id: "listPools", tag: "pools", summary: "List the VIP pools of a project with their allocations",
+		response: []model.Pool{},
+	},
+	"GET /v1/pools/:project/:name": {
+		id: "getPool", tag: "pools", summary: "Get a VIP pool with its allocations",
+		response: model.Pool{},
+	},
+	"POST /v1/pools/:project": {
+		id: "createPool", tag: "pools", summary: "Create a VIP pool",
+		request: model.Pool{}, status: http.StatusCreated, response: model.Pool{},
+	},
+	"PATCH /v1/pools/:project/:name": {
+		id: "updatePool", tag: "pools", summary: "Replace the prefixes of a VIP pool",
+		request: model.Pool{}, response: model.Pool{},
+	},
+	"DELETE /v1/pools/:project/:name": {
+		id: "deletePool", tag: "pools", summary: "Delete a VIP pool without allocations",
+		response: model.Pool{},
+	},
+	"GET /v1/sites": {
+		id: "listSites", tag: "sites", summary: "List the sites with the updaters registered with them",
+		response: []model.Site{},
+	},
+	"GET /v1/sites/:site": {
+		id: "getSite", tag: "sites", summary: "Get a site with the updaters registered with it",
+		response: model.Site{},
+	},
+	"PUT /v1/sites/:site/updaters/:identity": {
+		id: "registerUpdater", tag: "sites", summary: "Register an updater with a site or renew its registration",
+		request: model.SiteUpdater{}, response: model.Site{},
+	},
+	"DELETE /v1/sites/:site/updaters/:identity": {
+		id: "deregisterUpdater", tag: "sites", summary: "Remove the registration of an updater from a site",
+		response: model.Site{},
+	},
+	"GET /v1/gobgp/summary": {
+		id: "getBGPSessionSummary", tag: "gobgp", summary: "Get the summary of the BGP sessions of GoBGP",
+		response: model.BGPSessionSummary{},
+	},
+	"GET /v1/gobgp/global": {
+		id: "getGoBGPGlobalConfig", tag: "gobgp", summary: "Get the global BGP configuration of GoBGP",
+		response: model.GoBGPGlobalConfig{},
+	},
+	"PUT /v1/gobgp/global": {
+		id: "setGoBGPGlobalConfig", tag: "gobgp", summary: "Start GoBGP with the global BGP configuration",
+		request: model.GoBGPGlobalConfig{}, response: model.GoBGPGlobalConfig{},
+	},
+	"GET /v1/rib/announced": {
+		id: "listAnnouncedPaths", tag: "gobgp", summary: "List the paths originated by GoBGP with the announcements requiring them",
+		params:   []openAPIParam{{in: "query", name: "prefix", schemaType: "string", description: "IP address or prefix in CIDR notation the paths must be within"}},
+		response: []model.RIBPath{},
+	},
+	"GET /v1/peers": {
+		id: "listPeers", tag: "peers", summary: "List the BGP peers",
+		response: []model.Peer{},
+	},
+	"GET /v1/peers/:address": {
+		id: "getPeer", tag: "peers", summary: "Get a BGP peer with its session state",
+		response: model.Peer{},
+	},
+	"POST /v1/peers": {
+		id: "createPeer", tag: "peers", summary: "Create a BGP peer",
+		request: model.Peer{}, status: http.StatusCreated, response: model.Peer{},
+	},
+	"PATCH /v1/peers/:address": {
+		id: "updatePeer", tag: "peers", summary: "Update a BGP peer",
+		request: model.Peer{}, response: model.Peer{},
+	},
+	"DELETE /v1/peers/:address": {
+		id: "deletePeer", tag: "peers", summary: "Delete a BGP peer",
+		response: model.Peer{},
+	},
+	"PUT /v1/peers/:address/status": {
+		id: "updatePeerStatus", tag: "peers", summary: "Report the session state of a BGP peer",
+		request: model.PeerStatus{}, response: model.Peer{},
+	},
+}
+
+// openAPIEnums lists the values of the model string types with a fixed set of values.
+var openAPIEnums = map[reflect.Type][]string{
+	reflect.TypeOf(model.EventType("")):       {string(model.EventAdded), string(model.EventUpdated), string(model.EventDeleted), string(model.EventResyncRequired), string(model.EventBookmark)},
+	reflect.TypeOf(model.BGPOrigin("")):       {string(model.OriginIGP), string(model.OriginEGP), string(model.OriginIncomplete)},
+	reflect.TypeOf(model.HealthCheckType("")): {string(model.HealthCheckTCP), string(model.HealthCheckHTTP), string(model.HealthCheckICMP), string(model.HealthCheckGRPC)},
+	reflect.TypeOf(model.BatchAction("")):     {string(model.BatchApply), string(model.BatchDelete)},
+	reflect.TypeOf(model.AuditAction("")):     {string(model.AuditCreate), string(model.AuditUpdate), string(model.AuditDelete)},
+}
+
+// registerOpenAPIRoutes serves the OpenAPI document of the v1 routes registered so far, and the Swagger UI page
+// when it is enabled. Both are served outside the v1 group, so that they need no authentication.
+func registerOpenAPIRoutes(router *gin.Engine, authenticated, swaggerUIEnabled bool) {
+	document, err := json.Marshal(newOpenAPIDocument(router.Routes(), authenticated))
+	if err != nil {
+		panic(err)
+	}
+
+	router.GET("/openapi/v1", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", document)
+	})
+
+	if swaggerUIEnabled {
+		router.GET("/openapi/ui", func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUI)
+		})
+	}
+}
+
+// newOpenAPIDocument builds the OpenAPI 3 document of the v1 routes. The bearer authentication is documented when
+// the API requires it.
+func newOpenAPIDocument(routes gin.RoutesInfo, authenticated bool) gin.H {
+	schemas := openAPISchemas{}
+	paths := gin.H{}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/v1/") {
+			continue
+		}
+
+		path, pathParams := openAPIPath(route.Path)
+		item, ok := paths[path].(gin.H)
+		if !ok {
+			item = gin.H{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = schemas.operation(route.Method, route.Path, pathParams)
+	}
+
+	components := gin.H{
+		"schemas": schemas,
+	}
+	document := gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "CoreBGP API",
+			"version": "v1",
+		},
+		"paths":      paths,
+		"components": components,
+	}
+	if authenticated {
+		components["securitySchemes"] = gin.H{
+			"bearerAuth": gin.H{"type": "http", "scheme": "bearer"},
+		}
+		document["security"] = []gin.H{{"bearerAuth": []string{}}}
+	}
+	return document
+}
+
+// openAPIPath converts the gin path parameters such as :project to the OpenAPI {project} and returns their names.
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// openAPISchemas holds the schemas of the model types referenced by the operations, keyed by the type name.
+type openAPISchemas gin.H
+
+// operation returns the OpenAPI operation of the route.
+func (s openAPISchemas) operation(method, path string, pathParams []string) gin.H {
+	op, ok := openAPIOperations[method+" "+path]
+	if !ok {
+		op = openAPIOperation{id: openAPIOperationID(method, path), tag: "other"}
+	}
+
+	params := make([]gin.H, 0, len(pathParams)+len(op.params)+1)
+	for _, name := range pathParams {
+		params = append(params, gin.H{"in": "path", "name": name, "required": true, "schema": gin.H{"type": "string"}})
+	}
+	// All POST requests are made idempotent by the key
+	opParams := op.params
+	if method == http.MethodPost {
+		opParams = append(slices.Clip(opParams), idempotencyKeyParam)
+	}
+	for _, param := range opParams {
+		params = append(params, gin.H{
+			"in":          param.in,
+			"name":        param.name,
+			"description": param.description,
+			"required":    param.required,
+			"schema":      gin.H{"type": param.schemaType},
+		})
+	}
+
+	var content gin.H
+	if op.stream {
+		content = gin.H{"text/event-stream": gin.H{"schema": s.schema(reflect.TypeOf(op.response))}}
+	} else if op.raw {
+		schema := gin.H{"schema": s.schema(reflect.TypeOf(op.response))}
+		content = gin.H{"application/json": schema, "application/yaml": schema}
+	} else {
+		content = gin.H{"application/json": gin.H{"schema": s.envelope(op.response)}}
+	}
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	operation := gin.H{
+		"tags":        []string{op.tag},
+		"operationId": op.id,
+		"parameters":  params,
+		"responses": gin.H{
+			strconv.Itoa(status): gin.H{"description": http.StatusText(status), "content": content},
+			"default": gin.H{
+				"description": "Error",
+				"content":     gin.H{"application/json": gin.H{"schema": s.errorSchema()}},
+			},
+		},
+	}
+	if op.summary != "" {
+		operation["summary"] = op.summary
+	}
+	if op.request != nil {
+		operation["requestBody"] = gin.H{
+			"required": true,
+			"content":  gin.H{"application/json": gin.H{"schema": s.schema(reflect.TypeOf(op.request))}},
+		}
+	}
+	return operation
+}
+
+// openAPIOperationID derives the identifier of an undescribed operation from the method and the path, e.g.,
+// getAnnouncementsProjectNameStatus.
+func openAPIOperationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		if segment == "" || segment == "v1" {
+			continue
+		}
+		for _, word := range strings.Split(segment, "-") {
+			if word != "" {
+				id += strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+	}
+	return id
+}
+
+// envelope returns the schema of a successful response envelope with the data of the type of the value, or with
+// any data for a nil value.
+func (s openAPISchemas) envelope(data interface{}) gin.H {
+	var dataSchema gin.H
+	switch data := data.(type) {
+	case nil:
+		dataSchema = gin.H{}
+	case openAPIOneOf:
+		alternatives := make([]gin.H, 0, len(data))
+		for _, alternative := range data {
+			alternatives = append(alternatives, s.schema(reflect.TypeOf(alternative)))
+		}
+		dataSchema = gin.H{"oneOf": alternatives}
+	default:
+		dataSchema = s.schema(reflect.TypeOf(data))
+	}
+
+	return gin.H{
+		"type":     "object",
+		"required": []string{"status", "message", "data"},
+		"properties": gin.H{
+			"status":  gin.H{"type": "string", "enum": []string{"success"}},
+			"message": gin.H{"type": "string"},
+			"data":    dataSchema,
+		},
+	}
+}
+
+// errorSchema returns the reference to the schema of an error response envelope. The data of the rejections of an
+// invalid announcement lists the invalid fields.
+func (s openAPISchemas) errorSchema() gin.H {
+	if _, ok := s["Error"]; !ok {
+		s["Error"] = gin.H{
+			"type":     "object",
+			"required": []string{"status", "message"},
+			"properties": gin.H{
+				"status":  gin.H{"type": "string", "enum": []string{"error"}},
+				"message": gin.H{"type": "string"},
+				"data": gin.H{
+					"type":     "array",
+					"nullable": true,
+					"items":    s.schema(reflect.TypeOf(model.ValidationError{})),
+				},
+			},
+		}
+	}
+	return gin.H{"$ref": "#/components/schemas/Error"}
+}
+
+// schema returns the JSON schema of the type as it is encoded by encoding/json. Structs are added to the components
+// and referenced by their name. No field is required, since the same schemas describe the request bodies, in which
+// the server defaults the missing fields.
+func (s openAPISchemas) schema(t reflect.Type) gin.H {
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return gin.H{"type": "string", "format": "date-time"}
+	case reflect.TypeOf(json.RawMessage{}):
+		return gin.H{}
+	}
+	if values, ok := openAPIEnums[t]; ok {
+		return gin.H{"type": "string", "enum": values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return gin.H{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return gin.H{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return gin.H{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Uint64:
+		return gin.H{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		ref := gin.H{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := s[t.Name()]; ok {
+			return ref
+		}
+		// Register the name before the fields, so that recursive types end in a reference
+		s[t.Name()] = gin.H{}
+
+		properties := gin.H{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = s.schema(field.Type)
+		}
+		s[t.Name()] = gin.H{"type": "object", "properties": properties}
+		return ref
+	default:
+		return gin.H{}
+	}
+}
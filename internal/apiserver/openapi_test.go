@@ -0,0 +1,24 @@
+package apiserver
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// TestOpenAPIEventTypes checks that the schema of the watch events lists every event type the server sends.
+func TestOpenAPIEventTypes(t *testing.T) {
+	schemas := openAPISchemas{}
+	schemas.schema(reflect.TypeOf(model.Event{}))
+	property := schemas["Event"].(gin.H)["properties"].(gin.H)["type"].(gin.H)
+	values, _ := property["enum"].([]string)
+
+	for _, eventType := range []model.EventType{model.EventAdded, model.EventUpdated, model.EventDeleted, model.EventResyncRequired, model.EventBookmark} {
+		if !slices.Contains(values, string(eventType)) {
+			t.Errorf("event type %s is missing from the schema enum %v", eventType, values)
+		}
+	}
+}
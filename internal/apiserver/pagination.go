@@ -0,0 +1,107 @@
+package apiserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// maxPageSize is the maximum number of announcements returned in a single page.
+const maxPageSize = 1000
+
+// parsePagination reads the limit and continue query parameters and returns the page size and the key to start from.
+// The continue token must point inside the listed prefix.
+func parsePagination(c *gin.Context, prefix string) (int64, string, error) {
+	limit, err := strconv.ParseInt(c.Query("limit"), 10, 64)
+	if err != nil || limit < 1 {
+		return 0, "", fmt.Errorf("limit must be a positive integer")
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	startKey, err := decodeContinueToken(c.Query("continue"), prefix)
+	if err != nil {
+		return 0, "", err
+	}
+	return limit, startKey, nil
+}
+
+// decodeContinueToken converts the continue token to the key to start from, which must be inside the listed prefix.
+// An empty token starts from the beginning.
+func decodeContinueToken(token, prefix string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+
+	startKey, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(startKey), prefix) {
+		return "", fmt.Errorf("invalid continue token")
+	}
+	return string(startKey), nil
+}
+
+// encodeContinueToken converts the next start key to an opaque continue token.
+func encodeContinueToken(startKey string) string {
+	if startKey == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(startKey))
+}
+
+// listAnnouncementsPage responds with a single page of announcements stored under the prefix. The announcements not
+// selected by the selector are left out of the page, so a page may have fewer items than the limit.
+func listAnnouncementsPage(c *gin.Context, db model.DatabaseAdapter, prefix string, selector labelSelector) {
+	limit, startKey, err := parsePagination(c, prefix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	data, next, err := db.GetObjectsPage(prefix, startKey, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	page := model.AnnouncementList{
+		Items:    make([]model.Announcement, 0, len(data)),
+		Continue: encodeContinueToken(next),
+	}
+	for _, value := range data {
+		var announcement model.Announcement
+		err = json.Unmarshal([]byte(value), &announcement)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+		if skipWithdrawn(c, &announcement) || !selector.matches(announcement.Meta.Labels) {
+			continue
+		}
+		page.Items = append(page.Items, announcement)
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Status:  "success",
+		Message: "Announcements retrieved successfully",
+		Data:    page,
+	})
+}
@@ -0,0 +1,331 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+const (
+	peersPrefix           = "v1/peers/" // peersPrefix is the key prefix under which the peer resources are stored.
+	maxAuthPasswordLength = 80          // maxAuthPasswordLength is the maximum length of a TCP MD5 key.
+	minHoldTime           = 3           // minHoldTime is the minimum non-zero hold time allowed by RFC 4271.
+	maxAddPathSendMax     = 255         // maxAddPathSendMax is the maximum number of paths per prefix GoBGP advertises with ADD-PATH.
+)
+
+// getPeer returns the resource of the peer with the address, or nil if there is none.
+func getPeer(db model.DatabaseAdapter, address string) (*model.Peer, error) {
+	value, err := db.Get(peersPrefix + address)
+	if err != nil && err.Error() == "key not found" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer: %w", err)
+	}
+
+	var peer model.Peer
+	if err := json.Unmarshal([]byte(value), &peer); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal peer")
+	}
+	return &peer, nil
+}
+
+// peerAddress parses the address of a peer and returns it in the canonical form used as the key of the resource.
+func peerAddress(address string) (string, error) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil || addr.Zone() != "" {
+		return "", fmt.Errorf("address must be a valid IP address without a zone")
+	}
+	return addr.Unmap().String(), nil
+}
+
+// validatePeer checks the configuration of the peer and normalizes its address.
+func validatePeer(peer *model.Peer) error {
+	address, err := peerAddress(peer.Address)
+	if err != nil {
+		return err
+	}
+	peer.Address = address
+
+	switch {
+	case peer.ASN == 0:
+		return fmt.Errorf("asn is required")
+	case len(peer.AuthPassword) > maxAuthPasswordLength:
+		return fmt.Errorf("auth-password must not be longer than %d characters", maxAuthPasswordLength)
+	case peer.Timers.HoldTime != 0 && peer.Timers.HoldTime < minHoldTime:
+		return fmt.Errorf("timers.hold-time must be 0 or at least %d seconds", minHoldTime)
+	case peer.Timers.HoldTime != 0 && peer.Timers.KeepaliveInterval >= peer.Timers.HoldTime:
+		return fmt.Errorf("timers.keepalive-interval must be shorter than timers.hold-time")
+	case peer.AddPathSendMax > maxAddPathSendMax:
+		return fmt.Errorf("add-path-send-max must not be greater than %d", maxAddPathSendMax)
+	}
+
+	for i, family := range peer.AddressFamilies {
+		switch family {
+		case model.AddressFamilyIPv4Unicast, model.AddressFamilyIPv6Unicast:
+		default:
+			return fmt.Errorf("address-families: unsupported address family %q", family)
+		}
+		if slices.Contains(peer.AddressFamilies[:i], family) {
+			return fmt.Errorf("address-families: duplicate address family %q", family)
+		}
+	}
+	return nil
+}
+
+// registerPeerRoutes adds the routes that create, read, update and delete the peer resources, and the status
+// subresource of the peers reported by the updater.
+func registerPeerRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, clk clock.Clock) {
+	v1.GET("/peers", func(c *gin.Context) {
+		data, err := db.GetObjects(peersPrefix)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		peers := make([]model.Peer, 0, len(data))
+		for _, value := range data {
+			var peer model.Peer
+			if err := json.Unmarshal([]byte(value), &peer); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal peer",
+					Data:    nil,
+				})
+				return
+			}
+			peers = append(peers, peer)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Peers retrieved successfully",
+			Data:    peers,
+		})
+	})
+
+	v1.GET("/peers/:address", func(c *gin.Context) {
+		peer, ok := lookupPeer(c, db)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Peer retrieved successfully",
+			Data:    peer,
+		})
+	})
+
+	v1.POST("/peers", func(c *gin.Context) {
+		var peer model.Peer
+		if err := c.ShouldBindJSON(&peer); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := validatePeer(&peer); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		stored, err := getPeer(db, peer.Address)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if stored != nil {
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: "peer already exists",
+				Data:    nil,
+			})
+			return
+		}
+
+		// The status is reported by the updater once the peer is configured
+		peer.Status = model.PeerStatus{}
+		writePeer(c, db, &peer, http.StatusCreated, "Peer created successfully")
+	})
+
+	v1.PATCH("/peers/:address", func(c *gin.Context) {
+		var peer model.Peer
+		if err := c.ShouldBindJSON(&peer); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		stored, ok := lookupPeer(c, db)
+		if !ok {
+			return
+		}
+
+		// The address is taken from the path, a different one in the body would move the peer
+		if peer.Address != "" {
+			if address, err := peerAddress(peer.Address); err != nil || address != stored.Address {
+				c.JSON(http.StatusBadRequest, model.APIResponse{
+					Status:  "error",
+					Message: "the address of a peer can not be changed",
+					Data:    nil,
+				})
+				return
+			}
+		}
+		peer.Address = stored.Address
+
+		if err := validatePeer(&peer); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		peer.Status = stored.Status
+		writePeer(c, db, &peer, http.StatusOK, "Peer updated successfully")
+	})
+
+	v1.DELETE("/peers/:address", func(c *gin.Context) {
+		peer, ok := lookupPeer(c, db)
+		if !ok {
+			return
+		}
+
+		if err := db.Delete(peersPrefix + peer.Address); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to delete peer: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Peer deleted successfully",
+			Data:    peer,
+		})
+	})
+
+	v1.PUT("/peers/:address/status", func(c *gin.Context) {
+		var status model.PeerStatus
+		if err := c.ShouldBindJSON(&status); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		for _, router := range status.Routers {
+			if router.Router == "" || router.SessionState == "" {
+				c.JSON(http.StatusBadRequest, model.APIResponse{
+					Status:  "error",
+					Message: "routers must have a router and a session-state",
+					Data:    nil,
+				})
+				return
+			}
+		}
+
+		peer, ok := lookupPeer(c, db)
+		if !ok {
+			return
+		}
+
+		status.Timestamp = clk.Now().UTC()
+		peer.Status = status
+		writePeer(c, db, peer, http.StatusOK, "Peer status updated successfully")
+	})
+}
+
+// lookupPeer returns the peer addressed by the path of the request. It responds with an error and returns false if
+// the address is invalid, the peer does not exist or can not be read.
+func lookupPeer(c *gin.Context, db model.DatabaseAdapter) (*model.Peer, bool) {
+	address, err := peerAddress(c.Param("address"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return nil, false
+	}
+
+	peer, err := getPeer(db, address)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return nil, false
+	}
+
+	if peer == nil {
+		c.JSON(http.StatusNotFound, model.APIResponse{
+			Status:  "error",
+			Message: "peer not found",
+			Data:    nil,
+		})
+		return nil, false
+	}
+	return peer, true
+}
+
+// writePeer stores the peer and responds with it.
+func writePeer(c *gin.Context, db model.DatabaseAdapter, peer *model.Peer, status int, message string) {
+	value, err := json.Marshal(peer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := db.Put(peersPrefix+peer.Address, string(value)); err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: fmt.Errorf("failed to write peer: %w", err).Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(status, model.APIResponse{
+		Status:  "success",
+		Message: message,
+		Data:    peer,
+	})
+}
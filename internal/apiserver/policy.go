@@ -0,0 +1,192 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// PrefixPolicy rejects announcements whose prefixes or next hops are not allowed by the global rules or by the
+// rules of their project, and warns about or rejects announcements whose prefixes overlap those of other projects.
+// A nil policy allows everything.
+type PrefixPolicy struct {
+	global    *prefixRules
+	projects  map[string]*prefixRules
+	conflicts string // conflicts is the admission of cross-project overlaps, one of the model.Conflicts constants.
+}
+
+// prefixRules are the parsed model.PrefixRules of a scope, e.g., "global policy".
+type prefixRules struct {
+	scope           string
+	allowedPrefixes []netip.Prefix
+	allowedNextHops []netip.Prefix
+	forbidden       []model.PrefixLengthRange
+}
+
+// LoadPrefixPolicy reads the JSON prefix policy file and sets the admission of cross-project overlaps. It returns nil
+// when no file is configured and overlaps are admitted silently.
+func LoadPrefixPolicy(path, conflicts string) (*PrefixPolicy, error) {
+	switch conflicts {
+	case "", model.ConflictsOff:
+		if path == "" {
+			return nil, nil
+		}
+		conflicts = model.ConflictsOff
+	case model.ConflictsWarn, model.ConflictsReject:
+		if path == "" {
+			return &PrefixPolicy{conflicts: conflicts}, nil
+		}
+	default:
+		return nil, fmt.Errorf("invalid cross-project conflicts mode %q: must be %s, %s or %s", conflicts, model.ConflictsOff, model.ConflictsWarn, model.ConflictsReject)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read prefix policy file: %w", err)
+	}
+	var file model.PrefixPolicy
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse prefix policy file: %w", err)
+	}
+
+	policy := &PrefixPolicy{projects: make(map[string]*prefixRules, len(file.Projects)), conflicts: conflicts}
+	if policy.global, err = parsePrefixRules("global policy", file.Global); err != nil {
+		return nil, err
+	}
+	for project, rules := range file.Projects {
+		if policy.projects[project], err = parsePrefixRules("policy of project "+project, rules); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// parsePrefixRules checks and parses the rules of the scope.
+func parsePrefixRules(scope string, rules model.PrefixRules) (*prefixRules, error) {
+	parsed := &prefixRules{scope: scope, forbidden: rules.ForbiddenPrefixLengths}
+	for _, list := range []struct {
+		cidrs  []string
+		target *[]netip.Prefix
+	}{{rules.AllowedPrefixes, &parsed.allowedPrefixes}, {rules.AllowedNextHops, &parsed.allowedNextHops}} {
+		for _, cidr := range list.cidrs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil || prefix != prefix.Masked() {
+				return nil, fmt.Errorf("%s: %q is not a prefix in CIDR notation without host bits", scope, cidr)
+			}
+			*list.target = append(*list.target, prefix)
+		}
+	}
+
+	for _, lengths := range rules.ForbiddenPrefixLengths {
+		bits := map[string]int{"ipv4": 32, "ipv6": 128}[lengths.Family]
+		if bits == 0 {
+			return nil, fmt.Errorf("%s: unknown address family %q of forbidden prefix lengths, must be ipv4 or ipv6", scope, lengths.Family)
+		}
+		if lengths.Min < 0 || lengths.Min > lengths.Max || lengths.Max > bits {
+			return nil, fmt.Errorf("%s: invalid %s prefix length range %d-%d", scope, lengths.Family, lengths.Min, lengths.Max)
+		}
+	}
+	return parsed, nil
+}
+
+// conflictMode returns the admission of announcements overlapping prefixes of other projects.
+func (p *PrefixPolicy) conflictMode() string {
+	if p == nil {
+		return model.ConflictsOff
+	}
+	return p.conflicts
+}
+
+// violations returns an error for every prefix and next hop of the announcement that breaks the global rules or
+// the rules of its project.
+func (p *PrefixPolicy) violations(announcement *model.Announcement) []model.ValidationError {
+	if p == nil {
+		return nil
+	}
+
+	var errs []model.ValidationError
+	for _, rules := range []*prefixRules{p.global, p.projects[announcement.Meta.Project]} {
+		if rules != nil {
+			errs = append(errs, rules.violations(announcement)...)
+		}
+	}
+	return errs
+}
+
+// violations returns an error for every prefix and next hop of the announcement that breaks the rules.
+func (r *prefixRules) violations(announcement *model.Announcement) []model.ValidationError {
+	var errs []model.ValidationError
+	for field, prefix := range announcedPrefixes(announcement) {
+		if len(r.allowedPrefixes) > 0 && !prefixWithin(prefix, r.allowedPrefixes) {
+			errs = append(errs, model.ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("%s is outside the prefixes allowed by the %s: %s", prefix, r.scope, joinPrefixes(r.allowedPrefixes)),
+			})
+		}
+
+		family := "ipv4"
+		if prefix.Addr().Is6() {
+			family = "ipv6"
+		}
+		for _, lengths := range r.forbidden {
+			if lengths.Family == family && prefix.Bits() >= lengths.Min && prefix.Bits() <= lengths.Max {
+				errs = append(errs, model.ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("%s has a prefix length forbidden by the %s: /%d to /%d", prefix, r.scope, lengths.Min, lengths.Max),
+				})
+			}
+		}
+	}
+
+	if len(r.allowedNextHops) == 0 {
+		return errs
+	}
+	for field, address := range nextHopAddresses(announcement) {
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			continue
+		}
+		if !prefixWithin(netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), r.allowedNextHops) {
+			errs = append(errs, model.ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("next hop %s is outside the subnets allowed by the %s: %s", address, r.scope, joinPrefixes(r.allowedNextHops)),
+			})
+		}
+	}
+	return errs
+}
+
+// nextHopAddresses returns the next-hop addresses of the announcement keyed by the field that sets them.
+func nextHopAddresses(announcement *model.Announcement) map[string]string {
+	addresses := make(map[string]string, len(announcement.NextHops)+len(announcement.WeightedNextHops))
+	for i, nextHop := range announcement.NextHops {
+		addresses[fmt.Sprintf("next-hops[%d].ip", i)] = nextHop.IP
+	}
+	for i, nextHop := range announcement.WeightedNextHops {
+		addresses[fmt.Sprintf("weighted-next-hops[%d].address", i)] = nextHop.Address
+	}
+	return addresses
+}
+
+// prefixWithin reports whether the prefix is within one of the allowed prefixes.
+func prefixWithin(prefix netip.Prefix, allowed []netip.Prefix) bool {
+	for _, allowedPrefix := range allowed {
+		if prefix.Bits() >= allowedPrefix.Bits() && allowedPrefix.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPrefixes returns the comma-separated list of the prefixes.
+func joinPrefixes(prefixes []netip.Prefix) string {
+	cidrs := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		cidrs = append(cidrs, prefix.String())
+	}
+	return strings.Join(cidrs, ", ")
+}
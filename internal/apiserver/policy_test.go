@@ -0,0 +1,152 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// writePrefixPolicy writes the prefix policy to a file and returns its path.
+func writePrefixPolicy(t *testing.T, policy model.PrefixPolicy) string {
+	t.Helper()
+	data, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "prefix-policy.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadPrefixPolicy checks that the malformed rules are rejected when the policy is loaded.
+func TestLoadPrefixPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     model.PrefixRules
+		wantError string
+	}{
+		{name: "valid", rules: model.PrefixRules{AllowedPrefixes: []string{"192.0.2.0/24"}, AllowedNextHops: []string{"10.0.0.0/8"}, ForbiddenPrefixLengths: []model.PrefixLengthRange{{Family: "ipv4", Min: 0, Max: 8}}}},
+		{name: "not a prefix", rules: model.PrefixRules{AllowedPrefixes: []string{"192.0.2.1"}}, wantError: "not a prefix"},
+		{name: "host bits", rules: model.PrefixRules{AllowedNextHops: []string{"10.0.0.1/8"}}, wantError: "without host bits"},
+		{name: "unknown family", rules: model.PrefixRules{ForbiddenPrefixLengths: []model.PrefixLengthRange{{Family: "ipx", Max: 8}}}, wantError: "unknown address family"},
+		{name: "inverted range", rules: model.PrefixRules{ForbiddenPrefixLengths: []model.PrefixLengthRange{{Family: "ipv4", Min: 16, Max: 8}}}, wantError: "invalid ipv4 prefix length range"},
+		{name: "range too long", rules: model.PrefixRules{ForbiddenPrefixLengths: []model.PrefixLengthRange{{Family: "ipv4", Min: 0, Max: 64}}}, wantError: "invalid ipv4 prefix length range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePrefixPolicy(t, model.PrefixPolicy{Projects: map[string]model.PrefixRules{"alpha": tt.rules}})
+			_, err := LoadPrefixPolicy(path, "")
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("LoadPrefixPolicy: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) || !strings.Contains(err.Error(), "policy of project alpha") {
+				t.Fatalf("LoadPrefixPolicy returned %v, want an error of the project containing %q", err, tt.wantError)
+			}
+		})
+	}
+
+	if _, err := LoadPrefixPolicy("", "sometimes"); err == nil {
+		t.Error("LoadPrefixPolicy accepted an unknown conflicts mode")
+	}
+	if policy, err := LoadPrefixPolicy("", ""); err != nil || policy != nil {
+		t.Errorf("LoadPrefixPolicy without a file returned %v, %v, want no policy", policy, err)
+	}
+}
+
+// TestPrefixPolicyViolations checks the announcements rejected by the global rules and by the rules of their project.
+func TestPrefixPolicyViolations(t *testing.T) {
+	path := writePrefixPolicy(t, model.PrefixPolicy{
+		Global: model.PrefixRules{
+			ForbiddenPrefixLengths: []model.PrefixLengthRange{{Family: "ipv4", Min: 0, Max: 8}, {Family: "ipv6", Min: 0, Max: 16}},
+		},
+		Projects: map[string]model.PrefixRules{
+			"alpha": {AllowedPrefixes: []string{"192.0.2.0/24", "2001:db8::/32"}, AllowedNextHops: []string{"10.0.0.0/24"}},
+		},
+	})
+	policy, err := LoadPrefixPolicy(path, "")
+	if err != nil {
+		t.Fatalf("LoadPrefixPolicy: %v", err)
+	}
+
+	subnet := func(project, ip string, mask int) *model.Announcement {
+		announcement := testAnnouncement(project, "web", "")
+		announcement.Addresses.SourceSubnets = model.Subnet{IP: ip, Mask: uint8(mask)}
+		return announcement
+	}
+	nextHop := func(address string) *model.Announcement {
+		announcement := testAnnouncement("alpha", "web", "192.0.2.1")
+		announcement.NextHops = []model.Subnet{{IP: address, Mask: 32}}
+		return announcement
+	}
+	ipv6 := testAnnouncement("alpha", "web", "")
+	ipv6.Addresses.AnnouncedIPv6 = "2001:db8::1"
+
+	tests := []struct {
+		name         string
+		announcement *model.Announcement
+		wantFields   []string
+		wantMessage  string
+	}{
+		{name: "allowed", announcement: testAnnouncement("alpha", "web", "192.0.2.1")},
+		{name: "allowed IPv6", announcement: ipv6},
+		{name: "outside the allowed prefixes", announcement: testAnnouncement("alpha", "web", "198.51.100.1"), wantFields: []string{"addresses.announced-ip"}, wantMessage: "outside the prefixes allowed by the policy of project alpha"},
+		{name: "subnet larger than the allowed prefix", announcement: subnet("alpha", "192.0.0.0", 16), wantFields: []string{"addresses.announced-address"}, wantMessage: "outside the prefixes allowed"},
+		{name: "default route", announcement: subnet("beta", "0.0.0.0", 0), wantFields: []string{"addresses.announced-address"}, wantMessage: "forbidden by the global policy: /0 to /8"},
+		{name: "default route of a restricted project", announcement: subnet("alpha", "0.0.0.0", 0), wantFields: []string{"addresses.announced-address", "addresses.announced-address"}},
+		{name: "next hop outside the allowed subnets", announcement: nextHop("10.0.1.1"), wantFields: []string{"next-hops[0].ip"}, wantMessage: "next hop 10.0.1.1 is outside the subnets allowed"},
+		{name: "next hop allowed", announcement: nextHop("10.0.0.254")},
+		{name: "project without rules", announcement: testAnnouncement("beta", "web", "198.51.100.1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := policy.violations(tt.announcement)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("violations = %+v, want %d", errs, len(tt.wantFields))
+			}
+			for i, err := range errs {
+				if err.Field != tt.wantFields[i] {
+					t.Errorf("violation %d of field %q, want %q", i, err.Field, tt.wantFields[i])
+				}
+				if !strings.Contains(err.Message, tt.wantMessage) {
+					t.Errorf("violation %d = %q, want it to contain %q", i, err.Message, tt.wantMessage)
+				}
+			}
+		})
+	}
+
+	var nilPolicy *PrefixPolicy
+	if errs := nilPolicy.violations(subnet("alpha", "0.0.0.0", 0)); errs != nil {
+		t.Errorf("a nil policy rejected the default route: %+v", errs)
+	}
+}
+
+// TestPrefixPolicyRejectsWrites checks that the API server rejects the announcements breaking the policy with the
+// explanation of the violation.
+func TestPrefixPolicyRejectsWrites(t *testing.T) {
+	path := writePrefixPolicy(t, model.PrefixPolicy{
+		Global: model.PrefixRules{ForbiddenPrefixLengths: []model.PrefixLengthRange{{Family: "ipv4", Min: 0, Max: 8}}},
+	})
+	server := newTestServer(t, func(config *model.APIConfig) {
+		config.PrefixPolicyFile = path
+	})
+	announcement := testAnnouncement("alpha", "everything", "")
+	announcement.Addresses.SourceSubnets = model.Subnet{IP: "0.0.0.0", Mask: 0}
+
+	code, response := server.do(t, http.MethodPost, "/v1/announcements/", announcement)
+	if code != http.StatusBadRequest || !strings.Contains(response.Message+string(response.Data), "forbidden by the global policy") {
+		t.Fatalf("create answered %d: %s %s", code, response.Message, response.Data)
+	}
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/", testAnnouncement("alpha", "web", "192.0.2.1")); code != http.StatusCreated {
+		t.Fatalf("create of an allowed announcement answered %d: %s", code, response.Message)
+	}
+}
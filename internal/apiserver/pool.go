@@ -0,0 +1,524 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// poolsPrefix is the key prefix under which the VIP pools are stored by project.
+const poolsPrefix = "v1/pools/"
+
+// getPool returns the pool of the project, or nil if it does not exist.
+func getPool(db model.DatabaseAdapter, project, name string) (*model.Pool, error) {
+	value, err := db.Get(poolsPrefix + project + "/" + name)
+	if err != nil && err.Error() == "key not found" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool: %w", err)
+	}
+
+	var pool model.Pool
+	if err := json.Unmarshal([]byte(value), &pool); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pool")
+	}
+	return &pool, nil
+}
+
+// validatePool checks the name and the prefixes of the pool. The prefixes must not overlap each other.
+func validatePool(pool *model.Pool) error {
+	switch {
+	case pool.Name == "":
+		return fmt.Errorf("name is required")
+	case strings.Contains(pool.Name, "/"):
+		return fmt.Errorf("name must not contain '/'")
+	case len(pool.CIDRs) == 0:
+		return fmt.Errorf("cidrs must list at least one prefix")
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(pool.CIDRs))
+	for _, cidr := range pool.CIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || prefix != prefix.Masked() {
+			return fmt.Errorf("cidrs: %q is not a prefix in CIDR notation without host bits", cidr)
+		}
+		for _, other := range prefixes {
+			if prefix.Overlaps(other) {
+				return fmt.Errorf("cidrs: %s overlaps %s", prefix, other)
+			}
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return nil
+}
+
+// poolPrefixes returns the prefixes of the pool. Invalid prefixes are skipped, they are rejected when the pool is
+// written.
+func poolPrefixes(pool *model.Pool) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(pool.CIDRs))
+	for _, cidr := range pool.CIDRs {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// poolAllocations returns the addresses of the pool announced by the announcements of the project, mapped to the
+// names of the announcements. The values are the stored announcements of the project.
+func poolAllocations(pool *model.Pool, values []string) map[netip.Addr]string {
+	prefixes := poolPrefixes(pool)
+	allocations := make(map[netip.Addr]string)
+	for _, value := range values {
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			continue
+		}
+		for _, address := range announcement.AnnouncedAddresses() {
+			addr, err := netip.ParseAddr(address)
+			if err == nil && prefixWithin(netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), prefixes) {
+				allocations[addr.Unmap()] = announcement.Meta.Name
+			}
+		}
+	}
+	return allocations
+}
+
+// poolHost reports whether the address of the prefix can be allocated. The network and broadcast addresses of the
+// IPv4 prefixes are skipped, except for /31 and /32 prefixes.
+func poolHost(prefix netip.Prefix, addr netip.Addr) bool {
+	return !addr.Is4() || prefix.Bits() >= 31 || addr != prefix.Addr() && prefix.Contains(addr.Next())
+}
+
+// describePool sets the allocations and the number of free addresses of the pool from the stored announcements of
+// its project.
+func describePool(db model.DatabaseAdapter, pool *model.Pool) error {
+	values, err := db.GetObjects(announcementsPrefix + pool.Project + "/")
+	if err != nil {
+		return fmt.Errorf("failed to list project announcements: %w", err)
+	}
+
+	allocations := poolAllocations(pool, values)
+	pool.Allocations = make(map[string]string, len(allocations))
+	for addr, name := range allocations {
+		pool.Allocations[addr.String()] = name
+	}
+
+	var size uint64
+	for _, prefix := range poolPrefixes(pool) {
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits >= 64 {
+			size = math.MaxUint64
+			break
+		}
+		hosts := uint64(1) << hostBits
+		if prefix.Addr().Is4() && prefix.Bits() < 31 {
+			hosts -= 2
+		}
+		if size > math.MaxUint64-hosts {
+			size = math.MaxUint64
+			break
+		}
+		size += hosts
+	}
+	if size != math.MaxUint64 {
+		size -= min(size, uint64(len(allocations)))
+	}
+	pool.Free = size
+	return nil
+}
+
+// allocatePoolAddress sets the announced IP of an announcement created with a pool and without an announced IP to
+// the first free address of the pool. The IPv6 prefixes are skipped for dual-stack announcements, whose announced IP
+// must be an IPv4 address. The addresses of the pending writes of a batch or an import are allocated as if they were
+// stored already. It fails with an *invalidAnnouncementError if the pool does not exist or is exhausted.
+func allocatePoolAddress(db model.DatabaseAdapter, announcement *model.Announcement, pending *pendingWrites) error {
+	if announcement.Addresses.Pool == "" || announcement.Addresses.AnnouncedIP != "" {
+		return nil
+	}
+
+	pool, err := getPool(db, announcement.Meta.Project, announcement.Addresses.Pool)
+	if err != nil {
+		return err
+	}
+	if pool == nil {
+		return &invalidAnnouncementError{errors: []model.ValidationError{{
+			Field:   "addresses.pool",
+			Message: fmt.Sprintf("pool %s not found in project %s", announcement.Addresses.Pool, announcement.Meta.Project),
+		}}}
+	}
+
+	values, err := db.GetObjects(announcementsPrefix + announcement.Meta.Project + "/")
+	if err != nil {
+		return fmt.Errorf("failed to list project announcements: %w", err)
+	}
+	values = pending.apply(announcement.Meta.Project, values)
+
+	allocations := poolAllocations(pool, values)
+	for _, prefix := range poolPrefixes(pool) {
+		if announcement.Addresses.AnnouncedIPv6 != "" && !prefix.Addr().Is4() {
+			continue
+		}
+		for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			if _, ok := allocations[addr]; !ok && poolHost(prefix, addr) {
+				announcement.Addresses.AnnouncedIP = addr.String()
+				return nil
+			}
+		}
+	}
+	return &invalidAnnouncementError{errors: []model.ValidationError{{
+		Field:   "addresses.pool",
+		Message: fmt.Sprintf("pool %s has no free addresses", pool.Name),
+	}}}
+}
+
+// poolErrors returns an error if the announcement names a pool that does not exist, or if its announced IP is
+// outside the pool or allocated to another announcement of the project. The values are the stored announcements of
+// the project.
+func poolErrors(db model.DatabaseAdapter, announcement *model.Announcement, values []string) ([]model.ValidationError, error) {
+	name := announcement.Addresses.Pool
+	if name == "" {
+		return nil, nil
+	}
+
+	pool, err := getPool(db, announcement.Meta.Project, name)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return []model.ValidationError{{
+			Field:   "addresses.pool",
+			Message: fmt.Sprintf("pool %s not found in project %s", name, announcement.Meta.Project),
+		}}, nil
+	}
+
+	addr, err := netip.ParseAddr(announcement.Addresses.AnnouncedIP)
+	if err != nil {
+		// Addresses are allocated on create only, the updates must keep the allocated one
+		return []model.ValidationError{{
+			Field:   "addresses.announced-ip",
+			Message: fmt.Sprintf("announced IP is required to update an announcement of pool %s", name),
+		}}, nil
+	}
+	addr = addr.Unmap()
+
+	prefix := netip.PrefixFrom(addr, addr.BitLen())
+	var host bool
+	for _, poolPrefix := range poolPrefixes(pool) {
+		if prefixWithin(prefix, []netip.Prefix{poolPrefix}) {
+			host = poolHost(poolPrefix, addr)
+		}
+	}
+	if !host {
+		return []model.ValidationError{{
+			Field:   "addresses.announced-ip",
+			Message: fmt.Sprintf("%s is not an address of pool %s: %s", addr, name, strings.Join(pool.CIDRs, ", ")),
+		}}, nil
+	}
+
+	if owner, ok := poolAllocations(pool, values)[addr]; ok && owner != announcement.Meta.Name {
+		return []model.ValidationError{{
+			Field:   "addresses.announced-ip",
+			Message: fmt.Sprintf("%s of pool %s is allocated to %s", addr, name, owner),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// registerPoolRoutes adds the routes that create, read, update and delete the VIP pools. The writes are serialized
+// with the writes of the announcements of the project, so that an address is never allocated twice.
+func registerPoolRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer) {
+	v1.GET("/pools/:project", func(c *gin.Context) {
+		data, err := db.GetObjects(poolsPrefix + c.Param("project") + "/")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		pools := make([]model.Pool, 0, len(data))
+		for _, value := range data {
+			var pool model.Pool
+			if err := json.Unmarshal([]byte(value), &pool); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal pool",
+					Data:    nil,
+				})
+				return
+			}
+			if err := describePool(db, &pool); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    nil,
+				})
+				return
+			}
+			pools = append(pools, pool)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Pools retrieved successfully",
+			Data:    pools,
+		})
+	})
+
+	v1.GET("/pools/:project/:name", func(c *gin.Context) {
+		pool, err := getPool(db, c.Param("project"), c.Param("name"))
+		if err == nil && pool != nil {
+			err = describePool(db, pool)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if pool == nil {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "pool not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Pool retrieved successfully",
+			Data:    pool,
+		})
+	})
+
+	v1.POST("/pools/:project", func(c *gin.Context) {
+		pool, ok := bindPool(c, c.Param("project"), "")
+		if !ok {
+			return
+		}
+
+		unlock := serializer.Lock(pool.Project)
+		defer unlock()
+
+		stored, err := getPool(db, pool.Project, pool.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if stored != nil {
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: "pool already exists",
+				Data:    nil,
+			})
+			return
+		}
+
+		writePool(c, db, pool, http.StatusCreated, "Pool created successfully")
+	})
+
+	v1.PATCH("/pools/:project/:name", func(c *gin.Context) {
+		pool, ok := bindPool(c, c.Param("project"), c.Param("name"))
+		if !ok {
+			return
+		}
+
+		unlock := serializer.Lock(pool.Project)
+		defer unlock()
+
+		stored, err := getPool(db, pool.Project, pool.Name)
+		if err == nil && stored != nil {
+			err = describePool(db, stored)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if stored == nil {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "pool not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		// Shrinking the pool must not orphan the allocated addresses
+		prefixes := poolPrefixes(pool)
+		for address, name := range stored.Allocations {
+			addr := netip.MustParseAddr(address)
+			if !prefixWithin(netip.PrefixFrom(addr, addr.BitLen()), prefixes) {
+				c.JSON(http.StatusConflict, model.APIResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("%s is allocated to %s and must stay in the pool", address, name),
+					Data:    nil,
+				})
+				return
+			}
+		}
+
+		writePool(c, db, pool, http.StatusOK, "Pool updated successfully")
+	})
+
+	v1.DELETE("/pools/:project/:name", func(c *gin.Context) {
+		project := c.Param("project")
+
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		pool, err := getPool(db, project, c.Param("name"))
+		if err == nil && pool != nil {
+			err = describePool(db, pool)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if pool == nil {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "pool not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		// The announcements keep their addresses, which could be allocated again from a new pool
+		if len(pool.Allocations) > 0 {
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("pool has %d allocated addresses", len(pool.Allocations)),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := db.Delete(poolsPrefix + project + "/" + pool.Name); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to delete pool: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Pool deleted successfully",
+			Data:    pool,
+		})
+	})
+}
+
+// bindPool decodes and validates the pool of the request body. The project is taken from the path, and so is the
+// name when it is set. It responds with the error and returns false if the pool is invalid.
+func bindPool(c *gin.Context, project, name string) (*model.Pool, bool) {
+	var pool model.Pool
+	if err := c.ShouldBindJSON(&pool); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return nil, false
+	}
+
+	// A different project or name in the body would move the pool
+	switch {
+	case pool.Project != "" && pool.Project != project:
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Status:  "error",
+			Message: "pools can not be moved to another project",
+			Data:    nil,
+		})
+		return nil, false
+	case name != "" && pool.Name != "" && pool.Name != name:
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Status:  "error",
+			Message: "pools can not be renamed",
+			Data:    nil,
+		})
+		return nil, false
+	}
+	pool.Project = project
+	if name != "" {
+		pool.Name = name
+	}
+
+	if err := validatePool(&pool); err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return nil, false
+	}
+	return &pool, true
+}
+
+// writePool stores the pool without the fields set in responses and responds with it described.
+func writePool(c *gin.Context, db model.DatabaseAdapter, pool *model.Pool, status int, message string) {
+	pool.Allocations, pool.Free = nil, 0
+	value, err := json.Marshal(pool)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := db.Put(poolsPrefix+pool.Project+"/"+pool.Name, string(value)); err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: fmt.Errorf("failed to write pool: %w", err).Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := describePool(db, pool); err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(status, model.APIResponse{
+		Status:  "success",
+		Message: message,
+		Data:    pool,
+	})
+}
@@ -0,0 +1,101 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// TestPoolAllocationOnCreate checks that every create path allocates the announced IP of an announcement of a pool,
+// and that the announcements created together are allocated distinct addresses.
+func TestPoolAllocationOnCreate(t *testing.T) {
+	server := newTestServer(t, nil)
+	pool := model.Pool{Name: "vips", CIDRs: []string{"198.51.100.0/29"}}
+	if code, response := server.do(t, http.MethodPost, "/v1/pools/alpha", pool); code != http.StatusCreated {
+		t.Fatalf("pool create answered %d: %s", code, response.Message)
+	}
+	pooled := func(name string) *model.Announcement {
+		announcement := testAnnouncement("alpha", name, "")
+		announcement.Addresses.Pool = "vips"
+		return announcement
+	}
+
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/", pooled("create")); code != http.StatusCreated {
+		t.Fatalf("create answered %d: %s", code, response.Message)
+	}
+
+	batch := model.BatchRequest{Operations: []model.BatchOperation{
+		{Action: model.BatchApply, Announcement: *pooled("batch-1")},
+		{Action: model.BatchApply, Announcement: *pooled("batch-2")},
+	}}
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/batch", batch); code != http.StatusOK {
+		t.Fatalf("batch answered %d: %s %s", code, response.Message, response.Data)
+	}
+
+	raw, err := json.Marshal(pooled("apply"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apply := model.ApplyRequest{Manager: "test", Announcement: raw}
+	if code, response := server.do(t, http.MethodPost, "/v1/apply", apply); code != http.StatusCreated {
+		t.Fatalf("apply answered %d: %s %s", code, response.Message, response.Data)
+	}
+
+	bundle := model.Bundle{Version: model.BundleVersion, Announcements: []model.Announcement{*pooled("import")}}
+	if code, response := server.do(t, http.MethodPost, "/v1/import", bundle); code != http.StatusOK {
+		t.Fatalf("import answered %d: %s %s", code, response.Message, response.Data)
+	}
+
+	want := map[string]string{
+		"create":  "198.51.100.1",
+		"batch-1": "198.51.100.2",
+		"batch-2": "198.51.100.3",
+		"apply":   "198.51.100.4",
+		"import":  "198.51.100.5",
+	}
+	for name, address := range want {
+		if got := server.stored(t, "alpha", name).Addresses.AnnouncedIP; got != address {
+			t.Errorf("%s was allocated %q, want %q", name, got, address)
+		}
+	}
+
+	// The updates keep the allocated address and are never allocated another one
+	update := model.BatchRequest{Operations: []model.BatchOperation{{Action: model.BatchApply, Announcement: *pooled("create")}}}
+	code, response := server.do(t, http.MethodPost, "/v1/announcements/batch", update)
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("batch update without an announced IP answered %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+	var results []model.BatchItemResult
+	if err := json.Unmarshal(response.Data, &results); err != nil || len(results) != 1 || results[0].Error == "" {
+		t.Fatalf("batch update results = %s", response.Data)
+	}
+}
+
+// TestPoolExhausted checks that a create path reports an exhausted pool as an invalid announcement.
+func TestPoolExhausted(t *testing.T) {
+	server := newTestServer(t, nil)
+	pool := model.Pool{Name: "vips", CIDRs: []string{"198.51.100.0/31"}}
+	if code, response := server.do(t, http.MethodPost, "/v1/pools/alpha", pool); code != http.StatusCreated {
+		t.Fatalf("pool create answered %d: %s", code, response.Message)
+	}
+
+	var operations []model.BatchOperation
+	for _, name := range []string{"a", "b", "c"} {
+		announcement := testAnnouncement("alpha", name, "")
+		announcement.Addresses.Pool = "vips"
+		operations = append(operations, model.BatchOperation{Action: model.BatchApply, Announcement: *announcement})
+	}
+	code, response := server.do(t, http.MethodPost, "/v1/announcements/batch", model.BatchRequest{Operations: operations})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("batch answered %d, want %d", code, http.StatusUnprocessableEntity)
+	}
+	var results []model.BatchItemResult
+	if err := json.Unmarshal(response.Data, &results); err != nil || len(results) != 3 {
+		t.Fatalf("batch results = %s", response.Data)
+	}
+	if results[0].Error != "" || results[1].Error != "" || results[2].Error == "" {
+		t.Fatalf("only the third announcement must fail, got %+v", results)
+	}
+}
@@ -0,0 +1,36 @@
+package apiserver
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+)
+
+// newPprofServer creates a dedicated HTTP server exposing the net/http/pprof handlers. The handlers are
+// registered on a private mux, so they are never reachable through the public API listener.
+func newPprofServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+}
+
+// startPprofServer starts the profiling listener in the background and returns the server to shut it down.
+func startPprofServer(addr string) *http.Server {
+	slog.Warn("pprof profiling endpoints are enabled, never expose this address publicly", "address", addr)
+
+	server := newPprofServer(addr)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("pprof server failed", "error", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,349 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// projectsPrefix is the key prefix under which the project resources are stored.
+const projectsPrefix = "v1/projects/"
+
+// getProject returns the resource of the project, or nil if the project has none.
+func getProject(db model.DatabaseAdapter, name string) (*model.Project, error) {
+	value, err := db.Get(projectsPrefix + name)
+	if err != nil && err.Error() == "key not found" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	var project model.Project
+	if err := json.Unmarshal([]byte(value), &project); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal project")
+	}
+	return &project, nil
+}
+
+// validateProject checks the name and the quota of the project. The name follows the rules of the project in the
+// announcement metadata.
+func validateProject(project *model.Project) error {
+	switch {
+	case project.Name == "":
+		return fmt.Errorf("name is required")
+	case strings.Contains(project.Name, "/"):
+		return fmt.Errorf("name must not contain '/'")
+	case project.Name == model.AllProjects:
+		return fmt.Errorf("name must not be %q", model.AllProjects)
+	case project.Quota.MaxAnnouncements < 0:
+		return fmt.Errorf("quota.max-announcements must not be negative")
+	}
+
+	for _, owner := range project.Owners {
+		if owner == "" {
+			return fmt.Errorf("owners must not be empty")
+		}
+	}
+	for _, cidr := range project.Quota.AllowedPrefixes {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil || prefix != prefix.Masked() {
+			return fmt.Errorf("quota.allowed-prefixes: %q is not a prefix in CIDR notation without host bits", cidr)
+		}
+	}
+	return nil
+}
+
+// quotaErrors returns an error for every limit of the project quota exceeded by an announcement with the announced
+// prefixes. The number of announcements is limited for new announcements only, so that the existing ones can still
+// be updated after the quota was lowered; count is the number of the other announcements of the project.
+func quotaErrors(project *model.Project, prefixes map[string]netip.Prefix, exists bool, count int) []model.ValidationError {
+	var errs []model.ValidationError
+	if limit := project.Quota.MaxAnnouncements; limit > 0 && !exists && count >= limit {
+		errs = append(errs, model.ValidationError{
+			Field:   "meta.project",
+			Message: fmt.Sprintf("project %s is limited to %d announcements", project.Name, limit),
+		})
+	}
+
+	if len(project.Quota.AllowedPrefixes) == 0 {
+		return errs
+	}
+	for field, prefix := range prefixes {
+		if !prefixAllowed(prefix, project.Quota.AllowedPrefixes) {
+			errs = append(errs, model.ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("%s is outside the prefixes allowed in project %s: %s", prefix, project.Name, strings.Join(project.Quota.AllowedPrefixes, ", ")),
+			})
+		}
+	}
+	return errs
+}
+
+// prefixAllowed reports whether the prefix is within one of the allowed prefixes in CIDR notation.
+func prefixAllowed(prefix netip.Prefix, allowed []string) bool {
+	for _, cidr := range allowed {
+		if allowedPrefix, err := netip.ParsePrefix(cidr); err == nil && prefixWithin(prefix, []netip.Prefix{allowedPrefix}) {
+			return true
+		}
+	}
+	return false
+}
+
+// registerProjectResourceRoutes adds the routes that create, read, update and delete the project resources. The writes
+// are serialized with the writes of the announcements of the project, so that the quota they are admitted with does
+// not change before they are stored.
+func registerProjectResourceRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer) {
+	v1.GET("/projects", func(c *gin.Context) {
+		data, err := db.GetObjects(projectsPrefix)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		projects := make([]model.Project, 0, len(data))
+		for _, value := range data {
+			var project model.Project
+			if err := json.Unmarshal([]byte(value), &project); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal project",
+					Data:    nil,
+				})
+				return
+			}
+			projects = append(projects, project)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Projects retrieved successfully",
+			Data:    projects,
+		})
+	})
+
+	v1.GET("/projects/:project", func(c *gin.Context) {
+		project, err := getProject(db, c.Param("project"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if project == nil {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "project not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Project retrieved successfully",
+			Data:    project,
+		})
+	})
+
+	v1.POST("/projects", func(c *gin.Context) {
+		var project model.Project
+		if err := c.ShouldBindJSON(&project); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := validateProject(&project); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		unlock := serializer.Lock(project.Name)
+		defer unlock()
+
+		stored, err := getProject(db, project.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if stored != nil {
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: "project already exists",
+				Data:    nil,
+			})
+			return
+		}
+
+		writeProject(c, db, &project, http.StatusCreated, "Project created successfully")
+	})
+
+	v1.PATCH("/projects/:project", func(c *gin.Context) {
+		var project model.Project
+		if err := c.ShouldBindJSON(&project); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		// The name is taken from the path, a different one in the body would rename the project
+		if project.Name != "" && project.Name != c.Param("project") {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "projects can not be renamed",
+				Data:    nil,
+			})
+			return
+		}
+		project.Name = c.Param("project")
+
+		if err := validateProject(&project); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		unlock := serializer.Lock(project.Name)
+		defer unlock()
+
+		stored, err := getProject(db, project.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if stored == nil {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "project not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		writeProject(c, db, &project, http.StatusOK, "Project updated successfully")
+	})
+
+	v1.DELETE("/projects/:project", func(c *gin.Context) {
+		name := c.Param("project")
+		unlock := serializer.Lock(name)
+		defer unlock()
+
+		project, err := getProject(db, name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if project == nil {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "project not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		// Deleting the project would lift the quota of its announcements
+		keys, err := db.List(announcementsPrefix + name + "/")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if len(keys) > 0 {
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("project has %d announcements", len(keys)),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := db.Delete(projectsPrefix + name); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to delete project: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Project deleted successfully",
+			Data:    project,
+		})
+	})
+}
+
+// writeProject stores the project and responds with it.
+func writeProject(c *gin.Context, db model.DatabaseAdapter, project *model.Project, status int, message string) {
+	value, err := json.Marshal(project)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := db.Put(projectsPrefix+project.Name, string(value)); err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: fmt.Errorf("failed to write project: %w", err).Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(status, model.APIResponse{
+		Status:  "success",
+		Message: message,
+		Data:    project,
+	})
+}
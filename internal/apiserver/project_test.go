@@ -0,0 +1,69 @@
+package apiserver
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// blockingStore holds the writes of the announcements until release is closed, signalling each on started.
+type blockingStore struct {
+	model.DatabaseAdapter
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Put(key, value string) error {
+	if strings.HasPrefix(key, announcementsPrefix) {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return s.DatabaseAdapter.Put(key, value)
+}
+
+// TestProjectDeleteWaitsForCreate checks that a project is not deleted while an announcement admitted with its quota
+// is being stored, which would leave the announcement without a quota.
+func TestProjectDeleteWaitsForCreate(t *testing.T) {
+	store := &blockingStore{DatabaseAdapter: NewMemoryStore(), started: make(chan struct{}, 1), release: make(chan struct{})}
+	server := newTestServerOn(t, store, nil)
+
+	project := model.Project{Name: "alpha", Quota: model.ProjectQuota{MaxAnnouncements: 1}}
+	if code, response := server.do(t, http.MethodPost, "/v1/projects", project); code != http.StatusCreated {
+		t.Fatalf("project create answered %d: %s", code, response.Message)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if code, response := server.do(t, http.MethodPost, "/v1/announcements/", testAnnouncement("alpha", "web", "192.0.2.1")); code != http.StatusCreated {
+			t.Errorf("create answered %d: %s", code, response.Message)
+		}
+	}()
+	<-store.started
+
+	deleted := make(chan int, 1)
+	go func() {
+		code, _ := server.do(t, http.MethodDelete, "/v1/projects/alpha", nil)
+		deleted <- code
+	}()
+
+	// The deletion waits for the create, and then finds its announcement
+	select {
+	case code := <-deleted:
+		close(store.release)
+		wg.Wait()
+		t.Fatalf("project delete answered %d while the create was in flight", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(store.release)
+	wg.Wait()
+
+	if code := <-deleted; code != http.StatusConflict {
+		t.Fatalf("project delete answered %d, want %d", code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,213 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmespath/go-jmespath"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+const (
+	maxQueryExpressionLength = 1024 // maxQueryExpressionLength limits the size of a query expression to keep the parsing cost bounded.
+	maxQueryFanOut           = 1024 // maxQueryFanOut limits the number of values the nested multi-selects of an expression may produce from a single value.
+)
+
+// registerQueryRoutes adds the routes that filter announcements with JMESPath expressions.
+// The evaluation of a single request is aborted after the timeout.
+func registerQueryRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, timeout time.Duration) {
+	v1.POST("/announcements/:project/query", func(c *gin.Context) {
+		project := c.Param("project")
+
+		var request model.AnnouncementQuery
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if request.Expr == "" || len(request.Expr) > maxQueryExpressionLength {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("expr must be between 1 and %d characters long", maxQueryExpressionLength),
+				Data:    nil,
+			})
+			return
+		}
+
+		if fanOut := queryFanOut(request.Expr); fanOut > maxQueryFanOut {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("expr is too complex: its multi-selects may produce %d values from one, at most %d are allowed", fanOut, maxQueryFanOut),
+				Data:    nil,
+			})
+			return
+		}
+
+		expression, err := jmespath.Compile(request.Expr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("invalid expression: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		data, err := db.GetObjects(announcementsPrefix + project + "/")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		matches, err := queryAnnouncements(ctx, expression, data, c.Query("includeWithdrawn") == "true")
+		if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("query evaluation exceeded the timeout of %s", timeout),
+				Data:    nil,
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcements queried successfully",
+			Data:    matches,
+		})
+	})
+}
+
+// queryAnnouncements returns the announcements for which the expression yields a truthy value. The evaluation runs in
+// a separate goroutine so that the caller returns as soon as the context is done, even during a slow evaluation; the
+// goroutine stops before the next announcement then, the work on a single one being bounded by maxQueryFanOut.
+// Withdrawn announcements are skipped unless includeWithdrawn is set.
+func queryAnnouncements(ctx context.Context, expression *jmespath.JMESPath, data []string, includeWithdrawn bool) ([]model.Announcement, error) {
+	type result struct {
+		matches []model.Announcement
+		err     error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		matches := make([]model.Announcement, 0)
+		for _, value := range data {
+			if ctx.Err() != nil {
+				return
+			}
+
+			var announcement model.Announcement
+			if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+				done <- result{err: fmt.Errorf("failed to unmarshal announcement")}
+				return
+			}
+			if announcement.Status.Status == model.StatusWithdrawn && !includeWithdrawn {
+				continue
+			}
+
+			// The expression is evaluated against the generic JSON form, so that field names match the API
+			var document interface{}
+			if err := json.Unmarshal([]byte(value), &document); err != nil {
+				done <- result{err: fmt.Errorf("failed to unmarshal announcement")}
+				return
+			}
+
+			found, err := expression.Search(document)
+			if err != nil {
+				done <- result{err: fmt.Errorf("failed to evaluate expression: %w", err)}
+				return
+			}
+			if isTruthy(found) {
+				matches = append(matches, announcement)
+			}
+		}
+		done <- result{matches: matches}
+	}()
+
+	select {
+	case r := <-done:
+		return r.matches, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// queryFanOut returns an upper bound of the number of values the expression may produce from a single value: the
+// product of the number of elements of its multi-select lists and hashes, which copy their input once per element, so
+// that nesting them grows the result exponentially. Commas within quoted identifiers, string and JSON literals and
+// function arguments are not counted. The bound is capped slightly above maxQueryFanOut to avoid an overflow.
+func queryFanOut(expr string) int {
+	fanOut := 1
+	var elements []int // elements holds the number of elements of the open brackets, braces and parentheses; parentheses are -1.
+	var quote rune
+	for i, r := range expr {
+		if quote != 0 {
+			if r == quote && (i == 0 || expr[i-1] != '\\') {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '"', '\'', '`':
+			quote = r
+		case '[', '{':
+			elements = append(elements, 1)
+		case '(':
+			elements = append(elements, -1)
+		case ',':
+			if n := len(elements); n > 0 && elements[n-1] > 0 {
+				elements[n-1]++
+			}
+		case ']', '}', ')':
+			if n := len(elements); n > 0 {
+				if elements[n-1] > 0 {
+					fanOut = min(fanOut*elements[n-1], maxQueryFanOut+1)
+				}
+				elements = elements[:n-1]
+			}
+		}
+	}
+	return fanOut
+}
+
+// isTruthy reports whether the value is true according to the JMESPath rules: null, false and empty strings,
+// arrays and objects are false, everything else is true.
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
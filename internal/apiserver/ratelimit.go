@@ -0,0 +1,187 @@
+package apiserver
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	"golang.org/x/time/rate"
+)
+
+const (
+	limiterPruneInterval = time.Second // limiterPruneInterval is the minimum interval between the prunes of the idle clients.
+	inflightRetryAfter   = time.Second // inflightRetryAfter is the delay requested from clients rejected by the inflight limit.
+)
+
+// requestLimiter protects the API server from clients sending too many requests. Before authentication, every IP
+// address has a cheap token bucket and the number of requests served concurrently is capped; after authentication,
+// every subject has a token bucket of its own, so that a client cannot escape its limit by sending different tokens.
+// Rejected requests get a 429 response with a Retry-After header.
+type requestLimiter struct {
+	addresses *keyedLimiter // addresses limits the requests per IP address before authentication; nil disables the limit.
+	clients   *keyedLimiter // clients limits the requests per authenticated subject; nil disables the limit.
+	inflight  chan struct{} // inflight holds a slot per request being served; nil disables the inflight limit.
+}
+
+// keyedLimiter holds a token bucket per client key. The buckets of clients idle long enough for their bucket to be
+// full again are dropped, since a new bucket behaves the same, so that the map only holds the recently active clients.
+type keyedLimiter struct {
+	rate  rate.Limit
+	burst int
+	idle  time.Duration // idle is the time after which the bucket of an idle client is full again.
+	clock clock.Clock
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastPrune time.Time
+}
+
+// clientLimiter is the token bucket of a client.
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRequestLimiter creates the limiter of the rate and inflight limits of the config. It returns nil when all of them
+// are disabled.
+func newRequestLimiter(config *model.APIConfig, clk clock.Clock) *requestLimiter {
+	if config.RateLimit <= 0 && config.IPRateLimit <= 0 && config.MaxInflightRequests <= 0 {
+		return nil
+	}
+
+	l := &requestLimiter{
+		addresses: newKeyedLimiter(config.IPRateLimit, config.IPRateLimitBurst, clk),
+		clients:   newKeyedLimiter(config.RateLimit, config.RateLimitBurst, clk),
+	}
+	if config.MaxInflightRequests > 0 {
+		l.inflight = make(chan struct{}, config.MaxInflightRequests)
+	}
+	return l
+}
+
+// newKeyedLimiter creates the per-client token buckets of the rate and burst. It returns nil when the rate is not
+// positive.
+func newKeyedLimiter(limit float64, burst int, clk clock.Clock) *keyedLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	burst = max(burst, 1)
+	return &keyedLimiter{
+		rate:    rate.Limit(limit),
+		burst:   burst,
+		idle:    time.Duration(float64(burst) / limit * float64(time.Second)),
+		clock:   clk,
+		clients: make(map[string]*clientLimiter),
+	}
+}
+
+// AddressMiddleware rejects the requests exceeding the rate limit of their IP address or the inflight limit, before
+// they are authenticated. Watches count against the rate limit when they are established, but not against the
+// inflight limit, since they last for the lifetime of the connection.
+func (l *requestLimiter) AddressMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.addresses != nil {
+			if delay := l.addresses.reserve("ip:" + c.ClientIP()); delay > 0 {
+				rejectedRequests.WithLabelValues("ip_rate_limit").Inc()
+				tooManyRequests(c, delay, "rate limit exceeded")
+				return
+			}
+		}
+
+		if l.inflight != nil && !c.IsWebsocket() && !acceptsEventStream(c) {
+			select {
+			case l.inflight <- struct{}{}:
+				inflightRequests.Inc()
+				defer func() {
+					<-l.inflight
+					inflightRequests.Dec()
+				}()
+			default:
+				rejectedRequests.WithLabelValues("max_inflight").Inc()
+				tooManyRequests(c, inflightRetryAfter, "too many requests in flight")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// ClientMiddleware rejects the requests exceeding the rate limit of their client, identified by the subject set by
+// the authentication middleware it must follow, or by the IP address when authentication is disabled.
+func (l *requestLimiter) ClientMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.clients != nil {
+			if delay := l.clients.reserve(subjectKey(c)); delay > 0 {
+				rejectedRequests.WithLabelValues("rate_limit").Inc()
+				tooManyRequests(c, delay, "rate limit exceeded")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// reserve takes a token from the bucket of the client. It returns zero if the request is allowed, or the time until
+// the next token otherwise, in which case no token is taken.
+func (l *keyedLimiter) reserve(key string) time.Duration {
+	now := l.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+
+	client, ok := l.clients[key]
+	if !ok {
+		client = &clientLimiter{limiter: rate.NewLimiter(l.rate, l.burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = now
+
+	reservation := client.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// prune drops the buckets of the clients idle for long enough for their bucket to be full again. It runs at most once
+// per limiterPruneInterval.
+func (l *keyedLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < limiterPruneInterval {
+		return
+	}
+	l.lastPrune = now
+	for key, client := range l.clients {
+		if now.Sub(client.lastSeen) >= l.idle {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// subjectKey identifies the client of the request by the subject set by the authentication middleware, or by its IP
+// address when authentication is disabled.
+func subjectKey(c *gin.Context) string {
+	if subject := c.GetString(actorContextKey); subject != "" {
+		return "subject:" + subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// tooManyRequests aborts the request with a 429 response asking the client to retry after the delay, rounded up to
+// whole seconds.
+func tooManyRequests(c *gin.Context, delay time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, model.APIResponse{
+		Status:  "error",
+		Message: message,
+		Data:    nil,
+	})
+}
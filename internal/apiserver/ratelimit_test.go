@@ -0,0 +1,132 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// TestKeyedLimiter checks the token bucket of each client and the prune of the idle clients.
+func TestKeyedLimiter(t *testing.T) {
+	clk := clock.NewFakeClock(time.Unix(0, 0))
+	limiter := newKeyedLimiter(1, 2, clk)
+
+	for i := range 2 {
+		if delay := limiter.reserve("a"); delay != 0 {
+			t.Fatalf("request %d within the burst delayed by %s", i, delay)
+		}
+	}
+	if delay := limiter.reserve("a"); delay != time.Second {
+		t.Fatalf("request above the burst delayed by %s, want 1s", delay)
+	}
+	if delay := limiter.reserve("b"); delay != 0 {
+		t.Fatalf("request of another client delayed by %s", delay)
+	}
+
+	// A rejected request takes no token
+	clk.Advance(time.Second)
+	if delay := limiter.reserve("a"); delay != 0 {
+		t.Fatalf("request after the refill delayed by %s", delay)
+	}
+
+	clk.Advance(limiter.idle)
+	limiter.reserve("c")
+	if _, ok := limiter.clients["a"]; ok {
+		t.Fatal("the bucket of an idle client was not dropped")
+	}
+}
+
+// TestRequestLimiterMiddlewares checks the 429 responses of the rate limits of the IP addresses and the clients, and
+// of the inflight limit.
+func TestRequestLimiterMiddlewares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clk := clock.NewFakeClock(time.Unix(0, 0))
+
+	tests := []struct {
+		name      string
+		config    model.APIConfig
+		subjects  []string
+		wantCodes []int
+	}{
+		{
+			name:      "IP rate limit",
+			config:    model.APIConfig{IPRateLimit: 0.5, IPRateLimitBurst: 1},
+			subjects:  []string{"a", "b"},
+			wantCodes: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:      "client rate limit",
+			config:    model.APIConfig{RateLimit: 0.5, RateLimitBurst: 1},
+			subjects:  []string{"a", "a", "b"},
+			wantCodes: []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRequestLimiter(&tt.config, clk)
+			router := gin.New()
+			router.Use(limiter.AddressMiddleware(), func(c *gin.Context) {
+				c.Set(actorContextKey, c.GetHeader("X-Subject"))
+			}, limiter.ClientMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			for i, subject := range tt.subjects {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Subject", subject)
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+
+				if recorder.Code != tt.wantCodes[i] {
+					t.Fatalf("request %d of %s answered %d, want %d", i, subject, recorder.Code, tt.wantCodes[i])
+				}
+				if recorder.Code == http.StatusTooManyRequests && recorder.Header().Get("Retry-After") != "2" {
+					t.Fatalf("Retry-After = %q, want 2", recorder.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+
+	t.Run("inflight limit", func(t *testing.T) {
+		limiter := newRequestLimiter(&model.APIConfig{MaxInflightRequests: 1}, clk)
+		router := gin.New()
+		router.Use(limiter.AddressMiddleware())
+		started, release := make(chan struct{}), make(chan struct{})
+		router.GET("/slow", func(c *gin.Context) {
+			close(started)
+			<-release
+			c.Status(http.StatusOK)
+		})
+		router.GET("/", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		slow := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			router.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		}()
+		<-started
+
+		rejected := httptest.NewRecorder()
+		router.ServeHTTP(rejected, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rejected.Code != http.StatusTooManyRequests || rejected.Header().Get("Retry-After") != "1" {
+			t.Fatalf("request above the inflight limit answered %d with Retry-After %q", rejected.Code, rejected.Header().Get("Retry-After"))
+		}
+
+		close(release)
+		<-done
+		accepted := httptest.NewRecorder()
+		router.ServeHTTP(accepted, httptest.NewRequest(http.MethodGet, "/", nil))
+		if slow.Code != http.StatusOK || accepted.Code != http.StatusOK {
+			t.Fatalf("requests within the inflight limit answered %d and %d", slow.Code, accepted.Code)
+		}
+	})
+}
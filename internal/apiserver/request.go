@@ -0,0 +1,104 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// requestBody returns the middleware that checks the request bodies before any handler reads them: a body must be
+// JSON or YAML, as declared by the Content-Type header, and must not be larger than maxSize bytes, unless maxSize is
+// zero. The body is read at once, so that a body sent without a length is rejected as explicitly as one declaring it.
+func requestBody(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody || c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if _, yaml := yamlContentTypes[mediaType]; err != nil || mediaType != "application/json" && !yaml {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("unsupported content type %q: the request body must be application/json or application/yaml", c.GetHeader("Content-Type")),
+				Data:    nil,
+			})
+			return
+		}
+
+		if maxSize <= 0 {
+			c.Next()
+			return
+		}
+		tooLarge := model.APIResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("request body exceeds the maximum of %d bytes", maxSize),
+			Data:    nil,
+		}
+		if c.Request.ContentLength > maxSize {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "failed to read request body",
+				Data:    nil,
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// bindAnnouncementJSON decodes a request body carrying announcements like c.ShouldBindJSON. With strict decoding,
+// fields unknown to the model are rejected instead of being silently ignored, e.g., a misspelled field.
+func bindAnnouncementJSON(c *gin.Context, strict bool, out interface{}) error {
+	if !strict {
+		return c.ShouldBindJSON(out)
+	}
+	if c.Request.Body == nil {
+		return fmt.Errorf("invalid request")
+	}
+	if err := decodeStrict(c.Request.Body, out); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(out)
+}
+
+// decodeStrict decodes the JSON value, rejecting the fields unknown to out.
+func decodeStrict(r io.Reader, out interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+// listTooLarge responds with an error and returns true when an unpaginated list has more than maxSize items, unless
+// maxSize is zero. The error asks the client to request the list in pages.
+func listTooLarge(c *gin.Context, size, maxSize int) bool {
+	if maxSize <= 0 || size <= maxSize {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, model.APIResponse{
+		Status:  "error",
+		Message: fmt.Sprintf("the list has %d items, more than the maximum of %d: request it in pages with the limit and continue parameters", size, maxSize),
+		Data:    nil,
+	})
+	return true
+}
@@ -0,0 +1,56 @@
+package apiserver
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	etcdRetryBaseBackoff = 50 * time.Millisecond // etcdRetryBaseBackoff is the upper bound of the delay before the first retry.
+	etcdRetryMaxBackoff  = 2 * time.Second       // etcdRetryMaxBackoff caps the exponential growth of the delay.
+)
+
+// etcdRetryInterceptor retries the unary etcd requests failing with a transient error up to maxRetries times. The
+// delays grow exponentially with full jitter, so that the API server replicas do not retry in lockstep after a
+// leader change, and end with the deadline of the request. Writes are retried as well: puts are idempotent and the
+// announcement writes are transactions guarded by the mod revisions, so a retried write is never applied twice.
+func etcdRetryInterceptor(maxRetries int) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		backoff := etcdRetryBaseBackoff
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || attempt > maxRetries || !isTransientEtcdError(err) {
+				return err
+			}
+
+			delay := rand.N(backoff)
+			slog.Debug("retrying etcd request", "operation", path.Base(method), "attempt", attempt, "delay", delay, "error", err)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+			backoff = min(backoff*2, etcdRetryMaxBackoff)
+		}
+	}
+}
+
+// isTransientEtcdError reports whether the request may succeed when retried, e.g., after a leader election or when
+// the cluster was temporarily overloaded.
+func isTransientEtcdError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,160 @@
+package apiserver
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// registerRIBRoutes adds the read-only routes that list the paths in the RIB of the GoBGP instance, so that operators
+// can compare what is programmed with what the announcements require. When the GoBGP client is not configured, the
+// routes respond with 503 Service Unavailable.
+func registerRIBRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, goBGP *gobgp.Client, clk clock.Clock) {
+	group := v1.Group("/rib")
+	group.Use(requireGoBGP(goBGP))
+
+	group.GET("/announced", func(c *gin.Context) {
+		var filter netip.Prefix
+		if value := c.Query("prefix"); value != "" {
+			prefix, err := parsePrefixFilter(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    nil,
+				})
+				return
+			}
+			filter = prefix
+		}
+
+		owners, err := announcedPathOwners(db, clk.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		paths := make([]model.RIBPath, 0)
+		for _, family := range []string{model.AddressFamilyIPv4Unicast, model.AddressFamilyIPv6Unicast} {
+			if filter.IsValid() && model.AddressFamilyOf(filter.Addr().String()) != family {
+				continue
+			}
+
+			localPaths, err := goBGP.ListLocalPaths(family)
+			if err != nil {
+				c.JSON(http.StatusBadGateway, model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    nil,
+				})
+				return
+			}
+
+			for _, path := range localPaths {
+				prefix, err := netip.ParsePrefix(path.Prefix + "/" + strconv.FormatUint(uint64(path.PrefixLength), 10))
+				if err != nil || filter.IsValid() && !prefixWithin(prefix, []netip.Prefix{filter}) {
+					continue
+				}
+				paths = append(paths, model.RIBPath{
+					Prefix:        prefix.String(),
+					NextHop:       path.NextHop,
+					Origin:        model.OriginFromCode(path.Attributes.Origin),
+					MED:           path.Attributes.MED,
+					LocalPref:     path.Attributes.LocalPref,
+					ASPath:        path.Attributes.ASPath,
+					Communities:   path.Attributes.Communities,
+					LinkBandwidth: path.Attributes.LinkBandwidth,
+					Age:           path.Age,
+					Announcements: owners[ribPathKey(prefix.Addr(), path.NextHop)],
+				})
+			}
+		}
+
+		slices.SortFunc(paths, func(a, b model.RIBPath) int {
+			return cmp.Or(cmp.Compare(a.Prefix, b.Prefix), cmp.Compare(a.NextHop, b.NextHop))
+		})
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announced paths retrieved successfully",
+			Data:    paths,
+		})
+	})
+}
+
+// parsePrefixFilter parses the prefix query parameter given in CIDR notation or as a single address.
+func parsePrefixFilter(value string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("prefix must be an IP address or a prefix in CIDR notation")
+	}
+	return prefix.Masked(), nil
+}
+
+// announcedPathOwners maps the paths required by the announcements that are not withdrawn, cancelled or expired and
+// are within their schedule at the time to the announcements. Every announced address is routed via every next hop of
+// its address family that is not draining.
+func announcedPathOwners(db model.DatabaseAdapter, now time.Time) (map[string][]model.AnnouncementRef, error) {
+	data, err := db.GetObjects(announcementsPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string][]model.AnnouncementRef)
+	for _, value := range data {
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal announcement")
+		}
+		if announcement.Status.Status == model.StatusWithdrawn || announcement.Status.Status == model.StatusCancelled {
+			continue
+		}
+		// The routes of the announcements outside their schedule are withdrawn
+		if announcement.Status.Status == model.StatusExpired || !announcement.Active(now) {
+			continue
+		}
+
+		// The paths via draining next hops are withdrawn
+		nextHops := slices.DeleteFunc(announcement.NextHopAddresses(), announcement.Status.Drained)
+
+		ref := model.AnnouncementRef{Project: announcement.Meta.Project, Name: announcement.Meta.Name}
+		for _, address := range announcement.AnnouncedAddresses() {
+			addr, err := netip.ParseAddr(address)
+			if err != nil {
+				continue
+			}
+			for _, nextHop := range nextHops {
+				key := ribPathKey(addr.Unmap(), nextHop)
+				if !slices.Contains(owners[key], ref) {
+					owners[key] = append(owners[key], ref)
+				}
+			}
+		}
+	}
+	return owners, nil
+}
+
+// ribPathKey identifies a host path by its address and next hop.
+func ribPathKey(addr netip.Addr, nextHop string) string {
+	if hop, err := netip.ParseAddr(nextHop); err == nil {
+		nextHop = hop.Unmap().String()
+	}
+	return addr.String() + " via " + nextHop
+}
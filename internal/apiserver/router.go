@@ -1,46 +1,293 @@
 package apiserver
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
 	"github.com/nikitamishagin/corebgp/internal/model"
-	"go.etcd.io/etcd/client/v3"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // NewAPIServer initializes and runs a new API server on port 8080. It returns an error if the server fails to start.
-func NewAPIServer(databaseAdapter model.DatabaseAdapter) error {
-	router := setupRouter(databaseAdapter)
+func NewAPIServer(databaseAdapter model.DatabaseAdapter, config *model.APIConfig) error {
+	// Connect to GoBGP if it is configured to serve the BGP session endpoints
+	var goBGPClient *gobgp.Client
+	if config.GoBGPEndpoint != "" {
+		var err error
+		goBGPClient, err = gobgp.NewClient(&config.GoBGPEndpoint, &config.GoBGPCACert, &config.GoBGPClientCert, &config.GoBGPClientKey)
+		if err != nil {
+			return err
+		}
+		defer goBGPClient.Close()
+	}
+
+	clk := clock.RealClock{}
+
+	// Write the structured access log when a destination is configured
+	var middlewares []gin.HandlerFunc
+	if config.AccessLogPath != "" {
+		accessLog := openAccessLog(config.AccessLogPath)
+		defer accessLog.Close()
+		middlewares = append(middlewares, NewStructuredAccessLogger(accessLog, clk).Middleware())
+	}
+
+	handler, err := NewHandler(databaseAdapter, goBGPClient, config, clk, middlewares...)
+	if err != nil {
+		return err
+	}
+	defer handler.Close()
+
+	// Serve the gRPC API on a separate listener when an address is configured, with the TLS settings of the REST API
+	if config.GRPCAddr != "" {
+		var grpcTLSConfig *tls.Config
+		if config.TLSCert != "" {
+			if grpcTLSConfig, err = newServerTLSConfig(config); err != nil {
+				return err
+			}
+			cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+			if err != nil {
+				return fmt.Errorf("could not load TLS certificate: %w", err)
+			}
+			grpcTLSConfig.Certificates = []tls.Certificate{cert}
+		}
+		grpcServer, err := startGRPCServer(config.GRPCAddr, handler.router, grpcTLSConfig)
+		if err != nil {
+			return err
+		}
+		defer grpcServer.Stop()
+	}
+
+	// Serve the profiling endpoints on a separate listener only
+	if config.PprofAddr != "" {
+		pprofServer := startPprofServer(config.PprofAddr)
+		defer pprofServer.Close()
+	}
+
+	server := &http.Server{
+		Addr:    ":8080",
+		Handler: handler,
+	}
+
+	// Serve HTTPS when a certificate is configured, plain HTTP otherwise
+	if config.TLSCert == "" {
+		server.Handler = h2cHandler(handler)
+		return server.ListenAndServe()
+	}
 
-	err := router.Run(":8080")
+	tlsConfig, err := newServerTLSConfig(config)
 	if err != nil {
 		return err
 	}
+	server.TLSConfig = tlsConfig
+
+	return server.ListenAndServeTLS(config.TLSCert, config.TLSKey)
+}
+
+// h2cHandler serves HTTP/2 with prior knowledge (h2c) next to HTTP/1.1 on a plain HTTP listener, for the clients that
+// force HTTP/2 without TLS. The TLS listener negotiates HTTP/2 with ALPN.
+func h2cHandler(handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{})
+}
+
+// Handler serves the REST API and runs the background collectors of the API server, without its listeners. It lets
+// the API server be embedded, e.g., by the test servers of the apiservertest package.
+type Handler struct {
+	router   *gin.Engine
+	notifier *WebhookNotifier
+	stop     chan struct{} // stop is closed to stop the collectors.
+}
+
+// NewHandler sets up the REST API on the database as configured, except for the listeners, and starts the background
+// collectors. A nil GoBGP client disables the GoBGP endpoints. The middlewares are applied to all routes after the
+// built-in ones. Close stops the collectors.
+func NewHandler(db model.DatabaseAdapter, goBGP *gobgp.Client, config *model.APIConfig, clk clock.Clock, middlewares ...gin.HandlerFunc) (*Handler, error) {
+	// Require authentication on the v1 API when a policy file or an OIDC issuer is configured
+	authenticator, err := NewAuthenticator(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject announcements breaking the prefix policy when a policy file is configured, and warn about or reject
+	// announcements overlapping prefixes of other projects
+	policy, err := LoadPrefixPolicy(config.PrefixPolicyFile, config.CrossProjectConflicts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify the webhooks of the announcement lifecycle events when a webhooks file is configured
+	notifier, err := LoadWebhooks(config.WebhooksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Let the admission webhooks change or reject the created and updated announcements when a file is configured
+	admission, err := LoadAdmissionWebhooks(config.AdmissionWebhooksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := setupRouter(db, goBGP, config, authenticator, policy, admission, notifier, clk, middlewares...)
+	if err != nil {
+		return nil, err
+	}
+	h := &Handler{
+		router:   router,
+		notifier: notifier,
+		stop:     make(chan struct{}),
+	}
+
+	// Remove soft-deleted announcements after the retention period and the expired idempotent responses, and mark the
+	// announcements whose schedule has ended as expired
+	go runIdempotencyCollector(db, clk, h.stop)
+	go runWithdrawnCollector(db, newChangeLog(db, clk, config.HistoryRevisions, config.AnnouncementEvents, notifier), config.WithdrawnRetention, clk, h.stop)
+	go runExpiryMarker(db, newChangeLog(db, clk, config.HistoryRevisions, config.AnnouncementEvents, notifier), clk, h.stop)
+	return h, nil
+}
 
-	return nil
+// ServeHTTP serves a request of the REST API.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.router.ServeHTTP(w, r)
+}
+
+// Close stops the collectors and the webhook notifications.
+func (h *Handler) Close() {
+	close(h.stop)
+	h.notifier.Close()
 }
 
 // setupRouter initializes and returns a new Gin Engine with predefined routes for health checks and API endpoints.
-func setupRouter(db model.DatabaseAdapter) *gin.Engine {
+// The middlewares are applied to all routes after the built-in ones.
+// A nil authenticator leaves the v1 API unauthenticated, a nil policy admits announcements with any prefix, nil
+// admission webhooks admit every change, a nil notifier sends no webhook notifications.
+func setupRouter(db model.DatabaseAdapter, goBGP *gobgp.Client, config *model.APIConfig, authenticator *Authenticator, policy *PrefixPolicy, admission *AdmissionWebhooks, notifier *WebhookNotifier, clk clock.Clock, middlewares ...gin.HandlerFunc) (*gin.Engine, error) {
 	router := gin.Default()
+	// The client IP of the audit log, the access log and the rate limits is taken from the forwarding headers of the
+	// trusted proxies only, so that clients cannot spoof it
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(tracing.Middleware(), sizeMetrics(), durationMetrics())
+	router.Use(middlewares...)
+	router.Use(responseEncoding(), requestBody(config.MaxRequestBodySize), yamlBody())
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	registerHealthRoutes(router, db, goBGP)
+
+	v1 := router.Group("/v1")
+	// Throttle the IP addresses before authenticating the clients, so that a client retrying in a loop is rejected
+	// cheaply, and the authenticated subjects after, so that a client cannot escape its limit with made-up tokens
+	limiter := newRequestLimiter(config, clk)
+	if limiter != nil {
+		v1.Use(limiter.AddressMiddleware())
+	}
+	if authenticator != nil {
+		v1.Use(authenticator.Middleware())
+	}
+	if limiter != nil {
+		v1.Use(limiter.ClientMiddleware())
+	}
+	v1.Use(idempotency(db, clk))
 
-	router.GET("/healthz", func(c *gin.Context) {
-		// Check connection to etcd
-		if err := db.HealthCheck(); err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	registerGoBGPRoutes(v1, goBGP)
+	registerRIBRoutes(v1, db, goBGP, clk)
+	registerProjectRoutes(v1, db)
+	registerPeerRoutes(v1, db, clk)
+	registerQueryRoutes(v1, db, config.QueryTimeout)
+
+	v1.GET("/announcements", func(c *gin.Context) {
+		nextHop := c.Query("nextHop")
+		if nextHop == "" {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "nextHop query parameter is required",
+				Data:    nil,
+			})
+			return
+		}
+		if net.ParseIP(nextHop) == nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "nextHop must be a valid IP address",
+				Data:    nil,
+			})
 			return
 		}
-		c.String(http.StatusOK, "ok")
-	})
 
-	v1 := router.Group("/v1")
+		selector, err := querySelector(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		data, err := db.ListByNextHop(nextHop)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		announcementList := make([]model.Announcement, 0, len(data))
+		for _, value := range data {
+			var announcement model.Announcement
+			err = json.Unmarshal([]byte(value), &announcement)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+			if skipWithdrawn(c, &announcement) || !selector.matches(announcement.Meta.Labels) {
+				continue
+			}
+			announcementList = append(announcementList, announcement)
+		}
+
+		if listTooLarge(c, len(announcementList), config.MaxListSize) {
+			return
+		}
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcements retrieved successfully",
+			Data:    announcementList,
+		})
+	})
 
 	v1.GET("/announcements/", func(c *gin.Context) {
 		prefix := "v1/announcements/"
+		selector, err := querySelector(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
 
-		data, err := db.List(prefix)
+		data, err := db.GetObjects(prefix)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.APIResponse{
 				Status:  "error",
@@ -50,15 +297,49 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 
+		// Group announcements by project
+		announcementsByProject := make(map[string][]model.Announcement)
+		listed := 0
+		for _, value := range data {
+			var announcement model.Announcement
+			err = json.Unmarshal([]byte(value), &announcement)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+			if skipWithdrawn(c, &announcement) || !selector.matches(announcement.Meta.Labels) {
+				continue
+			}
+			project := announcement.Meta.Project
+			announcementsByProject[project] = append(announcementsByProject[project], announcement)
+			listed++
+		}
+
+		if listTooLarge(c, listed, config.MaxListSize) {
+			return
+		}
 		c.JSON(http.StatusOK, model.APIResponse{
 			Status:  "success",
 			Message: "Announcements retrieved successfully",
-			Data:    data,
+			Data:    announcementsByProject,
 		})
 	})
 
 	v1.GET("/announcements/all", func(c *gin.Context) {
 		prefix := "v1/announcements/"
+		selector, err := querySelector(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
 
 		data, err := db.GetObjects(prefix)
 		if err != nil {
@@ -82,9 +363,15 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 				})
 				return
 			}
+			if skipWithdrawn(c, &announcement) || !selector.matches(announcement.Meta.Labels) {
+				continue
+			}
 			announcementList = append(announcementList, announcement)
 		}
 
+		if listTooLarge(c, len(announcementList), config.MaxListSize) {
+			return
+		}
 		c.JSON(http.StatusOK, model.APIResponse{
 			Status:  "success",
 			Message: "Announcements retrieved successfully",
@@ -95,8 +382,17 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 	v1.GET("/announcements/:project/", func(c *gin.Context) {
 		project := c.Param("project")
 		prefix := "v1/announcements/" + project + "/"
+		selector, err := querySelector(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
 
-		data, err := db.List(prefix)
+		data, err := db.GetObjects(prefix)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.APIResponse{
 				Status:  "error",
@@ -106,16 +402,63 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 
+		keys := make([]string, 0, len(data))
+		for _, value := range data {
+			var announcement model.Announcement
+			err = json.Unmarshal([]byte(value), &announcement)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+			if skipWithdrawn(c, &announcement) || !selector.matches(announcement.Meta.Labels) {
+				continue
+			}
+			keys = append(keys, prefix+announcement.Meta.Name)
+		}
+
+		if listTooLarge(c, len(keys), config.MaxListSize) {
+			return
+		}
 		c.JSON(http.StatusOK, model.APIResponse{
 			Status:  "success",
 			Message: "Announcements retrieved successfully",
-			Data:    data,
+			Data:    keys,
 		})
 	})
 
 	v1.GET("/announcements/:project/all", func(c *gin.Context) {
 		project := c.Param("project")
-		prefix := "v1/announcements/" + project + "/"
+		namePrefix := c.Query("namePrefix")
+		if strings.Contains(namePrefix, "/") {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "namePrefix must not contain '/'",
+				Data:    nil,
+			})
+			return
+		}
+		// Narrow the listed key range to the names starting with the prefix
+		prefix := "v1/announcements/" + project + "/" + namePrefix
+
+		selector, err := querySelector(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		// Serve a single page when pagination is requested
+		if c.Query("limit") != "" {
+			listAnnouncementsPage(c, db, prefix, selector)
+			return
+		}
 
 		data, err := db.GetObjects(prefix)
 		if err != nil {
@@ -139,9 +482,15 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 				})
 				return
 			}
+			if skipWithdrawn(c, &announcement) || !selector.matches(announcement.Meta.Labels) {
+				continue
+			}
 			announcementList = append(announcementList, announcement)
 		}
 
+		if listTooLarge(c, len(announcementList), config.MaxListSize) {
+			return
+		}
 		c.JSON(http.StatusOK, model.APIResponse{
 			Status:  "success",
 			Message: "Announcements retrieved successfully",
@@ -188,6 +537,7 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 
+		c.Header("ETag", `"`+announcement.Meta.ResourceVersion+`"`)
 		c.JSON(http.StatusOK, model.APIResponse{
 			Status:  "success",
 			Message: "Announcement retrieved successfully",
@@ -195,10 +545,27 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 		})
 	})
 
-	// Write routes
+	// Write routes are serialized per project to avoid racing read-modify-write cycles, every write is recorded in
+	// the audit log and the revision history, and sent to the webhooks
+	serializer := NewPerProjectSerializer()
+	changes := newChangeLog(db, clk, config.HistoryRevisions, config.AnnouncementEvents, notifier)
+	registerBatchRoutes(v1, db, serializer, changes, policy, admission, config, clk)
+	registerApplyRoutes(v1, db, serializer, changes, policy, admission, config, clk)
+	registerStatusRoutes(v1, db, serializer, changes, clk)
+	registerDrainRoutes(v1, db, serializer, changes, clk)
+	registerCollectionRoutes(v1, db, serializer, changes)
+	registerExportRoutes(v1, db, serializer, changes, policy, config, clk)
+	registerConflictRoutes(v1, db)
+	registerPoolRoutes(v1, db, serializer)
+	registerProjectResourceRoutes(v1, db, serializer)
+	registerSiteRoutes(v1, db, clk)
+	registerAuditRoutes(v1, db)
+	registerHistoryRoutes(v1, db, serializer, changes, policy, admission, clk)
+	registerEventRoutes(v1, db)
+
 	v1.POST("/announcements/", func(c *gin.Context) {
 		var data model.Announcement
-		if err := c.ShouldBindJSON(&data); err != nil {
+		if err := bindAnnouncementJSON(c, config.StrictDecoding, &data); err != nil {
 			c.JSON(http.StatusBadRequest, model.APIResponse{
 				Status:  "error",
 				Message: err.Error(),
@@ -207,8 +574,39 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 
+		if err := checkNameLength(data.Meta.Name, config.MaxAnnouncementNameLength); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		if err := validateAnnouncement(&data); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		unlock := serializer.Lock(data.Meta.Project)
+		defer unlock()
+
 		key := "v1/announcements/" + data.Meta.Project + "/" + data.Meta.Name
 		_, err := db.Get(key)
+		if err == nil && c.GetHeader("If-None-Match") == "*" {
+			// Conditional create requested by the client, report the failed precondition
+			c.JSON(http.StatusPreconditionFailed, model.APIResponse{
+				Status:  "error",
+				Message: "announcement already exists",
+				Data:    nil,
+			})
+			return
+		}
+
 		if err == nil {
 			c.JSON(http.StatusConflict, model.APIResponse{
 				Status:  "error",
@@ -227,6 +625,44 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 
+		if err := admission.admit(c, isDryRun(c), nil, &data); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		// The allocation is recorded by the announcement itself and released when it is deleted
+		if err := allocatePoolAddress(db, &data, nil); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		unlockConflicts := lockConflicts(serializer, policy)
+		defer unlockConflicts()
+
+		if err := checkProjectAdmission(db, policy, &data, nil); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		// The resource version is assigned by the storage and the status is reported by the controllers through
+		// the status subresource
+		data.Meta.ResourceVersion = ""
+		data.Status = model.Status{}
+		now := clk.Now()
+		resolveSchedule(&data, now)
+		stampGeneration(c, nil, &data, now)
 		value, err := json.Marshal(data)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.APIResponse{
@@ -237,6 +673,18 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 
+		if isDryRun(c) {
+			c.JSON(http.StatusCreated, model.APIResponse{
+				Status:  "success",
+				Message: "Announcement would be created (dry run)",
+				Data: model.Event{
+					Type:         model.EventAdded,
+					Announcement: data,
+				},
+			})
+			return
+		}
+
 		err = db.Put("v1/announcements/"+data.Meta.Project+"/"+data.Meta.Name, string(value))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.APIResponse{
@@ -246,6 +694,8 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			})
 			return
 		}
+		refreshResourceVersion(db, key, &data)
+		changes.record(c, nil, &data)
 
 		c.JSON(http.StatusCreated, model.APIResponse{
 			Status:  "success",
@@ -257,9 +707,9 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 		})
 	})
 
-	v1.PATCH("/announcements/", func(c *gin.Context) {
+	v1.POST("/announcements/validate", func(c *gin.Context) {
 		var data model.Announcement
-		if err := c.ShouldBindJSON(&data); err != nil {
+		if err := bindAnnouncementJSON(c, config.StrictDecoding, &data); err != nil {
 			c.JSON(http.StatusBadRequest, model.APIResponse{
 				Status:  "error",
 				Message: err.Error(),
@@ -268,26 +718,203 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 
-		key := "v1/announcements/" + data.Meta.Project + "/" + data.Meta.Name
-		_, err := db.Get(key)
-		if err != nil && err.Error() == "key not found" {
-			c.JSON(http.StatusNotFound, model.APIResponse{
-				Status:  "error",
-				Message: "announcement not found",
-				Data:    nil,
-			})
-			return
+		// Run all checks without persisting anything
+		report := checkAnnouncement(&data)
+		if err := checkNameLength(data.Meta.Name, config.MaxAnnouncementNameLength); err != nil {
+			report.Errors = append(report.Errors, validationErrors(err)...)
 		}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, model.APIResponse{
-				Status:  "error",
-				Message: err.Error(),
-				Data:    nil,
+		// The admission webhooks review the announcement in dry-run mode, as a create or an update of the stored one.
+		// Like the writes, they only see announcements that pass the checks above
+		var previous *model.Announcement
+		if len(report.Errors) == 0 {
+			value, err := db.Get(announcementsPrefix + data.Meta.Project + "/" + data.Meta.Name)
+			if err != nil && err.Error() != "key not found" {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: fmt.Errorf("failed to check announcement existence: %w", err).Error(),
+					Data:    nil,
+				})
+				return
+			}
+			if err == nil {
+				previous = &model.Announcement{}
+				if err := json.Unmarshal([]byte(value), previous); err != nil {
+					previous = &model.Announcement{Meta: model.Meta{Project: data.Meta.Project, Name: data.Meta.Name}}
+				}
+			}
+
+			if err := admission.admit(c, true, previous, &data); err != nil {
+				fields := validationErrors(err)
+				if fields == nil {
+					fields = []model.ValidationError{{Message: err.Error()}}
+				}
+				report.Errors = append(report.Errors, fields...)
+			}
+		}
+
+		// A create is checked with the address its pool would allocate. A pool that can not allocate one is reported
+		// once, by the allocation
+		if previous == nil && data.Addresses.Pool != "" && data.Addresses.AnnouncedIP == "" {
+			if err := allocatePoolAddress(db, &data, nil); err != nil {
+				fields := validationErrors(err)
+				if fields == nil {
+					c.JSON(http.StatusInternalServerError, model.APIResponse{
+						Status:  "error",
+						Message: err.Error(),
+						Data:    nil,
+					})
+					return
+				}
+				report.Errors = append(report.Errors, fields...)
+				data.Addresses.Pool = ""
+			}
+		}
+
+		admissionErrors, err := projectAdmissionErrors(db, policy, &data, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		report.Errors = append(report.Errors, admissionErrors...)
+		report.Valid = len(report.Errors) == 0
+
+		if policy.conflictMode() == model.ConflictsWarn {
+			conflicts, err := crossProjectConflicts(db, &data)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: err.Error(),
+					Data:    nil,
+				})
+				return
+			}
+			for _, conflict := range conflicts {
+				report.Warnings = append(report.Warnings, conflictMessage(conflict))
+			}
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement validated successfully",
+			Data:    report,
+		})
+	})
+
+	v1.PATCH("/announcements/", func(c *gin.Context) {
+		var data model.Announcement
+		if err := bindAnnouncementJSON(c, config.StrictDecoding, &data); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		// The update is conditional if the client passes the resource version it read, the If-Match header
+		// takes precedence over the version in the body
+		if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+			data.Meta.ResourceVersion = parseIfMatch(ifMatch)
+		}
+		if version := data.Meta.ResourceVersion; version != "" {
+			if revision, err := strconv.ParseInt(version, 10, 64); err != nil || revision <= 0 {
+				c.JSON(http.StatusBadRequest, model.APIResponse{
+					Status:  "error",
+					Message: fmt.Sprintf("invalid resource version %q", version),
+					Data:    nil,
+				})
+				return
+			}
+		}
+
+		if err := checkNameLength(data.Meta.Name, config.MaxAnnouncementNameLength); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		if err := validateAnnouncement(&data); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
 			})
 			return
 		}
 
+		unlock := serializer.Lock(data.Meta.Project)
+		defer unlock()
+
+		key := "v1/announcements/" + data.Meta.Project + "/" + data.Meta.Name
+		stored, err := db.Get(key)
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "announcement not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var previous model.Announcement
+		if err := json.Unmarshal([]byte(stored), &previous); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := admission.admit(c, isDryRun(c), &previous, &data); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		unlockConflicts := lockConflicts(serializer, policy)
+		defer unlockConflicts()
+
+		if err := checkProjectAdmission(db, policy, &data, nil); err != nil {
+			c.JSON(validationErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    validationErrors(err),
+			})
+			return
+		}
+
+		// The status is reported by the controllers through the status subresource and is kept as stored.
+		// An update re-activates a cancelled announcement
+		data.Status = previous.Status
+		if data.Status.Status == model.StatusCancelled {
+			data.Status.Status = model.StatusPending
+		}
+		pruneDrainedNextHops(&data)
+		now := clk.Now()
+		resolveSchedule(&data, now)
+		stampGeneration(c, &previous, &data, now)
+
 		value, err := json.Marshal(data)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, model.APIResponse{
@@ -295,17 +922,33 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 				Message: err.Error(),
 				Data:    nil,
 			})
+			return
+		}
+
+		if isDryRun(c) {
+			c.JSON(http.StatusOK, model.APIResponse{
+				Status:  "success",
+				Message: "Announcement would be patched (dry run)",
+				Data: model.Event{
+					Type:         model.EventUpdated,
+					Announcement: data,
+					Previous:     &previous,
+				},
+			})
+			return
 		}
 
 		err = db.Put("v1/announcements/"+data.Meta.Project+"/"+data.Meta.Name, string(value))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, model.APIResponse{
+			c.JSON(writeErrorStatus(err), model.APIResponse{
 				Status:  "error",
 				Message: fmt.Errorf("failed to patch announcement: %w", err).Error(),
 				Data:    nil,
 			})
 			return
 		}
+		refreshResourceVersion(db, key, &data)
+		changes.record(c, &previous, &data)
 
 		c.JSON(http.StatusOK, model.APIResponse{
 			Status:  "success",
@@ -313,6 +956,7 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			Data: model.Event{
 				Type:         model.EventUpdated,
 				Announcement: data,
+				Previous:     &previous,
 			},
 		})
 	})
@@ -322,10 +966,16 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 		CheckOrigin: func(r *http.Request) bool {
 			return true // Allow connections from any client
 		},
+		EnableCompression: true, // Negotiate permessage-deflate when the client requests it
 	}
 
-	// Route for watching announcements
+	// Route for watching announcements over a WebSocket, or as Server-Sent Events when the client asks for them
 	v1.GET("/watch/announcements/", func(c *gin.Context) {
+		if !c.IsWebsocket() && acceptsEventStream(c) {
+			serveEventStream(c, db, config.WatchHeartbeatInterval)
+			return
+		}
+
 		// Upgrade HTTP connection to WebSocket
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
@@ -337,79 +987,233 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			return
 		}
 		defer conn.Close()
+		conn.EnableWriteCompression(true)
 
-		// Create a channel to stop the Watch
-		stopChan := make(chan struct{})
+		watchConnections.Inc()
+		defer watchConnections.Dec()
 
-		// Start watching keys with the prefix "/v1/announcements/"
-		eventsChan, err := db.Watch("v1/announcements/", stopChan)
+		// Parse the revision to resume the watch from, if provided, and the filter of the events
+		revision, err := parseWatchRevision(c.Query("revision"))
+		var filter watchFilter
+		if err == nil {
+			filter, err = parseWatchFilter(c)
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, model.APIResponse{
+			_ = conn.WriteJSON(model.APIResponse{
 				Status:  "error",
-				Message: fmt.Errorf("failed to start watching: %w", err).Error(),
+				Message: err.Error(),
 				Data:    nil,
 			})
 			return
 		}
 
+		// Create a channel to stop the Watch
+		stopChan := make(chan struct{})
+
 		// Goroutine to read from WebSocket connection
+		extendDeadline := keepWatchAlive(conn, config.WatchHeartbeatInterval)
 		go func() {
 			defer close(stopChan)
 			for {
 				_, _, err := conn.ReadMessage()
 				if err != nil {
-					// Stop work on read error (e.g., the client disconnected)
+					// Stop work on read error (e.g., the client disconnected or stopped answering the pings)
 					return
 				}
+				extendDeadline()
 			}
 		}()
 
-		// TODO: Fix deleting method for preview value
-
-		// Read changes from events and send them to the client
-		for watchResp := range eventsChan {
-			for _, watchEvent := range watchResp.Events {
-				var eventResp model.Event
-
-				switch watchEvent.Type {
-				case clientv3.EventTypePut:
-					if watchEvent.IsCreate() {
-						eventResp.Type = model.EventAdded
-					} else {
-						eventResp.Type = model.EventUpdated
-					}
-
-					err := json.Unmarshal(watchEvent.Kv.Value, &eventResp.Announcement)
-					if err != nil {
-						fmt.Printf("failed to unmarshal announcement: %v\n", err)
-						continue
-					}
-				case clientv3.EventTypeDelete:
-					eventResp.Type = model.EventDeleted
-
-					if watchEvent.PrevKv == nil {
-						err := json.Unmarshal(watchEvent.PrevKv.Value, &eventResp.Announcement)
-						if err != nil {
-							fmt.Printf("failed to unmarshal announcement: %v\n", err)
-							continue
-						}
-					}
-				}
+		// Start watching the keys of the selected announcements
+		eventsChan, err := db.Watch(filter.prefix(), revision, stopChan)
+		if err != nil {
+			_ = conn.WriteJSON(model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to start watching: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
 
-				// Send the eventResp to the client via WebSocket
-				if err := conn.WriteJSON(eventResp); err != nil {
-					return
+		// Read changes from events and send them to the client via WebSocket, pinging the client with every bookmark
+		streamAnnouncementEvents(eventsChan, filter, revision, config.WatchHeartbeatInterval, func(event model.Event) error {
+			if event.Type == model.EventBookmark {
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(config.WatchHeartbeatInterval)); err != nil {
+					return err
 				}
 			}
+			return conn.WriteJSON(event)
+		})
+	})
+
+	v1.POST("/announcements/:project/:name/withdraw", func(c *gin.Context) {
+		project := c.Param("project")
+		name := c.Param("name")
+
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		key := "v1/announcements/" + project + "/" + name
+		value, err := db.Get(key)
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "announcement not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to check announcement existence: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var announcement model.Announcement
+		err = json.Unmarshal([]byte(value), &announcement)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+
+		// Mark the announcement as withdrawn instead of removing the record
+		previous := announcement
+		announcement.Status.Status = model.StatusWithdrawn
+		announcement.Status.Timestamp = clk.Now().UTC().Format(time.RFC3339)
+
+		newValue, err := json.Marshal(announcement)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		err = db.Put(key, string(newValue))
+		if err != nil {
+			c.JSON(writeErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to withdraw announcement: %w", err).Error(),
+				Data:    nil,
+			})
+			return
 		}
+		refreshResourceVersion(db, key, &announcement)
+		changes.record(c, &previous, &announcement)
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement withdrawn successfully",
+			Data: model.Event{
+				Type:         model.EventUpdated,
+				Announcement: announcement,
+			},
+		})
+	})
+
+	v1.POST("/announcements/:project/:name/cancel", func(c *gin.Context) {
+		project := c.Param("project")
+		name := c.Param("name")
+
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		key := "v1/announcements/" + project + "/" + name
+		value, err := db.Get(key)
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "announcement not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to check announcement existence: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var announcement model.Announcement
+		err = json.Unmarshal([]byte(value), &announcement)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+
+		// Only announcements that are still waiting to be programmed can be cancelled
+		if announcement.Status.Status != "" && announcement.Status.Status != model.StatusPending {
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("announcement is not pending: %s", announcement.Status.Status),
+				Data:    nil,
+			})
+			return
+		}
+
+		previous := announcement
+		announcement.Status.Status = model.StatusCancelled
+		announcement.Status.Timestamp = clk.Now().UTC().Format(time.RFC3339)
+
+		newValue, err := json.Marshal(announcement)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		err = db.Put(key, string(newValue))
+		if err != nil {
+			c.JSON(writeErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to cancel announcement: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+		refreshResourceVersion(db, key, &announcement)
+		changes.record(c, &previous, &announcement)
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement cancelled successfully",
+			Data: model.Event{
+				Type:         model.EventUpdated,
+				Announcement: announcement,
+			},
+		})
 	})
 
 	v1.DELETE("/announcements/:project/:name", func(c *gin.Context) {
 		project := c.Param("project")
 		name := c.Param("name")
 
+		unlock := serializer.Lock(project)
+		defer unlock()
+
 		key := "v1/announcements/" + project + "/" + name
-		_, err := db.Get(key)
+		value, err := db.Get(key)
 		if err != nil && err.Error() == "key not found" {
 			c.JSON(http.StatusNotFound, model.APIResponse{
 				Status:  "error",
@@ -425,6 +1229,30 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 				Message: fmt.Errorf("failed to check announcement existence: %w", err).Error(),
 				Data:    nil,
 			})
+			return
+		}
+
+		var announcement model.Announcement
+		decodeErr := json.Unmarshal([]byte(value), &announcement)
+		if isDryRun(c) {
+			if decodeErr != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+
+			c.JSON(http.StatusOK, model.APIResponse{
+				Status:  "success",
+				Message: "Announcement would be deleted (dry run)",
+				Data: model.Event{
+					Type:         model.EventDeleted,
+					Announcement: announcement,
+				},
+			})
+			return
 		}
 
 		err = db.Delete(key)
@@ -436,6 +1264,11 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 			})
 			return
 		}
+		// A malformed announcement is audited by its identity only
+		if decodeErr != nil {
+			announcement = model.Announcement{Meta: model.Meta{Project: project, Name: name}}
+		}
+		changes.record(c, &announcement, nil)
 
 		c.JSON(http.StatusOK, model.APIResponse{
 			Status:  "success",
@@ -447,5 +1280,8 @@ func setupRouter(db model.DatabaseAdapter) *gin.Engine {
 		})
 	})
 
-	return router
+	// Describe the routes registered above in the OpenAPI document
+	registerOpenAPIRoutes(router, authenticator != nil, config.SwaggerUI)
+
+	return router, nil
 }
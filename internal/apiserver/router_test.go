@@ -0,0 +1,57 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// TestPlainHTTPServesHTTP2 checks that the plain HTTP listener serves the clients forcing HTTP/2 with h2c and keeps
+// serving HTTP/1.1.
+func TestPlainHTTPServesHTTP2(t *testing.T) {
+	db := NewMemoryStore()
+	defer db.Close()
+	handler, err := NewHandler(db, nil, &model.APIConfig{DBType: "memory", QueryTimeout: 2 * time.Second}, clock.RealClock{})
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	defer handler.Close()
+
+	var protocol atomic.Int32
+	server := httptest.NewServer(h2cHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protocol.Store(int32(r.ProtoMajor))
+		handler.ServeHTTP(w, r)
+	})))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		opts         []v1.ClientOption
+		announcement *model.Announcement
+		wantMajor    int32
+	}{
+		{name: "HTTP/1.1", announcement: testAnnouncement("alpha", "http1", "192.0.2.1"), wantMajor: 1},
+		{name: "h2c", opts: []v1.ClientOption{v1.WithHTTP2()}, announcement: testAnnouncement("alpha", "h2c", "192.0.2.2"), wantMajor: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := v1.NewAPIClient(&server.URL, 5*time.Second, tt.opts...)
+			if err := client.V1CreateAnnouncement(context.Background(), tt.announcement); err != nil {
+				t.Fatalf("V1CreateAnnouncement: %v", err)
+			}
+			if _, err := client.V1GetAnnouncement(context.Background(), "alpha", tt.announcement.Meta.Name); err != nil {
+				t.Fatalf("V1GetAnnouncement: %v", err)
+			}
+			if got := protocol.Load(); got != tt.wantMajor {
+				t.Fatalf("served over HTTP/%d, want HTTP/%d", got, tt.wantMajor)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// expiryMarkerActor is the actor of the audit entries of the announcements marked as expired.
+const expiryMarkerActor = "system:expiry-marker"
+
+// expiryInterval is the interval at which the announcements whose schedule has ended are marked as expired. The
+// updater withdraws their routes at the end of the schedule regardless of the mark.
+const expiryInterval = 10 * time.Second
+
+// resolveSchedule sets the end of the schedule of a written announcement from its TTL, which counts from the write or
+// from the start of the schedule, if later. An expired announcement whose schedule no longer ends before the write is
+// pending again.
+func resolveSchedule(announcement *model.Announcement, now time.Time) {
+	schedule := announcement.Schedule
+	if schedule == nil {
+		return
+	}
+
+	if schedule.TTL > 0 {
+		start := now
+		if from, _ := schedule.Window(); from.After(start) {
+			start = from
+		}
+		schedule.ActiveUntil = start.Add(time.Duration(schedule.TTL) * time.Second).UTC().Format(time.RFC3339)
+	}
+
+	if _, until := schedule.Window(); announcement.Status.Status == model.StatusExpired && (until.IsZero() || now.Before(until)) {
+		announcement.Status.Status = model.StatusPending
+	}
+}
+
+// runExpiryMarker periodically marks the announcements whose schedule has ended as expired until stopChan is closed.
+func runExpiryMarker(db model.DatabaseAdapter, changes *changeLog, clk clock.Clock, stopChan <-chan struct{}) {
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			if err := markExpired(db, changes, clk); err != nil {
+				slog.Error("failed to mark expired announcements", "error", err)
+			}
+		}
+	}
+}
+
+// markExpired sets the status of the announcements whose schedule has ended to expired. The writes are conditional on
+// the resource version read, so that a concurrent update is not overwritten; the announcement is marked on the next
+// run instead.
+func markExpired(db model.DatabaseAdapter, changes *changeLog, clk clock.Clock) error {
+	data, err := db.GetObjects(announcementsPrefix)
+	if err != nil {
+		return err
+	}
+
+	now := clk.Now()
+	for _, value := range data {
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			continue
+		}
+		switch announcement.Status.Status {
+		case model.StatusExpired, model.StatusWithdrawn, model.StatusCancelled:
+			continue
+		}
+		if _, until := announcement.Schedule.Window(); until.IsZero() || now.Before(until) {
+			continue
+		}
+
+		previous := announcement
+		announcement.Status.Status = model.StatusExpired
+		announcement.Status.Timestamp = now.UTC().Format(time.RFC3339)
+		newValue, err := json.Marshal(announcement)
+		if err != nil {
+			return err
+		}
+
+		key := announcementsPrefix + announcement.Meta.Project + "/" + announcement.Meta.Name
+		if err := db.Put(key, string(newValue)); err != nil {
+			slog.Warn("failed to mark announcement as expired", "project", announcement.Meta.Project, "name", announcement.Meta.Name, "error", err)
+			continue
+		}
+		refreshResourceVersion(db, key, &announcement)
+		changes.write(model.AuditEntry{Actor: expiryMarkerActor}, &previous, &announcement)
+		slog.Info("announcement expired", "project", announcement.Meta.Project, "name", announcement.Meta.Name, "active_until", announcement.Schedule.ActiveUntil)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package apiserver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// selectorOperator is the operator of a label selector requirement.
+type selectorOperator string
+
+const (
+	selectorEquals    selectorOperator = "="      // selectorEquals requires the label to have the value.
+	selectorNotEquals selectorOperator = "!="     // selectorNotEquals requires the label to be missing or to have another value.
+	selectorIn        selectorOperator = "in"     // selectorIn requires the label to have one of the values.
+	selectorNotIn     selectorOperator = "notin"  // selectorNotIn requires the label to be missing or to have none of the values.
+	selectorExists    selectorOperator = "exists" // selectorExists requires the label to be set.
+	selectorNotExists selectorOperator = "!"      // selectorNotExists requires the label to be missing.
+)
+
+// selectorRequirement is a single condition on a label.
+type selectorRequirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+// labelSelector selects announcements by their labels. All requirements must be met; an empty selector selects
+// all announcements.
+type labelSelector []selectorRequirement
+
+// parseLabelSelector parses a comma separated list of requirements in the syntax of Kubernetes label selectors:
+// "key=value", "key==value", "key!=value", "key in (a,b)", "key notin (a,b)", "key" and "!key".
+func parseLabelSelector(value string) (labelSelector, error) {
+	var selector labelSelector
+	for _, term := range splitSelector(value) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			if strings.TrimSpace(value) == "" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid label selector %q: empty requirement", value)
+		}
+
+		requirement, err := parseRequirement(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", value, err)
+		}
+		selector = append(selector, requirement)
+	}
+	return selector, nil
+}
+
+// splitSelector splits the selector at the commas outside the value sets.
+func splitSelector(value string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range value {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, value[start:])
+}
+
+// parseRequirement parses a single requirement of a selector.
+func parseRequirement(term string) (selectorRequirement, error) {
+	if key, ok := strings.CutPrefix(term, "!"); ok {
+		return newRequirement(strings.TrimSpace(key), selectorNotExists, nil)
+	}
+	if key, value, ok := strings.Cut(term, "!="); ok {
+		return newRequirement(strings.TrimSpace(key), selectorNotEquals, []string{strings.TrimSpace(value)})
+	}
+	if key, value, ok := strings.Cut(term, "="); ok {
+		value = strings.TrimPrefix(value, "=")
+		return newRequirement(strings.TrimSpace(key), selectorEquals, []string{strings.TrimSpace(value)})
+	}
+
+	fields := strings.Fields(term)
+	if len(fields) == 1 {
+		return newRequirement(fields[0], selectorExists, nil)
+	}
+	key, rest, _ := strings.Cut(term, " ")
+	operator, set, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	if operator != string(selectorIn) && operator != string(selectorNotIn) {
+		return selectorRequirement{}, fmt.Errorf("unknown operator %q in %q", operator, term)
+	}
+	set = strings.TrimSpace(set)
+	if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+		return selectorRequirement{}, fmt.Errorf("the values of %q must be enclosed in parentheses", term)
+	}
+	var values []string
+	for _, value := range strings.Split(set[1:len(set)-1], ",") {
+		values = append(values, strings.TrimSpace(value))
+	}
+	return newRequirement(key, selectorOperator(operator), values)
+}
+
+// newRequirement checks the key and the values of a requirement.
+func newRequirement(key string, operator selectorOperator, values []string) (selectorRequirement, error) {
+	if err := validateLabelKey(key); err != nil {
+		return selectorRequirement{}, err
+	}
+	for _, value := range values {
+		if err := validateLabelValue(value); err != nil {
+			return selectorRequirement{}, err
+		}
+	}
+	return selectorRequirement{key: key, operator: operator, values: values}, nil
+}
+
+// matches reports whether the labels meet all requirements of the selector.
+func (s labelSelector) matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		value, ok := labels[requirement.key]
+		var met bool
+		switch requirement.operator {
+		case selectorEquals, selectorIn:
+			met = ok && slices.Contains(requirement.values, value)
+		case selectorNotEquals, selectorNotIn:
+			met = !ok || !slices.Contains(requirement.values, value)
+		case selectorExists:
+			met = ok
+		case selectorNotExists:
+			met = !ok
+		}
+		if !met {
+			return false
+		}
+	}
+	return true
+}
+
+// querySelector parses the labelSelector query parameter of the request.
+func querySelector(c *gin.Context) (labelSelector, error) {
+	return parseLabelSelector(c.Query("labelSelector"))
+}
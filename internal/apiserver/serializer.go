@@ -0,0 +1,61 @@
+package apiserver
+
+import "sync"
+
+// PerProjectSerializer serializes write operations within a project while letting writes to different projects
+// proceed in parallel. It prevents concurrent read-modify-write cycles on the same project from racing each other
+// without a global lock. The few writes checked against all projects take the global lock in addition.
+type PerProjectSerializer struct {
+	mu     sync.Mutex
+	locks  map[string]*projectLock // locks maps the project name to its lock while the lock is held or awaited.
+	global sync.Mutex              // global serializes the writes checked against the announcements of all projects.
+}
+
+// projectLock is the lock of a project, counting the writers holding or waiting for it so that it is dropped when
+// none is left and the map does not grow with every project ever written.
+type projectLock struct {
+	mu   sync.Mutex
+	refs int // refs is the number of writers holding or waiting for the lock; guarded by PerProjectSerializer.mu.
+}
+
+// NewPerProjectSerializer creates an empty serializer.
+func NewPerProjectSerializer() *PerProjectSerializer {
+	return &PerProjectSerializer{locks: make(map[string]*projectLock)}
+}
+
+// Lock waits until no other update of the project is in flight and returns the function releasing the lock.
+func (s *PerProjectSerializer) Lock(project string) func() {
+	s.mu.Lock()
+	lock, ok := s.locks[project]
+	if !ok {
+		lock = &projectLock{}
+		s.locks[project] = lock
+	}
+	lock.refs++
+	s.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if lock.refs--; lock.refs == 0 {
+			delete(s.locks, project)
+		}
+	}
+}
+
+// LockGlobal waits until no other write checked against all projects is in flight and returns the function releasing
+// the lock. It is taken after the locks of the written projects, never before, to avoid deadlocks.
+func (s *PerProjectSerializer) LockGlobal() func() {
+	s.global.Lock()
+	return s.global.Unlock
+}
+
+// Do runs fn while holding the lock of the project.
+func (s *PerProjectSerializer) Do(project string, fn func() error) error {
+	unlock := s.Lock(project)
+	defer unlock()
+	return fn()
+}
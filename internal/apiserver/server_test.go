@@ -0,0 +1,183 @@
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// testResponse is the API response of a test request with the data left encoded.
+type testResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// testServer serves the REST API on the in-memory datastore.
+type testServer struct {
+	URL   string
+	Store model.DatabaseAdapter
+}
+
+// newTestServer starts the REST API on the in-memory datastore with the defaults of the command flags, changed by
+// configure unless it is nil. The server is shut down when the test completes.
+func newTestServer(t *testing.T, configure func(config *model.APIConfig)) *testServer {
+	t.Helper()
+	return newTestServerOn(t, NewMemoryStore(), configure)
+}
+
+// newTestServerOn starts the REST API like newTestServer on the datastore, which is closed when the test completes.
+func newTestServerOn(t *testing.T, db model.DatabaseAdapter, configure func(config *model.APIConfig)) *testServer {
+	t.Helper()
+	config := &model.APIConfig{
+		DBType:                    "memory",
+		CrossProjectConflicts:     model.ConflictsWarn,
+		HistoryRevisions:          10,
+		AnnouncementEvents:        50,
+		WithdrawnRetention:        7 * 24 * time.Hour,
+		QueryTimeout:              2 * time.Second,
+		MaxAnnouncementNameLength: 253,
+		RateLimitBurst:            50,
+		MaxInflightRequests:       400,
+		MaxRequestBodySize:        10 << 20,
+		WatchHeartbeatInterval:    15 * time.Second,
+		OIDCSubjectClaim:          "sub",
+	}
+	if configure != nil {
+		configure(config)
+	}
+
+	gin.SetMode(gin.TestMode)
+	handler, err := NewHandler(db, nil, config, clock.RealClock{})
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		server.Close()
+		handler.Close()
+		db.Close()
+	})
+	return &testServer{URL: server.URL, Store: db}
+}
+
+// do sends the request with the body encoded as JSON unless it is nil and returns the status code and the decoded
+// API response. The header is given as name and value pairs.
+func (s *testServer) do(t *testing.T, method, path string, body any, header ...string) (int, testResponse) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to marshal the request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("failed to build the request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var response testResponse
+	data, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(data, &response)
+	return resp.StatusCode, response
+}
+
+// client returns an API client of the server.
+func (s *testServer) client(opts ...v1.ClientOption) *v1.APIClient {
+	return v1.NewAPIClient(&s.URL, 5*time.Second, opts...)
+}
+
+// stored returns the announcement kept in the datastore of the server.
+func (s *testServer) stored(t *testing.T, project, name string) model.Announcement {
+	t.Helper()
+	value, err := s.Store.Get(announcementsPrefix + project + "/" + name)
+	if err != nil {
+		t.Fatalf("announcement %s/%s is not stored: %v", project, name, err)
+	}
+	var announcement model.Announcement
+	if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+		t.Fatalf("malformed stored announcement: %v", err)
+	}
+	return announcement
+}
+
+// put stores the announcement directly in the datastore of the server, bypassing the API.
+func (s *testServer) put(t *testing.T, announcement *model.Announcement) {
+	t.Helper()
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		t.Fatalf("failed to marshal the announcement: %v", err)
+	}
+	if err := s.Store.Put(announcementsPrefix+announcement.Meta.Project+"/"+announcement.Meta.Name, string(data)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+}
+
+// testAnnouncement returns a valid announcement of the address via a single next hop.
+func testAnnouncement(project, name, address string) *model.Announcement {
+	return &model.Announcement{
+		Meta:      model.Meta{Project: project, Name: name},
+		Addresses: model.Addresses{AnnouncedIP: address},
+		NextHops:  []model.Subnet{{IP: "10.0.0.1", Mask: 32}},
+	}
+}
+
+// newAdmissionWebhook starts an HTTPS admission webhook answering the reviews with review and returns its
+// configuration trusting the certificate of the server. The server is shut down when the test completes.
+func newAdmissionWebhook(t *testing.T, name, hookType string, review func(review *model.AdmissionReview) model.AdmissionResponse) model.AdmissionWebhook {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request model.AdmissionReview
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(review(&request))
+	}))
+	t.Cleanup(server.Close)
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caCert, certificate, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return model.AdmissionWebhook{Name: name, URL: server.URL, Type: hookType, CACert: caCert}
+}
+
+// writeAdmissionWebhooks writes the admission webhooks file with the webhooks and returns its path.
+func writeAdmissionWebhooks(t *testing.T, hooks ...model.AdmissionWebhook) string {
+	t.Helper()
+	data, err := json.Marshal(model.AdmissionConfig{Webhooks: hooks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "admission.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,246 @@
+package apiserver
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// sitesPrefix is the key prefix under which the updater registrations are stored by site.
+const sitesPrefix = "v1/sites/"
+
+// validateSiteID checks a site ID, which is a part of the storage key and of the field paths of the overrides.
+func validateSiteID(site string) error {
+	if site == "" || strings.ContainsAny(site, "/. ") {
+		return fmt.Errorf("site ID %q must not be empty nor contain '/', '.' or spaces", site)
+	}
+	return nil
+}
+
+// getSite returns the site with the updaters registered with it, or nil if none is.
+func getSite(db model.DatabaseAdapter, name string) (*model.Site, error) {
+	value, err := db.Get(sitesPrefix + name)
+	if err != nil && err.Error() == "key not found" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get site: %w", err)
+	}
+
+	var site model.Site
+	if err := json.Unmarshal([]byte(value), &site); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal site")
+	}
+	return &site, nil
+}
+
+// registerSiteRoutes adds the routes that list the sites and register the updaters with them. An updater started
+// with a site ID renews its registration periodically and removes it on shutdown.
+func registerSiteRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, clk clock.Clock) {
+	// Registrations of the updaters of the same site are read, modified and written one at a time
+	var mu sync.Mutex
+
+	v1.GET("/sites", func(c *gin.Context) {
+		data, err := db.GetObjects(sitesPrefix)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		sites := make([]model.Site, 0, len(data))
+		for _, value := range data {
+			var site model.Site
+			if err := json.Unmarshal([]byte(value), &site); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal site",
+					Data:    nil,
+				})
+				return
+			}
+			sites = append(sites, site)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Sites retrieved successfully",
+			Data:    sites,
+		})
+	})
+
+	v1.GET("/sites/:site", func(c *gin.Context) {
+		site, err := getSite(db, c.Param("site"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if site == nil {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "site not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Site retrieved successfully",
+			Data:    site,
+		})
+	})
+
+	v1.PUT("/sites/:site/updaters/:identity", func(c *gin.Context) {
+		var updater model.SiteUpdater
+		if err := c.ShouldBindJSON(&updater); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		name := c.Param("site")
+		if err := validateSiteID(name); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		// The identity is taken from the path, a different one in the body would register another updater
+		if updater.Identity != "" && updater.Identity != c.Param("identity") {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "identity must match the path",
+				Data:    nil,
+			})
+			return
+		}
+		updater.Identity = c.Param("identity")
+		updater.LastSeen = clk.Now().UTC().Format(time.RFC3339)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		site, err := getSite(db, name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+		if site == nil {
+			site = &model.Site{Name: name}
+		}
+
+		site.Updaters = slices.DeleteFunc(site.Updaters, func(registered model.SiteUpdater) bool {
+			return registered.Identity == updater.Identity
+		})
+		site.Updaters = append(site.Updaters, updater)
+		slices.SortFunc(site.Updaters, func(a, b model.SiteUpdater) int {
+			return cmp.Compare(a.Identity, b.Identity)
+		})
+		writeSite(c, db, site, "Updater registered successfully")
+	})
+
+	v1.DELETE("/sites/:site/updaters/:identity", func(c *gin.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		site, err := getSite(db, c.Param("site"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		identity := c.Param("identity")
+		if site == nil || !slices.ContainsFunc(site.Updaters, func(registered model.SiteUpdater) bool { return registered.Identity == identity }) {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "updater not registered with the site",
+				Data:    nil,
+			})
+			return
+		}
+
+		site.Updaters = slices.DeleteFunc(site.Updaters, func(registered model.SiteUpdater) bool {
+			return registered.Identity == identity
+		})
+		if len(site.Updaters) > 0 {
+			writeSite(c, db, site, "Updater deregistered successfully")
+			return
+		}
+
+		// The site is listed while updaters are registered with it
+		if err := db.Delete(sitesPrefix + site.Name); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to delete site: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Updater deregistered successfully",
+			Data:    site,
+		})
+	})
+}
+
+// writeSite stores the site and responds with it.
+func writeSite(c *gin.Context, db model.DatabaseAdapter, site *model.Site, message string) {
+	value, err := json.Marshal(site)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := db.Put(sitesPrefix+site.Name, string(value)); err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: fmt.Errorf("failed to write site: %w", err).Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.APIResponse{
+		Status:  "success",
+		Message: message,
+		Data:    site,
+	})
+}
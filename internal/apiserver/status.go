@@ -0,0 +1,284 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// registerStatusRoutes adds the routes of the announcement status subresource, which is reported by controllers
+// such as the updater.
+func registerStatusRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter, serializer *PerProjectSerializer, changes *changeLog, clk clock.Clock) {
+	v1.GET("/announcements/:project/:name/status", func(c *gin.Context) {
+		value, err := db.Get(announcementsPrefix + c.Param("project") + "/" + c.Param("name"))
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "announcement not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement status retrieved successfully",
+			Data:    announcement.Status,
+		})
+	})
+
+	v1.GET("/announcements/:project/:name/health", func(c *gin.Context) {
+		value, err := db.Get(announcementsPrefix + c.Param("project") + "/" + c.Param("name"))
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "announcement not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement health retrieved successfully",
+			Data:    announcementHealth(&announcement.Status),
+		})
+	})
+
+	v1.PATCH("/announcements/:project/:name/status", func(c *gin.Context) {
+		var patch model.Status
+		if err := c.ShouldBindJSON(&patch); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := validateStatusPatch(&patch); err != nil {
+			c.JSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		project := c.Param("project")
+		unlock := serializer.Lock(project)
+		defer unlock()
+
+		key := announcementsPrefix + project + "/" + c.Param("name")
+		value, err := db.Get(key)
+		if err != nil && err.Error() == "key not found" {
+			c.JSON(http.StatusNotFound, model.APIResponse{
+				Status:  "error",
+				Message: "announcement not found",
+				Data:    nil,
+			})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: "failed to unmarshal announcement",
+				Data:    nil,
+			})
+			return
+		}
+
+		// Cancelled, withdrawn and expired announcements are not programmed, so controllers have nothing to report on them
+		switch announcement.Status.Status {
+		case model.StatusCancelled, model.StatusWithdrawn, model.StatusExpired:
+			c.JSON(http.StatusConflict, model.APIResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("announcement is %s", announcement.Status.Status),
+				Data:    nil,
+			})
+			return
+		}
+
+		// The patch may modify the lists of the status in place, the audit entry needs an unaffected copy
+		var previous model.Announcement
+		_ = json.Unmarshal([]byte(value), &previous)
+		now := clk.Now()
+		applyStatusPatch(&announcement.Status, &patch, now.UTC().Format(time.RFC3339))
+		observed := observeGeneration(&announcement, now)
+
+		newValue, err := json.Marshal(announcement)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		if err := db.Put(key, string(newValue)); err != nil {
+			c.JSON(writeErrorStatus(err), model.APIResponse{
+				Status:  "error",
+				Message: fmt.Errorf("failed to update announcement status: %w", err).Error(),
+				Data:    nil,
+			})
+			return
+		}
+		if observed {
+			propagationDuration.Observe(announcement.Status.PropagationSeconds)
+		}
+		refreshResourceVersion(db, key, &announcement)
+		changes.record(c, &previous, &announcement)
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Announcement status updated successfully",
+			Data: model.Event{
+				Type:         model.EventUpdated,
+				Announcement: announcement,
+			},
+		})
+	})
+}
+
+// validateStatusPatch checks that the status patch reports an operational state and well-formed conditions. The
+// lifecycle states are changed by their own endpoints only.
+func validateStatusPatch(patch *model.Status) error {
+	switch patch.Status {
+	case "", model.StatusPending, model.StatusProgrammed, model.StatusFailed, model.StatusUnsupportedAddressFamily:
+	default:
+		return fmt.Errorf("status %q cannot be reported by a controller", patch.Status)
+	}
+
+	for _, condition := range patch.Conditions {
+		if condition.Type == "" {
+			return fmt.Errorf("condition type is required")
+		}
+		switch condition.Status {
+		case model.ConditionTrue, model.ConditionFalse, model.ConditionUnknown:
+		default:
+			return fmt.Errorf("condition %s has invalid status %q: must be %s, %s or %s",
+				condition.Type, condition.Status, model.ConditionTrue, model.ConditionFalse, model.ConditionUnknown)
+		}
+	}
+	return nil
+}
+
+// applyStatusPatch merges the patch into the status. The operational state is replaced when set in the patch, the
+// details and the router states are replaced by site and the conditions by type. Router states without a timestamp and
+// conditions that change their status without a transition time get the current time.
+func applyStatusPatch(status, patch *model.Status, now string) {
+	if patch.Status != "" {
+		status.Status = patch.Status
+	}
+	if patch.Details != nil {
+		// The updaters of a site report only the probe results of their site
+		sites := make(map[string]bool)
+		for _, details := range patch.Details {
+			sites[details.Site] = true
+		}
+		if len(patch.Details) == 0 {
+			sites[""] = true
+		}
+		details := slices.DeleteFunc(status.Details, func(details model.Details) bool {
+			return sites[""] || details.Site == "" || sites[details.Site]
+		})
+		status.Details = append(details, patch.Details...)
+	}
+	if patch.Routers != nil {
+		// The updaters of a site report only the routers of their site
+		sites := make(map[string]bool)
+		for _, router := range patch.Routers {
+			sites[router.Site] = true
+		}
+		routers := slices.DeleteFunc(status.Routers, func(router model.RouterStatus) bool {
+			return sites[""] || router.Site == "" || sites[router.Site]
+		})
+		status.Routers = append(routers, patch.Routers...)
+		for i := range status.Routers {
+			if status.Routers[i].Timestamp == "" {
+				status.Routers[i].Timestamp = now
+			}
+		}
+	}
+	for _, condition := range patch.Conditions {
+		if current := status.Condition(condition.Type); condition.LastTransitionTime == "" && (current == nil || current.Status != condition.Status) {
+			condition.LastTransitionTime = now
+		}
+		status.SetCondition(condition)
+	}
+	status.Timestamp = now
+}
+
+// announcementHealth summarizes the probe results reported in the status. Every site probes the next hops on its
+// own, so a next hop checked by the updaters of two sites is counted twice.
+func announcementHealth(status *model.Status) model.AnnouncementHealth {
+	health := model.AnnouncementHealth{
+		Total:    len(status.Details),
+		NextHops: status.Details,
+	}
+	if health.NextHops == nil {
+		health.NextHops = []model.Details{}
+	}
+	for _, details := range status.Details {
+		if details.Status == model.HealthHealthy {
+			health.Healthy++
+		}
+	}
+	return health
+}
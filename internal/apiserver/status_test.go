@@ -0,0 +1,72 @@
+package apiserver
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// TestStatusIsKeptFromWriters checks that the creates and the batches ignore the status sent by the client, which is
+// reported by the controllers through the status subresource only.
+func TestStatusIsKeptFromWriters(t *testing.T) {
+	server := newTestServer(t, nil)
+	forged := model.Status{Status: model.StatusProgrammed, Routers: []model.RouterStatus{{Router: "tor-1", Status: model.StatusProgrammed}}}
+
+	t.Run("create", func(t *testing.T) {
+		announcement := testAnnouncement("alpha", "create", "192.0.2.1")
+		announcement.Status = forged
+		if code, response := server.do(t, http.MethodPost, "/v1/announcements/", announcement); code != http.StatusCreated {
+			t.Fatalf("create answered %d: %s", code, response.Message)
+		}
+		if status := server.stored(t, "alpha", "create").Status; status.Status != "" || len(status.Routers) != 0 {
+			t.Fatalf("create stored the status of the client: %+v", status)
+		}
+	})
+
+	t.Run("batch create", func(t *testing.T) {
+		announcement := testAnnouncement("alpha", "batch-create", "192.0.2.2")
+		announcement.Status = forged
+		batch := model.BatchRequest{Operations: []model.BatchOperation{{Action: model.BatchApply, Announcement: *announcement}}}
+		if code, response := server.do(t, http.MethodPost, "/v1/announcements/batch", batch); code != http.StatusOK {
+			t.Fatalf("batch answered %d: %s", code, response.Message)
+		}
+		if status := server.stored(t, "alpha", "batch-create").Status; status.Status != "" || len(status.Routers) != 0 {
+			t.Fatalf("batch create stored the status of the client: %+v", status)
+		}
+	})
+
+	t.Run("batch update", func(t *testing.T) {
+		stored := testAnnouncement("alpha", "batch-update", "192.0.2.3")
+		stored.Status = model.Status{Status: model.StatusFailed, Details: []model.Details{{Message: "router down"}}}
+		server.put(t, stored)
+
+		announcement := testAnnouncement("alpha", "batch-update", "192.0.2.3")
+		announcement.Status = forged
+		batch := model.BatchRequest{Operations: []model.BatchOperation{{Action: model.BatchApply, Announcement: *announcement}}}
+		if code, response := server.do(t, http.MethodPost, "/v1/announcements/batch", batch); code != http.StatusOK {
+			t.Fatalf("batch answered %d: %s", code, response.Message)
+		}
+		status := server.stored(t, "alpha", "batch-update").Status
+		if status.Status != model.StatusFailed || len(status.Details) != 1 || len(status.Routers) != 0 {
+			t.Fatalf("batch update did not keep the stored status: %+v", status)
+		}
+	})
+
+	t.Run("batch update of a cancelled announcement", func(t *testing.T) {
+		stored := testAnnouncement("alpha", "batch-cancelled", "192.0.2.4")
+		stored.Status = model.Status{Status: model.StatusCancelled}
+		server.put(t, stored)
+
+		// The reset follows the stored status, not the one the client claims
+		announcement := testAnnouncement("alpha", "batch-cancelled", "192.0.2.4")
+		announcement.Status = forged
+		batch := model.BatchRequest{Operations: []model.BatchOperation{{Action: model.BatchApply, Announcement: *announcement}}}
+		if code, response := server.do(t, http.MethodPost, "/v1/announcements/batch", batch); code != http.StatusOK {
+			t.Fatalf("batch answered %d: %s", code, response.Message)
+		}
+		if status := server.stored(t, "alpha", "batch-cancelled").Status; status.Status != model.StatusPending {
+			t.Fatalf("status = %q, want %q", status.Status, model.StatusPending)
+		}
+	})
+}
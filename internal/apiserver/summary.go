@@ -0,0 +1,82 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// registerProjectRoutes adds the routes that expose aggregate information about projects.
+func registerProjectRoutes(v1 *gin.RouterGroup, db model.DatabaseAdapter) {
+	v1.GET("/projects/:project/summary", func(c *gin.Context) {
+		project := c.Param("project")
+
+		data, err := db.GetObjects(announcementsPrefix + project + "/")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.APIResponse{
+				Status:  "error",
+				Message: err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		announcements := make([]model.Announcement, 0, len(data))
+		for _, value := range data {
+			var announcement model.Announcement
+			if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+				c.JSON(http.StatusInternalServerError, model.APIResponse{
+					Status:  "error",
+					Message: "failed to unmarshal announcement",
+					Data:    nil,
+				})
+				return
+			}
+			if skipWithdrawn(c, &announcement) {
+				continue
+			}
+			announcements = append(announcements, announcement)
+		}
+
+		c.JSON(http.StatusOK, model.APIResponse{
+			Status:  "success",
+			Message: "Project summary retrieved successfully",
+			Data:    summarizeProject(announcements),
+		})
+	})
+}
+
+// summarizeProject counts the announcements by status and finds the oldest and the newest creation times. The
+// announcements created before the creation time was recorded are not considered for them.
+func summarizeProject(announcements []model.Announcement) model.ProjectSummary {
+	summary := model.ProjectSummary{
+		TotalAnnouncements: len(announcements),
+	}
+
+	for _, announcement := range announcements {
+		switch announcement.Status.Status {
+		case model.StatusProgrammed:
+			summary.ProgrammedCount++
+		case model.StatusFailed, model.StatusUnsupportedAddressFamily:
+			summary.FailedCount++
+		case "", model.StatusPending:
+			summary.PendingCount++
+		}
+
+		timestamp, err := time.Parse(time.RFC3339Nano, announcement.Meta.CreatedAt)
+		if err != nil {
+			continue
+		}
+		if summary.OldestAnnouncement.IsZero() || timestamp.Before(summary.OldestAnnouncement) {
+			summary.OldestAnnouncement = timestamp
+		}
+		if timestamp.After(summary.NewestAnnouncement) {
+			summary.NewestAnnouncement = timestamp
+		}
+	}
+
+	return summary
+}
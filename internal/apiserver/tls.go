@@ -0,0 +1,33 @@
+package apiserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// newServerTLSConfig builds the TLS configuration of the API server listener. When a client CA is configured,
+// clients must present a certificate signed by it (mutual TLS).
+func newServerTLSConfig(config *model.APIConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.TLSClientCA != "" {
+		caCert, err := os.ReadFile(config.TLSClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client CA certificate: %w", err)
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to append client CA certificate")
+		}
+		tlsConfig.ClientCAs = caPool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,717 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// validator checks a single aspect of an announcement and records the results in the report.
+type validator func(announcement *model.Announcement, report *model.ValidationReport)
+
+// validators is the ordered list of checks applied to every announcement.
+var validators = []validator{
+	validateMeta,
+	validateLabels,
+	validateAddresses,
+	validateNextHops,
+	validateWeightedNextHops,
+	validateFailover,
+	validateOrigin,
+	validateASPathPrepend,
+	validateCommunities,
+	validateHealthCheck,
+	validateBFDPeers,
+	validateSchedule,
+	validateSites,
+}
+
+// checkAnnouncement runs all validators against the announcement and returns the full report.
+func checkAnnouncement(announcement *model.Announcement) model.ValidationReport {
+	report := model.ValidationReport{
+		Warnings: []string{},
+		Errors:   []model.ValidationError{},
+	}
+	for _, validate := range validators {
+		validate(announcement, &report)
+	}
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// validateAnnouncement checks the announcement fields before it is written to the database. The returned error is
+// an *invalidAnnouncementError listing the invalid fields.
+func validateAnnouncement(announcement *model.Announcement) error {
+	report := checkAnnouncement(announcement)
+	if report.Valid {
+		return nil
+	}
+	return &invalidAnnouncementError{errors: report.Errors}
+}
+
+// invalidAnnouncementError is returned for announcements rejected by the validation.
+type invalidAnnouncementError struct {
+	errors []model.ValidationError
+}
+
+func (e *invalidAnnouncementError) Error() string {
+	messages := make([]string, 0, len(e.errors))
+	for _, validationError := range e.errors {
+		if validationError.Field == "" {
+			messages = append(messages, validationError.Message)
+			continue
+		}
+		messages = append(messages, validationError.Field+": "+validationError.Message)
+	}
+	return fmt.Sprintf("invalid announcement: %s", strings.Join(messages, "; "))
+}
+
+// validationErrors returns the invalid fields reported by the error, which are sent as the data of the error
+// response, or nil if the error is not a validation error.
+func validationErrors(err error) []model.ValidationError {
+	var invalid *invalidAnnouncementError
+	if errors.As(err, &invalid) {
+		return invalid.errors
+	}
+	return nil
+}
+
+// validationErrorStatus returns the HTTP status of a failed validation, which is an internal error if the validation
+// itself failed.
+func validationErrorStatus(err error) int {
+	if validationErrors(err) != nil {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// projectAdmissionErrors returns an error for every announced prefix of the announcement that overlaps a prefix announced
+// by another active announcement of the same project, and for every limit of the project quota the announcement
+// exceeds, and for every rule of the prefix policy it breaks, and if its announced IP is not a free address of its pool. Withdrawn and cancelled announcements are not programmed
+// and are ignored. Prefixes overlapping those of other projects are errors in reject mode only. The pending writes of
+// a batch or an import are admitted as if they were stored already; nil admits the announcement on its own.
+func projectAdmissionErrors(db model.DatabaseAdapter, policy *PrefixPolicy, announcement *model.Announcement, pending *pendingWrites) ([]model.ValidationError, error) {
+	project, err := pending.project(db, announcement.Meta.Project)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := db.GetObjects(announcementsPrefix + announcement.Meta.Project + "/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project announcements: %w", err)
+	}
+	values = pending.apply(announcement.Meta.Project, values)
+
+	prefixes := announcedPrefixes(announcement)
+	var errs []model.ValidationError
+	exists, count := false, 0
+	for _, value := range values {
+		var other model.Announcement
+		if err := json.Unmarshal([]byte(value), &other); err != nil {
+			continue
+		}
+		if other.Meta.Name == announcement.Meta.Name {
+			exists = true
+			continue
+		}
+		if other.Status.Status == model.StatusWithdrawn {
+			continue
+		}
+		count++
+		if other.Status.Status == model.StatusCancelled || other.Status.Status == model.StatusExpired {
+			continue
+		}
+
+		otherPrefixes := announcedPrefixes(&other)
+		for field, prefix := range prefixes {
+			for _, otherPrefix := range otherPrefixes {
+				if prefix.Overlaps(otherPrefix) {
+					errs = append(errs, model.ValidationError{
+						Field:   field,
+						Message: fmt.Sprintf("%s overlaps %s announced by %s", prefix, otherPrefix, other.Meta.Name),
+					})
+				}
+			}
+		}
+	}
+	if project != nil {
+		errs = append(errs, quotaErrors(project, prefixes, exists, count)...)
+	}
+	errs = append(errs, policy.violations(announcement)...)
+	poolErrs, err := poolErrors(db, announcement, values)
+	if err != nil {
+		return nil, err
+	}
+	errs = append(errs, poolErrs...)
+	if policy.conflictMode() == model.ConflictsReject {
+		conflicts, err := crossProjectConflicts(db, announcement)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, conflictErrors(conflicts)...)
+	}
+
+	// Report the errors in a stable order
+	slices.SortFunc(errs, func(a, b model.ValidationError) int {
+		return strings.Compare(a.Field+a.Message, b.Field+b.Message)
+	})
+	return errs, nil
+}
+
+// pendingWrites holds the writes of a batch or an import admitted before the write, so that the admission of every
+// announcement counts the announcements created by the others against the project quota and checks their prefixes
+// against each other, as if they were stored already. A nil *pendingWrites holds no writes.
+type pendingWrites struct {
+	announcements map[string]*model.Announcement // announcements holds the written announcements by project and name; nil for a deletion.
+	projects      map[string]*model.Project      // projects holds the written projects by name.
+}
+
+// newPendingWrites returns an empty set of pending writes.
+func newPendingWrites() *pendingWrites {
+	return &pendingWrites{
+		announcements: make(map[string]*model.Announcement),
+		projects:      make(map[string]*model.Project),
+	}
+}
+
+// putAnnouncement records the write of the announcement.
+func (p *pendingWrites) putAnnouncement(announcement *model.Announcement) {
+	p.announcements[announcement.Meta.Project+"/"+announcement.Meta.Name] = announcement
+}
+
+// deleteAnnouncement records the deletion of the announcement.
+func (p *pendingWrites) deleteAnnouncement(project, name string) {
+	p.announcements[project+"/"+name] = nil
+}
+
+// putProject records the write of the project.
+func (p *pendingWrites) putProject(project *model.Project) {
+	p.projects[project.Name] = project
+}
+
+// project returns the pending write of the project, or the stored project.
+func (p *pendingWrites) project(db model.DatabaseAdapter, name string) (*model.Project, error) {
+	if p != nil {
+		if project, ok := p.projects[name]; ok {
+			return project, nil
+		}
+	}
+	return getProject(db, name)
+}
+
+// apply returns the stored announcement values of the project with the pending writes of its announcements applied.
+func (p *pendingWrites) apply(project string, values []string) []string {
+	if p == nil || len(p.announcements) == 0 {
+		return values
+	}
+
+	applied := make([]string, 0, len(values))
+	for _, value := range values {
+		var stored model.Announcement
+		if err := json.Unmarshal([]byte(value), &stored); err == nil {
+			if _, ok := p.announcements[project+"/"+stored.Meta.Name]; ok {
+				continue
+			}
+		}
+		applied = append(applied, value)
+	}
+	for _, key := range slices.Sorted(maps.Keys(p.announcements)) {
+		announcement := p.announcements[key]
+		if announcement == nil || announcement.Meta.Project != project {
+			continue
+		}
+		if value, err := json.Marshal(announcement); err == nil {
+			applied = append(applied, string(value))
+		}
+	}
+	return applied
+}
+
+// checkProjectAdmission rejects the announcement with an *invalidAnnouncementError if its prefixes overlap those of
+// another announcement of the project, it exceeds the project quota or it breaks the prefix policy. In warn mode, the
+// overlaps with prefixes of other projects are logged.
+func checkProjectAdmission(db model.DatabaseAdapter, policy *PrefixPolicy, announcement *model.Announcement, pending *pendingWrites) error {
+	errs, err := projectAdmissionErrors(db, policy, announcement, pending)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &invalidAnnouncementError{errors: errs}
+	}
+
+	if policy.conflictMode() == model.ConflictsWarn {
+		conflicts, err := crossProjectConflicts(db, announcement)
+		if err != nil {
+			return err
+		}
+		warnConflicts(conflicts)
+	}
+	return nil
+}
+
+// announcedPrefixes returns the prefixes announced by the announcement keyed by the field that sets them. The
+// announced addresses are host prefixes. Invalid addresses are skipped, they are reported by the validators.
+func announcedPrefixes(announcement *model.Announcement) map[string]netip.Prefix {
+	prefixes := make(map[string]netip.Prefix)
+	addresses := announcement.Addresses
+	for field, address := range map[string]string{
+		"addresses.announced-ip":   addresses.AnnouncedIP,
+		"addresses.announced-ipv6": addresses.AnnouncedIPv6,
+	} {
+		if addr, err := netip.ParseAddr(address); err == nil {
+			prefixes[field] = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+	}
+	if addr, err := netip.ParseAddr(addresses.SourceSubnets.IP); err == nil {
+		if prefix, err := addr.Unmap().Prefix(int(addresses.SourceSubnets.Mask)); err == nil {
+			prefixes["addresses.announced-address"] = prefix
+		}
+	}
+	return prefixes
+}
+
+// addError appends a field-level error to the report.
+func addError(report *model.ValidationReport, field, format string, args ...interface{}) {
+	report.Errors = append(report.Errors, model.ValidationError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validateMeta checks the announcement name and project used to build the storage key.
+func validateMeta(announcement *model.Announcement, report *model.ValidationReport) {
+	if announcement.Meta.Name == "" {
+		addError(report, "meta.name", "name is required")
+	} else if !isDNSName(announcement.Meta.Name) {
+		addError(report, "meta.name", "name must contain only alphanumeric characters, '-' and '.', and not only dots")
+	}
+
+	if announcement.Meta.Project == "" {
+		addError(report, "meta.project", "project is required")
+	} else if strings.Contains(announcement.Meta.Project, "/") {
+		addError(report, "meta.project", "project must not contain '/'")
+	} else if strings.Trim(announcement.Meta.Project, ".") == "" {
+		addError(report, "meta.project", "project must not consist of dots only")
+	}
+}
+
+const (
+	maxLabelNameLength   = 63         // maxLabelNameLength is the maximum length of a label value and of the name part of a label or annotation key.
+	maxLabelPrefixLength = 253        // maxLabelPrefixLength is the maximum length of the DNS prefix of a label or annotation key.
+	maxAnnotationsSize   = 256 * 1024 // maxAnnotationsSize is the maximum total size of the keys and values of the annotations.
+)
+
+// validateLabels checks the keys and the values of the labels and the keys and the total size of the annotations.
+// Keys are names with an optional DNS prefix, e.g., "env" or "corebgp.io/team".
+func validateLabels(announcement *model.Announcement, report *model.ValidationReport) {
+	for _, key := range slices.Sorted(maps.Keys(announcement.Meta.Labels)) {
+		if err := validateLabelKey(key); err != nil {
+			addError(report, "meta.labels", "%v", err)
+		} else if err := validateLabelValue(announcement.Meta.Labels[key]); err != nil {
+			addError(report, "meta.labels."+key, "%v", err)
+		}
+	}
+
+	size := 0
+	for _, key := range slices.Sorted(maps.Keys(announcement.Meta.Annotations)) {
+		if err := validateLabelKey(key); err != nil {
+			addError(report, "meta.annotations", "%v", err)
+		}
+		size += len(key) + len(announcement.Meta.Annotations[key])
+	}
+	if size > maxAnnotationsSize {
+		addError(report, "meta.annotations", "annotations must not exceed %d bytes, got %d", maxAnnotationsSize, size)
+	}
+}
+
+// validateLabelKey checks a label or annotation key: an optional DNS prefix followed by a slash and a name of
+// alphanumeric characters, '-', '_' and '.', beginning and ending with an alphanumeric character.
+func validateLabelKey(key string) error {
+	prefix, name, found := strings.Cut(key, "/")
+	if !found {
+		prefix, name = "", key
+	} else if prefix == "" || len(prefix) > maxLabelPrefixLength || !isDNSName(prefix) {
+		return fmt.Errorf("key %q must have a DNS prefix of at most %d characters", key, maxLabelPrefixLength)
+	}
+	if name == "" || !isLabelName(name) {
+		return fmt.Errorf("key %q must have a name of at most %d alphanumeric characters, '-', '_' or '.', beginning and ending with an alphanumeric character", key, maxLabelNameLength)
+	}
+	return nil
+}
+
+// validateLabelValue checks a label value, which is empty or has the format of the name part of a key.
+func validateLabelValue(value string) error {
+	if value != "" && !isLabelName(value) {
+		return fmt.Errorf("value %q must be at most %d alphanumeric characters, '-', '_' or '.', beginning and ending with an alphanumeric character", value, maxLabelNameLength)
+	}
+	return nil
+}
+
+// isLabelName reports whether the name is at most maxLabelNameLength characters of letters, digits, '-', '_' and
+// '.', beginning and ending with a letter or a digit.
+func isLabelName(name string) bool {
+	if name == "" || len(name) > maxLabelNameLength {
+		return false
+	}
+	isAlphanumeric := func(b byte) bool {
+		return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+	}
+	for i := 0; i < len(name); i++ {
+		if !isAlphanumeric(name[i]) && name[i] != '-' && name[i] != '_' && name[i] != '.' {
+			return false
+		}
+	}
+	return isAlphanumeric(name[0]) && isAlphanumeric(name[len(name)-1])
+}
+
+// isDNSName reports whether the name contains only the characters allowed in DNS names: letters, digits, '-' and '.'.
+// Names of dots only, such as "." and "..", are rejected, since they would be read as relative paths in the storage
+// keys and URLs.
+func isDNSName(name string) bool {
+	if strings.Trim(name, ".") == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkNameLength rejects announcement names longer than maxLength with an *invalidAnnouncementError, like the other
+// checks of the name, since they are part of the storage key. A non-positive maxLength disables the check.
+func checkNameLength(name string, maxLength int) error {
+	if maxLength > 0 && len(name) > maxLength {
+		return &invalidAnnouncementError{errors: []model.ValidationError{{
+			Field:   "meta.name",
+			Message: fmt.Sprintf("name is %d characters long, the maximum is %d", len(name), maxLength),
+		}}}
+	}
+	return nil
+}
+
+// validateAddresses checks the announced IP address and the source subnet prefix length.
+func validateAddresses(announcement *model.Announcement, report *model.ValidationReport) {
+	addresses := announcement.Addresses
+	if addresses.AnnouncedIP == "" {
+		// The API server allocates the announced IP of the announcements created with a pool
+		if addresses.SourceSubnets.IP == "" && addresses.Pool == "" {
+			addError(report, "addresses.announced-ip", "announced IP, source subnet or pool is required")
+		}
+	} else if net.ParseIP(addresses.AnnouncedIP) == nil {
+		addError(report, "addresses.announced-ip", "%q is not a valid IP address", addresses.AnnouncedIP)
+	}
+
+	// A dual-stack announcement pairs an IPv4 announced IP with an IPv6 one
+	if addresses.AnnouncedIPv6 != "" {
+		if model.AddressFamilyOf(addresses.AnnouncedIPv6) != model.AddressFamilyIPv6Unicast {
+			addError(report, "addresses.announced-ipv6", "%q is not a valid IPv6 address", addresses.AnnouncedIPv6)
+		}
+		if model.AddressFamilyOf(addresses.AnnouncedIP) != model.AddressFamilyIPv4Unicast {
+			addError(report, "addresses.announced-ip", "announced IP must be an IPv4 address when announced-ipv6 is set")
+		}
+	}
+
+	if addresses.SourceSubnets.IP != "" {
+		validatePrefix(addresses.SourceSubnets, "addresses.announced-address", report)
+	}
+}
+
+// validateNextHops checks that at least one next hop is set, that all of them are valid addresses and that every
+// announced address has a next hop of its address family.
+func validateNextHops(announcement *model.Announcement, report *model.ValidationReport) {
+	if len(announcement.NextHops) == 0 && len(announcement.WeightedNextHops) == 0 {
+		addError(report, "next-hops", "at least one next hop is required")
+		return
+	}
+
+	for i, nextHop := range announcement.NextHops {
+		field := fmt.Sprintf("next-hops[%d]", i)
+		validateSubnet(nextHop, field, report)
+		validateNextHopAddress(nextHop.IP, field+".ip", report)
+	}
+
+	// Routes are programmed only via the next hops of the address family of the announced address
+	families := make(map[string]struct{})
+	if len(announcement.WeightedNextHops) > 0 {
+		for _, nextHop := range announcement.WeightedNextHops {
+			families[model.AddressFamilyOf(nextHop.Address)] = struct{}{}
+		}
+	} else {
+		for _, nextHop := range announcement.NextHops {
+			families[model.AddressFamilyOf(nextHop.IP)] = struct{}{}
+		}
+	}
+	for _, address := range announcement.AnnouncedAddresses() {
+		family := model.AddressFamilyOf(address)
+		if _, ok := families[family]; family != "" && !ok {
+			addError(report, "next-hops", "no %s next hop for the announced address %s", family, address)
+		}
+	}
+}
+
+// validateWeightedNextHops checks the addresses of the weighted next hops and that their weights are positive
+// and do not exceed model.MaxTotalNextHopWeight in total.
+func validateWeightedNextHops(announcement *model.Announcement, report *model.ValidationReport) {
+	var total uint64
+	for i, nextHop := range announcement.WeightedNextHops {
+		field := fmt.Sprintf("weighted-next-hops[%d]", i)
+		if net.ParseIP(nextHop.Address) == nil {
+			addError(report, field+".address", "%q is not a valid IP address", nextHop.Address)
+		} else {
+			validateNextHopAddress(nextHop.Address, field+".address", report)
+		}
+		if nextHop.Weight == 0 {
+			addError(report, field+".weight", "weight must be positive")
+		}
+		total += uint64(nextHop.Weight)
+	}
+
+	if total > model.MaxTotalNextHopWeight {
+		addError(report, "weighted-next-hops", "total weight %d exceeds the maximum of %d", total, model.MaxTotalNextHopWeight)
+	}
+	if len(announcement.WeightedNextHops) > 0 && len(announcement.NextHops) > 0 {
+		report.Warnings = append(report.Warnings, "weighted next hops take precedence, next-hops are ignored")
+	}
+}
+
+// validateFailover checks that the failover policy has one of the defined values. The primary-backup failover follows
+// the order of the next hops and ignores the weights of the weighted next hops.
+func validateFailover(announcement *model.Announcement, report *model.ValidationReport) {
+	if !announcement.Failover.Valid() {
+		addError(report, "failover", "invalid failover %q: must be one of %s, %s",
+			announcement.Failover, model.FailoverAllActive, model.FailoverPrimaryBackup)
+		return
+	}
+	if announcement.Failover == model.FailoverPrimaryBackup && len(announcement.WeightedNextHops) > 0 {
+		report.Warnings = append(report.Warnings, "the weights of the weighted next hops have no effect with the primary-backup failover")
+	}
+}
+
+// validateOrigin checks that the ORIGIN attribute has one of the defined values.
+func validateOrigin(announcement *model.Announcement, report *model.ValidationReport) {
+	if !announcement.Origin.Valid() {
+		addError(report, "origin", "invalid origin %q: must be one of %s, %s, %s",
+			announcement.Origin, model.OriginIGP, model.OriginEGP, model.OriginIncomplete)
+	}
+}
+
+// validateASPathPrepend checks that the AS_PATH prepend count does not exceed model.MaxASPathPrepend.
+func validateASPathPrepend(announcement *model.Announcement, report *model.ValidationReport) {
+	if announcement.ASPathPrepend > model.MaxASPathPrepend {
+		addError(report, "as-path-prepend", "as-path-prepend must be between 0 and %d", model.MaxASPathPrepend)
+	}
+}
+
+// validateCommunities checks that all communities have a supported format and that the per-next-hop communities
+// refer to next hops of the announcement.
+func validateCommunities(announcement *model.Announcement, report *model.ValidationReport) {
+	for i, community := range announcement.Communities {
+		if _, err := model.ParseCommunity(community); err != nil {
+			addError(report, fmt.Sprintf("communities[%d]", i), "%s", err.Error())
+		}
+	}
+
+	nextHops := make(map[string]struct{}, len(announcement.NextHops)+len(announcement.WeightedNextHops))
+	for _, nextHop := range announcement.NextHops {
+		nextHops[nextHop.IP] = struct{}{}
+	}
+	for _, nextHop := range announcement.WeightedNextHops {
+		nextHops[nextHop.Address] = struct{}{}
+	}
+
+	// Report the errors in a stable order
+	for _, nextHop := range slices.Sorted(maps.Keys(announcement.NextHopCommunities)) {
+		communities := announcement.NextHopCommunities[nextHop]
+		field := fmt.Sprintf("next-hop-communities[%s]", nextHop)
+		if _, ok := nextHops[nextHop]; !ok {
+			addError(report, field, "%q is not a next hop of the announcement", nextHop)
+		}
+		for i, community := range communities {
+			if _, err := model.ParseCommunity(community); err != nil {
+				addError(report, fmt.Sprintf("%s[%d]", field, i), "%s", err.Error())
+			}
+		}
+	}
+}
+
+// validateHealthCheck checks the health check parameters and warns when health checking is not configured.
+func validateHealthCheck(announcement *model.Announcement, report *model.ValidationReport) {
+	healthCheck := announcement.HealthCheck
+	if !healthCheck.Enabled() {
+		report.Warnings = append(report.Warnings, "health check is not configured, next hops will always be considered healthy")
+		return
+	}
+
+	switch healthCheck.ProbeType() {
+	case model.HealthCheckTCP, model.HealthCheckHTTP, model.HealthCheckGRPC:
+		if healthCheck.Port < 1 || healthCheck.Port > 65535 {
+			addError(report, "health-check.port", "port must be between 1 and 65535")
+		}
+	case model.HealthCheckICMP:
+	default:
+		addError(report, "health-check.type", "invalid type %q: must be one of %s, %s, %s, %s",
+			healthCheck.Type, model.HealthCheckTCP, model.HealthCheckHTTP, model.HealthCheckICMP, model.HealthCheckGRPC)
+	}
+	if healthCheck.Service != "" && healthCheck.ProbeType() != model.HealthCheckGRPC {
+		report.Warnings = append(report.Warnings, "health check service is used only by gRPC health checks")
+	}
+	if healthCheck.CheckInterval < 0 || healthCheck.CheckInterval > model.MaxHealthCheckPeriod {
+		addError(report, "health-check.interval", "interval must be between 0 and %d seconds", model.MaxHealthCheckPeriod)
+	}
+	if healthCheck.Timeout < 0 || healthCheck.Timeout > model.MaxHealthCheckPeriod {
+		addError(report, "health-check.timeout", "timeout must be between 0 and %d seconds", model.MaxHealthCheckPeriod)
+	}
+	if healthCheck.CheckInterval > 0 && healthCheck.Timeout > healthCheck.CheckInterval {
+		report.Warnings = append(report.Warnings, "health check timeout is longer than the check interval")
+	}
+	if healthCheck.GracePeriod < 0 || healthCheck.GracePeriod > model.MaxHealthCheckPeriod {
+		addError(report, "health-check.grace-period", "grace period must be between 0 and %d seconds", model.MaxHealthCheckPeriod)
+	}
+	if healthCheck.Rise < 0 || healthCheck.Rise > model.MaxHealthCheckThreshold {
+		addError(report, "health-check.rise", "rise must be between 0 and %d", model.MaxHealthCheckThreshold)
+	}
+	if healthCheck.Fall < 0 || healthCheck.Fall > model.MaxHealthCheckThreshold {
+		addError(report, "health-check.fall", "fall must be between 0 and %d", model.MaxHealthCheckThreshold)
+	}
+}
+
+// validateBFDPeers checks that the BFD peers are unique IP addresses without a zone and that there are at most
+// model.MaxBFDPeers of them.
+func validateBFDPeers(announcement *model.Announcement, report *model.ValidationReport) {
+	if len(announcement.BFDPeers) > model.MaxBFDPeers {
+		addError(report, "bfd-peers", "at most %d BFD peers are allowed", model.MaxBFDPeers)
+	}
+
+	seen := make(map[netip.Addr]struct{}, len(announcement.BFDPeers))
+	for i, peer := range announcement.BFDPeers {
+		field := fmt.Sprintf("bfd-peers[%d]", i)
+		addr, err := netip.ParseAddr(peer)
+		if err != nil || addr.Zone() != "" {
+			addError(report, field, "%q is not a valid IP address without a zone", peer)
+			continue
+		}
+		if _, ok := seen[addr.Unmap()]; ok {
+			addError(report, field, "duplicate BFD peer %s", peer)
+		}
+		seen[addr.Unmap()] = struct{}{}
+	}
+}
+
+// validateSchedule checks that the times of the schedule are in RFC 3339 format and form a window, and that the TTL
+// is not negative.
+func validateSchedule(announcement *model.Announcement, report *model.ValidationReport) {
+	schedule := announcement.Schedule
+	if schedule == nil {
+		return
+	}
+
+	var from, until time.Time
+	var err error
+	if schedule.ActiveFrom != "" {
+		if from, err = time.Parse(time.RFC3339, schedule.ActiveFrom); err != nil {
+			addError(report, "schedule.active-from", "%q is not a time in RFC 3339 format", schedule.ActiveFrom)
+		}
+	}
+	if schedule.ActiveUntil != "" {
+		if until, err = time.Parse(time.RFC3339, schedule.ActiveUntil); err != nil {
+			addError(report, "schedule.active-until", "%q is not a time in RFC 3339 format", schedule.ActiveUntil)
+		}
+	}
+	if !from.IsZero() && !until.IsZero() && !until.After(from) {
+		addError(report, "schedule.active-until", "must be after active-from")
+	}
+	if schedule.TTL < 0 {
+		addError(report, "schedule.ttl", "must not be negative")
+	}
+}
+
+// validateSites checks the site IDs of the overrides and the next hops of the announcement as programmed at every
+// site that replaces them. The errors of a site are reported under its override, e.g., "sites.dc1.next-hops[0].ip".
+func validateSites(announcement *model.Announcement, report *model.ValidationReport) {
+	for _, site := range slices.Sorted(maps.Keys(announcement.Sites)) {
+		field := "sites." + site
+		if err := validateSiteID(site); err != nil {
+			addError(report, field, "%s", err)
+			continue
+		}
+
+		override := announcement.Sites[site]
+		if len(override.NextHops) == 0 && len(override.WeightedNextHops) == 0 {
+			continue
+		}
+		view, _ := announcement.ForSite(site)
+		var siteReport model.ValidationReport
+		validateNextHops(&view, &siteReport)
+		validateWeightedNextHops(&view, &siteReport)
+		for _, siteError := range siteReport.Errors {
+			addError(report, field+"."+siteError.Field, "%s", siteError.Message)
+		}
+		for _, warning := range siteReport.Warnings {
+			report.Warnings = append(report.Warnings, field+": "+warning)
+		}
+	}
+}
+
+// validateSubnet checks the IP address and the mask of a subnet according to its address family.
+func validateSubnet(subnet model.Subnet, field string, report *model.ValidationReport) {
+	ip := net.ParseIP(subnet.IP)
+	if ip == nil {
+		addError(report, field+".ip", "%q is not a valid IP address", subnet.IP)
+		return
+	}
+
+	maxMask := uint8(128)
+	if ip.To4() != nil {
+		maxMask = 32
+	}
+	if subnet.Mask > maxMask {
+		addError(report, field+".mask", "mask must be between 0 and %d", maxMask)
+	}
+}
+
+// validatePrefix checks the subnet like validateSubnet and additionally that it is written in canonical CIDR
+// notation, i.e. the host bits of the address are not set.
+func validatePrefix(subnet model.Subnet, field string, report *model.ValidationReport) {
+	before := len(report.Errors)
+	validateSubnet(subnet, field, report)
+	if len(report.Errors) > before {
+		return
+	}
+
+	addr, _ := netip.ParseAddr(subnet.IP)
+	prefix, err := addr.Unmap().Prefix(int(subnet.Mask))
+	if err == nil && prefix.Addr() != addr.Unmap() {
+		addError(report, field+".ip", "%s/%d has host bits set, the prefix is %s", subnet.IP, subnet.Mask, prefix)
+	}
+}
+
+// validateNextHopAddress checks that the next hop is a unicast address that a router can forward traffic to.
+func validateNextHopAddress(address, field string, report *model.ValidationReport) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return
+	}
+
+	addr = addr.Unmap()
+	switch {
+	case addr.IsUnspecified():
+		addError(report, field, "%s is the unspecified address and cannot be a next hop", address)
+	case addr.IsLoopback():
+		addError(report, field, "%s is a loopback address and cannot be a next hop", address)
+	case addr.IsMulticast():
+		addError(report, field, "%s is a multicast address and cannot be a next hop", address)
+	case addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}):
+		addError(report, field, "%s is the broadcast address and cannot be a next hop", address)
+	}
+}
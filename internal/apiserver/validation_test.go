@@ -0,0 +1,100 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// validate posts the announcement to the pre-flight check and returns its report.
+func validate(t *testing.T, server *testServer, announcement *model.Announcement) model.ValidationReport {
+	t.Helper()
+	code, response := server.do(t, http.MethodPost, "/v1/announcements/validate", announcement)
+	if code != http.StatusOK {
+		t.Fatalf("validate answered %d: %s", code, response.Message)
+	}
+	var report model.ValidationReport
+	if err := json.Unmarshal(response.Data, &report); err != nil {
+		t.Fatalf("malformed validation report: %v", err)
+	}
+	return report
+}
+
+// TestValidateRunsEveryCheck checks that the pre-flight check reports the errors a create would be rejected with.
+func TestValidateRunsEveryCheck(t *testing.T) {
+	var dryRun atomic.Bool
+	hook := newAdmissionWebhook(t, "naming", model.AdmissionValidating, func(review *model.AdmissionReview) model.AdmissionResponse {
+		dryRun.Store(review.DryRun)
+		if strings.HasPrefix(review.Announcement.Meta.Name, "tmp-") {
+			return model.AdmissionResponse{Allowed: false, Errors: []model.ValidationError{{Field: "meta.name", Message: "temporary names are not allowed"}}}
+		}
+		return model.AdmissionResponse{Allowed: true}
+	})
+	server := newTestServer(t, func(config *model.APIConfig) {
+		config.MaxAnnouncementNameLength = 8
+		config.AdmissionWebhooksFile = writeAdmissionWebhooks(t, hook)
+	})
+
+	tests := []struct {
+		name         string
+		announcement *model.Announcement
+		wantError    string
+	}{
+		{name: "valid", announcement: testAnnouncement("alpha", "web", "192.0.2.1")},
+		{name: "name too long", announcement: testAnnouncement("alpha", "website-frontend", "192.0.2.1"), wantError: "the maximum is 8"},
+		{name: "rejected by webhook", announcement: testAnnouncement("alpha", "tmp-web", "192.0.2.1"), wantError: "rejected by admission webhook naming"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRun.Store(false)
+			report := validate(t, server, tt.announcement)
+			if tt.wantError == "" {
+				if !report.Valid || len(report.Errors) != 0 {
+					t.Fatalf("report = %+v, want valid", report)
+				}
+				if !dryRun.Load() {
+					t.Fatal("the webhook was not called in dry-run mode")
+				}
+				return
+			}
+			if report.Valid || len(report.Errors) != 1 || !strings.Contains(report.Errors[0].Message, tt.wantError) {
+				t.Fatalf("report = %+v, want a single error containing %q", report, tt.wantError)
+			}
+
+			// The create is rejected for the same reason
+			code, response := server.do(t, http.MethodPost, "/v1/announcements/", tt.announcement)
+			if code == http.StatusCreated || !strings.Contains(response.Message, tt.wantError) {
+				t.Fatalf("create answered %d: %s", code, response.Message)
+			}
+		})
+	}
+}
+
+// TestValidatePoolCreate checks that the pre-flight check of a create of a pool checks the address it would be
+// allocated, and reports an exhausted pool once.
+func TestValidatePoolCreate(t *testing.T) {
+	server := newTestServer(t, nil)
+	pool := model.Pool{Name: "vips", CIDRs: []string{"198.51.100.0/32"}}
+	if code, response := server.do(t, http.MethodPost, "/v1/pools/alpha", pool); code != http.StatusCreated {
+		t.Fatalf("pool create answered %d: %s", code, response.Message)
+	}
+	announcement := testAnnouncement("alpha", "web", "")
+	announcement.Addresses.Pool = "vips"
+
+	if report := validate(t, server, announcement); !report.Valid {
+		t.Fatalf("report = %+v, want valid", report)
+	}
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/", announcement); code != http.StatusCreated {
+		t.Fatalf("create answered %d: %s", code, response.Message)
+	}
+
+	announcement.Meta.Name = "api"
+	report := validate(t, server, announcement)
+	if report.Valid || len(report.Errors) != 1 || !strings.Contains(report.Errors[0].Message, "no free addresses") {
+		t.Fatalf("report = %+v, want the exhausted pool", report)
+	}
+}
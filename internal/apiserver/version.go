@@ -0,0 +1,85 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+// kvValue returns the value of the etcd key with the resource version set by withResourceVersion.
+func kvValue(kv *mvccpb.KeyValue) string {
+	return withResourceVersion(string(kv.Key), string(kv.Value), kv.ModRevision)
+}
+
+// withResourceVersion returns the value of the key. Stored announcements get their resource version set to the mod
+// revision of the key, so that clients can make their updates conditional on it.
+func withResourceVersion(key, value string, modRevision int64) string {
+	if !isAnnouncementKey(key) {
+		return value
+	}
+
+	var announcement model.Announcement
+	if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+		return value
+	}
+	announcement.Meta.ResourceVersion = strconv.FormatInt(modRevision, 10)
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		return value
+	}
+	return string(data)
+}
+
+// splitResourceVersion returns the mod revision the announcement must have in storage for the write to succeed and
+// the announcement without the resource version, which is never stored. A zero revision means an unconditional write.
+func splitResourceVersion(value string) (int64, string, error) {
+	var announcement model.Announcement
+	if err := json.Unmarshal([]byte(value), &announcement); err != nil || announcement.Meta.ResourceVersion == "" {
+		return 0, value, nil
+	}
+
+	revision, err := strconv.ParseInt(announcement.Meta.ResourceVersion, 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, "", fmt.Errorf("invalid resource version %q", announcement.Meta.ResourceVersion)
+	}
+	announcement.Meta.ResourceVersion = ""
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		return 0, "", err
+	}
+	return revision, string(data), nil
+}
+
+// parseIfMatch returns the resource version of the If-Match header, which may be quoted like an entity tag.
+func parseIfMatch(header string) string {
+	return strings.Trim(strings.TrimPrefix(strings.TrimSpace(header), "W/"), `"`)
+}
+
+// writeErrorStatus returns the HTTP status of a failed write, which is a conflict if the announcement was modified
+// since it was read.
+func writeErrorStatus(err error) int {
+	if errors.Is(err, model.ErrResourceVersionConflict) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// refreshResourceVersion sets the resource version of the announcement written to the key to the stored one, so that
+// the response can be used for the next conditional update. The version is cleared if the key cannot be read.
+func refreshResourceVersion(db model.DatabaseAdapter, key string, announcement *model.Announcement) {
+	announcement.Meta.ResourceVersion = ""
+	value, err := db.Get(key)
+	if err != nil {
+		return
+	}
+	var stored model.Announcement
+	if err := json.Unmarshal([]byte(value), &stored); err == nil {
+		announcement.Meta.ResourceVersion = stored.Meta.ResourceVersion
+	}
+}
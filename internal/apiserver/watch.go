@@ -0,0 +1,301 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// deadWatchHeartbeats is the number of heartbeat intervals after which a WebSocket watch client that has not answered
+// the pings is considered gone, e.g., behind a NAT that dropped the connection.
+const deadWatchHeartbeats = 3
+
+// parseWatchRevision parses the revision to resume a watch from; empty means the current revision.
+func parseWatchRevision(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, fmt.Errorf("revision must be a non-negative integer")
+	}
+	return revision, nil
+}
+
+// watchFilter selects the events of a watch. Resync signals are always sent, empty fields select all events.
+type watchFilter struct {
+	project    string                   // project is the project of the selected announcements.
+	namePrefix string                   // namePrefix is the prefix of the names of the selected announcements.
+	types      map[model.EventType]bool // types holds the selected event types.
+	selector   labelSelector            // selector selects the announcements by their labels.
+}
+
+// parseWatchFilter parses the project, namePrefix, labelSelector and types query parameters of a watch request. The
+// types are a comma separated list of added, updated and deleted.
+func parseWatchFilter(c *gin.Context) (watchFilter, error) {
+	filter := watchFilter{project: c.Query("project"), namePrefix: c.Query("namePrefix")}
+	if strings.Contains(filter.project, "/") {
+		return watchFilter{}, fmt.Errorf("project must not contain a slash")
+	}
+	selector, err := querySelector(c)
+	if err != nil {
+		return watchFilter{}, err
+	}
+	filter.selector = selector
+	if types := c.Query("types"); types != "" {
+		filter.types = make(map[model.EventType]bool)
+		for _, eventType := range strings.Split(types, ",") {
+			switch model.EventType(eventType) {
+			case model.EventAdded, model.EventUpdated, model.EventDeleted:
+				filter.types[model.EventType(eventType)] = true
+			default:
+				return watchFilter{}, fmt.Errorf("unknown event type %q, must be %s, %s or %s", eventType, model.EventAdded, model.EventUpdated, model.EventDeleted)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// prefix returns the key prefix to watch. The watch of a project is narrowed down to the announcements of the
+// project with the name prefix, so that the database does not send the changes of other projects at all.
+func (f watchFilter) prefix() string {
+	if f.project == "" {
+		return announcementsPrefix
+	}
+	return announcementsPrefix + f.project + "/" + f.namePrefix
+}
+
+// matches reports whether the event is selected by the filter.
+func (f watchFilter) matches(event model.Event) bool {
+	if event.Type == model.EventResyncRequired {
+		return true
+	}
+	return (f.types == nil || f.types[event.Type]) &&
+		(f.project == "" || event.Announcement.Meta.Project == f.project) &&
+		strings.HasPrefix(event.Announcement.Meta.Name, f.namePrefix) &&
+		f.selector.matches(event.Announcement.Meta.Labels)
+}
+
+// selectUpdate converts an update that moves the announcement into or out of the label selection into an added or a
+// deleted event, so that the client sees the announcement leave the selection. Updates within the selection and
+// outside it are kept, matches drops the latter.
+func (f watchFilter) selectUpdate(event *model.Event, prevValue string) {
+	if len(f.selector) == 0 || prevValue == "" {
+		return
+	}
+	var previous model.Announcement
+	if err := json.Unmarshal([]byte(prevValue), &previous); err != nil {
+		return
+	}
+	wasSelected := f.selector.matches(previous.Meta.Labels)
+	switch isSelected := f.selector.matches(event.Announcement.Meta.Labels); {
+	case wasSelected && !isSelected:
+		event.Type = model.EventDeleted
+		event.Announcement = previous
+		event.Announcement.Meta.ResourceVersion = strconv.FormatInt(event.Revision, 10)
+	case !wasSelected && isSelected:
+		event.Type = model.EventAdded
+	}
+}
+
+// acceptsEventStream reports whether the request asks for a Server-Sent Events stream.
+func acceptsEventStream(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+// streamAnnouncementEvents converts the changes of the announcements to events and passes those selected by the
+// filter to send until the watch ends or send fails. Every heartbeat interval, unless it is zero, a bookmark event
+// with the revision up to which all selected changes have been sent is passed as well, starting with the revision the
+// watch resumes from.
+func streamAnnouncementEvents(watchChan <-chan model.WatchResponse, filter watchFilter, revision int64, heartbeat time.Duration, send func(model.Event) error) {
+	var ticks <-chan time.Time
+	if heartbeat > 0 {
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		var watchResp model.WatchResponse
+		select {
+		case <-ticks:
+			if err := send(model.Event{Type: model.EventBookmark, Revision: revision}); err != nil {
+				return
+			}
+			continue
+		case resp, ok := <-watchChan:
+			if !ok {
+				return
+			}
+			watchResp = resp
+		}
+
+		// The watch history has a gap, the client must rebuild its state from a full list
+		if watchResp.CompactRevision != 0 {
+			if err := send(model.Event{
+				Type:     model.EventResyncRequired,
+				Revision: watchResp.Revision,
+			}); err != nil {
+				return
+			}
+			revision = max(revision, watchResp.Revision)
+			continue
+		}
+
+		for _, watchEvent := range watchResp.Events {
+			var eventResp model.Event
+			eventResp.Revision = watchEvent.ModRevision
+
+			switch watchEvent.Type {
+			case model.WatchEventPut:
+				if watchEvent.Created {
+					eventResp.Type = model.EventAdded
+				} else {
+					eventResp.Type = model.EventUpdated
+				}
+
+				err := json.Unmarshal([]byte(watchEvent.Value), &eventResp.Announcement)
+				if err != nil {
+					slog.Error("failed to unmarshal announcement", "error", err)
+					continue
+				}
+				eventResp.Announcement.Meta.ResourceVersion = strconv.FormatInt(watchEvent.ModRevision, 10)
+				if eventResp.Type == model.EventUpdated {
+					filter.selectUpdate(&eventResp, watchEvent.PrevValue)
+				}
+			case model.WatchEventDelete:
+				eventResp.Type = model.EventDeleted
+
+				if watchEvent.PrevValue != "" {
+					err := json.Unmarshal([]byte(watchEvent.PrevValue), &eventResp.Announcement)
+					if err != nil {
+						slog.Error("failed to unmarshal announcement", "error", err)
+						continue
+					}
+				}
+			}
+
+			if !filter.matches(eventResp) {
+				continue
+			}
+			if err := send(eventResp); err != nil {
+				return
+			}
+		}
+
+		// The response without events reports the progress of the watch. The revision of a response with events is not
+		// used, since etcd may still send changes up to it.
+		if n := len(watchResp.Events); n > 0 {
+			revision = max(revision, watchResp.Events[n-1].ModRevision)
+		} else {
+			revision = max(revision, watchResp.Revision)
+		}
+	}
+}
+
+// serveEventStream streams the announcement events as Server-Sent Events, an alternative to the WebSocket watch for
+// clients behind proxies that do not pass the upgrade through. The ID of every event is its revision, so that a
+// reconnecting client resumes the stream with the Last-Event-ID header, which takes precedence over the revision
+// query parameter. Resync events carry no ID, since the client must re-list before resuming. The bookmarks keep the
+// stream from being closed for inactivity by proxies.
+func serveEventStream(c *gin.Context, db model.DatabaseAdapter, heartbeat time.Duration) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("revision")
+	}
+	revision, err := parseWatchRevision(lastEventID)
+	var filter watchFilter
+	if err == nil {
+		filter, err = parseWatchFilter(c)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// The watch is stopped when the client disconnects, which cancels the request context
+	stopChan := make(chan struct{})
+	go func() {
+		<-c.Request.Context().Done()
+		close(stopChan)
+	}()
+
+	eventsChan, err := db.Watch(filter.prefix(), revision, stopChan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.APIResponse{
+			Status:  "error",
+			Message: fmt.Errorf("failed to start watching: %w", err).Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	watchConnections.Inc()
+	defer watchConnections.Dec()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Disable the response buffering of nginx
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	streamAnnouncementEvents(eventsChan, filter, revision, heartbeat, func(event model.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		var message strings.Builder
+		if event.Type != model.EventResyncRequired && event.Revision > 0 {
+			fmt.Fprintf(&message, "id: %d\n", event.Revision)
+		}
+		fmt.Fprintf(&message, "data: %s\n\n", data)
+		if _, err := io.WriteString(c.Writer, message.String()); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+}
+
+// keepWatchAlive makes the connection of a WebSocket watch fail when the client has not answered the pings sent with
+// the bookmarks, nor sent anything, for deadWatchHeartbeats heartbeat intervals, unless the interval is zero. The
+// returned function extends the deadline after a message of the client.
+func keepWatchAlive(conn *websocket.Conn, heartbeat time.Duration) func() {
+	if heartbeat <= 0 {
+		return func() {}
+	}
+	extend := func() {
+		_ = conn.SetReadDeadline(time.Now().Add(deadWatchHeartbeats * heartbeat))
+	}
+	extend()
+	conn.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+	// Answer the pings of the client like the default handler, which is replaced once a handler is set
+	conn.SetPingHandler(func(message string) error {
+		extend()
+		err := conn.WriteControl(websocket.PongMessage, []byte(message), time.Now().Add(heartbeat))
+		var netErr net.Error
+		if errors.Is(err, websocket.ErrCloseSent) || errors.As(err, &netErr) {
+			return nil
+		}
+		return err
+	})
+	return extend
+}
@@ -0,0 +1,320 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+const (
+	webhookQueueSize       = 1000             // webhookQueueSize is the number of events buffered per webhook, further events are dead-lettered.
+	webhookTimeout         = 10 * time.Second // webhookTimeout bounds every delivery attempt.
+	defaultWebhookAttempts = 5                // defaultWebhookAttempts is the number of attempts of a webhook without max-attempts.
+	webhookRetryBase       = time.Second      // webhookRetryBase is the delay before the first retry, doubled for every further one.
+	webhookRetryMax        = time.Minute      // webhookRetryMax caps the delay between retries.
+)
+
+// WebhookNotifier posts the announcement lifecycle events to the configured webhooks. Every webhook has its own
+// queue and delivery goroutine, so a slow endpoint neither delays the others nor the API requests. A nil notifier
+// sends nothing.
+type WebhookNotifier struct {
+	hooks  []*webhook
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// webhook is a configured webhook with its pending events.
+type webhook struct {
+	model.Webhook
+	queue chan model.WebhookEvent
+}
+
+// LoadWebhooks reads the JSON webhooks file and starts the delivery goroutines. It returns nil when no file is
+// configured.
+func LoadWebhooks(path string) (*WebhookNotifier, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read webhooks file: %w", err)
+	}
+	var file model.WebhookConfig
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks file: %w", err)
+	}
+	return newWebhookNotifier(file.Webhooks)
+}
+
+// newWebhookNotifier checks the webhooks and starts their delivery goroutines.
+func newWebhookNotifier(hooks []model.Webhook) (*WebhookNotifier, error) {
+	names := make(map[string]struct{}, len(hooks))
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			return nil, fmt.Errorf("webhook %d: name must not be empty", i)
+		}
+		if _, ok := names[hook.Name]; ok {
+			return nil, fmt.Errorf("webhook %s: duplicate name", hook.Name)
+		}
+		names[hook.Name] = struct{}{}
+
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook %s: %q is not an HTTP or HTTPS URL", hook.Name, hook.URL)
+		}
+		for _, event := range hook.Events {
+			switch event {
+			case model.WebhookCreated, model.WebhookUpdated, model.WebhookDeleted, model.WebhookHealthStateChanged:
+			default:
+				return nil, fmt.Errorf("webhook %s: unknown event type %q", hook.Name, event)
+			}
+		}
+		if hook.Format != "" && hook.Format != model.WebhookFormatJSON && hook.Format != model.WebhookFormatSlack {
+			return nil, fmt.Errorf("webhook %s: unknown format %q, must be %s or %s", hook.Name, hook.Format, model.WebhookFormatJSON, model.WebhookFormatSlack)
+		}
+		if hook.MaxAttempts < 0 {
+			return nil, fmt.Errorf("webhook %s: max-attempts must not be negative", hook.Name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &WebhookNotifier{client: &http.Client{Timeout: webhookTimeout}, ctx: ctx, cancel: cancel}
+	for _, config := range hooks {
+		if config.MaxAttempts == 0 {
+			config.MaxAttempts = defaultWebhookAttempts
+		}
+		hook := &webhook{Webhook: config, queue: make(chan model.WebhookEvent, webhookQueueSize)}
+		n.hooks = append(n.hooks, hook)
+
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			n.run(hook)
+		}()
+	}
+	return n, nil
+}
+
+// Close stops the delivery goroutines. Pending events are dropped.
+func (n *WebhookNotifier) Close() {
+	if n == nil {
+		return
+	}
+	n.cancel()
+	n.wg.Wait()
+}
+
+// notify queues the events of the change recorded by the audit entry for the webhooks subscribed to them. Events
+// arriving at a full queue are dead-lettered rather than blocking the request.
+func (n *WebhookNotifier) notify(entry model.AuditEntry, before, after *model.Announcement) {
+	if n == nil {
+		return
+	}
+
+	for _, eventType := range webhookEventTypes(before, after) {
+		event := model.WebhookEvent{
+			ID:           entry.ID + "-" + string(eventType),
+			Type:         eventType,
+			Timestamp:    entry.Timestamp,
+			Project:      entry.Project,
+			Name:         entry.Name,
+			Actor:        entry.Actor,
+			Announcement: after,
+			Previous:     before,
+		}
+		for _, hook := range n.hooks {
+			if !hook.subscribed(event) {
+				continue
+			}
+			select {
+			case hook.queue <- event:
+			default:
+				webhookDeadLetters.WithLabelValues(hook.Name, "queue_full").Inc()
+				slog.Error("webhook event dropped, the queue is full", "webhook", hook.Name, "event_id", event.ID, "event_type", event.Type)
+			}
+		}
+	}
+}
+
+// subscribed reports whether the event matches the event types and the projects of the webhook.
+func (h *webhook) subscribed(event model.WebhookEvent) bool {
+	return (len(h.Events) == 0 || slices.Contains(h.Events, event.Type)) &&
+		(len(h.Projects) == 0 || slices.Contains(h.Projects, event.Project))
+}
+
+// webhookEventTypes returns the types of the events of a change. A change of the status is only reported as an
+// update when the state of the announcement changes, not on every report of the routers.
+func webhookEventTypes(before, after *model.Announcement) []model.WebhookEventType {
+	switch {
+	case before == nil:
+		return []model.WebhookEventType{model.WebhookCreated}
+	case after == nil:
+		return []model.WebhookEventType{model.WebhookDeleted}
+	}
+
+	var types []model.WebhookEventType
+	if before.Status.Status != after.Status.Status || !reflect.DeepEqual(specFields(before), specFields(after)) {
+		types = append(types, model.WebhookUpdated)
+	}
+	if healthState(before) != healthState(after) {
+		types = append(types, model.WebhookHealthStateChanged)
+	}
+	return types
+}
+
+// healthState returns the status of the HealthCheckPassing condition of the announcement, or empty if it has none.
+func healthState(announcement *model.Announcement) string {
+	if condition := announcement.Status.Condition(model.ConditionHealthCheckPassing); condition != nil {
+		return condition.Status
+	}
+	return ""
+}
+
+// run delivers the queued events of the webhook one by one until the notifier is closed.
+func (n *WebhookNotifier) run(hook *webhook) {
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case event := <-hook.queue:
+			n.deliver(hook, event)
+		}
+	}
+}
+
+// deliver posts the event to the webhook, retrying failed attempts with exponential backoff. The event is
+// dead-lettered when the webhook rejects it with a client error or all attempts fail.
+func (n *WebhookNotifier) deliver(hook *webhook, event model.WebhookEvent) {
+	body, err := webhookPayload(hook, event)
+	if err != nil {
+		webhookDeadLetters.WithLabelValues(hook.Name, "encoding").Inc()
+		slog.Error("failed to encode webhook event", "webhook", hook.Name, "event_id", event.ID, "error", err)
+		return
+	}
+
+	delay := webhookRetryBase
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.post(hook, event, body)
+		if err == nil {
+			webhookDeliveries.WithLabelValues(hook.Name, "success").Inc()
+			return
+		}
+		webhookDeliveries.WithLabelValues(hook.Name, "failure").Inc()
+		if n.ctx.Err() != nil {
+			return
+		}
+
+		reason := "rejected"
+		if retryable {
+			if attempt < hook.MaxAttempts {
+				slog.Warn("webhook delivery failed, retrying", "webhook", hook.Name, "event_id", event.ID, "attempt", attempt, "retry_in", delay, "error", err)
+				select {
+				case <-n.ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+				delay = min(delay*2, webhookRetryMax)
+				continue
+			}
+			reason = "attempts_exhausted"
+		}
+		webhookDeadLetters.WithLabelValues(hook.Name, reason).Inc()
+		slog.Error("webhook event dead-lettered", "webhook", hook.Name, "event_id", event.ID, "event_type", event.Type, "attempts", attempt, "reason", reason, "error", err)
+		return
+	}
+}
+
+// post sends one delivery attempt and reports whether a failure is worth retrying: network errors, server errors,
+// timeouts and rate limiting are, other client errors are not.
+func (n *WebhookNotifier) post(hook *webhook, event model.WebhookEvent, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "corebgp-apiserver")
+	req.Header.Set("X-CoreBGP-Event", string(event.Type))
+	req.Header.Set("X-CoreBGP-Delivery", event.ID)
+	if hook.Secret != "" {
+		req.Header.Set("X-CoreBGP-Signature", webhookSignature(hook.Secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retryable, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+}
+
+// webhookSignature returns the value of the signature header: the hex encoded HMAC-SHA256 of the body keyed with the
+// secret, prefixed by the algorithm as in "sha256=<hex>".
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookPayload encodes the event in the format of the webhook.
+func webhookPayload(hook *webhook, event model.WebhookEvent) ([]byte, error) {
+	if hook.Format == model.WebhookFormatSlack {
+		return json.Marshal(map[string]string{"text": slackMessage(event)})
+	}
+	return json.Marshal(event)
+}
+
+// slackMessage summarizes the event in one line of Slack markup.
+func slackMessage(event model.WebhookEvent) string {
+	id := "`" + event.Project + "/" + event.Name + "`"
+	var text string
+	switch event.Type {
+	case model.WebhookCreated:
+		text = "Announcement " + id + " was created"
+	case model.WebhookDeleted:
+		text = "Announcement " + id + " was deleted"
+	case model.WebhookUpdated:
+		text = "Announcement " + id + " was updated, its status is " + event.Announcement.Status.Status
+	case model.WebhookHealthStateChanged:
+		condition := event.Announcement.Status.Condition(model.ConditionHealthCheckPassing)
+		switch {
+		case condition == nil:
+			text = "Health checks of announcement " + id + " are no longer reported"
+		case condition.Status == model.ConditionTrue:
+			text = ":white_check_mark: Health checks of announcement " + id + " are passing"
+		case condition.Status == model.ConditionFalse:
+			text = ":rotating_light: Health checks of announcement " + id + " are failing"
+		default:
+			text = ":grey_question: Health state of announcement " + id + " is unknown"
+		}
+		if condition != nil && condition.Message != "" {
+			text += ": " + condition.Message
+		}
+	}
+	if event.Actor != "" && event.Type != model.WebhookHealthStateChanged {
+		text += " by " + event.Actor
+	}
+	return text
+}
@@ -0,0 +1,304 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// webhookReceiver records the deliveries posted to a webhook, answering them with the queued status codes and 200
+// once the queue is empty.
+type webhookReceiver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	statuses   []int
+	deliveries []webhookDelivery
+}
+
+// webhookDelivery is a delivery received by a webhookReceiver.
+type webhookDelivery struct {
+	header http.Header
+	body   []byte
+	event  model.WebhookEvent
+}
+
+// newWebhookReceiver starts a webhookReceiver answering with the statuses first. It is shut down when the test
+// completes.
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	receiver := &webhookReceiver{statuses: statuses}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		delivery := webhookDelivery{header: r.Header, body: body}
+		_ = json.Unmarshal(body, &delivery.event)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.deliveries = append(receiver.deliveries, delivery)
+		if len(receiver.statuses) > 0 {
+			w.WriteHeader(receiver.statuses[0])
+			receiver.statuses = receiver.statuses[1:]
+		}
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+// wait waits until the receiver has received count deliveries and returns them.
+func (r *webhookReceiver) wait(t *testing.T, count int) []webhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		deliveries := slices.Clone(r.deliveries)
+		r.mu.Unlock()
+		if len(deliveries) >= count {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d deliveries, want %d", len(deliveries), count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// counterValue returns the value of the counter of the default registry with the labels, or zero if it has none.
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(labels) {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// TestNewWebhookNotifier checks that the invalid webhook configurations are rejected.
+func TestNewWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		hooks     []model.Webhook
+		wantError string
+	}{
+		{name: "valid", hooks: []model.Webhook{{Name: "slack", URL: "https://hooks.example.com/x", Events: []model.WebhookEventType{model.WebhookDeleted}, Format: model.WebhookFormatSlack}}},
+		{name: "no name", hooks: []model.Webhook{{URL: "https://hooks.example.com/x"}}, wantError: "name must not be empty"},
+		{name: "duplicate name", hooks: []model.Webhook{{Name: "a", URL: "https://hooks.example.com/x"}, {Name: "a", URL: "https://hooks.example.com/y"}}, wantError: "duplicate name"},
+		{name: "not an HTTP URL", hooks: []model.Webhook{{Name: "a", URL: "ftp://hooks.example.com/x"}}, wantError: "not an HTTP or HTTPS URL"},
+		{name: "unknown event", hooks: []model.Webhook{{Name: "a", URL: "https://hooks.example.com/x", Events: []model.WebhookEventType{"renamed"}}}, wantError: "unknown event type"},
+		{name: "unknown format", hooks: []model.Webhook{{Name: "a", URL: "https://hooks.example.com/x", Format: "xml"}}, wantError: "unknown format"},
+		{name: "negative attempts", hooks: []model.Webhook{{Name: "a", URL: "https://hooks.example.com/x", MaxAttempts: -1}}, wantError: "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, err := newWebhookNotifier(tt.hooks)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("newWebhookNotifier: %v", err)
+				}
+				notifier.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Fatalf("newWebhookNotifier returned %v, want an error containing %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+// TestWebhookEventTypes checks the events reported for the changes of an announcement.
+func TestWebhookEventTypes(t *testing.T) {
+	announcement := func(status, health string) *model.Announcement {
+		a := testAnnouncement("alpha", "web", "192.0.2.1")
+		a.Status.Status = status
+		if health != "" {
+			a.Status.SetCondition(model.Condition{Type: model.ConditionHealthCheckPassing, Status: health})
+		}
+		return a
+	}
+	changed := announcement(model.StatusProgrammed, "")
+	changed.Addresses.AnnouncedIP = "192.0.2.2"
+	reported := announcement(model.StatusProgrammed, "")
+	reported.Status.Routers = []model.RouterStatus{{Router: "tor-1", Status: model.StatusProgrammed}}
+
+	tests := []struct {
+		name   string
+		before *model.Announcement
+		after  *model.Announcement
+		want   []model.WebhookEventType
+	}{
+		{name: "create", after: announcement(model.StatusPending, ""), want: []model.WebhookEventType{model.WebhookCreated}},
+		{name: "delete", before: announcement(model.StatusProgrammed, ""), want: []model.WebhookEventType{model.WebhookDeleted}},
+		{name: "spec change", before: announcement(model.StatusProgrammed, ""), after: changed, want: []model.WebhookEventType{model.WebhookUpdated}},
+		{name: "state change", before: announcement(model.StatusPending, ""), after: announcement(model.StatusProgrammed, ""), want: []model.WebhookEventType{model.WebhookUpdated}},
+		{name: "router report only", before: announcement(model.StatusProgrammed, ""), after: reported},
+		{name: "health checks failing", before: announcement(model.StatusProgrammed, model.ConditionTrue), after: announcement(model.StatusProgrammed, model.ConditionFalse), want: []model.WebhookEventType{model.WebhookHealthStateChanged}},
+		{name: "withdrawn on failing health checks", before: announcement(model.StatusProgrammed, model.ConditionTrue), after: announcement(model.StatusFailed, model.ConditionFalse), want: []model.WebhookEventType{model.WebhookUpdated, model.WebhookHealthStateChanged}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhookEventTypes(tt.before, tt.after); !slices.Equal(got, tt.want) {
+				t.Fatalf("webhookEventTypes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWebhookDelivery checks that the events are filtered by type and project and posted with the HMAC signature of
+// the payload.
+func TestWebhookDelivery(t *testing.T) {
+	all := newWebhookReceiver(t)
+	deletions := newWebhookReceiver(t)
+	notifier, err := newWebhookNotifier([]model.Webhook{
+		{Name: "delivery-all", URL: all.URL, Secret: "s3cret"},
+		{Name: "delivery-deletions", URL: deletions.URL, Events: []model.WebhookEventType{model.WebhookDeleted}, Projects: []string{"alpha"}},
+	})
+	if err != nil {
+		t.Fatalf("newWebhookNotifier: %v", err)
+	}
+	defer notifier.Close()
+
+	beta := testAnnouncement("beta", "web", "192.0.2.2")
+	alpha := testAnnouncement("alpha", "web", "192.0.2.1")
+	notifier.notify(model.AuditEntry{ID: "1", Project: "alpha", Name: "web", Actor: "alice"}, nil, alpha)
+	notifier.notify(model.AuditEntry{ID: "2", Project: "beta", Name: "web"}, beta, nil)
+	notifier.notify(model.AuditEntry{ID: "3", Project: "alpha", Name: "web"}, alpha, nil)
+
+	deliveries := all.wait(t, 3)
+	for i, want := range []string{"1-created", "2-deleted", "3-deleted"} {
+		delivery := deliveries[i]
+		if delivery.event.ID != want || delivery.header.Get("X-CoreBGP-Delivery") != want {
+			t.Errorf("delivery %d of event %q, want %q", i, delivery.event.ID, want)
+		}
+		if got := delivery.header.Get("X-CoreBGP-Event"); got != string(delivery.event.Type) {
+			t.Errorf("delivery %d has event header %q, want %q", i, got, delivery.event.Type)
+		}
+		if got, want := delivery.header.Get("X-CoreBGP-Signature"), webhookSignature("s3cret", delivery.body); got != want || !strings.HasPrefix(got, "sha256=") {
+			t.Errorf("delivery %d is signed %q, want %q", i, got, want)
+		}
+	}
+	if deliveries[0].event.Actor != "alice" || deliveries[0].event.Announcement == nil || deliveries[0].event.Previous != nil {
+		t.Errorf("created event = %+v", deliveries[0].event)
+	}
+
+	// The deletion of beta is filtered out, so the deletion of alpha is the only one delivered
+	deliveries = deletions.wait(t, 1)
+	time.Sleep(50 * time.Millisecond)
+	deletions.mu.Lock()
+	defer deletions.mu.Unlock()
+	if len(deletions.deliveries) != 1 || deliveries[0].event.ID != "3-deleted" {
+		t.Fatalf("filtered webhook received %d deliveries, first %q, want only 3-deleted", len(deletions.deliveries), deliveries[0].event.ID)
+	}
+	if signature := deliveries[0].header.Get("X-CoreBGP-Signature"); signature != "" {
+		t.Errorf("webhook without a secret received signature %q", signature)
+	}
+}
+
+// TestWebhookRetry checks that the server errors are retried, and that the events rejected by the webhook or failing
+// every attempt are dead-lettered.
+func TestWebhookRetry(t *testing.T) {
+	tests := []struct {
+		name           string
+		statuses       []int
+		maxAttempts    int
+		wantDeliveries int
+		wantReason     string
+	}{
+		{name: "retried", statuses: []int{http.StatusServiceUnavailable}, maxAttempts: 2, wantDeliveries: 2},
+		{name: "rejected", statuses: []int{http.StatusBadRequest}, maxAttempts: 2, wantDeliveries: 1, wantReason: "rejected"},
+		{name: "attempts exhausted", statuses: []int{http.StatusInternalServerError, http.StatusTooManyRequests}, maxAttempts: 2, wantDeliveries: 2, wantReason: "attempts_exhausted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := newWebhookReceiver(t, tt.statuses...)
+			name := "retry-" + strings.ReplaceAll(tt.name, " ", "-")
+			notifier, err := newWebhookNotifier([]model.Webhook{{Name: name, URL: receiver.URL, MaxAttempts: tt.maxAttempts}})
+			if err != nil {
+				t.Fatalf("newWebhookNotifier: %v", err)
+			}
+			defer notifier.Close()
+
+			notifier.notify(model.AuditEntry{ID: "1", Project: "alpha", Name: "web"}, nil, testAnnouncement("alpha", "web", "192.0.2.1"))
+			deliveries := receiver.wait(t, tt.wantDeliveries)
+			for _, delivery := range deliveries {
+				if delivery.event.ID != "1-created" {
+					t.Fatalf("attempt delivered event %q, want the same event 1-created", delivery.event.ID)
+				}
+			}
+
+			// The event is counted once, as delivered or as dead-lettered
+			metric, labels := "corebgp_webhook_deliveries_total", map[string]string{"webhook": name, "result": "success"}
+			if tt.wantReason != "" {
+				metric, labels = "corebgp_webhook_dead_letters_total", map[string]string{"webhook": name, "reason": tt.wantReason}
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for counterValue(t, metric, labels) != 1 {
+				if time.Now().After(deadline) {
+					t.Fatalf("%s%v was not incremented", metric, labels)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if got := len(receiver.wait(t, tt.wantDeliveries)); got != tt.wantDeliveries {
+				t.Fatalf("received %d attempts, want %d", got, tt.wantDeliveries)
+			}
+		})
+	}
+}
+
+// TestWebhooksNotifiedOfWrites checks that the API server notifies the webhooks of the file of the changes of the
+// announcements.
+func TestWebhooksNotifiedOfWrites(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	data, err := json.Marshal(model.WebhookConfig{Webhooks: []model.Webhook{{Name: "writes", URL: receiver.URL, Secret: "s3cret"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, func(config *model.APIConfig) {
+		config.WebhooksFile = path
+	})
+
+	if code, response := server.do(t, http.MethodPost, "/v1/announcements/", testAnnouncement("alpha", "web", "192.0.2.1")); code != http.StatusCreated {
+		t.Fatalf("create answered %d: %s", code, response.Message)
+	}
+	if code, response := server.do(t, http.MethodDelete, "/v1/announcements/alpha/web", nil); code != http.StatusOK {
+		t.Fatalf("delete answered %d: %s", code, response.Message)
+	}
+
+	deliveries := receiver.wait(t, 2)
+	for i, want := range []model.WebhookEventType{model.WebhookCreated, model.WebhookDeleted} {
+		if deliveries[i].event.Type != want || deliveries[i].event.Project != "alpha" || deliveries[i].event.Name != "web" {
+			t.Errorf("delivery %d = %+v, want %s of alpha/web", i, deliveries[i].event, want)
+		}
+		if deliveries[i].header.Get("X-CoreBGP-Signature") != webhookSignature("s3cret", deliveries[i].body) {
+			t.Errorf("delivery %d has an invalid signature", i)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// withdrawnCollectorActor is the actor of the audit entries of the deletions made by the withdrawn collector.
+const withdrawnCollectorActor = "system:withdrawn-collector"
+
+// skipWithdrawn reports whether the announcement must be excluded from a list response.
+// Withdrawn announcements are excluded unless the request has the includeWithdrawn=true query parameter.
+func skipWithdrawn(c *gin.Context, announcement *model.Announcement) bool {
+	if announcement.Status.Status != model.StatusWithdrawn {
+		return false
+	}
+	return c.Query("includeWithdrawn") != "true"
+}
+
+// runWithdrawnCollector periodically deletes withdrawn announcements older than the retention period until stopChan is closed.
+// A zero retention disables the collection.
+func runWithdrawnCollector(db model.DatabaseAdapter, changes *changeLog, retention time.Duration, clk clock.Clock, stopChan <-chan struct{}) {
+	if retention <= 0 {
+		return
+	}
+
+	interval := retention / 10
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+			if err := collectWithdrawn(db, changes, retention, clk); err != nil {
+				slog.Error("failed to collect withdrawn announcements", "error", err)
+			}
+		}
+	}
+}
+
+// collectWithdrawn deletes all withdrawn announcements whose withdrawal timestamp is older than the retention period.
+func collectWithdrawn(db model.DatabaseAdapter, changes *changeLog, retention time.Duration, clk clock.Clock) error {
+	data, err := db.GetObjects(announcementsPrefix)
+	if err != nil {
+		return err
+	}
+
+	deadline := clk.Now().Add(-retention)
+	for _, value := range data {
+		var announcement model.Announcement
+		if err := json.Unmarshal([]byte(value), &announcement); err != nil {
+			continue
+		}
+		if announcement.Status.Status != model.StatusWithdrawn {
+			continue
+		}
+
+		withdrawnAt, err := time.Parse(time.RFC3339, announcement.Status.Timestamp)
+		if err != nil || withdrawnAt.After(deadline) {
+			continue
+		}
+
+		key := announcementsPrefix + announcement.Meta.Project + "/" + announcement.Meta.Name
+		if err := db.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete withdrawn announcement %s: %w", key, err)
+		}
+		changes.write(model.AuditEntry{Actor: withdrawnCollectorActor}, &announcement, nil)
+	}
+
+	return nil
+}
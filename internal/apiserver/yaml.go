@@ -0,0 +1,56 @@
+package apiserver
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// yamlContentTypes are the media types of YAML request bodies.
+var yamlContentTypes = map[string]struct{}{
+	"application/yaml":   {},
+	"application/x-yaml": {},
+	"text/yaml":          {},
+	"text/x-yaml":        {},
+}
+
+// yamlBody returns the middleware that converts YAML request bodies to JSON, so that announcements stored as YAML
+// manifests can be sent to every endpoint as they are. The handlers see a JSON request.
+func yamlBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if _, ok := yamlContentTypes[mediaType]; err != nil || !ok || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "failed to read request body",
+				Data:    nil,
+			})
+			return
+		}
+
+		jsonData, err := model.YAMLToJSON(data)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, model.APIResponse{
+				Status:  "error",
+				Message: "invalid YAML body: " + err.Error(),
+				Data:    nil,
+			})
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(jsonData))
+		c.Request.ContentLength = int64(len(jsonData))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Next()
+	}
+}
@@ -0,0 +1,441 @@
+// Package bfd runs single-hop asynchronous BFD sessions (RFC 5880, RFC 5881) with the peers that announcements
+// require to be up, so that the routes are withdrawn within the BFD detection time when a peer fails, independent of
+// the slower health checks of the next hops.
+package bfd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Defaults of the session parameters that are not set in the configuration.
+const (
+	defaultMinInterval      = 300 * time.Millisecond
+	defaultDetectMultiplier = 3
+)
+
+// ChangeFunc is called when the BFD sessions of an announcement all come up, or when one of them goes down.
+type ChangeFunc func(announcement model.Announcement, up bool)
+
+// Manager runs the BFD sessions with the peers of the tracked announcements. A session is shared by all
+// announcements requiring its peer and is stopped when none does anymore. Sessions start down, so the routes of
+// an announcement are programmed only after all its sessions have come up.
+type Manager struct {
+	mu             sync.Mutex
+	local          netip.Addr
+	minInterval    time.Duration
+	detectMult     uint8
+	onChange       ChangeFunc
+	sessions       map[netip.Addr]*session         // sessions maps the peer addresses to their sessions.
+	discriminators map[uint32]*session             // discriminators maps the local discriminators to their sessions.
+	tracked        map[string]*trackedAnnouncement // tracked maps the "project/name" IDs to the announcements with BFD peers.
+	listeners      []*listener
+	changes        chan change
+	done           chan struct{}
+	wg             sync.WaitGroup
+	stopped        bool
+}
+
+// trackedAnnouncement is an announcement together with its BFD peers and whether all their sessions are up.
+type trackedAnnouncement struct {
+	announcement model.Announcement
+	peers        []netip.Addr
+	up           bool
+}
+
+// change is a pending call of the change callback.
+type change struct {
+	announcement model.Announcement
+	up           bool
+}
+
+// NewManager listens for the control packets of the peers and returns a manager that calls onChange on every change
+// of the session states of an announcement. It returns nil if BFD is disabled in the configuration.
+func NewManager(config model.BFD, onChange ChangeFunc) (*Manager, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	m := &Manager{
+		minInterval:    defaultMinInterval,
+		detectMult:     defaultDetectMultiplier,
+		onChange:       onChange,
+		sessions:       make(map[netip.Addr]*session),
+		discriminators: make(map[uint32]*session),
+		tracked:        make(map[string]*trackedAnnouncement),
+		changes:        make(chan change, 256),
+		done:           make(chan struct{}),
+	}
+	if config.MinInterval > 0 {
+		m.minInterval = config.MinInterval
+	}
+	if config.DetectMultiplier < 0 || config.DetectMultiplier > 255 {
+		return nil, fmt.Errorf("BFD detect multiplier must be between 1 and 255")
+	}
+	if config.DetectMultiplier > 0 {
+		m.detectMult = uint8(config.DetectMultiplier)
+	}
+
+	networks := []string{"udp4", "udp6"}
+	if config.ListenAddress != "" {
+		local, err := netip.ParseAddr(config.ListenAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BFD listen address: %w", err)
+		}
+		m.local = local.Unmap()
+		networks = []string{"udp4"}
+		if m.local.Is6() {
+			networks = []string{"udp6"}
+		}
+	}
+
+	for _, network := range networks {
+		l, err := listen(network, m.local)
+		if err != nil {
+			for _, l := range m.listeners {
+				l.conn.Close()
+			}
+			return nil, fmt.Errorf("failed to listen for BFD control packets: %w", err)
+		}
+		m.listeners = append(m.listeners, l)
+	}
+
+	for _, l := range m.listeners {
+		m.wg.Add(1)
+		go m.receive(l)
+	}
+	m.wg.Add(1)
+	go m.notify()
+	return m, nil
+}
+
+// announcementID returns the "project/name" ID of the announcement.
+func announcementID(announcement *model.Announcement) string {
+	return announcement.Meta.Project + "/" + announcement.Meta.Name
+}
+
+// Track starts or keeps the sessions with the BFD peers of the announcement and stops the sessions that are no
+// longer required. Announcements without BFD peers are not tracked.
+func (m *Manager) Track(announcement *model.Announcement) {
+	if m == nil {
+		return
+	}
+	if len(announcement.BFDPeers) == 0 {
+		m.Untrack(announcement)
+		return
+	}
+
+	var peers []netip.Addr
+	for _, address := range announcement.BFDPeers {
+		if addr, err := netip.ParseAddr(address); err == nil && !slices.Contains(peers, addr.Unmap()) {
+			peers = append(peers, addr.Unmap())
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+
+	id := announcementID(announcement)
+	tracked, ok := m.tracked[id]
+	if !ok {
+		tracked = &trackedAnnouncement{}
+		m.tracked[id] = tracked
+	}
+	for _, peer := range peers {
+		if !slices.Contains(tracked.peers, peer) {
+			m.acquire(peer)
+		}
+	}
+	for _, peer := range tracked.peers {
+		if !slices.Contains(peers, peer) {
+			m.release(peer)
+		}
+	}
+	tracked.announcement = *announcement
+	tracked.peers = peers
+	tracked.up = m.sessionsUp(peers)
+}
+
+// Untrack stops the sessions that were required only by the announcement.
+func (m *Manager) Untrack(announcement *model.Announcement) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := announcementID(announcement)
+	if tracked, ok := m.tracked[id]; ok {
+		for _, peer := range tracked.peers {
+			m.release(peer)
+		}
+		delete(m.tracked, id)
+	}
+}
+
+// Up reports whether the sessions with all BFD peers of the announcement are up. Announcements that are not
+// tracked, and all announcements when BFD is disabled, are up.
+func (m *Manager) Up(announcement *model.Announcement) bool {
+	if m == nil {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tracked, ok := m.tracked[announcementID(announcement)]
+	return !ok || tracked.up
+}
+
+// PeerUp reports whether the session with the peer is up. All peers are up when BFD is disabled.
+func (m *Manager) PeerUp(address string) bool {
+	if m == nil {
+		return true
+	}
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[addr.Unmap()]
+	return ok && s.State() == StateUp
+}
+
+// Stop stops all sessions, telling the peers that they are administratively down, and the listeners. The change
+// callback is not called anymore.
+func (m *Manager) Stop() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+	m.stopped = true
+	sessions := make([]*session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	clear(m.sessions)
+	clear(m.discriminators)
+	clear(m.tracked)
+	m.mu.Unlock()
+
+	for _, s := range sessions {
+		s.stop()
+	}
+	for _, s := range sessions {
+		<-s.done
+	}
+	close(m.done)
+	for _, l := range m.listeners {
+		l.conn.Close()
+	}
+	m.wg.Wait()
+}
+
+// acquire starts the session with the peer unless it is running, and counts the announcement requiring it. A
+// session whose socket can not be opened never comes up. It must be called with m.mu held.
+func (m *Manager) acquire(peer netip.Addr) {
+	s, ok := m.sessions[peer]
+	if !ok {
+		discriminator := mathrand.Uint32()
+		for discriminator == 0 || m.discriminators[discriminator] != nil {
+			discriminator = mathrand.Uint32()
+		}
+
+		var err error
+		s, err = newSession(peer, m.local, discriminator)
+		if err != nil {
+			slog.Error("failed to start BFD session", "peer", peer, "error", err)
+		}
+		m.sessions[peer] = s
+		m.discriminators[discriminator] = s
+		s.start(m.minInterval, m.detectMult, func(state State) {
+			m.sessionChanged(s, state)
+		})
+		slog.Info("started BFD session", "peer", peer)
+	}
+	s.refs++
+}
+
+// release stops the session with the peer when no tracked announcement requires it anymore. It must be called with
+// m.mu held.
+func (m *Manager) release(peer netip.Addr) {
+	s, ok := m.sessions[peer]
+	if !ok {
+		return
+	}
+	if s.refs--; s.refs > 0 {
+		return
+	}
+	delete(m.sessions, peer)
+	delete(m.discriminators, s.discriminator)
+	s.stop()
+	slog.Info("stopped BFD session", "peer", peer)
+}
+
+// sessionsUp reports whether the sessions with all peers are up. It must be called with m.mu held.
+func (m *Manager) sessionsUp(peers []netip.Addr) bool {
+	for _, peer := range peers {
+		if s, ok := m.sessions[peer]; !ok || s.State() != StateUp {
+			return false
+		}
+	}
+	return true
+}
+
+// sessionChanged recomputes whether the sessions of the announcements requiring the peer of the session are up and
+// queues the change callbacks of the announcements that changed. Changes of stopped sessions are ignored.
+func (m *Manager) sessionChanged(s *session, state State) {
+	transitions.WithLabelValues(state.String()).Inc()
+	switch state {
+	case StateUp:
+		slog.Info("BFD session is up", "peer", s.peer)
+	case StateDown:
+		slog.Warn("BFD session is down", "peer", s.peer)
+	}
+
+	m.mu.Lock()
+	if m.sessions[s.peer] != s {
+		m.mu.Unlock()
+		return
+	}
+	var changes []change
+	for _, tracked := range m.tracked {
+		if !slices.Contains(tracked.peers, s.peer) {
+			continue
+		}
+		if up := m.sessionsUp(tracked.peers); up != tracked.up {
+			tracked.up = up
+			changes = append(changes, change{announcement: tracked.announcement, up: up})
+		}
+	}
+	m.mu.Unlock()
+
+	for _, c := range changes {
+		select {
+		case m.changes <- c:
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// notify calls the change callback in order of the changes, so that a slow router does not delay the sessions.
+func (m *Manager) notify() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.done:
+			return
+		case c := <-m.changes:
+			m.onChange(c.announcement, c.up)
+		}
+	}
+}
+
+// listener is a socket receiving the control packets of one address family.
+type listener struct {
+	conn *net.UDPConn
+	read func(b []byte) (int, int, net.Addr, error) // read returns the TTL or the hop limit of the packet together with it.
+}
+
+// listen opens the socket receiving the control packets of the address family of the network.
+func listen(network string, local netip.Addr) (*listener, error) {
+	conn, err := net.ListenUDP(network, net.UDPAddrFromAddrPort(netip.AddrPortFrom(local, controlPort)))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listener{conn: conn}
+	if network == "udp4" {
+		packetConn := ipv4.NewPacketConn(conn)
+		err = packetConn.SetControlMessage(ipv4.FlagTTL, true)
+		l.read = func(b []byte) (int, int, net.Addr, error) {
+			n, cm, src, err := packetConn.ReadFrom(b)
+			if cm == nil {
+				return n, 0, src, err
+			}
+			return n, cm.TTL, src, err
+		}
+	} else {
+		packetConn := ipv6.NewPacketConn(conn)
+		err = packetConn.SetControlMessage(ipv6.FlagHopLimit, true)
+		l.read = func(b []byte) (int, int, net.Addr, error) {
+			n, cm, src, err := packetConn.ReadFrom(b)
+			if cm == nil {
+				return n, 0, src, err
+			}
+			return n, cm.HopLimit, src, err
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// receive reads the control packets from the listener and passes them to their sessions. Packets that were not sent
+// with the maximum TTL may come from more than one hop away and are discarded (RFC 5881, section 5).
+func (m *Manager) receive(l *listener) {
+	defer m.wg.Done()
+
+	b := make([]byte, 1500)
+	for {
+		n, ttl, src, err := l.read(b)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			slog.Error("failed to read BFD control packet", "error", err)
+			continue
+		}
+		udpAddr, ok := src.(*net.UDPAddr)
+		if !ok || ttl != maxTTL {
+			continue
+		}
+		peer := udpAddr.AddrPort().Addr().Unmap()
+
+		p, err := unmarshalPacket(b[:n])
+		if err != nil {
+			slog.Debug("discarded BFD control packet", "peer", peer, "error", err)
+			continue
+		}
+
+		m.mu.Lock()
+		s := m.sessions[peer]
+		if p.yourDiscriminator != 0 {
+			s = m.discriminators[p.yourDiscriminator]
+		}
+		m.mu.Unlock()
+		if s == nil || s.peer != peer {
+			continue
+		}
+		select {
+		case s.packets <- p:
+		default:
+		}
+	}
+}
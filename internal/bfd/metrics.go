@@ -0,0 +1,12 @@
+package bfd
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// transitions counts the changes of the BFD session states, so that flapping sessions can be detected.
+var transitions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "corebgp_updater_bfd_session_transitions_total",
+	Help: "Number of BFD session state changes by the new state.",
+}, []string{"state"})
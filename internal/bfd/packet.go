@@ -0,0 +1,121 @@
+package bfd
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// State is the state of a BFD session as defined in RFC 5880, section 4.1.
+type State uint8
+
+// Session states carried in the Sta field of the control packets.
+const (
+	StateAdminDown State = 0
+	StateDown      State = 1
+	StateInit      State = 2
+	StateUp        State = 3
+)
+
+// String returns the lowercase name of the state used in logs and metrics.
+func (s State) String() string {
+	switch s {
+	case StateAdminDown:
+		return "admin-down"
+	case StateDown:
+		return "down"
+	case StateInit:
+		return "init"
+	case StateUp:
+		return "up"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic codes sent to the peer to explain the last change to the down state.
+const (
+	diagNone              uint8 = 0 // diagNone is sent while the session has not gone down.
+	diagDetectionExpired  uint8 = 1 // diagDetectionExpired means that no control packet was received within the detection time.
+	diagNeighborDown      uint8 = 3 // diagNeighborDown means that the peer signaled the session down.
+	diagAdministrativeOff uint8 = 7 // diagAdministrativeOff means that the session was stopped locally.
+)
+
+const (
+	version      = 1  // version is the protocol version of the control packets.
+	packetLength = 24 // packetLength is the length of a control packet without the authentication section.
+)
+
+// controlPacket is a BFD control packet (RFC 5880, section 4.1). Authentication is not supported.
+type controlPacket struct {
+	diag                  uint8
+	state                 State
+	poll                  bool
+	final                 bool
+	detectMult            uint8
+	myDiscriminator       uint32
+	yourDiscriminator     uint32
+	desiredMinTxInterval  uint32 // desiredMinTxInterval is in microseconds.
+	requiredMinRxInterval uint32 // requiredMinRxInterval is in microseconds.
+}
+
+// marshal encodes the packet.
+func (p *controlPacket) marshal() []byte {
+	b := make([]byte, packetLength)
+	b[0] = version<<5 | p.diag&0x1f
+	b[1] = byte(p.state) << 6
+	if p.poll {
+		b[1] |= 0x20
+	}
+	if p.final {
+		b[1] |= 0x10
+	}
+	b[2] = p.detectMult
+	b[3] = packetLength
+	binary.BigEndian.PutUint32(b[4:], p.myDiscriminator)
+	binary.BigEndian.PutUint32(b[8:], p.yourDiscriminator)
+	binary.BigEndian.PutUint32(b[12:], p.desiredMinTxInterval)
+	binary.BigEndian.PutUint32(b[16:], p.requiredMinRxInterval)
+	// The required min echo RX interval stays zero, echo mode is not supported
+	return b
+}
+
+// unmarshalPacket decodes a control packet and checks it according to the reception rules of RFC 5880,
+// section 6.8.6, up to the lookup of the session.
+func unmarshalPacket(b []byte) (*controlPacket, error) {
+	if len(b) < packetLength {
+		return nil, errors.New("packet is too short")
+	}
+	if b[0]>>5 != version {
+		return nil, errors.New("unsupported version")
+	}
+	if length := int(b[3]); length < packetLength || length > len(b) {
+		return nil, errors.New("invalid length")
+	}
+	if b[1]&0x04 != 0 {
+		return nil, errors.New("authentication is not supported")
+	}
+	if b[1]&0x01 != 0 {
+		return nil, errors.New("multipoint bit is set")
+	}
+
+	p := &controlPacket{
+		diag:                  b[0] & 0x1f,
+		state:                 State(b[1] >> 6),
+		poll:                  b[1]&0x20 != 0,
+		final:                 b[1]&0x10 != 0,
+		detectMult:            b[2],
+		myDiscriminator:       binary.BigEndian.Uint32(b[4:]),
+		yourDiscriminator:     binary.BigEndian.Uint32(b[8:]),
+		desiredMinTxInterval:  binary.BigEndian.Uint32(b[12:]),
+		requiredMinRxInterval: binary.BigEndian.Uint32(b[16:]),
+	}
+	switch {
+	case p.detectMult == 0:
+		return nil, errors.New("detect multiplier is zero")
+	case p.myDiscriminator == 0:
+		return nil, errors.New("my discriminator is zero")
+	case p.yourDiscriminator == 0 && p.state != StateDown && p.state != StateAdminDown:
+		return nil, errors.New("your discriminator is zero")
+	}
+	return p, nil
+}
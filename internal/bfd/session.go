@@ -0,0 +1,208 @@
+package bfd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	controlPort    = 3784        // controlPort is the destination port of single-hop control packets (RFC 5881).
+	minSourcePort  = 49152       // minSourcePort is the lowest source port of the control packets (RFC 5881).
+	maxTTL         = 255         // maxTTL is the TTL or hop limit of sent packets, which is also required in received ones.
+	slowTxInterval = time.Second // slowTxInterval is the transmit interval while the session is not up (RFC 5880, section 6.8.3).
+)
+
+// session is a BFD session with a single peer. Its state machine runs in its own goroutine, which owns all state
+// except the session state published for the readers.
+type session struct {
+	peer          netip.Addr
+	discriminator uint32              // discriminator is the local discriminator identifying the session.
+	conn          *net.UDPConn        // conn is the socket the control packets are sent from; nil if it could not be opened.
+	packets       chan *controlPacket // packets receives the control packets of the peer from the listeners.
+	state         atomic.Uint32       // state holds the current State.
+	cancel        context.CancelFunc
+	done          chan struct{}
+	refs          int // refs counts the tracked announcements requiring the session, it is guarded by Manager.mu.
+}
+
+// newSession opens the socket of a session with the peer from a random source port. The session is not started.
+func newSession(peer, local netip.Addr, discriminator uint32) (*session, error) {
+	s := &session{
+		peer:          peer,
+		discriminator: discriminator,
+		packets:       make(chan *controlPacket, 16),
+		cancel:        func() {},
+		done:          make(chan struct{}),
+	}
+	s.state.Store(uint32(StateDown))
+
+	if local.IsValid() && local.Is4() != peer.Is4() {
+		close(s.done)
+		return s, fmt.Errorf("listen address %s is not of the address family of the peer", local)
+	}
+	network := "udp4"
+	if peer.Is6() {
+		network = "udp6"
+	}
+
+	var err error
+	for range 10 {
+		port := minSourcePort + mathrand.IntN(65536-minSourcePort)
+		s.conn, err = net.ListenUDP(network, net.UDPAddrFromAddrPort(netip.AddrPortFrom(local, uint16(port))))
+		if err == nil {
+			break
+		}
+	}
+	if err == nil {
+		if peer.Is4() {
+			err = ipv4.NewConn(s.conn).SetTTL(maxTTL)
+		} else {
+			err = ipv6.NewConn(s.conn).SetHopLimit(maxTTL)
+		}
+		if err != nil {
+			s.conn.Close()
+		}
+	}
+	if err != nil {
+		s.conn = nil
+		close(s.done)
+		return s, fmt.Errorf("failed to open the BFD socket: %w", err)
+	}
+	return s, nil
+}
+
+// State returns the current state of the session.
+func (s *session) State() State {
+	return State(s.state.Load())
+}
+
+// start runs the state machine of the session until stop is called. onState is called after every change of the
+// session state.
+func (s *session) start(minInterval time.Duration, detectMult uint8, onState func(State)) {
+	if s.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.run(ctx, minInterval, detectMult, onState)
+}
+
+// stop stops the session after telling the peer that it is administratively down.
+func (s *session) stop() {
+	s.cancel()
+}
+
+// run is the state machine of RFC 5880, section 6.8, in asynchronous mode without echo and authentication.
+func (s *session) run(ctx context.Context, minInterval time.Duration, detectMult uint8, onState func(State)) {
+	defer close(s.done)
+	defer s.conn.Close()
+
+	state, diag := StateDown, diagNone
+	desiredMinTx := slowTxInterval
+	var remoteDiscriminator uint32
+	var remoteMinTx time.Duration
+	remoteMinRx := time.Microsecond
+	var poll bool
+
+	send := func(final bool) {
+		p := controlPacket{
+			diag:                  diag,
+			state:                 state,
+			poll:                  poll && !final,
+			final:                 final,
+			detectMult:            detectMult,
+			myDiscriminator:       s.discriminator,
+			yourDiscriminator:     remoteDiscriminator,
+			desiredMinTxInterval:  uint32(desiredMinTx / time.Microsecond),
+			requiredMinRxInterval: uint32(minInterval / time.Microsecond),
+		}
+		if _, err := s.conn.WriteToUDPAddrPort(p.marshal(), netip.AddrPortFrom(s.peer, controlPort)); err != nil && ctx.Err() == nil {
+			slog.Debug("failed to send BFD control packet", "peer", s.peer, "error", err)
+		}
+	}
+
+	transmit := time.NewTimer(0)
+	defer transmit.Stop()
+	detect := time.NewTimer(time.Hour)
+	detect.Stop()
+	defer detect.Stop()
+
+	// setState changes the state and switches between the slow and the configured transmit interval; the change of
+	// the interval starts a poll sequence and the new state is sent to the peer at once
+	setState := func(next State, reason uint8) {
+		if next == state {
+			return
+		}
+		state, diag = next, reason
+		interval := slowTxInterval
+		if next == StateUp {
+			interval = max(minInterval, time.Microsecond)
+		}
+		if interval != desiredMinTx {
+			desiredMinTx, poll = interval, true
+		}
+		s.state.Store(uint32(next))
+		onState(next)
+		transmit.Reset(0)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			state, diag = StateAdminDown, diagAdministrativeOff
+			s.state.Store(uint32(state))
+			send(false)
+			return
+
+		case <-transmit.C:
+			// The peer does not want any packets while its required minimum receive interval is zero
+			if remoteMinRx > 0 {
+				send(false)
+			}
+			interval := max(desiredMinTx, remoteMinRx)
+			jitter := 75 + mathrand.IntN(26)
+			if detectMult == 1 {
+				jitter = 75 + mathrand.IntN(16)
+			}
+			transmit.Reset(interval * time.Duration(jitter) / 100)
+
+		case <-detect.C:
+			if state == StateInit || state == StateUp {
+				remoteDiscriminator = 0
+				setState(StateDown, diagDetectionExpired)
+			}
+
+		case p := <-s.packets:
+			remoteDiscriminator = p.myDiscriminator
+			remoteMinTx = time.Duration(p.desiredMinTxInterval) * time.Microsecond
+			remoteMinRx = time.Duration(p.requiredMinRxInterval) * time.Microsecond
+			if p.final {
+				poll = false
+			}
+			detect.Reset(time.Duration(p.detectMult) * max(minInterval, remoteMinTx))
+
+			switch {
+			case p.state == StateAdminDown:
+				setState(StateDown, diagNeighborDown)
+			case state == StateDown && p.state == StateDown:
+				setState(StateInit, diagNone)
+			case state == StateDown && p.state == StateInit, state == StateInit && p.state != StateDown:
+				setState(StateUp, diagNone)
+			case state == StateUp && p.state == StateDown:
+				setState(StateDown, diagNeighborDown)
+			}
+			if p.poll {
+				send(true)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package configfile loads the configuration files of the CoreBGP components onto their model config structs. The
+// values are taken from the command line flags first, then from the environment variables and finally from the file.
+package configfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the names of the environment variables overriding the flags, e.g. COREBGP_API_ENDPOINT sets
+// --api-endpoint.
+const EnvPrefix = "COREBGP_"
+
+// Load decodes the configuration file named by the path flag onto the target, a pointer to the config struct the
+// flags are bound to, and applies the environment variables and the flags set on the command line over it. Without
+// a path only the environment variables are applied.
+func Load(flags *pflag.FlagSet, pathFlag string, target any) error {
+	// Remember the flags set on the command line, the file overwrites the fields they are bound to
+	explicit := make(map[string][]string)
+	flags.Visit(func(f *pflag.Flag) {
+		explicit[f.Name] = flagValue(f)
+	})
+
+	pathValue := flags.Lookup(pathFlag).Value
+	if value, ok := os.LookupEnv(EnvName(pathFlag)); ok && !flags.Changed(pathFlag) {
+		if err := pathValue.Set(value); err != nil {
+			return err
+		}
+	}
+	if path := pathValue.String(); path != "" {
+		if err := ReadFile(path, target); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Name == pathFlag {
+			return
+		}
+		if values, ok := explicit[f.Name]; ok {
+			err = setFlag(f, values)
+			return
+		}
+		if value, ok := os.LookupEnv(EnvName(f.Name)); ok {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = fmt.Errorf("invalid value %q of %s: %w", value, EnvName(f.Name), setErr)
+			}
+		}
+	})
+	return err
+}
+
+// ReadFile decodes the YAML or, with the .toml extension, the TOML file onto the target. The keys are those of the
+// yaml tags of the config struct, and unknown keys are rejected.
+func ReadFile(path string, target any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	// TOML is converted to YAML, so that both formats share the keys of the yaml tags
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		var document map[string]any
+		if err := toml.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(document); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(target); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Overridden reports whether the flag is set on the command line or by its environment variable, so that the value
+// from the configuration file does not apply.
+func Overridden(flags *pflag.FlagSet, name string) bool {
+	if flags.Changed(name) {
+		return true
+	}
+	_, ok := os.LookupEnv(EnvName(name))
+	return ok
+}
+
+// EnvName returns the name of the environment variable overriding the flag.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// flagValue returns the current value of the flag, as the elements of slice flags.
+func flagValue(f *pflag.Flag) []string {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		return slice.GetSlice()
+	}
+	return []string{f.Value.String()}
+}
+
+// setFlag sets the flag to the value returned by flagValue.
+func setFlag(f *pflag.Flag, values []string) error {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		return slice.Replace(values)
+	}
+	return f.Value.Set(values[0])
+}
@@ -0,0 +1,497 @@
+package corebgpctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/spf13/cobra"
+)
+
+// getCmd returns the command that prints a single announcement.
+func getCmd(options *globalOptions) *cobra.Command {
+	var output string
+	var cmd = &cobra.Command{
+		Use:   "get PROJECT/NAME",
+		Short: "Print an announcement",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, name, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			announcement, err := client.V1GetAnnouncement(cmd.Context(), project, name)
+			if err != nil {
+				return err
+			}
+			return printAnnouncements(cmd.OutOrStdout(), output, announcement, []model.Announcement{*announcement})
+		},
+	}
+	addOutputFlag(cmd, &output)
+	return cmd
+}
+
+// listCmd returns the command that lists the announcements of all projects or of a single one.
+func listCmd(options *globalOptions) *cobra.Command {
+	var output, selector string
+	var cmd = &cobra.Command{
+		Use:   "list [PROJECT]",
+		Short: "List announcements",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			var announcements []model.Announcement
+			switch {
+			case len(args) == 1 && selector != "":
+				announcements, err = listSelected(cmd.Context(), client, args[0], selector)
+			case len(args) == 1:
+				announcements, err = client.V1ListAllProjectAnnouncements(cmd.Context(), args[0])
+			case selector != "":
+				announcements, err = client.V1ListAnnouncementsBySelector(cmd.Context(), selector)
+			default:
+				announcements, err = client.V1ListAllAnnouncements(cmd.Context())
+			}
+			if err != nil {
+				return err
+			}
+			return printAnnouncements(cmd.OutOrStdout(), output, announcements, announcements)
+		},
+	}
+	addOutputFlag(cmd, &output)
+	addSelectorFlag(cmd, &selector, "List only the announcements whose labels meet the selector, e.g., env=staging")
+	return cmd
+}
+
+// listSelected returns the announcements of the project whose labels meet the selector, reading all pages.
+func listSelected(ctx context.Context, client *v1.APIClient, project, selector string) ([]model.Announcement, error) {
+	var announcements []model.Announcement
+	opts := v1.ListOptions{LabelSelector: selector}
+	for {
+		page, err := client.V1ListAnnouncementsPage(ctx, project, opts)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, page.Items...)
+		if page.Continue == "" {
+			return announcements, nil
+		}
+		opts.Continue = page.Continue
+	}
+}
+
+// describeCmd returns the command that prints an announcement with its status and events in a human-readable form.
+func describeCmd(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe PROJECT/NAME",
+		Short: "Show the details and the status of an announcement",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			project, name, err := parseID(args[0])
+			if err != nil {
+				return err
+			}
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			announcement, err := client.V1GetAnnouncement(cmd.Context(), project, name)
+			if err != nil {
+				return err
+			}
+			events, err := client.V1ListAnnouncementEvents(cmd.Context(), project, name)
+			if err != nil {
+				return err
+			}
+			return describeAnnouncement(cmd.OutOrStdout(), announcement, events)
+		},
+	}
+}
+
+// describeAnnouncement writes the announcement, the programming state on the routers, the health of the next hops,
+// the status conditions and the events.
+func describeAnnouncement(w io.Writer, announcement *model.Announcement, events []model.AnnouncementEvent) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", announcement.Meta.Name)
+	fmt.Fprintf(tw, "Project:\t%s\n", announcement.Meta.Project)
+	fmt.Fprintf(tw, "Resource Version:\t%s\n", orNone(announcement.Meta.ResourceVersion))
+	fmt.Fprintf(tw, "Created:\t%s\n", orNone(announcement.Meta.CreatedAt))
+	if announcement.Meta.Generation != 0 {
+		fmt.Fprintf(tw, "Generation:\t%d (change %s at %s)\n", announcement.Meta.Generation, orNone(announcement.Meta.ChangeID), orNone(announcement.Meta.ChangedAt))
+	}
+	fmt.Fprintf(tw, "Labels:\t%s\n", orNone(formatLabels(announcement.Meta.Labels)))
+	for _, key := range slices.Sorted(maps.Keys(announcement.Meta.Annotations)) {
+		fmt.Fprintf(tw, "Annotation:\t%s=%s\n", key, announcement.Meta.Annotations[key])
+	}
+	fmt.Fprintf(tw, "Announced:\t%s\n", announcedSummary(announcement))
+	fmt.Fprintf(tw, "Zone:\t%s\n", orNone(announcement.Addresses.Zone))
+	fmt.Fprintf(tw, "Next Hops:\t%s\n", nextHopSummary(announcement))
+	if announcement.Failover != "" {
+		fmt.Fprintf(tw, "Failover:\t%s\n", announcement.Failover)
+	}
+	fmt.Fprintf(tw, "Origin:\t%s\n", orNone(string(announcement.Origin)))
+	if announcement.LocalPref != 0 {
+		fmt.Fprintf(tw, "Local Preference:\t%d\n", announcement.LocalPref)
+	}
+	if announcement.MED != 0 {
+		fmt.Fprintf(tw, "MED:\t%d\n", announcement.MED)
+	}
+	if announcement.ASPathPrepend != 0 {
+		fmt.Fprintf(tw, "AS Path Prepend:\t%d\n", announcement.ASPathPrepend)
+	}
+	for _, community := range announcement.Communities {
+		fmt.Fprintf(tw, "Community:\t%s\n", community)
+	}
+
+	fmt.Fprintf(tw, "Health Check:\t%s\n", healthCheckSummary(announcement.HealthCheck))
+	if len(announcement.BFDPeers) > 0 {
+		fmt.Fprintf(tw, "BFD Peers:\t%s\n", strings.Join(announcement.BFDPeers, ", "))
+	}
+	if schedule := announcement.Schedule; schedule != nil {
+		fmt.Fprintf(tw, "Active From:\t%s\n", orNone(schedule.ActiveFrom))
+		fmt.Fprintf(tw, "Active Until:\t%s\n", orNone(schedule.ActiveUntil))
+		if schedule.TTL > 0 {
+			fmt.Fprintf(tw, "TTL:\t%s\n", time.Duration(schedule.TTL)*time.Second)
+		}
+	}
+
+	status := announcement.Status
+	fmt.Fprintf(tw, "Status:\t%s\n", orNone(status.Status))
+	fmt.Fprintf(tw, "Updated:\t%s\n", orNone(status.Timestamp))
+	if status.ObservedGeneration != 0 {
+		fmt.Fprintf(tw, "Observed Generation:\t%d (programmed at %s in %.3fs)\n", status.ObservedGeneration, orNone(status.ProgrammedAt), status.PropagationSeconds)
+	}
+	if len(status.Draining) > 0 {
+		fmt.Fprintf(tw, "Draining:\t%s\n", strings.Join(status.Draining, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(status.Routers) > 0 {
+		fmt.Fprintln(w, "Routers:")
+		tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "  ROUTER\tSTATUS\tGENERATION\tUPDATED\tMESSAGE")
+		for _, router := range status.Routers {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", router.Router, router.Status, orNone(generationOf(router)), router.Timestamp, router.Message)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(status.Details) > 0 {
+		healthy := 0
+		for _, details := range status.Details {
+			if details.Status == model.HealthHealthy {
+				healthy++
+			}
+		}
+		fmt.Fprintf(w, "Next Hop Health (%d of %d healthy):\n", healthy, len(status.Details))
+		tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "  HOST\tSTATUS\tCHECKED\tLATENCY\tFAILURES\tMESSAGE")
+		for _, details := range status.Details {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%.1fms\t%d\t%s\n", details.Host, details.Status, details.Timestamp,
+				details.LatencyMS, details.ConsecutiveFailures, details.Message)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(status.Conditions) > 0 {
+		fmt.Fprintln(w, "Conditions:")
+		tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "  TYPE\tSTATUS\tREASON\tLAST TRANSITION\tMESSAGE")
+		for _, condition := range status.Conditions {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason,
+				condition.LastTransitionTime, condition.Message)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(events) == 0 {
+		fmt.Fprintln(w, "Events: <none>")
+		return nil
+	}
+	fmt.Fprintln(w, "Events:")
+	tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "  TIME\tTYPE\tREASON\tACTOR\tMESSAGE")
+	for _, event := range events {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", event.Timestamp.Format(time.RFC3339), event.Type, event.Reason,
+			orNone(event.Actor), event.Message)
+	}
+	return tw.Flush()
+}
+
+// formatLabels joins the labels in the key=value form, sorted by key.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// generationOf returns the generation programmed on the router, empty for a failed router or an announcement written
+// before the generations were tracked.
+func generationOf(router model.RouterStatus) string {
+	if router.ObservedGeneration == 0 {
+		return ""
+	}
+	return fmt.Sprint(router.ObservedGeneration)
+}
+
+// healthCheckSummary describes the probe and the timing of the health check.
+func healthCheckSummary(healthCheck model.HealthCheck) string {
+	if !healthCheck.Enabled() {
+		return "<none>"
+	}
+
+	probe := string(healthCheck.ProbeType())
+	switch healthCheck.ProbeType() {
+	case model.HealthCheckHTTP:
+		probe += fmt.Sprintf(" port %d path %s", healthCheck.Port, orNone(healthCheck.Path))
+	case model.HealthCheckGRPC:
+		probe += fmt.Sprintf(" port %d service %s", healthCheck.Port, orNone(healthCheck.Service))
+	case model.HealthCheckTCP:
+		probe += fmt.Sprintf(" port %d", healthCheck.Port)
+	}
+	return fmt.Sprintf("%s (interval %ds, timeout %ds, rise %d, fall %d)", probe,
+		healthCheck.CheckInterval, healthCheck.Timeout, healthCheck.Rise, healthCheck.Fall)
+}
+
+// createCmd returns the command that creates the announcements of the manifest files.
+func createCmd(options *globalOptions) *cobra.Command {
+	var (
+		files  []string
+		dryRun bool
+	)
+	var cmd = &cobra.Command{
+		Use:   "create -f FILE...",
+		Short: "Create announcements from YAML or JSON manifests",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			announcements, err := readManifests(files, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			var writeOpts []v1.WriteOption
+			if dryRun {
+				writeOpts = append(writeOpts, v1.DryRun())
+			}
+			return forEach(cmd, announcements, "created", dryRun, func(announcement *model.Announcement) error {
+				return client.V1CreateAnnouncement(cmd.Context(), announcement, writeOpts...)
+			})
+		},
+	}
+	addFileFlag(cmd, &files)
+	addDryRunFlag(cmd, &dryRun)
+	return cmd
+}
+
+// applyCmd returns the command that creates or updates the announcements of the manifest files with declarative
+// apply semantics: fields previously applied by the manager and removed from the manifest are removed.
+func applyCmd(options *globalOptions) *cobra.Command {
+	var (
+		files   []string
+		manager string
+	)
+	var cmd = &cobra.Command{
+		Use:   "apply -f FILE...",
+		Short: "Create or update announcements from YAML or JSON manifests",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			announcements, err := readManifests(files, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			return forEach(cmd, announcements, "applied", false, func(announcement *model.Announcement) error {
+				_, err := client.V1ApplyAnnouncement(cmd.Context(), manager, announcement)
+				return err
+			})
+		},
+	}
+	addFileFlag(cmd, &files)
+	cmd.Flags().StringVar(&manager, "manager", "corebgpctl", "Name of the manager owning the applied fields")
+	return cmd
+}
+
+// deleteCmd returns the command that deletes announcements given by ID or by manifest files.
+func deleteCmd(options *globalOptions) *cobra.Command {
+	var (
+		files    []string
+		selector string
+		dryRun   bool
+		withdraw bool
+	)
+	var cmd = &cobra.Command{
+		Use:   "delete (PROJECT/NAME... | -f FILE... | PROJECT -l SELECTOR)",
+		Short: "Delete announcements",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if selector != "" {
+				if len(args) != 1 || len(files) > 0 || withdraw {
+					return fmt.Errorf("--selector requires a single PROJECT argument and does not support --filename and --withdraw")
+				}
+				return deleteSelected(cmd, options, args[0], selector, dryRun)
+			}
+			if len(args) == 0 && len(files) == 0 {
+				return fmt.Errorf("announcements must be given as PROJECT/NAME arguments or with --filename")
+			}
+			if withdraw && dryRun {
+				return fmt.Errorf("--withdraw does not support --dry-run")
+			}
+
+			var announcements []*model.Announcement
+			for _, id := range args {
+				project, name, err := parseID(id)
+				if err != nil {
+					return err
+				}
+				announcements = append(announcements, &model.Announcement{Meta: model.Meta{Project: project, Name: name}})
+			}
+			fromFiles, err := readManifests(files, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			announcements = append(announcements, fromFiles...)
+
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			if withdraw {
+				return forEach(cmd, announcements, "withdrawn", false, func(announcement *model.Announcement) error {
+					return client.V1SoftDeleteAnnouncement(cmd.Context(), announcement.Meta.Project, announcement.Meta.Name)
+				})
+			}
+			var writeOpts []v1.WriteOption
+			if dryRun {
+				writeOpts = append(writeOpts, v1.DryRun())
+			}
+			return forEach(cmd, announcements, "deleted", dryRun, func(announcement *model.Announcement) error {
+				return client.V1DeleteAnnouncement(cmd.Context(), announcement.Meta.Project, announcement.Meta.Name, writeOpts...)
+			})
+		},
+	}
+	addFileFlag(cmd, &files)
+	addDryRunFlag(cmd, &dryRun)
+	cmd.Flags().BoolVar(&withdraw, "withdraw", false, "Mark the announcements as withdrawn instead of deleting them")
+	addSelectorFlag(cmd, &selector, "Delete the announcements of the project whose labels meet the selector, e.g., env=staging")
+	return cmd
+}
+
+// deleteSelected deletes the announcements of the project selected by the label selector in a single request and
+// reports each of them.
+func deleteSelected(cmd *cobra.Command, options *globalOptions, project, selector string, dryRun bool) error {
+	client, err := options.client()
+	if err != nil {
+		return err
+	}
+
+	var writeOpts []v1.WriteOption
+	suffix := ""
+	if dryRun {
+		writeOpts = append(writeOpts, v1.DryRun())
+		suffix = " (dry run)"
+	}
+	deleted, err := client.V1DeleteCollection(cmd.Context(), project, selector, writeOpts...)
+	if err != nil {
+		return err
+	}
+	if len(deleted) == 0 {
+		return fmt.Errorf("no announcements of project %s match %q", project, selector)
+	}
+	for _, announcement := range deleted {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s/%s deleted%s\n", announcement.Meta.Project, announcement.Meta.Name, suffix)
+	}
+	return nil
+}
+
+// addSelectorFlag adds the --selector flag of a label selector.
+func addSelectorFlag(cmd *cobra.Command, selector *string, usage string) {
+	cmd.Flags().StringVarP(selector, "selector", "l", "", usage)
+}
+
+// addFileFlag adds the repeatable --filename flag of the manifest files.
+func addFileFlag(cmd *cobra.Command, files *[]string) {
+	cmd.Flags().StringArrayVarP(files, "filename", "f", nil, "Path to a YAML or JSON manifest or a directory of manifests, - reads standard input (repeatable)")
+}
+
+// addDryRunFlag adds the --dry-run flag.
+func addDryRunFlag(cmd *cobra.Command, dryRun *bool) {
+	cmd.Flags().BoolVar(dryRun, "dry-run", false, "Validate the changes on the API server without persisting them")
+}
+
+// forEach calls fn for every announcement and reports the outcome of each call. All announcements are processed
+// even if some of them fail, the returned error counts the failures.
+func forEach(cmd *cobra.Command, announcements []*model.Announcement, action string, dryRun bool, fn func(announcement *model.Announcement) error) error {
+	if len(announcements) == 0 {
+		return fmt.Errorf("no announcements given")
+	}
+
+	suffix := ""
+	if dryRun {
+		suffix = " (dry run)"
+	}
+
+	failed := 0
+	for _, announcement := range announcements {
+		id := announcement.Meta.Project + "/" + announcement.Meta.Name
+		if err := fn(announcement); err != nil {
+			failed++
+			printError(cmd.ErrOrStderr(), id, err)
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s%s\n", id, action, suffix)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d announcements failed", failed, len(announcements))
+	}
+	return nil
+}
+
+// printError writes the error of the announcement, listing the invalid fields of a validation error on separate lines.
+func printError(w io.Writer, id string, err error) {
+	var validationErr *v1.ValidationError
+	if !errors.As(err, &validationErr) {
+		fmt.Fprintf(w, "%s: %v\n", id, err)
+		return
+	}
+
+	fmt.Fprintf(w, "%s: invalid announcement:\n", id)
+	for _, field := range validationErr.Fields {
+		fmt.Fprintf(w, "  %s: %s\n", field.Field, field.Message)
+	}
+}
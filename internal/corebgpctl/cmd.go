@@ -0,0 +1,96 @@
+// Package corebgpctl implements corebgpctl, the command line client operators use to manage announcements and
+// to check the CoreBGP API server.
+package corebgpctl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/spf13/cobra"
+)
+
+// globalOptions holds the connection parameters shared by all subcommands.
+type globalOptions struct {
+	server     string        // server is the URL of the API server.
+	caCert     string        // caCert is the path to the CA certificate used to verify the API server.
+	clientCert string        // clientCert is the path to the client certificate presented to the API server.
+	clientKey  string        // clientKey is the path to the key of the client certificate.
+	insecure   bool          // insecure disables the verification of the API server certificate.
+	tokenFile  string        // tokenFile is the path to the file with the bearer token.
+	timeout    time.Duration // timeout is the deadline of every request.
+}
+
+// RootCmd initializes and returns the root command of corebgpctl.
+func RootCmd() *cobra.Command {
+	var options globalOptions
+	var cmd = &cobra.Command{
+		Use:           "corebgpctl",
+		Short:         "Command line client of the CoreBGP API server",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.PersistentFlags().StringVarP(&options.server, "server", "s", "http://localhost:8080", "URL of the API server")
+	cmd.PersistentFlags().StringVar(&options.caCert, "ca-cert", "", "Path to CA certificate used to verify the API server")
+	cmd.PersistentFlags().StringVar(&options.clientCert, "client-cert", "", "Path to client certificate presented to the API server")
+	cmd.PersistentFlags().StringVar(&options.clientKey, "client-key", "", "Path to client key presented to the API server")
+	cmd.PersistentFlags().BoolVar(&options.insecure, "insecure-skip-verify", false, "Skip verification of the API server certificate (testing only)")
+	cmd.PersistentFlags().StringVar(&options.tokenFile, "token-file", "", "Path to the file with the bearer token used to authenticate to the API server")
+	cmd.PersistentFlags().DurationVar(&options.timeout, "timeout", 10*time.Second, "Deadline of every request")
+
+	cmd.AddCommand(
+		getCmd(&options),
+		listCmd(&options),
+		describeCmd(&options),
+		createCmd(&options),
+		applyCmd(&options),
+		deleteCmd(&options),
+		watchCmd(&options),
+		healthCmd(&options),
+		statusCmd(&options),
+		exportCmd(&options),
+		importCmd(&options),
+		conflictsCmd(&options),
+	)
+	return cmd
+}
+
+// client creates the API client from the connection flags.
+func (o *globalOptions) client() (*v1.APIClient, error) {
+	var clientOpts []v1.ClientOption
+	if o.caCert != "" || o.clientCert != "" || o.insecure {
+		tlsConfig, err := v1.NewTLSConfig(v1.ClientConfig{
+			CACert:             o.caCert,
+			ClientCert:         o.clientCert,
+			ClientKey:          o.clientKey,
+			InsecureSkipVerify: o.insecure,
+		})
+		if err != nil {
+			return nil, err
+		}
+		clientOpts = append(clientOpts, v1.WithTLSConfig(tlsConfig))
+	}
+	if o.tokenFile != "" {
+		token, err := os.ReadFile(o.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read API token: %w", err)
+		}
+		clientOpts = append(clientOpts, v1.WithBearerToken(strings.TrimSpace(string(token))))
+	}
+	// Retry the requests throttled by the API server after the delay it asks for, and the idempotent requests
+	// failing with a connection or server error
+	clientOpts = append(clientOpts, v1.WithThrottleRetries(3), v1.WithRetryPolicy(v1.DefaultRetryPolicy))
+	return v1.NewAPIClient(&o.server, o.timeout, clientOpts...), nil
+}
+
+// parseID splits an announcement ID in the PROJECT/NAME form.
+func parseID(id string) (string, string, error) {
+	project, name, ok := strings.Cut(id, "/")
+	if !ok || project == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid announcement %q: must be PROJECT/NAME", id)
+	}
+	return project, name, nil
+}
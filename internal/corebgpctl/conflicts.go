@@ -0,0 +1,52 @@
+package corebgpctl
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// conflictsCmd returns the command that lists the announcements of different projects whose prefixes overlap.
+func conflictsCmd(options *globalOptions) *cobra.Command {
+	var output string
+	var cmd = &cobra.Command{
+		Use:   "conflicts [PROJECT]",
+		Short: "List the announcements of different projects whose prefixes equal or overlap",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var project string
+			if len(args) == 1 {
+				project = args[0]
+			}
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			conflicts, err := client.V1ListConflicts(cmd.Context(), project)
+			if err != nil {
+				return err
+			}
+			switch output {
+			case outputTable:
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+				fmt.Fprintln(tw, "PROJECT\tNAME\tPREFIX\tCONFLICTING-PROJECT\tCONFLICTING-NAME\tCONFLICTING-PREFIX\tEQUAL")
+				for _, conflict := range conflicts {
+					first, second := conflict.Announcements[0], conflict.Announcements[1]
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%t\n", first.Project, first.Name, first.Prefix,
+						second.Project, second.Name, second.Prefix, conflict.Equal)
+				}
+				return tw.Flush()
+			case outputJSON:
+				return printJSON(cmd.OutOrStdout(), conflicts)
+			case outputYAML:
+				return printYAML(cmd.OutOrStdout(), conflicts)
+			default:
+				return fmt.Errorf("invalid output format %q: must be %s, %s or %s", output, outputTable, outputJSON, outputYAML)
+			}
+		},
+	}
+	addOutputFlag(cmd, &output)
+	return cmd
+}
@@ -0,0 +1,112 @@
+package corebgpctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd returns the command that prints the bundle of all projects or of a project.
+func exportCmd(options *globalOptions) *cobra.Command {
+	var output string
+	var cmd = &cobra.Command{
+		Use:   "export [PROJECT]",
+		Short: "Export the resources of all projects or of a project as a bundle",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var project string
+			if len(args) == 1 {
+				project = args[0]
+			}
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			bundle, err := client.V1Export(cmd.Context(), project)
+			if err != nil {
+				return err
+			}
+			switch output {
+			case outputJSON:
+				return printJSON(cmd.OutOrStdout(), bundle)
+			case outputYAML:
+				return printYAML(cmd.OutOrStdout(), bundle)
+			default:
+				return fmt.Errorf("invalid output format %q: must be %s or %s", output, outputJSON, outputYAML)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", outputYAML, "Output format: json or yaml")
+	return cmd
+}
+
+// importCmd returns the command that imports an exported bundle.
+func importCmd(options *globalOptions) *cobra.Command {
+	var (
+		file     string
+		strategy string
+		dryRun   bool
+	)
+	var cmd = &cobra.Command{
+		Use:   "import -f FILE",
+		Short: "Import the resources of an exported bundle",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !model.ImportStrategy(strategy).Valid() {
+				return fmt.Errorf("invalid strategy %q: must be %s, %s or %s", strategy, model.ImportSkip, model.ImportOverwrite, model.ImportFail)
+			}
+
+			var data []byte
+			var err error
+			if file == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return fmt.Errorf("could not read bundle: %w", err)
+			}
+			var bundle model.Bundle
+			if err := model.UnmarshalYAML(data, &bundle); err != nil {
+				return fmt.Errorf("invalid bundle %s: %w", file, err)
+			}
+
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+			var writeOpts []v1.WriteOption
+			suffix := ""
+			if dryRun {
+				writeOpts = append(writeOpts, v1.DryRun())
+				suffix = " (dry run)"
+			}
+			report, err := client.V1Import(cmd.Context(), &bundle, model.ImportStrategy(strategy), writeOpts...)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			for _, id := range report.Created {
+				fmt.Fprintf(w, "%s created%s\n", id, suffix)
+			}
+			for _, id := range report.Overwritten {
+				fmt.Fprintf(w, "%s overwritten%s\n", id, suffix)
+			}
+			for _, id := range report.Skipped {
+				fmt.Fprintf(w, "%s skipped%s\n", id, suffix)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "Path to a YAML or JSON bundle, - reads standard input")
+	_ = cmd.MarkFlagRequired("filename")
+	cmd.Flags().StringVar(&strategy, "strategy", string(model.ImportFail), "Treatment of the existing resources: fail, skip or overwrite")
+	addDryRunFlag(cmd, &dryRun)
+	return cmd
+}
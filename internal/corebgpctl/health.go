@@ -0,0 +1,66 @@
+package corebgpctl
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// healthCmd returns the command that checks that the API server is healthy.
+func healthCmd(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "health",
+		Short: "Check the health of the API server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			if err := client.V1HealthCheck(cmd.Context()); err != nil {
+				return fmt.Errorf("API server %s is unhealthy: %w", options.server, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "API server %s is healthy\n", options.server)
+			return nil
+		},
+	}
+}
+
+// statusCmd returns the command that prints the health of the API server and the state of the BGP sessions of
+// its GoBGP instance.
+func statusCmd(options *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the API server and its BGP sessions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			defer tw.Flush()
+
+			fmt.Fprintf(tw, "API Server:\t%s\n", options.server)
+			if err := client.V1HealthCheck(cmd.Context()); err != nil {
+				fmt.Fprintf(tw, "Health:\tunhealthy (%v)\n", err)
+				return fmt.Errorf("API server is unhealthy")
+			}
+			fmt.Fprintf(tw, "Health:\thealthy\n")
+
+			summary, err := client.V1GetBGPSessionSummary(cmd.Context())
+			if err != nil {
+				fmt.Fprintf(tw, "BGP:\tunavailable (%v)\n", err)
+				return fmt.Errorf("failed to get BGP session summary: %w", err)
+			}
+			fmt.Fprintf(tw, "AS Number:\t%d\n", summary.ASN)
+			fmt.Fprintf(tw, "Router ID:\t%s\n", summary.RouterID)
+			fmt.Fprintf(tw, "Peers:\t%d established, %d idle, %d total\n", summary.EstablishedPeers, summary.IdlePeers, summary.TotalPeers)
+			fmt.Fprintf(tw, "Prefixes:\t%d received, %d sent\n", summary.TotalPrefixesReceived, summary.TotalPrefixesSent)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package corebgpctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// readManifests reads the announcements of the manifest files. A file holds YAML documents separated by "---" or
+// JSON, since JSON is valid YAML, and every document is an announcement or a list of announcements. The path "-"
+// reads the standard input, a directory is expanded to its .yaml, .yml and .json files.
+func readManifests(paths []string, stdin io.Reader) ([]*model.Announcement, error) {
+	paths, err := expandDirectories(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var announcements []*model.Announcement
+	for _, path := range paths {
+		var data []byte
+		var err error
+		if path == "-" {
+			data, err = io.ReadAll(stdin)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest: %w", err)
+		}
+
+		parsed, err := model.UnmarshalAnnouncementsYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+		}
+		announcements = append(announcements, parsed...)
+	}
+	return announcements, nil
+}
+
+// expandDirectories replaces the directories among the paths with the manifest files they contain, in lexical order.
+func expandDirectories(paths []string) ([]string, error) {
+	expanded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if path == "-" || err != nil || !info.IsDir() {
+			expanded = append(expanded, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest directory: %w", err)
+		}
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					expanded = append(expanded, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// printJSON writes the object as indented JSON.
+func printJSON(w io.Writer, object interface{}) error {
+	data, err := json.MarshalIndent(object, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// printYAML writes the object as YAML with the JSON field names of the API.
+func printYAML(w io.Writer, object interface{}) error {
+	data, err := model.MarshalYAML(object)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,87 @@
+package corebgpctl
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// Output formats of the get and list commands.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// addOutputFlag adds the --output flag selecting the output format.
+func addOutputFlag(cmd *cobra.Command, output *string) {
+	cmd.Flags().StringVarP(output, "output", "o", outputTable, "Output format: table, json or yaml")
+}
+
+// printAnnouncements writes the object in the output format. The table lists the announcements, the other formats
+// print the object itself, i.e. a single announcement or a list.
+func printAnnouncements(w io.Writer, output string, object interface{}, announcements []model.Announcement) error {
+	switch output {
+	case outputTable:
+		return printAnnouncementTable(w, announcements)
+	case outputJSON:
+		return printJSON(w, object)
+	case outputYAML:
+		return printYAML(w, object)
+	default:
+		return fmt.Errorf("invalid output format %q: must be %s, %s or %s", output, outputTable, outputJSON, outputYAML)
+	}
+}
+
+// printAnnouncementTable writes one line per announcement with its addresses, next hops and status.
+func printAnnouncementTable(w io.Writer, announcements []model.Announcement) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tNAME\tANNOUNCED\tNEXT-HOPS\tSTATUS")
+	for i := range announcements {
+		announcement := &announcements[i]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", announcement.Meta.Project, announcement.Meta.Name,
+			announcedSummary(announcement), nextHopSummary(announcement), orNone(announcement.Status.Status))
+	}
+	return tw.Flush()
+}
+
+// announcedSummary returns the announced addresses, or the source subnet if the address is allocated from it.
+func announcedSummary(announcement *model.Announcement) string {
+	if addresses := announcement.AnnouncedAddresses(); len(addresses) > 0 {
+		return strings.Join(addresses, ",")
+	}
+	if subnet := announcement.Addresses.SourceSubnets; subnet.IP != "" {
+		return fmt.Sprintf("%s/%d", subnet.IP, subnet.Mask)
+	}
+	return "<none>"
+}
+
+// nextHopSummary returns the next hops of the announcement, with the weights of weighted next hops.
+func nextHopSummary(announcement *model.Announcement) string {
+	var nextHops []string
+	if len(announcement.WeightedNextHops) > 0 {
+		for _, nextHop := range announcement.WeightedNextHops {
+			nextHops = append(nextHops, fmt.Sprintf("%s*%d", nextHop.Address, nextHop.Weight))
+		}
+	} else {
+		for _, nextHop := range announcement.NextHops {
+			nextHops = append(nextHops, nextHop.IP)
+		}
+	}
+	if len(nextHops) == 0 {
+		return "<none>"
+	}
+	return strings.Join(nextHops, ",")
+}
+
+// orNone returns the value, or "<none>" if it is empty.
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
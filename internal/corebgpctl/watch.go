@@ -0,0 +1,107 @@
+package corebgpctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/spf13/cobra"
+)
+
+// watchLineFormat is the format of the lines of the watch table. The columns have a fixed width, since the events
+// are printed as they arrive.
+const watchLineFormat = "%-8s  %-8s  %-16s  %-24s  %-32s  %-32s  %s\n"
+
+// watchCmd returns the command that prints the announcement changes as they happen.
+func watchCmd(options *globalOptions) *cobra.Command {
+	var (
+		output       string
+		revision     int64
+		namePrefix   string
+		selector     string
+		events       []string
+		pingInterval time.Duration
+	)
+	var cmd = &cobra.Command{
+		Use:   "watch [PROJECT]",
+		Short: "Watch announcement changes",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != outputTable && output != outputJSON {
+				return fmt.Errorf("invalid output format %q: must be %s or %s", output, outputTable, outputJSON)
+			}
+			client, err := options.client()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			w := cmd.OutOrStdout()
+			if output == outputTable {
+				fmt.Fprintf(w, watchLineFormat, "TIME", "EVENT", "PROJECT", "NAME", "ANNOUNCED", "NEXT-HOPS", "STATUS")
+			}
+
+			eventTypes := make([]model.EventType, len(events))
+			for i, event := range events {
+				eventTypes[i] = model.EventType(event)
+			}
+			watchOpts := []v1.WatchOption{
+				v1.WithNamePrefix(namePrefix),
+				v1.WithLabelSelector(selector),
+				v1.WithEventTypes(eventTypes...),
+				v1.WithResyncCallback(func() {
+					fmt.Fprintln(cmd.ErrOrStderr(), "watch history was compacted, events may have been missed")
+				}),
+				v1.WithBookmarkCallback(func(bookmark int64) {
+					revision = max(revision, bookmark)
+				}),
+				v1.WithPingInterval(pingInterval),
+			}
+			if len(args) == 1 {
+				watchOpts = append(watchOpts, v1.WithProject(args[0]))
+			}
+			printEvent := func(event model.Event) {
+				revision = max(revision, event.Revision)
+				if output == outputJSON {
+					_ = printJSON(w, event)
+					return
+				}
+				fmt.Fprintf(w, watchLineFormat, time.Now().Format(time.TimeOnly), event.Type,
+					event.Announcement.Meta.Project, event.Announcement.Meta.Name, announcedSummary(&event.Announcement),
+					nextHopSummary(&event.Announcement), orNone(event.Announcement.Status.Status))
+			}
+
+			// A lost connection is re-established from the last seen or bookmarked revision, while a watch that can
+			// not be established at all fails
+			for {
+				err = client.V1WatchAnnouncements(ctx, printEvent, append(watchOpts, v1.WithRevision(revision))...)
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil && !errors.Is(err, v1.ErrWatchTimeout) {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "watch connection lost, resuming from revision %d\n", revision)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(time.Second):
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", outputTable, "Output format: table or json")
+	cmd.Flags().Int64Var(&revision, "revision", 0, "Resume the watch right after the revision instead of starting from the current state")
+	cmd.Flags().StringVar(&namePrefix, "name-prefix", "", "Watch only the announcements whose name starts with the prefix")
+	addSelectorFlag(cmd, &selector, "Watch only the announcements whose labels meet the selector, e.g., env=staging")
+	cmd.Flags().StringSliceVar(&events, "events", nil, "Comma separated list of the watched event types: added, updated or deleted (all if empty)")
+	cmd.Flags().DurationVar(&pingInterval, "ping-interval", 15*time.Second, "Interval of the pings detecting a dead connection, which is re-established after three intervals without anything received (0 disables them)")
+	return cmd
+}
@@ -0,0 +1,183 @@
+package frr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// vtyshTimeout bounds a single vtysh invocation.
+const vtyshTimeout = 10 * time.Second
+
+// Client programs paths into the BGP instance of the default VRF of a local FRRouting daemon through vtysh. Every
+// path is a network statement with a route map that sets the next hop and the path attributes, so FRR originates a
+// single path per prefix. The network statements and route maps of other origins are left untouched.
+type Client struct {
+	vtysh string
+	mu    sync.Mutex // mu serializes the configuration changes, which read the running configuration first.
+}
+
+// NewClient checks that vtysh can read the running configuration of FRR and that a BGP instance is configured in
+// the default VRF.
+func NewClient(vtysh string) (*Client, error) {
+	c := &Client{vtysh: vtysh}
+	config, err := c.runningConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config.asn == 0 {
+		return nil, errors.New("FRR has no BGP instance in the default VRF")
+	}
+	if config.importCheck {
+		slog.Warn("FRR originates the announced addresses only while they are in its RIB, configure \"no bgp network import-check\" to originate them unconditionally",
+			"asn", config.asn)
+	}
+	return c, nil
+}
+
+// Close releases the resources of the client. vtysh is started for every change, so there is nothing to close.
+func (c *Client) Close() {}
+
+// AddPath originates the prefix via the next hop with the path attributes, replacing the attributes of the path
+// originated before. FRR originates a single path per prefix, so a path via another next hop must be deleted first.
+func (c *Client) AddPath(prefix string, prefixLength uint32, nextHop string, attrs gobgp.PathAttributes) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	config, err := c.runningConfig()
+	if err != nil {
+		return err
+	}
+	network := networkPrefix(prefix, prefixLength)
+	if current, ok := config.paths[network]; ok && !model.SameAddress(current.NextHop, nextHop) {
+		return fmt.Errorf("FRR originates a single path per prefix, %s is already originated via %s", network, current.NextHop)
+	}
+
+	clauses, err := setClauses(prefix, nextHop, attrs, config.asn)
+	if err != nil {
+		return err
+	}
+
+	// The route map is created again, so that the clauses of the attributes no longer set are removed
+	routeMap := routeMapName(network)
+	commands := []string{"configure terminal"}
+	if config.routeMaps[routeMap] {
+		commands = append(commands, "no route-map "+routeMap)
+	}
+	commands = append(commands, "route-map "+routeMap+" permit 10")
+	commands = append(commands, clauses...)
+	commands = append(commands, "exit",
+		"router bgp "+strconv.FormatUint(uint64(config.asn), 10),
+		"address-family "+addressFamily(prefix),
+		"network "+network+" route-map "+routeMap,
+	)
+	if err := c.run(commands...); err != nil {
+		return fmt.Errorf("failed to add path to FRR: %w", err)
+	}
+	return nil
+}
+
+// DeletePath stops originating the prefix via the next hop. Deleting a path that is not originated is not an error.
+func (c *Client) DeletePath(prefix string, prefixLength uint32, nextHop string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	config, err := c.runningConfig()
+	if err != nil {
+		return err
+	}
+	network := networkPrefix(prefix, prefixLength)
+	if current, ok := config.paths[network]; !ok || !model.SameAddress(current.NextHop, nextHop) {
+		return nil
+	}
+
+	routeMap := routeMapName(network)
+	err = c.run("configure terminal",
+		"router bgp "+strconv.FormatUint(uint64(config.asn), 10),
+		"address-family "+addressFamily(prefix),
+		"no network "+network,
+		"exit-address-family",
+		"exit",
+		"no route-map "+routeMap,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete path from FRR: %w", err)
+	}
+	return nil
+}
+
+// DeleteLocalPath stops originating the path listed by ListLocalPaths.
+func (c *Client) DeleteLocalPath(path gobgp.LocalPath) error {
+	return c.DeletePath(path.Prefix, path.PrefixLength, path.NextHop)
+}
+
+// ListLocalPaths returns the paths of the address family ("ipv4-unicast" or "ipv6-unicast") originated by the
+// client. Only the prefix and the next hop of the paths are read back from the configuration.
+func (c *Client) ListLocalPaths(family string) ([]gobgp.LocalPath, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	config, err := c.runningConfig()
+	if err != nil {
+		return nil, err
+	}
+	var paths []gobgp.LocalPath
+	for _, path := range config.paths {
+		if model.AddressFamilyOf(path.Prefix) == family {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// runningConfig reads and parses the running configuration of FRR.
+func (c *Client) runningConfig() (*runningConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), vtyshTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, c.vtysh, "-c", "show running-config").Output()
+	if err != nil {
+		vtyshErrors.Inc()
+		return nil, fmt.Errorf("failed to read the running configuration of FRR: %w", err)
+	}
+	return parseRunningConfig(string(output)), nil
+}
+
+// run executes the commands in a single vtysh session. vtysh reports failed commands with lines starting with "%",
+// which are returned as the error.
+func (c *Client) run(commands ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), vtyshTimeout)
+	defer cancel()
+
+	args := make([]string, 0, 2*len(commands))
+	for _, command := range commands {
+		args = append(args, "-c", command)
+	}
+	output, err := exec.CommandContext(ctx, c.vtysh, args...).CombinedOutput()
+
+	var messages []string
+	for _, line := range strings.Split(string(bytes.TrimSpace(output)), "\n") {
+		if strings.HasPrefix(line, "%") {
+			messages = append(messages, strings.TrimSpace(strings.TrimPrefix(line, "%")))
+		}
+	}
+	switch {
+	case len(messages) > 0:
+		vtyshErrors.Inc()
+		return errors.New(strings.Join(messages, "; "))
+	case err != nil:
+		vtyshErrors.Inc()
+		return fmt.Errorf("vtysh failed: %w", err)
+	}
+	return nil
+}
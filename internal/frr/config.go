@@ -0,0 +1,170 @@
+package frr
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+const (
+	routeMapPrefix    = "corebgp-" // routeMapPrefix starts the names of the route maps of the paths originated by the client.
+	maxBandwidthMbits = 25600      // maxBandwidthMbits is the largest link bandwidth in Mbit/s accepted by FRR.
+)
+
+// runningConfig holds the parts of the running configuration of FRR that the client manages.
+type runningConfig struct {
+	asn         uint32                     // asn is the AS number of the BGP instance of the default VRF; zero if there is none.
+	importCheck bool                       // importCheck reports whether FRR originates the networks only while they are in its RIB.
+	paths       map[string]gobgp.LocalPath // paths maps the networks originated by the client to their paths.
+	routeMaps   map[string]bool            // routeMaps holds the names of the route maps of the client.
+}
+
+// parseRunningConfig extracts the BGP instance of the default VRF, its network statements with the route maps of the
+// client and the next hops set by these route maps from the output of "show running-config".
+func parseRunningConfig(text string) *runningConfig {
+	config := &runningConfig{
+		importCheck: true,
+		paths:       make(map[string]gobgp.LocalPath),
+		routeMaps:   make(map[string]bool),
+	}
+	networks := make(map[string]string) // networks maps the networks to their route maps.
+	nextHops := make(map[string]string) // nextHops maps the route maps to the next hops they set.
+
+	var bgp bool
+	var routeMap string
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Unindented lines start a new section
+		if !strings.HasPrefix(line, " ") {
+			bgp, routeMap = false, ""
+			switch {
+			case len(fields) == 3 && fields[0] == "router" && fields[1] == "bgp":
+				if asn, err := strconv.ParseUint(fields[2], 10, 32); err == nil {
+					config.asn, bgp = uint32(asn), true
+				}
+			case len(fields) >= 2 && fields[0] == "route-map" && strings.HasPrefix(fields[1], routeMapPrefix):
+				routeMap = fields[1]
+				config.routeMaps[routeMap] = true
+			}
+			continue
+		}
+
+		switch {
+		case bgp && strings.Join(fields, " ") == "no bgp network import-check":
+			config.importCheck = false
+		case bgp && len(fields) == 4 && fields[0] == "network" && fields[2] == "route-map" && strings.HasPrefix(fields[3], routeMapPrefix):
+			networks[fields[1]] = fields[3]
+		case routeMap != "" && len(fields) == 4 && strings.Join(fields[:3], " ") == "set ip next-hop":
+			nextHops[routeMap] = fields[3]
+		case routeMap != "" && len(fields) == 5 && strings.Join(fields[:4], " ") == "set ipv6 next-hop global":
+			nextHops[routeMap] = fields[4]
+		}
+	}
+
+	for network, routeMap := range networks {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			continue
+		}
+		config.paths[network] = gobgp.LocalPath{
+			Prefix:       prefix.Addr().String(),
+			PrefixLength: uint32(prefix.Bits()),
+			NextHop:      nextHops[routeMap],
+		}
+	}
+	return config
+}
+
+// setClauses returns the route map clauses setting the next hop and the path attributes of the path. The local AS
+// number is prepended to the AS_PATH.
+func setClauses(prefix, nextHop string, attrs gobgp.PathAttributes, localASN uint32) ([]string, error) {
+	clauses := []string{"set ip next-hop " + nextHop}
+	if model.AddressFamilyOf(prefix) == model.AddressFamilyIPv6Unicast {
+		clauses = []string{"set ipv6 next-hop global " + nextHop}
+	}
+	clauses = append(clauses, "set origin "+string(model.OriginFromCode(attrs.Origin)))
+	if attrs.MED != 0 {
+		clauses = append(clauses, "set metric "+strconv.FormatUint(uint64(attrs.MED), 10))
+	}
+	if attrs.LocalPref != 0 {
+		clauses = append(clauses, "set local-preference "+strconv.FormatUint(uint64(attrs.LocalPref), 10))
+	}
+	if attrs.ASPathPrepend != 0 {
+		asn := strconv.FormatUint(uint64(localASN), 10)
+		clauses = append(clauses, "set as-path prepend "+strings.TrimSpace(strings.Repeat(asn+" ", int(attrs.ASPathPrepend))))
+	}
+
+	var standard, routeTargets, routeOrigins, large []string
+	for _, value := range attrs.Communities {
+		community, err := model.ParseCommunity(value)
+		if err != nil {
+			return nil, err
+		}
+		switch community.Type {
+		case model.CommunityStandard:
+			standard = append(standard, fmt.Sprintf("%d:%d", community.Value>>16, community.Value&0xFFFF))
+		case model.CommunityLarge:
+			large = append(large, community.String())
+		case model.CommunityExtended:
+			global := community.IPv4
+			if global == "" {
+				global = strconv.FormatUint(uint64(community.ASN), 10)
+			}
+			if community.SubType == model.ExtendedSubTypeRouteOrigin {
+				routeOrigins = append(routeOrigins, global+":"+strconv.FormatUint(uint64(community.LocalAdmin), 10))
+			} else {
+				routeTargets = append(routeTargets, global+":"+strconv.FormatUint(uint64(community.LocalAdmin), 10))
+			}
+		}
+	}
+	if len(standard) > 0 {
+		clauses = append(clauses, "set community "+strings.Join(standard, " "))
+	}
+	if len(routeTargets) > 0 {
+		clauses = append(clauses, "set extcommunity rt "+strings.Join(routeTargets, " "))
+	}
+	if len(routeOrigins) > 0 {
+		clauses = append(clauses, "set extcommunity soo "+strings.Join(routeOrigins, " "))
+	}
+	if len(large) > 0 {
+		clauses = append(clauses, "set large-community "+strings.Join(large, " "))
+	}
+
+	// FRR sets the link bandwidth in whole Mbit/s
+	if attrs.LinkBandwidth != 0 {
+		mbits := min(max(math.Round(float64(attrs.LinkBandwidth)*8/1e6), 1), maxBandwidthMbits)
+		clauses = append(clauses, "set extcommunity bandwidth "+strconv.Itoa(int(mbits)))
+	}
+	return clauses, nil
+}
+
+// networkPrefix returns the prefix of the network statement of the path in the canonical form shown by FRR.
+func networkPrefix(prefix string, prefixLength uint32) string {
+	addr, err := netip.ParseAddr(prefix)
+	if err != nil {
+		return prefix + "/" + strconv.FormatUint(uint64(prefixLength), 10)
+	}
+	return netip.PrefixFrom(addr.Unmap(), int(prefixLength)).Masked().String()
+}
+
+// routeMapName returns the name of the route map of the network.
+func routeMapName(network string) string {
+	return routeMapPrefix + network
+}
+
+// addressFamily returns the address family of the BGP instance the prefix is originated in.
+func addressFamily(prefix string) string {
+	if model.AddressFamilyOf(prefix) == model.AddressFamilyIPv6Unicast {
+		return "ipv6 unicast"
+	}
+	return "ipv4 unicast"
+}
@@ -0,0 +1,12 @@
+package frr
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// vtyshErrors counts the failed vtysh invocations.
+var vtyshErrors = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "corebgp_frr_vtysh_errors_total",
+	Help: "Number of failed vtysh invocations.",
+})
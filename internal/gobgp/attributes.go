@@ -0,0 +1,265 @@
+package gobgp
+
+import (
+	"fmt"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+const (
+	linkBandwidthUnit = 125000 // linkBandwidthUnit is the bandwidth in bytes per second of a unit of next hop weight, i.e. 1 Mbit/s.
+	asTrans           = 23456  // asTrans is the 2-octet AS number representing a 4-octet AS number (RFC 6793).
+)
+
+// PathAttributes holds the optional BGP path attributes programmed together with a route.
+type PathAttributes struct {
+	Origin        uint32   // Origin is the numeric value of the ORIGIN attribute (0 - IGP, 1 - EGP, 2 - INCOMPLETE).
+	MED           uint32   // MED is the MULTI_EXIT_DISC attribute value; zero means the attribute is not sent.
+	LocalPref     uint32   // LocalPref is the LOCAL_PREF attribute value; zero means the attribute is not sent.
+	ASPathPrepend uint32   // ASPathPrepend is the number of times the local AS is prepended to the AS_PATH.
+	Communities   []string // Communities lists the standard, extended and large communities in the textual form.
+	LinkBandwidth float32  // LinkBandwidth is the bandwidth in bytes per second of the link bandwidth extended community; zero means the community is not sent.
+}
+
+// PathAttributesFromAnnouncement collects the path attributes defined in the announcement.
+func PathAttributesFromAnnouncement(announcement *model.Announcement) PathAttributes {
+	return PathAttributes{
+		Origin:        announcement.Origin.Code(),
+		MED:           announcement.MED,
+		LocalPref:     announcement.LocalPref,
+		ASPathPrepend: uint32(announcement.ASPathPrepend),
+		Communities:   announcement.Communities,
+	}
+}
+
+// ForNextHop returns the path attributes of the path via the next hop, with the communities of the next hop
+// appended to the communities of the announcement.
+func (attrs PathAttributes) ForNextHop(announcement *model.Announcement, nextHop string) PathAttributes {
+	extra := announcement.NextHopCommunities[nextHop]
+	if len(extra) == 0 {
+		return attrs
+	}
+
+	communities := make([]string, 0, len(attrs.Communities)+len(extra))
+	communities = append(communities, attrs.Communities...)
+	attrs.Communities = append(communities, extra...)
+	return attrs
+}
+
+// WeightedMED derives the MED of a weighted next hop path from the base MED of the announcement. Paths via next hops
+// with a higher weight get a lower MED and are therefore preferred.
+func WeightedMED(med, weight uint32) uint32 {
+	if weight > model.MaxTotalNextHopWeight {
+		weight = model.MaxTotalNextHopWeight
+	}
+	return med + model.MaxTotalNextHopWeight - weight
+}
+
+// WeightedLinkBandwidth converts the weight of a next hop into the bandwidth of its link bandwidth extended
+// community, counting every unit of weight as 1 Mbit/s. Routers performing weighted ECMP split the traffic in
+// proportion to the bandwidths of the paths.
+func WeightedLinkBandwidth(weight uint32) float32 {
+	return float32(weight) * linkBandwidthUnit
+}
+
+// marshalPathAttributes converts the next hop and the path attributes into GoBGP path attributes. The local AS number
+// is used for the AS_PATH prepending and the link bandwidth extended community.
+func marshalPathAttributes(family *api.Family, nlri *anypb.Any, nextHop string, attrs PathAttributes, localASN uint32) ([]*anypb.Any, error) {
+	messages := []proto.Message{
+		&api.OriginAttribute{Origin: attrs.Origin},
+		nextHopAttribute(family, nlri, nextHop),
+	}
+	if attrs.MED != 0 {
+		messages = append(messages, &api.MultiExitDiscAttribute{Med: attrs.MED})
+	}
+	if attrs.LocalPref != 0 {
+		messages = append(messages, &api.LocalPrefAttribute{LocalPref: attrs.LocalPref})
+	}
+	if attrs.ASPathPrepend != 0 {
+		messages = append(messages, asPathPrepend(localASN, attrs.ASPathPrepend))
+	}
+
+	communityAttrs, err := communityAttributes(attrs.Communities)
+	if err != nil {
+		return nil, err
+	}
+	if attrs.LinkBandwidth != 0 {
+		if communityAttrs, err = appendLinkBandwidth(communityAttrs, localASN, attrs.LinkBandwidth); err != nil {
+			return nil, err
+		}
+	}
+	messages = append(messages, communityAttrs...)
+
+	pattrs := make([]*anypb.Any, 0, len(messages))
+	for _, message := range messages {
+		attr, err := anypb.New(message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal path attribute: %w", err)
+		}
+		pattrs = append(pattrs, attr)
+	}
+	return pattrs, nil
+}
+
+// pathFamily returns the unicast family of the prefix: IPv6 for IPv6 addresses and IPv4 otherwise.
+func pathFamily(prefix string) *api.Family {
+	if model.AddressFamilyOf(prefix) == model.AddressFamilyIPv6Unicast {
+		return &api.Family{Afi: api.Family_AFI_IP6, Safi: api.Family_SAFI_UNICAST}
+	}
+	return &api.Family{Afi: api.Family_AFI_IP, Safi: api.Family_SAFI_UNICAST}
+}
+
+// nextHopAttribute returns the attribute carrying the next hop of the path. IPv4 routes use the NEXT_HOP attribute,
+// IPv6 routes carry the next hop in MP_REACH_NLRI as defined in RFC 4760.
+func nextHopAttribute(family *api.Family, nlri *anypb.Any, nextHop string) proto.Message {
+	if family.Afi == api.Family_AFI_IP6 {
+		return &api.MpReachNLRIAttribute{
+			Family:   family,
+			NextHops: []string{nextHop},
+			Nlris:    []*anypb.Any{nlri},
+		}
+	}
+	return &api.NextHopAttribute{NextHop: nextHop}
+}
+
+// asPathPrepend builds the AS_PATH attribute with the local AS repeated count times. GoBGP adds the local AS once more
+// when the route is advertised to an eBGP peer.
+func asPathPrepend(localASN, count uint32) *api.AsPathAttribute {
+	numbers := make([]uint32, count)
+	for i := range numbers {
+		numbers[i] = localASN
+	}
+	return &api.AsPathAttribute{
+		Segments: []*api.AsSegment{{Type: api.AsSegment_AS_SEQUENCE, Numbers: numbers}},
+	}
+}
+
+// communityAttributes groups the communities by type and builds the COMMUNITIES, EXTENDED_COMMUNITIES and
+// LARGE_COMMUNITY attributes. Attributes without communities are omitted.
+func communityAttributes(communities []string) ([]proto.Message, error) {
+	var (
+		standard []uint32
+		extended []*anypb.Any
+		large    []*api.LargeCommunity
+	)
+
+	for _, value := range communities {
+		community, err := model.ParseCommunity(value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch community.Type {
+		case model.CommunityStandard:
+			standard = append(standard, community.Value)
+		case model.CommunityLarge:
+			large = append(large, &api.LargeCommunity{
+				GlobalAdmin: community.GlobalAdmin,
+				LocalData1:  community.LocalData1,
+				LocalData2:  community.LocalData2,
+			})
+		case model.CommunityExtended:
+			ext, err := extendedCommunity(community)
+			if err != nil {
+				return nil, err
+			}
+			extended = append(extended, ext)
+		}
+	}
+
+	var attrs []proto.Message
+	if len(standard) > 0 {
+		attrs = append(attrs, &api.CommunitiesAttribute{Communities: standard})
+	}
+	if len(extended) > 0 {
+		attrs = append(attrs, &api.ExtendedCommunitiesAttribute{Communities: extended})
+	}
+	if len(large) > 0 {
+		attrs = append(attrs, &api.LargeCommunitiesAttribute{Communities: large})
+	}
+	return attrs, nil
+}
+
+// appendLinkBandwidth adds the link bandwidth extended community to the EXTENDED_COMMUNITIES attribute of the
+// community attributes, creating the attribute if there is none. The community carries a 2-octet AS number, so
+// AS_TRANS replaces a 4-octet local AS number.
+func appendLinkBandwidth(attrs []proto.Message, localASN uint32, bandwidth float32) ([]proto.Message, error) {
+	if localASN > 0xFFFF {
+		localASN = asTrans
+	}
+	ext, err := anypb.New(&api.LinkBandwidthExtended{Asn: localASN, Bandwidth: bandwidth})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal link bandwidth extended community: %w", err)
+	}
+
+	for _, attr := range attrs {
+		if extended, ok := attr.(*api.ExtendedCommunitiesAttribute); ok {
+			extended.Communities = append(extended.Communities, ext)
+			return attrs, nil
+		}
+	}
+	return append(attrs, &api.ExtendedCommunitiesAttribute{Communities: []*anypb.Any{ext}}), nil
+}
+
+// extendedCommunity marshals the extended community into the matching GoBGP message.
+func extendedCommunity(community model.Community) (*anypb.Any, error) {
+	var message proto.Message
+	switch {
+	case community.IPv4 != "":
+		message = &api.IPv4AddressSpecificExtended{
+			IsTransitive: true,
+			SubType:      community.SubType,
+			Address:      community.IPv4,
+			LocalAdmin:   community.LocalAdmin,
+		}
+	case community.ASN > 0xFFFF:
+		message = &api.FourOctetAsSpecificExtended{
+			IsTransitive: true,
+			SubType:      community.SubType,
+			Asn:          community.ASN,
+			LocalAdmin:   community.LocalAdmin,
+		}
+	default:
+		message = &api.TwoOctetAsSpecificExtended{
+			IsTransitive: true,
+			SubType:      community.SubType,
+			Asn:          community.ASN,
+			LocalAdmin:   community.LocalAdmin,
+		}
+	}
+
+	ext, err := anypb.New(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal extended community: %w", err)
+	}
+	return ext, nil
+}
+
+// parseExtendedCommunity converts the GoBGP extended community into a route target or route origin community. It
+// returns false for the other kinds of extended communities.
+func parseExtendedCommunity(ext *anypb.Any) (model.Community, bool) {
+	message, err := ext.UnmarshalNew()
+	if err != nil {
+		return model.Community{}, false
+	}
+
+	community := model.Community{Type: model.CommunityExtended}
+	switch e := message.(type) {
+	case *api.TwoOctetAsSpecificExtended:
+		community.SubType, community.ASN, community.LocalAdmin = e.SubType, e.Asn, e.LocalAdmin
+	case *api.FourOctetAsSpecificExtended:
+		community.SubType, community.ASN, community.LocalAdmin = e.SubType, e.Asn, e.LocalAdmin
+	case *api.IPv4AddressSpecificExtended:
+		community.SubType, community.IPv4, community.LocalAdmin = e.SubType, e.Address, e.LocalAdmin
+	default:
+		return model.Community{}, false
+	}
+	if community.SubType != model.ExtendedSubTypeRouteTarget && community.SubType != model.ExtendedSubTypeRouteOrigin {
+		return model.Community{}, false
+	}
+	return community, true
+}
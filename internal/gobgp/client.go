@@ -0,0 +1,611 @@
+package gobgp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/types/known/anypb"
+	"hash/fnv"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
+
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+// connectionDrainTimeout is the time after which a replaced gRPC connection is closed, allowing in-flight calls to finish.
+const connectionDrainTimeout = 15 * time.Second
+
+// reconnectBackoff is the backoff of the attempts to re-establish a lost connection to the GoBGP server.
+var reconnectBackoff = backoff.Config{
+	BaseDelay:  time.Second,
+	Multiplier: 1.6,
+	Jitter:     0.2,
+	MaxDelay:   30 * time.Second,
+}
+
+// Client is struct for manage GoBGP client
+type Client struct {
+	mu     sync.RWMutex
+	client api.GobgpApiClient
+	conn   *grpc.ClientConn
+}
+
+// NewClient initializes the new GoBGP client
+func NewClient(endpoint, caFile, certFile, keyFile *string) (*Client, error) {
+	conn, err := dialGoBGP(*endpoint, *caFile, *certFile, *keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		client: api.NewGobgpApiClient(conn),
+		conn:   conn,
+	}, nil
+}
+
+// dialGoBGP reads the TLS certificates and opens a new gRPC connection to the GoBGP server.
+func dialGoBGP(endpoint, caFile, certFile, keyFile string) (*grpc.ClientConn, error) {
+	caCert, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA certificate: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to append CA certificate")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load client certificate and key: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      caPool,
+	}
+
+	creds := credentials.NewTLS(tlsConfig)
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(unaryErrorInterceptor, tracing.UnaryClientInterceptor("gobgp")),
+		grpc.WithChainStreamInterceptor(streamErrorInterceptor, tracing.StreamClientInterceptor("gobgp")),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: reconnectBackoff, MinConnectTimeout: 5 * time.Second}),
+		// Keep the connection up between the calls, so that a lost connection is noticed and re-established at once
+		grpc.WithIdleTimeout(0),
+	}
+
+	conn, err := grpc.Dial(endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to GoBGP server: %w", err)
+	}
+
+	return conn, nil
+}
+
+// Reload re-reads the certificates from the paths in the router config and atomically replaces the gRPC connection.
+// The previous connection is closed after a drain timeout so that in-flight calls can complete.
+func (g *Client) Reload(config model.GoBGPRouter) error {
+	conn, err := dialGoBGP(config.Endpoint, config.CACert, config.ClientCert, config.ClientKey)
+	if err != nil {
+		return fmt.Errorf("failed to reload GoBGP client: %w", err)
+	}
+
+	g.mu.Lock()
+	oldConn := g.conn
+	g.conn = conn
+	g.client = api.NewGobgpApiClient(conn)
+	g.mu.Unlock()
+
+	time.AfterFunc(connectionDrainTimeout, func() {
+		_ = oldConn.Close()
+	})
+
+	return nil
+}
+
+// Close closes GoBGP API server connection
+func (g *Client) Close() {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_ = g.conn.Close()
+}
+
+// MonitorConnection keeps the connection to the GoBGP server up until the context is canceled and calls onChange
+// when it is lost and when it is established again. The connection is re-established with an exponential backoff.
+// A connection replaced by Reload is not reported as lost.
+func (g *Client) MonitorConnection(ctx context.Context, onChange func(connected bool)) {
+	connected := true
+	var current *grpc.ClientConn
+	var wasReady bool
+	for {
+		g.mu.RLock()
+		conn := g.conn
+		g.mu.RUnlock()
+		if conn != current {
+			current, wasReady = conn, false
+		}
+
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			wasReady = true
+			if !connected {
+				connected = true
+				onChange(true)
+			}
+		case connectivity.Idle, connectivity.TransientFailure:
+			// The connection is re-established only on the next call otherwise
+			conn.Connect()
+			if connected && (wasReady || state == connectivity.TransientFailure) {
+				connected = false
+				onChange(false)
+			}
+		}
+
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+// api returns the gRPC client bound to the current connection.
+func (g *Client) api() api.GobgpApiClient {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.client
+}
+
+// GetBGP retrieves the current BGP configuration from the GoBGP server and returns it as a string.
+func (g *Client) GetBGP() (string, error) {
+	// Create a request to retrieve the current BGP configuration
+	bgpConfig, err := g.api().GetBgp(context.Background(), &api.GetBgpRequest{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get BGP config: %w", err)
+	}
+
+	// Convert the BGP configuration to a string and return it
+	return bgpConfig.String(), nil
+}
+
+// GetGlobal retrieves the global BGP configuration (AS number, router ID, listen port) from the GoBGP server.
+func (g *Client) GetGlobal() (*api.Global, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := g.api().GetBgp(ctx, &api.GetBgpRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get BGP config: %w", err)
+	}
+
+	return resp.Global, nil
+}
+
+// StartBGP starts the BGP server of GoBGP with the specified AS number, router ID and listen port.
+func (g *Client) StartBGP(config model.GoBGPGlobalConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := g.api().StartBgp(ctx, &api.StartBgpRequest{
+		Global: &api.Global{
+			Asn:        config.ASN,
+			RouterId:   config.RouterID,
+			ListenPort: config.ListenPort,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start BGP: %w", err)
+	}
+
+	return nil
+}
+
+// ListPeers retrieves all configured BGP peers together with their session state from the GoBGP server.
+func (g *Client) ListPeers() ([]*api.Peer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := g.api().ListPeer(ctx, &api.ListPeerRequest{EnableAdvertised: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers from GoBGP: %w", err)
+	}
+
+	var peers []*api.Peer
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("error while receiving peer from stream: %w", err)
+		}
+		peers = append(peers, resp.Peer)
+	}
+
+	return peers, nil
+}
+
+// AddPath adds a specified BGP route (prefix) with associated attributes to the GoBGP server.
+func (g *Client) AddPath(prefix string, prefixLength uint32, nextHop string, attrs PathAttributes) error {
+	// Generate the context for the gRPC call
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Marshal the NLRI (route information) into *anypb.Any
+	nlri, err := anypb.New(&api.IPAddressPrefix{
+		Prefix:    prefix,
+		PrefixLen: prefixLength,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal NLRI: %w", err)
+	}
+
+	// The local AS number is needed only to prepend it to the AS_PATH and for the link bandwidth community
+	var localASN uint32
+	if attrs.ASPathPrepend != 0 || attrs.LinkBandwidth != 0 {
+		global, err := g.GetGlobal()
+		if err != nil {
+			return err
+		}
+		localASN = global.Asn
+	}
+
+	// Marshal the path attributes (origin, next hop, MED, local preference, AS_PATH and communities)
+	family := pathFamily(prefix)
+	pattrs, err := marshalPathAttributes(family, nlri, nextHop, attrs, localASN)
+	if err != nil {
+		return err
+	}
+
+	// Construct the Path object, identified by its next hop so that the paths via other next hops are kept
+	path := &api.Path{
+		Family:     family,
+		Nlri:       nlri,
+		Pattrs:     pattrs,
+		Identifier: pathIdentifier(nextHop),
+	}
+
+	// Add the route to the GoBGP server
+	_, err = g.api().AddPath(ctx, &api.AddPathRequest{
+		Path: path,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add path to GoBGP: %w", err)
+	}
+
+	return nil
+}
+
+// ListPath retrieves a list of BGP paths for the specified prefix from the GoBGP server. Returns a slice of paths or an error.
+func (g *Client) ListPath(prefix string) ([]string, error) {
+	// Create context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Call ListPath API with a prefix filter
+	address, _, _ := strings.Cut(prefix, "/")
+	stream, err := g.api().ListPath(ctx, &api.ListPathRequest{
+		Family: pathFamily(address),
+		Prefixes: []*api.TableLookupPrefix{
+			{
+				Prefix: prefix,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paths from GoBGP: %w", err)
+	}
+
+	// Collect paths from the stream
+	var paths []string
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if err.Error() == "EOF" {
+				break
+			}
+			return nil, fmt.Errorf("error while receiving path from stream: %w", err)
+		}
+		paths = append(paths, resp.String())
+	}
+
+	return paths, nil
+}
+
+// LocalPath is a path originated by this GoBGP server, as opposed to the paths received from peers.
+type LocalPath struct {
+	Prefix       string            // Prefix is the address of the announced prefix.
+	PrefixLength uint32            // PrefixLength is the length of the announced prefix.
+	NextHop      string            // NextHop is the next-hop address of the path.
+	Identifier   uint32            // Identifier distinguishes the paths of the prefix via different next hops.
+	Attributes   RIBPathAttributes // Attributes holds the other path attributes of the path.
+	Age          time.Time         // Age is the time the path was added to the RIB.
+}
+
+// RIBPathAttributes holds the path attributes of a path read from the RIB.
+type RIBPathAttributes struct {
+	Origin        uint32   // Origin is the numeric value of the ORIGIN attribute.
+	MED           uint32   // MED is the MULTI_EXIT_DISC attribute value; zero if the attribute is not set.
+	LocalPref     uint32   // LocalPref is the LOCAL_PREF attribute value; zero if the attribute is not set.
+	ASPath        []uint32 // ASPath lists the AS numbers of the AS_PATH attribute.
+	Communities   []string // Communities lists the standard, extended and large communities in the textual form.
+	LinkBandwidth float32  // LinkBandwidth is the bandwidth in bytes per second of the link bandwidth extended community; zero if it is not set.
+}
+
+// Matches reports whether the attributes read from the RIB are the programmed attributes. The AS_PATH is compared by
+// the number of prepended AS numbers, and the communities in their canonical textual form regardless of their order.
+func (a RIBPathAttributes) Matches(attrs PathAttributes) bool {
+	if a.Origin != attrs.Origin || a.MED != attrs.MED || a.LocalPref != attrs.LocalPref || a.LinkBandwidth != attrs.LinkBandwidth ||
+		len(a.ASPath) != int(attrs.ASPathPrepend) || len(a.Communities) != len(attrs.Communities) {
+		return false
+	}
+
+	programmed := make([]string, 0, len(attrs.Communities))
+	for _, value := range attrs.Communities {
+		if community, err := model.ParseCommunity(value); err == nil {
+			value = community.String()
+		}
+		programmed = append(programmed, value)
+	}
+	slices.Sort(programmed)
+	return slices.Equal(slices.Sorted(slices.Values(a.Communities)), programmed)
+}
+
+// ListLocalPaths retrieves the paths of the address family ("ipv4-unicast" or "ipv6-unicast") that were added
+// through the API of the GoBGP server. Paths received from peers are skipped.
+func (g *Client) ListLocalPaths(addressFamily string) ([]LocalPath, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	family := &api.Family{Afi: api.Family_AFI_IP, Safi: api.Family_SAFI_UNICAST}
+	if addressFamily == model.AddressFamilyIPv6Unicast {
+		family.Afi = api.Family_AFI_IP6
+	}
+
+	stream, err := g.api().ListPath(ctx, &api.ListPathRequest{
+		TableType: api.TableType_GLOBAL,
+		Family:    family,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list paths from GoBGP: %w", err)
+	}
+
+	var paths []LocalPath
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("error while receiving path from stream: %w", err)
+		}
+
+		for _, path := range resp.Destination.Paths {
+			// Locally originated paths have no neighbor
+			if ip := net.ParseIP(path.NeighborIp); ip != nil && !ip.IsUnspecified() {
+				continue
+			}
+			localPath, err := parseLocalPath(path)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, localPath)
+		}
+	}
+
+	return paths, nil
+}
+
+// parseLocalPath extracts the prefix and the next hop of the GoBGP path.
+func parseLocalPath(path *api.Path) (LocalPath, error) {
+	var prefix api.IPAddressPrefix
+	if err := path.Nlri.UnmarshalTo(&prefix); err != nil {
+		return LocalPath{}, fmt.Errorf("failed to unmarshal NLRI: %w", err)
+	}
+	localPath := LocalPath{Prefix: prefix.Prefix, PrefixLength: prefix.PrefixLen, Identifier: path.Identifier}
+	if path.Age != nil {
+		localPath.Age = path.Age.AsTime()
+	}
+
+	for _, attr := range path.Pattrs {
+		message, err := attr.UnmarshalNew()
+		if err != nil {
+			return LocalPath{}, fmt.Errorf("failed to unmarshal path attribute: %w", err)
+		}
+		switch a := message.(type) {
+		case *api.NextHopAttribute:
+			localPath.NextHop = a.NextHop
+		case *api.MpReachNLRIAttribute:
+			if len(a.NextHops) > 0 {
+				localPath.NextHop = a.NextHops[0]
+			}
+		case *api.OriginAttribute:
+			localPath.Attributes.Origin = a.Origin
+		case *api.MultiExitDiscAttribute:
+			localPath.Attributes.MED = a.Med
+		case *api.LocalPrefAttribute:
+			localPath.Attributes.LocalPref = a.LocalPref
+		case *api.AsPathAttribute:
+			for _, segment := range a.Segments {
+				localPath.Attributes.ASPath = append(localPath.Attributes.ASPath, segment.Numbers...)
+			}
+		case *api.CommunitiesAttribute:
+			for _, value := range a.Communities {
+				localPath.Attributes.Communities = append(localPath.Attributes.Communities, model.Community{Type: model.CommunityStandard, Value: value}.String())
+			}
+		case *api.ExtendedCommunitiesAttribute:
+			for _, ext := range a.Communities {
+				if linkBandwidth := new(api.LinkBandwidthExtended); ext.UnmarshalTo(linkBandwidth) == nil {
+					localPath.Attributes.LinkBandwidth = linkBandwidth.Bandwidth
+					continue
+				}
+				if community, ok := parseExtendedCommunity(ext); ok {
+					localPath.Attributes.Communities = append(localPath.Attributes.Communities, community.String())
+				}
+			}
+		case *api.LargeCommunitiesAttribute:
+			for _, large := range a.Communities {
+				localPath.Attributes.Communities = append(localPath.Attributes.Communities, model.Community{
+					Type:        model.CommunityLarge,
+					GlobalAdmin: large.GlobalAdmin,
+					LocalData1:  large.LocalData1,
+					LocalData2:  large.LocalData2,
+				}.String())
+			}
+		}
+	}
+	return localPath, nil
+}
+
+// DeletePath removes a specified BGP route (prefix) from GoBGP
+func (g *Client) DeletePath(prefix string, prefixLength uint32, nextHop string) error {
+	return g.deletePath(prefix, prefixLength, nextHop, pathIdentifier(nextHop))
+}
+
+// DeleteLocalPath removes the path listed by ListLocalPaths, including a path added without an identifier.
+func (g *Client) DeleteLocalPath(path LocalPath) error {
+	return g.deletePath(path.Prefix, path.PrefixLength, path.NextHop, path.Identifier)
+}
+
+// pathIdentifier derives the path identifier (RFC 7911) of a path from its next hop. GoBGP keeps one local path per
+// prefix and identifier, so the paths via the weighted next hops need distinct identifiers; peers with ADD-PATH
+// receive all of them.
+func pathIdentifier(nextHop string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(nextHop))
+	if id := h.Sum32(); id != 0 {
+		return id
+	}
+	return 1
+}
+
+// deletePath removes the path of the prefix via the next hop with the identifier.
+func (g *Client) deletePath(prefix string, prefixLength uint32, nextHop string, identifier uint32) error {
+	// Create context with timeout for gRPC call
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Marshal the NLRI (route information) into *anypb.Any
+	nlri, err := anypb.New(&api.IPAddressPrefix{
+		Prefix:    prefix,
+		PrefixLen: prefixLength,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal NLRI for deletion: %w", err)
+	}
+
+	// Marshal the NextHop attribute into *anypb.Any (if required)
+	family := pathFamily(prefix)
+	nextHopAttr, err := anypb.New(nextHopAttribute(family, nlri, nextHop))
+	if err != nil {
+		return fmt.Errorf("failed to marshal next-hop attribute for deletion: %w", err)
+	}
+
+	// Construct the Path object with the NLRI, NextHop and identifier
+	path := &api.Path{
+		Family: family,
+		Nlri:   nlri,
+		Pattrs: []*anypb.Any{
+			nextHopAttr,
+		},
+		Identifier: identifier,
+	}
+
+	// Call DeletePath API with the constructed path
+	_, err = g.api().DeletePath(ctx, &api.DeletePathRequest{
+		Path: path,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete path from GoBGP: %w", err)
+	}
+
+	return nil
+}
+
+// AddPeer configures a new BGP neighbor on the GoBGP server.
+func (g *Client) AddPeer(peer model.Peer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := g.api().AddPeer(ctx, &api.AddPeerRequest{Peer: peerConfig(peer)}); err != nil {
+		return fmt.Errorf("failed to add peer %s to GoBGP: %w", peer.Address, err)
+	}
+	return nil
+}
+
+// UpdatePeer replaces the configuration of an existing BGP neighbor on the GoBGP server. Changes of the ASN, the
+// password or the address families reset the session.
+func (g *Client) UpdatePeer(peer model.Peer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := g.api().UpdatePeer(ctx, &api.UpdatePeerRequest{Peer: peerConfig(peer)}); err != nil {
+		return fmt.Errorf("failed to update peer %s in GoBGP: %w", peer.Address, err)
+	}
+	return nil
+}
+
+// DeletePeer removes the BGP neighbor with the address from the GoBGP server, closing its session.
+func (g *Client) DeletePeer(address string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := g.api().DeletePeer(ctx, &api.DeletePeerRequest{Address: address}); err != nil {
+		return fmt.Errorf("failed to delete peer %s from GoBGP: %w", address, err)
+	}
+	return nil
+}
+
+// peerConfig converts the peer resource to the GoBGP neighbor configuration.
+func peerConfig(peer model.Peer) *api.Peer {
+	config := &api.Peer{
+		Conf: &api.PeerConf{
+			NeighborAddress: peer.Address,
+			PeerAsn:         peer.ASN,
+			Description:     peer.Description,
+			AuthPassword:    peer.AuthPassword,
+		},
+		Timers: &api.Timers{
+			Config: &api.TimersConfig{
+				HoldTime:          peer.Timers.HoldTime,
+				KeepaliveInterval: peer.Timers.KeepaliveInterval,
+				ConnectRetry:      peer.Timers.ConnectRetry,
+			},
+		},
+	}
+	for _, addressFamily := range PeerAddressFamilies(peer) {
+		family := &api.Family{Afi: api.Family_AFI_IP, Safi: api.Family_SAFI_UNICAST}
+		if addressFamily == model.AddressFamilyIPv6Unicast {
+			family.Afi = api.Family_AFI_IP6
+		}
+		afiSafi := &api.AfiSafi{
+			Config: &api.AfiSafiConfig{Family: family, Enabled: true},
+		}
+		if peer.AddPathSendMax > 0 {
+			afiSafi.AddPaths = &api.AddPaths{Config: &api.AddPathsConfig{SendMax: peer.AddPathSendMax}}
+		}
+		config.AfiSafis = append(config.AfiSafis, afiSafi)
+	}
+	return config
+}
+
+// PeerAddressFamilies returns the address families of the peer, defaulting to the family of its address.
+func PeerAddressFamilies(peer model.Peer) []string {
+	if len(peer.AddressFamilies) > 0 {
+		return peer.AddressFamilies
+	}
+	return []string{model.AddressFamilyOf(peer.Address)}
+}
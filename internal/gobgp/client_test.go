@@ -0,0 +1,105 @@
+package gobgp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	api "github.com/osrg/gobgp/v3/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeRIB is a GoBGP API server keeping the added paths in the global RIB the way GoBGP returns them from ListPath.
+type fakeRIB struct {
+	api.UnimplementedGobgpApiServer
+
+	mu    sync.Mutex
+	paths []*api.Path
+}
+
+func (r *fakeRIB) AddPath(_ context.Context, req *api.AddPathRequest) (*api.AddPathResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths = append(r.paths, req.Path)
+	return &api.AddPathResponse{}, nil
+}
+
+func (r *fakeRIB) ListPath(req *api.ListPathRequest, stream api.GobgpApi_ListPathServer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, path := range r.paths {
+		if path.Family.Afi != req.Family.Afi || path.Family.Safi != req.Family.Safi {
+			continue
+		}
+		var prefix api.IPAddressPrefix
+		if err := path.Nlri.UnmarshalTo(&prefix); err != nil {
+			return err
+		}
+		if err := stream.Send(&api.ListPathResponse{Destination: &api.Destination{Prefix: prefix.Prefix, Paths: []*api.Path{path}}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newFakeRIBClient serves a fakeRIB on the loopback interface and returns a client connected to it.
+func newFakeRIBClient(t *testing.T) *Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	api.RegisterGobgpApiServer(server, &fakeRIB{})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect to the fake GoBGP server: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return &Client{client: api.NewGobgpApiClient(conn), conn: conn}
+}
+
+// TestAddPathAttributes checks that the MED, the LOCAL_PREF and the communities of all types programmed with a path
+// are read back from the RIB intact.
+func TestAddPathAttributes(t *testing.T) {
+	client := newFakeRIBClient(t)
+	announcement := &model.Announcement{
+		Origin:      model.OriginIncomplete,
+		MED:         150,
+		LocalPref:   200,
+		Communities: []string{"65000:100", "no-export", "rt:65000:42", "soo:192.0.2.1:7", "65000:1:2"},
+	}
+	attrs := PathAttributesFromAnnouncement(announcement)
+
+	if err := client.AddPath("192.0.2.10", 32, "10.0.0.1", attrs); err != nil {
+		t.Fatalf("AddPath: %v", err)
+	}
+
+	paths, err := client.ListLocalPaths(model.AddressFamilyIPv4Unicast)
+	if err != nil {
+		t.Fatalf("ListLocalPaths: %v", err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("RIB holds %d paths, want 1", len(paths))
+	}
+	path := paths[0]
+	if path.Prefix != "192.0.2.10" || path.PrefixLength != 32 || path.NextHop != "10.0.0.1" {
+		t.Fatalf("unexpected path %s/%d via %s", path.Prefix, path.PrefixLength, path.NextHop)
+	}
+	if path.Attributes.MED != 150 || path.Attributes.LocalPref != 200 {
+		t.Errorf("MED = %d, LOCAL_PREF = %d, want 150 and 200", path.Attributes.MED, path.Attributes.LocalPref)
+	}
+	if !path.Attributes.Matches(attrs) {
+		t.Errorf("attributes read from the RIB %+v do not match the programmed %+v", path.Attributes, attrs)
+	}
+}
@@ -0,0 +1,34 @@
+package gobgp
+
+import (
+	"context"
+	"path"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+)
+
+// rpcErrors counts the failed GoBGP gRPC calls per method.
+var rpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "corebgp_gobgp_rpc_errors_total",
+	Help: "Number of failed GoBGP gRPC calls.",
+}, []string{"method"})
+
+// unaryErrorInterceptor counts the errors of unary GoBGP calls.
+func unaryErrorInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if err != nil {
+		rpcErrors.WithLabelValues(path.Base(method)).Inc()
+	}
+	return err
+}
+
+// streamErrorInterceptor counts the GoBGP streaming calls that could not be started.
+func streamErrorInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		rpcErrors.WithLabelValues(path.Base(method)).Inc()
+	}
+	return stream, err
+}
@@ -0,0 +1,86 @@
+package healthcheck
+
+import (
+	"math"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// withdrawPenalty is the penalty added by every transition to unhealthy, as for a withdrawn route in RFC 2439.
+const withdrawPenalty = 1000
+
+// damper dampens the health changes of a next hop, so that a flapping health check does not flap its routes. The
+// transitions to unhealthy are always applied at once, while the transitions back to healthy are held back during
+// the hold-down after a withdrawal, while the transitions of the last minute exceed the limit and while the
+// decaying penalty of the withdrawals keeps the next hop suppressed.
+type damper struct {
+	config      model.Dampening
+	penalty     float64     // penalty is the accumulated penalty at the time of updated.
+	updated     time.Time   // updated is the time the penalty was last decayed.
+	suppressed  bool        // suppressed reports whether the penalty exceeded the suppress threshold and has not decayed below the reuse threshold yet.
+	withdrawn   time.Time   // withdrawn is the time of the last transition to unhealthy.
+	transitions []time.Time // transitions holds the times of the applied transitions within the last minute.
+}
+
+// withdraw records a transition to unhealthy and penalizes it.
+func (d *damper) withdraw(now time.Time) {
+	d.decay(now)
+	if d.config.HalfLife > 0 {
+		d.penalty = min(d.penalty+withdrawPenalty, d.maxPenalty())
+		if d.penalty > d.config.SuppressThreshold {
+			d.suppressed = true
+		}
+	}
+	d.withdrawn = now
+	d.record(now)
+}
+
+// announce reports whether a transition to healthy may be applied now and records it if so.
+func (d *damper) announce(now time.Time) bool {
+	d.decay(now)
+	if d.suppressed && d.penalty < d.config.ReuseThreshold {
+		d.suppressed = false
+	}
+	if d.suppressed || now.Sub(d.withdrawn) < d.config.HoldDown {
+		return false
+	}
+	if d.config.MaxOperations > 0 && len(d.recent(now)) >= d.config.MaxOperations {
+		return false
+	}
+	d.record(now)
+	return true
+}
+
+// decay reduces the penalty exponentially with the half-life since the last update.
+func (d *damper) decay(now time.Time) {
+	if d.config.HalfLife <= 0 {
+		d.penalty = 0
+	} else if !d.updated.IsZero() {
+		d.penalty *= math.Exp2(-now.Sub(d.updated).Seconds() / d.config.HalfLife.Seconds())
+	}
+	d.updated = now
+}
+
+// maxPenalty returns the penalty that decays to the reuse threshold within the maximum suppression time.
+func (d *damper) maxPenalty() float64 {
+	if d.config.MaxSuppressTime <= 0 {
+		return math.Inf(1)
+	}
+	return d.config.ReuseThreshold * math.Exp2(d.config.MaxSuppressTime.Seconds()/d.config.HalfLife.Seconds())
+}
+
+// record adds the transition to the transitions of the last minute.
+func (d *damper) record(now time.Time) {
+	d.transitions = append(d.recent(now), now)
+}
+
+// recent drops the transitions older than a minute and returns the others.
+func (d *damper) recent(now time.Time) []time.Time {
+	i := 0
+	for i < len(d.transitions) && now.Sub(d.transitions[i]) >= time.Minute {
+		i++
+	}
+	d.transitions = d.transitions[i:]
+	return d.transitions
+}
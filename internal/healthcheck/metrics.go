@@ -0,0 +1,20 @@
+package healthcheck
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// probes counts the health check probes per type and result, so that the success ratio can be derived.
+	probes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "corebgp_updater_health_checks_total",
+		Help: "Number of health check probes by type and result.",
+	}, []string{"type", "result"})
+
+	// dampenedTransitions counts the recoveries of next hops held back by the dampening.
+	dampenedTransitions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "corebgp_updater_dampened_health_transitions_total",
+		Help: "Number of next hop recoveries held back by the route flap dampening.",
+	})
+)
@@ -0,0 +1,378 @@
+// Package healthcheck probes the next hops of announcements and reports changes of their health state, so that
+// the routes via failed next hops can be withdrawn.
+package healthcheck
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults are the health check parameters used when neither the announcement nor the updater sets them.
+var Defaults = model.HealthCheckDefaults{
+	Interval: 5 * time.Second,
+	Timeout:  2 * time.Second,
+	Rise:     2,
+	Fall:     3,
+}
+
+// ChangeFunc is called when a next hop of an announcement becomes healthy or unhealthy.
+type ChangeFunc func(announcement model.Announcement, nextHop string, healthy bool)
+
+// Monitor runs the health checks of the next hops of the tracked announcements. Next hops start as healthy and
+// change their state after Fall consecutive failed or Rise consecutive successful probes.
+type Monitor struct {
+	mu        sync.Mutex
+	clk       clock.Clock
+	dampening model.Dampening           // dampening holds back the recoveries of the flapping next hops.
+	defaults  model.HealthCheckDefaults // defaults holds the parameters of the health checks not set in the announcements.
+	onChange  ChangeFunc
+	tracked   map[string]*trackedAnnouncement // tracked maps the "project/name" IDs to the checked announcements.
+}
+
+// trackedAnnouncement is an announcement together with the checkers of its next hops.
+type trackedAnnouncement struct {
+	announcement model.Announcement
+	checkers     map[string]*checker // checkers maps the next-hop addresses to their checkers.
+}
+
+// checker is the health state of a single next hop.
+type checker struct {
+	check    model.HealthCheck
+	cancel   context.CancelFunc
+	healthy  bool
+	damper   damper
+	dampened bool // dampened reports whether the next hop passes its health check but its recovery is held back.
+
+	lastProbe time.Time     // lastProbe is the start time of the last probe.
+	latency   time.Duration // latency is the duration of the last probe.
+	failures  int           // failures is the number of failed probes since the last successful one.
+	lastError string        // lastError describes the failure of the last probe.
+}
+
+// NewMonitor creates a monitor that calls onChange on every change of the health state of a next hop. The
+// recoveries of next hops are dampened with the dampening parameters; their zero values disable the dampening.
+func NewMonitor(clk clock.Clock, dampening model.Dampening, onChange ChangeFunc) *Monitor {
+	return &Monitor{
+		clk:       clk,
+		dampening: dampening,
+		defaults:  Defaults,
+		onChange:  onChange,
+		tracked:   make(map[string]*trackedAnnouncement),
+	}
+}
+
+// SetDefaults changes the parameters of the health checks that the announcements do not set. The running checks
+// apply them from their next probe; non-positive values keep Defaults.
+func (m *Monitor) SetDefaults(defaults model.HealthCheckDefaults) {
+	if defaults.Interval <= 0 {
+		defaults.Interval = Defaults.Interval
+	}
+	if defaults.Timeout <= 0 {
+		defaults.Timeout = Defaults.Timeout
+	}
+	if defaults.Rise <= 0 {
+		defaults.Rise = Defaults.Rise
+	}
+	if defaults.Fall <= 0 {
+		defaults.Fall = Defaults.Fall
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaults = defaults
+}
+
+// parameters returns the interval, the timeout, the rise and the fall of the health check, taking the values it
+// does not set from the defaults of the monitor.
+func (m *Monitor) parameters(check model.HealthCheck) (interval, timeout time.Duration, rise, fall int) {
+	m.mu.Lock()
+	interval, timeout, rise, fall = m.defaults.Interval, m.defaults.Timeout, m.defaults.Rise, m.defaults.Fall
+	m.mu.Unlock()
+
+	if check.CheckInterval > 0 {
+		interval = time.Duration(check.CheckInterval) * time.Second
+	}
+	if check.Timeout > 0 {
+		timeout = time.Duration(check.Timeout) * time.Second
+	}
+	if check.Rise > 0 {
+		rise = check.Rise
+	}
+	if check.Fall > 0 {
+		fall = check.Fall
+	}
+	return interval, timeout, rise, fall
+}
+
+// announcementID returns the "project/name" ID of the announcement.
+func announcementID(announcement *model.Announcement) string {
+	return announcement.Meta.Project + "/" + announcement.Meta.Name
+}
+
+// Track starts or updates the health checks of the next hops of the announcement. The state of next hops whose
+// health check is unchanged is kept. Announcements without a configured health check are not tracked.
+func (m *Monitor) Track(announcement *model.Announcement) {
+	if !announcement.HealthCheck.Enabled() {
+		m.Untrack(announcement)
+		return
+	}
+
+	nextHops := make(map[string]struct{})
+	for _, nextHop := range announcement.NextHops {
+		nextHops[nextHop.IP] = struct{}{}
+	}
+	for _, nextHop := range announcement.WeightedNextHops {
+		nextHops[nextHop.Address] = struct{}{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := announcementID(announcement)
+	tracked, ok := m.tracked[id]
+	if !ok {
+		tracked = &trackedAnnouncement{checkers: make(map[string]*checker)}
+		m.tracked[id] = tracked
+	}
+	tracked.announcement = *announcement
+
+	for nextHop, c := range tracked.checkers {
+		if _, ok := nextHops[nextHop]; !ok || c.check != announcement.HealthCheck {
+			c.cancel()
+			delete(tracked.checkers, nextHop)
+		}
+	}
+
+	for nextHop := range nextHops {
+		if _, ok := tracked.checkers[nextHop]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		c := &checker{check: announcement.HealthCheck, cancel: cancel, healthy: true, damper: damper{config: m.dampening}}
+		tracked.checkers[nextHop] = c
+		go m.run(ctx, id, nextHop, c)
+	}
+}
+
+// Untrack stops the health checks of the announcement.
+func (m *Monitor) Untrack(announcement *model.Announcement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := announcementID(announcement)
+	if tracked, ok := m.tracked[id]; ok {
+		for _, c := range tracked.checkers {
+			c.cancel()
+		}
+		delete(m.tracked, id)
+	}
+}
+
+// Healthy reports whether the next hop of the announcement is healthy. Next hops that are not checked are healthy.
+func (m *Monitor) Healthy(announcement *model.Announcement, nextHop string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tracked, ok := m.tracked[announcementID(announcement)]
+	if !ok {
+		return true
+	}
+	c, ok := tracked.checkers[nextHop]
+	return !ok || c.healthy
+}
+
+// Details returns the latest probe results of the checked next hops of the announcement in the order of its next
+// hops. It returns nil when the announcement is not tracked.
+func (m *Monitor) Details(announcement *model.Announcement) []model.Details {
+	var nextHops []string
+	for _, nextHop := range announcement.NextHops {
+		nextHops = append(nextHops, nextHop.IP)
+	}
+	for _, nextHop := range announcement.WeightedNextHops {
+		nextHops = append(nextHops, nextHop.Address)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tracked, ok := m.tracked[announcementID(announcement)]
+	if !ok {
+		return nil
+	}
+	details := make([]model.Details, 0, len(tracked.checkers))
+	seen := make(map[string]bool)
+	for _, nextHop := range nextHops {
+		c, ok := tracked.checkers[nextHop]
+		if !ok || seen[nextHop] {
+			continue
+		}
+		seen[nextHop] = true
+
+		result := model.Details{
+			Host:                nextHop,
+			Status:              model.HealthHealthy,
+			Message:             c.lastError,
+			LatencyMS:           float64(c.latency) / float64(time.Millisecond),
+			ConsecutiveFailures: c.failures,
+		}
+		switch {
+		case !c.healthy:
+			result.Status = model.HealthUnhealthy
+		case c.dampened:
+			result.Status = model.HealthDampened
+		}
+		if !c.lastProbe.IsZero() {
+			result.Timestamp = c.lastProbe.UTC().Format(time.RFC3339)
+		}
+		details = append(details, result)
+	}
+	return details
+}
+
+// Announcements returns the tracked announcements as they were last tracked.
+func (m *Monitor) Announcements() []model.Announcement {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	announcements := make([]model.Announcement, 0, len(m.tracked))
+	for _, tracked := range m.tracked {
+		announcements = append(announcements, tracked.announcement)
+	}
+	return announcements
+}
+
+// Stop stops all health checks.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, tracked := range m.tracked {
+		for _, c := range tracked.checkers {
+			c.cancel()
+		}
+		delete(m.tracked, id)
+	}
+}
+
+// run probes the next hop until the context is cancelled. A next hop becomes unhealthy after Fall consecutive
+// failures once the grace period since the first of them has passed, and healthy again after Rise consecutive
+// successes.
+func (m *Monitor) run(ctx context.Context, id, nextHop string, c *checker) {
+	gracePeriod := time.Duration(c.check.GracePeriod) * time.Second
+
+	var successes, failures int
+	var firstFailure time.Time
+	for {
+		interval, timeout, rise, fall := m.parameters(c.check)
+		probeCtx, span := tracing.Tracer().Start(ctx, "healthcheck/Probe", trace.WithAttributes(
+			attribute.String("announcement", id),
+			attribute.String("next_hop", nextHop),
+			attribute.String("probe_type", string(c.check.ProbeType())),
+		))
+		probeCtx, cancel := context.WithTimeout(probeCtx, timeout)
+		start := m.clk.Now()
+		err := probe(probeCtx, c.check, nextHop)
+		latency := m.clk.Now().Sub(start)
+		cancel()
+		tracing.RecordError(span, err)
+		span.End()
+		if ctx.Err() != nil {
+			return
+		}
+
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		probes.WithLabelValues(string(c.check.ProbeType()), result).Inc()
+
+		if err == nil {
+			successes++
+			failures = 0
+		} else {
+			if failures == 0 {
+				firstFailure = m.clk.Now()
+			}
+			failures++
+			successes = 0
+		}
+		m.recordProbe(c, start, latency, failures, err)
+
+		switch {
+		case successes >= rise:
+			m.setHealthy(id, nextHop, c, true, nil)
+		case failures >= fall && m.clk.Now().Sub(firstFailure) >= gracePeriod:
+			m.setHealthy(id, nextHop, c, false, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.clk.After(interval):
+		}
+	}
+}
+
+// recordProbe records the result of the last probe of the next hop for the health reports.
+func (m *Monitor) recordProbe(c *checker, start time.Time, latency time.Duration, failures int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c.lastProbe, c.latency, c.failures, c.lastError = start, latency, failures, ""
+	if err != nil {
+		c.lastError = err.Error()
+	}
+}
+
+// setHealthy records the health state of the next hop and calls the change callback if the state has changed.
+// The update is ignored when the checker has been replaced in the meantime. A recovery held back by the dampening
+// is retried with the next successful probe.
+func (m *Monitor) setHealthy(id, nextHop string, c *checker, healthy bool, cause error) {
+	m.mu.Lock()
+	tracked, ok := m.tracked[id]
+	if !ok || tracked.checkers[nextHop] != c {
+		m.mu.Unlock()
+		return
+	}
+
+	// A next hop failing again while its recovery is held back flaps as well, and is penalized without a change
+	now := m.clk.Now()
+	if !healthy && c.dampened {
+		c.dampened = false
+		c.damper.withdraw(now)
+	}
+	if c.healthy == healthy {
+		m.mu.Unlock()
+		return
+	}
+	if healthy && !c.damper.announce(now) {
+		if !c.dampened {
+			c.dampened = true
+			dampenedTransitions.Inc()
+			slog.Info("next hop is healthy, dampening its recovery", "announcement", id, "next_hop", nextHop)
+		}
+		m.mu.Unlock()
+		return
+	}
+	if !healthy {
+		c.damper.withdraw(now)
+	}
+	c.healthy, c.dampened = healthy, false
+	announcement := tracked.announcement
+	m.mu.Unlock()
+
+	if healthy {
+		slog.Info("next hop is healthy", "announcement", id, "next_hop", nextHop)
+	} else {
+		slog.Warn("next hop is unhealthy", "announcement", id, "next_hop", nextHop, "error", cause)
+	}
+	m.onChange(announcement, nextHop, healthy)
+}
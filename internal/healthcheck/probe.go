@@ -0,0 +1,180 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// icmpSequence is the sequence number of the last ICMP echo request.
+var icmpSequence atomic.Uint32
+
+// probe runs a single health check against the next hop and returns an error if the next hop is unhealthy.
+func probe(ctx context.Context, check model.HealthCheck, nextHop string) error {
+	switch check.ProbeType() {
+	case model.HealthCheckTCP:
+		return probeTCP(ctx, check.Target(nextHop))
+	case model.HealthCheckHTTP:
+		return probeHTTP(ctx, check, nextHop)
+	case model.HealthCheckICMP:
+		return probeICMP(ctx, nextHop)
+	case model.HealthCheckGRPC:
+		return probeGRPC(ctx, check, nextHop)
+	default:
+		return fmt.Errorf("unsupported health check type %q", check.Type)
+	}
+}
+
+// probeTCP checks that a TCP connection to the target can be established.
+func probeTCP(ctx context.Context, target string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeGRPC calls the standard grpc.health.v1.Health/Check method of the next hop and expects the SERVING status.
+func probeGRPC(ctx context.Context, check model.HealthCheck, nextHop string) error {
+	transportCredentials := insecure.NewCredentials()
+	if check.TLS {
+		transportCredentials = credentials.NewTLS(probeTLSConfig(check))
+	}
+
+	conn, err := grpc.DialContext(ctx, check.Target(nextHop), grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: check.Service})
+	if err != nil {
+		return err
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("service is %s", resp.Status)
+	}
+	return nil
+}
+
+// probeTLSConfig returns the TLS configuration of the HTTP and gRPC probes. The certificate of the next hop is
+// verified only when a server name is configured, as next hops are addressed by IP.
+func probeTLSConfig(check model.HealthCheck) *tls.Config {
+	return &tls.Config{
+		ServerName:         check.TLSServerName,
+		InsecureSkipVerify: check.TLSServerName == "",
+	}
+}
+
+// probeHTTP sends the configured request to the next hop and expects a 2xx or 3xx status. Redirects are not followed.
+func probeHTTP(ctx context.Context, check model.HealthCheck, nextHop string) error {
+	method := check.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := check.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	scheme := "http://"
+	if check.TLS {
+		scheme = "https://"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, scheme+check.Target(nextHop)+path, nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: probeTLSConfig(check), DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// probeICMP sends an ICMP echo request to the next hop and waits for the reply. It uses unprivileged ICMP sockets,
+// which requires the group of the process to be allowed by the net.ipv4.ping_group_range sysctl.
+func probeICMP(ctx context.Context, nextHop string) error {
+	ip := net.ParseIP(nextHop)
+	if ip == nil {
+		return fmt.Errorf("%q is not a valid IP address", nextHop)
+	}
+
+	network, listenAddress, protocol := "udp4", "0.0.0.0", 1
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.To4() == nil {
+		network, listenAddress, protocol = "udp6", "::", 58
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	conn, err := icmp.ListenPacket(network, listenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	sequence := int(icmpSequence.Add(1) & 0xffff)
+	request, err := (&icmp.Message{
+		Type: requestType,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: sequence, Data: []byte("corebgp")},
+	}).Marshal(nil)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.WriteTo(request, &net.UDPAddr{IP: ip}); err != nil {
+		return fmt.Errorf("failed to send ICMP echo request: %w", err)
+	}
+
+	buffer := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return fmt.Errorf("no ICMP echo reply: %w", err)
+		}
+		if udpAddr, ok := peer.(*net.UDPAddr); !ok || !udpAddr.IP.Equal(ip) {
+			continue
+		}
+
+		reply, err := icmp.ParseMessage(protocol, buffer[:n])
+		if err != nil {
+			continue
+		}
+		// The kernel rewrites the identifier of unprivileged echo requests, so only the sequence is compared
+		if echo, ok := reply.Body.(*icmp.Echo); ok && reply.Type == replyType && echo.Seq == sequence {
+			return nil
+		}
+	}
+}
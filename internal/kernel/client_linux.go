@@ -0,0 +1,340 @@
+//go:build linux
+
+package kernel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// Client installs paths as routes into a table of the Linux kernel through rtnetlink. The paths of a prefix form a
+// single multipath route whose next hops are weighted by the link bandwidth of the paths. The routes are marked with
+// routeProtocol and the metric of the client, so that the routes of other origins are left untouched.
+type Client struct {
+	table  uint32
+	metric uint32
+	mu     sync.Mutex // mu serializes the route changes, which read the current route of the prefix first.
+	sock   *socket
+}
+
+// route is a route of the client in the kernel.
+type route struct {
+	prefix   netip.Prefix
+	nextHops []routeNextHop
+}
+
+// routeNextHop is a gateway of a multipath route.
+type routeNextHop struct {
+	addr netip.Addr
+	hops uint8 // hops is the weight of the next hop minus one, as in the rtnh_hops field.
+}
+
+// NewClient opens the rtnetlink socket and checks that the routes of the table can be read.
+func NewClient(table, metric uint32) (*Client, error) {
+	sock, err := openSocket()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{table: table, metric: metric, sock: sock}
+	if _, err := c.routes(unix.AF_INET); err != nil {
+		sock.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close closes the rtnetlink socket. The installed routes are kept.
+func (c *Client) Close() {
+	c.sock.close()
+}
+
+// AddPath installs the route of the prefix via the next hop, adding the next hop to the multipath route of the
+// prefix. Adding a path again updates the weight of its next hop. The other path attributes have no meaning in the
+// kernel and are ignored.
+func (c *Client) AddPath(prefix string, prefixLength uint32, nextHop string, attrs gobgp.PathAttributes) error {
+	dst, gateway, err := parsePath(prefix, prefixLength, nextHop)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current, err := c.route(dst)
+	if err != nil {
+		return err
+	}
+	r := route{prefix: dst}
+	if current != nil {
+		r = *current
+	}
+	hops := weightHops(attrs.LinkBandwidth)
+	if i := slices.IndexFunc(r.nextHops, func(nh routeNextHop) bool { return nh.addr == gateway }); i >= 0 {
+		if r.nextHops[i].hops == hops {
+			return nil
+		}
+		r.nextHops[i].hops = hops
+	} else {
+		r.nextHops = append(r.nextHops, routeNextHop{addr: gateway, hops: hops})
+	}
+
+	if err := c.replace(r); err != nil {
+		return fmt.Errorf("failed to add route to the kernel: %w", err)
+	}
+	return nil
+}
+
+// DeletePath removes the next hop from the multipath route of the prefix, deleting the route with its last next hop.
+// Deleting a path that is not installed is not an error.
+func (c *Client) DeletePath(prefix string, prefixLength uint32, nextHop string) error {
+	dst, gateway, err := parsePath(prefix, prefixLength, nextHop)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, err := c.route(dst)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return nil
+	}
+	i := slices.IndexFunc(r.nextHops, func(nh routeNextHop) bool { return nh.addr == gateway })
+	if i < 0 {
+		return nil
+	}
+	r.nextHops = slices.Delete(r.nextHops, i, i+1)
+
+	if len(r.nextHops) == 0 {
+		err = c.delete(r.prefix)
+	} else {
+		err = c.replace(*r)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete route from the kernel: %w", err)
+	}
+	return nil
+}
+
+// DeleteLocalPath removes the path listed by ListLocalPaths.
+func (c *Client) DeleteLocalPath(path gobgp.LocalPath) error {
+	return c.DeletePath(path.Prefix, path.PrefixLength, path.NextHop)
+}
+
+// ListLocalPaths returns a path for every next hop of the routes of the address family ("ipv4-unicast" or
+// "ipv6-unicast") installed by the client.
+func (c *Client) ListLocalPaths(family string) ([]gobgp.LocalPath, error) {
+	af := uint8(unix.AF_INET)
+	if family == model.AddressFamilyIPv6Unicast {
+		af = unix.AF_INET6
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	routes, err := c.routes(af)
+	if err != nil {
+		return nil, err
+	}
+	var paths []gobgp.LocalPath
+	for _, r := range routes {
+		for _, nh := range r.nextHops {
+			paths = append(paths, gobgp.LocalPath{
+				Prefix:       r.prefix.Addr().String(),
+				PrefixLength: uint32(r.prefix.Bits()),
+				NextHop:      nh.addr.String(),
+			})
+		}
+	}
+	return paths, nil
+}
+
+// route returns the route of the client for the prefix, or nil if there is none.
+func (c *Client) route(prefix netip.Prefix) (*route, error) {
+	af := uint8(unix.AF_INET)
+	if prefix.Addr().Is6() {
+		af = unix.AF_INET6
+	}
+	routes, err := c.routes(af)
+	if err != nil {
+		return nil, err
+	}
+	for i := range routes {
+		if routes[i].prefix == prefix {
+			return &routes[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// routes dumps the routes of the address family and returns those installed by the client in its table.
+func (c *Client) routes(af uint8) ([]route, error) {
+	messages, err := c.sock.request(unix.RTM_GETROUTE, unix.NLM_F_DUMP, routeMessage(rtmsg{family: af}, nil))
+	if err != nil {
+		netlinkErrors.Inc()
+		return nil, fmt.Errorf("failed to list the routes of the kernel: %w", err)
+	}
+
+	var routes []route
+	for _, message := range messages {
+		if message.Header.Type != unix.RTM_NEWROUTE || len(message.Data) < unix.SizeofRtMsg {
+			continue
+		}
+		r, ok := c.parseRoute(message.Data)
+		if ok {
+			routes = append(routes, r)
+		}
+	}
+	return routes, nil
+}
+
+// parseRoute parses the route message, reporting whether the route is a unicast route of the client.
+func (c *Client) parseRoute(data []byte) (route, bool) {
+	header := parseRtmsg(data)
+	if header.protocol != routeProtocol || header.typ != unix.RTN_UNICAST {
+		return route{}, false
+	}
+
+	table := uint32(header.table)
+	var metric uint32
+	var dst netip.Addr
+	var r route
+	for _, a := range parseAttributes(data[unix.SizeofRtMsg:]) {
+		switch a.typ {
+		case unix.RTA_TABLE:
+			if len(a.data) == 4 {
+				table = binary.NativeEndian.Uint32(a.data)
+			}
+		case unix.RTA_PRIORITY:
+			if len(a.data) == 4 {
+				metric = binary.NativeEndian.Uint32(a.data)
+			}
+		case unix.RTA_DST:
+			dst, _ = netip.AddrFromSlice(a.data)
+		case unix.RTA_GATEWAY:
+			if addr, ok := netip.AddrFromSlice(a.data); ok {
+				r.nextHops = append(r.nextHops, routeNextHop{addr: addr})
+			}
+		case unix.RTA_MULTIPATH:
+			r.nextHops = append(r.nextHops, parseMultipath(a.data)...)
+		}
+	}
+	if table != c.table || metric != c.metric || len(r.nextHops) == 0 {
+		return route{}, false
+	}
+	if !dst.IsValid() {
+		dst = netip.IPv4Unspecified()
+		if header.family == unix.AF_INET6 {
+			dst = netip.IPv6Unspecified()
+		}
+	}
+	r.prefix = netip.PrefixFrom(dst, int(header.dstLen))
+	return r, true
+}
+
+// replace installs the route, replacing the route of its prefix with the metric of the client.
+func (c *Client) replace(r route) error {
+	attrs := c.routeAttributes(r.prefix)
+	if len(r.nextHops) == 1 && r.nextHops[0].hops == 0 {
+		attrs = appendAttribute(attrs, unix.RTA_GATEWAY, r.nextHops[0].addr.AsSlice())
+	} else {
+		var multipath []byte
+		for _, nh := range r.nextHops {
+			gateway := appendAttribute(nil, unix.RTA_GATEWAY, nh.addr.AsSlice())
+			rtnh := make([]byte, unix.SizeofRtNexthop)
+			binary.NativeEndian.PutUint16(rtnh[0:2], uint16(unix.SizeofRtNexthop+len(gateway)))
+			rtnh[3] = nh.hops
+			multipath = append(append(multipath, rtnh...), gateway...)
+		}
+		attrs = appendAttribute(attrs, unix.RTA_MULTIPATH, multipath)
+	}
+
+	header := c.routeHeader(r.prefix)
+	header.scope = unix.RT_SCOPE_UNIVERSE
+	header.typ = unix.RTN_UNICAST
+	_, err := c.sock.request(unix.RTM_NEWROUTE, unix.NLM_F_CREATE|unix.NLM_F_REPLACE, routeMessage(header, attrs))
+	if err != nil {
+		netlinkErrors.Inc()
+	}
+	return err
+}
+
+// delete removes the route of the prefix with the metric of the client.
+func (c *Client) delete(prefix netip.Prefix) error {
+	header := c.routeHeader(prefix)
+	header.scope = unix.RT_SCOPE_NOWHERE
+	_, err := c.sock.request(unix.RTM_DELROUTE, 0, routeMessage(header, c.routeAttributes(prefix)))
+	if err != nil {
+		netlinkErrors.Inc()
+	}
+	return err
+}
+
+// routeHeader returns the route message header of the prefix in the table of the client.
+func (c *Client) routeHeader(prefix netip.Prefix) rtmsg {
+	header := rtmsg{
+		family:   unix.AF_INET,
+		dstLen:   uint8(prefix.Bits()),
+		protocol: routeProtocol,
+		table:    unix.RT_TABLE_UNSPEC,
+	}
+	if prefix.Addr().Is6() {
+		header.family = unix.AF_INET6
+	}
+	if c.table < 256 {
+		header.table = uint8(c.table)
+	}
+	return header
+}
+
+// routeAttributes returns the attributes identifying the route of the prefix: its destination, table and metric.
+func (c *Client) routeAttributes(prefix netip.Prefix) []byte {
+	table := binary.NativeEndian.AppendUint32(nil, c.table)
+	metric := binary.NativeEndian.AppendUint32(nil, c.metric)
+	attrs := appendAttribute(nil, unix.RTA_DST, prefix.Addr().AsSlice())
+	attrs = appendAttribute(attrs, unix.RTA_TABLE, table)
+	return appendAttribute(attrs, unix.RTA_PRIORITY, metric)
+}
+
+// parsePath parses the prefix and the next hop of the path, which must be of the same address family.
+func parsePath(prefix string, prefixLength uint32, nextHop string) (netip.Prefix, netip.Addr, error) {
+	addr, err := netip.ParseAddr(prefix)
+	if err != nil {
+		return netip.Prefix{}, netip.Addr{}, fmt.Errorf("invalid prefix %q: %w", prefix, err)
+	}
+	dst, err := addr.Unmap().Prefix(int(prefixLength))
+	if err != nil {
+		return netip.Prefix{}, netip.Addr{}, fmt.Errorf("invalid prefix %s/%d: %w", prefix, prefixLength, err)
+	}
+	gateway, err := netip.ParseAddr(nextHop)
+	if err != nil {
+		return netip.Prefix{}, netip.Addr{}, fmt.Errorf("invalid next hop %q: %w", nextHop, err)
+	}
+	gateway = gateway.Unmap()
+	if gateway.Is4() != dst.Addr().Is4() {
+		return netip.Prefix{}, netip.Addr{}, fmt.Errorf("next hop %s is not of the address family of %s", gateway, dst)
+	}
+	return dst, gateway, nil
+}
+
+// weightHops converts the link bandwidth of a weighted path, in bytes per second, back to the weight in Mbit/s and
+// returns it in the rtnh_hops form, i.e., minus one and capped at 256. Unweighted paths have the weight one.
+func weightHops(linkBandwidth float32) uint8 {
+	if linkBandwidth == 0 {
+		return 0
+	}
+	weight := min(max(math.Round(float64(linkBandwidth)*8/1e6), 1), 256)
+	return uint8(weight - 1)
+}
@@ -0,0 +1,43 @@
+//go:build !linux
+
+package kernel
+
+import (
+	"errors"
+
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+)
+
+// errUnsupported is returned on the platforms without rtnetlink.
+var errUnsupported = errors.New("the kernel routing table backend is only supported on Linux")
+
+// Client installs paths as routes into a table of the Linux kernel. It is not supported on this platform.
+type Client struct{}
+
+// NewClient returns an error, the kernel routing table is only programmed on Linux.
+func NewClient(table, metric uint32) (*Client, error) {
+	return nil, errUnsupported
+}
+
+// Close does nothing.
+func (c *Client) Close() {}
+
+// AddPath returns an error.
+func (c *Client) AddPath(prefix string, prefixLength uint32, nextHop string, attrs gobgp.PathAttributes) error {
+	return errUnsupported
+}
+
+// DeletePath returns an error.
+func (c *Client) DeletePath(prefix string, prefixLength uint32, nextHop string) error {
+	return errUnsupported
+}
+
+// DeleteLocalPath returns an error.
+func (c *Client) DeleteLocalPath(path gobgp.LocalPath) error {
+	return errUnsupported
+}
+
+// ListLocalPaths returns an error.
+func (c *Client) ListLocalPaths(family string) ([]gobgp.LocalPath, error) {
+	return nil, errUnsupported
+}
@@ -0,0 +1,12 @@
+package kernel
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// netlinkErrors counts the failed rtnetlink requests.
+var netlinkErrors = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "corebgp_kernel_netlink_errors_total",
+	Help: "Number of failed rtnetlink requests.",
+})
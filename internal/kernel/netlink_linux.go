@@ -0,0 +1,184 @@
+//go:build linux
+
+package kernel
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync/atomic"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	routeProtocol  = 201 // routeProtocol marks the routes installed by the client, "ip route" shows it as "proto 201".
+	receiveTimeout = 10  // receiveTimeout is the time in seconds after which a netlink request without a reply fails.
+)
+
+// socket is an rtnetlink socket. The requests are not serialized, so the callers must not send them concurrently.
+type socket struct {
+	fd  int
+	seq atomic.Uint32
+}
+
+// openSocket opens and binds an rtnetlink socket.
+func openSocket() (*socket, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+	err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &unix.Timeval{Sec: receiveTimeout})
+	if err == nil {
+		err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+	}
+	if err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("failed to set up netlink socket: %w", err)
+	}
+	return &socket{fd: fd}, nil
+}
+
+// close closes the socket.
+func (s *socket) close() {
+	_ = unix.Close(s.fd)
+}
+
+// request sends the message and waits for the kernel to acknowledge it, or to finish the dump if flags contain
+// NLM_F_DUMP. It returns the messages of the dump.
+func (s *socket) request(msgType, flags uint16, data []byte) ([]syscall.NetlinkMessage, error) {
+	seq := s.seq.Add(1)
+	header := make([]byte, unix.SizeofNlMsghdr, unix.SizeofNlMsghdr+len(data))
+	binary.NativeEndian.PutUint32(header[0:4], uint32(unix.SizeofNlMsghdr+len(data)))
+	binary.NativeEndian.PutUint16(header[4:6], msgType)
+	binary.NativeEndian.PutUint16(header[6:8], flags|unix.NLM_F_REQUEST|unix.NLM_F_ACK)
+	binary.NativeEndian.PutUint32(header[8:12], seq)
+	if err := unix.Sendto(s.fd, append(header, data...), 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, err
+	}
+
+	// Every read gets its own buffer, as the returned messages refer to it
+	var messages []syscall.NetlinkMessage
+	for {
+		buf := make([]byte, 1<<16)
+		n, _, err := unix.Recvfrom(s.fd, buf, 0)
+		if err != nil {
+			return nil, err
+		}
+		received, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range received {
+			// Skip the late replies of requests that timed out
+			if message.Header.Seq != seq {
+				continue
+			}
+			switch message.Header.Type {
+			case unix.NLMSG_DONE:
+				return messages, nil
+			case unix.NLMSG_ERROR:
+				if len(message.Data) < 4 {
+					return nil, errors.New("truncated netlink error message")
+				}
+				if code := int32(binary.NativeEndian.Uint32(message.Data[0:4])); code != 0 {
+					return nil, unix.Errno(-code)
+				}
+				return messages, nil
+			default:
+				messages = append(messages, message)
+			}
+		}
+	}
+}
+
+// rtmsg is the header of the route messages.
+type rtmsg struct {
+	family   uint8
+	dstLen   uint8
+	table    uint8
+	protocol uint8
+	scope    uint8
+	typ      uint8
+}
+
+// routeMessage encodes the header and the attributes of a route message.
+func routeMessage(header rtmsg, attrs []byte) []byte {
+	data := make([]byte, unix.SizeofRtMsg, unix.SizeofRtMsg+len(attrs))
+	data[0] = header.family
+	data[1] = header.dstLen
+	data[4] = header.table
+	data[5] = header.protocol
+	data[6] = header.scope
+	data[7] = header.typ
+	return append(data, attrs...)
+}
+
+// parseRtmsg decodes the header of a route message.
+func parseRtmsg(data []byte) rtmsg {
+	return rtmsg{
+		family:   data[0],
+		dstLen:   data[1],
+		table:    data[4],
+		protocol: data[5],
+		scope:    data[6],
+		typ:      data[7],
+	}
+}
+
+// attribute is a netlink attribute.
+type attribute struct {
+	typ  uint16
+	data []byte
+}
+
+// appendAttribute appends the attribute with its padding to the encoded attributes.
+func appendAttribute(attrs []byte, typ uint16, data []byte) []byte {
+	length := unix.SizeofRtAttr + len(data)
+	attrs = binary.NativeEndian.AppendUint16(attrs, uint16(length))
+	attrs = binary.NativeEndian.AppendUint16(attrs, typ)
+	attrs = append(attrs, data...)
+	return append(attrs, make([]byte, align(length)-length)...)
+}
+
+// parseAttributes decodes the attributes, stopping at the first malformed one.
+func parseAttributes(data []byte) []attribute {
+	var attrs []attribute
+	for len(data) >= unix.SizeofRtAttr {
+		length := int(binary.NativeEndian.Uint16(data[0:2]))
+		if length < unix.SizeofRtAttr || length > len(data) {
+			break
+		}
+		attrs = append(attrs, attribute{typ: binary.NativeEndian.Uint16(data[2:4]), data: data[unix.SizeofRtAttr:length]})
+		data = data[min(align(length), len(data)):]
+	}
+	return attrs
+}
+
+// parseMultipath decodes the next hops of the RTA_MULTIPATH attribute, skipping those without a gateway.
+func parseMultipath(data []byte) []routeNextHop {
+	var nextHops []routeNextHop
+	for len(data) >= unix.SizeofRtNexthop {
+		length := int(binary.NativeEndian.Uint16(data[0:2]))
+		if length < unix.SizeofRtNexthop || length > len(data) {
+			break
+		}
+		for _, a := range parseAttributes(data[unix.SizeofRtNexthop:length]) {
+			if a.typ != unix.RTA_GATEWAY {
+				continue
+			}
+			if addr, ok := netip.AddrFromSlice(a.data); ok {
+				nextHops = append(nextHops, routeNextHop{addr: addr, hops: data[3]})
+			}
+		}
+		data = data[min(align(length), len(data)):]
+	}
+	return nextHops
+}
+
+// align rounds the length up to the netlink alignment of four bytes.
+func align(length int) int {
+	return (length + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
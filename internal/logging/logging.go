@@ -0,0 +1,96 @@
+// Package logging configures the structured loggers shared by the CoreBGP components.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Formats of the log output.
+const (
+	FormatJSON    = "json"    // FormatJSON writes one JSON object per record.
+	FormatConsole = "console" // FormatConsole writes human-readable key=value records.
+)
+
+// Config describes the destination and the format of a log.
+type Config struct {
+	Path       string // Path is the log destination: "stdout", "stderr" or a file path rotated by size.
+	Format     string // Format is the output format, FormatJSON or FormatConsole.
+	Verbose    int8   // Verbose is the verbosity level, see Level.
+	MaxSize    int    // MaxSize is the size in megabytes at which the log file is rotated.
+	MaxBackups int    // MaxBackups is the number of rotated log files to keep.
+
+	LevelVar *slog.LevelVar // LevelVar, if set, holds the minimal logged level so that it can be changed at runtime; it is set from Verbose.
+}
+
+// New creates the logger described by the config. The returned closer releases the log file.
+func New(config Config) (*slog.Logger, io.Closer, error) {
+	var level slog.Leveler = Level(config.Verbose)
+	if config.LevelVar != nil {
+		config.LevelVar.Set(Level(config.Verbose))
+		level = config.LevelVar
+	}
+	options := &slog.HandlerOptions{Level: level}
+	w := Open(config.Path, config.MaxSize, config.MaxBackups)
+
+	var handler slog.Handler
+	switch config.Format {
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(w, options)
+	case FormatConsole:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		_ = w.Close()
+		return nil, nil, fmt.Errorf("unsupported log format %q: must be %s or %s", config.Format, FormatJSON, FormatConsole)
+	}
+
+	return slog.New(handler), w, nil
+}
+
+// Level maps the verbosity level to the minimal logged level: 0 logs info, positive values add debug records,
+// -1 logs only warnings and errors, lower values only errors.
+func Level(verbose int8) slog.Level {
+	switch {
+	case verbose > 0:
+		return slog.LevelDebug
+	case verbose == 0:
+		return slog.LevelInfo
+	case verbose == -1:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// Open opens the log destination. The special values "stdout" and "stderr" select the standard streams, any other
+// value is a file path rotated when it reaches maxSize megabytes, keeping maxBackups compressed old files.
+func Open(path string, maxSize, maxBackups int) io.WriteCloser {
+	switch path {
+	case "stdout":
+		return nopWriteCloser{os.Stdout}
+	case "stderr", "":
+		return nopWriteCloser{os.Stderr}
+	default:
+		return &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+			MaxAge:     30, // days
+			Compress:   true,
+		}
+	}
+}
+
+// nopWriteCloser prevents the standard streams from being closed together with the log.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
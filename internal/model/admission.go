@@ -0,0 +1,58 @@
+package model
+
+// AdmissionOperation is the change of an announcement reviewed by the admission webhooks.
+type AdmissionOperation string
+
+const (
+	AdmissionCreate AdmissionOperation = "create" // AdmissionCreate reviews the creation of an announcement.
+	AdmissionUpdate AdmissionOperation = "update" // AdmissionUpdate reviews the change of an existing announcement.
+)
+
+// Types of the admission webhooks.
+const (
+	AdmissionMutating   = "mutating"   // AdmissionMutating webhooks may change the announcement, e.g., inject default communities.
+	AdmissionValidating = "validating" // AdmissionValidating webhooks only admit or reject the announcement.
+)
+
+// Failure policies of the admission webhooks.
+const (
+	AdmissionFailClosed = "fail-closed" // AdmissionFailClosed rejects the change when the webhook cannot be called or answers invalidly.
+	AdmissionFailOpen   = "fail-open"   // AdmissionFailOpen admits the change as if the webhook was not configured.
+)
+
+// AdmissionConfig is the content of the API server admission webhooks file.
+type AdmissionConfig struct {
+	Webhooks []AdmissionWebhook `json:"webhooks"` // Webhooks lists the endpoints reviewing the created and updated announcements.
+}
+
+// AdmissionWebhook is an HTTPS endpoint reviewing the created and updated announcements before they are stored. The
+// mutating webhooks are called first in the order of the file, each with the announcement changed by the previous
+// ones, then the validating webhooks.
+type AdmissionWebhook struct {
+	Name          string               `json:"name"`                     // Name identifies the webhook in the errors, the logs and the metrics.
+	URL           string               `json:"url"`                      // URL is the HTTPS URL the reviews are posted to.
+	Type          string               `json:"type"`                     // Type is AdmissionMutating or AdmissionValidating.
+	Operations    []AdmissionOperation `json:"operations,omitempty"`     // Operations lists the reviewed operations; empty reviews creations and updates.
+	Projects      []string             `json:"projects,omitempty"`       // Projects lists the projects whose announcements are reviewed; empty reviews all projects.
+	FailurePolicy string               `json:"failure-policy,omitempty"` // FailurePolicy is AdmissionFailClosed (default) or AdmissionFailOpen.
+	Timeout       int                  `json:"timeout,omitempty"`        // Timeout is the time in seconds to wait for the review, at most 30; defaults to 10.
+	CACert        string               `json:"ca-cert,omitempty"`        // CACert is the path to the PEM CA bundle verifying the webhook certificate; empty uses the system roots.
+}
+
+// AdmissionReview is the payload posted to the admission webhooks.
+type AdmissionReview struct {
+	ID           string             `json:"id"`                 // ID uniquely identifies the review.
+	Operation    AdmissionOperation `json:"operation"`          // Operation is the reviewed change.
+	DryRun       bool               `json:"dry-run"`            // DryRun reports whether the change is only checked and not stored; webhooks with side effects must skip them.
+	Actor        string             `json:"actor,omitempty"`    // Actor is the authenticated subject making the change; empty when authentication is disabled.
+	Announcement *Announcement      `json:"announcement"`       // Announcement is the announcement after the change.
+	Previous     *Announcement      `json:"previous,omitempty"` // Previous is the stored announcement; nil for a creation.
+}
+
+// AdmissionResponse is the answer of an admission webhook to a review.
+type AdmissionResponse struct {
+	Allowed      bool              `json:"allowed"`                // Allowed admits the change; a rejected change is not stored.
+	Message      string            `json:"message,omitempty"`      // Message explains the rejection to the client.
+	Errors       []ValidationError `json:"errors,omitempty"`       // Errors lists the rejected fields, returned to the client like the built-in validation errors.
+	Announcement *Announcement     `json:"announcement,omitempty"` // Announcement is the changed announcement of a mutating webhook; nil keeps it as reviewed.
+}
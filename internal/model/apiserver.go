@@ -1,5 +1,12 @@
 package model
 
+import (
+	"encoding/json"
+	"net"
+	"strconv"
+	"time"
+)
+
 // EventType defines the type of event such as added, updated or deleted.
 type EventType string
 
@@ -7,12 +14,21 @@ const (
 	EventAdded   EventType = "added"   // EventAdded represents the event type for adding a new announcement.
 	EventUpdated EventType = "updated" // EventUpdated represents the event type for updating an existing announcement.
 	EventDeleted EventType = "deleted" // EventDeleted represents the event type for deleting an existing announcement.
+
+	// EventResyncRequired signals that the watch history has a gap and the client must re-list all announcements.
+	EventResyncRequired EventType = "RESYNC_REQUIRED"
+	// EventBookmark is sent periodically on idle watches with the revision up to which all selected changes have been
+	// sent, so that the client resumes from it after a reconnection. It keeps the connection alive behind NATs as well.
+	EventBookmark EventType = "BOOKMARK"
 )
 
 // Event represents a BGP announcement event, encapsulating the type of action and the specific announcement.
 type Event struct {
 	Type         EventType    `json:"type"`         // Action specifies the type of event: add, update, or delete.
 	Announcement Announcement `json:"announcement"` // Announcement is the BGP announcement data associated with the event.
+	Revision     int64        `json:"revision"`     // Revision is the storage revision at which the event occurred.
+
+	Previous *Announcement `json:"previous,omitempty"` // Previous is the stored announcement replaced by an update; it is set in update responses only.
 }
 
 // APIResponse represents a standard response structure for API calls.
@@ -22,26 +38,181 @@ type APIResponse struct {
 	Data    interface{} `json:"data"`    // Data contains the response payload, which can vary depending on the endpoint.
 }
 
+// Readiness is the result of the readiness check of the API server.
+type Readiness struct {
+	Ready  bool              `json:"ready"`  // Ready reports whether all dependencies of the API server are reachable.
+	Checks map[string]string `json:"checks"` // Checks maps the checked dependencies, "datastore" and "gobgp", to "ok" or their error.
+}
+
+// BuildInfo describes the build of a CoreBGP binary.
+type BuildInfo struct {
+	Version   string `json:"version"`    // Version is the released version, "dev" for development builds.
+	Commit    string `json:"commit"`     // Commit is the git commit the binary is built from, empty if unknown.
+	GoVersion string `json:"go-version"` // GoVersion is the version of the Go toolchain the binary is built with.
+}
+
+// AnnouncementList is a single page of announcements returned by paginated list requests.
+type AnnouncementList struct {
+	Items    []Announcement `json:"items"`              // Items contains the announcements of the page.
+	Continue string         `json:"continue,omitempty"` // Continue is the opaque token to request the next page; empty on the last page.
+}
+
+// AnnouncementRevision is a revision of an announcement kept in its history.
+type AnnouncementRevision struct {
+	Revision     string       `json:"revision"`        // Revision is the resource version the announcement had after the change.
+	Timestamp    time.Time    `json:"timestamp"`       // Timestamp is the time of the change.
+	Actor        string       `json:"actor,omitempty"` // Actor is the authenticated subject that made the change; empty without authentication.
+	Announcement Announcement `json:"announcement"`    // Announcement is the announcement as it was stored by the change.
+}
+
+// RollbackRequest is the body of a request restoring a previous revision of an announcement.
+type RollbackRequest struct {
+	Revision string `json:"revision"` // Revision is the revision of the announcement history to restore.
+}
+
+// AnnouncementEvent is a significant event in the life of an announcement, e.g., its creation, a failed health check
+// of a next hop or a programming error, recorded by the API server from the changes of the announcement.
+type AnnouncementEvent struct {
+	Timestamp       time.Time `json:"timestamp"`                  // Timestamp is the time of the change that caused the event.
+	Type            string    `json:"type"`                       // Type is EventNormal or EventWarning.
+	Reason          string    `json:"reason"`                     // Reason identifies the event in CamelCase, e.g., "Created" or "NextHopsUnhealthy".
+	Message         string    `json:"message,omitempty"`          // Message describes the event.
+	Actor           string    `json:"actor,omitempty"`            // Actor is the authenticated subject, or the server component, that made the change.
+	ResourceVersion string    `json:"resource-version,omitempty"` // ResourceVersion is the resource version written by the change; empty for deletions.
+}
+
+// Types of the announcement events.
+const (
+	EventNormal  = "Normal"  // EventNormal is the type of the events of the expected operation.
+	EventWarning = "Warning" // EventWarning is the type of the events of failures, e.g., unhealthy next hops.
+)
+
 // Announcement represents a BGP routing configuration, including metadata, addresses, next-hop details, health checks, and status.
 type Announcement struct {
-	Meta        Meta        `json:"meta"`         // Meta represents metadata information including a descriptive name and associated project for a BGP announcement.
-	Addresses   Addresses   `json:"addresses"`    // Addresses represents a collection of network-related data, including subnets, zone, and announcing ip.
-	NextHops    []Subnet    `json:"next-hops"`    // NextHops represents a collection of next-hop IP addresses used for routing purposes.
-	HealthCheck HealthCheck `json:"health-check"` // HealthCheck represents the configuration and parameters for performing health checks on next hops.
-	Status      Status      `json:"status"`       // Status represents the current state of an announcement with details and a timestamp.
+	Meta             Meta              `json:"meta"`                         // Meta represents metadata information including a descriptive name and associated project for a BGP announcement.
+	Addresses        Addresses         `json:"addresses"`                    // Addresses represents a collection of network-related data, including subnets, zone, and announcing ip.
+	NextHops         []Subnet          `json:"next-hops"`                    // NextHops represents a collection of next-hop IP addresses used for routing purposes.
+	WeightedNextHops []WeightedNextHop `json:"weighted-next-hops,omitempty"` // WeightedNextHops lists next hops with capacity weights for weighted ECMP; it takes precedence over NextHops.
+	Origin           BGPOrigin         `json:"origin,omitempty"`             // Origin specifies the value of the BGP ORIGIN attribute; empty means OriginIGP.
+	LocalPref        uint32            `json:"local-pref,omitempty"`         // LocalPref specifies the BGP LOCAL_PREF attribute; zero means the attribute is not set.
+	MED              uint32            `json:"med,omitempty"`                // MED specifies the BGP MULTI_EXIT_DISC attribute; zero means the attribute is not set.
+	ASPathPrepend    uint8             `json:"as-path-prepend,omitempty"`    // ASPathPrepend is the number of times the local AS is prepended to the AS_PATH to make the route less preferred.
+	Communities      []string          `json:"communities,omitempty"`        // Communities lists the standard, extended (rt:/soo:) and large communities attached to the route.
+	HealthCheck      HealthCheck       `json:"health-check"`                 // HealthCheck represents the configuration and parameters for performing health checks on next hops.
+	BFDPeers         []string          `json:"bfd-peers,omitempty"`          // BFDPeers lists the addresses of the BFD peers whose sessions must be up for the routes to be programmed.
+	Schedule         *Schedule         `json:"schedule,omitempty"`           // Schedule limits the time the routes are programmed; nil means always.
+	Failover         FailoverPolicy    `json:"failover,omitempty"`           // Failover decides whether the routes are programmed via all next hops or only via the first healthy one; empty means FailoverAllActive.
+	Status           Status            `json:"status"`                       // Status represents the current state of an announcement with details and a timestamp.
+
+	// NextHopCommunities maps a next-hop address to the communities attached only to the path via that next hop,
+	// in addition to Communities.
+	NextHopCommunities map[string][]string `json:"next-hop-communities,omitempty"`
+
+	// Sites maps the IDs of the sites to the overrides applied by the updaters registered with the site. The
+	// updaters of other sites program the announcement as it is.
+	Sites map[string]SiteOverride `json:"sites,omitempty"`
+
+	// LastApplied records the configuration last applied by each field manager through the apply endpoint.
+	// It is maintained by the server and used to detect the fields a manager stopped managing.
+	LastApplied map[string]json.RawMessage `json:"last-applied,omitempty"`
+}
+
+// MaxASPathPrepend is the maximum number of times the local AS can be prepended to the AS_PATH of an announcement.
+const MaxASPathPrepend = 10
+
+// MaxHealthCheckPeriod is the maximum interval, timeout and grace period of a health check in seconds.
+const MaxHealthCheckPeriod = 3600
+
+// MaxBFDPeers is the maximum number of BFD peers an announcement can require.
+const MaxBFDPeers = 8
+
+// MaxHealthCheckThreshold is the maximum number of consecutive probes of the rise and fall thresholds of a health check.
+const MaxHealthCheckThreshold = 100
+
+// BGPOrigin defines the value of the BGP ORIGIN path attribute.
+type BGPOrigin string
+
+const (
+	OriginIGP        BGPOrigin = "igp"        // OriginIGP marks the route as originated from an interior routing protocol or a static route.
+	OriginEGP        BGPOrigin = "egp"        // OriginEGP marks the route as learned through the EGP protocol.
+	OriginIncomplete BGPOrigin = "incomplete" // OriginIncomplete marks the route as redistributed from another source.
+)
+
+// Valid reports whether the origin is empty (defaults to OriginIGP) or one of the defined values.
+func (o BGPOrigin) Valid() bool {
+	switch o {
+	case "", OriginIGP, OriginEGP, OriginIncomplete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Code returns the numeric ORIGIN attribute value as defined in RFC 4271. An empty origin is treated as OriginIGP.
+func (o BGPOrigin) Code() uint32 {
+	switch o {
+	case OriginEGP:
+		return 1
+	case OriginIncomplete:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// OriginFromCode returns the origin with the numeric ORIGIN attribute value as defined in RFC 4271.
+func OriginFromCode(code uint32) BGPOrigin {
+	switch code {
+	case 1:
+		return OriginEGP
+	case 2:
+		return OriginIncomplete
+	default:
+		return OriginIGP
+	}
+}
+
+// FailoverPolicy defines how the routes of an announcement are spread over its next hops.
+type FailoverPolicy string
+
+const (
+	FailoverAllActive     FailoverPolicy = "all-active"     // FailoverAllActive programs a path via every healthy next hop, so that the routers balance the traffic with ECMP.
+	FailoverPrimaryBackup FailoverPolicy = "primary-backup" // FailoverPrimaryBackup programs a path only via the first healthy next hop in the order of the list, for stateful services that cannot tolerate ECMP.
+)
+
+// Valid reports whether the failover policy is empty (defaults to FailoverAllActive) or one of the defined values.
+func (f FailoverPolicy) Valid() bool {
+	switch f {
+	case "", FailoverAllActive, FailoverPrimaryBackup:
+		return true
+	default:
+		return false
+	}
 }
 
 // Meta represents metadata information including a descriptive name and associated project for a BGP announcement.
 type Meta struct {
 	Name    string `json:"name"`    // Name specifies the descriptive name for the BGP announce.
 	Project string `json:"project"` // Project specifies the project associated with the BGP announce.
+
+	Labels      map[string]string `json:"labels,omitempty"`      // Labels are identifying key-value pairs that list, watch and delete requests select announcements by, e.g., env=staging.
+	Annotations map[string]string `json:"annotations,omitempty"` // Annotations hold arbitrary non-identifying metadata, e.g., the owning team or a ticket.
+
+	ResourceVersion string `json:"resource-version,omitempty"` // ResourceVersion is the storage revision of the last modification; updates carrying it fail with a conflict if it is stale.
+	CreatedAt       string `json:"created-at,omitempty"`       // CreatedAt is the time at which the announcement was created in RFC 3339 format; it is set by the API server and kept by the updates.
+
+	Generation int64  `json:"generation,omitempty"` // Generation is incremented by the API server on every change of the announcement other than its status.
+	ChangeID   string `json:"change-id,omitempty"`  // ChangeID identifies the change that produced the generation; it is the trace ID of the write request when it is traced.
+	ChangedAt  string `json:"changed-at,omitempty"` // ChangedAt is the time at which the generation was written in RFC 3339 format.
 }
 
 // Addresses represents a collection of network-related data, including subnets, zone, and announcing ip.
 type Addresses struct {
-	SourceSubnets Subnet `json:"announced-address"` // SourceSubnets specifies the subnet from which the announced address should be obtained (IPAM).
-	Zone          string `json:"zone"`              // Zone specifies the geographical or logical zone associated with the addresses.
-	AnnouncedIP   string `json:"announced-ip"`      // AnnouncedIP specifies the IP address being announced for routing purposes.
+	SourceSubnets Subnet `json:"announced-address"`        // SourceSubnets specifies the subnet from which the announced address should be obtained (IPAM).
+	Zone          string `json:"zone"`                     // Zone specifies the geographical or logical zone associated with the addresses.
+	AnnouncedIP   string `json:"announced-ip"`             // AnnouncedIP specifies the IP address being announced for routing purposes.
+	AnnouncedIPv6 string `json:"announced-ipv6,omitempty"` // AnnouncedIPv6 specifies the IPv6 address announced together with an IPv4 AnnouncedIP for dual-stack services.
+	Pool          string `json:"pool,omitempty"`           // Pool names the VIP pool of the project the announced IP is allocated from; on create, an empty announced IP is allocated by the API server.
 }
 
 // Subnet represents a network subnet with an IP address and subnet mask.
@@ -50,14 +221,167 @@ type Subnet struct {
 	Mask uint8  `json:"mask"` // Mask represents the subnet mask as an unsigned 8-bit integer.
 }
 
+// MaxTotalNextHopWeight is the maximum allowed sum of the weights of all weighted next hops of an announcement.
+const MaxTotalNextHopWeight = 10000
+
+// WeightedNextHop represents a next hop with a weight proportional to the share of traffic it should receive.
+type WeightedNextHop struct {
+	Address string `json:"address"` // Address represents the IP address of the next hop.
+	Weight  uint32 `json:"weight"`  // Weight represents the relative capacity of the next hop; a higher weight attracts more traffic.
+}
+
+// HealthCheckType defines the kind of probe used to check a next hop.
+type HealthCheckType string
+
+const (
+	HealthCheckTCP  HealthCheckType = "tcp"  // HealthCheckTCP checks that a TCP connection to the port can be established.
+	HealthCheckHTTP HealthCheckType = "http" // HealthCheckHTTP checks that an HTTP request to the path returns a 2xx or 3xx status.
+	HealthCheckICMP HealthCheckType = "icmp" // HealthCheckICMP checks that the next hop answers ICMP echo requests.
+	HealthCheckGRPC HealthCheckType = "grpc" // HealthCheckGRPC checks that the grpc.health.v1.Health/Check call reports the service as serving.
+)
+
 // HealthCheck is a configuration for performing health checks on the next hop.
 type HealthCheck struct {
-	Path          string `json:"path"`         // Path specifies the endpoint to be used for the health check process.
-	Port          int    `json:"port"`         // Port specifies the port number to be used for the health check process.
-	Method        string `json:"method"`       // Method specifies the HTTP method to be used for the health check process.
-	CheckInterval int    `json:"interval"`     // CheckInterval specifies the interval in seconds between consecutive health check attempts.
-	Timeout       int    `json:"timeout"`      // Timeout specifies the duration in seconds before a health check request times out.
-	GracePeriod   int    `json:"grace-period"` // GracePeriod specifies the time in seconds to wait before marking the health check as failed after a disruption.
+	Type          HealthCheckType `json:"type,omitempty"` // Type specifies the probe; empty means HealthCheckHTTP when Path is set and HealthCheckTCP otherwise.
+	Path          string          `json:"path"`           // Path specifies the endpoint to be used for the health check process.
+	Port          int             `json:"port"`           // Port specifies the port number to be used for the health check process.
+	Method        string          `json:"method"`         // Method specifies the HTTP method to be used for the health check process.
+	CheckInterval int             `json:"interval"`       // CheckInterval specifies the interval in seconds between consecutive health check attempts.
+	Timeout       int             `json:"timeout"`        // Timeout specifies the duration in seconds before a health check request times out.
+	GracePeriod   int             `json:"grace-period"`   // GracePeriod specifies the time in seconds to wait before marking the health check as failed after a disruption.
+	Rise          int             `json:"rise,omitempty"` // Rise is the number of consecutive successful probes after which a next hop is healthy again.
+	Fall          int             `json:"fall,omitempty"` // Fall is the number of consecutive failed probes after which a next hop is unhealthy.
+
+	Service       string `json:"service,omitempty"`         // Service is the service name sent in gRPC health checks; empty checks the overall server health.
+	TLS           bool   `json:"tls,omitempty"`             // TLS enables TLS for the HTTP and gRPC health checks.
+	TLSServerName string `json:"tls-server-name,omitempty"` // TLSServerName is the name used to verify the certificate of the next hop; empty skips the verification.
+}
+
+// ProbeType returns the effective probe type, resolving the empty type.
+func (h HealthCheck) ProbeType() HealthCheckType {
+	switch {
+	case h.Type != "":
+		return h.Type
+	case h.Path != "":
+		return HealthCheckHTTP
+	default:
+		return HealthCheckTCP
+	}
+}
+
+// Enabled reports whether health checking is configured. ICMP probes need no port, the other probes are
+// disabled when the port is not set.
+func (h HealthCheck) Enabled() bool {
+	return h.Port != 0 || h.Type == HealthCheckICMP
+}
+
+// Schedule is the time window in which the routes of an announcement are programmed, e.g., for a time-boxed traffic
+// drain or a temporary test VIP. The updater withdraws the routes outside the window.
+type Schedule struct {
+	ActiveFrom  string `json:"active-from,omitempty"`  // ActiveFrom is the time in RFC 3339 format before which the routes are not programmed; empty means immediately.
+	ActiveUntil string `json:"active-until,omitempty"` // ActiveUntil is the time in RFC 3339 format at which the routes are withdrawn; empty means never.
+	TTL         int    `json:"ttl,omitempty"`          // TTL is the lifetime in seconds; every write of the announcement sets ActiveUntil to the TTL after the write or after ActiveFrom, if later.
+}
+
+// Window returns the times the routes are programmed from and until; a zero time means that the window is open on
+// that side. Malformed times are treated as unset, since they are rejected by the API server.
+func (s *Schedule) Window() (from, until time.Time) {
+	if s == nil {
+		return time.Time{}, time.Time{}
+	}
+	from, _ = time.Parse(time.RFC3339, s.ActiveFrom)
+	until, _ = time.Parse(time.RFC3339, s.ActiveUntil)
+	return from, until
+}
+
+// Active reports whether the time is within the schedule of the announcement. Announcements without a schedule are
+// always active.
+func (a *Announcement) Active(now time.Time) bool {
+	from, until := a.Schedule.Window()
+	return (from.IsZero() || !now.Before(from)) && (until.IsZero() || now.Before(until))
+}
+
+// NextTransition returns the next time after now at which the announcement enters or leaves its schedule, or the
+// zero time if it never does.
+func (a *Announcement) NextTransition(now time.Time) time.Time {
+	from, until := a.Schedule.Window()
+	switch {
+	case !from.IsZero() && now.Before(from):
+		return from
+	case !until.IsZero() && now.Before(until):
+		return until
+	default:
+		return time.Time{}
+	}
+}
+
+// Target returns the host:port address used to health check the next hop. IPv6 next hops are enclosed in brackets,
+// so that both address families of a dual-stack announcement are checked on their own addresses.
+func (h HealthCheck) Target(nextHop string) string {
+	return net.JoinHostPort(nextHop, strconv.Itoa(h.Port))
+}
+
+const (
+	StatusPending                  = "pending"                    // StatusPending is the status of an announcement that has not been programmed yet; an empty status means the same.
+	StatusProgrammed               = "programmed"                 // StatusProgrammed is the status of an announcement whose routes are programmed into GoBGP.
+	StatusFailed                   = "failed"                     // StatusFailed is the status of an announcement whose routes could not be programmed.
+	StatusCancelled                = "cancelled"                  // StatusCancelled is the status of a pending announcement abandoned by an operator; the updater ignores it.
+	StatusWithdrawn                = "withdrawn"                  // StatusWithdrawn is the status of a soft-deleted announcement that is kept for the retention period.
+	StatusExpired                  = "expired"                    // StatusExpired is set by the API server when the schedule of the announcement has ended; the routes are withdrawn.
+	StatusUnsupportedAddressFamily = "unsupported-address-family" // StatusUnsupportedAddressFamily is set by the updater when the address family of the announcement is not enabled.
+)
+
+const (
+	AddressFamilyIPv4Unicast = "ipv4-unicast" // AddressFamilyIPv4Unicast is the IPv4 unicast address family (AFI 1, SAFI 1).
+	AddressFamilyIPv6Unicast = "ipv6-unicast" // AddressFamilyIPv6Unicast is the IPv6 unicast address family (AFI 2, SAFI 1).
+)
+
+// AddressFamily returns the address family of the announced IP address, or an empty string if the address is invalid.
+func (a *Announcement) AddressFamily() string {
+	return AddressFamilyOf(a.Addresses.AnnouncedIP)
+}
+
+// AnnouncedAddresses returns the announced IP address followed by the announced IPv6 address of a dual-stack
+// announcement. Empty addresses are omitted.
+func (a *Announcement) AnnouncedAddresses() []string {
+	addresses := make([]string, 0, 2)
+	for _, address := range []string{a.Addresses.AnnouncedIP, a.Addresses.AnnouncedIPv6} {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// NextHopAddresses returns the addresses of the next hops followed by the addresses of the weighted next hops.
+func (a *Announcement) NextHopAddresses() []string {
+	addresses := make([]string, 0, len(a.NextHops)+len(a.WeightedNextHops))
+	for _, nextHop := range a.NextHops {
+		addresses = append(addresses, nextHop.IP)
+	}
+	for _, nextHop := range a.WeightedNextHops {
+		addresses = append(addresses, nextHop.Address)
+	}
+	return addresses
+}
+
+// AddressFamilyOf returns the unicast address family of the IP address, or an empty string if the address is invalid.
+func AddressFamilyOf(address string) string {
+	ip := net.ParseIP(address)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return AddressFamilyIPv4Unicast
+	default:
+		return AddressFamilyIPv6Unicast
+	}
+}
+
+// SameAddress reports whether both strings are the same valid IP address, regardless of their textual form.
+func SameAddress(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	return ipA != nil && ipA.Equal(ipB)
 }
 
 // Status represents the current state of an announcement with details and a timestamp.
@@ -65,13 +389,197 @@ type Status struct {
 	Status    string    `json:"status"`    // Status indicates the current operational state of the announcement.
 	Details   []Details `json:"details"`   // Details gives a detailed description of the status of the announcement.
 	Timestamp string    `json:"timestamp"` // Timestamp represents the time at which the status was recorded in ISO 8601 format.
+
+	Routers    []RouterStatus `json:"routers,omitempty"`    // Routers holds the programming state of the announcement on each GoBGP router of the updater.
+	Conditions []Condition    `json:"conditions,omitempty"` // Conditions holds the latest observations of the state of the announcement, one per type.
+	Draining   []string       `json:"draining,omitempty"`   // Draining lists the next hops drained by an operator; their paths are withdrawn while they are still health-checked.
+
+	ObservedGeneration int64   `json:"observed-generation,omitempty"` // ObservedGeneration is the latest generation programmed on all reported routers.
+	ProgrammedAt       string  `json:"programmed-at,omitempty"`       // ProgrammedAt is the time at which the observed generation was programmed on the last router in RFC 3339 format.
+	PropagationSeconds float64 `json:"propagation-seconds,omitempty"` // PropagationSeconds is the time in seconds from the write of the observed generation until it was programmed.
+}
+
+// Drained reports whether the next hop is draining. Addresses are compared as IP addresses, so that the textual
+// form of the next hop does not matter.
+func (s *Status) Drained(nextHop string) bool {
+	for _, address := range s.Draining {
+		if SameAddress(address, nextHop) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProgrammedGeneration returns the generation programmed on all reported routers, the lowest generation reported by
+// the routers. It returns zero when no router is reported or a router failed.
+func (s *Status) ProgrammedGeneration() int64 {
+	var generation int64
+	for i, router := range s.Routers {
+		if router.Status != StatusProgrammed {
+			return 0
+		}
+		if i == 0 || router.ObservedGeneration < generation {
+			generation = router.ObservedGeneration
+		}
+	}
+	return generation
+}
+
+// Types of the announcement status conditions.
+const (
+	ConditionProgrammed         = "Programmed"         // ConditionProgrammed is true when the routes are programmed on all GoBGP routers.
+	ConditionHealthCheckPassing = "HealthCheckPassing" // ConditionHealthCheckPassing is true when all health-checked next hops are healthy.
+	ConditionBFDSessionsUp      = "BFDSessionsUp"      // ConditionBFDSessionsUp is true when the BFD sessions with all required peers are up.
+)
+
+// Statuses of the announcement status conditions.
+const (
+	ConditionTrue    = "True"    // ConditionTrue means that the condition holds.
+	ConditionFalse   = "False"   // ConditionFalse means that the condition does not hold.
+	ConditionUnknown = "Unknown" // ConditionUnknown means that the controller cannot determine the condition.
+)
+
+// Condition is an observation of one aspect of the state of an announcement reported by a controller.
+type Condition struct {
+	Type               string `json:"type"`                 // Type is the observed aspect, e.g., ConditionProgrammed.
+	Status             string `json:"status"`               // Status is ConditionTrue, ConditionFalse or ConditionUnknown.
+	Reason             string `json:"reason,omitempty"`     // Reason is a CamelCase identifier of the cause of the last transition.
+	Message            string `json:"message,omitempty"`    // Message is a human-readable description of the cause.
+	LastTransitionTime string `json:"last-transition-time"` // LastTransitionTime is the time the status last changed in ISO 8601 format.
+}
+
+// Condition returns the condition of the type, or nil if the status has none.
+func (s *Status) Condition(conditionType string) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds the condition or replaces the condition of the same type. The last transition time of the
+// replaced condition is kept when its status does not change.
+func (s *Status) SetCondition(condition Condition) {
+	current := s.Condition(condition.Type)
+	if current == nil {
+		s.Conditions = append(s.Conditions, condition)
+		return
+	}
+	if current.Status == condition.Status && current.LastTransitionTime != "" {
+		condition.LastTransitionTime = current.LastTransitionTime
+	}
+	*current = condition
+}
+
+// RouterStatus is the programming state of an announcement on a single GoBGP router.
+type RouterStatus struct {
+	Router    string `json:"router"`         // Router is the name of the GoBGP router.
+	Site      string `json:"site,omitempty"` // Site is the site ID of the updater programming the router; the states reported by the updaters of other sites are kept.
+	Status    string `json:"status"`         // Status is StatusProgrammed or StatusFailed.
+	Message   string `json:"msg,omitempty"`  // Message describes the programming error of a failed router.
+	Timestamp string `json:"timestamp"`      // Timestamp represents the time at which the state was recorded in ISO 8601 format.
+
+	ObservedGeneration int64 `json:"observed-generation,omitempty"` // ObservedGeneration is the generation of the announcement programmed on the router; zero for a failed router.
 }
 
 // Details provides information about the health check results for a specific host, including its status and message.
 type Details struct {
-	Host      string `json:"host"`      // Host represents the address associated with the next hop.
-	Status    string `json:"status"`    // Status indicates the current health check result.
-	Code      int    `json:"code"`      // Code is the health check HTTP response status codes.
-	Message   string `json:"msg"`       // Message provides additional details or context about the health check result.
-	Timestamp string `json:"timestamp"` // Timestamp represents the time at which the status was recorded in ISO 8601 format.
+	Host                string  `json:"host"`                 // Host represents the address associated with the next hop.
+	Site                string  `json:"site,omitempty"`       // Site is the site ID of the updater probing the next hop; the results reported by the updaters of other sites are kept.
+	Status              string  `json:"status"`               // Status indicates the current health check result, e.g., HealthHealthy.
+	Code                int     `json:"code"`                 // Code is the health check HTTP response status codes.
+	Message             string  `json:"msg"`                  // Message provides additional details or context about the health check result.
+	Timestamp           string  `json:"timestamp"`            // Timestamp represents the time of the last probe in ISO 8601 format.
+	LatencyMS           float64 `json:"latency-ms"`           // LatencyMS is the duration of the last probe in milliseconds.
+	ConsecutiveFailures int     `json:"consecutive-failures"` // ConsecutiveFailures is the number of failed probes since the last successful one.
+}
+
+// Health check results of the next hops.
+const (
+	HealthHealthy   = "healthy"   // HealthHealthy means that the next hop passes its health check.
+	HealthUnhealthy = "unhealthy" // HealthUnhealthy means that the next hop fails its health check.
+	HealthDampened  = "dampened"  // HealthDampened means that the next hop is healthy but held withdrawn after flapping.
+)
+
+// AnnouncementHealth summarizes the health check results of the next hops of an announcement.
+type AnnouncementHealth struct {
+	Healthy  int       `json:"healthy"`   // Healthy is the number of next hops passing their health check.
+	Total    int       `json:"total"`     // Total is the number of health-checked next hops.
+	NextHops []Details `json:"next-hops"` // NextHops holds the latest health check result of every next hop.
+}
+
+// BatchAction defines the change applied to an announcement by a batch operation.
+type BatchAction string
+
+const (
+	BatchApply  BatchAction = "apply"  // BatchApply creates the announcement or replaces the stored one.
+	BatchDelete BatchAction = "delete" // BatchDelete removes the announcement; it must exist.
+)
+
+// BatchOperation is a single item of a batch request.
+type BatchOperation struct {
+	Action       BatchAction  `json:"action"`       // Action specifies whether the announcement is applied or deleted.
+	Announcement Announcement `json:"announcement"` // Announcement is the desired state; only the meta is used for deletions.
+}
+
+// BatchRequest is the body of a request applying several announcement changes atomically.
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"` // Operations lists the changes, at most one per announcement.
+}
+
+// BatchItemResult reports the outcome of a single batch operation.
+type BatchItemResult struct {
+	Project string    `json:"project"`         // Project is the project of the announcement.
+	Name    string    `json:"name"`            // Name is the name of the announcement.
+	Type    EventType `json:"type,omitempty"`  // Type is the change made to the announcement: added, updated or deleted.
+	Error   string    `json:"error,omitempty"` // Error describes why the operation was rejected; empty on success.
+}
+
+// ApplyRequest is the body of a declarative apply request.
+type ApplyRequest struct {
+	Manager      string          `json:"manager"`      // Manager identifies the controller or user owning the applied fields.
+	Announcement json.RawMessage `json:"announcement"` // Announcement is the partial announcement with the fields managed by the manager.
+}
+
+// AnnouncementQuery is the body of a request that filters the announcements of a project with a JMESPath expression.
+type AnnouncementQuery struct {
+	Expr string `json:"expr"` // Expr is the JMESPath expression; announcements for which it yields a truthy value match.
+}
+
+// Project is the resource holding the metadata and the quota of a project. Announcements of projects without the
+// resource are admitted without a quota.
+type Project struct {
+	Name        string       `json:"name"`                  // Name is the project name used in the announcement metadata.
+	Description string       `json:"description,omitempty"` // Description is a free-form description of the project.
+	Owners      []string     `json:"owners,omitempty"`      // Owners lists the people or teams responsible for the project.
+	Quota       ProjectQuota `json:"quota"`                 // Quota limits the announcements of the project.
+}
+
+// ProjectQuota limits the announcements of a project. The zero value imposes no limits.
+type ProjectQuota struct {
+	MaxAnnouncements int      `json:"max-announcements,omitempty"` // MaxAnnouncements is the maximum number of announcements, withdrawn ones excluded; zero means no limit.
+	AllowedPrefixes  []string `json:"allowed-prefixes,omitempty"`  // AllowedPrefixes lists the prefixes in CIDR notation the announced prefixes must be within; empty allows any prefix.
+}
+
+// Pool is a set of prefixes of a project from which the API server allocates the announced IPs of the announcements
+// created with the pool name. An address is allocated while an announcement of the project, withdrawn ones included,
+// announces it, so it is released when the announcement is deleted.
+type Pool struct {
+	Name        string            `json:"name"`                  // Name is the pool name used in the announcement addresses.
+	Project     string            `json:"project"`               // Project is the project whose announcements allocate from the pool.
+	Description string            `json:"description,omitempty"` // Description is a free-form description of the pool.
+	CIDRs       []string          `json:"cidrs"`                 // CIDRs lists the prefixes of the pool in CIDR notation.
+	Allocations map[string]string `json:"allocations,omitempty"` // Allocations maps the allocated addresses to the names of the announcements; set by the API server in responses.
+	Free        uint64            `json:"free"`                  // Free is the number of addresses left to allocate, capped at the maximum uint64; set by the API server in responses.
+}
+
+// ProjectSummary contains aggregate statistics of the announcements of a project.
+type ProjectSummary struct {
+	TotalAnnouncements int       `json:"total-announcements"` // TotalAnnouncements is the number of announcements in the project.
+	ProgrammedCount    int       `json:"programmed-count"`    // ProgrammedCount is the number of announcements with the StatusProgrammed status.
+	FailedCount        int       `json:"failed-count"`        // FailedCount is the number of announcements that could not be programmed.
+	PendingCount       int       `json:"pending-count"`       // PendingCount is the number of announcements that are not programmed yet.
+	OldestAnnouncement time.Time `json:"oldest-announcement"` // OldestAnnouncement is the earliest creation time among the announcements.
+	NewestAnnouncement time.Time `json:"newest-announcement"` // NewestAnnouncement is the latest creation time among the announcements.
 }
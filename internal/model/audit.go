@@ -0,0 +1,46 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction defines the kind of change recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create" // AuditCreate records the creation of an announcement.
+	AuditUpdate AuditAction = "update" // AuditUpdate records any change of a stored announcement, including its status.
+	AuditDelete AuditAction = "delete" // AuditDelete records the removal of an announcement.
+)
+
+// AuditEntry is a single record of the append-only audit log of the announcement changes.
+type AuditEntry struct {
+	ID              string        `json:"id"`                         // ID orders the entries by time and is unique across API server replicas.
+	Timestamp       time.Time     `json:"timestamp"`                  // Timestamp is the time the change was written.
+	Action          AuditAction   `json:"action"`                     // Action is the kind of the change.
+	Project         string        `json:"project"`                    // Project is the project of the changed announcement.
+	Name            string        `json:"name"`                       // Name is the name of the changed announcement.
+	ResourceVersion string        `json:"resource-version,omitempty"` // ResourceVersion is the resource version written by the change; empty for deletions.
+	Actor           string        `json:"actor,omitempty"`            // Actor is the authenticated subject, or the server component making the change; empty without authentication.
+	Token           string        `json:"token,omitempty"`            // Token is the fingerprint of the bearer token of the request, never the token itself.
+	RemoteAddr      string        `json:"remote-addr,omitempty"`      // RemoteAddr is the client IP address.
+	RequestID       string        `json:"request-id,omitempty"`       // RequestID is the value of the X-Request-ID header, if any.
+	Method          string        `json:"method,omitempty"`           // Method is the HTTP method of the request.
+	Path            string        `json:"path,omitempty"`             // Path is the URL path of the request.
+	Changes         []AuditChange `json:"changes"`                    // Changes lists the changed fields of the announcement.
+}
+
+// AuditChange is the change of a single announcement field. Nested objects are compared field by field, lists and
+// scalar values as a whole.
+type AuditChange struct {
+	Field  string          `json:"field"`            // Field is the dot-separated path of the field, e.g., "status.status".
+	Before json.RawMessage `json:"before,omitempty"` // Before is the JSON value before the change; empty if the field was not set.
+	After  json.RawMessage `json:"after,omitempty"`  // After is the JSON value after the change; empty if the field was removed.
+}
+
+// AuditList is a single page of audit entries ordered from the oldest to the newest.
+type AuditList struct {
+	Items    []AuditEntry `json:"items"`              // Items contains the entries of the page.
+	Continue string       `json:"continue,omitempty"` // Continue is the opaque token to request the next page; empty on the last page.
+}
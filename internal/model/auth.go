@@ -0,0 +1,47 @@
+package model
+
+// Role defines the access level granted to a subject within a project.
+type Role string
+
+const (
+	RoleRead       Role = "read"       // RoleRead allows listing, getting, querying and watching announcements.
+	RoleWrite      Role = "write"      // RoleWrite allows everything RoleRead does and modifying announcements.
+	RoleController Role = "controller" // RoleController allows everything RoleWrite does and reporting the announcement status.
+)
+
+// AllProjects is the project of a role binding that applies to every project and to the cluster-wide endpoints.
+const AllProjects = "*"
+
+// Allows reports whether the role grants the required access level. RoleController implies RoleWrite, which
+// implies RoleRead.
+func (r Role) Allows(required Role) bool {
+	switch r {
+	case RoleController:
+		return required == RoleRead || required == RoleWrite || required == RoleController
+	case RoleWrite:
+		return required == RoleRead || required == RoleWrite
+	case RoleRead:
+		return required == RoleRead
+	default:
+		return false
+	}
+}
+
+// AuthPolicy is the content of the API server authorization policy file.
+type AuthPolicy struct {
+	Tokens   []StaticToken `json:"tokens"`   // Tokens lists the static API tokens accepted by the server.
+	Bindings []RoleBinding `json:"bindings"` // Bindings grants roles to subjects authenticated by a static token or an OIDC token.
+}
+
+// StaticToken maps a static API token to the subject it authenticates.
+type StaticToken struct {
+	Token   string `json:"token"`   // Token is the secret value sent in the Authorization: Bearer header.
+	Subject string `json:"subject"` // Subject is the identity of the token holder used in role bindings.
+}
+
+// RoleBinding grants a role within a project to a subject.
+type RoleBinding struct {
+	Subject string `json:"subject"` // Subject is the static token subject or the value of the configured OIDC claim.
+	Project string `json:"project"` // Project is the project name, or AllProjects to grant the role everywhere.
+	Role    Role   `json:"role"`    // Role is the granted access level.
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// BundleVersion is the format version of the exported bundles.
+const BundleVersion = "corebgp/v1"
+
+// Bundle is a snapshot of the resources of the API server, exported to restore the control plane after the loss of
+// the datastore.
+type Bundle struct {
+	Version       string         `json:"version"`            // Version is the format version of the bundle, BundleVersion.
+	ExportedAt    time.Time      `json:"exported-at"`        // ExportedAt is the time of the export.
+	Project       string         `json:"project,omitempty"`  // Project is the exported project; empty for a bundle of all projects.
+	Revision      int64          `json:"revision"`           // Revision is the highest resource version of the exported announcements.
+	Projects      []Project      `json:"projects,omitempty"` // Projects are the project resources of the exported projects.
+	Peers         []Peer         `json:"peers,omitempty"`    // Peers are exported with all projects only, since they are not bound to a project.
+	Announcements []Announcement `json:"announcements"`      // Announcements are exported with their status and resource version.
+}
+
+// ImportStrategy defines how an import treats the resources that already exist.
+type ImportStrategy string
+
+const (
+	ImportSkip      ImportStrategy = "skip"      // ImportSkip keeps the existing resources and imports the others.
+	ImportOverwrite ImportStrategy = "overwrite" // ImportOverwrite replaces the existing resources with those of the bundle.
+	ImportFail      ImportStrategy = "fail"      // ImportFail rejects the whole import if any resource exists.
+)
+
+// Valid reports whether the strategy is one of the defined values.
+func (s ImportStrategy) Valid() bool {
+	switch s {
+	case ImportSkip, ImportOverwrite, ImportFail:
+		return true
+	default:
+		return false
+	}
+}
+
+// ImportReport lists the resources of an imported bundle by the outcome. A resource is identified by its kind and
+// name, e.g., "announcements/project/name", "projects/name" or "peers/192.0.2.1".
+type ImportReport struct {
+	Created     []string `json:"created"`     // Created lists the resources that did not exist.
+	Overwritten []string `json:"overwritten"` // Overwritten lists the existing resources replaced with the strategy overwrite.
+	Skipped     []string `json:"skipped"`     // Skipped lists the existing resources kept with the strategy skip.
+	Conflicts   []string `json:"conflicts"`   // Conflicts lists the existing resources that made an import with the strategy fail fail.
+}
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// CommunityType defines the kind of BGP community.
+type CommunityType int
+
+const (
+	CommunityStandard CommunityType = iota // CommunityStandard is a standard community as defined in RFC 1997 (ASN:VALUE).
+	CommunityExtended                      // CommunityExtended is an extended community as defined in RFC 4360 (rt:X:Y or soo:X:Y).
+	CommunityLarge                         // CommunityLarge is a large community as defined in RFC 8092 (GLOBAL:LOCAL1:LOCAL2).
+)
+
+const (
+	ExtendedSubTypeRouteTarget uint32 = 0x02 // ExtendedSubTypeRouteTarget is the sub-type of the route target extended community.
+	ExtendedSubTypeRouteOrigin uint32 = 0x03 // ExtendedSubTypeRouteOrigin is the sub-type of the route origin (site of origin) extended community.
+)
+
+// wellKnownCommunities maps the names of well-known standard communities to their values.
+var wellKnownCommunities = map[string]uint32{
+	"graceful-shutdown":   0xFFFF0000,
+	"blackhole":           0xFFFF029A,
+	"no-export":           0xFFFFFF01,
+	"no-advertise":        0xFFFFFF02,
+	"no-export-subconfed": 0xFFFFFF03,
+	"no-peer":             0xFFFFFF04,
+}
+
+// Community is a parsed BGP community of any type.
+type Community struct {
+	Type CommunityType // Type is the kind of the community.
+
+	Value uint32 // Value is the 32-bit value of a standard community.
+
+	SubType    uint32 // SubType is the sub-type of an extended community, e.g., ExtendedSubTypeRouteTarget.
+	ASN        uint32 // ASN is the global administrator of an AS-specific extended community.
+	IPv4       string // IPv4 is the global administrator of an IPv4-address-specific extended community.
+	LocalAdmin uint32 // LocalAdmin is the local administrator of an extended community.
+
+	GlobalAdmin uint32 // GlobalAdmin is the global administrator of a large community.
+	LocalData1  uint32 // LocalData1 is the first local data part of a large community.
+	LocalData2  uint32 // LocalData2 is the second local data part of a large community.
+}
+
+// ParseCommunity parses the textual community representation.
+// Supported formats: "ASN:VALUE" and well-known names (standard), "rt:X:Y" and "soo:X:Y" where X is an ASN or
+// an IPv4 address (extended), and "GLOBAL:LOCAL1:LOCAL2" (large).
+func ParseCommunity(s string) (Community, error) {
+	if value, ok := wellKnownCommunities[strings.ToLower(s)]; ok {
+		return Community{Type: CommunityStandard, Value: value}, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) == 3 {
+		switch strings.ToLower(parts[0]) {
+		case "rt":
+			return parseExtendedCommunity(ExtendedSubTypeRouteTarget, parts[1], parts[2], s)
+		case "soo":
+			return parseExtendedCommunity(ExtendedSubTypeRouteOrigin, parts[1], parts[2], s)
+		}
+	}
+
+	switch len(parts) {
+	case 2:
+		asn, err := parseUint(parts[0], 16)
+		if err != nil {
+			return Community{}, fmt.Errorf("invalid community %q: %w", s, err)
+		}
+		value, err := parseUint(parts[1], 16)
+		if err != nil {
+			return Community{}, fmt.Errorf("invalid community %q: %w", s, err)
+		}
+		return Community{Type: CommunityStandard, Value: asn<<16 | value}, nil
+
+	case 3:
+		var fields [3]uint32
+		for i, part := range parts {
+			value, err := parseUint(part, 32)
+			if err != nil {
+				return Community{}, fmt.Errorf("invalid large community %q: %w", s, err)
+			}
+			fields[i] = value
+		}
+		return Community{Type: CommunityLarge, GlobalAdmin: fields[0], LocalData1: fields[1], LocalData2: fields[2]}, nil
+
+	default:
+		return Community{}, fmt.Errorf("invalid community %q: unknown format", s)
+	}
+}
+
+// String returns the textual representation of the community accepted by ParseCommunity. Well-known standard
+// communities are returned by name.
+func (c Community) String() string {
+	switch c.Type {
+	case CommunityStandard:
+		for name, value := range wellKnownCommunities {
+			if value == c.Value {
+				return name
+			}
+		}
+		return fmt.Sprintf("%d:%d", c.Value>>16, c.Value&0xFFFF)
+	case CommunityLarge:
+		return fmt.Sprintf("%d:%d:%d", c.GlobalAdmin, c.LocalData1, c.LocalData2)
+	default:
+		prefix := "rt"
+		if c.SubType == ExtendedSubTypeRouteOrigin {
+			prefix = "soo"
+		}
+		if c.IPv4 != "" {
+			return fmt.Sprintf("%s:%s:%d", prefix, c.IPv4, c.LocalAdmin)
+		}
+		return fmt.Sprintf("%s:%d:%d", prefix, c.ASN, c.LocalAdmin)
+	}
+}
+
+// parseExtendedCommunity parses the global and local administrator parts of an extended community.
+func parseExtendedCommunity(subType uint32, global, local, s string) (Community, error) {
+	community := Community{Type: CommunityExtended, SubType: subType}
+
+	if ip := net.ParseIP(global); ip != nil {
+		if ip.To4() == nil {
+			return Community{}, fmt.Errorf("invalid extended community %q: only IPv4 addresses are supported", s)
+		}
+		community.IPv4 = global
+		localAdmin, err := parseUint(local, 16)
+		if err != nil {
+			return Community{}, fmt.Errorf("invalid extended community %q: %w", s, err)
+		}
+		community.LocalAdmin = localAdmin
+		return community, nil
+	}
+
+	asn, err := parseUint(global, 32)
+	if err != nil {
+		return Community{}, fmt.Errorf("invalid extended community %q: %w", s, err)
+	}
+	// Two-octet ASNs have a 32-bit local administrator, four-octet ASNs have a 16-bit one
+	localBits := 32
+	if asn > 0xFFFF {
+		localBits = 16
+	}
+	localAdmin, err := parseUint(local, localBits)
+	if err != nil {
+		return Community{}, fmt.Errorf("invalid extended community %q: %w", s, err)
+	}
+	community.ASN = asn
+	community.LocalAdmin = localAdmin
+	return community, nil
+}
+
+// parseUint parses an unsigned decimal number that fits into the given number of bits.
+func parseUint(s string, bits int) (uint32, error) {
+	value, err := strconv.ParseUint(s, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid %d-bit unsigned number", s, bits)
+	}
+	return uint32(value), nil
+}
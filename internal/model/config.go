@@ -1,14 +1,62 @@
 package model
 
+import "time"
+
 // APIConfig represents the configuration parameters required to initialize and run the API server.
 type APIConfig struct {
-	DBType    string   `yaml:"db_type"`   // DBType specifies the type of database to be used, e.g., "etcd".
-	Endpoints []string `yaml:"endpoints"` // Endpoints defines the list of database endpoint URLs for connecting the API server to the database backend.
-	Etcd      Etcd     `yaml:"etcd"`      // Etcd contains the configuration details needed to connect to an Etcd cluster.
-	TLSCert   string   `yaml:"tls_cert"`  // TLSCert specifies the file path to the TLS certificate used for securing API server communication.
-	TLSKey    string   `yaml:"tls_key"`   // TLSKey specifies the file path to the TLS private key used for securing API server communication.
-	LogPath   string   `yaml:"log_path"`  // LogPath specifies the file path to the log file for storing API server logs.
-	Verbose   int8     `yaml:"verbose"`   // Verbose specifies the verbosity level for logging, where higher values produce more detailed logs.
+	DBType      string   `yaml:"db_type"`       // DBType specifies the type of database to be used: "etcd", the embedded "bolt" or "memory".
+	DBPath      string   `yaml:"db_path"`       // DBPath specifies the file of the embedded bolt database.
+	Endpoints   []string `yaml:"endpoints"`     // Endpoints defines the list of database endpoint URLs for connecting the API server to the database backend.
+	Etcd        Etcd     `yaml:"etcd"`          // Etcd contains the configuration details needed to connect to an Etcd cluster.
+	TLSCert     string   `yaml:"tls_cert"`      // TLSCert specifies the file path to the TLS certificate used for securing API server communication.
+	TLSKey      string   `yaml:"tls_key"`       // TLSKey specifies the file path to the TLS private key used for securing API server communication.
+	TLSClientCA string   `yaml:"tls_client_ca"` // TLSClientCA specifies the file path to the CA certificate used to verify client certificates; non-empty enables mutual TLS.
+	LogPath     string   `yaml:"log_path"`      // LogPath specifies the file path to the log file for storing API server logs, or "stdout" or "stderr".
+	Verbose     int8     `yaml:"verbose"`       // Verbose specifies the verbosity level for logging, where higher values produce more detailed logs.
+
+	LogFormat     string `yaml:"log_format"`      // LogFormat specifies the log output format: "json" or "console".
+	LogMaxSize    int    `yaml:"log_max_size"`    // LogMaxSize specifies the size in megabytes at which the log file is rotated.
+	LogMaxBackups int    `yaml:"log_max_backups"` // LogMaxBackups specifies the number of rotated log files to keep.
+
+	GoBGPEndpoint   string `yaml:"gobgp_endpoint"`    // GoBGPEndpoint specifies the URL to the GoBGP API; empty disables the GoBGP endpoints.
+	GoBGPCACert     string `yaml:"gobgp_ca_cert"`     // GoBGPCACert specifies the path to the GoBGP CA certificate file.
+	GoBGPClientCert string `yaml:"gobgp_client_cert"` // GoBGPClientCert specifies the path to the GoBGP client certificate file.
+	GoBGPClientKey  string `yaml:"gobgp_client_key"`  // GoBGPClientKey specifies the path to the GoBGP client key file.
+
+	WithdrawnRetention time.Duration `yaml:"withdrawn_retention"` // WithdrawnRetention specifies how long soft-deleted announcements are kept; zero keeps them forever.
+	HistoryRevisions   int           `yaml:"history_revisions"`   // HistoryRevisions specifies the number of revisions kept in the history of every announcement; zero disables the history.
+	AnnouncementEvents int           `yaml:"announcement_events"` // AnnouncementEvents specifies the number of events kept for every announcement; zero disables the events.
+	GRPCAddr           string        `yaml:"grpc_addr"`           // GRPCAddr specifies the address of the separate listener serving the gRPC API; empty disables it.
+	SwaggerUI          bool          `yaml:"swagger_ui"`          // SwaggerUI enables the Swagger UI page rendering the OpenAPI document at /openapi/ui.
+	PprofAddr          string        `yaml:"pprof_addr"`          // PprofAddr specifies the address of the separate listener serving pprof handlers; empty disables profiling.
+	AccessLogPath      string        `yaml:"access_log_path"`     // AccessLogPath specifies the destination of the JSON access log: a file path, "stdout" or "stderr"; empty disables it.
+	QueryTimeout       time.Duration `yaml:"query_timeout"`       // QueryTimeout specifies the maximum evaluation time of an announcement query expression.
+	OTLPEndpoint       string        `yaml:"otlp_endpoint"`       // OTLPEndpoint specifies the OTLP/HTTP collector URL receiving the traces; empty disables tracing.
+
+	AuthPolicyFile   string `yaml:"auth_policy_file"`   // AuthPolicyFile specifies the path to the JSON file with static tokens and role bindings; empty disables authentication.
+	OIDCIssuerURL    string `yaml:"oidc_issuer_url"`    // OIDCIssuerURL specifies the issuer of accepted OIDC bearer tokens; empty disables OIDC.
+	OIDCClientID     string `yaml:"oidc_client_id"`     // OIDCClientID specifies the expected audience of OIDC tokens.
+	OIDCSubjectClaim string `yaml:"oidc_subject_claim"` // OIDCSubjectClaim specifies the token claim used as the subject in role bindings, e.g., "email".
+
+	MaxAnnouncementNameLength int    `yaml:"max_announcement_name_length"` // MaxAnnouncementNameLength specifies the maximum length of an announcement name; non-positive disables the limit.
+	PrefixPolicyFile          string `yaml:"prefix_policy_file"`           // PrefixPolicyFile specifies the path to the JSON file with the global and per-project prefix rules; empty admits any prefix.
+	WebhooksFile              string `yaml:"webhooks_file"`                // WebhooksFile specifies the path to the JSON file with the webhooks notified of announcement lifecycle events; empty disables the notifications.
+	AdmissionWebhooksFile     string `yaml:"admission_webhooks_file"`      // AdmissionWebhooksFile specifies the path to the JSON file with the webhooks mutating and validating the created and updated announcements; empty disables them.
+	CrossProjectConflicts     string `yaml:"cross_project_conflicts"`      // CrossProjectConflicts specifies the admission of announcements overlapping prefixes of other projects: "off", "warn" or "reject".
+
+	TrustedProxies []string `yaml:"trusted_proxies"` // TrustedProxies lists the addresses and CIDR ranges of the reverse proxies whose X-Forwarded-For and X-Real-IP headers are trusted; empty trusts none and identifies clients by the peer address.
+
+	RateLimit           float64 `yaml:"rate_limit"`            // RateLimit specifies the number of requests per second allowed per client, identified by its authenticated subject or, without authentication, its IP address; zero disables the limit.
+	RateLimitBurst      int     `yaml:"rate_limit_burst"`      // RateLimitBurst specifies the number of requests a client may send at once above the rate limit.
+	IPRateLimit         float64 `yaml:"ip_rate_limit"`         // IPRateLimit specifies the number of requests per second allowed per IP address before authentication; zero disables the limit.
+	IPRateLimitBurst    int     `yaml:"ip_rate_limit_burst"`   // IPRateLimitBurst specifies the number of requests an IP address may send at once above the IP rate limit.
+	MaxInflightRequests int     `yaml:"max_inflight_requests"` // MaxInflightRequests specifies the maximum number of requests served concurrently, watches excluded; zero disables the limit.
+
+	MaxRequestBodySize int64 `yaml:"max_request_body_size"` // MaxRequestBodySize specifies the maximum size of a request body in bytes; zero disables the limit.
+	MaxListSize        int   `yaml:"max_list_size"`         // MaxListSize specifies the maximum number of announcements in an unpaginated list response; zero disables the limit.
+	StrictDecoding     bool  `yaml:"strict_decoding"`       // StrictDecoding rejects the announcement request bodies with fields unknown to the API instead of ignoring them.
+
+	WatchHeartbeatInterval time.Duration `yaml:"watch_heartbeat_interval"` // WatchHeartbeatInterval specifies the interval of the bookmarks and pings sent on watches; zero disables them.
 }
 
 // Etcd is a configuration structure used for specifying Etcd cluster connection parameters.
@@ -16,15 +64,175 @@ type Etcd struct {
 	CACert     string `yaml:"ca_cert"`     // CACert specifies the file path to the CA certificate to establish secure communication with the Etcd cluster.
 	ClientCert string `yaml:"client_cert"` // ClientCert specifies the file path to the client certificate for authenticating with the Etcd cluster.
 	ClientKey  string `yaml:"client_key"`  // ClientKey specifies the file path to the client private key used for authenticating with the Etcd cluster.
+
+	Username     string `yaml:"username"`      // Username specifies the user authenticating to the Etcd cluster; empty disables authentication.
+	PasswordFile string `yaml:"password_file"` // PasswordFile specifies the path to the file with the password of the user.
+	KeyPrefix    string `yaml:"key_prefix"`    // KeyPrefix specifies the prefix of all keys, so that several CoreBGP instances can share an Etcd cluster.
+
+	RequestTimeout time.Duration `yaml:"request_timeout"` // RequestTimeout specifies the deadline of every request to the Etcd cluster.
+	MaxRetries     int           `yaml:"max_retries"`     // MaxRetries specifies how often a request failing with a transient error is retried; zero disables retries.
 }
 
+const (
+	ConflictsOff    = "off"    // ConflictsOff admits announcements overlapping prefixes of other projects silently.
+	ConflictsWarn   = "warn"   // ConflictsWarn admits announcements overlapping prefixes of other projects and logs a warning.
+	ConflictsReject = "reject" // ConflictsReject rejects announcements overlapping prefixes of other projects.
+)
+
+const (
+	WeightEncodingLinkBandwidth = "link-bandwidth" // WeightEncodingLinkBandwidth attaches a link bandwidth extended community proportional to the weight to every weighted path.
+	WeightEncodingMED           = "med"            // WeightEncodingMED derives the MED of every weighted path from the weight, so that the heaviest next hop is preferred.
+)
+
+const (
+	BGPBackendGoBGP  = "gobgp"  // BGPBackendGoBGP programs the paths into GoBGP daemons through their gRPC API.
+	BGPBackendFRR    = "frr"    // BGPBackendFRR programs the paths into the local FRRouting daemon through vtysh.
+	BGPBackendKernel = "kernel" // BGPBackendKernel installs the paths as routes into the Linux kernel routing table through netlink.
+)
+
 // UpdaterConfig represents the configuration parameters required to initialize and run the Updater controller.
 type UpdaterConfig struct {
 	APIEndpoint     string `yaml:"api_endpoint"`      // APIEndpoint specifies the URL to the API server endpoint.
+	APICACert       string `yaml:"api_ca_cert"`       // APICACert specifies the path to the CA certificate used to verify the API server.
+	APIClientCert   string `yaml:"api_client_cert"`   // APIClientCert specifies the path to the client certificate presented to the API server.
+	APIClientKey    string `yaml:"api_client_key"`    // APIClientKey specifies the path to the client key presented to the API server.
+	APIInsecure     bool   `yaml:"api_insecure"`      // APIInsecure disables the verification of the API server certificate.
+	APITokenFile    string `yaml:"api_token_file"`    // APITokenFile specifies the path to the file with the bearer token used to authenticate to the API server.
 	GoBGPEndpoint   string `yaml:"gobgp_endpoint"`    // GoBGPEndpoint specifies the URL to the GoBGP API.
 	GoBGPCACert     string `yaml:"gobgp_ca_cert"`     // GoBGPCACert specifies the path to the GoBGP CA certificate file.
 	GoBGPClientCert string `yaml:"gobgp_client_cert"` // GoBGPClientCert specifies the path to the GoBGP client certificate file.
 	GoBGPClientKey  string `yaml:"gobgp_client_key"`  // GoBGPClientKey specifies the path to the GoBGP client key file.
-	LogPath         string `yaml:"log_path"`          // LogPath specifies the file path to the log file for storing updater logs.
+	LogPath         string `yaml:"log_path"`          // LogPath specifies the file path to the log file for storing updater logs, or "stdout" or "stderr".
+	MetricsAddress  string `yaml:"metrics_address"`   // MetricsAddress specifies the listen address of the Prometheus metrics endpoint; empty disables it.
+	OTLPEndpoint    string `yaml:"otlp_endpoint"`     // OTLPEndpoint specifies the OTLP/HTTP collector URL receiving the traces; empty disables tracing.
 	Verbose         int8   `yaml:"verbose"`           // Verbose specifies the verbosity level for logging, where higher values produce more detailed logs.
+	LogFormat       string `yaml:"log_format"`        // LogFormat specifies the log output format: "json" or "console".
+	LogMaxSize      int    `yaml:"log_max_size"`      // LogMaxSize specifies the size in megabytes at which the log file is rotated.
+	LogMaxBackups   int    `yaml:"log_max_backups"`   // LogMaxBackups specifies the number of rotated log files to keep.
+
+	GoBGPRouters []GoBGPRouter `yaml:"gobgp_routers"` // GoBGPRouters lists the GoBGP daemons programmed in parallel; empty programs only GoBGPEndpoint.
+
+	BGPBackend   string `yaml:"bgp_backend"`    // BGPBackend specifies where the paths are programmed, BGPBackendGoBGP, BGPBackendFRR or BGPBackendKernel.
+	FRRVtyshPath string `yaml:"frr_vtysh_path"` // FRRVtyshPath specifies the vtysh binary used to configure FRR when BGPBackend is BGPBackendFRR.
+	KernelTable  uint32 `yaml:"kernel_table"`   // KernelTable specifies the kernel routing table the routes are installed into when BGPBackend is BGPBackendKernel.
+	KernelMetric uint32 `yaml:"kernel_metric"`  // KernelMetric specifies the metric of the installed kernel routes, which distinguishes them from the routes of other origins.
+
+	EnabledAddressFamilies []string      `yaml:"enabled_address_families"` // EnabledAddressFamilies lists the address families the updater programs, e.g., "ipv4-unicast".
+	NextHopWeightEncoding  string        `yaml:"next_hop_weight_encoding"` // NextHopWeightEncoding specifies how the weights of the weighted next hops are programmed, WeightEncodingLinkBandwidth or WeightEncodingMED.
+	ResyncInterval         time.Duration `yaml:"resync_interval"`          // ResyncInterval specifies how often the GoBGP RIB is fully reconciled with the announcements; zero disables it.
+
+	WithdrawOnShutdown  bool          `yaml:"withdraw_on_shutdown"`  // WithdrawOnShutdown enables withdrawing all programmed paths when the updater shuts down.
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period"` // ShutdownGracePeriod specifies how long the updater waits after withdrawing the paths before it exits.
+
+	ConfigureGoBGPGlobal bool              `yaml:"gobgp_configure_global"` // ConfigureGoBGPGlobal enables configuring the GoBGP global parameters via the API server on startup.
+	GoBGPGlobal          GoBGPGlobalConfig `yaml:"gobgp_global"`           // GoBGPGlobal contains the global parameters applied when ConfigureGoBGPGlobal is set.
+
+	ManagePeers      bool          `yaml:"manage_peers"`       // ManagePeers enables configuring the BGP neighbors of the routers from the peer resources; neighbors without a resource are removed.
+	PeerSyncInterval time.Duration `yaml:"peer_sync_interval"` // PeerSyncInterval specifies how often the neighbors are synced and their session states are reported.
+
+	Site string `yaml:"site"` // Site specifies the site ID the updater registers with and whose overrides of the announcements it applies; empty ignores the overrides.
+
+	LeaderElection LeaderElection `yaml:"leader_election"` // LeaderElection contains the settings of the election of the active updater among the replicas.
+	BFD            BFD            `yaml:"bfd"`             // BFD contains the settings of the BFD sessions that gate the announcements declaring BFD peers.
+	Dampening      Dampening      `yaml:"dampening"`       // Dampening contains the settings holding back the routes via next hops with flapping health checks.
+
+	HealthCheckDefaults  HealthCheckDefaults `yaml:"health_check_defaults"`  // HealthCheckDefaults contains the health check parameters that the announcements do not set.
+	HealthReportInterval time.Duration       `yaml:"health_report_interval"` // HealthReportInterval specifies how often the latest probe results of the next hops are reported; zero reports only health changes.
+}
+
+// OperatorConfig represents the configuration parameters required to run the Kubernetes operator that syncs the
+// Announcement custom resources to the API server.
+type OperatorConfig struct {
+	APIEndpoint   string `yaml:"api_endpoint"`    // APIEndpoint specifies the URL to the API server endpoint.
+	APICACert     string `yaml:"api_ca_cert"`     // APICACert specifies the path to the CA certificate used to verify the API server.
+	APIClientCert string `yaml:"api_client_cert"` // APIClientCert specifies the path to the client certificate presented to the API server.
+	APIClientKey  string `yaml:"api_client_key"`  // APIClientKey specifies the path to the client key presented to the API server.
+	APIInsecure   bool   `yaml:"api_insecure"`    // APIInsecure disables the verification of the API server certificate.
+	APITokenFile  string `yaml:"api_token_file"`  // APITokenFile specifies the path to the file with the bearer token used to authenticate to the API server.
+
+	KubeAPIServer string `yaml:"kube_api_server"` // KubeAPIServer specifies the URL of the Kubernetes API server; empty uses the in-cluster service.
+	KubeCACert    string `yaml:"kube_ca_cert"`    // KubeCACert specifies the path to the CA certificate of the Kubernetes API server.
+	KubeTokenFile string `yaml:"kube_token_file"` // KubeTokenFile specifies the path to the bearer token of the Kubernetes API server, re-read on every request.
+	Namespace     string `yaml:"namespace"`       // Namespace restricts the watched resources to a single namespace; empty watches all namespaces.
+
+	Manager        string        `yaml:"manager"`         // Manager specifies the name under which the resources are applied to the API server.
+	ResyncInterval time.Duration `yaml:"resync_interval"` // ResyncInterval specifies how often all resources are applied again to correct drift; zero disables it.
+
+	AnnouncementResources bool     `yaml:"announcement_resources"` // AnnouncementResources enables the sync of the Announcement custom resources.
+	ServiceLB             bool     `yaml:"service_lb"`             // ServiceLB enables the controller announcing VIPs for the Services of type LoadBalancer.
+	ServiceLBPools        []string `yaml:"service_lb_pools"`       // ServiceLBPools lists the prefixes in CIDR notation from which the Service VIPs are allocated.
+	ServiceLBProject      string   `yaml:"service_lb_project"`     // ServiceLBProject specifies the project of the Service announcements.
+	ServiceLBClass        string   `yaml:"service_lb_class"`       // ServiceLBClass specifies the load balancer class of the handled Services; empty handles Services without a class.
+
+	LogPath       string `yaml:"log_path"`        // LogPath specifies the file path to the log file for storing operator logs, or "stdout" or "stderr".
+	Verbose       int8   `yaml:"verbose"`         // Verbose specifies the verbosity level for logging, where higher values produce more detailed logs.
+	LogFormat     string `yaml:"log_format"`      // LogFormat specifies the log output format: "json" or "console".
+	LogMaxSize    int    `yaml:"log_max_size"`    // LogMaxSize specifies the size in megabytes at which the log file is rotated.
+	LogMaxBackups int    `yaml:"log_max_backups"` // LogMaxBackups specifies the number of rotated log files to keep.
+	OTLPEndpoint  string `yaml:"otlp_endpoint"`   // OTLPEndpoint specifies the OTLP/HTTP collector URL receiving the traces; empty disables tracing.
+}
+
+// GoBGPRouter is a configuration structure used for connecting the updater to one of the GoBGP daemons it programs.
+type GoBGPRouter struct {
+	Name       string `yaml:"name"`        // Name identifies the router in the announcement status, e.g., "tor-1".
+	Endpoint   string `yaml:"endpoint"`    // Endpoint specifies the GoBGP gRPC endpoint in the "ip:port" form.
+	CACert     string `yaml:"ca_cert"`     // CACert specifies the path to the GoBGP CA certificate file.
+	ClientCert string `yaml:"client_cert"` // ClientCert specifies the path to the GoBGP client certificate file.
+	ClientKey  string `yaml:"client_key"`  // ClientKey specifies the path to the GoBGP client key file.
+}
+
+// Routers returns the GoBGP routers programmed by the updater. Without configured routers it returns the single
+// router of GoBGPEndpoint, named after its endpoint.
+func (c *UpdaterConfig) Routers() []GoBGPRouter {
+	if len(c.GoBGPRouters) > 0 {
+		return c.GoBGPRouters
+	}
+	return []GoBGPRouter{{
+		Name:       c.GoBGPEndpoint,
+		Endpoint:   c.GoBGPEndpoint,
+		CACert:     c.GoBGPCACert,
+		ClientCert: c.GoBGPClientCert,
+		ClientKey:  c.GoBGPClientKey,
+	}}
+}
+
+// LeaderElection is a configuration structure used for electing the single active updater among the replicas that
+// program the same GoBGP daemon. The election is held in the etcd cluster of the API server.
+type LeaderElection struct {
+	Enabled       bool          `yaml:"enabled"`        // Enabled makes the updater wait for the leadership before programming GoBGP.
+	Endpoints     []string      `yaml:"endpoints"`      // Endpoints defines the list of etcd endpoint URLs holding the election.
+	Etcd          Etcd          `yaml:"etcd"`           // Etcd contains the TLS parameters of the etcd connection; an empty CA certificate disables TLS.
+	Name          string        `yaml:"name"`           // Name identifies the group of replicas that share a GoBGP daemon.
+	Identity      string        `yaml:"identity"`       // Identity is the name of this replica in the election; empty uses the host name.
+	LeaseDuration time.Duration `yaml:"lease_duration"` // LeaseDuration specifies how long the leadership outlives a failed leader before a standby takes over.
+}
+
+// BFD is a configuration structure used for running the BFD sessions (RFC 5880, RFC 5881) with the peers that the
+// announcements require to be up before their routes are programmed.
+type BFD struct {
+	Enabled          bool          `yaml:"enabled"`           // Enabled starts the BFD sessions; without it the BFD peers of the announcements are ignored.
+	ListenAddress    string        `yaml:"listen_address"`    // ListenAddress is the local address of the sessions; empty listens on all addresses.
+	MinInterval      time.Duration `yaml:"min_interval"`      // MinInterval is the desired transmit and the required receive interval of the control packets.
+	DetectMultiplier int           `yaml:"detect_multiplier"` // DetectMultiplier is the number of missed control packets after which the peer declares the session down.
+}
+
+// Dampening is a configuration structure used for dampening the routes via next hops whose health checks flap. The
+// routes are withdrawn as soon as a next hop fails, but programmed again only once the dampening allows it. The zero
+// values disable the respective limits.
+type Dampening struct {
+	HoldDown          time.Duration `yaml:"hold_down"`          // HoldDown is the minimum time after a withdrawal before the routes are programmed again.
+	MaxOperations     int           `yaml:"max_operations"`     // MaxOperations limits the withdrawals and re-announcements of the routes via a next hop per minute.
+	HalfLife          time.Duration `yaml:"half_life"`          // HalfLife is the time in which the penalty of the withdrawals decays by half; zero disables the penalty.
+	SuppressThreshold float64       `yaml:"suppress_threshold"` // SuppressThreshold is the penalty above which the routes stay withdrawn; every withdrawal adds 1000.
+	ReuseThreshold    float64       `yaml:"reuse_threshold"`    // ReuseThreshold is the penalty below which the suppressed routes are programmed again.
+	MaxSuppressTime   time.Duration `yaml:"max_suppress_time"`  // MaxSuppressTime caps the penalty so that the routes are suppressed for at most this long.
+}
+
+// HealthCheckDefaults is a configuration structure used for the parameters of the health checks that the
+// announcements leave unset. The values can be changed at runtime by reloading the configuration file.
+type HealthCheckDefaults struct {
+	Interval time.Duration `yaml:"interval"` // Interval is the time between consecutive probes.
+	Timeout  time.Duration `yaml:"timeout"`  // Timeout is the time after which a probe fails.
+	Rise     int           `yaml:"rise"`     // Rise is the number of consecutive successful probes after which a next hop is healthy again.
+	Fall     int           `yaml:"fall"`     // Fall is the number of consecutive failed probes after which a next hop is unhealthy.
 }
@@ -0,0 +1,16 @@
+package model
+
+// Conflict is a pair of active announcements of different projects whose announced prefixes are equal or overlap,
+// so that the routers forward the traffic of the shared addresses to the next hops of either project.
+type Conflict struct {
+	Announcements [2]ConflictingAnnouncement `json:"announcements"` // Announcements are the two conflicting announcements.
+	Equal         bool                       `json:"equal"`         // Equal reports whether the prefixes are the same rather than nested.
+}
+
+// ConflictingAnnouncement identifies an announcement of a conflict and its overlapping prefix.
+type ConflictingAnnouncement struct {
+	Project string `json:"project"` // Project is the project of the announcement.
+	Name    string `json:"name"`    // Name is the name of the announcement.
+	Field   string `json:"field"`   // Field is the JSON path of the field announcing the prefix, e.g., "addresses.announced-ip".
+	Prefix  string `json:"prefix"`  // Prefix is the announced prefix in CIDR notation.
+}
@@ -1,16 +1,58 @@
 package model
 
-import clientv3 "go.etcd.io/etcd/client/v3"
+import (
+	"errors"
+)
 
-// DatabaseAdapter defines interface for database communication
+// ErrResourceVersionConflict is returned by the writes of announcements whose resource version does not match the
+// stored one, i.e. the announcement was modified since it was read.
+var ErrResourceVersionConflict = errors.New("resource version conflict")
+
+// DatabaseAdapter defines interface for database communication. It is implemented by every storage backend of the
+// API server, so the backends must keep a revision that increases with every write and stamp each key with the
+// revision of its last modification.
 type DatabaseAdapter interface {
 	HealthCheck() error
 	Close()
 	Get(string) (string, error)
 	List(string) ([]string, error)
 	GetObjects(string) ([]string, error)
+	GetObjectsPage(string, string, int64) ([]string, string, error)
+	ListByNextHop(string) ([]string, error)
 	Put(string, string) error
 	Patch(string, string) error
-	Watch(string, <-chan struct{}) (<-chan clientv3.WatchResponse, error)
+	Watch(string, int64, <-chan struct{}) (<-chan WatchResponse, error)
 	Delete(string) error
+	Batch([]BatchWrite) error
+}
+
+// BatchWrite is a single put or delete applied atomically together with the other writes of a batch.
+type BatchWrite struct {
+	Key    string // Key is the storage key to write.
+	Value  string // Value is the value to put; ignored when Delete is set.
+	Delete bool   // Delete removes the key instead of putting the value.
+}
+
+// WatchResponse is a group of changes streamed by a watch of the database. A response without events and
+// CompactRevision reports the progress of the watch: all changes of the watched keys up to Revision have been sent.
+type WatchResponse struct {
+	Revision        int64        // Revision is the database revision at the time of the response.
+	CompactRevision int64        // CompactRevision is set when the changes after the requested revision are no longer available; the consumer must list all keys again.
+	Events          []WatchEvent // Events lists the changes in the order they were made.
+}
+
+// Types of the watch events.
+const (
+	WatchEventPut    = "put"    // WatchEventPut is the creation or the update of a key.
+	WatchEventDelete = "delete" // WatchEventDelete is the deletion of a key.
+)
+
+// WatchEvent is a single change of a key.
+type WatchEvent struct {
+	Type        string // Type is WatchEventPut or WatchEventDelete.
+	Key         string // Key is the changed key.
+	Value       string // Value is the new value of a put.
+	PrevValue   string // PrevValue is the value before the change, empty if the key did not exist.
+	ModRevision int64  // ModRevision is the revision of the change.
+	Created     bool   // Created reports whether the put created the key.
 }
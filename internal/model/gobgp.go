@@ -0,0 +1,77 @@
+package model
+
+import "time"
+
+// BGPSessionSummary contains aggregate statistics of the BGP sessions of a GoBGP instance.
+type BGPSessionSummary struct {
+	ASN                   uint32 `json:"asn"`                     // ASN is the local autonomous system number of the GoBGP instance.
+	RouterID              string `json:"router-id"`               // RouterID is the BGP router identifier of the GoBGP instance.
+	TotalPeers            int    `json:"total-peers"`             // TotalPeers is the number of configured peers.
+	EstablishedPeers      int    `json:"established-peers"`       // EstablishedPeers is the number of peers in the Established state.
+	IdlePeers             int    `json:"idle-peers"`              // IdlePeers is the number of peers in the Idle state.
+	TotalPrefixesReceived int64  `json:"total-prefixes-received"` // TotalPrefixesReceived is the number of prefixes received from all peers.
+	TotalPrefixesSent     int64  `json:"total-prefixes-sent"`     // TotalPrefixesSent is the number of prefixes advertised to all peers.
+}
+
+// GoBGPGlobalConfig contains the global BGP parameters GoBGP must be started with before peers can be established.
+type GoBGPGlobalConfig struct {
+	ASN        uint32 `json:"asn" yaml:"asn"`                 // ASN is the local autonomous system number.
+	RouterID   string `json:"router-id" yaml:"router_id"`     // RouterID is the BGP router identifier in the IPv4 address form.
+	ListenPort int32  `json:"listen-port" yaml:"listen_port"` // ListenPort is the TCP port GoBGP listens on for BGP sessions; -1 disables listening.
+}
+
+// Peer is the resource of a BGP neighbor that the updater configures on the GoBGP routers. The address identifies the
+// peer.
+type Peer struct {
+	Address         string     `json:"address"`                     // Address is the IP address of the neighbor.
+	ASN             uint32     `json:"asn"`                         // ASN is the autonomous system number of the neighbor.
+	Description     string     `json:"description,omitempty"`       // Description is a free-form description of the neighbor.
+	AuthPassword    string     `json:"auth-password,omitempty"`     // AuthPassword is the TCP MD5 password of the session; empty disables authentication.
+	Timers          PeerTimers `json:"timers"`                      // Timers configures the timers of the session.
+	AddressFamilies []string   `json:"address-families,omitempty"`  // AddressFamilies lists the address families negotiated with the neighbor; empty uses the family of the address.
+	AddPathSendMax  uint32     `json:"add-path-send-max,omitempty"` // AddPathSendMax is the number of paths per prefix advertised with ADD-PATH (RFC 7911), e.g., for weighted ECMP; zero advertises only the best path.
+	Status          PeerStatus `json:"status"`                      // Status reports the session state on every router; it is maintained by the updater.
+}
+
+// PeerTimers configures the timers of a BGP session in seconds. Zero values use the GoBGP defaults.
+type PeerTimers struct {
+	HoldTime          uint64 `json:"hold-time,omitempty"`          // HoldTime is the hold time proposed to the neighbor.
+	KeepaliveInterval uint64 `json:"keepalive-interval,omitempty"` // KeepaliveInterval is the interval between keepalive messages.
+	ConnectRetry      uint64 `json:"connect-retry,omitempty"`      // ConnectRetry is the interval between connection attempts.
+}
+
+// PeerStatus reports the state of the sessions with a neighbor.
+type PeerStatus struct {
+	Routers   []PeerSessionStatus `json:"routers,omitempty"` // Routers lists the session state on every GoBGP router.
+	Timestamp time.Time           `json:"timestamp"`         // Timestamp is the time of the last status report.
+}
+
+// PeerSessionStatus is the state of the session of a GoBGP router with a neighbor.
+type PeerSessionStatus struct {
+	Router           string     `json:"router"`                      // Router is the name of the GoBGP router.
+	SessionState     string     `json:"session-state"`               // SessionState is the BGP FSM state, e.g., "established" or "idle", or "failed" if the peer could not be configured.
+	EstablishedSince *time.Time `json:"established-since,omitempty"` // EstablishedSince is the time the session was established; nil unless the session is established.
+	PrefixesReceived int64      `json:"prefixes-received"`           // PrefixesReceived is the number of prefixes received from the neighbor.
+	PrefixesSent     int64      `json:"prefixes-sent"`               // PrefixesSent is the number of prefixes advertised to the neighbor.
+	Message          string     `json:"message,omitempty"`           // Message describes why the peer could not be configured on the router.
+}
+
+// RIBPath is a path originated by GoBGP as it is stored in its RIB, together with the announcements that require it.
+type RIBPath struct {
+	Prefix        string            `json:"prefix"`                   // Prefix is the announced prefix in CIDR notation.
+	NextHop       string            `json:"next-hop"`                 // NextHop is the next-hop address of the path.
+	Origin        BGPOrigin         `json:"origin"`                   // Origin is the value of the ORIGIN attribute.
+	MED           uint32            `json:"med,omitempty"`            // MED is the MULTI_EXIT_DISC attribute; zero if the attribute is not set.
+	LocalPref     uint32            `json:"local-pref,omitempty"`     // LocalPref is the LOCAL_PREF attribute; zero if the attribute is not set.
+	ASPath        []uint32          `json:"as-path,omitempty"`        // ASPath lists the AS numbers of the AS_PATH attribute.
+	Communities   []string          `json:"communities,omitempty"`    // Communities lists the standard, extended and large communities of the path.
+	LinkBandwidth float32           `json:"link-bandwidth,omitempty"` // LinkBandwidth is the bandwidth in bytes per second of the link bandwidth extended community of a weighted path.
+	Age           time.Time         `json:"age"`                      // Age is the time the path was added to the RIB.
+	Announcements []AnnouncementRef `json:"announcements,omitempty"`  // Announcements lists the announcements that require the path; empty for a stale path.
+}
+
+// AnnouncementRef identifies an announcement.
+type AnnouncementRef struct {
+	Project string `json:"project"` // Project is the project of the announcement.
+	Name    string `json:"name"`    // Name is the name of the announcement.
+}
@@ -0,0 +1,22 @@
+package model
+
+// PrefixPolicy is the content of the API server prefix policy file. The global policy applies to every announcement,
+// the policy of a project applies to the announcements of the project in addition.
+type PrefixPolicy struct {
+	Global   PrefixRules            `json:"global"`   // Global holds the rules applied to all announcements.
+	Projects map[string]PrefixRules `json:"projects"` // Projects maps a project name to the rules applied to its announcements.
+}
+
+// PrefixRules restricts the prefixes and the next hops of announcements. The zero value allows everything.
+type PrefixRules struct {
+	AllowedPrefixes        []string            `json:"allowed-prefixes,omitempty"`         // AllowedPrefixes lists the prefixes in CIDR notation the announced prefixes must be within; empty allows any prefix.
+	AllowedNextHops        []string            `json:"allowed-next-hops,omitempty"`        // AllowedNextHops lists the subnets in CIDR notation the next hops must be within; empty allows any next hop.
+	ForbiddenPrefixLengths []PrefixLengthRange `json:"forbidden-prefix-lengths,omitempty"` // ForbiddenPrefixLengths lists the prefix lengths that must not be announced, e.g., the default route.
+}
+
+// PrefixLengthRange is an inclusive range of prefix lengths of an address family.
+type PrefixLengthRange struct {
+	Family string `json:"family"` // Family is "ipv4" or "ipv6".
+	Min    int    `json:"min"`    // Min is the shortest prefix length of the range.
+	Max    int    `json:"max"`    // Max is the longest prefix length of the range.
+}
@@ -0,0 +1,44 @@
+package model
+
+// SiteOverride changes how an announcement is programmed by the updaters of a site, so that a VIP announced from
+// several datacenters is a single announcement. The zero value programs the announcement as it is.
+type SiteOverride struct {
+	Disabled         bool              `json:"disabled,omitempty"`           // Disabled withdraws the routes of the announcement from the routers of the site.
+	NextHops         []Subnet          `json:"next-hops,omitempty"`          // NextHops replaces the next hops of the announcement at the site.
+	WeightedNextHops []WeightedNextHop `json:"weighted-next-hops,omitempty"` // WeightedNextHops replaces the weighted next hops of the announcement at the site.
+	MED              uint32            `json:"med,omitempty"`                // MED replaces the MULTI_EXIT_DISC attribute at the site; zero keeps the MED of the announcement.
+}
+
+// Site lists the updaters registered with a site ID.
+type Site struct {
+	Name     string        `json:"name"`     // Name is the site ID, e.g., the datacenter or the zone of the updaters.
+	Updaters []SiteUpdater `json:"updaters"` // Updaters lists the registered updaters ordered by identity.
+}
+
+// SiteUpdater is the registration of an updater with its site, renewed periodically while the updater runs.
+type SiteUpdater struct {
+	Identity string   `json:"identity"`            // Identity is the name of the updater, unique within the site.
+	Routers  []string `json:"routers,omitempty"`   // Routers lists the names of the routers programmed by the updater.
+	Version  string   `json:"version,omitempty"`   // Version is the version of the updater.
+	LastSeen string   `json:"last-seen,omitempty"` // LastSeen is the time of the last registration in RFC 3339 format; set by the API server.
+}
+
+// ForSite returns the announcement as programmed by the updaters of the site, with the overrides of the site applied
+// and the overrides of all sites removed, and reports whether the site programs it at all. Overriding next hops
+// replace both the next hops and the weighted next hops. An empty site gets the announcement without overrides.
+func (a *Announcement) ForSite(site string) (Announcement, bool) {
+	view := *a
+	view.Sites = nil
+
+	override, ok := a.Sites[site]
+	if site == "" || !ok {
+		return view, true
+	}
+	if len(override.NextHops) > 0 || len(override.WeightedNextHops) > 0 {
+		view.NextHops, view.WeightedNextHops = override.NextHops, override.WeightedNextHops
+	}
+	if override.MED != 0 {
+		view.MED = override.MED
+	}
+	return view, !override.Disabled
+}
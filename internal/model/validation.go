@@ -0,0 +1,14 @@
+package model
+
+// ValidationReport is the result of checking an announcement against all server-side policies.
+type ValidationReport struct {
+	Valid    bool              `json:"valid"`    // Valid reports whether the announcement passed all checks without errors.
+	Warnings []string          `json:"warnings"` // Warnings lists non-fatal issues that do not prevent the announcement from being stored.
+	Errors   []ValidationError `json:"errors"`   // Errors lists the violations that prevent the announcement from being stored.
+}
+
+// ValidationError describes a single invalid field of an announcement.
+type ValidationError struct {
+	Field   string `json:"field"`   // Field is the JSON path of the invalid field, e.g., "addresses.announced-ip".
+	Message string `json:"message"` // Message explains why the field value is invalid.
+}
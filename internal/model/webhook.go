@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// WebhookEventType is the type of an announcement lifecycle event sent to the webhooks.
+type WebhookEventType string
+
+const (
+	WebhookCreated            WebhookEventType = "created"              // WebhookCreated is sent when an announcement is created.
+	WebhookUpdated            WebhookEventType = "updated"              // WebhookUpdated is sent when the spec or the status of an announcement changes, e.g., on a withdrawal.
+	WebhookDeleted            WebhookEventType = "deleted"              // WebhookDeleted is sent when an announcement is deleted.
+	WebhookHealthStateChanged WebhookEventType = "health-state-changed" // WebhookHealthStateChanged is sent when the HealthCheckPassing condition of an announcement changes.
+)
+
+// Payload formats of the webhooks.
+const (
+	WebhookFormatJSON  = "json"  // WebhookFormatJSON sends the WebhookEvent as is.
+	WebhookFormatSlack = "slack" // WebhookFormatSlack sends a Slack incoming webhook message summarizing the event.
+)
+
+// WebhookConfig is the content of the API server webhooks file.
+type WebhookConfig struct {
+	Webhooks []Webhook `json:"webhooks"` // Webhooks lists the endpoints notified of the announcement lifecycle events.
+}
+
+// Webhook is an HTTP endpoint receiving the announcement lifecycle events as POST requests.
+type Webhook struct {
+	Name        string             `json:"name"`                   // Name identifies the webhook in the logs and the metrics.
+	URL         string             `json:"url"`                    // URL is the HTTP or HTTPS URL the events are posted to.
+	Secret      string             `json:"secret,omitempty"`       // Secret is the key of the HMAC-SHA256 signature of the payload; empty sends unsigned payloads.
+	Events      []WebhookEventType `json:"events,omitempty"`       // Events lists the event types sent to the webhook; empty sends all events.
+	Projects    []string           `json:"projects,omitempty"`     // Projects lists the projects whose events are sent to the webhook; empty sends the events of all projects.
+	Format      string             `json:"format,omitempty"`       // Format is WebhookFormatJSON (default) or WebhookFormatSlack.
+	MaxAttempts int                `json:"max-attempts,omitempty"` // MaxAttempts is the number of delivery attempts of an event before it is dead-lettered; defaults to 5.
+}
+
+// WebhookEvent is the payload posted to the webhooks for an announcement lifecycle event.
+type WebhookEvent struct {
+	ID           string           `json:"id"`                     // ID uniquely identifies the event, it is the same for all attempts to deliver it.
+	Type         WebhookEventType `json:"type"`                   // Type is the type of the event.
+	Timestamp    time.Time        `json:"timestamp"`              // Timestamp is the time of the change.
+	Project      string           `json:"project"`                // Project is the project of the announcement.
+	Name         string           `json:"name"`                   // Name is the name of the announcement.
+	Actor        string           `json:"actor,omitempty"`        // Actor is the authenticated subject that made the change; empty when authentication is disabled.
+	Announcement *Announcement    `json:"announcement,omitempty"` // Announcement is the announcement after the change; nil for a deletion.
+	Previous     *Announcement    `json:"previous,omitempty"`     // Previous is the announcement before the change; nil for a creation.
+}
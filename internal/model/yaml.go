@@ -0,0 +1,118 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLToJSON converts a single YAML document to JSON. The field names are kept as they are, so YAML documents use
+// the JSON field names of the API, e.g., "next-hops".
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return yamlDocumentToJSON(document)
+}
+
+// yamlDocumentToJSON encodes a decoded YAML document as JSON.
+func yamlDocumentToJSON(document interface{}) ([]byte, error) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("YAML document must consist of objects with string keys: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalYAML decodes a single YAML document into v using the JSON field names. Unknown fields are rejected to
+// catch typos in hand-written manifests.
+func UnmarshalYAML(data []byte, v interface{}) error {
+	jsonData, err := YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+	return unmarshalStrict(jsonData, v)
+}
+
+// unmarshalStrict decodes JSON into v, rejecting unknown fields.
+func unmarshalStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// MarshalYAML encodes v as YAML in the block style with the JSON field names, keeping the field order.
+func MarshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML, decoding it into a node keeps the order of the fields
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle resets the JSON flow style and quoting of the nodes, so that they are written in the block style.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// UnmarshalAnnouncementsYAML decodes the announcements of a manifest. The manifest holds YAML documents separated by
+// "---", or JSON since JSON is valid YAML, and every document is an announcement or a list of announcements.
+// Unknown fields are rejected.
+func UnmarshalAnnouncementsYAML(data []byte) ([]*Announcement, error) {
+	var announcements []*Announcement
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return announcements, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		items := []interface{}{document}
+		if list, ok := document.([]interface{}); ok {
+			items = list
+		}
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			jsonData, err := yamlDocumentToJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			var announcement Announcement
+			if err := unmarshalStrict(jsonData, &announcement); err != nil {
+				return nil, err
+			}
+			announcements = append(announcements, &announcement)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package operator
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/configfile"
+	"github.com/nikitamishagin/corebgp/internal/logging"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	"github.com/spf13/cobra"
+)
+
+// manifests holds the CustomResourceDefinition of the Announcement resource and the ClusterRole of the operator,
+// which also covers the Services and Nodes read by the Service load balancer.
+//
+//go:embed manifests.yaml
+var manifests string
+
+// RootCmd initializes and returns the root command for the CoreBGP Kubernetes operator.
+func RootCmd() *cobra.Command {
+	var (
+		config     model.OperatorConfig
+		configPath string
+	)
+	var cmd = &cobra.Command{
+		Use:   "operator",
+		Short: "CoreBGP Kubernetes operator syncing Announcement custom resources and LoadBalancer Services to the API server",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := configfile.Load(cmd.Flags(), "config", &config); err != nil {
+				return err
+			}
+
+			logger, logFile, err := logging.New(logging.Config{
+				Path:       config.LogPath,
+				Format:     config.LogFormat,
+				Verbose:    config.Verbose,
+				MaxSize:    config.LogMaxSize,
+				MaxBackups: config.LogMaxBackups,
+			})
+			if err != nil {
+				return err
+			}
+			defer logFile.Close()
+			slog.SetDefault(logger)
+
+			shutdownTracing, err := tracing.Setup(cmd.Context(), config.OTLPEndpoint, "corebgp-operator")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := shutdownTracing(context.Background()); err != nil {
+					slog.Error("failed to flush traces", "error", err)
+				}
+			}()
+
+			// Shut down gracefully on SIGTERM or SIGINT
+			ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGTERM, os.Interrupt)
+			defer cancel()
+
+			if config.Manager == "" {
+				return fmt.Errorf("manager must not be empty")
+			}
+			if !config.AnnouncementResources && !config.ServiceLB {
+				return fmt.Errorf("neither the Announcement resources nor the Service load balancer are enabled")
+			}
+			var pool *vipPool
+			if config.ServiceLB {
+				if pool, err = newVIPPool(config.ServiceLBPools); err != nil {
+					return err
+				}
+			}
+
+			kube, err := newKubeClient(config)
+			if err != nil {
+				return err
+			}
+
+			// Initialize the CoreBGP API client
+			var clientOpts []v1.ClientOption
+			if config.APICACert != "" || config.APIClientCert != "" || config.APIInsecure {
+				tlsConfig, err := v1.NewTLSConfig(v1.ClientConfig{
+					CACert:             config.APICACert,
+					ClientCert:         config.APIClientCert,
+					ClientKey:          config.APIClientKey,
+					InsecureSkipVerify: config.APIInsecure,
+				})
+				if err != nil {
+					return err
+				}
+				clientOpts = append(clientOpts, v1.WithTLSConfig(tlsConfig))
+			}
+			if config.APITokenFile != "" {
+				token, err := os.ReadFile(config.APITokenFile)
+				if err != nil {
+					return fmt.Errorf("could not read API token: %w", err)
+				}
+				clientOpts = append(clientOpts, v1.WithBearerToken(strings.TrimSpace(string(token))))
+			}
+			// Retry the requests throttled by the API server after the delay it asks for, and the idempotent requests
+			// failing with a connection or server error
+			clientOpts = append(clientOpts, v1.WithThrottleRetries(3), v1.WithRetryPolicy(v1.DefaultRetryPolicy))
+			apiClient := v1.NewAPIClient(&config.APIEndpoint, time.Second*5, clientOpts...)
+
+			// Check if CoreBGP API server is healthy
+			if err := apiClient.V1HealthCheck(ctx); err != nil {
+				return err
+			}
+
+			var wg sync.WaitGroup
+			var resyncs []func(context.Context)
+
+			if config.AnnouncementResources {
+				ctrl := newController(kube, apiClient, clock.RealClock{}, config.Manager, config.Namespace)
+				resyncs = append(resyncs, ctrl.resync)
+
+				// Goroutine syncing the Announcement resources to CoreBGP
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ctrl.run(ctx)
+				}()
+
+				// Goroutine writing the status reported to CoreBGP, e.g., by the updater, back to the resources
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					watcher := apiClient.NewResumableWatcher(func(event model.Event) {
+						ctrl.handleAnnouncementEvent(ctx, event)
+					}, nil)
+					if err := watcher.Run(ctx); err != nil && ctx.Err() == nil {
+						slog.Error("error while watching announcements", "error", err)
+						cancel()
+					}
+				}()
+			}
+
+			if config.ServiceLB {
+				serviceCtrl := newServiceController(kube, apiClient, pool, config.Manager, config.ServiceLBProject, config.ServiceLBClass, config.Namespace)
+				resyncs = append(resyncs, serviceCtrl.resync)
+
+				// Goroutine announcing the VIPs of the LoadBalancer Services
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					serviceCtrl.run(ctx)
+				}()
+			}
+
+			// Goroutine for the periodic resync that corrects changes made to the announcements outside the operator
+			if config.ResyncInterval > 0 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ticker := time.NewTicker(config.ResyncInterval)
+					defer ticker.Stop()
+					for {
+						select {
+						case <-ctx.Done():
+							return
+						case <-ticker.C:
+							for _, resync := range resyncs {
+								resync(ctx)
+							}
+						}
+					}
+				}()
+			}
+
+			slog.Info("operator is running", "namespace", config.Namespace, "manager", config.Manager)
+			wg.Wait()
+			slog.Info("operator stopped")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", "", "Path to the YAML or TOML (.toml) configuration file; flags and COREBGP_* environment variables take precedence")
+	cmd.Flags().StringVar(&config.APIEndpoint, "api-endpoint", "http://localhost:8080", "URL of the API server")
+	cmd.Flags().StringVar(&config.APICACert, "api-ca-cert", "", "Path to CA certificate used to verify the API server")
+	cmd.Flags().StringVar(&config.APIClientCert, "api-client-cert", "", "Path to client certificate presented to the API server")
+	cmd.Flags().StringVar(&config.APIClientKey, "api-client-key", "", "Path to client key presented to the API server")
+	cmd.Flags().BoolVar(&config.APIInsecure, "api-insecure-skip-verify", false, "Skip verification of the API server certificate (testing only)")
+	cmd.Flags().StringVar(&config.APITokenFile, "api-token-file", "", "Path to the file with the bearer token used to authenticate to the API server")
+	cmd.Flags().StringVar(&config.KubeAPIServer, "kube-api-server", "", "URL of the Kubernetes API server (defaults to the in-cluster service)")
+	cmd.Flags().StringVar(&config.KubeCACert, "kube-ca-cert", "", "Path to CA certificate of the Kubernetes API server (defaults to that of the service account in-cluster)")
+	cmd.Flags().StringVar(&config.KubeTokenFile, "kube-token-file", "", "Path to the bearer token of the Kubernetes API server (defaults to that of the service account in-cluster)")
+	cmd.Flags().StringVar(&config.Namespace, "namespace", "", "Namespace of the watched Announcement resources and Services (all namespaces if empty)")
+	cmd.Flags().StringVar(&config.Manager, "manager", "corebgp-operator", "Name of the manager owning the fields applied to the API server")
+	cmd.Flags().DurationVar(&config.ResyncInterval, "resync-interval", 5*time.Minute, "Interval at which all resources are applied again to correct drift (0 disables it)")
+	cmd.Flags().BoolVar(&config.AnnouncementResources, "enable-announcement-resources", true, "Sync the Announcement custom resources to the API server")
+	cmd.Flags().BoolVar(&config.ServiceLB, "enable-service-lb", false, "Allocate VIPs to the Services of type LoadBalancer and announce them via the ready nodes")
+	cmd.Flags().StringSliceVar(&config.ServiceLBPools, "service-lb-pools", nil, "Comma separated list of prefixes in CIDR notation from which the Service VIPs are allocated")
+	cmd.Flags().StringVar(&config.ServiceLBProject, "service-lb-project", "kubernetes", "Project of the announcements of the Service VIPs")
+	cmd.Flags().StringVar(&config.ServiceLBClass, "service-lb-class", "", "Load balancer class of the handled Services (Services without a class if empty)")
+	cmd.Flags().StringVar(&config.OTLPEndpoint, "otlp-endpoint", "", "URL of the OTLP/HTTP collector receiving the traces, e.g. http://localhost:4318 (disabled if empty)")
+	cmd.Flags().StringVar(&config.LogPath, "log-path", "stderr", "Path to the log file (rotated by size), stdout or stderr")
+	cmd.Flags().Int8VarP(&config.Verbose, "verbose", "v", 0, "Verbosity level (1 enables debug logs, -1 logs only warnings and errors)")
+	cmd.Flags().StringVar(&config.LogFormat, "log-format", logging.FormatJSON, "Log output format: json or console")
+	cmd.Flags().IntVar(&config.LogMaxSize, "log-max-size", 100, "Size in megabytes at which the log file is rotated")
+	cmd.Flags().IntVar(&config.LogMaxBackups, "log-max-backups", 10, "Number of rotated log files to keep")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "manifests",
+		Short: "Print the CustomResourceDefinition and the ClusterRole required by the operator",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprint(cmd.OutOrStdout(), manifests)
+		},
+	})
+
+	return cmd
+}
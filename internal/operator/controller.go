@@ -0,0 +1,275 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// maxConflictRetries is the number of times a status update is retried after the resource was modified concurrently.
+const maxConflictRetries = 3
+
+// controller syncs the Announcement resources to CoreBGP and writes the status of the announcements back to them.
+// All reconciliations are serialized by mu, the CoreBGP API and the Kubernetes API are only called under it.
+type controller struct {
+	kube      *kubeClient
+	api       v1.AnnouncementsInterface
+	clk       clock.Clock
+	manager   string
+	namespace string
+
+	mu        sync.Mutex
+	resources map[string]*resource // resources holds the last seen version of every resource keyed by namespace/name.
+}
+
+// newController creates the controller of the resources of the namespace, or of all namespaces if empty.
+func newController(kube *kubeClient, api v1.AnnouncementsInterface, clk clock.Clock, manager, namespace string) *controller {
+	return &controller{
+		kube:      kube,
+		api:       api,
+		clk:       clk,
+		manager:   manager,
+		namespace: namespace,
+		resources: make(map[string]*resource),
+	}
+}
+
+// run lists and watches the resources until the context is canceled. Every listed resource is applied again.
+func (c *controller) run(ctx context.Context) {
+	c.kube.listAndWatch(ctx, resourcePath(c.namespace, "", ""), c.relist, func(eventType string, object json.RawMessage) {
+		var res resource
+		if err := json.Unmarshal(object, &res); err != nil {
+			slog.Error("failed to decode resource", "error", err)
+			return
+		}
+		c.handleEvent(ctx, eventType, &res)
+	})
+}
+
+// relist reconciles all resources and deletes the announcements of the resources removed while the watch was down.
+// It returns the resource version to watch from.
+func (c *controller) relist(ctx context.Context) (string, error) {
+	var list resourceList
+	if err := c.kube.get(ctx, resourcePath(c.namespace, "", ""), &list); err != nil {
+		return "", fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.resources
+	c.resources = make(map[string]*resource, len(list.Items))
+	for i := range list.Items {
+		res := &list.Items[i]
+		c.resources[res.key()] = res
+		delete(previous, res.key())
+	}
+	for _, res := range previous {
+		c.deleteAnnouncement(ctx, res)
+	}
+	for _, res := range list.Items {
+		c.reconcile(ctx, c.resources[res.key()], true)
+	}
+	return list.Metadata.ResourceVersion, nil
+}
+
+// resync applies all resources again, which corrects changes made to the announcements outside the operator.
+func (c *controller) resync(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, res := range c.resources {
+		c.reconcile(ctx, res, true)
+	}
+}
+
+// handleEvent reconciles the resource of a watch event.
+func (c *controller) handleEvent(ctx context.Context, eventType string, res *resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch eventType {
+	case "ADDED", "MODIFIED":
+		c.resources[res.key()] = res
+		c.reconcile(ctx, res, false)
+	case "DELETED":
+		// The finalizer normally deletes the announcement first, this covers resources whose finalizer was removed
+		delete(c.resources, res.key())
+		c.deleteAnnouncement(ctx, res)
+	}
+}
+
+// reconcile applies the spec of the resource to CoreBGP and records the result in its status. Without force a
+// resource whose generation is already synced is skipped. A resource being deleted has its announcement deleted
+// before the finalizer is removed.
+func (c *controller) reconcile(ctx context.Context, res *resource, force bool) {
+	if res.deleting() {
+		if !res.hasFinalizer() {
+			return
+		}
+		if !c.deleteAnnouncement(ctx, res) {
+			return
+		}
+		finalizers := slices.DeleteFunc(slices.Clone(res.Metadata.Finalizers), func(f string) bool { return f == finalizer })
+		var updated resource
+		if err := c.kube.setFinalizers(ctx, res.path(), res.Metadata.ResourceVersion, finalizers, &updated); err != nil && !isNotFound(err) {
+			slog.Error("failed to remove finalizer", "resource", res.key(), "error", err)
+		}
+		return
+	}
+
+	if !res.hasFinalizer() {
+		var updated resource
+		if err := c.kube.setFinalizers(ctx, res.path(), res.Metadata.ResourceVersion, append(slices.Clone(res.Metadata.Finalizers), finalizer), &updated); err != nil {
+			// A conflict means a newer version is on its way through the watch
+			if !isConflict(err) && !isNotFound(err) {
+				slog.Error("failed to add finalizer", "resource", res.key(), "error", err)
+			}
+			return
+		}
+		res = &updated
+		c.resources[res.key()] = res
+	}
+
+	if synced := res.Status.Condition(conditionSynced); !force && synced != nil && synced.Status == model.ConditionTrue && res.Status.ObservedGeneration == res.Metadata.Generation {
+		return
+	}
+
+	announcement, err := res.announcement()
+	var stored *model.Announcement
+	if err == nil {
+		stored, err = c.api.V1ApplyAnnouncement(ctx, c.manager, announcement)
+	}
+	if err != nil {
+		slog.Error("failed to apply announcement", "resource", res.key(), "announcement", res.announcementID(), "error", err)
+	} else {
+		slog.Debug("applied announcement", "resource", res.key(), "announcement", res.announcementID())
+	}
+
+	generation := res.Metadata.Generation
+	c.writeStatus(ctx, res, func(current *resource) resourceStatus {
+		return c.syncStatus(current, generation, stored, err)
+	})
+}
+
+// deleteAnnouncement deletes the announcement of the resource from CoreBGP and reports whether it is gone.
+func (c *controller) deleteAnnouncement(ctx context.Context, res *resource) bool {
+	err := c.api.V1DeleteAnnouncement(ctx, res.project(), res.Metadata.Name)
+	if err != nil && !errors.Is(err, v1.ErrNotFound) {
+		slog.Error("failed to delete announcement", "resource", res.key(), "announcement", res.announcementID(), "error", err)
+		return false
+	}
+	slog.Info("deleted announcement", "resource", res.key(), "announcement", res.announcementID())
+	return true
+}
+
+// handleAnnouncementEvent writes the status of a changed announcement back to the resources it belongs to.
+func (c *controller) handleAnnouncementEvent(ctx context.Context, event model.Event) {
+	if event.Type == model.EventDeleted {
+		return
+	}
+	id := event.Announcement.Meta.Project + "/" + event.Announcement.Meta.Name
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, res := range c.resources {
+		if res.deleting() || res.announcementID() != id {
+			continue
+		}
+		c.writeStatus(ctx, res, func(current *resource) resourceStatus {
+			status := resourceStatus{Status: cloneStatus(event.Announcement.Status), ObservedGeneration: current.Status.ObservedGeneration}
+			if synced := current.Status.Condition(conditionSynced); synced != nil {
+				status.SetCondition(*synced)
+			}
+			return status
+		})
+	}
+}
+
+// syncStatus returns the status of the resource after the generation was applied to CoreBGP. The status of the
+// stored announcement replaces that of CoreBGP, which is kept when the apply failed.
+func (c *controller) syncStatus(res *resource, generation int64, stored *model.Announcement, err error) resourceStatus {
+	status := resourceStatus{Status: cloneStatus(res.Status.Status), ObservedGeneration: generation}
+	if stored != nil {
+		status.Status = cloneStatus(stored.Status)
+	}
+
+	synced := model.Condition{
+		Type:    conditionSynced,
+		Status:  model.ConditionTrue,
+		Reason:  "Applied",
+		Message: "announcement " + res.announcementID() + " is applied to CoreBGP",
+	}
+	if err != nil {
+		synced.Status = model.ConditionFalse
+		synced.Reason = "ApplyFailed"
+		synced.Message = err.Error()
+	}
+	if current := res.Status.Condition(conditionSynced); current != nil && current.Status == synced.Status {
+		synced.LastTransitionTime = current.LastTransitionTime
+	} else {
+		synced.LastTransitionTime = c.clk.Now().UTC().Format(time.RFC3339)
+	}
+	status.SetCondition(synced)
+	return status
+}
+
+// writeStatus replaces the status of the resource by the one statusFn returns for it, unless it is unchanged. On a
+// conflict the latest version of the resource is read and statusFn is called again.
+func (c *controller) writeStatus(ctx context.Context, res *resource, statusFn func(*resource) resourceStatus) {
+	for attempt := 0; ; attempt++ {
+		status := statusFn(res)
+		if statusEqual(res.Status, status) {
+			return
+		}
+
+		updated, err := c.kube.updateStatus(ctx, res, status)
+		if err == nil {
+			c.resources[res.key()] = updated
+			return
+		}
+		if isNotFound(err) {
+			return
+		}
+		if !isConflict(err) || attempt == maxConflictRetries {
+			slog.Error("failed to update resource status", "resource", res.key(), "error", err)
+			return
+		}
+
+		var latest resource
+		if err := c.kube.get(ctx, res.path(), &latest); err != nil {
+			if !isNotFound(err) {
+				slog.Error("failed to get resource", "resource", res.key(), "error", err)
+			}
+			return
+		}
+		res = &latest
+	}
+}
+
+// statusEqual reports whether both statuses have the same JSON representation. Comparing the encoding rather than
+// the values avoids a write for statuses that only differ in nil and empty lists omitted from the JSON.
+func statusEqual(a, b resourceStatus) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// cloneStatus returns a copy of the status that shares no slices with it.
+func cloneStatus(status model.Status) model.Status {
+	status.Details = slices.Clone(status.Details)
+	status.Routers = slices.Clone(status.Routers)
+	status.Conditions = slices.Clone(status.Conditions)
+	return status
+}
@@ -0,0 +1,298 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// serviceAccountDir is the directory of the service account credentials mounted into the pods.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// requestTimeout is the deadline of every Kubernetes API request except watches.
+const requestTimeout = 10 * time.Second
+
+// watchTimeout is the duration after which the Kubernetes API server ends a watch, so that it is restarted regularly.
+const watchTimeout = 5 * time.Minute
+
+// relistBackoff is the delay before the objects are listed again after the watch failed.
+const relistBackoff = 5 * time.Second
+
+// errWatchExpired is returned by a watch whose resource version is too old, the resources must be listed again.
+var errWatchExpired = errors.New("watch resource version expired")
+
+// kubeClient is a minimal client of the Kubernetes API for the Announcement resources.
+type kubeClient struct {
+	server     string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// kubeStatus is the error response of the Kubernetes API.
+type kubeStatus struct {
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Code    int    `json:"code"`
+}
+
+// kubeError is returned for failed Kubernetes API requests.
+type kubeError struct {
+	status kubeStatus
+}
+
+func (e *kubeError) Error() string {
+	return fmt.Sprintf("kubernetes API: %s (%d %s)", e.status.Message, e.status.Code, e.status.Reason)
+}
+
+// isConflict reports whether the request failed because the resource was modified concurrently.
+func isConflict(err error) bool {
+	var kubeErr *kubeError
+	return errors.As(err, &kubeErr) && kubeErr.status.Code == http.StatusConflict
+}
+
+// isNotFound reports whether the request failed because the resource no longer exists.
+func isNotFound(err error) bool {
+	var kubeErr *kubeError
+	return errors.As(err, &kubeErr) && kubeErr.status.Code == http.StatusNotFound
+}
+
+// newKubeClient creates the client of the Kubernetes API server. Without a server URL the in-cluster service and
+// the credentials of the service account of the pod are used.
+func newKubeClient(config model.OperatorConfig) (*kubeClient, error) {
+	server, caCert, tokenFile := config.KubeAPIServer, config.KubeCACert, config.KubeTokenFile
+	if server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("not running in a Kubernetes cluster, the Kubernetes API server must be set")
+		}
+		server = "https://" + net.JoinHostPort(host, port)
+		if caCert == "" {
+			caCert = serviceAccountDir + "/ca.crt"
+		}
+		if tokenFile == "" {
+			tokenFile = serviceAccountDir + "/token"
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read Kubernetes CA certificate: %w", err)
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to append Kubernetes CA certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: caPool}
+	}
+
+	return &kubeClient{
+		server:     strings.TrimSuffix(server, "/"),
+		tokenFile:  tokenFile,
+		httpClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+// resourcePath returns the API path of the resources of the namespace, all namespaces if empty, or of a single
+// resource and its subresource.
+func resourcePath(namespace, name, subresource string) string {
+	path := "/apis/" + apiGroup + "/" + apiVersion
+	if namespace != "" {
+		path += "/namespaces/" + url.PathEscape(namespace)
+	}
+	path += "/announcements"
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	if subresource != "" {
+		path += "/" + subresource
+	}
+	return path
+}
+
+// request sends the request and returns the response if it succeeded. The token is read on every request, since
+// the projected service account tokens are rotated.
+func (k *kubeClient) request(ctx context.Context, method, path, contentType string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, k.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if k.tokenFile != "" {
+		token, err := os.ReadFile(k.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read Kubernetes token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	status := kubeStatus{Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	_ = json.NewDecoder(resp.Body).Decode(&status)
+	return nil, &kubeError{status: status}
+}
+
+// do sends the request and decodes the response into out.
+func (k *kubeClient) do(ctx context.Context, method, path, contentType string, body, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	resp, err := k.request(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Kubernetes API response: %w", err)
+	}
+	return nil
+}
+
+// get reads the object or the list of objects at the path into out.
+func (k *kubeClient) get(ctx context.Context, path string, out interface{}) error {
+	return k.do(ctx, http.MethodGet, path, "", nil, out)
+}
+
+// mergePatch applies the JSON merge patch to the object at the path and decodes the updated object into out.
+func (k *kubeClient) mergePatch(ctx context.Context, path string, patch, out interface{}) error {
+	return k.do(ctx, http.MethodPatch, path, "application/merge-patch+json", patch, out)
+}
+
+// setFinalizers replaces the finalizers of the object at the path and decodes the updated object into out. The
+// update fails with a conflict if the object was modified since the resource version was read.
+func (k *kubeClient) setFinalizers(ctx context.Context, path, resourceVersion string, finalizers []string, out interface{}) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": resourceVersion,
+			"finalizers":      finalizers,
+		},
+	}
+	return k.mergePatch(ctx, path, patch, out)
+}
+
+// watch streams the changes of the objects at the path after the resource version to fn until the server ends the
+// watch, and returns the resource version to continue from. It returns errWatchExpired if the version is too old.
+func (k *kubeClient) watch(ctx context.Context, path, resourceVersion string, fn func(eventType string, object json.RawMessage)) (string, error) {
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("allowWatchBookmarks", "true")
+	query.Set("resourceVersion", resourceVersion)
+	query.Set("timeoutSeconds", fmt.Sprint(int(watchTimeout.Seconds())))
+
+	resp, err := k.request(ctx, http.MethodGet, path+"?"+query.Encode(), "", nil)
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return resourceVersion, nil
+			}
+			return resourceVersion, err
+		}
+
+		if event.Type == "ERROR" {
+			var status kubeStatus
+			if err := json.Unmarshal(event.Object, &status); err == nil && status.Code == http.StatusGone {
+				return resourceVersion, errWatchExpired
+			}
+			return resourceVersion, fmt.Errorf("watch failed: %s", event.Object)
+		}
+
+		var object struct {
+			Metadata objectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(event.Object, &object); err != nil {
+			return resourceVersion, fmt.Errorf("failed to decode watch event: %w", err)
+		}
+		resourceVersion = object.Metadata.ResourceVersion
+		if event.Type != "BOOKMARK" {
+			fn(event.Type, event.Object)
+		}
+	}
+}
+
+// listAndWatch lists the objects at the path with relist, which returns the resource version of the list, and
+// passes their changes to fn until the context is canceled. The objects are listed again whenever the watch cannot
+// be resumed.
+func (k *kubeClient) listAndWatch(ctx context.Context, path string, relist func(ctx context.Context) (string, error), fn func(eventType string, object json.RawMessage)) {
+	for {
+		resourceVersion, err := relist(ctx)
+		for err == nil {
+			resourceVersion, err = k.watch(ctx, path, resourceVersion, fn)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errWatchExpired) {
+			slog.Info("watch expired, listing again", "path", path)
+			continue
+		}
+
+		slog.Warn("watch failed, listing again", "path", path, "error", err, "backoff", relistBackoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(relistBackoff):
+		}
+	}
+}
+
+// updateStatus replaces the status of the Announcement resource. The update fails with a conflict if the resource
+// was modified since it was read.
+func (k *kubeClient) updateStatus(ctx context.Context, res *resource, status resourceStatus) (*resource, error) {
+	object := *res
+	object.APIVersion = apiGroup + "/" + apiVersion
+	object.Kind = "Announcement"
+	object.Status = status
+
+	var updated resource
+	path := resourcePath(res.Metadata.Namespace, res.Metadata.Name, "status")
+	if err := k.do(ctx, http.MethodPut, path, "application/json", object, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
@@ -0,0 +1,96 @@
+package operator
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// vipPool allocates the VIPs of the LoadBalancer Services from the configured prefixes. The allocations are not
+// persisted: they are rebuilt from the addresses recorded in the status of the Services whenever those are listed.
+type vipPool struct {
+	prefixes  []netip.Prefix
+	allocated map[netip.Addr]string // allocated maps the allocated addresses to the keys of the owning Services.
+}
+
+// newVIPPool creates the pool of the prefixes given in CIDR notation.
+func newVIPPool(cidrs []string) (*vipPool, error) {
+	pool := &vipPool{allocated: make(map[netip.Addr]string)}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VIP pool %q: %w", cidr, err)
+		}
+		if prefix != prefix.Masked() {
+			return nil, fmt.Errorf("invalid VIP pool %q: host bits are set, use %s", cidr, prefix.Masked())
+		}
+		pool.prefixes = append(pool.prefixes, prefix)
+	}
+	if len(pool.prefixes) == 0 {
+		return nil, fmt.Errorf("at least one VIP pool is required")
+	}
+	return pool, nil
+}
+
+// contains reports whether the address belongs to one of the prefixes of the pool.
+func (p *vipPool) contains(addr netip.Addr) bool {
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// assign allocates the address to the owner and reports whether it succeeded, i.e. the address belongs to the pool
+// and is free or already allocated to the owner. The previous address of the owner is released.
+func (p *vipPool) assign(addr netip.Addr, owner string) bool {
+	if !p.contains(addr) {
+		return false
+	}
+	if current, ok := p.allocated[addr]; ok {
+		return current == owner
+	}
+	p.release(owner)
+	p.allocated[addr] = owner
+	return true
+}
+
+// allocate allocates the first free address of the pool to the owner. The network and broadcast addresses of the
+// IPv4 prefixes are skipped, except for /31 and /32 prefixes.
+func (p *vipPool) allocate(owner string) (netip.Addr, bool) {
+	for _, prefix := range p.prefixes {
+		for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			if addr.Is4() && prefix.Bits() < 31 && (addr == prefix.Addr() || !prefix.Contains(addr.Next())) {
+				continue
+			}
+			if _, ok := p.allocated[addr]; !ok {
+				p.release(owner)
+				p.allocated[addr] = owner
+				return addr, true
+			}
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// owned returns the address allocated to the owner.
+func (p *vipPool) owned(owner string) (netip.Addr, bool) {
+	for addr, current := range p.allocated {
+		if current == owner {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// release frees the address allocated to the owner, if any.
+func (p *vipPool) release(owner string) {
+	if addr, ok := p.owned(owner); ok {
+		delete(p.allocated, addr)
+	}
+}
+
+// reset frees all addresses.
+func (p *vipPool) reset() {
+	clear(p.allocated)
+}
@@ -0,0 +1,109 @@
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+const (
+	apiGroup   = "corebgp.io"                      // apiGroup is the API group of the Announcement resource.
+	apiVersion = "v1alpha1"                        // apiVersion is the served version of the Announcement resource.
+	finalizer  = "corebgp.io/announcement-cleanup" // finalizer keeps the resource until the announcement is deleted from CoreBGP.
+
+	conditionSynced = "Synced" // conditionSynced reports whether the spec of the resource is applied to CoreBGP.
+)
+
+// resource is an Announcement custom resource. Its spec holds the announcement with the field names of the
+// CoreBGP API, without meta and status.
+type resource struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   objectMeta      `json:"metadata"`
+	Spec       json.RawMessage `json:"spec,omitempty"`
+	Status     resourceStatus  `json:"status,omitempty"`
+}
+
+// objectMeta holds the metadata fields of a Kubernetes object used by the operator. Other fields are dropped, which
+// is safe since the operator writes only the status and the finalizers.
+type objectMeta struct {
+	Name              string   `json:"name"`
+	Namespace         string   `json:"namespace"`
+	UID               string   `json:"uid,omitempty"`
+	ResourceVersion   string   `json:"resourceVersion,omitempty"`
+	Generation        int64    `json:"generation,omitempty"`
+	DeletionTimestamp *string  `json:"deletionTimestamp,omitempty"`
+	Finalizers        []string `json:"finalizers,omitempty"`
+}
+
+// listMeta holds the metadata of a list response.
+type listMeta struct {
+	ResourceVersion string `json:"resourceVersion"`
+}
+
+// resourceList is the response of an Announcement list request.
+type resourceList struct {
+	Metadata listMeta   `json:"metadata"`
+	Items    []resource `json:"items"`
+}
+
+// resourceStatus is the status of an Announcement resource: the status reported by CoreBGP, extended by the
+// Synced condition of the operator and the generation of the spec it describes.
+type resourceStatus struct {
+	model.Status
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// key returns the namespace/name key of the resource.
+func (r *resource) key() string {
+	return r.Metadata.Namespace + "/" + r.Metadata.Name
+}
+
+// path returns the Kubernetes API path of the resource.
+func (r *resource) path() string {
+	return resourcePath(r.Metadata.Namespace, r.Metadata.Name, "")
+}
+
+// project returns the CoreBGP project of the resource, which defaults to the namespace.
+func (r *resource) project() string {
+	var spec struct {
+		Project string `json:"project"`
+	}
+	if err := json.Unmarshal(r.Spec, &spec); err == nil && spec.Project != "" {
+		return spec.Project
+	}
+	return r.Metadata.Namespace
+}
+
+// announcementID returns the project/name ID of the announcement of the resource in CoreBGP.
+func (r *resource) announcementID() string {
+	return r.project() + "/" + r.Metadata.Name
+}
+
+// announcement converts the spec to the announcement applied to CoreBGP.
+func (r *resource) announcement() (*model.Announcement, error) {
+	var announcement model.Announcement
+	if len(r.Spec) > 0 {
+		if err := json.Unmarshal(r.Spec, &announcement); err != nil {
+			return nil, fmt.Errorf("invalid spec: %w", err)
+		}
+	}
+
+	// The identity and the status are owned by the resource and CoreBGP respectively
+	announcement.Meta = model.Meta{Project: r.project(), Name: r.Metadata.Name}
+	announcement.Status = model.Status{}
+	announcement.LastApplied = nil
+	return &announcement, nil
+}
+
+// hasFinalizer reports whether the cleanup finalizer of the operator is set.
+func (r *resource) hasFinalizer() bool {
+	return slices.Contains(r.Metadata.Finalizers, finalizer)
+}
+
+// deleting reports whether the resource is being deleted and waits for its finalizers.
+func (r *resource) deleting() bool {
+	return r.Metadata.DeletionTimestamp != nil
+}
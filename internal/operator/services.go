@@ -0,0 +1,491 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sync"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+const (
+	serviceFinalizer = "corebgp.io/service-lb-cleanup"                           // serviceFinalizer keeps the Service until its announcement is deleted and its VIP released.
+	excludeLabel     = "node.kubernetes.io/exclude-from-external-load-balancers" // excludeLabel marks the nodes that must not receive load balancer traffic.
+)
+
+// service holds the fields of a Kubernetes Service used by the Service LoadBalancer controller.
+type service struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Type              string  `json:"type"`
+		LoadBalancerClass *string `json:"loadBalancerClass,omitempty"`
+		LoadBalancerIP    string  `json:"loadBalancerIP,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer struct {
+			Ingress []loadBalancerIngress `json:"ingress,omitempty"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+// loadBalancerIngress is an address of a load balancer in the status of a Service.
+type loadBalancerIngress struct {
+	IP       string `json:"ip,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// serviceList is the response of a Service list request.
+type serviceList struct {
+	Metadata listMeta  `json:"metadata"`
+	Items    []service `json:"items"`
+}
+
+// node holds the fields of a Kubernetes Node used by the Service LoadBalancer controller.
+type node struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels,omitempty"`
+	} `json:"metadata"`
+	Status struct {
+		Addresses []struct {
+			Type    string `json:"type"`
+			Address string `json:"address"`
+		} `json:"addresses"`
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// nodeList is the response of a Node list request.
+type nodeList struct {
+	Metadata listMeta `json:"metadata"`
+	Items    []node   `json:"items"`
+}
+
+// servicesPath returns the API path of the Services of the namespace, all namespaces if empty, or of a single
+// Service and its subresource.
+func servicesPath(namespace, name, subresource string) string {
+	path := "/api/v1"
+	if namespace != "" {
+		path += "/namespaces/" + url.PathEscape(namespace)
+	}
+	path += "/services"
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	if subresource != "" {
+		path += "/" + subresource
+	}
+	return path
+}
+
+// nodesPath is the API path of the Nodes.
+const nodesPath = "/api/v1/nodes"
+
+// key returns the namespace/name key of the Service.
+func (s *service) key() string {
+	return s.Metadata.Namespace + "/" + s.Metadata.Name
+}
+
+// path returns the Kubernetes API path of the Service.
+func (s *service) path() string {
+	return servicesPath(s.Metadata.Namespace, s.Metadata.Name, "")
+}
+
+// hasFinalizer reports whether the cleanup finalizer of the controller is set.
+func (s *service) hasFinalizer() bool {
+	return slices.Contains(s.Metadata.Finalizers, serviceFinalizer)
+}
+
+// deleting reports whether the Service is being deleted and waits for its finalizers.
+func (s *service) deleting() bool {
+	return s.Metadata.DeletionTimestamp != nil
+}
+
+// ready reports whether the node is ready and may receive load balancer traffic.
+func (n *node) ready() bool {
+	if _, ok := n.Metadata.Labels[excludeLabel]; ok {
+		return false
+	}
+	for _, condition := range n.Status.Conditions {
+		if condition.Type == "Ready" {
+			return condition.Status == "True"
+		}
+	}
+	return false
+}
+
+// internalIPs returns the internal addresses of the node.
+func (n *node) internalIPs() []netip.Addr {
+	var addrs []netip.Addr
+	for _, address := range n.Status.Addresses {
+		if address.Type != "InternalIP" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(address.Address); err == nil {
+			addrs = append(addrs, addr.Unmap())
+		}
+	}
+	return addrs
+}
+
+// serviceController allocates VIPs to the Services of type LoadBalancer, announces them via the ready nodes and
+// records them in the status of the Services. All reconciliations are serialized by mu.
+type serviceController struct {
+	kube      *kubeClient
+	api       v1.AnnouncementsInterface
+	manager   string
+	project   string
+	class     string
+	namespace string
+
+	mu       sync.Mutex
+	pool     *vipPool
+	services map[string]*service // services holds the last seen version of every Service keyed by namespace/name.
+	nodes    map[string]*node    // nodes holds the last seen version of every Node keyed by name.
+	applied  map[string]string   // applied holds the last announcement applied for every Service, to skip unchanged applies.
+}
+
+// newServiceController creates the controller of the Services of the namespace, or of all namespaces if empty. The
+// announcements are created in the project. Services with the load balancer class are handled, or those without a
+// class if it is empty.
+func newServiceController(kube *kubeClient, api v1.AnnouncementsInterface, pool *vipPool, manager, project, class, namespace string) *serviceController {
+	return &serviceController{
+		kube:      kube,
+		api:       api,
+		manager:   manager,
+		project:   project,
+		class:     class,
+		namespace: namespace,
+		pool:      pool,
+		services:  make(map[string]*service),
+		nodes:     make(map[string]*node),
+		applied:   make(map[string]string),
+	}
+}
+
+// run lists and watches the Nodes and the Services until the context is canceled.
+func (c *serviceController) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.kube.listAndWatch(ctx, nodesPath, c.relistNodes, func(eventType string, object json.RawMessage) {
+			var n node
+			if err := json.Unmarshal(object, &n); err != nil {
+				slog.Error("failed to decode node", "error", err)
+				return
+			}
+			c.handleNodeEvent(ctx, eventType, &n)
+		})
+	}()
+
+	c.kube.listAndWatch(ctx, servicesPath(c.namespace, "", ""), c.relistServices, func(eventType string, object json.RawMessage) {
+		var svc service
+		if err := json.Unmarshal(object, &svc); err != nil {
+			slog.Error("failed to decode service", "error", err)
+			return
+		}
+		c.handleServiceEvent(ctx, eventType, &svc)
+	})
+	wg.Wait()
+}
+
+// relistNodes replaces the known nodes and reconciles all Services with the new next hops.
+func (c *serviceController) relistNodes(ctx context.Context) (string, error) {
+	var list nodeList
+	if err := c.kube.get(ctx, nodesPath, &list); err != nil {
+		return "", fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nodes = make(map[string]*node, len(list.Items))
+	for i := range list.Items {
+		c.nodes[list.Items[i].Metadata.Name] = &list.Items[i]
+	}
+	for _, svc := range c.services {
+		c.reconcile(ctx, svc)
+	}
+	return list.Metadata.ResourceVersion, nil
+}
+
+// relistServices replaces the known Services, rebuilds the VIP allocations from their status and reconciles them.
+// The announcements of the Services removed while the watch was down are deleted.
+func (c *serviceController) relistServices(ctx context.Context) (string, error) {
+	var list serviceList
+	if err := c.kube.get(ctx, servicesPath(c.namespace, "", ""), &list); err != nil {
+		return "", fmt.Errorf("failed to list services: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.services
+	c.services = make(map[string]*service, len(list.Items))
+	c.pool.reset()
+	for i := range list.Items {
+		svc := &list.Items[i]
+		c.services[svc.key()] = svc
+		delete(previous, svc.key())
+
+		// Keep the VIPs already assigned, so that they do not move between Services
+		if c.manages(svc) && !svc.deleting() {
+			for _, ingress := range svc.Status.LoadBalancer.Ingress {
+				if addr, err := netip.ParseAddr(ingress.IP); err == nil {
+					c.pool.assign(addr, svc.key())
+				}
+			}
+		}
+	}
+	for _, svc := range previous {
+		if svc.hasFinalizer() {
+			c.deleteAnnouncement(ctx, svc)
+		}
+	}
+	for _, svc := range c.services {
+		c.reconcile(ctx, svc)
+	}
+	return list.Metadata.ResourceVersion, nil
+}
+
+// resync applies the announcements of all Services again, which corrects changes made outside the operator.
+func (c *serviceController) resync(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.applied)
+	for _, svc := range c.services {
+		c.reconcile(ctx, svc)
+	}
+}
+
+// handleNodeEvent updates the known nodes and reconciles all Services if the next hops changed.
+func (c *serviceController) handleNodeEvent(ctx context.Context, eventType string, n *node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	before := c.nextHops()
+	switch eventType {
+	case "ADDED", "MODIFIED":
+		c.nodes[n.Metadata.Name] = n
+	case "DELETED":
+		delete(c.nodes, n.Metadata.Name)
+	}
+	if slices.Equal(before, c.nextHops()) {
+		return
+	}
+
+	slog.Info("node addresses changed, updating load balancer announcements", "node", n.Metadata.Name)
+	for _, svc := range c.services {
+		c.reconcile(ctx, svc)
+	}
+}
+
+// handleServiceEvent reconciles the Service of a watch event.
+func (c *serviceController) handleServiceEvent(ctx context.Context, eventType string, svc *service) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch eventType {
+	case "ADDED", "MODIFIED":
+		c.services[svc.key()] = svc
+		c.reconcile(ctx, svc)
+	case "DELETED":
+		// The finalizer normally cleans up first, this covers Services whose finalizer was removed
+		delete(c.services, svc.key())
+		if svc.hasFinalizer() {
+			c.deleteAnnouncement(ctx, svc)
+		}
+	}
+}
+
+// manages reports whether the Service is a LoadBalancer handled by the controller.
+func (c *serviceController) manages(svc *service) bool {
+	if svc.Spec.Type != "LoadBalancer" {
+		return false
+	}
+	if svc.Spec.LoadBalancerClass == nil {
+		return c.class == ""
+	}
+	return *svc.Spec.LoadBalancerClass == c.class
+}
+
+// reconcile announces the VIP of the Service via the ready nodes and records it in the status of the Service. A
+// Service that is deleted or no longer handled has its announcement deleted and its VIP released before the
+// finalizer is removed.
+func (c *serviceController) reconcile(ctx context.Context, svc *service) {
+	if svc.deleting() || !c.manages(svc) {
+		if svc.hasFinalizer() {
+			c.cleanup(ctx, svc)
+		}
+		return
+	}
+
+	if !svc.hasFinalizer() {
+		var updated service
+		if err := c.kube.setFinalizers(ctx, svc.path(), svc.Metadata.ResourceVersion, append(slices.Clone(svc.Metadata.Finalizers), serviceFinalizer), &updated); err != nil {
+			// A conflict means a newer version is on its way through the watch
+			if !isConflict(err) && !isNotFound(err) {
+				slog.Error("failed to add finalizer", "service", svc.key(), "error", err)
+			}
+			return
+		}
+		svc = &updated
+		c.services[svc.key()] = svc
+	}
+
+	vip, err := c.assignVIP(svc)
+	if err != nil {
+		slog.Error("failed to assign VIP", "service", svc.key(), "error", err)
+		return
+	}
+
+	if err := c.applyAnnouncement(ctx, svc, vip); err != nil {
+		slog.Error("failed to apply announcement", "service", svc.key(), "vip", vip.String(), "error", err)
+		return
+	}
+
+	ingress := []loadBalancerIngress{{IP: vip.String()}}
+	if slices.Equal(svc.Status.LoadBalancer.Ingress, ingress) {
+		return
+	}
+	if err := c.setIngress(ctx, svc, ingress); err != nil {
+		slog.Error("failed to update service status", "service", svc.key(), "error", err)
+		return
+	}
+	slog.Info("assigned VIP", "service", svc.key(), "vip", vip.String())
+}
+
+// assignVIP returns the VIP of the Service: the requested load balancer IP if set, the VIP it already has or the
+// next free address of the pool.
+func (c *serviceController) assignVIP(svc *service) (netip.Addr, error) {
+	if svc.Spec.LoadBalancerIP != "" {
+		addr, err := netip.ParseAddr(svc.Spec.LoadBalancerIP)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("invalid load balancer IP %q", svc.Spec.LoadBalancerIP)
+		}
+		if !c.pool.assign(addr, svc.key()) {
+			return netip.Addr{}, fmt.Errorf("load balancer IP %s is not in the pool or is already in use", addr)
+		}
+		return addr, nil
+	}
+
+	if addr, ok := c.pool.owned(svc.key()); ok {
+		return addr, nil
+	}
+	addr, ok := c.pool.allocate(svc.key())
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("VIP pool is exhausted")
+	}
+	return addr, nil
+}
+
+// nextHops returns the sorted internal addresses of the ready nodes.
+func (c *serviceController) nextHops() []netip.Addr {
+	var addrs []netip.Addr
+	for _, n := range c.nodes {
+		if n.ready() {
+			addrs = append(addrs, n.internalIPs()...)
+		}
+	}
+	slices.SortFunc(addrs, func(a, b netip.Addr) int { return a.Compare(b) })
+	return slices.Compact(addrs)
+}
+
+// announcementName returns the name of the announcement of the Service, which is unique since neither namespaces
+// nor Service names contain dots.
+func (c *serviceController) announcementName(svc *service) string {
+	return svc.Metadata.Namespace + "." + svc.Metadata.Name
+}
+
+// applyAnnouncement announces the VIP via the ready nodes of its address family. The apply is skipped if the same
+// announcement was applied before.
+func (c *serviceController) applyAnnouncement(ctx context.Context, svc *service, vip netip.Addr) error {
+	announcement := &model.Announcement{
+		Meta:      model.Meta{Project: c.project, Name: c.announcementName(svc)},
+		Addresses: model.Addresses{AnnouncedIP: vip.String()},
+	}
+	for _, addr := range c.nextHops() {
+		if addr.Is4() == vip.Is4() {
+			announcement.NextHops = append(announcement.NextHops, model.Subnet{IP: addr.String(), Mask: uint8(addr.BitLen())})
+		}
+	}
+	if len(announcement.NextHops) == 0 {
+		return fmt.Errorf("no ready node has an internal address of the address family of %s", vip)
+	}
+
+	desired, err := json.Marshal(announcement)
+	if err != nil {
+		return err
+	}
+	if c.applied[svc.key()] == string(desired) {
+		return nil
+	}
+	if _, err := c.api.V1ApplyAnnouncement(ctx, c.manager, announcement); err != nil {
+		return err
+	}
+	c.applied[svc.key()] = string(desired)
+	return nil
+}
+
+// setIngress replaces the load balancer addresses in the status of the Service.
+func (c *serviceController) setIngress(ctx context.Context, svc *service, ingress []loadBalancerIngress) error {
+	patch := map[string]interface{}{
+		"status": map[string]interface{}{
+			"loadBalancer": map[string]interface{}{"ingress": ingress},
+		},
+	}
+	var updated service
+	if err := c.kube.mergePatch(ctx, servicesPath(svc.Metadata.Namespace, svc.Metadata.Name, "status"), patch, &updated); err != nil {
+		return err
+	}
+	c.services[svc.key()] = &updated
+	return nil
+}
+
+// cleanup deletes the announcement of the Service, releases its VIP and removes the finalizer. A Service that is
+// no longer a handled LoadBalancer also has the VIP removed from its status.
+func (c *serviceController) cleanup(ctx context.Context, svc *service) {
+	if !c.deleteAnnouncement(ctx, svc) {
+		return
+	}
+
+	if !svc.deleting() && len(svc.Status.LoadBalancer.Ingress) > 0 {
+		if err := c.setIngress(ctx, svc, nil); err != nil && !isNotFound(err) {
+			slog.Error("failed to update service status", "service", svc.key(), "error", err)
+			return
+		}
+		svc = c.services[svc.key()]
+	}
+
+	finalizers := slices.DeleteFunc(slices.Clone(svc.Metadata.Finalizers), func(f string) bool { return f == serviceFinalizer })
+	var updated service
+	if err := c.kube.setFinalizers(ctx, svc.path(), svc.Metadata.ResourceVersion, finalizers, &updated); err != nil && !isNotFound(err) {
+		slog.Error("failed to remove finalizer", "service", svc.key(), "error", err)
+	}
+}
+
+// deleteAnnouncement deletes the announcement of the Service from CoreBGP, releases its VIP and reports whether the
+// announcement is gone.
+func (c *serviceController) deleteAnnouncement(ctx context.Context, svc *service) bool {
+	err := c.api.V1DeleteAnnouncement(ctx, c.project, c.announcementName(svc))
+	if err != nil && !errors.Is(err, v1.ErrNotFound) {
+		slog.Error("failed to delete announcement", "service", svc.key(), "error", err)
+		return false
+	}
+	delete(c.applied, svc.key())
+	c.pool.release(svc.key())
+	slog.Info("released VIP", "service", svc.key())
+	return true
+}
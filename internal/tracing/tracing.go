@@ -0,0 +1,133 @@
+// Package tracing configures the OpenTelemetry tracing shared by the CoreBGP components and provides the
+// instrumentation of their HTTP handlers and gRPC clients.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// instrumentationName is the name of the tracer of the CoreBGP spans.
+const instrumentationName = "github.com/nikitamishagin/corebgp"
+
+// Setup installs the global tracer provider that exports the spans of the service to the OTLP/HTTP endpoint, e.g.,
+// "http://collector:4318". With an empty endpoint tracing stays disabled and only the trace context of incoming
+// requests is propagated. The returned function flushes the pending spans and stops the exporter.
+func Setup(ctx context.Context, endpoint, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the CoreBGP spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// RecordError marks the span as failed with the error. A nil error is ignored.
+func RecordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Middleware returns the gin middleware that continues the trace of the incoming request and wraps the handler in
+// a server span named after the route template.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
+
+// UnaryClientInterceptor returns the gRPC interceptor that wraps every unary call to the system, e.g., "etcd", in
+// a client span named after the method.
+func UnaryClientInterceptor(system string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := startRPCSpan(ctx, system, method)
+		defer span.End()
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		RecordError(span, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns the gRPC interceptor that wraps the start of every streaming call to the system
+// in a client span. The span ends once the stream is established, not when it is drained.
+func StreamClientInterceptor(system string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := startRPCSpan(ctx, system, method)
+		defer span.End()
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		RecordError(span, err)
+		return stream, err
+	}
+}
+
+// startRPCSpan starts the client span of the gRPC method of the system.
+func startRPCSpan(ctx context.Context, system, method string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, system+"/"+path.Base(method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(path.Dir(method)[1:]),
+			semconv.RPCMethod(path.Base(method)),
+			attribute.String("peer.service", system),
+		),
+	)
+}
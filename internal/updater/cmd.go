@@ -3,41 +3,164 @@ package updater
 import (
 	"context"
 	"fmt"
+	"github.com/nikitamishagin/corebgp/internal/bfd"
+	"github.com/nikitamishagin/corebgp/internal/configfile"
+	"github.com/nikitamishagin/corebgp/internal/healthcheck"
+	"github.com/nikitamishagin/corebgp/internal/logging"
 	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
 	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 // RootCmd initializes and returns the root command for the CoreBGP API server application.
 func RootCmd() *cobra.Command {
-	var config model.UpdaterConfig
+	var (
+		config      model.UpdaterConfig
+		configPath  string
+		routerSpecs []string
+		logLevel    slog.LevelVar
+	)
 	var cmd = &cobra.Command{
 		Use:   "updater",
 		Short: "CoreBGP update controller",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := configfile.Load(cmd.Flags(), "config", &config); err != nil {
+				return err
+			}
+
+			logger, logFile, err := logging.New(logging.Config{
+				Path:       config.LogPath,
+				Format:     config.LogFormat,
+				Verbose:    config.Verbose,
+				MaxSize:    config.LogMaxSize,
+				MaxBackups: config.LogMaxBackups,
+				LevelVar:   &logLevel,
+			})
+			if err != nil {
+				return err
+			}
+			defer logFile.Close()
+			slog.SetDefault(logger)
+
+			shutdownTracing, err := tracing.Setup(cmd.Context(), config.OTLPEndpoint, "corebgp-updater")
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := shutdownTracing(context.Background()); err != nil {
+					slog.Error("failed to flush traces", "error", err)
+				}
+			}()
+
 			// Create a context with cancel function for managing the goroutines
 			ctx, cancel := context.WithCancel(cmd.Context())
 			defer cancel()
 
-			// Initialize the new GoBGP client
-			goBGPClient, err := NewGoBGPClient(&config.GoBGPEndpoint, &config.GoBGPCACert, &config.GoBGPClientCert, &config.GoBGPClientKey)
+			// Shut down gracefully on SIGTERM or SIGINT
+			shutdownSignals := make(chan os.Signal, 1)
+			signal.Notify(shutdownSignals, syscall.SIGTERM, os.Interrupt)
+			defer signal.Stop(shutdownSignals)
+			go func() {
+				select {
+				case sig := <-shutdownSignals:
+					slog.Info("shutting down", "signal", sig.String())
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			clk := clock.RealClock{}
+
+			if err := validateWeightEncoding(config.NextHopWeightEncoding); err != nil {
+				return err
+			}
+			if err := validateAddressFamilies(config.EnabledAddressFamilies); err != nil {
+				return err
+			}
+			if err := validateBGPBackend(config, routerSpecs); err != nil {
+				return err
+			}
+			if err := validateDampening(config.Dampening); err != nil {
+				return err
+			}
+
+			// Normalize the GoBGP endpoint before dialing
+			goBGPEndpoint, err := ParseGoBGPEndpoint(config.GoBGPEndpoint)
 			if err != nil {
 				return err
 			}
-			defer goBGPClient.Close()
+			config.GoBGPEndpoint = goBGPEndpoint.String()
+
+			// Parse the GoBGP routers programmed in parallel, the TLS settings default to the single-router flags
+			defaultRouter := config.Routers()[0]
+			for _, s := range routerSpecs {
+				router, err := parseGoBGPRouter(s, defaultRouter)
+				if err != nil {
+					return err
+				}
+				if slices.ContainsFunc(config.GoBGPRouters, func(r model.GoBGPRouter) bool { return r.Name == router.Name }) {
+					return fmt.Errorf("duplicate GoBGP router name %q", router.Name)
+				}
+				config.GoBGPRouters = append(config.GoBGPRouters, router)
+			}
 
+			// Initialize the clients of the BGP daemons
 			// TODO: Implement configuration checking
-			_, err = goBGPClient.GetBGP()
+			var routers []*router
+			switch config.BGPBackend {
+			case model.BGPBackendFRR:
+				routers, err = connectFRR(config.FRRVtyshPath)
+			case model.BGPBackendKernel:
+				routers, err = connectKernel(config.KernelTable, config.KernelMetric)
+			default:
+				routers, err = connectRouters(config.Routers())
+			}
 			if err != nil {
 				return err
 			}
-
-			// TODO: Implement reconnection
+			defer closeRouters(routers)
 
 			// Initialize the CoreBGP API client
-			apiClient := v1.NewAPIClient(&config.APIEndpoint, time.Second*5)
+			var clientOpts []v1.ClientOption
+			if config.APICACert != "" || config.APIClientCert != "" || config.APIInsecure {
+				tlsConfig, err := v1.NewTLSConfig(v1.ClientConfig{
+					CACert:             config.APICACert,
+					ClientCert:         config.APIClientCert,
+					ClientKey:          config.APIClientKey,
+					InsecureSkipVerify: config.APIInsecure,
+				})
+				if err != nil {
+					return err
+				}
+				clientOpts = append(clientOpts, v1.WithTLSConfig(tlsConfig))
+			}
+			if config.APITokenFile != "" {
+				token, err := os.ReadFile(config.APITokenFile)
+				if err != nil {
+					return fmt.Errorf("could not read API token: %w", err)
+				}
+				clientOpts = append(clientOpts, v1.WithBearerToken(strings.TrimSpace(string(token))))
+			}
+			// Retry the requests throttled by the API server after the delay it asks for, and the idempotent requests
+			// failing with a connection or server error
+			clientOpts = append(clientOpts, v1.WithThrottleRetries(3), v1.WithRetryPolicy(v1.DefaultRetryPolicy))
+			// Revalidate the periodic lists with their ETags, so that unchanged ones are not transferred again
+			clientOpts = append(clientOpts, v1.WithETagCache(16))
+			apiClient := v1.NewAPIClient(&config.APIEndpoint, time.Second*5, clientOpts...)
 
 			// Check if CoreBGP API server is healthy
 			err = apiClient.V1HealthCheck(ctx)
@@ -45,9 +168,101 @@ func RootCmd() *cobra.Command {
 				return err
 			}
 
+			// Wait for the leadership before touching GoBGP, so that only one replica programs the daemon
+			var leadershipLost atomic.Bool
+			if config.LeaderElection.Enabled {
+				elector, err := newLeaderElector(config.LeaderElection)
+				if err != nil {
+					return err
+				}
+				defer elector.close()
+
+				if err := elector.campaign(ctx); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return err
+				}
+
+				// Stop programming as soon as another replica may have taken over
+				go func() {
+					select {
+					case <-elector.lost():
+						slog.Error("lost leadership, stopping")
+						leadershipLost.Store(true)
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+			}
+
+			// Start the GoBGP server with the configured global parameters
+			if config.ConfigureGoBGPGlobal {
+				if err := apiClient.V1ConfigureGoBGP(ctx, config.GoBGPGlobal); err != nil {
+					return err
+				}
+			}
+
+			// Withdraw and restore the routes via the next hops that fail or pass their health checks
+			var monitor *healthcheck.Monitor
+			var sessions *bfd.Manager
+			monitor = healthcheck.NewMonitor(clk, config.Dampening, func(announcement model.Announcement, nextHop string, healthy bool) {
+				if err := handleHealthChange(routers, &announcement, nextHop, healthy, config.EnabledAddressFamilies, config.NextHopWeightEncoding, monitor, sessions); err != nil {
+					slog.Error("failed to apply health change", "error", err)
+				}
+				if err := reportHealthStatus(ctx, apiClient, &announcement, config.Site, monitor); err != nil {
+					slog.Error("failed to report health status", "error", err)
+				}
+			})
+			monitor.SetDefaults(config.HealthCheckDefaults)
+			defer monitor.Stop()
+
+			// Withdraw the routes of the announcements as soon as one of their BFD sessions goes down
+			sessions, err = bfd.NewManager(config.BFD, func(announcement model.Announcement, up bool) {
+				if err := handleBFDChange(routers, &announcement, up, config.EnabledAddressFamilies, config.NextHopWeightEncoding, monitor); err != nil {
+					slog.Error("failed to apply BFD change", "error", err)
+				}
+				if err := reportBFDStatus(ctx, apiClient, &announcement, sessions); err != nil {
+					slog.Error("failed to report BFD status", "error", err)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			defer sessions.Stop()
+
 			// Create a channel to process events
 			events := make(chan model.Event, 100) // Buffered channel to handle bursts of events
-			defer close(events)
+
+			// Re-process the announcements at the start and at the end of their schedule
+			schedules := newScheduler(clk, func(announcement model.Announcement) {
+				select {
+				case events <- model.Event{Type: model.EventUpdated, Announcement: announcement}:
+				case <-ctx.Done():
+				}
+			})
+			defer schedules.Stop()
+
+			// Reload the configuration file, certificates and endpoints on SIGHUP without restarting the watch loop
+			handleReloadSignals(ctx, func() error {
+				return reloadConfig(cmd.Flags(), configPath, config, routers, apiClient, &logLevel, monitor)
+			})
+
+			// Program the existing announcements and withdraw the stale paths before consuming the watch events
+			resync := func() error {
+				return reconcile(ctx, apiClient, config.Site, routers, config.EnabledAddressFamilies, config.NextHopWeightEncoding, monitor, sessions, schedules)
+			}
+			if err := resync(); err != nil {
+				return err
+			}
+
+			// The updater registers with its site under the identity of the leader election
+			var identity string
+			if config.Site != "" {
+				if identity, err = replicaIdentity(config.LeaderElection.Identity); err != nil {
+					return err
+				}
+			}
 
 			// Create a WaitGroup to manage goroutines
 			var wg sync.WaitGroup
@@ -57,52 +272,290 @@ func RootCmd() *cobra.Command {
 			go func(ctx context.Context, cancel context.CancelFunc) {
 				defer wg.Done() // Decrement the WaitGroup counter when the goroutine ends
 
-				fmt.Println("Starting to watch announcements...")
-				err := apiClient.V1WatchAnnouncements(ctx, func(event model.Event) {
+				slog.Info("starting to watch announcements")
+				// Resume the watch from the last seen revision after connection drops, so that no event is missed
+				watcher := apiClient.NewResumableWatcher(func(event model.Event) {
 					// Push each incoming event into the channel
-					events <- event
+					select {
+					case events <- event:
+					case <-ctx.Done():
+					}
+				}, func() {
+					slog.Warn("watch history has a gap, re-listing announcements")
+					if err := resync(); err != nil {
+						slog.Error("resync failed", "error", err)
+					}
 				})
-				if err != nil {
-					fmt.Printf("Error while watching announcements: %v\n", err)
+				err := watcher.Run(ctx)
+				if err != nil && ctx.Err() == nil {
+					slog.Error("error while watching announcements", "error", err)
 					cancel() // Cancel the context in case of an error
 				}
 			}(ctx, cancel) // Pass both context and cancel as arguments
 
+			// Expose the Prometheus metrics
+			if config.MetricsAddress != "" {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					serveMetrics(ctx, config.MetricsAddress)
+				}()
+			}
+
+			// Goroutine for the periodic full resync that heals the RIB from missed events
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runPeriodicResync(ctx, config.ResyncInterval, resync)
+			}()
+
+			// Goroutine for reporting the latest probe results of the health-checked next hops
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runHealthReports(ctx, config.HealthReportInterval, apiClient, config.Site, monitor)
+			}()
+
+			// Goroutine for configuring the BGP neighbors from the peer resources and reporting their session states
+			if config.ManagePeers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					runPeerSync(ctx, config.PeerSyncInterval, func() error {
+						return syncPeers(ctx, apiClient, routers)
+					})
+				}()
+			}
+
+			// Goroutine for monitoring the connections to the GoBGP routers, re-programming the routers that reconnect
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				monitorRouters(ctx, routers, func() error {
+					if err := resync(); err != nil {
+						return err
+					}
+					if config.ManagePeers {
+						return syncPeers(ctx, apiClient, routers)
+					}
+					return nil
+				})
+			}()
+
+			// Goroutine for registering with the site, so that the site lists the updaters programming its routers
+			if config.Site != "" {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					runSiteRegistration(ctx, apiClient, config.Site, identity, routers)
+				}()
+			}
+
 			// Goroutine for processing events from the channel
 			wg.Add(1) // Increment the WaitGroup counter
 			go func() {
 				defer wg.Done() // Ensure the WaitGroup counter is decremented after processing ends
-				for event := range events {
+				for {
+					var event model.Event
+					select {
+					case <-ctx.Done():
+						return
+					case event = <-events:
+					}
+
 					// Handle each event in a separate goroutine
 					go func(ev model.Event) {
-						if err := handleAnnouncementEvent(goBGPClient, &ev); err != nil {
-							fmt.Printf("Failed to process event: %v\n", err)
+						_, span := tracing.Tracer().Start(ctx, "updater/HandleEvent", trace.WithAttributes(
+							attribute.String("event_type", string(ev.Type)),
+							attribute.String("announcement", ev.Announcement.Meta.Project+"/"+ev.Announcement.Meta.Name),
+							attribute.Int64("generation", ev.Announcement.Meta.Generation),
+							attribute.String("change_id", ev.Announcement.Meta.ChangeID),
+						))
+						defer span.End()
+
+						// Program the announcement as the site sees it
+						ev = siteEvent(ev, config.Site)
+
+						// Skip announcements of address families that are not configured in GoBGP
+						if !addressFamilyEnabled(config.EnabledAddressFamilies, &ev.Announcement) {
+							if ev.Type != model.EventDeleted {
+								if err := reportUnsupportedAddressFamily(ctx, apiClient, &ev.Announcement); err != nil {
+									slog.Error("failed to report unsupported address family", "error", err)
+								}
+							}
+							return
+						}
+
+						errs, err := handleAnnouncementEvent(routers, &ev, config.EnabledAddressFamilies, config.NextHopWeightEncoding, monitor, sessions, schedules)
+						if err == nil {
+							err = joinRouterErrors(errs)
+						}
+						if err != nil {
+							tracing.RecordError(span, err)
+							slog.Error("failed to process event", "change_id", ev.Announcement.Meta.ChangeID, "error", err)
+						}
+
+						// Report the programming state of the announcements that are still announced; errs is nil when the
+						// event was not applied to the routers at all
+						if errs == nil || ev.Type == model.EventDeleted || ev.Announcement.Status.Status == model.StatusWithdrawn || !scheduled(&ev.Announcement, schedules) {
+							return
+						}
+						if err := reportProgrammingStatus(ctx, apiClient, &ev.Announcement, config.Site, routers, errs, monitor, sessions); err != nil {
+							slog.Error("failed to report programming status", "error", err)
 						}
 					}(event)
 				}
 			}()
 
-			// Graceful shutdown: Ensure events channel is closed when the context is done
-			go func() {
-				<-ctx.Done()  // Wait for context cancellation or deadline
-				close(events) // Close the channel to signal worker goroutines to stop
-			}()
-			fmt.Println("Updater is running. Listening for events and performing tasks...")
+			slog.Info("updater is running")
 
 			// Wait for all goroutines to finish
 			wg.Wait()
 
-			return nil
+			// Stop the health checks, the BFD sessions and the schedules, so that they do not restore the withdrawn paths
+			monitor.Stop()
+			sessions.Stop()
+			schedules.Stop()
+			if leadershipLost.Load() {
+				// The paths now belong to the new leader and must not be withdrawn
+				return fmt.Errorf("leadership lost")
+			}
+			if !config.WithdrawOnShutdown {
+				slog.Info("leaving the programmed paths in place")
+				return nil
+			}
+			return drain(routers, config.EnabledAddressFamilies, config.ShutdownGracePeriod, shutdownSignals)
 		},
 	}
 
+	cmd.Flags().StringVar(&configPath, "config", "", "Path to the YAML or TOML (.toml) configuration file; flags and COREBGP_* environment variables take precedence, "+
+		"the log verbosity, the health check defaults and the API endpoint are re-read on SIGHUP")
 	cmd.Flags().StringVar(&config.APIEndpoint, "api-endpoint", "http://localhost:8080", "URL of the API server")
-	cmd.Flags().StringVar(&config.GoBGPEndpoint, "gobgp-endpoint", "localhost:50051", "GoBGP gRPC endpoint")
+	cmd.Flags().StringVar(&config.APICACert, "api-ca-cert", "", "Path to CA certificate used to verify the API server")
+	cmd.Flags().StringVar(&config.APIClientCert, "api-client-cert", "", "Path to client certificate presented to the API server")
+	cmd.Flags().StringVar(&config.APIClientKey, "api-client-key", "", "Path to client key presented to the API server")
+	cmd.Flags().BoolVar(&config.APIInsecure, "api-insecure-skip-verify", false, "Skip verification of the API server certificate (testing only)")
+	cmd.Flags().StringVar(&config.APITokenFile, "api-token-file", "", "Path to the file with the bearer token used to authenticate to the API server")
+	cmd.Flags().StringVar(&config.GoBGPEndpoint, "gobgp-endpoint", "127.0.0.1:50051", "GoBGP gRPC endpoint in ip:port form (IPv6 in brackets)")
 	cmd.Flags().StringVar(&config.GoBGPCACert, "gobgp-ca-cert", "", "Path to CA certificate")
 	cmd.Flags().StringVar(&config.GoBGPClientCert, "gobgp-client-cert", "", "Path to client certificate")
 	cmd.Flags().StringVar(&config.GoBGPClientKey, "gobgp-client-key", "", "Path to client key")
-	cmd.Flags().StringVar(&config.LogPath, "log-path", "/var/log/corebgp/updater.log", "Path to the log file")
-	cmd.Flags().Int8VarP(&config.Verbose, "verbose", "v", 0, "Verbosity level")
+	cmd.Flags().StringArrayVar(&routerSpecs, "gobgp-router", nil, "GoBGP router programmed in parallel with the others, as comma separated name=,endpoint=,ca-cert=,client-cert=,client-key= pairs; "+
+		"repeat for every router (replaces --gobgp-endpoint, the certificates default to the --gobgp-* flags)")
+	cmd.Flags().StringVar(&config.BGPBackend, "bgp-backend", model.BGPBackendGoBGP, "Where the paths are programmed: gobgp, frr for the local FRRouting daemon configured through vtysh, or kernel for the Linux routing table")
+	cmd.Flags().StringVar(&config.FRRVtyshPath, "frr-vtysh-path", "vtysh", "Path to the vtysh binary used to configure FRR when --bgp-backend is frr")
+	cmd.Flags().Uint32Var(&config.KernelTable, "kernel-table", 254, "Kernel routing table the routes are installed into when --bgp-backend is kernel (254 is the main table)")
+	cmd.Flags().Uint32Var(&config.KernelMetric, "kernel-metric", 20, "Metric of the kernel routes installed when --bgp-backend is kernel; routes with other metrics are left untouched")
+	cmd.Flags().BoolVar(&config.ConfigureGoBGPGlobal, "gobgp-configure-global", false, "Configure the GoBGP global parameters via the API server on startup")
+	cmd.Flags().Uint32Var(&config.GoBGPGlobal.ASN, "gobgp-asn", 0, "Local AS number applied when --gobgp-configure-global is set")
+	cmd.Flags().StringVar(&config.GoBGPGlobal.RouterID, "gobgp-router-id", "", "Router ID applied when --gobgp-configure-global is set")
+	cmd.Flags().Int32Var(&config.GoBGPGlobal.ListenPort, "gobgp-listen-port", 179, "BGP listen port applied when --gobgp-configure-global is set (-1 disables listening)")
+	cmd.Flags().BoolVar(&config.ManagePeers, "manage-peers", false, "Configure the BGP neighbors of the routers from the peer resources of the API server; neighbors without a resource are removed")
+	cmd.Flags().DurationVar(&config.PeerSyncInterval, "peer-sync-interval", 30*time.Second, "Interval at which the neighbors are synced and their session states are reported when --manage-peers is set")
+	cmd.Flags().StringVar(&config.MetricsAddress, "metrics-address", ":9091", "Listen address of the Prometheus metrics endpoint (empty disables it)")
+	cmd.Flags().StringVar(&config.OTLPEndpoint, "otlp-endpoint", "", "URL of the OTLP/HTTP collector receiving the traces, e.g. http://localhost:4318 (disabled if empty)")
+	cmd.Flags().StringVar(&config.LogPath, "log-path", "stderr", "Path to the log file (rotated by size), stdout or stderr")
+	cmd.Flags().Int8VarP(&config.Verbose, "verbose", "v", 0, "Verbosity level (1 enables debug logs, -1 logs only warnings and errors)")
+	cmd.Flags().StringVar(&config.LogFormat, "log-format", logging.FormatJSON, "Log output format: json or console")
+	cmd.Flags().IntVar(&config.LogMaxSize, "log-max-size", 100, "Size in megabytes at which the log file is rotated")
+	cmd.Flags().IntVar(&config.LogMaxBackups, "log-max-backups", 10, "Number of rotated log files to keep")
+	cmd.Flags().BoolVar(&config.WithdrawOnShutdown, "withdraw-on-shutdown", false, "Withdraw all programmed paths on shutdown instead of leaving them in place")
+	cmd.Flags().DurationVar(&config.ShutdownGracePeriod, "shutdown-grace-period", 10*time.Second, "Time to wait after withdrawing the paths on shutdown, so that peers converge before the updater exits")
+	cmd.Flags().DurationVar(&config.ResyncInterval, "resync-interval", 5*time.Minute, "Interval of the full reconciliation of the GoBGP RIB with the announcements (0 disables it)")
+	cmd.Flags().BoolVar(&config.BFD.Enabled, "enable-bfd", false, "Run BFD sessions with the BFD peers of the announcements and withdraw their routes when a session goes down")
+	cmd.Flags().StringVar(&config.BFD.ListenAddress, "bfd-listen-address", "", "Local address of the BFD sessions (all addresses if empty)")
+	cmd.Flags().DurationVar(&config.BFD.MinInterval, "bfd-min-interval", 300*time.Millisecond, "Desired transmit and required receive interval of the BFD control packets")
+	cmd.Flags().IntVar(&config.BFD.DetectMultiplier, "bfd-detect-multiplier", 3, "Number of missed BFD control packets after which a session is declared down")
+	cmd.Flags().DurationVar(&config.HealthCheckDefaults.Interval, "health-check-interval", healthcheck.Defaults.Interval, "Interval between the probes of the health checks that do not set it")
+	cmd.Flags().DurationVar(&config.HealthCheckDefaults.Timeout, "health-check-timeout", healthcheck.Defaults.Timeout, "Timeout of the probes of the health checks that do not set it")
+	cmd.Flags().IntVar(&config.HealthCheckDefaults.Rise, "health-check-rise", healthcheck.Defaults.Rise, "Consecutive successful probes after which a next hop is healthy again, for the health checks that do not set it")
+	cmd.Flags().IntVar(&config.HealthCheckDefaults.Fall, "health-check-fall", healthcheck.Defaults.Fall, "Consecutive failed probes after which a next hop is unhealthy, for the health checks that do not set it")
+	cmd.Flags().DurationVar(&config.HealthReportInterval, "health-report-interval", time.Minute, "Interval at which the latest probe results of the next hops are reported in the announcement status (0 reports only health changes)")
+	cmd.Flags().DurationVar(&config.Dampening.HoldDown, "dampening-hold-down", 0, "Minimum time after a next hop failed its health check before its routes are programmed again (0 disables it)")
+	cmd.Flags().IntVar(&config.Dampening.MaxOperations, "dampening-max-operations", 0, "Maximum number of withdrawals and re-announcements of the routes via a next hop per minute; further re-announcements wait (0 is unlimited)")
+	cmd.Flags().DurationVar(&config.Dampening.HalfLife, "dampening-half-life", 0, "Half-life of the penalty of 1000 added by every failure of a next hop (0 disables the penalty)")
+	cmd.Flags().Float64Var(&config.Dampening.SuppressThreshold, "dampening-suppress-threshold", 2000, "Penalty above which the routes via a next hop are suppressed until it decays below --dampening-reuse-threshold")
+	cmd.Flags().Float64Var(&config.Dampening.ReuseThreshold, "dampening-reuse-threshold", 750, "Penalty below which the suppressed routes via a next hop are programmed again")
+	cmd.Flags().DurationVar(&config.Dampening.MaxSuppressTime, "dampening-max-suppress-time", time.Hour, "Maximum time the routes via a next hop stay suppressed by the penalty")
+
+	cmd.Flags().StringVar(&config.Site, "site", "", "Site ID the updater registers with, e.g. the datacenter; the per-site overrides of the announcements are applied (ignored if empty)")
+
+	cmd.Flags().BoolVar(&config.LeaderElection.Enabled, "enable-leader-election", false, "Elect a single active updater among the replicas sharing a GoBGP daemon; standbys wait for the leadership")
+	cmd.Flags().StringSliceVar(&config.LeaderElection.Endpoints, "leader-election-endpoints", []string{"http://localhost:2379"}, "Comma separated list of etcd endpoints holding the election")
+	cmd.Flags().StringVar(&config.LeaderElection.Etcd.CACert, "leader-election-etcd-ca", "", "Path to etcd CA certificate (TLS is disabled if empty)")
+	cmd.Flags().StringVar(&config.LeaderElection.Etcd.ClientCert, "leader-election-etcd-cert", "", "Path to etcd client certificate")
+	cmd.Flags().StringVar(&config.LeaderElection.Etcd.ClientKey, "leader-election-etcd-key", "", "Path to etcd client key")
+	cmd.Flags().StringVar(&config.LeaderElection.Name, "leader-election-name", "default", "Name of the election, shared by the replicas programming the same GoBGP daemon")
+	cmd.Flags().StringVar(&config.LeaderElection.Identity, "leader-election-identity", "", "Identity of this replica in the election (defaults to the host name)")
+	cmd.Flags().DurationVar(&config.LeaderElection.LeaseDuration, "leader-election-lease-duration", 10*time.Second, "Time after which a standby takes over from a leader that stopped renewing its lease")
+	cmd.Flags().StringSliceVar(&config.EnabledAddressFamilies, "enabled-address-families", []string{model.AddressFamilyIPv4Unicast}, "Comma separated list of address families to program (ipv4-unicast, ipv6-unicast)")
+	cmd.Flags().StringVar(&config.NextHopWeightEncoding, "next-hop-weight-encoding", model.WeightEncodingLinkBandwidth, "How the weights of weighted next hops are programmed: link-bandwidth for routers performing weighted ECMP, or med to prefer the heaviest next hop")
 
 	return cmd
 }
+
+// handleReloadSignals calls reload on every SIGHUP until the context is done. The handler is registered before the
+// function returns, so a SIGHUP sent afterward never terminates the process.
+func handleReloadSignals(ctx context.Context, reload func() error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if err := reload(); err != nil {
+					slog.Error("failed to reload configuration", "error", err)
+					continue
+				}
+				slog.Info("configuration reloaded")
+			}
+		}
+	}()
+}
+
+// reloadConfig re-reads the configuration file, applying the log verbosity, the health check defaults and the API
+// endpoint that no flag or environment variable overrides, and re-reads the GoBGP certificates of all GoBGP routers
+// from the configured paths.
+func reloadConfig(flags *pflag.FlagSet, path string, config model.UpdaterConfig, routers []*router, apiClient *v1.APIClient, logLevel *slog.LevelVar, monitor *healthcheck.Monitor) error {
+	if path != "" {
+		reloaded := config
+		if err := configfile.ReadFile(path, &reloaded); err != nil {
+			return err
+		}
+		if !configfile.Overridden(flags, "verbose") {
+			config.Verbose = reloaded.Verbose
+		}
+		if !configfile.Overridden(flags, "health-check-interval") {
+			config.HealthCheckDefaults.Interval = reloaded.HealthCheckDefaults.Interval
+		}
+		if !configfile.Overridden(flags, "health-check-timeout") {
+			config.HealthCheckDefaults.Timeout = reloaded.HealthCheckDefaults.Timeout
+		}
+		if !configfile.Overridden(flags, "health-check-rise") {
+			config.HealthCheckDefaults.Rise = reloaded.HealthCheckDefaults.Rise
+		}
+		if !configfile.Overridden(flags, "health-check-fall") {
+			config.HealthCheckDefaults.Fall = reloaded.HealthCheckDefaults.Fall
+		}
+		if !configfile.Overridden(flags, "api-endpoint") {
+			config.APIEndpoint = reloaded.APIEndpoint
+		}
+		logLevel.Set(logging.Level(config.Verbose))
+		monitor.SetDefaults(config.HealthCheckDefaults)
+	}
+
+	for i, routerConfig := range config.Routers() {
+		if routers[i].goBGP == nil {
+			continue
+		}
+		if err := routers[i].goBGP.Reload(routerConfig); err != nil {
+			return fmt.Errorf("router %s: %w", routerConfig.Name, err)
+		}
+	}
+	apiClient.SetBaseURL(config.APIEndpoint)
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package updater
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/healthcheck"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	api "github.com/osrg/gobgp/v3/api"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// TestReloadOnSIGHUP checks that a SIGHUP makes the next GoBGP call present the client certificate re-read from the
+// configured paths and moves the API client to the endpoint rewritten in the configuration file.
+func TestReloadOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	config := model.UpdaterConfig{
+		GoBGPCACert:     filepath.Join(dir, "ca.pem"),
+		GoBGPClientCert: filepath.Join(dir, "client.pem"),
+		GoBGPClientKey:  filepath.Join(dir, "client-key.pem"),
+		APIEndpoint:     "http://127.0.0.1:1",
+	}
+	writePEM(t, config.GoBGPCACert, "CERTIFICATE", ca.cert.Raw)
+	ca.issueClient(t, "updater-old", config.GoBGPClientCert, config.GoBGPClientKey)
+	config.GoBGPEndpoint = startFakeGoBGP(t, ca)
+
+	client, err := gobgp.NewClient(&config.GoBGPEndpoint, &config.GoBGPCACert, &config.GoBGPClientCert, &config.GoBGPClientKey)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	expectClientIdentity(t, client, "updater-old")
+
+	var apiRequests atomic.Int32
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiRequests.Add(1)
+	}))
+	defer apiServer.Close()
+	configPath := filepath.Join(dir, "updater.yaml")
+	writeFile(t, configPath, "api_endpoint: "+config.APIEndpoint+"\n")
+	apiClient := v1.NewAPIClient(&config.APIEndpoint, 5*time.Second)
+	monitor := healthcheck.NewMonitor(clock.RealClock{}, model.Dampening{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan error, 1)
+	routers := []*router{{name: config.GoBGPEndpoint, goBGP: client}}
+	handleReloadSignals(ctx, func() error {
+		err := reloadConfig(pflag.NewFlagSet("updater", pflag.ContinueOnError), configPath, config, routers, apiClient, new(slog.LevelVar), monitor)
+		reloaded <- err
+		return err
+	})
+
+	// Rotate the client certificate in place, as a certificate manager does before signalling the updater
+	ca.issueClient(t, "updater-new", config.GoBGPClientCert, config.GoBGPClientKey)
+	writeFile(t, configPath, "api_endpoint: "+apiServer.URL+"\n")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("failed to send SIGHUP: %v", err)
+	}
+	select {
+	case err := <-reloaded:
+		if err != nil {
+			t.Fatalf("reload failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP did not trigger a reload")
+	}
+
+	expectClientIdentity(t, client, "updater-new")
+	if err := apiClient.V1HealthCheck(context.Background()); err != nil {
+		t.Fatalf("V1HealthCheck after the reload: %v", err)
+	}
+	if apiRequests.Load() == 0 {
+		t.Fatal("API client still uses the previous endpoint")
+	}
+}
+
+// fakeGoBGP answers GetBgp with the common name of the client certificate as the router ID.
+type fakeGoBGP struct {
+	api.UnimplementedGobgpApiServer
+}
+
+func (fakeGoBGP) GetBgp(ctx context.Context, _ *api.GetBgpRequest) (*api.GetBgpResponse, error) {
+	p, _ := peer.FromContext(ctx)
+	state := p.AuthInfo.(credentials.TLSInfo).State
+	return &api.GetBgpResponse{Global: &api.Global{RouterId: state.PeerCertificates[0].Subject.CommonName}}, nil
+}
+
+// startFakeGoBGP serves fakeGoBGP with a server certificate of the CA, requiring client certificates of the CA, and
+// returns its endpoint.
+func startFakeGoBGP(t *testing.T, ca *testCA) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "gobgp", x509.ExtKeyUsageServerAuth)},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	api.RegisterGobgpApiServer(server, fakeGoBGP{})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+// expectClientIdentity checks the common name of the client certificate the GoBGP server sees on the next call.
+func expectClientIdentity(t *testing.T, client *gobgp.Client, want string) {
+	t.Helper()
+	global, err := client.GetGlobal()
+	if err != nil {
+		t.Fatalf("GetGlobal: %v", err)
+	}
+	if global.RouterId != want {
+		t.Fatalf("GoBGP server saw client certificate %q, want %q", global.RouterId, want)
+	}
+}
+
+// testCA is a self-signed certificate authority issuing the certificates of a test.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "corebgp-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+// issue creates a certificate for the common name that is also valid for 127.0.0.1.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// issueClient writes a client certificate for the common name and its key to the files.
+func (ca *testCA) issueClient(t *testing.T, commonName, certFile, keyFile string) {
+	t.Helper()
+	cert := ca.issue(t, commonName, x509.ExtKeyUsageClientAuth)
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	writePEM(t, certFile, "CERTIFICATE", cert.Certificate[0])
+	writePEM(t, keyFile, "EC PRIVATE KEY", key)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	writeFile(t, path, string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
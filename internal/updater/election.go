@@ -0,0 +1,123 @@
+package updater
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// leaderElectionPrefix is the etcd key prefix of the updater elections.
+const leaderElectionPrefix = "/corebgp/updater-leader/"
+
+// leaderElector campaigns for the leadership of the updater replicas in etcd. The leadership is bound to an etcd
+// lease kept alive by the session, so it passes to a standby once the leader stops renewing it.
+type leaderElector struct {
+	client   *clientv3.Client
+	session  *concurrency.Session
+	election *concurrency.Election
+	identity string
+}
+
+// newLeaderElector connects to etcd and creates the session of the candidate. The identity defaults to the host name.
+func newLeaderElector(config model.LeaderElection) (*leaderElector, error) {
+	identity, err := replicaIdentity(config.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig *tls.Config
+	if config.Etcd.CACert != "" {
+		if tlsConfig, err = electionTLSConfig(config.Etcd); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   config.Endpoints,
+		DialTimeout: 3 * time.Second,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create etcd client: %w", err)
+	}
+
+	ttl := int(config.LeaseDuration.Seconds())
+	if ttl < 1 {
+		ttl = 1
+	}
+	session, err := concurrency.NewSession(client, concurrency.WithTTL(ttl))
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create leader election session: %w", err)
+	}
+
+	return &leaderElector{
+		client:   client,
+		session:  session,
+		election: concurrency.NewElection(session, leaderElectionPrefix+config.Name),
+		identity: identity,
+	}, nil
+}
+
+// electionTLSConfig loads the TLS configuration of the etcd connection. The client certificate is optional.
+func electionTLSConfig(config model.Etcd) (*tls.Config, error) {
+	caCert, err := os.ReadFile(config.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("could not read etcd CA certificate: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to append etcd CA certificate")
+	}
+
+	tlsConfig := &tls.Config{RootCAs: caPool}
+	if config.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load etcd client certificate and key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// campaign blocks until the replica becomes the leader or the context is done.
+func (l *leaderElector) campaign(ctx context.Context) error {
+	if leader, err := l.election.Leader(ctx); err == nil && len(leader.Kvs) > 0 {
+		slog.Info("waiting for leadership", "identity", l.identity, "leader", string(leader.Kvs[0].Value))
+	}
+
+	if err := l.election.Campaign(ctx, l.identity); err != nil {
+		return fmt.Errorf("leader election campaign failed: %w", err)
+	}
+	slog.Info("acquired leadership", "identity", l.identity)
+	return nil
+}
+
+// lost returns a channel closed when the lease of the session expires, i.e. the leadership may be held by another
+// replica.
+func (l *leaderElector) lost() <-chan struct{} {
+	return l.session.Done()
+}
+
+// close resigns the leadership, so that a standby takes over immediately, and releases the etcd connection.
+func (l *leaderElector) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := l.election.Resign(ctx); err != nil {
+		slog.Warn("failed to resign leadership", "error", err)
+	}
+	if err := l.session.Close(); err != nil {
+		slog.Warn("failed to revoke leader election lease", "error", err)
+	}
+	l.client.Close()
+}
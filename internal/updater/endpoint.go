@@ -0,0 +1,19 @@
+package updater
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// ParseGoBGPEndpoint parses the GoBGP gRPC endpoint in the "ip:port" form. IPv6 addresses must be enclosed
+// in square brackets, e.g. "[::1]:50051".
+func ParseGoBGPEndpoint(s string) (netip.AddrPort, error) {
+	addrPort, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid GoBGP endpoint %q: %w", s, err)
+	}
+	if addrPort.Port() == 0 {
+		return netip.AddrPort{}, fmt.Errorf("invalid GoBGP endpoint %q: port must be in range 1-65535", s)
+	}
+	return addrPort, nil
+}
@@ -0,0 +1,46 @@
+package updater
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseGoBGPEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    netip.AddrPort
+		wantErr bool
+	}{
+		{name: "IPv4", input: "127.0.0.1:50051", want: netip.MustParseAddrPort("127.0.0.1:50051")},
+		{name: "IPv4 highest port", input: "192.0.2.1:65535", want: netip.MustParseAddrPort("192.0.2.1:65535")},
+		{name: "IPv6 in brackets", input: "[::1]:50051", want: netip.MustParseAddrPort("[::1]:50051")},
+		{name: "IPv6 full address", input: "[2001:db8::10]:179", want: netip.MustParseAddrPort("[2001:db8::10]:179")},
+		{name: "empty", input: "", wantErr: true},
+		{name: "missing port", input: "127.0.0.1", wantErr: true},
+		{name: "zero port", input: "127.0.0.1:0", wantErr: true},
+		{name: "port out of range", input: "127.0.0.1:65536", wantErr: true},
+		{name: "negative port", input: "127.0.0.1:-1", wantErr: true},
+		{name: "IPv6 without brackets", input: "::1:50051", wantErr: true},
+		{name: "host name", input: "localhost:50051", wantErr: true},
+		{name: "invalid address", input: "256.0.0.1:50051", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGoBGPEndpoint(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseGoBGPEndpoint(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGoBGPEndpoint(%q) returned an error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseGoBGPEndpoint(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
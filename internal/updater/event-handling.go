@@ -2,39 +2,270 @@ package updater
 
 import (
 	"fmt"
+	"github.com/nikitamishagin/corebgp/internal/bfd"
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/healthcheck"
 	"github.com/nikitamishagin/corebgp/internal/model"
+	"log/slog"
+	"slices"
 )
 
-func handleAnnouncementEvent(client *GoBGPClient, event *model.Event) error {
+// handleAnnouncementEvent adds or withdraws the paths of the announcement on all routers in parallel. The paths of
+// announcements outside their schedule are withdrawn. It returns the errors of the routers that failed to apply the
+// event, keyed by the router names.
+func handleAnnouncementEvent(routers []*router, event *model.Event, families []string, weightEncoding string, monitor *healthcheck.Monitor, sessions *bfd.Manager, schedules *scheduler) (map[string]error, error) {
 	// Log the event being processed
-	fmt.Printf("Processing event: type=%s, address=%s, next-hops=%v\n", event.Type, event.Announcement.Addresses.AnnouncedIP, event.Announcement.NextHops)
-
-	// Handle the event based on the Type
-	switch event.Type {
-	case model.EventAdded:
-		// Add route (only one next hop for test)
-		err := client.AddPath(event.Announcement.Addresses.AnnouncedIP, 32, event.Announcement.NextHops[0].IP)
-		if err != nil {
-			return fmt.Errorf("failed to add route %s via %v: %w", event.Announcement.Addresses.AnnouncedIP, event.Announcement.NextHops, err)
-		}
-	//case model.EventUpdated:
-	//	// Update announcement (update route)
-	//	err := client.UpdatePath(event.Announcement.Addresses.AnnouncedIP, 32, event.Announcement.NextHops[0].IP)
-	//	if err != nil {
-	//		return fmt.Errorf("failed to update route %s/%d: %w",
-	//			event.Announcement.Addresses.AnnouncedIP, 32, err)
-	//	}
-	case model.EventDeleted:
-		// Delete announcement (remove route)
-		err := client.DeletePath(event.Announcement.Addresses.AnnouncedIP, 32, event.Announcement.NextHops[0].IP)
-		if err != nil {
-			return fmt.Errorf("failed to delete route %s/%d: %w",
-				event.Announcement.Addresses.AnnouncedIP, 32, err)
+	slog.Info("processing event", "type", event.Type, "project", event.Announcement.Meta.Project, "name", event.Announcement.Meta.Name,
+		"addresses", event.Announcement.AnnouncedAddresses(), "next_hops", event.Announcement.NextHops)
+
+	if event.Type != model.EventAdded && event.Type != model.EventUpdated && event.Type != model.EventDeleted {
+		return nil, fmt.Errorf("unrecognized event type: %s", event.Type)
+	}
+
+	// Cancelled announcements are abandoned by an operator and must not be programmed
+	if event.Type != model.EventDeleted && event.Announcement.Status.Status == model.StatusCancelled {
+		monitor.Untrack(&event.Announcement)
+		sessions.Untrack(&event.Announcement)
+		schedules.Untrack(&event.Announcement)
+		return nil, nil
+	}
+
+	paths := announcementPaths(&event.Announcement, families, weightEncoding)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("announcement %s/%s has no next hops", event.Announcement.Meta.Project, event.Announcement.Meta.Name)
+	}
+
+	// Routes via unhealthy next hops are already withdrawn and are not programmed until they recover
+	removed := event.Type == model.EventDeleted || event.Announcement.Status.Status == model.StatusWithdrawn
+	if removed || event.Announcement.Status.Status == model.StatusExpired {
+		schedules.Untrack(&event.Announcement)
+	} else {
+		// An announcement waiting for the start of its schedule is re-processed then
+		schedules.Track(&event.Announcement)
+	}
+	removed = removed || !scheduled(&event.Announcement, schedules)
+	if !removed {
+		monitor.Track(&event.Announcement)
+		sessions.Track(&event.Announcement)
+	}
+	// Routes via draining next hops are withdrawn, while the next hops are still health-checked
+	var withdrawn []announcementPath
+	if !removed {
+		paths = slices.DeleteFunc(paths, func(path announcementPath) bool {
+			if event.Announcement.Status.Drained(path.nextHop) {
+				withdrawn = append(withdrawn, path)
+				return true
+			}
+			return false
+		})
+	}
+	paths = slices.DeleteFunc(paths, func(path announcementPath) bool {
+		return !monitor.Healthy(&event.Announcement, path.nextHop)
+	})
+	// Routes via backup next hops are withdrawn as well, e.g., after the primary next hop recovered
+	if !removed {
+		var backup []announcementPath
+		paths, backup = failoverPaths(&event.Announcement, paths)
+		withdrawn = append(withdrawn, backup...)
+	}
+	if removed {
+		monitor.Untrack(&event.Announcement)
+		sessions.Untrack(&event.Announcement)
+	}
+
+	// Re-adding a path replaces the previous one with the updated attributes, while a deleted, withdrawn
+	// (soft-deleted) or unscheduled announcement is a signal to remove the routes. The routes are also withdrawn while a BFD
+	// session of the announcement is down, e.g. after an update added a BFD peer that has not come up yet. The paths
+	// via other next hops are withdrawn first, since FRR originates a single path per prefix.
+	add := !removed && sessions.Up(&event.Announcement)
+	return programRouters(routers, func(r *router) error {
+		if err := applyPaths(r.client, withdrawn, false); err != nil {
+			return err
+		}
+		return applyPaths(r.client, paths, add)
+	}), nil
+}
+
+// scheduled reports whether the routes of the announcement are programmed at this time: it is within its schedule
+// and has not been marked as expired.
+func scheduled(announcement *model.Announcement, schedules *scheduler) bool {
+	return announcement.Status.Status != model.StatusExpired && schedules.Active(announcement)
+}
+
+// applyPaths adds the paths to the router, or withdraws them if add is false.
+func applyPaths(client RouteProgrammer, paths []announcementPath, add bool) error {
+	for _, path := range paths {
+		if add {
+			if err := client.AddPath(path.prefix, path.prefixLength, path.nextHop, path.attrs); err != nil {
+				return fmt.Errorf("failed to add route %s via %s: %w", path.prefix, path.nextHop, err)
+			}
+			continue
+		}
+		if err := client.DeletePath(path.prefix, path.prefixLength, path.nextHop); err != nil {
+			return fmt.Errorf("failed to withdraw route %s via %s: %w", path.prefix, path.nextHop, err)
+		}
+	}
+	return nil
+}
+
+// handleHealthChange withdraws the routes via the next hop from all routers when it becomes unhealthy and programs
+// them again when it recovers, unless a BFD session of the announcement is down or the next hop is draining. With the
+// primary-backup failover, the routes fail over to the first healthy next hop instead.
+func handleHealthChange(routers []*router, announcement *model.Announcement, nextHop string, healthy bool, families []string, weightEncoding string, monitor *healthcheck.Monitor, sessions *bfd.Manager) error {
+	if announcement.Failover == model.FailoverPrimaryBackup && sessions.Up(announcement) {
+		return handleFailover(routers, announcement, families, weightEncoding, monitor)
+	}
+	if healthy && (!sessions.Up(announcement) || announcement.Status.Drained(nextHop)) {
+		return nil
+	}
+	paths := slices.DeleteFunc(announcementPaths(announcement, families, weightEncoding), func(path announcementPath) bool {
+		return path.nextHop != nextHop
+	})
+	return joinRouterErrors(programRouters(routers, func(r *router) error {
+		return applyPaths(r.client, paths, healthy)
+	}))
+}
+
+// handleFailover programs the routes of the announcement via its first healthy next hop that is not draining on all
+// routers, and withdraws the routes via the other next hops first.
+func handleFailover(routers []*router, announcement *model.Announcement, families []string, weightEncoding string, monitor *healthcheck.Monitor) error {
+	var usable, withdrawn []announcementPath
+	for _, path := range announcementPaths(announcement, families, weightEncoding) {
+		if monitor.Healthy(announcement, path.nextHop) && !announcement.Status.Drained(path.nextHop) {
+			usable = append(usable, path)
+		} else {
+			withdrawn = append(withdrawn, path)
+		}
+	}
+	active, backup := failoverPaths(announcement, usable)
+	withdrawn = append(withdrawn, backup...)
+	return joinRouterErrors(programRouters(routers, func(r *router) error {
+		if err := applyPaths(r.client, withdrawn, false); err != nil {
+			return err
+		}
+		return applyPaths(r.client, active, true)
+	}))
+}
+
+// handleBFDChange withdraws the routes of the announcement from all routers when one of its BFD sessions goes down
+// and programs the routes via the healthy next hops that are not draining again when all sessions are up.
+func handleBFDChange(routers []*router, announcement *model.Announcement, up bool, families []string, weightEncoding string, monitor *healthcheck.Monitor) error {
+	paths := slices.DeleteFunc(announcementPaths(announcement, families, weightEncoding), func(path announcementPath) bool {
+		return !monitor.Healthy(announcement, path.nextHop) || announcement.Status.Drained(path.nextHop)
+	})
+	paths, _ = failoverPaths(announcement, paths)
+	return joinRouterErrors(programRouters(routers, func(r *router) error {
+		return applyPaths(r.client, paths, up)
+	}))
+}
+
+// failoverPaths splits the paths via the usable next hops into the paths to program and the paths via the backup
+// next hops. With the primary-backup failover, only the paths via the first next hop of every announced address are
+// programmed, the paths following the order of the next hops; otherwise all of them are.
+func failoverPaths(announcement *model.Announcement, paths []announcementPath) (active, backup []announcementPath) {
+	if announcement.Failover != model.FailoverPrimaryBackup {
+		return paths, nil
+	}
+	primaries := make(map[string]bool)
+	for _, path := range paths {
+		if primaries[path.prefix] {
+			backup = append(backup, path)
+			continue
+		}
+		primaries[path.prefix] = true
+		active = append(active, path)
+	}
+	return active, backup
+}
+
+// announcementPath is a single GoBGP path programmed for an announcement.
+type announcementPath struct {
+	prefix       string               // prefix is the announced address.
+	prefixLength uint32               // prefixLength is the host prefix length of the address family, 32 or 128.
+	nextHop      string               // nextHop is the next-hop address of the path.
+	attrs        gobgp.PathAttributes // attrs holds the path attributes of the path.
+}
+
+// announcementPaths returns the paths to program for the announced addresses of the enabled address families, one
+// per next hop in the order of the next hops. Every address is routed only via the next hops of its own family. The
+// weights of weighted next hops are encoded as a link bandwidth extended community or as the MED, depending on the
+// weight encoding. The communities of each next hop are added to the communities of the announcement.
+func announcementPaths(announcement *model.Announcement, families []string, weightEncoding string) []announcementPath {
+	attrs := gobgp.PathAttributesFromAnnouncement(announcement)
+
+	var paths []announcementPath
+	for _, address := range announcement.AnnouncedAddresses() {
+		family := model.AddressFamilyOf(address)
+		if !slices.Contains(families, family) {
+			continue
 		}
+		prefixLength := uint32(32)
+		if family == model.AddressFamilyIPv6Unicast {
+			prefixLength = 128
+		}
+
+		if len(announcement.WeightedNextHops) > 0 {
+			for _, nextHop := range announcement.WeightedNextHops {
+				if model.AddressFamilyOf(nextHop.Address) != family {
+					continue
+				}
+				weightedAttrs := attrs.ForNextHop(announcement, nextHop.Address)
+				if weightEncoding == model.WeightEncodingMED {
+					weightedAttrs.MED = gobgp.WeightedMED(attrs.MED, nextHop.Weight)
+				} else {
+					weightedAttrs.LinkBandwidth = gobgp.WeightedLinkBandwidth(nextHop.Weight)
+				}
+				paths = append(paths, announcementPath{prefix: address, prefixLength: prefixLength, nextHop: nextHop.Address, attrs: weightedAttrs})
+			}
+			continue
+		}
+
+		for _, nextHop := range announcement.NextHops {
+			if model.AddressFamilyOf(nextHop.IP) == family {
+				paths = append(paths, announcementPath{prefix: address, prefixLength: prefixLength, nextHop: nextHop.IP, attrs: attrs.ForNextHop(announcement, nextHop.IP)})
+			}
+		}
+	}
+	return paths
+}
+
+// addressFamilyEnabled reports whether the address family of any announced address is in the list of enabled families.
+// The other family of a dual-stack announcement is skipped when programming the paths.
+func addressFamilyEnabled(enabled []string, announcement *model.Announcement) bool {
+	for _, address := range announcement.AnnouncedAddresses() {
+		if slices.Contains(enabled, model.AddressFamilyOf(address)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWeightEncoding checks that the weights of the weighted next hops can be programmed with the encoding.
+func validateWeightEncoding(encoding string) error {
+	switch encoding {
+	case model.WeightEncodingLinkBandwidth, model.WeightEncodingMED:
+		return nil
 	default:
-		// Unrecognized event type
-		return fmt.Errorf("unrecognized event type: %s", event.Type)
+		return fmt.Errorf("unsupported next hop weight encoding: %s", encoding)
 	}
+}
 
+// validateAddressFamilies checks that all configured address families are supported by the updater.
+func validateAddressFamilies(families []string) error {
+	for _, family := range families {
+		switch family {
+		case model.AddressFamilyIPv4Unicast, model.AddressFamilyIPv6Unicast:
+		default:
+			return fmt.Errorf("unsupported address family: %s", family)
+		}
+	}
+	return nil
+}
+
+// validateDampening checks that the suppressed routes can be reused once the penalty decays.
+func validateDampening(dampening model.Dampening) error {
+	if dampening.HalfLife > 0 && dampening.ReuseThreshold >= dampening.SuppressThreshold {
+		return fmt.Errorf("dampening reuse threshold %g must be below the suppress threshold %g", dampening.ReuseThreshold, dampening.SuppressThreshold)
+	}
 	return nil
 }
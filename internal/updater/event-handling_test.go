@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/healthcheck"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// recordingRouter is a RouteProgrammer that records the paths added and deleted in the "prefix/length via next hop"
+// form.
+type recordingRouter struct {
+	mu      sync.Mutex
+	added   []string
+	deleted []string
+}
+
+func (r *recordingRouter) AddPath(prefix string, prefixLength uint32, nextHop string, _ gobgp.PathAttributes) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.added = append(r.added, pathID(prefix, prefixLength, nextHop))
+	return nil
+}
+
+func (r *recordingRouter) DeletePath(prefix string, prefixLength uint32, nextHop string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted = append(r.deleted, pathID(prefix, prefixLength, nextHop))
+	return nil
+}
+
+func (r *recordingRouter) ListLocalPaths(string) ([]gobgp.LocalPath, error) {
+	return nil, nil
+}
+
+func (r *recordingRouter) DeleteLocalPath(gobgp.LocalPath) error {
+	return nil
+}
+
+func (r *recordingRouter) Close() {}
+
+// reset returns the recorded paths and forgets them.
+func (r *recordingRouter) reset() (added, deleted []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added, deleted = r.added, r.deleted
+	r.added, r.deleted = nil, nil
+	return added, deleted
+}
+
+func pathID(prefix string, prefixLength uint32, nextHop string) string {
+	return fmt.Sprintf("%s/%d via %s", prefix, prefixLength, nextHop)
+}
+
+// TestWithdrawnAnnouncementDeletesPaths checks that an update soft-deleting an announcement withdraws its paths from
+// the routers instead of programming them.
+func TestWithdrawnAnnouncementDeletesPaths(t *testing.T) {
+	programmer := &recordingRouter{}
+	routers := []*router{{name: "gobgp", client: programmer}}
+	clk := clock.RealClock{}
+	monitor := healthcheck.NewMonitor(clk, model.Dampening{}, nil)
+	defer monitor.Stop()
+	schedules := newScheduler(clk, nil)
+	defer schedules.Stop()
+	families := []string{model.AddressFamilyIPv4Unicast}
+
+	announcement := model.Announcement{
+		Meta:      model.Meta{Name: "web", Project: "alpha"},
+		Addresses: model.Addresses{AnnouncedIP: "192.0.2.10"},
+		NextHops:  []model.Subnet{{IP: "10.0.0.1", Mask: 32}, {IP: "10.0.0.2", Mask: 32}},
+	}
+	want := []string{"192.0.2.10/32 via 10.0.0.1", "192.0.2.10/32 via 10.0.0.2"}
+
+	event := model.Event{Type: model.EventAdded, Announcement: announcement}
+	if errs, err := handleAnnouncementEvent(routers, &event, families, model.WeightEncodingLinkBandwidth, monitor, nil, schedules); err != nil || len(errs) > 0 {
+		t.Fatalf("handleAnnouncementEvent(added): %v %v", err, errs)
+	}
+	if added, deleted := programmer.reset(); !slices.Equal(added, want) || len(deleted) != 0 {
+		t.Fatalf("active announcement added %v and deleted %v, want to add %v", added, deleted, want)
+	}
+
+	announcement.Status.Status = model.StatusWithdrawn
+	event = model.Event{Type: model.EventUpdated, Announcement: announcement}
+	if errs, err := handleAnnouncementEvent(routers, &event, families, model.WeightEncodingLinkBandwidth, monitor, nil, schedules); err != nil || len(errs) > 0 {
+		t.Fatalf("handleAnnouncementEvent(withdrawn): %v %v", err, errs)
+	}
+	if added, deleted := programmer.reset(); len(added) != 0 || !slices.Equal(deleted, want) {
+		t.Fatalf("withdrawn announcement added %v and deleted %v, want to delete %v", added, deleted, want)
+	}
+}
@@ -0,0 +1,52 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// announcedPaths is the number of paths programmed in each GoBGP router after the last reconciliation.
+	announcedPaths = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "corebgp_updater_announced_paths",
+		Help: "Number of paths programmed in GoBGP after the last reconciliation.",
+	}, []string{"router"})
+
+	// goBGPConnected is whether the connection to each GoBGP router is established.
+	goBGPConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "corebgp_updater_gobgp_connected",
+		Help: "Whether the connection to the GoBGP router is established (1) or lost (0).",
+	}, []string{"router"})
+
+	// reconcileDuration is the distribution of the durations of the reconciliation runs.
+	reconcileDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "corebgp_updater_reconcile_duration_seconds",
+		Help:    "Duration of the reconciliations of the GoBGP RIB in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+// serveMetrics serves the Prometheus metrics on the address until the context is done.
+func serveMetrics(ctx context.Context, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: address, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("metrics server failed", "error", err)
+	}
+}
@@ -0,0 +1,226 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+// peerStateFailed is the session state reported for a peer that could not be configured on a router.
+const peerStateFailed = "failed"
+
+// syncPeers makes the neighbors of every router match the peer resources: missing neighbors are added, changed ones
+// are updated and the neighbors without a resource are removed. The session states are then reported via the status
+// subresource of the peers.
+func syncPeers(ctx context.Context, apiClient *v1.APIClient, routers []*router) error {
+	peers, err := apiClient.V1ListPeers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list peers: %w", err)
+	}
+
+	var mu sync.Mutex
+	sessions := make(map[string]map[string]model.PeerSessionStatus)
+	errs := fanOut(routers, func(r *router) error {
+		states, err := syncRouterPeers(r, peers)
+		mu.Lock()
+		sessions[r.name] = states
+		mu.Unlock()
+		return err
+	})
+
+	for _, peer := range peers {
+		status := model.PeerStatus{Routers: make([]model.PeerSessionStatus, 0, len(routers))}
+		for _, r := range routers {
+			session, ok := sessions[r.name][peer.Address]
+			if !ok {
+				session = model.PeerSessionStatus{Router: r.name, SessionState: peerStateFailed, Message: "failed to list the neighbors of the router"}
+			}
+			status.Routers = append(status.Routers, session)
+		}
+
+		if slices.EqualFunc(peer.Status.Routers, status.Routers, samePeerSession) {
+			continue
+		}
+		if _, err := apiClient.V1UpdatePeerStatus(ctx, peer.Address, &status); err != nil {
+			slog.Error("failed to report peer status", "peer", peer.Address, "error", err)
+		}
+	}
+	return joinRouterErrors(errs)
+}
+
+// syncRouterPeers configures the peers on the router and removes its other neighbors. It returns the session state
+// of every peer keyed by the address; the peers that could not be configured have the failed state.
+func syncRouterPeers(r *router, peers []model.Peer) (map[string]model.PeerSessionStatus, error) {
+	neighbors, err := r.goBGP.ListPeers()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*api.Peer, len(neighbors))
+	for _, neighbor := range neighbors {
+		current[neighborAddress(neighbor)] = neighbor
+	}
+
+	var errs []error
+	failed := make(map[string]error)
+	changed := false
+	desired := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		desired[peer.Address] = true
+
+		neighbor, ok := current[peer.Address]
+		switch {
+		case !ok:
+			err = r.goBGP.AddPeer(peer)
+		case neighborChanged(peer, neighbor):
+			err = r.goBGP.UpdatePeer(peer)
+		default:
+			continue
+		}
+		if err != nil {
+			failed[peer.Address] = err
+			errs = append(errs, err)
+			continue
+		}
+		changed = true
+		slog.Info("configured BGP neighbor", "router", r.name, "peer", peer.Address, "asn", peer.ASN)
+	}
+	for _, address := range slices.Sorted(maps.Keys(current)) {
+		if desired[address] {
+			continue
+		}
+		if err := r.goBGP.DeletePeer(address); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changed = true
+		slog.Info("removed BGP neighbor", "router", r.name, "peer", address)
+	}
+
+	// Read the states of the neighbors configured above
+	if changed {
+		if neighbors, err = r.goBGP.ListPeers(); err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		clear(current)
+		for _, neighbor := range neighbors {
+			current[neighborAddress(neighbor)] = neighbor
+		}
+	}
+
+	states := make(map[string]model.PeerSessionStatus, len(peers))
+	for _, peer := range peers {
+		if err, ok := failed[peer.Address]; ok {
+			states[peer.Address] = model.PeerSessionStatus{Router: r.name, SessionState: peerStateFailed, Message: err.Error()}
+			continue
+		}
+		states[peer.Address] = sessionStatus(r.name, current[peer.Address])
+	}
+	return states, errors.Join(errs...)
+}
+
+// neighborAddress returns the address of the GoBGP neighbor in the canonical form of the peer resources.
+func neighborAddress(neighbor *api.Peer) string {
+	address := neighbor.GetConf().GetNeighborAddress()
+	if addr, err := netip.ParseAddr(address); err == nil {
+		return addr.Unmap().String()
+	}
+	return address
+}
+
+// neighborChanged reports whether the configuration of the GoBGP neighbor differs from the peer. The timers left at
+// zero in the peer are not compared, since GoBGP replaces them with its defaults.
+func neighborChanged(peer model.Peer, neighbor *api.Peer) bool {
+	conf := neighbor.GetConf()
+	if conf.GetPeerAsn() != peer.ASN || conf.GetDescription() != peer.Description || conf.GetAuthPassword() != peer.AuthPassword {
+		return true
+	}
+
+	timers := neighbor.GetTimers().GetConfig()
+	if peer.Timers.HoldTime != 0 && timers.GetHoldTime() != peer.Timers.HoldTime ||
+		peer.Timers.KeepaliveInterval != 0 && timers.GetKeepaliveInterval() != peer.Timers.KeepaliveInterval ||
+		peer.Timers.ConnectRetry != 0 && timers.GetConnectRetry() != peer.Timers.ConnectRetry {
+		return true
+	}
+
+	var families []string
+	for _, afiSafi := range neighbor.GetAfiSafis() {
+		family := afiSafi.GetConfig().GetFamily()
+		if !afiSafi.GetConfig().GetEnabled() || family.GetSafi() != api.Family_SAFI_UNICAST {
+			continue
+		}
+		if afiSafi.GetAddPaths().GetConfig().GetSendMax() != peer.AddPathSendMax {
+			return true
+		}
+		switch family.GetAfi() {
+		case api.Family_AFI_IP:
+			families = append(families, model.AddressFamilyIPv4Unicast)
+		case api.Family_AFI_IP6:
+			families = append(families, model.AddressFamilyIPv6Unicast)
+		}
+	}
+	return !slices.Equal(slices.Sorted(slices.Values(families)), slices.Sorted(slices.Values(gobgp.PeerAddressFamilies(peer))))
+}
+
+// sessionStatus returns the session state of the GoBGP neighbor, which is unknown if the neighbor is missing.
+func sessionStatus(routerName string, neighbor *api.Peer) model.PeerSessionStatus {
+	status := model.PeerSessionStatus{Router: routerName, SessionState: "unknown"}
+	if neighbor == nil {
+		return status
+	}
+
+	state := neighbor.GetState().GetSessionState()
+	status.SessionState = strings.ToLower(state.String())
+	if uptime := neighbor.GetTimers().GetState().GetUptime(); state == api.PeerState_ESTABLISHED && uptime != nil {
+		since := uptime.AsTime().UTC()
+		status.EstablishedSince = &since
+	}
+	for _, afiSafi := range neighbor.GetAfiSafis() {
+		status.PrefixesReceived += int64(afiSafi.GetState().GetReceived())
+		status.PrefixesSent += int64(afiSafi.GetState().GetAdvertised())
+	}
+	return status
+}
+
+// samePeerSession reports whether the session states are equal, so that unchanged states are not written again.
+func samePeerSession(a, b model.PeerSessionStatus) bool {
+	sameSince := a.EstablishedSince == nil && b.EstablishedSince == nil ||
+		a.EstablishedSince != nil && b.EstablishedSince != nil && a.EstablishedSince.Equal(*b.EstablishedSince)
+	return a.Router == b.Router && a.SessionState == b.SessionState && a.Message == b.Message && sameSince &&
+		a.PrefixesReceived == b.PrefixesReceived && a.PrefixesSent == b.PrefixesSent
+}
+
+// runPeerSync syncs the neighbors of the routers every interval until the context is done.
+func runPeerSync(ctx context.Context, interval time.Duration, sync func() error) {
+	if err := sync(); err != nil {
+		slog.Error("peer sync failed", "error", err)
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sync(); err != nil {
+				slog.Error("peer sync failed", "error", err)
+			}
+		}
+	}
+}
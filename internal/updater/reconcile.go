@@ -0,0 +1,173 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/bfd"
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/healthcheck"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/tracing"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+// reconcile lists all announcements and makes the RIB of every router match them: missing paths are programmed and
+// local paths that no announcement requires anymore are withdrawn. It heals the RIBs from events missed by the watch
+// and reports the programming status of the announcements. The paths of announcements with a BFD session down and
+// the paths via draining next hops are not desired and are withdrawn, as are the paths of the announcements outside
+// their schedule. The announcements are programmed as the site sees them, and those disabled at the site are withdrawn.
+func reconcile(ctx context.Context, apiClient v1.AnnouncementsInterface, site string, routers []*router, families []string, weightEncoding string, monitor *healthcheck.Monitor, sessions *bfd.Manager, schedules *scheduler) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "updater/Reconcile")
+	start := time.Now()
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		reconcileDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	}()
+
+	programmed, errs, err := reconcileRouters(ctx, apiClient, site, routers, families, weightEncoding, monitor, sessions, schedules)
+	if err != nil {
+		return err
+	}
+	for _, announcement := range programmed {
+		if err := reportProgrammingStatus(ctx, apiClient, announcement, site, routers, errs, monitor, sessions); err != nil {
+			slog.Error("failed to report programming status", "error", err)
+		}
+	}
+	return joinRouterErrors(errs)
+}
+
+// reconcileRouters lists the announcements and makes the RIBs of the routers match them under the programming lock, so
+// that no path programmed in between by an event is withdrawn as stale. It returns the announcements with paths to
+// program and the errors of the routers.
+func reconcileRouters(ctx context.Context, apiClient v1.AnnouncementsInterface, site string, routers []*router, families []string, weightEncoding string, monitor *healthcheck.Monitor, sessions *bfd.Manager, schedules *scheduler) ([]*model.Announcement, map[string]error, error) {
+	programming.Lock()
+	defer programming.Unlock()
+
+	announcements, err := apiClient.V1ListAllAnnouncements(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list announcements: %w", err)
+	}
+
+	desired := make(map[string]announcementPath)
+	var programmed []*model.Announcement
+	for i := range announcements {
+		view, enabled := announcements[i].ForSite(site)
+		announcement := &view
+		if !enabled || announcement.Status.Status == model.StatusCancelled || announcement.Status.Status == model.StatusWithdrawn || announcement.Status.Status == model.StatusExpired {
+			monitor.Untrack(announcement)
+			sessions.Untrack(announcement)
+			schedules.Untrack(announcement)
+			continue
+		}
+		schedules.Track(announcement)
+		if !schedules.Active(announcement) {
+			monitor.Untrack(announcement)
+			sessions.Untrack(announcement)
+			continue
+		}
+
+		monitor.Track(announcement)
+		sessions.Track(announcement)
+		paths := announcementPaths(announcement, families, weightEncoding)
+		if len(paths) > 0 {
+			programmed = append(programmed, announcement)
+		}
+		if !sessions.Up(announcement) {
+			continue
+		}
+		paths = slices.DeleteFunc(paths, func(path announcementPath) bool {
+			return !monitor.Healthy(announcement, path.nextHop) || announcement.Status.Drained(path.nextHop)
+		})
+		paths, _ = failoverPaths(announcement, paths)
+		for _, path := range paths {
+			desired[pathKey(path.prefix, path.prefixLength, path.nextHop)] = path
+		}
+	}
+
+	errs := fanOut(routers, func(r *router) error {
+		return reconcileRouter(r, desired, families)
+	})
+	return programmed, errs, nil
+}
+
+// reconcileRouter makes the RIB of the router contain exactly the desired paths of the families. The paths of a GoBGP
+// router whose attributes drifted, e.g., communities or the MED, are programmed again; the other backends do not read
+// the attributes back.
+func reconcileRouter(r *router, desired map[string]announcementPath, families []string) error {
+	current := make(map[string]gobgp.LocalPath)
+	for _, family := range families {
+		paths, err := r.client.ListLocalPaths(family)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			current[pathKey(path.Prefix, path.PrefixLength, path.NextHop)] = path
+		}
+	}
+
+	var added, withdrawn int
+	for _, key := range slices.Sorted(maps.Keys(desired)) {
+		path := desired[key]
+		if local, ok := current[key]; ok && (r.goBGP == nil || local.Attributes.Matches(path.attrs)) {
+			continue
+		}
+		if err := r.client.AddPath(path.prefix, path.prefixLength, path.nextHop, path.attrs); err != nil {
+			return fmt.Errorf("failed to add route %s via %s: %w", path.prefix, path.nextHop, err)
+		}
+		added++
+	}
+	for _, key := range slices.Sorted(maps.Keys(current)) {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		path := current[key]
+		if err := r.client.DeleteLocalPath(path); err != nil {
+			return fmt.Errorf("failed to withdraw stale route %s via %s: %w", path.Prefix, path.NextHop, err)
+		}
+		withdrawn++
+	}
+
+	announcedPaths.WithLabelValues(r.name).Set(float64(len(desired)))
+	if added > 0 || withdrawn > 0 {
+		slog.Info("reconciled GoBGP RIB", "router", r.name, "added", added, "withdrawn", withdrawn)
+	}
+	return nil
+}
+
+// runPeriodicResync reconciles the GoBGP RIB every interval until the context is done. A zero interval disables
+// the periodic resync.
+func runPeriodicResync(ctx context.Context, interval time.Duration, resync func() error) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := resync(); err != nil {
+				slog.Error("periodic resync failed", "error", err)
+			}
+		}
+	}
+}
+
+// pathKey identifies a path by its prefix and next hop.
+func pathKey(prefix string, prefixLength uint32, nextHop string) string {
+	return prefix + "/" + strconv.FormatUint(uint64(prefixLength), 10) + " via " + nextHop
+}
@@ -0,0 +1,236 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/frr"
+	"github.com/nikitamishagin/corebgp/internal/gobgp"
+	"github.com/nikitamishagin/corebgp/internal/kernel"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// RouteProgrammer programs the paths of the announcements into a BGP daemon or the kernel routing table. The
+// programmed paths are listed back for the reconciliation.
+type RouteProgrammer interface {
+	AddPath(prefix string, prefixLength uint32, nextHop string, attrs gobgp.PathAttributes) error
+	DeletePath(prefix string, prefixLength uint32, nextHop string) error
+	ListLocalPaths(family string) ([]gobgp.LocalPath, error)
+	DeleteLocalPath(path gobgp.LocalPath) error
+	Close()
+}
+
+// router is a BGP daemon or a routing table programmed by the updater.
+type router struct {
+	name   string
+	client RouteProgrammer
+	goBGP  *gobgp.Client // goBGP configures the neighbors and reloads the certificates; nil for the other backends.
+}
+
+// connectRouters connects to all GoBGP routers and checks that they respond. The routers connected so far are
+// closed on error.
+func connectRouters(configs []model.GoBGPRouter) ([]*router, error) {
+	routers := make([]*router, 0, len(configs))
+	for _, config := range configs {
+		client, err := gobgp.NewClient(&config.Endpoint, &config.CACert, &config.ClientCert, &config.ClientKey)
+		if err == nil {
+			_, err = client.GetBGP()
+			if err != nil {
+				client.Close()
+			}
+		}
+		if err != nil {
+			closeRouters(routers)
+			return nil, fmt.Errorf("router %s: %w", config.Name, err)
+		}
+		routers = append(routers, &router{name: config.Name, client: client, goBGP: client})
+	}
+	return routers, nil
+}
+
+// reconnectResyncAttempts is the number of attempts to re-program the paths after a GoBGP router reconnects, e.g.,
+// while the restarted daemon has not started BGP yet. The periodic resync heals the router if all attempts fail.
+const reconnectResyncAttempts = 5
+
+// monitorRouters reports the connectivity of the GoBGP routers in the goBGPConnected gauge until the context is
+// canceled. A restarted GoBGP daemon has lost the programmed paths, so resync is called whenever a router reconnects.
+func monitorRouters(ctx context.Context, routers []*router, resync func() error) {
+	var wg sync.WaitGroup
+	for _, r := range routers {
+		if r.goBGP == nil {
+			continue
+		}
+		goBGPConnected.WithLabelValues(r.name).Set(1)
+
+		wg.Add(1)
+		go func(r *router) {
+			defer wg.Done()
+			r.goBGP.MonitorConnection(ctx, func(connected bool) {
+				if !connected {
+					slog.Error("lost connection to GoBGP router, reconnecting", "router", r.name)
+					goBGPConnected.WithLabelValues(r.name).Set(0)
+					return
+				}
+				slog.Info("reconnected to GoBGP router, re-programming all paths", "router", r.name)
+				goBGPConnected.WithLabelValues(r.name).Set(1)
+				resyncAfterReconnect(ctx, r, resync)
+			})
+		}(r)
+	}
+	wg.Wait()
+}
+
+// resyncAfterReconnect calls resync until it succeeds, backing off between the attempts, or gives up after
+// reconnectResyncAttempts attempts.
+func resyncAfterReconnect(ctx context.Context, r *router, resync func() error) {
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		err := resync()
+		if err == nil {
+			return
+		}
+		if attempt == reconnectResyncAttempts {
+			slog.Error("failed to re-program paths after reconnect, waiting for the periodic resync", "router", r.name, "error", err)
+			return
+		}
+		slog.Warn("failed to re-program paths after reconnect, retrying", "router", r.name, "error", err, "backoff", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// connectFRR checks that the local FRR daemon can be configured through vtysh and returns it as the only router.
+func connectFRR(vtysh string) ([]*router, error) {
+	client, err := frr.NewClient(vtysh)
+	if err != nil {
+		return nil, fmt.Errorf("router frr: %w", err)
+	}
+	return []*router{{name: "frr", client: client}}, nil
+}
+
+// connectKernel opens the netlink socket installing the routes into the kernel routing table and returns the table as
+// the only router.
+func connectKernel(table, metric uint32) ([]*router, error) {
+	client, err := kernel.NewClient(table, metric)
+	if err != nil {
+		return nil, fmt.Errorf("router kernel: %w", err)
+	}
+	return []*router{{name: "kernel", client: client}}, nil
+}
+
+// validateBGPBackend checks that the backend is supported and that the enabled features are available with it.
+func validateBGPBackend(config model.UpdaterConfig, routerSpecs []string) error {
+	switch config.BGPBackend {
+	case model.BGPBackendGoBGP:
+		return nil
+	case model.BGPBackendFRR, model.BGPBackendKernel:
+		if len(routerSpecs) > 0 {
+			return fmt.Errorf("--gobgp-router is not supported with the %s backend", config.BGPBackend)
+		}
+		if config.ManagePeers {
+			return fmt.Errorf("--manage-peers is not supported with the %s backend", config.BGPBackend)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported BGP backend: %s", config.BGPBackend)
+	}
+}
+
+// closeRouters closes the connections to the routers.
+func closeRouters(routers []*router) {
+	for _, r := range routers {
+		r.client.Close()
+	}
+}
+
+// programming serializes the programming of the routers by the events, the health and BFD changes, the
+// reconciliation and the drain, so that the reconciliation never withdraws a path programmed after it listed the
+// desired paths.
+var programming sync.Mutex
+
+// programRouters calls fn for every router in parallel like fanOut while holding the programming lock.
+func programRouters(routers []*router, fn func(r *router) error) map[string]error {
+	programming.Lock()
+	defer programming.Unlock()
+	return fanOut(routers, fn)
+}
+
+// fanOut calls fn for every router in parallel and returns the errors keyed by the router names. Routers that
+// succeeded have no entry.
+func fanOut(routers []*router, fn func(r *router) error) map[string]error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+	for _, r := range routers {
+		wg.Add(1)
+		go func(r *router) {
+			defer wg.Done()
+			if err := fn(r); err != nil {
+				mu.Lock()
+				errs[r.name] = err
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+	return errs
+}
+
+// joinRouterErrors combines the errors of the routers into one error, or returns nil if there are none.
+func joinRouterErrors(errs map[string]error) error {
+	joined := make([]error, 0, len(errs))
+	for _, name := range slices.Sorted(maps.Keys(errs)) {
+		joined = append(joined, fmt.Errorf("router %s: %w", name, errs[name]))
+	}
+	return errors.Join(joined...)
+}
+
+// parseGoBGPRouter parses a router given as comma separated key=value pairs, e.g.,
+// "name=tor-1,endpoint=10.0.0.1:50051,ca-cert=/etc/ca.pem". The certificates default to those of the defaults.
+// The endpoint is required and the name defaults to the endpoint.
+func parseGoBGPRouter(s string, defaults model.GoBGPRouter) (model.GoBGPRouter, error) {
+	router := defaults
+	router.Name, router.Endpoint = "", ""
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return model.GoBGPRouter{}, fmt.Errorf("invalid GoBGP router %q: %q is not a key=value pair", s, pair)
+		}
+		switch key {
+		case "name":
+			router.Name = value
+		case "endpoint":
+			router.Endpoint = value
+		case "ca-cert":
+			router.CACert = value
+		case "client-cert":
+			router.ClientCert = value
+		case "client-key":
+			router.ClientKey = value
+		default:
+			return model.GoBGPRouter{}, fmt.Errorf("invalid GoBGP router %q: unknown key %q", s, key)
+		}
+	}
+
+	endpoint, err := ParseGoBGPEndpoint(router.Endpoint)
+	if err != nil {
+		return model.GoBGPRouter{}, err
+	}
+	router.Endpoint = endpoint.String()
+	if router.Name == "" {
+		router.Name = router.Endpoint
+	}
+	return router, nil
+}
@@ -0,0 +1,96 @@
+package updater
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// TransitionFunc is called when an announcement enters or leaves its schedule.
+type TransitionFunc func(announcement model.Announcement)
+
+// scheduler re-processes the announcements at the start and at the end of their schedule, so that their routes are
+// programmed and withdrawn on time rather than on the next event or resync.
+type scheduler struct {
+	mu           sync.Mutex
+	clk          clock.Clock
+	onTransition TransitionFunc
+	timers       map[string]*time.Timer // timers maps the "project/name" IDs to the timers of the next transitions.
+	stopped      bool
+}
+
+// newScheduler creates a scheduler that calls onTransition with the announcement at every transition.
+func newScheduler(clk clock.Clock, onTransition TransitionFunc) *scheduler {
+	return &scheduler{
+		clk:          clk,
+		onTransition: onTransition,
+		timers:       make(map[string]*time.Timer),
+	}
+}
+
+// Active reports whether the announcement is within its schedule now.
+func (s *scheduler) Active(announcement *model.Announcement) bool {
+	return announcement.Active(s.clk.Now())
+}
+
+// Track sets the timer of the next transition of the announcement, replacing the timer of its previous version.
+// Announcements without an upcoming transition are untracked.
+func (s *scheduler) Track(announcement *model.Announcement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := announcement.Meta.Project + "/" + announcement.Meta.Name
+	if timer, ok := s.timers[id]; ok {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+
+	now := s.clk.Now()
+	next := announcement.NextTransition(now)
+	if next.IsZero() || s.stopped {
+		return
+	}
+
+	tracked := *announcement
+	var timer *time.Timer
+	timer = time.AfterFunc(next.Sub(now), func() {
+		s.mu.Lock()
+		current := s.timers[id] == timer
+		if current {
+			delete(s.timers, id)
+		}
+		s.mu.Unlock()
+
+		// The timer may fire after it was replaced by a newer version of the announcement
+		if current {
+			s.onTransition(tracked)
+		}
+	})
+	s.timers[id] = timer
+}
+
+// Untrack stops the timer of the announcement.
+func (s *scheduler) Untrack(announcement *model.Announcement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := announcement.Meta.Project + "/" + announcement.Meta.Name
+	if timer, ok := s.timers[id]; ok {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+}
+
+// Stop stops all timers, so that no transition is reported anymore.
+func (s *scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+}
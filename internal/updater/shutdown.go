@@ -0,0 +1,43 @@
+package updater
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// drain withdraws all paths programmed in the GoBGP routers and waits for the grace period, so that the peers move
+// the traffic away before the updater exits. Another shutdown signal ends the wait early.
+func drain(routers []*router, families []string, gracePeriod time.Duration, signals <-chan os.Signal) error {
+	var withdrawn atomic.Int64
+	errs := programRouters(routers, func(r *router) error {
+		for _, family := range families {
+			paths, err := r.client.ListLocalPaths(family)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				if err := r.client.DeleteLocalPath(path); err != nil {
+					return fmt.Errorf("failed to withdraw route %s via %s: %w", path.Prefix, path.NextHop, err)
+				}
+				withdrawn.Add(1)
+			}
+		}
+		return nil
+	})
+	if err := joinRouterErrors(errs); err != nil {
+		return err
+	}
+	slog.Info("withdrew all paths, waiting before exiting", "paths", withdrawn.Load(), "grace_period", gracePeriod)
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case sig := <-signals:
+		slog.Info("exiting without waiting for the grace period", "signal", sig.String())
+	}
+	return nil
+}
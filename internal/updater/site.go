@@ -0,0 +1,72 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/internal/version"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+// siteRegistrationInterval is the interval at which the updater renews its registration with its site.
+const siteRegistrationInterval = 30 * time.Second
+
+// replicaIdentity returns the configured identity of the updater, or the host name if none is configured.
+func replicaIdentity(identity string) (string, error) {
+	if identity != "" {
+		return identity, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("could not determine the identity of the updater: %w", err)
+	}
+	return hostname, nil
+}
+
+// runSiteRegistration registers the updater with the site and renews the registration until the context is
+// canceled, then removes it, so that the site lists only the running updaters.
+func runSiteRegistration(ctx context.Context, apiClient *v1.APIClient, site, identity string, routers []*router) {
+	updater := model.SiteUpdater{Identity: identity, Version: version.Version}
+	for _, r := range routers {
+		updater.Routers = append(updater.Routers, r.name)
+	}
+
+	register := func() {
+		if err := apiClient.V1RegisterUpdater(ctx, site, &updater); err != nil {
+			slog.Error("failed to register with the site", "site", site, "error", err)
+		}
+	}
+	register()
+
+	ticker := time.NewTicker(siteRegistrationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// The context of the updater is canceled, the registration is removed with a fresh one
+			deregisterCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := apiClient.V1DeregisterUpdater(deregisterCtx, site, identity); err != nil {
+				slog.Error("failed to deregister from the site", "site", site, "error", err)
+			}
+			return
+		case <-ticker.C:
+			register()
+		}
+	}
+}
+
+// siteEvent returns the event with the announcement as programmed at the site. An announcement disabled at the site
+// is handled like a deleted one, so that its routes are withdrawn from the routers of the site.
+func siteEvent(event model.Event, site string) model.Event {
+	announcement, enabled := event.Announcement.ForSite(site)
+	event.Announcement = announcement
+	if !enabled {
+		event.Type = model.EventDeleted
+	}
+	return event
+}
@@ -0,0 +1,271 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/bfd"
+	"github.com/nikitamishagin/corebgp/internal/healthcheck"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+// reportProgrammingStatus reports via the status subresource whether the announcement is programmed on all routers,
+// together with the state of every router, the health of its next hops and the state of its BFD sessions. The status
+// is written only when it changes, to avoid an update loop caused by the resulting watch event. The routers are
+// reported with the site, so that the updaters of other sites keep their router states, and the programmed routers with
+// the generation of the announcement, so that the API server observes when the generation is programmed on all routers.
+func reportProgrammingStatus(ctx context.Context, apiClient v1.AnnouncementsInterface, announcement *model.Announcement, site string, routers []*router, errs map[string]error, monitor *healthcheck.Monitor, sessions *bfd.Manager) error {
+	patch := model.Status{
+		Status:  model.StatusProgrammed,
+		Routers: make([]model.RouterStatus, 0, len(routers)),
+	}
+	var failed []string
+	for _, r := range routers {
+		routerStatus := model.RouterStatus{Router: r.name, Site: site, Status: model.StatusProgrammed, ObservedGeneration: announcement.Meta.Generation}
+		if err, ok := errs[r.name]; ok {
+			routerStatus.Status = model.StatusFailed
+			routerStatus.Message = err.Error()
+			routerStatus.ObservedGeneration = 0
+			failed = append(failed, r.name)
+		}
+		patch.Routers = append(patch.Routers, routerStatus)
+	}
+
+	programmed := model.Condition{
+		Type:    model.ConditionProgrammed,
+		Status:  model.ConditionTrue,
+		Reason:  "Programmed",
+		Message: fmt.Sprintf("routes are programmed on %d routers", len(routers)),
+	}
+	if len(failed) > 0 {
+		patch.Status = model.StatusFailed
+		programmed.Status = model.ConditionFalse
+		programmed.Reason = "ProgrammingFailed"
+		programmed.Message = "failed to program routes on " + strings.Join(failed, ", ")
+	}
+	patch.Conditions = []model.Condition{programmed}
+	if condition, ok := healthCondition(announcement, monitor); ok {
+		patch.Conditions = append(patch.Conditions, condition)
+	}
+	if condition, ok := bfdCondition(announcement, sessions); ok {
+		patch.Conditions = append(patch.Conditions, condition)
+	}
+
+	if statusUnchanged(&announcement.Status, &patch) {
+		return nil
+	}
+	if _, err := apiClient.V1UpdateAnnouncementStatus(ctx, announcement.Meta.Project, announcement.Meta.Name, &patch); err != nil {
+		return fmt.Errorf("failed to update status of %s/%s: %w", announcement.Meta.Project, announcement.Meta.Name, err)
+	}
+	return nil
+}
+
+// reportHealthStatus reports the health of the next hops of the announcement via the status subresource, with the
+// latest probe result of every next hop, after a next hop changed its health state and every health report interval.
+// The results are reported with the site, so that the updaters of other sites keep their results.
+func reportHealthStatus(ctx context.Context, apiClient v1.AnnouncementsInterface, announcement *model.Announcement, site string, monitor *healthcheck.Monitor) error {
+	condition, ok := healthCondition(announcement, monitor)
+	if !ok {
+		return nil
+	}
+
+	details := monitor.Details(announcement)
+	for i := range details {
+		details[i].Site = site
+	}
+	patch := model.Status{Details: details, Conditions: []model.Condition{condition}}
+	if _, err := apiClient.V1UpdateAnnouncementStatus(ctx, announcement.Meta.Project, announcement.Meta.Name, &patch); err != nil {
+		return fmt.Errorf("failed to update status of %s/%s: %w", announcement.Meta.Project, announcement.Meta.Name, err)
+	}
+	return nil
+}
+
+// runHealthReports reports the latest probe results of the next hops of the tracked announcements every interval
+// until the context is done, so that the latencies and failure counts stay fresh between the health changes. A zero
+// interval reports the results only when a next hop changes its health state.
+func runHealthReports(ctx context.Context, interval time.Duration, apiClient v1.AnnouncementsInterface, site string, monitor *healthcheck.Monitor) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, announcement := range monitor.Announcements() {
+				if err := reportHealthStatus(ctx, apiClient, &announcement, site, monitor); err != nil {
+					slog.Error("failed to report health status", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// reportBFDStatus reports the state of the BFD sessions of the announcement via the status subresource after its
+// sessions all came up or one of them went down.
+func reportBFDStatus(ctx context.Context, apiClient v1.AnnouncementsInterface, announcement *model.Announcement, sessions *bfd.Manager) error {
+	condition, ok := bfdCondition(announcement, sessions)
+	if !ok {
+		return nil
+	}
+
+	patch := model.Status{Conditions: []model.Condition{condition}}
+	if _, err := apiClient.V1UpdateAnnouncementStatus(ctx, announcement.Meta.Project, announcement.Meta.Name, &patch); err != nil {
+		return fmt.Errorf("failed to update status of %s/%s: %w", announcement.Meta.Project, announcement.Meta.Name, err)
+	}
+	return nil
+}
+
+// reportUnsupportedAddressFamily sets the announcement status to StatusUnsupportedAddressFamily via the status
+// subresource. The status is written only once to avoid an update loop caused by the resulting watch event.
+func reportUnsupportedAddressFamily(ctx context.Context, apiClient v1.AnnouncementsInterface, announcement *model.Announcement) error {
+	if announcement.Status.Status == model.StatusUnsupportedAddressFamily {
+		return nil
+	}
+
+	patch := model.Status{Status: model.StatusUnsupportedAddressFamily}
+	if _, err := apiClient.V1UpdateAnnouncementStatus(ctx, announcement.Meta.Project, announcement.Meta.Name, &patch); err != nil {
+		return fmt.Errorf("failed to update status of %s/%s: %w", announcement.Meta.Project, announcement.Meta.Name, err)
+	}
+	return nil
+}
+
+// healthCondition returns the HealthCheckPassing condition of the announcement. It reports false when the
+// announcement has no health check.
+func healthCondition(announcement *model.Announcement, monitor *healthcheck.Monitor) (model.Condition, bool) {
+	if !announcement.HealthCheck.Enabled() {
+		return model.Condition{}, false
+	}
+
+	var nextHops, unhealthy []string
+	for _, nextHop := range announcement.NextHops {
+		nextHops = append(nextHops, nextHop.IP)
+	}
+	for _, nextHop := range announcement.WeightedNextHops {
+		nextHops = append(nextHops, nextHop.Address)
+	}
+	for _, nextHop := range nextHops {
+		if !monitor.Healthy(announcement, nextHop) {
+			unhealthy = append(unhealthy, nextHop)
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		message := "unhealthy next hops: " + strings.Join(unhealthy, ", ")
+		if backups := failedOverTo(announcement, monitor); len(backups) > 0 {
+			message += "; failed over to " + strings.Join(backups, ", ")
+		}
+		return model.Condition{
+			Type:    model.ConditionHealthCheckPassing,
+			Status:  model.ConditionFalse,
+			Reason:  "NextHopsUnhealthy",
+			Message: message,
+		}, true
+	}
+	return model.Condition{
+		Type:    model.ConditionHealthCheckPassing,
+		Status:  model.ConditionTrue,
+		Reason:  "NextHopsHealthy",
+		Message: "all next hops pass the health check",
+	}, true
+}
+
+// failedOverTo returns the backup next hops the routes of the announcement are programmed via with the primary-backup
+// failover, one per address family whose first next hop is unhealthy or draining.
+func failedOverTo(announcement *model.Announcement, monitor *healthcheck.Monitor) []string {
+	if announcement.Failover != model.FailoverPrimaryBackup {
+		return nil
+	}
+
+	// The weighted next hops take precedence over the next hops
+	var nextHops []string
+	if len(announcement.WeightedNextHops) > 0 {
+		for _, nextHop := range announcement.WeightedNextHops {
+			nextHops = append(nextHops, nextHop.Address)
+		}
+	} else {
+		for _, nextHop := range announcement.NextHops {
+			nextHops = append(nextHops, nextHop.IP)
+		}
+	}
+
+	var backups []string
+	seen, selected := make(map[string]bool), make(map[string]bool)
+	for _, nextHop := range nextHops {
+		family := model.AddressFamilyOf(nextHop)
+		if selected[family] {
+			continue
+		}
+		if monitor.Healthy(announcement, nextHop) && !announcement.Status.Drained(nextHop) {
+			selected[family] = true
+			if seen[family] {
+				backups = append(backups, nextHop)
+			}
+		}
+		seen[family] = true
+	}
+	return backups
+}
+
+// bfdCondition returns the BFDSessionsUp condition of the announcement. It reports false when the announcement has
+// no BFD peers or BFD is disabled.
+func bfdCondition(announcement *model.Announcement, sessions *bfd.Manager) (model.Condition, bool) {
+	if len(announcement.BFDPeers) == 0 || sessions == nil {
+		return model.Condition{}, false
+	}
+
+	var down []string
+	for _, peer := range announcement.BFDPeers {
+		if !sessions.PeerUp(peer) {
+			down = append(down, peer)
+		}
+	}
+
+	if len(down) > 0 {
+		return model.Condition{
+			Type:    model.ConditionBFDSessionsUp,
+			Status:  model.ConditionFalse,
+			Reason:  "BFDSessionsDown",
+			Message: "BFD sessions are down with " + strings.Join(down, ", "),
+		}, true
+	}
+	return model.Condition{
+		Type:    model.ConditionBFDSessionsUp,
+		Status:  model.ConditionTrue,
+		Reason:  "BFDSessionsUp",
+		Message: "BFD sessions with all peers are up",
+	}, true
+}
+
+// statusUnchanged reports whether applying the patch would leave the status as it is, ignoring timestamps. The
+// routers of a site patch are compared with the routers of the same site only.
+func statusUnchanged(status, patch *model.Status) bool {
+	if status.Status != patch.Status {
+		return false
+	}
+	routers := status.Routers
+	if len(patch.Routers) > 0 && patch.Routers[0].Site != "" {
+		routers = slices.DeleteFunc(slices.Clone(routers), func(r model.RouterStatus) bool { return r.Site != patch.Routers[0].Site })
+	}
+	sameRouter := func(a, b model.RouterStatus) bool {
+		return a.Router == b.Router && a.Site == b.Site && a.Status == b.Status && a.Message == b.Message && a.ObservedGeneration == b.ObservedGeneration
+	}
+	if !slices.EqualFunc(routers, patch.Routers, sameRouter) {
+		return false
+	}
+	for _, condition := range patch.Conditions {
+		current := status.Condition(condition.Type)
+		if current == nil || current.Status != condition.Status || current.Reason != condition.Reason || current.Message != condition.Message {
+			return false
+		}
+	}
+	return true
+}
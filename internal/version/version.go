@@ -0,0 +1,32 @@
+// Package version holds the build information of the CoreBGP binaries. The version and the commit are set at link
+// time, e.g. with -ldflags "-X github.com/nikitamishagin/corebgp/internal/version.Version=v1.2.0".
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+var (
+	Version = "dev" // Version is the released version of the binary.
+	Commit  = ""    // Commit is the git commit the binary is built from; empty uses the revision recorded by the go tool.
+)
+
+// Info returns the build information of the running binary. Without a linked commit, the VCS revision embedded by
+// the go tool is used.
+func Info() model.BuildInfo {
+	info := model.BuildInfo{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,131 @@
+// Package apiservertest boots the complete CoreBGP API server with the in-memory datastore on an ephemeral port, so
+// that the integration tests of controllers and updaters exercise the real REST API, watch websockets included,
+// without etcd or GoBGP. Every test gets its own server with empty storage.
+package apiservertest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nikitamishagin/corebgp/internal/apiserver"
+	"github.com/nikitamishagin/corebgp/internal/model"
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// Server is a running API server of a test.
+type Server struct {
+	URL    string                // URL is the base URL of the server, e.g., http://127.0.0.1:41863.
+	Config *model.APIConfig      // Config is the configuration the server was started with.
+	Store  model.DatabaseAdapter // Store is the in-memory datastore of the server, e.g., for seeding keys directly.
+
+	clock      clock.Clock
+	handler    *apiserver.Handler
+	httpServer *httptest.Server
+	closeOnce  sync.Once
+
+	mu       sync.Mutex
+	hijacked map[net.Conn]struct{} // hijacked holds the connections of the watches, which httptest.Server does not close.
+}
+
+// Option configures a test server.
+type Option func(*Server)
+
+// WithConfig lets configure change the configuration of the server before it starts, e.g., to set an
+// authorization policy file. The configuration starts with the defaults of the corebgp-api command flags.
+func WithConfig(configure func(config *model.APIConfig)) Option {
+	return func(s *Server) {
+		configure(s.Config)
+	}
+}
+
+// WithClock sets the clock of the server, e.g., a clock.FakeClock to expire scheduled announcements on demand.
+func WithClock(clk clock.Clock) Option {
+	return func(s *Server) {
+		s.clock = clk
+	}
+}
+
+// NewTestServer starts an API server with the in-memory datastore on an ephemeral port of the loopback interface.
+// The server is shut down when the test and its subtests complete.
+func NewTestServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	s := &Server{
+		Config: defaultConfig(),
+		Store:  apiserver.NewMemoryStore(),
+		clock:  clock.RealClock{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Skip the route listing gin prints in debug mode
+	gin.SetMode(gin.TestMode)
+	handler, err := apiserver.NewHandler(s.Store, nil, s.Config, s.clock)
+	if err != nil {
+		t.Fatalf("failed to set up the API server: %v", err)
+	}
+	s.handler = handler
+	s.hijacked = make(map[net.Conn]struct{})
+	s.httpServer = httptest.NewUnstartedServer(handler)
+	s.httpServer.Config.ConnState = s.trackHijacked
+	s.httpServer.Start()
+	s.URL = s.httpServer.URL
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client of the server with the options.
+func (s *Server) Client(opts ...v1.ClientOption) *v1.APIClient {
+	baseURL := s.URL
+	return v1.NewAPIClient(&baseURL, 10*time.Second, opts...)
+}
+
+// Close shuts the server down, closing the open watches. It is safe to call it more than once.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		for conn := range s.hijacked {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+		s.httpServer.Close()
+		s.handler.Close()
+		s.Store.Close()
+	})
+}
+
+// trackHijacked records the connections upgraded to the websockets of the watches.
+func (s *Server) trackHijacked(conn net.Conn, state http.ConnState) {
+	if state != http.StateHijacked {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hijacked[conn] = struct{}{}
+}
+
+// defaultConfig returns the configuration of the API server with the defaults of the command flags.
+func defaultConfig() *model.APIConfig {
+	return &model.APIConfig{
+		DBType:                    "memory",
+		CrossProjectConflicts:     model.ConflictsWarn,
+		HistoryRevisions:          10,
+		AnnouncementEvents:        50,
+		WithdrawnRetention:        7 * 24 * time.Hour,
+		QueryTimeout:              2 * time.Second,
+		MaxAnnouncementNameLength: 253,
+		RateLimitBurst:            50,
+		MaxInflightRequests:       400,
+		MaxRequestBodySize:        10 << 20,
+		WatchHeartbeatInterval:    15 * time.Second,
+		OIDCSubjectClaim:          "sub",
+	}
+}
@@ -0,0 +1,121 @@
+package apiservertest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/apiservertest"
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+func newAnnouncement(name, address string) *model.Announcement {
+	return &model.Announcement{
+		Meta:      model.Meta{Name: name, Project: "alpha"},
+		Addresses: model.Addresses{AnnouncedIP: address},
+		NextHops:  []model.Subnet{{IP: "10.0.0.1", Mask: 32}},
+	}
+}
+
+func TestAnnouncementCRUD(t *testing.T) {
+	client := apiservertest.NewTestServer(t).Client()
+	ctx := context.Background()
+
+	if err := client.V1CreateAnnouncement(ctx, newAnnouncement("web", "192.0.2.10")); err != nil {
+		t.Fatalf("V1CreateAnnouncement: %v", err)
+	}
+	announcement, err := client.V1GetAnnouncement(ctx, "alpha", "web")
+	if err != nil {
+		t.Fatalf("V1GetAnnouncement: %v", err)
+	}
+	if announcement.Addresses.AnnouncedIP != "192.0.2.10" || announcement.Meta.Generation != 1 {
+		t.Fatalf("unexpected announcement after the create: %+v", announcement)
+	}
+
+	announcement.NextHops = append(announcement.NextHops, model.Subnet{IP: "10.0.0.2", Mask: 32})
+	if err := client.V1UpdateAnnouncement(ctx, announcement); err != nil {
+		t.Fatalf("V1UpdateAnnouncement: %v", err)
+	}
+	announcement, err = client.V1GetAnnouncement(ctx, "alpha", "web")
+	if err != nil {
+		t.Fatalf("V1GetAnnouncement: %v", err)
+	}
+	if len(announcement.NextHops) != 2 || announcement.Meta.Generation != 2 {
+		t.Fatalf("unexpected announcement after the update: %+v", announcement)
+	}
+
+	if err := client.V1DeleteAnnouncement(ctx, "alpha", "web"); err != nil {
+		t.Fatalf("V1DeleteAnnouncement: %v", err)
+	}
+	if _, err := client.V1GetAnnouncement(ctx, "alpha", "web"); !errors.Is(err, v1.ErrNotFound) {
+		t.Fatalf("V1GetAnnouncement after the delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestServersAreIsolated(t *testing.T) {
+	first, second := apiservertest.NewTestServer(t), apiservertest.NewTestServer(t)
+	ctx := context.Background()
+
+	if err := first.Client().V1CreateAnnouncement(ctx, newAnnouncement("web", "192.0.2.10")); err != nil {
+		t.Fatalf("V1CreateAnnouncement: %v", err)
+	}
+	if _, err := second.Client().V1GetAnnouncement(ctx, "alpha", "web"); !errors.Is(err, v1.ErrNotFound) {
+		t.Fatalf("announcement of the first server is visible on the second one: %v", err)
+	}
+	if _, err := first.Store.Get("v1/announcements/alpha/web"); err != nil {
+		t.Fatalf("announcement is not in the store of its server: %v", err)
+	}
+}
+
+func TestWatchAnnouncements(t *testing.T) {
+	server := apiservertest.NewTestServer(t)
+	client := server.Client()
+	ctx := context.Background()
+
+	events := make(chan model.Event, 10)
+	watchDone := make(chan error, 1)
+	go func() {
+		watchDone <- client.V1WatchAnnouncements(ctx, func(event model.Event) {
+			events <- event
+		}, v1.WithTransport(v1.TransportWebSocket))
+	}()
+
+	// The watch may start after the first write, so the writes are repeated until their events arrive
+	expectEvent := func(eventType model.EventType, write func() error) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		retry := time.NewTicker(100 * time.Millisecond)
+		defer retry.Stop()
+		if err := write(); err != nil {
+			t.Fatalf("write for the %s event failed: %v", eventType, err)
+		}
+		for {
+			select {
+			case event := <-events:
+				if event.Type == eventType && event.Announcement.Meta.Name == "web" {
+					return
+				}
+			case <-retry.C:
+				_ = write()
+			case <-deadline:
+				t.Fatalf("no %s event received", eventType)
+			}
+		}
+	}
+	expectEvent(model.EventAdded, func() error {
+		return client.V1CreateAnnouncement(ctx, newAnnouncement("web", "192.0.2.10"))
+	})
+	expectEvent(model.EventDeleted, func() error {
+		return client.V1DeleteAnnouncement(ctx, "alpha", "web")
+	})
+
+	// Closing the server ends the open watches
+	server.Close()
+	select {
+	case <-watchDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not end when the server was closed")
+	}
+}
@@ -0,0 +1,346 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: announcement.proto
+
+package corebgpv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	AnnouncementService_Get_FullMethodName    = "/corebgp.v1.AnnouncementService/Get"
+	AnnouncementService_List_FullMethodName   = "/corebgp.v1.AnnouncementService/List"
+	AnnouncementService_Create_FullMethodName = "/corebgp.v1.AnnouncementService/Create"
+	AnnouncementService_Update_FullMethodName = "/corebgp.v1.AnnouncementService/Update"
+	AnnouncementService_Delete_FullMethodName = "/corebgp.v1.AnnouncementService/Delete"
+	AnnouncementService_Watch_FullMethodName  = "/corebgp.v1.AnnouncementService/Watch"
+)
+
+// AnnouncementServiceClient is the client API for AnnouncementService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AnnouncementService manages the BGP announcements. It is served by the API server next to the v1 REST API and
+// applies the same authentication, validation, admission and resource version checks.
+type AnnouncementServiceClient interface {
+	// Get returns an announcement.
+	Get(ctx context.Context, in *GetAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error)
+	// List returns a page of the announcements of a project.
+	List(ctx context.Context, in *ListAnnouncementsRequest, opts ...grpc.CallOption) (*ListAnnouncementsResponse, error)
+	// Create creates an announcement.
+	Create(ctx context.Context, in *CreateAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error)
+	// Update replaces an announcement. It fails with ABORTED if the resource version is set and stale.
+	Update(ctx context.Context, in *UpdateAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error)
+	// Delete deletes an announcement and returns its last state.
+	Delete(ctx context.Context, in *DeleteAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error)
+	// Watch streams the changes of the announcements.
+	Watch(ctx context.Context, in *WatchAnnouncementsRequest, opts ...grpc.CallOption) (AnnouncementService_WatchClient, error)
+}
+
+type announcementServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAnnouncementServiceClient(cc grpc.ClientConnInterface) AnnouncementServiceClient {
+	return &announcementServiceClient{cc}
+}
+
+func (c *announcementServiceClient) Get(ctx context.Context, in *GetAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Announcement)
+	err := c.cc.Invoke(ctx, AnnouncementService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *announcementServiceClient) List(ctx context.Context, in *ListAnnouncementsRequest, opts ...grpc.CallOption) (*ListAnnouncementsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAnnouncementsResponse)
+	err := c.cc.Invoke(ctx, AnnouncementService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *announcementServiceClient) Create(ctx context.Context, in *CreateAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Announcement)
+	err := c.cc.Invoke(ctx, AnnouncementService_Create_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *announcementServiceClient) Update(ctx context.Context, in *UpdateAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Announcement)
+	err := c.cc.Invoke(ctx, AnnouncementService_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *announcementServiceClient) Delete(ctx context.Context, in *DeleteAnnouncementRequest, opts ...grpc.CallOption) (*Announcement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Announcement)
+	err := c.cc.Invoke(ctx, AnnouncementService_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *announcementServiceClient) Watch(ctx context.Context, in *WatchAnnouncementsRequest, opts ...grpc.CallOption) (AnnouncementService_WatchClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AnnouncementService_ServiceDesc.Streams[0], AnnouncementService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &announcementServiceWatchClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AnnouncementService_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type announcementServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *announcementServiceWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AnnouncementServiceServer is the server API for AnnouncementService service.
+// All implementations must embed UnimplementedAnnouncementServiceServer
+// for forward compatibility
+//
+// AnnouncementService manages the BGP announcements. It is served by the API server next to the v1 REST API and
+// applies the same authentication, validation, admission and resource version checks.
+type AnnouncementServiceServer interface {
+	// Get returns an announcement.
+	Get(context.Context, *GetAnnouncementRequest) (*Announcement, error)
+	// List returns a page of the announcements of a project.
+	List(context.Context, *ListAnnouncementsRequest) (*ListAnnouncementsResponse, error)
+	// Create creates an announcement.
+	Create(context.Context, *CreateAnnouncementRequest) (*Announcement, error)
+	// Update replaces an announcement. It fails with ABORTED if the resource version is set and stale.
+	Update(context.Context, *UpdateAnnouncementRequest) (*Announcement, error)
+	// Delete deletes an announcement and returns its last state.
+	Delete(context.Context, *DeleteAnnouncementRequest) (*Announcement, error)
+	// Watch streams the changes of the announcements.
+	Watch(*WatchAnnouncementsRequest, AnnouncementService_WatchServer) error
+	mustEmbedUnimplementedAnnouncementServiceServer()
+}
+
+// UnimplementedAnnouncementServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAnnouncementServiceServer struct {
+}
+
+func (UnimplementedAnnouncementServiceServer) Get(context.Context, *GetAnnouncementRequest) (*Announcement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedAnnouncementServiceServer) List(context.Context, *ListAnnouncementsRequest) (*ListAnnouncementsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedAnnouncementServiceServer) Create(context.Context, *CreateAnnouncementRequest) (*Announcement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedAnnouncementServiceServer) Update(context.Context, *UpdateAnnouncementRequest) (*Announcement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedAnnouncementServiceServer) Delete(context.Context, *DeleteAnnouncementRequest) (*Announcement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedAnnouncementServiceServer) Watch(*WatchAnnouncementsRequest, AnnouncementService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedAnnouncementServiceServer) mustEmbedUnimplementedAnnouncementServiceServer() {}
+
+// UnsafeAnnouncementServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AnnouncementServiceServer will
+// result in compilation errors.
+type UnsafeAnnouncementServiceServer interface {
+	mustEmbedUnimplementedAnnouncementServiceServer()
+}
+
+func RegisterAnnouncementServiceServer(s grpc.ServiceRegistrar, srv AnnouncementServiceServer) {
+	s.RegisterService(&AnnouncementService_ServiceDesc, srv)
+}
+
+func _AnnouncementService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAnnouncementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnnouncementServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnnouncementService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnnouncementServiceServer).Get(ctx, req.(*GetAnnouncementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnnouncementService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAnnouncementsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnnouncementServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnnouncementService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnnouncementServiceServer).List(ctx, req.(*ListAnnouncementsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnnouncementService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAnnouncementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnnouncementServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnnouncementService_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnnouncementServiceServer).Create(ctx, req.(*CreateAnnouncementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnnouncementService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAnnouncementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnnouncementServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnnouncementService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnnouncementServiceServer).Update(ctx, req.(*UpdateAnnouncementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnnouncementService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAnnouncementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnnouncementServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AnnouncementService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnnouncementServiceServer).Delete(ctx, req.(*DeleteAnnouncementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnnouncementService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAnnouncementsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AnnouncementServiceServer).Watch(m, &announcementServiceWatchServer{ServerStream: stream})
+}
+
+type AnnouncementService_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type announcementServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *announcementServiceWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// AnnouncementService_ServiceDesc is the grpc.ServiceDesc for AnnouncementService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AnnouncementService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "corebgp.v1.AnnouncementService",
+	HandlerType: (*AnnouncementServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _AnnouncementService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _AnnouncementService_List_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _AnnouncementService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _AnnouncementService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _AnnouncementService_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _AnnouncementService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "announcement.proto",
+}
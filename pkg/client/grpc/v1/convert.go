@@ -0,0 +1,278 @@
+package corebgpv1
+
+import (
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// AnnouncementFromModel converts the announcement of the REST API model to its message. The configurations last
+// applied by the field managers are not part of the message.
+func AnnouncementFromModel(a *model.Announcement) *Announcement {
+	announcement := &Announcement{
+		Meta: &Meta{
+			Name:            a.Meta.Name,
+			Project:         a.Meta.Project,
+			ResourceVersion: a.Meta.ResourceVersion,
+			CreatedAt:       a.Meta.CreatedAt,
+			Labels:          a.Meta.Labels,
+			Annotations:     a.Meta.Annotations,
+			Generation:      a.Meta.Generation,
+			ChangeId:        a.Meta.ChangeID,
+			ChangedAt:       a.Meta.ChangedAt,
+		},
+		Addresses: &Addresses{
+			AnnouncedAddress: subnetFromModel(a.Addresses.SourceSubnets),
+			Zone:             a.Addresses.Zone,
+			AnnouncedIp:      a.Addresses.AnnouncedIP,
+			AnnouncedIpv6:    a.Addresses.AnnouncedIPv6,
+			Pool:             a.Addresses.Pool,
+		},
+		Origin:        string(a.Origin),
+		LocalPref:     a.LocalPref,
+		Med:           a.MED,
+		AsPathPrepend: uint32(a.ASPathPrepend),
+		Communities:   a.Communities,
+		BfdPeers:      a.BFDPeers,
+		Failover:      string(a.Failover),
+		HealthCheck: &HealthCheck{
+			Type:          string(a.HealthCheck.Type),
+			Path:          a.HealthCheck.Path,
+			Port:          int32(a.HealthCheck.Port),
+			Method:        a.HealthCheck.Method,
+			Interval:      int32(a.HealthCheck.CheckInterval),
+			Timeout:       int32(a.HealthCheck.Timeout),
+			GracePeriod:   int32(a.HealthCheck.GracePeriod),
+			Rise:          int32(a.HealthCheck.Rise),
+			Fall:          int32(a.HealthCheck.Fall),
+			Service:       a.HealthCheck.Service,
+			Tls:           a.HealthCheck.TLS,
+			TlsServerName: a.HealthCheck.TLSServerName,
+		},
+		Status: &Status{
+			Status:             a.Status.Status,
+			Timestamp:          a.Status.Timestamp,
+			Draining:           a.Status.Draining,
+			ObservedGeneration: a.Status.ObservedGeneration,
+			ProgrammedAt:       a.Status.ProgrammedAt,
+			PropagationSeconds: a.Status.PropagationSeconds,
+		},
+	}
+
+	if a.Schedule != nil {
+		announcement.Schedule = &Schedule{ActiveFrom: a.Schedule.ActiveFrom, ActiveUntil: a.Schedule.ActiveUntil, Ttl: int32(a.Schedule.TTL)}
+	}
+	for _, nextHop := range a.NextHops {
+		announcement.NextHops = append(announcement.NextHops, subnetFromModel(nextHop))
+	}
+	for _, nextHop := range a.WeightedNextHops {
+		announcement.WeightedNextHops = append(announcement.WeightedNextHops, &WeightedNextHop{Address: nextHop.Address, Weight: nextHop.Weight})
+	}
+	if len(a.NextHopCommunities) > 0 {
+		announcement.NextHopCommunities = make(map[string]*Communities, len(a.NextHopCommunities))
+		for nextHop, communities := range a.NextHopCommunities {
+			announcement.NextHopCommunities[nextHop] = &Communities{Values: communities}
+		}
+	}
+	if len(a.Sites) > 0 {
+		announcement.Sites = make(map[string]*SiteOverride, len(a.Sites))
+		for site, override := range a.Sites {
+			announcement.Sites[site] = siteOverrideFromModel(override)
+		}
+	}
+
+	for _, details := range a.Status.Details {
+		announcement.Status.Details = append(announcement.Status.Details, &Details{
+			Host:                details.Host,
+			Site:                details.Site,
+			Status:              details.Status,
+			Code:                int32(details.Code),
+			Msg:                 details.Message,
+			Timestamp:           details.Timestamp,
+			LatencyMs:           details.LatencyMS,
+			ConsecutiveFailures: int32(details.ConsecutiveFailures),
+		})
+	}
+	for _, router := range a.Status.Routers {
+		announcement.Status.Routers = append(announcement.Status.Routers, &RouterStatus{
+			Router:             router.Router,
+			Site:               router.Site,
+			Status:             router.Status,
+			Msg:                router.Message,
+			Timestamp:          router.Timestamp,
+			ObservedGeneration: router.ObservedGeneration,
+		})
+	}
+	for _, condition := range a.Status.Conditions {
+		announcement.Status.Conditions = append(announcement.Status.Conditions, &Condition{
+			Type:               condition.Type,
+			Status:             condition.Status,
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime,
+		})
+	}
+	return announcement
+}
+
+// ToModel converts the message to the announcement of the REST API model. Missing fields are left at their zero
+// values.
+func (x *Announcement) ToModel() *model.Announcement {
+	a := &model.Announcement{
+		Meta: model.Meta{
+			Name:            x.GetMeta().GetName(),
+			Project:         x.GetMeta().GetProject(),
+			ResourceVersion: x.GetMeta().GetResourceVersion(),
+			CreatedAt:       x.GetMeta().GetCreatedAt(),
+			Labels:          x.GetMeta().GetLabels(),
+			Annotations:     x.GetMeta().GetAnnotations(),
+			Generation:      x.GetMeta().GetGeneration(),
+			ChangeID:        x.GetMeta().GetChangeId(),
+			ChangedAt:       x.GetMeta().GetChangedAt(),
+		},
+		Addresses: model.Addresses{
+			SourceSubnets: x.GetAddresses().GetAnnouncedAddress().toModel(),
+			Zone:          x.GetAddresses().GetZone(),
+			AnnouncedIP:   x.GetAddresses().GetAnnouncedIp(),
+			AnnouncedIPv6: x.GetAddresses().GetAnnouncedIpv6(),
+			Pool:          x.GetAddresses().GetPool(),
+		},
+		Origin:        model.BGPOrigin(x.GetOrigin()),
+		LocalPref:     x.GetLocalPref(),
+		MED:           x.GetMed(),
+		ASPathPrepend: uint8(min(x.GetAsPathPrepend(), 255)),
+		Communities:   x.GetCommunities(),
+		BFDPeers:      x.GetBfdPeers(),
+		Failover:      model.FailoverPolicy(x.GetFailover()),
+		HealthCheck: model.HealthCheck{
+			Type:          model.HealthCheckType(x.GetHealthCheck().GetType()),
+			Path:          x.GetHealthCheck().GetPath(),
+			Port:          int(x.GetHealthCheck().GetPort()),
+			Method:        x.GetHealthCheck().GetMethod(),
+			CheckInterval: int(x.GetHealthCheck().GetInterval()),
+			Timeout:       int(x.GetHealthCheck().GetTimeout()),
+			GracePeriod:   int(x.GetHealthCheck().GetGracePeriod()),
+			Rise:          int(x.GetHealthCheck().GetRise()),
+			Fall:          int(x.GetHealthCheck().GetFall()),
+			Service:       x.GetHealthCheck().GetService(),
+			TLS:           x.GetHealthCheck().GetTls(),
+			TLSServerName: x.GetHealthCheck().GetTlsServerName(),
+		},
+		Status: model.Status{
+			Status:             x.GetStatus().GetStatus(),
+			Timestamp:          x.GetStatus().GetTimestamp(),
+			Draining:           x.GetStatus().GetDraining(),
+			ObservedGeneration: x.GetStatus().GetObservedGeneration(),
+			ProgrammedAt:       x.GetStatus().GetProgrammedAt(),
+			PropagationSeconds: x.GetStatus().GetPropagationSeconds(),
+		},
+	}
+
+	if schedule := x.GetSchedule(); schedule != nil {
+		a.Schedule = &model.Schedule{ActiveFrom: schedule.GetActiveFrom(), ActiveUntil: schedule.GetActiveUntil(), TTL: int(schedule.GetTtl())}
+	}
+	for _, nextHop := range x.GetNextHops() {
+		a.NextHops = append(a.NextHops, nextHop.toModel())
+	}
+	for _, nextHop := range x.GetWeightedNextHops() {
+		a.WeightedNextHops = append(a.WeightedNextHops, model.WeightedNextHop{Address: nextHop.GetAddress(), Weight: nextHop.GetWeight()})
+	}
+	if len(x.GetNextHopCommunities()) > 0 {
+		a.NextHopCommunities = make(map[string][]string, len(x.GetNextHopCommunities()))
+		for nextHop, communities := range x.GetNextHopCommunities() {
+			a.NextHopCommunities[nextHop] = communities.GetValues()
+		}
+	}
+	if len(x.GetSites()) > 0 {
+		a.Sites = make(map[string]model.SiteOverride, len(x.GetSites()))
+		for site, override := range x.GetSites() {
+			a.Sites[site] = override.toModel()
+		}
+	}
+
+	for _, details := range x.GetStatus().GetDetails() {
+		a.Status.Details = append(a.Status.Details, model.Details{
+			Host:                details.GetHost(),
+			Site:                details.GetSite(),
+			Status:              details.GetStatus(),
+			Code:                int(details.GetCode()),
+			Message:             details.GetMsg(),
+			Timestamp:           details.GetTimestamp(),
+			LatencyMS:           details.GetLatencyMs(),
+			ConsecutiveFailures: int(details.GetConsecutiveFailures()),
+		})
+	}
+	for _, router := range x.GetStatus().GetRouters() {
+		a.Status.Routers = append(a.Status.Routers, model.RouterStatus{
+			Router:             router.GetRouter(),
+			Site:               router.GetSite(),
+			Status:             router.GetStatus(),
+			Message:            router.GetMsg(),
+			Timestamp:          router.GetTimestamp(),
+			ObservedGeneration: router.GetObservedGeneration(),
+		})
+	}
+	for _, condition := range x.GetStatus().GetConditions() {
+		a.Status.Conditions = append(a.Status.Conditions, model.Condition{
+			Type:               condition.GetType(),
+			Status:             condition.GetStatus(),
+			Reason:             condition.GetReason(),
+			Message:            condition.GetMessage(),
+			LastTransitionTime: condition.GetLastTransitionTime(),
+		})
+	}
+	return a
+}
+
+// WatchEventFromModel converts the watch event of the REST API model to its message.
+func WatchEventFromModel(event model.Event) *WatchEvent {
+	return &WatchEvent{
+		Type:         string(event.Type),
+		Revision:     event.Revision,
+		Announcement: AnnouncementFromModel(&event.Announcement),
+	}
+}
+
+// ToModel converts the message to the watch event of the REST API model.
+func (x *WatchEvent) ToModel() model.Event {
+	event := model.Event{
+		Type:     model.EventType(x.GetType()),
+		Revision: x.GetRevision(),
+	}
+	if x.GetAnnouncement() != nil {
+		event.Announcement = *x.GetAnnouncement().ToModel()
+	}
+	return event
+}
+
+// subnetFromModel converts the subnet of the REST API model to its message.
+func subnetFromModel(subnet model.Subnet) *Subnet {
+	return &Subnet{Ip: subnet.IP, Mask: uint32(subnet.Mask)}
+}
+
+// toModel converts the message to the subnet of the REST API model.
+func (x *Subnet) toModel() model.Subnet {
+	return model.Subnet{IP: x.GetIp(), Mask: uint8(min(x.GetMask(), 255))}
+}
+
+// siteOverrideFromModel converts the site override of the REST API model to its message.
+func siteOverrideFromModel(override model.SiteOverride) *SiteOverride {
+	message := &SiteOverride{Disabled: override.Disabled, Med: override.MED}
+	for _, nextHop := range override.NextHops {
+		message.NextHops = append(message.NextHops, subnetFromModel(nextHop))
+	}
+	for _, nextHop := range override.WeightedNextHops {
+		message.WeightedNextHops = append(message.WeightedNextHops, &WeightedNextHop{Address: nextHop.Address, Weight: nextHop.Weight})
+	}
+	return message
+}
+
+// toModel converts the message to the site override of the REST API model.
+func (x *SiteOverride) toModel() model.SiteOverride {
+	override := model.SiteOverride{Disabled: x.GetDisabled(), MED: x.GetMed()}
+	for _, nextHop := range x.GetNextHops() {
+		override.NextHops = append(override.NextHops, nextHop.toModel())
+	}
+	for _, nextHop := range x.GetWeightedNextHops() {
+		override.WeightedNextHops = append(override.WeightedNextHops, model.WeightedNextHop{Address: nextHop.GetAddress(), Weight: nextHop.GetWeight()})
+	}
+	return override
+}
@@ -0,0 +1,26 @@
+package corebgpv1
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// WithBearerToken authenticates every call with the static API token or OIDC token by sending it in the
+// authorization metadata, as the REST client sends it in the Authorization header.
+func WithBearerToken(token string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(bearerToken(token))
+}
+
+// bearerToken is a credentials.PerRPCCredentials sending a bearer token.
+type bearerToken string
+
+// GetRequestMetadata returns the authorization metadata of a call.
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows the token on plaintext connections, like the REST client does on http URLs.
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,5 @@
+// Package corebgpv1 holds the gRPC AnnouncementService of the API server and its generated client. The messages
+// mirror the model of the v1 REST API; the conversion functions translate between both.
+package corebgpv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative announcement.proto
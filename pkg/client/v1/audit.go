@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// AuditOptions holds the optional filters of audit log requests.
+type AuditOptions struct {
+	Project  string    // Project restricts the result to the entries of the project; empty returns all projects.
+	Since    time.Time // Since excludes the entries written before the time; zero means no lower bound.
+	Until    time.Time // Until excludes the entries written after the time; zero means no upper bound.
+	Limit    int       // Limit is the maximum number of entries in a page; zero uses the server default.
+	Continue string    // Continue is the token returned with the previous page; empty requests the first page.
+}
+
+// V1ListAudit returns a single page of the audit log of the announcement changes, ordered from the oldest entry to
+// the newest. Pass the Continue token of the returned page in opts to request the next one.
+func (c *APIClient) V1ListAudit(ctx context.Context, opts AuditOptions) (*model.AuditList, error) {
+	query := url.Values{}
+	if opts.Project != "" {
+		query.Set("project", opts.Project)
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.Format(time.RFC3339))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Continue != "" {
+		query.Set("continue", opts.Continue)
+	}
+	baseURL := fmt.Sprintf("%s/v1/audit?%s", c.endpoint(), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list audit entries", resp)
+	}
+
+	var list model.AuditList
+	if err := decodeResponse(resp, &list); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
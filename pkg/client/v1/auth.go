@@ -0,0 +1,27 @@
+package v1
+
+import "net/http"
+
+// WithBearerToken authenticates every request and watch connection with the static API token or OIDC token
+// by sending it in the Authorization header.
+func WithBearerToken(token string) ClientOption {
+	return func(c *APIClient) {
+		c.authHeader = "Bearer " + token
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &authTransport{next: next, header: c.authHeader}
+		})
+	}
+}
+
+// authTransport is an http.RoundTripper that sets the Authorization header on each request.
+type authTransport struct {
+	next   http.RoundTripper
+	header string
+}
+
+// RoundTrip sets the Authorization header on a copy of the request, since RoundTrippers must not modify it.
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", t.header)
+	return t.next.RoundTrip(req)
+}
@@ -1,37 +1,86 @@
 package v1
 
 import (
+	"bufio"
 	"bytes"
+	"compress/flate"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/nikitamishagin/corebgp/internal/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // APIClient represents the client for interacting with the API server.
 type APIClient struct {
-	baseURL    string
-	httpClient *http.Client
+	mu          sync.RWMutex
+	baseURL     string
+	httpClient  *http.Client
+	tlsConfig   *tls.Config                                 // tlsConfig is the TLS configuration used for HTTPS and WSS connections.
+	enableHTTP2 bool                                        // enableHTTP2 forces the HTTP/2 transport even without ALPN negotiation.
+	middlewares []func(http.RoundTripper) http.RoundTripper // middlewares wrap the base transport in the order of the options.
+	authHeader  string                                      // authHeader is the Authorization header value sent on requests and watch dials.
+	logger      *slog.Logger                                // logger receives the client diagnostics; nil means slog.Default().
+
+	droppedEvents atomic.Uint64 // droppedEvents counts the watch events discarded by the DropOldest backpressure strategy.
+	preferSSE     atomic.Bool   // preferSSE is set once a watch fell back to Server-Sent Events, so that automatic watches skip the WebSocket upgrade.
 }
 
-// NewAPIClient creates a new API client instance.
-func NewAPIClient(baseURL *string, timeout time.Duration) *APIClient {
-	return &APIClient{
+// NewAPIClient creates a new API client instance. Optional behaviour is configured with ClientOption values.
+func NewAPIClient(baseURL *string, timeout time.Duration, opts ...ClientOption) *APIClient {
+	c := &APIClient{
 		baseURL: *baseURL,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.httpClient = &http.Client{
+		Timeout:   timeout,
+		Transport: c.buildTransport(),
+	}
+	return c
+}
+
+// SetBaseURL replaces the API server URL used by subsequent requests. Established watch connections are not affected.
+func (c *APIClient) SetBaseURL(baseURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.baseURL = baseURL
+}
+
+// log returns the logger of the client diagnostics.
+func (c *APIClient) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return slog.Default()
+}
+
+// endpoint returns the current API server URL.
+func (c *APIClient) endpoint() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.baseURL
 }
 
 // V1HealthCheck checks the health status of the API server (Version 1).
 func (c *APIClient) V1HealthCheck(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/healthz", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint()+"/healthz", nil)
 	if err != nil {
 		return err
 	}
@@ -43,15 +92,99 @@ func (c *APIClient) V1HealthCheck(ctx context.Context) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("health check failed: status code %d", resp.StatusCode)
+		return responseError("health check failed", resp)
 	}
 
 	return nil
 }
 
-// V1ListAnnouncements кeturns a list of announcement IDs from the API (globally).
-func (c *APIClient) V1ListAnnouncements(ctx context.Context) ([]string, error) {
-	baseURL := fmt.Sprintf("%s/v1/announcements/", c.baseURL)
+// V1Ready checks the readiness of the API server, which requires its datastore and GoBGP to be reachable. It returns
+// the results of the checks, or an error wrapping ErrServerError when the server is not ready.
+func (c *APIClient) V1Ready(ctx context.Context) (*model.Readiness, error) {
+	var readiness model.Readiness
+	if err := c.getInfo(ctx, "/readyz", "readiness check failed", &readiness); err != nil {
+		return nil, err
+	}
+	return &readiness, nil
+}
+
+// V1Version returns the build version, the commit and the Go version of the API server.
+func (c *APIClient) V1Version(ctx context.Context) (*model.BuildInfo, error) {
+	var info model.BuildInfo
+	if err := c.getInfo(ctx, "/version", "failed to get version", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// getInfo sends a GET request to the path outside the v1 API and decodes the data of the response.
+func (c *APIClient) getInfo(ctx context.Context, path, message string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint()+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(message, resp)
+	}
+	return decodeResponse(resp, out)
+}
+
+// ListOptions holds the optional parameters of list requests.
+type ListOptions struct {
+	IncludeWithdrawn bool   // IncludeWithdrawn includes soft-deleted announcements in the result.
+	Limit            int    // Limit is the maximum number of announcements in a page; zero uses the server maximum.
+	Continue         string // Continue is the token returned with the previous page; empty requests the first page.
+	NamePrefix       string // NamePrefix restricts the result to the announcements whose names start with the prefix.
+	LabelSelector    string // LabelSelector restricts the result to the announcements whose labels meet it, e.g., "env=staging".
+}
+
+// maxListPageSize is the page size requested when ListOptions.Limit is not set. The server caps it to its own maximum.
+const maxListPageSize = 1000
+
+// V1ListAnnouncements returns a list of announcement IDs in the "project/name" form from the API (globally).
+func (c *APIClient) V1ListAnnouncements(ctx context.Context, opts ListOptions) ([]string, error) {
+	query := url.Values{}
+	if opts.IncludeWithdrawn {
+		query.Set("includeWithdrawn", "true")
+	}
+	if opts.LabelSelector != "" {
+		query.Set("labelSelector", opts.LabelSelector)
+	}
+
+	announcementsByProject, err := c.getAllAnnouncements(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(announcementsByProject))
+	for project, announcements := range announcementsByProject {
+		for _, announcement := range announcements {
+			ids = append(ids, project+"/"+announcement.Meta.Name)
+		}
+	}
+
+	return ids, nil
+}
+
+// V1GetAllAnnouncements returns a snapshot of every announcement in the system keyed by project name.
+// The returned map is never nil, even when there are no announcements.
+func (c *APIClient) V1GetAllAnnouncements(ctx context.Context) (map[string][]*model.Announcement, error) {
+	return c.getAllAnnouncements(ctx, url.Values{})
+}
+
+// getAllAnnouncements requests all announcements keyed by project name with the specified query parameters.
+func (c *APIClient) getAllAnnouncements(ctx context.Context, query url.Values) (map[string][]*model.Announcement, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/", c.endpoint())
+	if len(query) > 0 {
+		baseURL += "?" + query.Encode()
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
 	if err != nil {
@@ -65,22 +198,23 @@ func (c *APIClient) V1ListAnnouncements(ctx context.Context) ([]string, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to list announcements: status code %d", resp.StatusCode)
+		return nil, responseError("failed to get all announcements", resp)
 	}
 
-	var response struct {
-		Announcements []string `json:"announcements"`
+	announcements := make(map[string][]*model.Announcement)
+	if err := decodeResponse(resp, &announcements); err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if announcements == nil {
+		announcements = make(map[string][]*model.Announcement)
 	}
 
-	return response.Announcements, nil
+	return announcements, nil
 }
 
 // V1ListAllAnnouncements returns a list of all announcements from the API (globally).
 func (c *APIClient) V1ListAllAnnouncements(ctx context.Context) ([]model.Announcement, error) {
-	baseURL := fmt.Sprintf("%s/v1/announcements/all", c.baseURL)
+	baseURL := fmt.Sprintf("%s/v1/announcements/all", c.endpoint())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
 	if err != nil {
@@ -94,12 +228,12 @@ func (c *APIClient) V1ListAllAnnouncements(ctx context.Context) ([]model.Announc
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to list all announcements: status code %d", resp.StatusCode)
+		return nil, responseError("failed to list all announcements", resp)
 	}
 
 	var announcements []model.Announcement
-	if err := json.NewDecoder(resp.Body).Decode(&announcements); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if err := decodeResponse(resp, &announcements); err != nil {
+		return nil, err
 	}
 
 	return announcements, nil
@@ -107,7 +241,7 @@ func (c *APIClient) V1ListAllAnnouncements(ctx context.Context) ([]model.Announc
 
 // V1ListProjectAnnouncements returns a list of announcement IDs from the API for the specified project.
 func (c *APIClient) V1ListProjectAnnouncements(ctx context.Context, project string) ([]string, error) {
-	baseURL := fmt.Sprintf("%s/v1/announcements/%s/", c.baseURL, project)
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/", c.endpoint(), project)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
 	if err != nil {
@@ -121,14 +255,14 @@ func (c *APIClient) V1ListProjectAnnouncements(ctx context.Context, project stri
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to list announcements for project: status code %d", resp.StatusCode)
+		return nil, responseError("failed to list announcements for project", resp)
 	}
 
 	var response struct {
 		Announcements []string `json:"announcements"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	return response.Announcements, nil
@@ -136,7 +270,7 @@ func (c *APIClient) V1ListProjectAnnouncements(ctx context.Context, project stri
 
 // V1ListAllProjectAnnouncements returns a list of all announcements from the API for the specified project.
 func (c *APIClient) V1ListAllProjectAnnouncements(ctx context.Context, project string) ([]model.Announcement, error) {
-	baseURL := fmt.Sprintf("%s/v1/announcements/%s/all", c.baseURL, project)
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/all", c.endpoint(), project)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
 	if err != nil {
@@ -150,20 +284,94 @@ func (c *APIClient) V1ListAllProjectAnnouncements(ctx context.Context, project s
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to list all announcements for project: status code %d", resp.StatusCode)
+		return nil, responseError("failed to list all announcements for project", resp)
 	}
 
 	var announcements []model.Announcement
 	if err := json.NewDecoder(resp.Body).Decode(&announcements); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	return announcements, nil
 }
 
-// V1GetAnnouncement retrieves an announcement by project and name.
-func (c *APIClient) V1GetAnnouncement(ctx context.Context, project, name string) (*model.Announcement, error) {
-	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s", c.baseURL, project, name)
+// V1ListAnnouncementsPage returns a single page of the project announcements. Pass the Continue token of the returned
+// page in opts to request the next one; an empty token in the result marks the last page.
+func (c *APIClient) V1ListAnnouncementsPage(ctx context.Context, project string, opts ListOptions) (*model.AnnouncementList, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = maxListPageSize
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if opts.Continue != "" {
+		query.Set("continue", opts.Continue)
+	}
+	if opts.NamePrefix != "" {
+		query.Set("namePrefix", opts.NamePrefix)
+	}
+	if opts.LabelSelector != "" {
+		query.Set("labelSelector", opts.LabelSelector)
+	}
+	if opts.IncludeWithdrawn {
+		query.Set("includeWithdrawn", "true")
+	}
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/all?%s", c.endpoint(), url.PathEscape(project), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list announcements page", resp)
+	}
+
+	var page model.AnnouncementList
+	if err := decodeResponse(resp, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+// V1ListAnnouncementsByNextHop returns all announcements that use the specified next-hop address.
+func (c *APIClient) V1ListAnnouncementsByNextHop(ctx context.Context, nextHop string) ([]*model.Announcement, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements?%s", c.endpoint(), url.Values{"nextHop": {nextHop}}.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list announcements by next hop", resp)
+	}
+
+	var announcements []*model.Announcement
+	if err := decodeResponse(resp, &announcements); err != nil {
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+// V1ListAnnouncementsBySelector returns the announcements of all projects whose labels meet the label selector, e.g.,
+// "env=staging" or "env in (staging,test),!pinned".
+func (c *APIClient) V1ListAnnouncementsBySelector(ctx context.Context, selector string) ([]model.Announcement, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/all?%s", c.endpoint(), url.Values{"labelSelector": {selector}}.Encode())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
 	if err != nil {
@@ -176,25 +384,117 @@ func (c *APIClient) V1GetAnnouncement(ctx context.Context, project, name string)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("announcement not found")
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list announcements by selector", resp)
+	}
+
+	var announcements []model.Announcement
+	if err := decodeResponse(resp, &announcements); err != nil {
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+// V1QueryAnnouncements returns the announcements of the project for which the JMESPath expression yields a truthy
+// value, e.g. "contains(communities, '65000:100')".
+func (c *APIClient) V1QueryAnnouncements(ctx context.Context, project string, expr string) ([]*model.Announcement, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/query", c.endpoint(), url.PathEscape(project))
+
+	data, err := json.Marshal(model.AnnouncementQuery{Expr: expr})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to query announcements", resp)
+	}
+
+	var announcements []*model.Announcement
+	if err := decodeResponse(resp, &announcements); err != nil {
+		return nil, err
+	}
+
+	return announcements, nil
+}
+
+// V1GetAnnouncement retrieves an announcement by project and name.
+func (c *APIClient) V1GetAnnouncement(ctx context.Context, project, name string) (*model.Announcement, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s", c.endpoint(), project, name)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch announcement: status code %d", resp.StatusCode)
+		return nil, responseError("failed to fetch announcement", resp)
 	}
 
 	var announcement model.Announcement
-	if err := json.NewDecoder(resp.Body).Decode(&announcement); err != nil {
-		return nil, fmt.Errorf("failed to decode announcement: %v", err)
+	if err := decodeResponse(resp, &announcement); err != nil {
+		return nil, fmt.Errorf("failed to decode announcement: %w", err)
 	}
 
 	return &announcement, nil
 }
 
+// WriteOption configures optional behaviour of the methods that create, update or delete an announcement.
+type WriteOption func(*WriteOptions)
+
+// WriteOptions holds the parameters of a write.
+type WriteOptions struct {
+	IfMatch string // IfMatch is the resource version the stored announcement must have; empty makes the write unconditional.
+	DryRun  bool   // DryRun runs all checks of the write without persisting anything.
+}
+
+// newWriteOptions applies the options to the default parameters.
+func newWriteOptions(opts []WriteOption) WriteOptions {
+	var options WriteOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// url returns the URL of the write request, requesting a dry run if enabled.
+func (o WriteOptions) url(baseURL string) string {
+	if o.DryRun {
+		return baseURL + "?dryRun=true"
+	}
+	return baseURL
+}
+
+// DryRun makes the API server run all validations and conflict checks of the write without persisting anything.
+// It lets announcement manifests be checked, e.g., in CI, before they are deployed.
+func DryRun() WriteOption {
+	return func(o *WriteOptions) {
+		o.DryRun = true
+	}
+}
+
 // V1CreateAnnouncement creates a new announcement.
-func (c *APIClient) V1CreateAnnouncement(ctx context.Context, announcement *model.Announcement) error {
-	baseURL := c.baseURL + "/v1/announcements/"
+func (c *APIClient) V1CreateAnnouncement(ctx context.Context, announcement *model.Announcement, opts ...WriteOption) error {
+	baseURL := newWriteOptions(opts).url(c.endpoint() + "/v1/announcements/")
 
 	data, err := json.Marshal(announcement)
 	if err != nil {
@@ -214,129 +514,798 @@ func (c *APIClient) V1CreateAnnouncement(ctx context.Context, announcement *mode
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusConflict {
-		return fmt.Errorf("announcement already exists")
-	}
-
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create announcement: status code %d", resp.StatusCode)
+		return responseError("failed to create announcement", resp)
 	}
 
 	return nil
 }
 
-// V1UpdateAnnouncement updates an existing announcement.
-func (c *APIClient) V1UpdateAnnouncement(ctx context.Context, announcement *model.Announcement) error {
-	baseURL := c.baseURL + "/v1/announcements/"
+// V1CreateAnnouncementIfNotExists atomically creates the announcement unless it already exists. It returns
+// created=false without an error when an announcement with the same project and name is already stored.
+func (c *APIClient) V1CreateAnnouncementIfNotExists(ctx context.Context, announcement *model.Announcement) (bool, error) {
+	baseURL := c.endpoint() + "/v1/announcements/"
 
 	data, err := json.Marshal(announcement)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PATCH", baseURL, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(data))
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-None-Match", "*")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("announcement not found")
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusPreconditionFailed:
+		return false, nil
+	default:
+		return false, responseError("failed to create announcement", resp)
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update announcement: status code %d", resp.StatusCode)
+// V1BatchApply atomically creates or replaces all the announcements: either every announcement is stored or none is.
+// The per-item results are returned also when the batch is rejected, explaining which announcements are invalid.
+func (c *APIClient) V1BatchApply(ctx context.Context, announcements []model.Announcement) ([]model.BatchItemResult, error) {
+	operations := make([]model.BatchOperation, 0, len(announcements))
+	for _, announcement := range announcements {
+		operations = append(operations, model.BatchOperation{Action: model.BatchApply, Announcement: announcement})
 	}
-
-	return nil
+	return c.V1Batch(ctx, operations)
 }
 
-// V1DeleteAnnouncement deletes an announcement by project and name.
-func (c *APIClient) V1DeleteAnnouncement(ctx context.Context, project, name string) error {
-	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s", c.baseURL, project, name)
+// V1Batch atomically applies a mix of apply and delete operations. Either all operations succeed or none is applied.
+// The per-item results are returned also when the batch is rejected, explaining which operations are invalid.
+func (c *APIClient) V1Batch(ctx context.Context, operations []model.BatchOperation) ([]model.BatchItemResult, error) {
+	baseURL := c.endpoint() + "/v1/announcements/batch"
 
-	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL, nil)
+	data, err := json.Marshal(model.BatchRequest{Operations: operations})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("announcement not found")
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		var results []model.BatchItemResult
+		if err := decodeResponse(resp, &results); err != nil {
+			return nil, err
+		}
+		return results, &APIError{Op: "failed to apply batch", StatusCode: resp.StatusCode, Message: "batch rejected, no changes were applied"}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to delete announcement: status code %d", resp.StatusCode)
+		return nil, responseError("failed to apply batch", resp)
 	}
 
-	return nil
+	var results []model.BatchItemResult
+	if err := decodeResponse(resp, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
 }
 
-// V1WatchAnnouncements establishes a WebSocket connection to watch announcements.
-func (c *APIClient) V1WatchAnnouncements(ctx context.Context, onEvent func(event model.Event)) error {
+// V1ApplyAnnouncement creates or updates the announcement with declarative apply semantics. The non-zero fields of the
+// announcement become the configuration owned by the manager; fields the manager applied before but no longer sets are
+// removed, fields owned by other managers are kept. The stored announcement is returned.
+func (c *APIClient) V1ApplyAnnouncement(ctx context.Context, manager string, announcement *model.Announcement) (*model.Announcement, error) {
+	baseURL := c.endpoint() + "/v1/apply"
 
-	parsedURL, err := url.Parse(c.baseURL)
+	desired, err := managedFields(announcement)
 	if err != nil {
-		return fmt.Errorf("failed to parse base URL: %w", err)
+		return nil, err
 	}
 
-	// Replace 'http' with 'ws' and 'https' with 'wss'
-	switch parsedURL.Scheme {
-	case "http":
-		parsedURL.Scheme = "ws"
-	case "https":
-		parsedURL.Scheme = "wss"
-	default:
-		return fmt.Errorf("unsupported URL scheme: %s", parsedURL.Scheme)
+	data, err := json.Marshal(model.ApplyRequest{Manager: manager, Announcement: desired})
+	if err != nil {
+		return nil, err
 	}
 
-	// Append the path for WebSocket announcements
-	parsedURL.Path = "/v1/watch/announcements/"
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
 
-	// Build the WebSocket URL
-	webSocketURL := parsedURL.String()
+	req.Header.Set("Content-Type", "application/json")
 
-	// Initialize WebSocket connection
-	dialer := websocket.Dialer{}
-	conn, _, err := dialer.DialContext(ctx, webSocketURL, nil)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to establish websocket connection: %w", err)
+		return nil, err
 	}
-	defer conn.Close()
+	defer resp.Body.Close()
 
-	done := make(chan struct{})
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, responseError("failed to apply announcement", resp)
+	}
 
-	// Goroutine to read events from WebSocket.
-	go func() {
-		defer close(done)
-		for {
-			_, message, err := conn.ReadMessage()
-			if err != nil {
-				return
-			}
+	var event model.Event
+	if err := decodeResponse(resp, &event); err != nil {
+		return nil, err
+	}
 
-			var event model.Event
-			if err := json.Unmarshal(message, &event); err != nil {
-				fmt.Printf("failed to unmarshal websocket message: %v\n", err)
-				continue
-			}
+	return &event.Announcement, nil
+}
+
+// managedFields encodes the announcement without its zero-valued fields, so that only the fields set by the caller
+// are managed by the apply.
+func managedFields(announcement *model.Announcement) (json.RawMessage, error) {
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	pruneZeroValues(object)
+
+	return json.Marshal(object)
+}
 
-			onEvent(event)
+// pruneZeroValues removes null, false, zero, empty string and empty collection values from the object recursively.
+func pruneZeroValues(object map[string]interface{}) {
+	for key, value := range object {
+		if nested, ok := value.(map[string]interface{}); ok {
+			pruneZeroValues(nested)
+			value = nested
 		}
-	}()
 
-	<-done
+		switch v := value.(type) {
+		case nil:
+			delete(object, key)
+		case bool:
+			if !v {
+				delete(object, key)
+			}
+		case float64:
+			if v == 0 {
+				delete(object, key)
+			}
+		case string:
+			if v == "" {
+				delete(object, key)
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				delete(object, key)
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				delete(object, key)
+			}
+		}
+	}
+}
+
+// V1ValidateAnnouncement checks the announcement against all server-side policies without storing it.
+func (c *APIClient) V1ValidateAnnouncement(ctx context.Context, announcement *model.Announcement) (*model.ValidationReport, error) {
+	baseURL := c.endpoint() + "/v1/announcements/validate"
+
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to validate announcement", resp)
+	}
+
+	var report model.ValidationReport
+	if err := decodeResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// IfMatch makes the update conditional on the resource version of the stored announcement. The update fails with
+// ErrConflict if the announcement was modified since the version was read. It applies to V1UpdateAnnouncement and
+// V1RollbackAnnouncement only.
+func IfMatch(resourceVersion string) WriteOption {
+	return func(o *WriteOptions) {
+		o.IfMatch = resourceVersion
+	}
+}
+
+// V1UpdateAnnouncement updates an existing announcement. The update is conditional if the announcement carries a
+// resource version or the IfMatch option is given.
+func (c *APIClient) V1UpdateAnnouncement(ctx context.Context, announcement *model.Announcement, opts ...WriteOption) error {
+	options := newWriteOptions(opts)
+	baseURL := options.url(c.endpoint() + "/v1/announcements/")
+
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if options.IfMatch != "" {
+		req.Header.Set("If-Match", `"`+options.IfMatch+`"`)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to update announcement", resp)
+	}
+
+	return nil
+}
+
+// V1GetAnnouncementStatus returns the status of the announcement reported by the controllers, e.g., whether its
+// routes are programmed on the routers.
+func (c *APIClient) V1GetAnnouncementStatus(ctx context.Context, project, name string) (*model.Status, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/status", c.endpoint(), project, name)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get announcement status", resp)
+	}
+
+	var status model.Status
+	if err := decodeResponse(resp, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// V1GetAnnouncementHealth returns the latest health check results of the next hops of the announcement reported by
+// the updaters, with the number of healthy next hops, e.g., to show "3 of 4 next hops healthy".
+func (c *APIClient) V1GetAnnouncementHealth(ctx context.Context, project, name string) (*model.AnnouncementHealth, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/health", c.endpoint(), project, name)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get announcement health", resp)
+	}
+
+	var health model.AnnouncementHealth
+	if err := decodeResponse(resp, &health); err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
+// V1UpdateAnnouncementStatus merges the status patch into the status of the announcement and returns the result.
+// The operational state is replaced when set, the details and the router states by site, the conditions by type.
+// Writing the status requires the controller role.
+func (c *APIClient) V1UpdateAnnouncementStatus(ctx context.Context, project, name string, patch *model.Status) (*model.Status, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/status", c.endpoint(), project, name)
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to update announcement status", resp)
+	}
+
+	var event model.Event
+	if err := decodeResponse(resp, &event); err != nil {
+		return nil, err
+	}
+
+	return &event.Announcement.Status, nil
+}
+
+// V1DeleteAnnouncement deletes an announcement by project and name.
+func (c *APIClient) V1DeleteAnnouncement(ctx context.Context, project, name string, opts ...WriteOption) error {
+	baseURL := newWriteOptions(opts).url(fmt.Sprintf("%s/v1/announcements/%s/%s", c.endpoint(), project, name))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to delete announcement", resp)
+	}
+
+	return nil
+}
+
+// V1DeleteCollection deletes the announcements of the project whose labels meet the label selector, e.g.,
+// "env=staging", and returns them. The selector must not be empty. With DryRun, the announcements that would be
+// deleted are returned and nothing is deleted.
+func (c *APIClient) V1DeleteCollection(ctx context.Context, project, selector string, opts ...WriteOption) ([]model.Announcement, error) {
+	query := url.Values{"labelSelector": {selector}}
+	if newWriteOptions(opts).DryRun {
+		query.Set("dryRun", "true")
+	}
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/?%s", c.endpoint(), url.PathEscape(project), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to delete announcements", resp)
+	}
+
+	var events []model.Event
+	if err := decodeResponse(resp, &events); err != nil {
+		return nil, err
+	}
+
+	announcements := make([]model.Announcement, len(events))
+	for i, event := range events {
+		announcements[i] = event.Announcement
+	}
+	return announcements, nil
+}
+
+// V1SoftDeleteAnnouncement marks an announcement as withdrawn instead of removing it. Withdrawn announcements are
+// excluded from lists by default and are removed by the server after the retention period.
+func (c *APIClient) V1SoftDeleteAnnouncement(ctx context.Context, project, name string) error {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/withdraw", c.endpoint(), project, name)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to withdraw announcement", resp)
+	}
+
+	return nil
+}
+
+// V1CancelPendingAnnouncement abandons an announcement stuck in the pending state. The updater ignores cancelled
+// announcements until they are re-activated by a subsequent update.
+func (c *APIClient) V1CancelPendingAnnouncement(ctx context.Context, project, name string) error {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/cancel", c.endpoint(), project, name)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to cancel announcement", resp)
+	}
+
+	return nil
+}
+
+// V1WatchAnnouncements watches the announcements over a WebSocket or as Server-Sent Events, depending on the
+// transport selected with WithTransport. It returns when the connection drops or the context is canceled.
+// Resync signals from the server are passed to the callback registered with WithResyncCallback instead of onEvent,
+// and bookmarks to the one registered with WithBookmarkCallback. A watch timing out returns ErrWatchTimeout.
+func (c *APIClient) V1WatchAnnouncements(ctx context.Context, onEvent func(event model.Event), opts ...WatchOption) error {
+	options := newWatchOptions(opts)
+
+	// Canceling the watch context closes the connection, which stops the reader
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Bookmarks are dispatched in order with the events, so that the consumer never sees a bookmark before the events
+	// it covers
+	deliver := func(event model.Event) {
+		if event.Type == model.EventBookmark {
+			if options.OnBookmark != nil {
+				options.OnBookmark(event.Revision)
+			}
+			return
+		}
+		onEvent(event)
+	}
+
+	// Dispatch events through a buffer when configured, so that a slow consumer does not stall the reader
+	dispatch := deliver
+	var dispatcher *eventDispatcher
+	if options.EventBuffer > 0 {
+		dispatcher = newEventDispatcher(options.EventBuffer, options.Backpressure, deliver, &c.droppedEvents)
+		defer dispatcher.close()
+		dispatch = func(event model.Event) {
+			if err := dispatcher.push(watchCtx, event); err != nil {
+				// Stop reading, the consumer can not keep up with the stream
+				cancel()
+			}
+		}
+	}
+
+	handle := func(event model.Event) {
+		if event.Type == model.EventResyncRequired {
+			if options.OnResync != nil {
+				options.OnResync(event.Revision)
+			}
+			return
+		}
+		dispatch(event)
+	}
+
+	var err error
+	switch {
+	case options.Transport == TransportSSE, options.Transport == TransportAuto && c.preferSSE.Load():
+		err = c.watchEventStream(watchCtx, options, handle)
+	default:
+		err = c.watchWebSocket(watchCtx, options, handle)
+		if options.Transport == TransportAuto && errors.Is(err, websocket.ErrBadHandshake) {
+			// A proxy on the way may not pass the upgrade through, retry with an event stream and keep using it
+			// for the next watches when it works
+			c.log().Warn("websocket upgrade failed, falling back to server-sent events", "error", err)
+			if err = c.watchEventStream(watchCtx, options, handle); err == nil {
+				c.preferSSE.Store(true)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if dispatcher != nil && dispatcher.overflowed() {
+		return ErrEventBufferFull
+	}
+	return ctx.Err()
+}
+
+// watchWebSocket passes the events received over a WebSocket connection to handle until the connection drops or
+// the context is canceled. It returns an error only when the connection cannot be established or times out.
+func (c *APIClient) watchWebSocket(ctx context.Context, options *WatchOptions, handle func(model.Event)) error {
+	parsedURL, err := url.Parse(c.endpoint())
+	if err != nil {
+		return fmt.Errorf("failed to parse base URL: %w", err)
+	}
+
+	// Replace 'http' with 'ws' and 'https' with 'wss'
+	switch parsedURL.Scheme {
+	case "http":
+		parsedURL.Scheme = "ws"
+	case "https":
+		parsedURL.Scheme = "wss"
+	default:
+		return fmt.Errorf("unsupported URL scheme: %s", parsedURL.Scheme)
+	}
+
+	// Append the path for WebSocket announcements
+	parsedURL.Path = "/v1/watch/announcements/"
+	query := options.filterQuery()
+	if options.Revision > 0 {
+		query.Set("revision", strconv.FormatInt(options.Revision, 10))
+	}
+	parsedURL.RawQuery = query.Encode()
+
+	// Build the WebSocket URL
+	webSocketURL := parsedURL.String()
+
+	// Initialize WebSocket connection
+	dialer := websocket.Dialer{
+		EnableCompression: options.EnableCompression,
+		TLSClientConfig:   c.tlsConfig,
+	}
+	header := http.Header{}
+	if c.authHeader != "" {
+		header.Set("Authorization", c.authHeader)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	conn, _, err := dialer.DialContext(ctx, webSocketURL, header)
+	if err != nil {
+		return fmt.Errorf("failed to establish websocket connection: %w", err)
+	}
+	defer conn.Close()
+
+	if options.EnableCompression {
+		conn.EnableWriteCompression(true)
+		if err := conn.SetCompressionLevel(flate.DefaultCompression); err != nil {
+			return fmt.Errorf("failed to set compression level: %w", err)
+		}
+	}
+
+	done := make(chan struct{})
+
+	// Close the connection when the context is canceled to unblock the reader
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	// Ping the server, so that a connection dropped silently on the way fails the read once the deadline passes
+	timeout := deadWatchPings * options.PingInterval
+	extendDeadline := func() {}
+	if options.PingInterval > 0 {
+		extendDeadline = func() {
+			_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+		extendDeadline()
+		conn.SetPongHandler(func(string) error {
+			extendDeadline()
+			return nil
+		})
+		go func() {
+			ticker := time.NewTicker(options.PingInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(options.PingInterval)); err != nil {
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	// Goroutine to read events from WebSocket.
+	var timedOut bool
+	go func() {
+		defer close(done)
+		for {
+			_, message, err := conn.ReadMessage()
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				timedOut = true
+			}
+			if err != nil {
+				return
+			}
+			extendDeadline()
+
+			var event model.Event
+			if err := json.Unmarshal(message, &event); err != nil {
+				c.log().Error("failed to unmarshal websocket message", "error", err)
+				continue
+			}
+			handle(event)
+		}
+	}()
+
+	<-done
+	if timedOut {
+		return fmt.Errorf("%w: nothing received for %s", ErrWatchTimeout, timeout)
+	}
+	return nil
+}
+
+// watchEventStream passes the events received as Server-Sent Events to handle until the connection drops or the
+// context is canceled. It returns an error only when the stream cannot be opened or times out. The revision to
+// resume from is sent in the Last-Event-ID header.
+func (c *APIClient) watchEventStream(ctx context.Context, options *WatchOptions, handle func(model.Event)) error {
+	streamURL := c.endpoint() + "/v1/watch/announcements/"
+	if query := options.filterQuery(); len(query) > 0 {
+		streamURL += "?" + query.Encode()
+	}
+
+	// An event stream can not be pinged, it times out when even the bookmarks of the server stop arriving
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timeout := deadWatchPings * options.PingInterval
+	var timedOut atomic.Bool
+	resetIdle := func() {}
+	if options.PingInterval > 0 {
+		idle := time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			cancel()
+		})
+		defer idle.Stop()
+		resetIdle = func() {
+			idle.Reset(timeout)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(streamCtx, "GET", streamURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if options.Revision > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(options.Revision, 10))
+	}
+
+	// The stream lasts as long as the watch, so the request timeout of the client does not apply
+	client := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := client.Do(req)
+	if timedOut.Load() {
+		return fmt.Errorf("%w: nothing received for %s", ErrWatchTimeout, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open event stream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to open event stream", resp)
+	}
+
+	// Only the data fields are used, the ID of an event is its revision, which is part of the data as well.
+	// Comments, e.g., heartbeats, are skipped.
+	reader := bufio.NewReader(resp.Body)
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if timedOut.Load() {
+			return fmt.Errorf("%w: nothing received for %s", ErrWatchTimeout, timeout)
+		}
+		if err != nil {
+			return nil
+		}
+		resetIdle()
+		line = strings.TrimRight(line, "\r\n")
+
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		// An empty line ends the event
+		var event model.Event
+		if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+			c.log().Error("failed to unmarshal server-sent event", "error", err)
+		} else {
+			handle(event)
+		}
+		data.Reset()
+	}
+}
+
+// DroppedWatchEvents returns the number of watch events discarded by the DropOldest backpressure strategy
+// across all watches of the client.
+func (c *APIClient) DroppedWatchEvents() uint64 {
+	return c.droppedEvents.Load()
+}
+
+// V1GetProjectSummary returns aggregate statistics of the announcements of the project without listing them.
+func (c *APIClient) V1GetProjectSummary(ctx context.Context, project string) (*model.ProjectSummary, error) {
+	baseURL := fmt.Sprintf("%s/v1/projects/%s/summary", c.endpoint(), url.PathEscape(project))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get project summary", resp)
+	}
+
+	var summary model.ProjectSummary
+	if err := decodeResponse(resp, &summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// decodeResponse decodes the standard API response envelope and unmarshals its data payload into out.
+func decodeResponse(resp *http.Response, out interface{}) error {
+	var envelope struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response data: %w", err)
+	}
+
 	return nil
 }
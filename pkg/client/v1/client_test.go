@@ -0,0 +1,431 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// errorCase describes a failing server response and the check the client error must satisfy.
+type errorCase struct {
+	name    string
+	handler http.HandlerFunc
+	timeout time.Duration // timeout is the context deadline of the request, zero means no deadline.
+	check   func(err error) bool
+}
+
+func TestAPIClientErrorHandling(t *testing.T) {
+	announcement := &model.Announcement{Meta: model.Meta{Project: "project", Name: "name"}}
+
+	methods := []struct {
+		name          string
+		successStatus int  // successStatus is the status code the method treats as a successful response.
+		decodes       bool // decodes reports whether the method decodes the response body.
+		call          func(ctx context.Context, c *APIClient) error
+	}{
+		{"V1HealthCheck", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1HealthCheck(ctx)
+		}},
+		{"V1Ready", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1Ready(ctx)
+			return err
+		}},
+		{"V1Version", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1Version(ctx)
+			return err
+		}},
+		{"V1ListAnnouncements", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAnnouncements(ctx, ListOptions{})
+			return err
+		}},
+		{"V1GetAllAnnouncements", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetAllAnnouncements(ctx)
+			return err
+		}},
+		{"V1ListAllAnnouncements", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAllAnnouncements(ctx)
+			return err
+		}},
+		{"V1ListProjectAnnouncements", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListProjectAnnouncements(ctx, "project")
+			return err
+		}},
+		{"V1ListAllProjectAnnouncements", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAllProjectAnnouncements(ctx, "project")
+			return err
+		}},
+		{"V1ListAnnouncementsPage", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAnnouncementsPage(ctx, "project", ListOptions{Limit: 10})
+			return err
+		}},
+		{"V1ListAnnouncementsByNextHop", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAnnouncementsByNextHop(ctx, "192.0.2.1")
+			return err
+		}},
+		{"V1ListAnnouncementsBySelector", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAnnouncementsBySelector(ctx, "env=staging")
+			return err
+		}},
+		{"V1IterateAnnouncements", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			for _, err := range c.V1IterateAnnouncements(ctx, "project") {
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"V1QueryAnnouncements", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1QueryAnnouncements(ctx, "project", "meta.name")
+			return err
+		}},
+		{"V1GetAnnouncement", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetAnnouncement(ctx, "project", "name")
+			return err
+		}},
+		{"V1CreateAnnouncement", http.StatusCreated, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1CreateAnnouncement(ctx, announcement)
+		}},
+		{"V1CreateAnnouncementDryRun", http.StatusCreated, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1CreateAnnouncement(ctx, announcement, DryRun())
+		}},
+		{"V1CreateAnnouncementIfNotExists", http.StatusCreated, false, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1CreateAnnouncementIfNotExists(ctx, announcement)
+			return err
+		}},
+		{"V1BatchApply", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1BatchApply(ctx, []model.Announcement{*announcement})
+			return err
+		}},
+		{"V1ApplyAnnouncement", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ApplyAnnouncement(ctx, "manager", announcement)
+			return err
+		}},
+		{"V1ValidateAnnouncement", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ValidateAnnouncement(ctx, announcement)
+			return err
+		}},
+		{"V1UpdateAnnouncement", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1UpdateAnnouncement(ctx, announcement)
+		}},
+		{"V1UpdateAnnouncementDryRun", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1UpdateAnnouncement(ctx, announcement, DryRun())
+		}},
+		{"V1UpdateAnnouncementIfMatch", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1UpdateAnnouncement(ctx, announcement, IfMatch("42"))
+		}},
+		{"V1DeleteCollection", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1DeleteCollection(ctx, "project", "env=staging")
+			return err
+		}},
+		{"V1DeleteAnnouncement", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1DeleteAnnouncement(ctx, "project", "name")
+		}},
+		{"V1DeleteAnnouncementDryRun", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1DeleteAnnouncement(ctx, "project", "name", DryRun())
+		}},
+		{"V1SoftDeleteAnnouncement", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1SoftDeleteAnnouncement(ctx, "project", "name")
+		}},
+		{"V1CancelPendingAnnouncement", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1CancelPendingAnnouncement(ctx, "project", "name")
+		}},
+		{"V1DrainNextHop", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1DrainNextHop(ctx, "project", "name", "192.0.2.1")
+			return err
+		}},
+		{"V1UndrainNextHop", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1UndrainNextHop(ctx, "project", "name", "192.0.2.1")
+			return err
+		}},
+		{"V1GetAnnouncementStatus", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetAnnouncementStatus(ctx, "project", "name")
+			return err
+		}},
+		{"V1GetAnnouncementHealth", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetAnnouncementHealth(ctx, "project", "name")
+			return err
+		}},
+		{"V1UpdateAnnouncementStatus", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1UpdateAnnouncementStatus(ctx, "project", "name", &model.Status{Status: model.StatusProgrammed})
+			return err
+		}},
+		{"V1GetProjectSummary", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetProjectSummary(ctx, "project")
+			return err
+		}},
+		{"V1GetAnnouncementHistory", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetAnnouncementHistory(ctx, "project", "name")
+			return err
+		}},
+		{"V1ListAnnouncementEvents", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAnnouncementEvents(ctx, "project", "name")
+			return err
+		}},
+		{"V1RollbackAnnouncement", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1RollbackAnnouncement(ctx, "project", "name", "1")
+			return err
+		}},
+		{"V1ListAudit", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAudit(ctx, AuditOptions{Project: "project"})
+			return err
+		}},
+		{"V1CreateProject", http.StatusCreated, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1CreateProject(ctx, &model.Project{Name: "project"})
+		}},
+		{"V1UpdateProject", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1UpdateProject(ctx, &model.Project{Name: "project"})
+		}},
+		{"V1ListProjects", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListProjects(ctx)
+			return err
+		}},
+		{"V1GetProject", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetProject(ctx, "project")
+			return err
+		}},
+		{"V1DeleteProject", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1DeleteProject(ctx, "project")
+		}},
+		{"V1CreatePool", http.StatusCreated, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1CreatePool(ctx, &model.Pool{Name: "pool", Project: "project"})
+			return err
+		}},
+		{"V1UpdatePool", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1UpdatePool(ctx, &model.Pool{Name: "pool", Project: "project"})
+			return err
+		}},
+		{"V1ListPools", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListPools(ctx, "project")
+			return err
+		}},
+		{"V1GetPool", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetPool(ctx, "project", "pool")
+			return err
+		}},
+		{"V1DeletePool", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1DeletePool(ctx, "project", "pool")
+		}},
+		{"V1RegisterUpdater", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1RegisterUpdater(ctx, "site", &model.SiteUpdater{Identity: "updater"})
+		}},
+		{"V1DeregisterUpdater", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1DeregisterUpdater(ctx, "site", "updater")
+		}},
+		{"V1ListSites", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListSites(ctx)
+			return err
+		}},
+		{"V1GetSite", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetSite(ctx, "site")
+			return err
+		}},
+		{"V1GetBGPSessionSummary", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetBGPSessionSummary(ctx)
+			return err
+		}},
+		{"V1GetGoBGPGlobalConfig", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetGoBGPGlobalConfig(ctx)
+			return err
+		}},
+		{"V1ConfigureGoBGP", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1ConfigureGoBGP(ctx, model.GoBGPGlobalConfig{})
+		}},
+		{"V1ListAnnouncedPaths", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListAnnouncedPaths(ctx, "192.0.2.0/24")
+			return err
+		}},
+		{"V1CreatePeer", http.StatusCreated, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1CreatePeer(ctx, &model.Peer{Address: "192.0.2.1", ASN: 65001})
+			return err
+		}},
+		{"V1UpdatePeer", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1UpdatePeer(ctx, &model.Peer{Address: "192.0.2.1", ASN: 65001})
+			return err
+		}},
+		{"V1UpdatePeerStatus", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1UpdatePeerStatus(ctx, "192.0.2.1", &model.PeerStatus{})
+			return err
+		}},
+		{"V1ListPeers", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListPeers(ctx)
+			return err
+		}},
+		{"V1GetPeer", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1GetPeer(ctx, "192.0.2.1")
+			return err
+		}},
+		{"V1DeletePeer", http.StatusOK, false, func(ctx context.Context, c *APIClient) error {
+			return c.V1DeletePeer(ctx, "192.0.2.1")
+		}},
+		{"V1Export", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1Export(ctx, "project")
+			return err
+		}},
+		{"V1Import", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1Import(ctx, &model.Bundle{Version: model.BundleVersion}, model.ImportSkip, DryRun())
+			return err
+		}},
+		{"V1ListConflicts", http.StatusOK, true, func(ctx context.Context, c *APIClient) error {
+			_, err := c.V1ListConflicts(ctx, "project")
+			return err
+		}},
+	}
+
+	// validates lists the methods that report invalid announcement fields as a *ValidationError
+	validates := map[string]bool{
+		"V1CreateAnnouncement":            true,
+		"V1CreateAnnouncementDryRun":      true,
+		"V1CreateAnnouncementIfNotExists": true,
+		"V1ApplyAnnouncement":             true,
+		"V1UpdateAnnouncement":            true,
+		"V1UpdateAnnouncementIfMatch":     true,
+		"V1UpdateAnnouncementDryRun":      true,
+		"V1RollbackAnnouncement":          true,
+		"V1Import":                        true,
+	}
+
+	respondWith := func(status int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}
+	}
+
+	for _, method := range methods {
+		cases := []errorCase{
+			{"404", respondWith(http.StatusNotFound, `{}`), 0, func(err error) bool {
+				return errors.Is(err, ErrNotFound)
+			}},
+			{"403", respondWith(http.StatusForbidden, `{"status": "error", "message": "role viewer may not perform this request"}`), 0, func(err error) bool {
+				var apiErr *APIError
+				return errors.Is(err, ErrForbidden) && errors.As(err, &apiErr) &&
+					apiErr.StatusCode == http.StatusForbidden && apiErr.Message == "role viewer may not perform this request"
+			}},
+			{"409", respondWith(http.StatusConflict, `{}`), 0, func(err error) bool {
+				return errors.Is(err, ErrConflict)
+			}},
+			{"429", respondWith(http.StatusTooManyRequests, `{}`), 0, func(err error) bool {
+				return errors.Is(err, ErrTooManyRequests)
+			}},
+			{"500", respondWith(http.StatusInternalServerError, `{}`), 0, func(err error) bool {
+				return errors.Is(err, ErrServerError)
+			}},
+			{"timeout", func(w http.ResponseWriter, r *http.Request) {
+				// Hold the response until the client gives up, the body is drained so that the server notices the disconnect
+				_, _ = io.Copy(io.Discard, r.Body)
+				<-r.Context().Done()
+			}, 50 * time.Millisecond, func(err error) bool {
+				return errors.Is(err, context.DeadlineExceeded)
+			}},
+		}
+		if validates[method.name] {
+			cases = append(cases, errorCase{"400", respondWith(http.StatusBadRequest, `{"status": "error", "message": "invalid announcement", "data": [{"field": "addresses.announced-ip", "message": "\"x\" is not a valid IP address"}]}`), 0, func(err error) bool {
+				var validationErr *ValidationError
+				return errors.As(err, &validationErr) && errors.Is(err, ErrValidation) &&
+					len(validationErr.Fields) == 1 && validationErr.Fields[0].Field == "addresses.announced-ip"
+			}})
+		}
+		if method.decodes {
+			cases = append(cases, errorCase{"malformed", respondWith(method.successStatus, `{"status": "success", "data": {not json}}`), 0, func(err error) bool {
+				var syntaxErr *json.SyntaxError
+				return errors.As(err, &syntaxErr)
+			}})
+		}
+
+		for _, tc := range cases {
+			t.Run(method.name+"/"+tc.name, func(t *testing.T) {
+				// Every sub-test has its own server, so that a handler still serving a previous request is not swapped
+				server := httptest.NewServer(tc.handler)
+				defer server.Close()
+				client := NewAPIClient(&server.URL, 5*time.Second)
+
+				ctx := context.Background()
+				if tc.timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+					defer cancel()
+				}
+
+				err := method.call(ctx, client)
+				if err == nil {
+					t.Fatal("expected an error, got nil")
+				}
+				if !tc.check(err) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestV1GetAllAnnouncements(t *testing.T) {
+	t.Run("multiple projects", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.URL.Path != "/v1/announcements/" {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status": "success", "data": {
+				"alpha": [{"meta": {"name": "web", "project": "alpha"}}, {"meta": {"name": "db", "project": "alpha"}}],
+				"beta": [{"meta": {"name": "dns", "project": "beta"}}]
+			}}`))
+		}))
+		defer server.Close()
+
+		announcements, err := NewAPIClient(&server.URL, 5*time.Second).V1GetAllAnnouncements(context.Background())
+		if err != nil {
+			t.Fatalf("V1GetAllAnnouncements: %v", err)
+		}
+		if len(announcements) != 2 || len(announcements["alpha"]) != 2 || len(announcements["beta"]) != 1 {
+			t.Fatalf("unexpected announcements: %v", announcements)
+		}
+		if name := announcements["alpha"][1].Meta.Name; name != "db" {
+			t.Errorf("second announcement of alpha is %q, want db", name)
+		}
+		if project := announcements["beta"][0].Meta.Project; project != "beta" {
+			t.Errorf("announcement of beta belongs to %q", project)
+		}
+	})
+
+	t.Run("no announcements", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status": "success", "data": null}`))
+		}))
+		defer server.Close()
+
+		announcements, err := NewAPIClient(&server.URL, 5*time.Second).V1GetAllAnnouncements(context.Background())
+		if err != nil {
+			t.Fatalf("V1GetAllAnnouncements: %v", err)
+		}
+		if announcements == nil || len(announcements) != 0 {
+			t.Fatalf("expected an empty non-nil map, got %#v", announcements)
+		}
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"status": "error", "message": "etcd is unavailable"}`))
+		}))
+		defer server.Close()
+
+		_, err := NewAPIClient(&server.URL, 5*time.Second).V1GetAllAnnouncements(context.Background())
+		if !errors.Is(err, ErrServerError) {
+			t.Fatalf("expected ErrServerError, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "failed to get all announcements") || !strings.Contains(err.Error(), "etcd is unavailable") {
+			t.Errorf("error does not describe the failure: %v", err)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1ListConflicts returns the announcements of different projects whose prefixes equal or overlap. If project is not
+// empty, only the conflicts of the project are returned, with its announcement first.
+func (c *APIClient) V1ListConflicts(ctx context.Context, project string) ([]model.Conflict, error) {
+	query := url.Values{}
+	if project != "" {
+		query.Set("project", project)
+	}
+	baseURL := fmt.Sprintf("%s/v1/conflicts?%s", c.endpoint(), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list conflicts", resp)
+	}
+
+	var conflicts []model.Conflict
+	if err := decodeResponse(resp, &conflicts); err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
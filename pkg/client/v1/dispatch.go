@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// eventDispatcher passes watch events from the connection reader to the consumer through a bounded buffer.
+type eventDispatcher struct {
+	events   chan model.Event
+	strategy BackpressureStrategy
+	dropped  *atomic.Uint64
+
+	mu       sync.Mutex // mu makes dropping the oldest event and pushing the new one atomic.
+	overflow atomic.Bool
+	wg       sync.WaitGroup
+}
+
+// newEventDispatcher creates a dispatcher and starts the goroutine delivering events to onEvent.
+func newEventDispatcher(capacity int, strategy BackpressureStrategy, onEvent func(model.Event), dropped *atomic.Uint64) *eventDispatcher {
+	d := &eventDispatcher{
+		events:   make(chan model.Event, capacity),
+		strategy: strategy,
+		dropped:  dropped,
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for event := range d.events {
+			onEvent(event)
+		}
+	}()
+
+	return d
+}
+
+// push adds the event to the buffer according to the backpressure strategy. It returns ErrEventBufferFull
+// when the buffer is full and the strategy is ErrorOnFull, and the error of the context when it is done while
+// BlockSender waits for space in the buffer.
+func (d *eventDispatcher) push(ctx context.Context, event model.Event) error {
+	switch d.strategy {
+	case DropOldest:
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		for {
+			select {
+			case d.events <- event:
+				return nil
+			default:
+			}
+			// Make room by discarding the oldest event; the consumer may have taken it in the meantime
+			select {
+			case <-d.events:
+				d.dropped.Add(1)
+			default:
+			}
+		}
+	case ErrorOnFull:
+		select {
+		case d.events <- event:
+			return nil
+		default:
+			d.overflow.Store(true)
+			return ErrEventBufferFull
+		}
+	default:
+		select {
+		case d.events <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// overflowed reports whether an event was rejected by the ErrorOnFull strategy.
+func (d *eventDispatcher) overflowed() bool {
+	return d.overflow.Load()
+}
+
+// close stops accepting events and waits until the consumer processes the buffered ones.
+func (d *eventDispatcher) close() {
+	close(d.events)
+	d.wg.Wait()
+}
@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1DrainNextHop drains the next hop of the announcement for maintenance: the updater withdraws the paths via the
+// next hop while its health checks continue. It returns the announcement with the next hop listed as draining in the
+// status.
+func (c *APIClient) V1DrainNextHop(ctx context.Context, project, name, nextHop string) (*model.Announcement, error) {
+	return c.setNextHopDraining(ctx, project, name, nextHop, "drain")
+}
+
+// V1UndrainNextHop restores a drained next hop of the announcement, so that the paths via the next hop are programmed
+// again once it is healthy.
+func (c *APIClient) V1UndrainNextHop(ctx context.Context, project, name, nextHop string) (*model.Announcement, error) {
+	return c.setNextHopDraining(ctx, project, name, nextHop, "undrain")
+}
+
+// setNextHopDraining posts the drain or undrain action of the next hop and returns the updated announcement.
+func (c *APIClient) setNextHopDraining(ctx context.Context, project, name, nextHop, action string) (*model.Announcement, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/next-hops/%s/%s", c.endpoint(), url.PathEscape(project), url.PathEscape(name), url.PathEscape(nextHop), action)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to "+action+" next hop", resp)
+	}
+
+	var event model.Event
+	if err := decodeResponse(resp, &event); err != nil {
+		return nil, err
+	}
+
+	return &event.Announcement, nil
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// ErrServerError is returned when the API server responds with a 5xx status code.
+var ErrServerError = errors.New("server error")
+
+// ErrNotFound is returned when the requested resource does not exist on the API server.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when the request conflicts with the current state of the resource on the API server,
+// e.g., the announcement already exists or its resource version is stale.
+var ErrConflict = errors.New("conflict")
+
+// ErrValidation is returned when the API server rejects the request as invalid with a 400 or 422 status code. The
+// error is a *ValidationError when the server reported the invalid fields of an announcement.
+var ErrValidation = errors.New("invalid request")
+
+// ErrUnauthorized is returned when the API server requires authentication and the request carries no valid token.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrForbidden is returned when the authenticated client lacks the role required by the request.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrTooManyRequests is returned when the API server rejects the request with 429 Too Many Requests because of its
+// rate or inflight limits.
+var ErrTooManyRequests = errors.New("too many requests")
+
+// ErrWatchTimeout is returned by a watch that received nothing, neither events nor bookmarks nor answers to its pings,
+// for three ping intervals, e.g., after a NAT dropped the connection silently.
+var ErrWatchTimeout = errors.New("watch connection timed out")
+
+// ErrEventBufferFull is returned by a watch with the ErrorOnFull backpressure strategy when the consumer
+// does not keep up with the incoming events.
+var ErrEventBufferFull = errors.New("watch event buffer is full")
+
+// APIError is returned when the API server responds with an unexpected status code. It wraps the sentinel error
+// matching the status code, e.g., ErrNotFound, so that callers can use errors.Is for the kind of the failure and
+// errors.As for the details.
+type APIError struct {
+	Op         string // Op describes the failed operation, e.g., "failed to get announcement".
+	StatusCode int    // StatusCode is the HTTP status code of the response.
+	Message    string // Message is the error message of the API server; empty if the response carried none.
+}
+
+func (e *APIError) Error() string {
+	message := e.Message
+	if message == "" {
+		if sentinel := e.Unwrap(); sentinel != nil {
+			message = sentinel.Error()
+		} else {
+			message = strings.ToLower(http.StatusText(e.StatusCode))
+		}
+	}
+	return fmt.Sprintf("%s: %s (status code %d)", e.Op, message, e.StatusCode)
+}
+
+// Unwrap returns the sentinel error matching the status code, or nil if there is none.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrValidation
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed:
+		return ErrConflict
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServerError
+	default:
+		return nil
+	}
+}
+
+// ValidationError is returned when the API server rejects an announcement because of invalid fields. It wraps the
+// *APIError of the response, so it also matches ErrValidation.
+type ValidationError struct {
+	Message string                  // Message is the error message of the API server.
+	Fields  []model.ValidationError // Fields lists the invalid fields and the reasons they were rejected.
+
+	err *APIError
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		fields = append(fields, field.Field+": "+field.Message)
+	}
+	return "invalid announcement: " + strings.Join(fields, "; ")
+}
+
+// Unwrap returns the *APIError of the response.
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// responseError builds the error for an unexpected response from the message of the API server. It returns a
+// *ValidationError if the server reported invalid fields, and an *APIError otherwise.
+func responseError(op string, resp *http.Response) error {
+	var envelope struct {
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	// The body is optional, e.g., proxies respond with plain text errors
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	_ = json.Unmarshal(body, &envelope)
+
+	err := &APIError{Op: op, StatusCode: resp.StatusCode, Message: envelope.Message}
+	if resp.StatusCode == http.StatusBadRequest {
+		var fields []model.ValidationError
+		if json.Unmarshal(envelope.Data, &fields) == nil && len(fields) > 0 {
+			return &ValidationError{Message: envelope.Message, Fields: fields, err: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// WithETagCache keeps the bodies of up to maxEntries GET responses carrying an ETag and revalidates them with
+// If-None-Match, so that an unchanged list or announcement is answered with an empty 304 Not Modified instead of
+// being transferred again. The least recently used responses are evicted first. The responses are compressed with
+// gzip independently of the cache, since the transport requests and decompresses them transparently.
+func WithETagCache(maxEntries int) ClientOption {
+	return func(c *APIClient) {
+		cache := &etagCacheTransport{maxEntries: maxEntries, entries: make(map[string]*list.Element), order: list.New()}
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			cache.next = next
+			return cache
+		})
+	}
+}
+
+// etagCacheTransport is an http.RoundTripper that revalidates the cached GET responses.
+type etagCacheTransport struct {
+	next       http.RoundTripper
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // entries maps the request URLs to the elements of order.
+	order   *list.List               // order holds the cached responses from the most recently used.
+}
+
+// cachedResponse is a GET response kept by the ETag cache.
+type cachedResponse struct {
+	url    string
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+// RoundTrip sends the GET requests with the ETag of the cached response and serves the body from the cache when the
+// server answers 304 Not Modified.
+func (t *etagCacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("If-None-Match") != "" || t.maxEntries <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached := t.get(key)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		_ = resp.Body.Close()
+		header := cached.header.Clone()
+		header.Set("Content-Length", strconv.Itoa(len(cached.body)))
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         resp.Proto,
+			ProtoMajor:    resp.ProtoMajor,
+			ProtoMinor:    resp.ProtoMinor,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(cached.body)),
+			ContentLength: int64(len(cached.body)),
+			Request:       resp.Request,
+		}, nil
+	case resp.StatusCode == http.StatusOK && resp.Header.Get("ETag") != "":
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.put(&cachedResponse{url: key, etag: resp.Header.Get("ETag"), header: resp.Header.Clone(), body: body})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	default:
+		return resp, nil
+	}
+}
+
+// get returns the cached response of the URL, or nil, and marks it as the most recently used.
+func (t *etagCacheTransport) get(key string) *cachedResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	element, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+	t.order.MoveToFront(element)
+	return element.Value.(*cachedResponse)
+}
+
+// put caches the response, evicting the least recently used ones above the limit.
+func (t *etagCacheTransport) put(cached *cachedResponse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if element, ok := t.entries[cached.url]; ok {
+		element.Value = cached
+		t.order.MoveToFront(element)
+		return
+	}
+	t.entries[cached.url] = t.order.PushFront(cached)
+	for t.order.Len() > t.maxEntries {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.entries, oldest.Value.(*cachedResponse).url)
+	}
+}
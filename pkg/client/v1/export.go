@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1Export exports the resources of the project as a bundle, or of all projects together with the peers if the
+// project is empty. The bundle can be imported by V1Import, e.g., to restore the control plane after the loss of the
+// datastore.
+func (c *APIClient) V1Export(ctx context.Context, project string) (*model.Bundle, error) {
+	baseURL := c.endpoint() + "/v1/export"
+	if project != "" {
+		baseURL += "?" + url.Values{"project": {project}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to export", resp)
+	}
+
+	// The bundle is not wrapped into the response envelope
+	var bundle model.Bundle
+	if err := json.NewDecoder(resp.Body).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle: %w", err)
+	}
+
+	return &bundle, nil
+}
+
+// V1Import imports the resources of the bundle. The strategy defines the treatment of the resources that already
+// exist; with model.ImportFail, the import is rejected with ErrConflict if any of them exists. The returned report
+// lists the resources by the outcome.
+func (c *APIClient) V1Import(ctx context.Context, bundle *model.Bundle, strategy model.ImportStrategy, opts ...WriteOption) (*model.ImportReport, error) {
+	query := url.Values{"strategy": {string(strategy)}}
+	if newWriteOptions(opts).DryRun {
+		query.Set("dryRun", "true")
+	}
+	baseURL := fmt.Sprintf("%s/v1/import?%s", c.endpoint(), query.Encode())
+
+	body, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to import", resp)
+	}
+
+	var report model.ImportReport
+	if err := decodeResponse(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
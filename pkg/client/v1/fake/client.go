@@ -0,0 +1,403 @@
+// Package fake provides an in-memory implementation of v1.AnnouncementsInterface for the unit tests of controllers.
+// It keeps resource versions and generations like the API server, records the calls as actions, lets a reactor fail them, and
+// streams the changes and injected events to its watches.
+package fake
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+// Action is a call of the fake client.
+type Action struct {
+	Verb    string // Verb is the kind of the call: get, list, create, update, apply, delete, get-status, update-status or watch.
+	Project string // Project is the project of the call; empty for the calls on all projects.
+	Name    string // Name is the name of the announcement of the call; empty for lists and watches.
+}
+
+// Reactor is called with every action before it is performed. A non-nil error fails the call without effect.
+type Reactor func(action Action) error
+
+// Client is an in-memory announcement client. It is safe for concurrent use.
+type Client struct {
+	mu            sync.Mutex
+	revision      int64
+	announcements map[string]model.Announcement // announcements maps the "project/name" IDs to the stored announcements.
+	history       []model.Event                 // history holds every change, so that watches resume from a revision.
+	watches       map[*watch]struct{}
+	actions       []Action
+	reactor       Reactor
+}
+
+var _ v1.AnnouncementsInterface = (*Client)(nil)
+
+// NewClient creates a client storing the announcements, which get increasing resource versions in the given order.
+func NewClient(announcements ...model.Announcement) *Client {
+	c := &Client{
+		announcements: make(map[string]model.Announcement),
+		watches:       make(map[*watch]struct{}),
+	}
+	for _, announcement := range announcements {
+		c.revision++
+		announcement.Meta.ResourceVersion = strconv.FormatInt(c.revision, 10)
+		c.announcements[key(announcement.Meta.Project, announcement.Meta.Name)] = announcement
+	}
+	return c
+}
+
+// SetReactor sets the reactor called before every action; nil removes it.
+func (c *Client) SetReactor(reactor Reactor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reactor = reactor
+}
+
+// Actions returns the actions performed so far, including the failed ones.
+func (c *Client) Actions() []Action {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.actions)
+}
+
+// ClearActions forgets the actions performed so far.
+func (c *Client) ClearActions() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = nil
+}
+
+// Revision returns the revision of the last change.
+func (c *Client) Revision() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.revision
+}
+
+// V1GetAnnouncement returns the stored announcement.
+func (c *Client) V1GetAnnouncement(ctx context.Context, project, name string) (*model.Announcement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "get", Project: project, Name: name}); err != nil {
+		return nil, err
+	}
+	announcement, ok := c.announcements[key(project, name)]
+	if !ok {
+		return nil, notFound("failed to get announcement")
+	}
+	return &announcement, nil
+}
+
+// V1ListAllAnnouncements returns the announcements of all projects except the withdrawn ones.
+func (c *Client) V1ListAllAnnouncements(ctx context.Context) ([]model.Announcement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "list"}); err != nil {
+		return nil, err
+	}
+	return c.list(false, func(*model.Announcement) bool { return true }), nil
+}
+
+// V1ListAllProjectAnnouncements returns the announcements of the project except the withdrawn ones.
+func (c *Client) V1ListAllProjectAnnouncements(ctx context.Context, project string) ([]model.Announcement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "list", Project: project}); err != nil {
+		return nil, err
+	}
+	return c.list(false, func(announcement *model.Announcement) bool { return announcement.Meta.Project == project }), nil
+}
+
+// V1ListAnnouncementsPage returns a page of the project announcements selected by the options. The continue token
+// is the name of the last announcement of the previous page.
+func (c *Client) V1ListAnnouncementsPage(ctx context.Context, project string, opts v1.ListOptions) (*model.AnnouncementList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "list", Project: project}); err != nil {
+		return nil, err
+	}
+	selector, err := parseSelector(opts.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	items := c.list(opts.IncludeWithdrawn, func(announcement *model.Announcement) bool {
+		return announcement.Meta.Project == project &&
+			strings.HasPrefix(announcement.Meta.Name, opts.NamePrefix) &&
+			announcement.Meta.Name > opts.Continue &&
+			selector.matches(announcement.Meta.Labels)
+	})
+	page := &model.AnnouncementList{Items: items}
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		page.Items = items[:opts.Limit]
+		page.Continue = page.Items[opts.Limit-1].Meta.Name
+	}
+	return page, nil
+}
+
+// V1ListAnnouncementsBySelector returns the announcements of all projects whose labels meet the selector. Equality
+// and existence requirements are supported, e.g., "env=staging,!pinned".
+func (c *Client) V1ListAnnouncementsBySelector(ctx context.Context, selector string) ([]model.Announcement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "list"}); err != nil {
+		return nil, err
+	}
+	parsed, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return c.list(false, func(announcement *model.Announcement) bool { return parsed.matches(announcement.Meta.Labels) }), nil
+}
+
+// V1CreateAnnouncement stores a new announcement.
+func (c *Client) V1CreateAnnouncement(ctx context.Context, announcement *model.Announcement, opts ...v1.WriteOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "create", Project: announcement.Meta.Project, Name: announcement.Meta.Name}); err != nil {
+		return err
+	}
+	id := key(announcement.Meta.Project, announcement.Meta.Name)
+	if _, ok := c.announcements[id]; ok {
+		return conflict("failed to create announcement", "announcement already exists")
+	}
+	if writeOptions(opts).DryRun {
+		return nil
+	}
+
+	created := *announcement
+	stampGeneration(nil, &created)
+	c.store(model.EventAdded, nil, created)
+	return nil
+}
+
+// V1UpdateAnnouncement replaces the configuration of a stored announcement and keeps its status. The update is
+// conditional if the announcement carries a resource version or the IfMatch option is given.
+func (c *Client) V1UpdateAnnouncement(ctx context.Context, announcement *model.Announcement, opts ...v1.WriteOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "update", Project: announcement.Meta.Project, Name: announcement.Meta.Name}); err != nil {
+		return err
+	}
+	id := key(announcement.Meta.Project, announcement.Meta.Name)
+	previous, ok := c.announcements[id]
+	if !ok {
+		return notFound("failed to update announcement")
+	}
+	options := writeOptions(opts)
+	version := cmp.Or(options.IfMatch, announcement.Meta.ResourceVersion)
+	if version != "" && version != previous.Meta.ResourceVersion {
+		return conflict("failed to update announcement", "resource version "+version+" is stale")
+	}
+	if options.DryRun {
+		return nil
+	}
+
+	updated := *announcement
+	updated.Status = previous.Status
+	stampGeneration(&previous, &updated)
+	if updated.Status.Status == model.StatusCancelled {
+		updated.Status.Status = model.StatusPending
+	}
+	c.store(model.EventUpdated, &previous, updated)
+	return nil
+}
+
+// V1ApplyAnnouncement creates the announcement or replaces the configuration of the stored one, keeping its status.
+// Unlike the API server, the fake does not track the fields owned by every manager.
+func (c *Client) V1ApplyAnnouncement(ctx context.Context, manager string, announcement *model.Announcement) (*model.Announcement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "apply", Project: announcement.Meta.Project, Name: announcement.Meta.Name}); err != nil {
+		return nil, err
+	}
+	applied := *announcement
+	if previous, ok := c.announcements[key(announcement.Meta.Project, announcement.Meta.Name)]; ok {
+		applied.Status = previous.Status
+		stampGeneration(&previous, &applied)
+		applied = c.store(model.EventUpdated, &previous, applied)
+	} else {
+		stampGeneration(nil, &applied)
+		applied = c.store(model.EventAdded, nil, applied)
+	}
+	return &applied, nil
+}
+
+// V1DeleteAnnouncement deletes the stored announcement.
+func (c *Client) V1DeleteAnnouncement(ctx context.Context, project, name string, opts ...v1.WriteOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "delete", Project: project, Name: name}); err != nil {
+		return err
+	}
+	previous, ok := c.announcements[key(project, name)]
+	if !ok {
+		return notFound("failed to delete announcement")
+	}
+	if writeOptions(opts).DryRun {
+		return nil
+	}
+
+	c.store(model.EventDeleted, &previous, previous)
+	return nil
+}
+
+// V1GetAnnouncementStatus returns the status of the stored announcement.
+func (c *Client) V1GetAnnouncementStatus(ctx context.Context, project, name string) (*model.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "get-status", Project: project, Name: name}); err != nil {
+		return nil, err
+	}
+	announcement, ok := c.announcements[key(project, name)]
+	if !ok {
+		return nil, notFound("failed to get announcement status")
+	}
+	return &announcement.Status, nil
+}
+
+// V1UpdateAnnouncementStatus merges the patch into the status of the stored announcement like the API server: the
+// operational state, the details and the router states are replaced when set, the conditions by type.
+func (c *Client) V1UpdateAnnouncementStatus(ctx context.Context, project, name string, patch *model.Status) (*model.Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.invoke(Action{Verb: "update-status", Project: project, Name: name}); err != nil {
+		return nil, err
+	}
+	previous, ok := c.announcements[key(project, name)]
+	if !ok {
+		return nil, notFound("failed to update announcement status")
+	}
+	switch previous.Status.Status {
+	case model.StatusCancelled, model.StatusWithdrawn, model.StatusExpired:
+		return nil, conflict("failed to update announcement status", "announcement is "+previous.Status.Status)
+	}
+
+	updated := previous
+	status := &updated.Status
+	status.Conditions = slices.Clone(status.Conditions)
+	now := time.Now().UTC().Format(time.RFC3339)
+	if patch.Status != "" {
+		status.Status = patch.Status
+	}
+	if patch.Details != nil {
+		status.Details = patch.Details
+	}
+	if patch.Routers != nil {
+		status.Routers = patch.Routers
+	}
+	for _, condition := range patch.Conditions {
+		if current := status.Condition(condition.Type); condition.LastTransitionTime == "" && (current == nil || current.Status != condition.Status) {
+			condition.LastTransitionTime = now
+		}
+		status.SetCondition(condition)
+	}
+	status.Timestamp = now
+	if generation := status.ProgrammedGeneration(); generation != 0 && generation == updated.Meta.Generation && generation != status.ObservedGeneration {
+		status.ObservedGeneration = generation
+		status.ProgrammedAt = now
+	}
+	updated = c.store(model.EventUpdated, &previous, updated)
+	return &updated.Status, nil
+}
+
+// invoke records the action and calls the reactor. The lock must be held.
+func (c *Client) invoke(action Action) error {
+	c.actions = append(c.actions, action)
+	if c.reactor != nil {
+		return c.reactor(action)
+	}
+	return nil
+}
+
+// list returns the stored announcements matching the function ordered by project and name, like the API server
+// without the withdrawn ones unless they are included. The lock must be held.
+func (c *Client) list(includeWithdrawn bool, match func(*model.Announcement) bool) []model.Announcement {
+	announcements := make([]model.Announcement, 0, len(c.announcements))
+	for _, announcement := range c.announcements {
+		if (includeWithdrawn || announcement.Status.Status != model.StatusWithdrawn) && match(&announcement) {
+			announcements = append(announcements, announcement)
+		}
+	}
+	slices.SortFunc(announcements, func(a, b model.Announcement) int {
+		return cmp.Or(cmp.Compare(a.Meta.Project, b.Meta.Project), cmp.Compare(a.Meta.Name, b.Meta.Name))
+	})
+	return announcements
+}
+
+// store applies the change with the next revision, records it and passes it to the watches. It returns the stored
+// announcement. The lock must be held.
+func (c *Client) store(eventType model.EventType, previous *model.Announcement, announcement model.Announcement) model.Announcement {
+	c.revision++
+	announcement.Meta.ResourceVersion = strconv.FormatInt(c.revision, 10)
+
+	id := key(announcement.Meta.Project, announcement.Meta.Name)
+	if eventType == model.EventDeleted {
+		delete(c.announcements, id)
+	} else {
+		c.announcements[id] = announcement
+	}
+
+	event := model.Event{Type: eventType, Announcement: announcement, Revision: c.revision}
+	c.history = append(c.history, event)
+	for w := range c.watches {
+		w.push(event, previous)
+	}
+	return announcement
+}
+
+// stampGeneration sets the creation time and increments the generation of the written announcement; previous is the
+// stored announcement, nil for a creation. Unlike the API server, the fake increments the generation on every write.
+func stampGeneration(previous, announcement *model.Announcement) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	announcement.Meta.CreatedAt = now
+	announcement.Meta.Generation = 1
+	if previous != nil {
+		announcement.Meta.CreatedAt = previous.Meta.CreatedAt
+		announcement.Meta.Generation = previous.Meta.Generation + 1
+	}
+	announcement.Meta.ChangedAt = now
+}
+
+// writeOptions applies the write options.
+func writeOptions(opts []v1.WriteOption) v1.WriteOptions {
+	var options v1.WriteOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// notFound returns the error of the client for a missing announcement.
+func notFound(op string) error {
+	return &v1.APIError{Op: op, StatusCode: http.StatusNotFound, Message: "announcement not found"}
+}
+
+// conflict returns the error of the client for a write conflicting with the stored announcement.
+func conflict(op, message string) error {
+	return &v1.APIError{Op: op, StatusCode: http.StatusConflict, Message: message}
+}
+
+// key returns the "project/name" ID of an announcement.
+func key(project, name string) string {
+	return project + "/" + name
+}
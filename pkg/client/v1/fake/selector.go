@@ -0,0 +1,70 @@
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+// requirement is a single equality or existence condition of a label selector.
+type requirement struct {
+	key    string
+	value  string
+	negate bool // negate inverts the condition: "key!=value" or "!key".
+	exists bool // exists checks only that the label is set: "key" or "!key".
+}
+
+// selector is a parsed label selector. All requirements must be met; an empty selector selects everything.
+type selector []requirement
+
+// parseSelector parses the equality and existence requirements of a label selector: "key=value", "key==value",
+// "key!=value", "key" and "!key". Set-based requirements are rejected like an invalid selector.
+func parseSelector(value string) (selector, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var parsed selector
+	for _, term := range strings.Split(value, ",") {
+		term = strings.TrimSpace(term)
+		var r requirement
+		switch {
+		case strings.HasPrefix(term, "!"):
+			r = requirement{key: strings.TrimSpace(term[1:]), negate: true, exists: true}
+		case strings.Contains(term, "!="):
+			key, val, _ := strings.Cut(term, "!=")
+			r = requirement{key: strings.TrimSpace(key), value: strings.TrimSpace(val), negate: true}
+		case strings.Contains(term, "="):
+			key, val, _ := strings.Cut(term, "=")
+			r = requirement{key: strings.TrimSpace(key), value: strings.TrimSpace(strings.TrimPrefix(val, "="))}
+		default:
+			r = requirement{key: term, exists: true}
+		}
+		if r.key == "" || strings.ContainsAny(r.key, " ()") || strings.ContainsAny(r.value, " ()") {
+			return nil, &v1.APIError{
+				Op:         "failed to list announcements",
+				StatusCode: http.StatusBadRequest,
+				Message:    fmt.Sprintf("invalid label selector %q: the fake client supports equality and existence requirements only", value),
+			}
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// matches reports whether the labels meet all requirements of the selector.
+func (s selector) matches(labels map[string]string) bool {
+	for _, r := range s {
+		value, ok := labels[r.key]
+		met := ok
+		if !r.exists {
+			met = ok && value == r.value
+		}
+		if met == r.negate {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+// ErrWatchDropped is returned by the watches closed with DropWatches.
+var ErrWatchDropped = errors.New("watch connection dropped")
+
+// watch is an active watch of the fake client. The events are queued, so that the changes never block on a slow
+// consumer.
+type watch struct {
+	options  v1.WatchOptions
+	selector selector
+
+	mu      sync.Mutex
+	queue   []model.Event
+	dropped bool
+	notify  chan struct{} // notify is signaled when the queue or the dropped flag changes.
+}
+
+// V1WatchAnnouncements passes the changes selected by the options to onEvent until the context is canceled or
+// DropWatches is called. With a revision, the recorded changes after it are passed first. Resync signals and
+// bookmarks injected with InjectEvent are passed to the resync and bookmark callbacks instead of onEvent, like the
+// client does.
+func (c *Client) V1WatchAnnouncements(ctx context.Context, onEvent func(event model.Event), opts ...v1.WatchOption) error {
+	var options v1.WatchOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	c.mu.Lock()
+	if err := c.invoke(Action{Verb: "watch", Project: options.Project}); err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	parsed, err := parseSelector(options.LabelSelector)
+	if err != nil {
+		c.mu.Unlock()
+		return err
+	}
+	w := &watch{options: options, selector: parsed, notify: make(chan struct{}, 1)}
+	if options.Revision > 0 {
+		for _, event := range c.history {
+			if event.Revision > options.Revision {
+				w.push(event, nil)
+			}
+		}
+	}
+	c.watches[w] = struct{}{}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.watches, w)
+		c.mu.Unlock()
+	}()
+
+	for {
+		w.mu.Lock()
+		queue, dropped := w.queue, w.dropped
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, event := range queue {
+			switch event.Type {
+			case model.EventResyncRequired:
+				if options.OnResync != nil {
+					options.OnResync(event.Revision)
+				}
+			case model.EventBookmark:
+				if options.OnBookmark != nil {
+					options.OnBookmark(event.Revision)
+				}
+			default:
+				onEvent(event)
+			}
+		}
+		if dropped {
+			return ErrWatchDropped
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.notify:
+		}
+	}
+}
+
+// InjectEvent passes the event to the active watches without changing the stored announcements, e.g., to simulate
+// a change by another client, a model.EventResyncRequired signal after a gap in the watch history or a
+// model.EventBookmark.
+func (c *Client) InjectEvent(event model.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for w := range c.watches {
+		w.push(event, nil)
+	}
+}
+
+// DropWatches makes the active watches return ErrWatchDropped once they have passed the queued events, to simulate
+// a lost connection.
+func (c *Client) DropWatches() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for w := range c.watches {
+		w.mu.Lock()
+		w.dropped = true
+		w.mu.Unlock()
+		w.signal()
+	}
+}
+
+// Watches returns the number of active watches, so that a test can wait for a controller to start watching.
+func (c *Client) Watches() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.watches)
+}
+
+// push queues the event if the watch selects it. Like the API server, an update moving the announcement out of the
+// label selection is passed as a deleted event, and one moving it in as an added event; the previous state of the
+// announcement is nil for the events replayed from the history and injected events.
+func (w *watch) push(event model.Event, previous *model.Announcement) {
+	announcement := &event.Announcement
+	if event.Type != model.EventResyncRequired && event.Type != model.EventBookmark {
+		if w.options.Project != "" && announcement.Meta.Project != w.options.Project ||
+			!strings.HasPrefix(announcement.Meta.Name, w.options.NamePrefix) {
+			return
+		}
+
+		matches := w.selector.matches(announcement.Meta.Labels)
+		if event.Type == model.EventUpdated && previous != nil {
+			switch matched := w.selector.matches(previous.Meta.Labels); {
+			case matched && !matches:
+				event.Type = model.EventDeleted
+			case !matched && matches:
+				event.Type = model.EventAdded
+			case !matched:
+				return
+			}
+		} else if !matches {
+			return
+		}
+
+		if len(w.options.EventTypes) > 0 && !slices.Contains(w.options.EventTypes, event.Type) {
+			return
+		}
+	}
+
+	w.mu.Lock()
+	w.queue = append(w.queue, event)
+	w.mu.Unlock()
+	w.signal()
+}
+
+// signal wakes up the watch loop without blocking.
+func (w *watch) signal() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
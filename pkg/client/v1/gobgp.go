@@ -0,0 +1,126 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1GetBGPSessionSummary returns aggregate statistics of the BGP sessions of the GoBGP instance connected to the API server.
+func (c *APIClient) V1GetBGPSessionSummary(ctx context.Context) (*model.BGPSessionSummary, error) {
+	baseURL := c.endpoint() + "/v1/gobgp/summary"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get BGP session summary", resp)
+	}
+
+	var summary model.BGPSessionSummary
+	if err := decodeResponse(resp, &summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+// V1GetGoBGPGlobalConfig returns the global configuration (AS number, router ID and listen port) of the GoBGP instance.
+func (c *APIClient) V1GetGoBGPGlobalConfig(ctx context.Context) (*model.GoBGPGlobalConfig, error) {
+	baseURL := c.endpoint() + "/v1/gobgp/global"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get GoBGP global configuration", resp)
+	}
+
+	var config model.GoBGPGlobalConfig
+	if err := decodeResponse(resp, &config); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// V1ConfigureGoBGP starts the BGP server of the GoBGP instance with the specified global configuration.
+func (c *APIClient) V1ConfigureGoBGP(ctx context.Context, config model.GoBGPGlobalConfig) error {
+	baseURL := c.endpoint() + "/v1/gobgp/global"
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to configure GoBGP", resp)
+	}
+
+	return nil
+}
+
+// V1ListAnnouncedPaths returns the paths originated by the GoBGP instance connected to the API server, each with its
+// attributes and the announcements requiring it. A non-empty prefix, given as an IP address or in CIDR notation,
+// selects the paths within it.
+func (c *APIClient) V1ListAnnouncedPaths(ctx context.Context, prefix string) ([]model.RIBPath, error) {
+	baseURL := c.endpoint() + "/v1/rib/announced"
+	if prefix != "" {
+		baseURL += "?" + url.Values{"prefix": {prefix}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list announced paths", resp)
+	}
+
+	var paths []model.RIBPath
+	if err := decodeResponse(resp, &paths); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
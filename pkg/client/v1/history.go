@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1GetAnnouncementHistory returns the revisions of the announcement kept by the API server, the newest first. The
+// history of a deleted announcement is kept, so that it can be restored with V1RollbackAnnouncement.
+func (c *APIClient) V1GetAnnouncementHistory(ctx context.Context, project, name string) ([]model.AnnouncementRevision, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/history", c.endpoint(), url.PathEscape(project), url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get announcement history", resp)
+	}
+
+	var revisions []model.AnnouncementRevision
+	if err := decodeResponse(resp, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// V1RollbackAnnouncement restores the configuration of the announcement from a revision of its history and returns
+// the stored announcement. The status is kept, a deleted announcement is created again. The rollback is conditional
+// on the current resource version with the IfMatch option.
+func (c *APIClient) V1RollbackAnnouncement(ctx context.Context, project, name, revision string, opts ...WriteOption) (*model.Announcement, error) {
+	options := newWriteOptions(opts)
+	baseURL := options.url(fmt.Sprintf("%s/v1/announcements/%s/%s/rollback", c.endpoint(), url.PathEscape(project), url.PathEscape(name)))
+
+	data, err := json.Marshal(model.RollbackRequest{Revision: revision})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if options.IfMatch != "" {
+		req.Header.Set("If-Match", `"`+options.IfMatch+`"`)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, responseError("failed to roll back announcement", resp)
+	}
+
+	var event model.Event
+	if err := decodeResponse(resp, &event); err != nil {
+		return nil, err
+	}
+
+	return &event.Announcement, nil
+}
+
+// V1ListAnnouncementEvents returns the events of the announcement recorded by the API server, the oldest first, e.g.,
+// its updates, the failed health checks of its next hops and the programming errors. The events of a deleted
+// announcement are kept.
+func (c *APIClient) V1ListAnnouncementEvents(ctx context.Context, project, name string) ([]model.AnnouncementEvent, error) {
+	baseURL := fmt.Sprintf("%s/v1/announcements/%s/%s/events", c.endpoint(), url.PathEscape(project), url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list announcement events", resp)
+	}
+
+	var events []model.AnnouncementEvent
+	if err := decodeResponse(resp, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
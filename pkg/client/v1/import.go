@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// ImportOption configures optional behaviour of V1ImportAnnouncements.
+type ImportOption func(*importOptions)
+
+// importOptions holds the parameters of an import.
+type importOptions struct {
+	deduplicate bool
+}
+
+// WithDeduplication skips announcements whose project, labels, prefix, next hops and communities match an announcement
+// that is already stored or was imported earlier in the same batch. It prevents unnecessary GoBGP churn on incremental imports.
+func WithDeduplication() ImportOption {
+	return func(o *importOptions) {
+		o.deduplicate = true
+	}
+}
+
+// ImportResult summarizes the outcome of V1ImportAnnouncements.
+type ImportResult struct {
+	Created      int              // Created is the number of announcements successfully created.
+	Deduplicated int              // Deduplicated is the number of announcements skipped as duplicates.
+	Failed       map[string]error // Failed maps the "project/name" IDs of the announcements that could not be created to the cause.
+}
+
+// V1ImportAnnouncements creates the announcements one by one. A failure to create an announcement does not stop the
+// import, it is recorded in ImportResult.Failed instead. An error is returned only if the import could not be started.
+func (c *APIClient) V1ImportAnnouncements(ctx context.Context, announcements []*model.Announcement, opts ...ImportOption) (*ImportResult, error) {
+	var options importOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	seen := make(map[string]struct{})
+	if options.deduplicate {
+		existing, err := c.V1ListAllAnnouncements(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list existing announcements: %w", err)
+		}
+		for i := range existing {
+			seen[announcementHash(&existing[i])] = struct{}{}
+		}
+	}
+
+	result := &ImportResult{Failed: make(map[string]error)}
+	for _, announcement := range announcements {
+		var hash string
+		if options.deduplicate {
+			hash = announcementHash(announcement)
+			if _, ok := seen[hash]; ok {
+				result.Deduplicated++
+				continue
+			}
+		}
+
+		if err := c.V1CreateAnnouncement(ctx, announcement); err != nil {
+			result.Failed[announcement.Meta.Project+"/"+announcement.Meta.Name] = err
+			continue
+		}
+
+		result.Created++
+		if options.deduplicate {
+			seen[hash] = struct{}{}
+		}
+	}
+
+	return result, nil
+}
+
+// announcementHash returns a stable hash of the project, the labels and the routing-relevant fields of the
+// announcement: the announced prefix, the next hops and the communities, including those of each next hop. The order
+// of labels, next hops and communities does not affect the hash.
+func announcementHash(announcement *model.Announcement) string {
+	prefix := announcement.Addresses.AnnouncedIP
+	if prefix == "" {
+		prefix = announcement.Addresses.SourceSubnets.IP + "/" + strconv.Itoa(int(announcement.Addresses.SourceSubnets.Mask))
+	}
+	if announcement.Addresses.AnnouncedIPv6 != "" {
+		prefix += "," + announcement.Addresses.AnnouncedIPv6
+	}
+
+	nextHops := make([]string, 0, len(announcement.NextHops)+len(announcement.WeightedNextHops))
+	for _, nextHop := range announcement.NextHops {
+		nextHops = append(nextHops, nextHop.IP+"/"+strconv.Itoa(int(nextHop.Mask))+nextHopCommunities(announcement, nextHop.IP))
+	}
+	for _, nextHop := range announcement.WeightedNextHops {
+		nextHops = append(nextHops, nextHop.Address+"*"+strconv.FormatUint(uint64(nextHop.Weight), 10)+nextHopCommunities(announcement, nextHop.Address))
+	}
+	slices.Sort(nextHops)
+
+	communities := slices.Clone(announcement.Communities)
+	slices.Sort(communities)
+
+	labels := make([]string, 0, len(announcement.Meta.Labels))
+	for key, value := range announcement.Meta.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	slices.Sort(labels)
+
+	// Marshalling a struct keeps the field order fixed, so the encoding is stable
+	data, _ := json.Marshal(struct {
+		Project     string   `json:"project"`
+		Labels      []string `json:"labels"`
+		Prefix      string   `json:"prefix"`
+		NextHops    []string `json:"nextHops"`
+		Communities []string `json:"communities"`
+	}{announcement.Meta.Project, labels, prefix, nextHops, communities})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// nextHopCommunities returns the sorted communities of the next hop in a form suitable for announcementHash.
+func nextHopCommunities(announcement *model.Announcement, nextHop string) string {
+	communities := slices.Clone(announcement.NextHopCommunities[nextHop])
+	if len(communities) == 0 {
+		return ""
+	}
+	slices.Sort(communities)
+	return "[" + strings.Join(communities, ",") + "]"
+}
@@ -0,0 +1,43 @@
+package informers
+
+import "github.com/nikitamishagin/corebgp/internal/model"
+
+// ResourceEventHandler is notified of the changes of the cached announcements. The handlers are called one at a time
+// in the order of the changes and must not modify the announcements they receive, which are shared with the cache.
+type ResourceEventHandler interface {
+	// OnAdd is called when an announcement enters the cache, also for every cached announcement when the handler
+	// is added to a synced informer.
+	OnAdd(announcement *model.Announcement)
+	// OnUpdate is called when a cached announcement changes, and with equal announcements on every periodic resync.
+	OnUpdate(oldAnnouncement, newAnnouncement *model.Announcement)
+	// OnDelete is called with the last cached state of an announcement when it leaves the cache.
+	OnDelete(announcement *model.Announcement)
+}
+
+// ResourceEventHandlerFuncs adapts functions to a ResourceEventHandler. Nil functions ignore their notifications.
+type ResourceEventHandlerFuncs struct {
+	AddFunc    func(announcement *model.Announcement)
+	UpdateFunc func(oldAnnouncement, newAnnouncement *model.Announcement)
+	DeleteFunc func(announcement *model.Announcement)
+}
+
+// OnAdd calls AddFunc if it is set.
+func (f ResourceEventHandlerFuncs) OnAdd(announcement *model.Announcement) {
+	if f.AddFunc != nil {
+		f.AddFunc(announcement)
+	}
+}
+
+// OnUpdate calls UpdateFunc if it is set.
+func (f ResourceEventHandlerFuncs) OnUpdate(oldAnnouncement, newAnnouncement *model.Announcement) {
+	if f.UpdateFunc != nil {
+		f.UpdateFunc(oldAnnouncement, newAnnouncement)
+	}
+}
+
+// OnDelete calls DeleteFunc if it is set.
+func (f ResourceEventHandlerFuncs) OnDelete(announcement *model.Announcement) {
+	if f.DeleteFunc != nil {
+		f.DeleteFunc(announcement)
+	}
+}
@@ -0,0 +1,340 @@
+// Package informers keeps in-memory caches of the API server announcements in sync with a watch, so that controllers
+// read the announcements from memory on every reconciliation instead of listing them from the API server, and are
+// notified of the changes through event handlers.
+package informers
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+)
+
+const (
+	initialWatchBackoff = time.Second      // initialWatchBackoff is the delay before the first reconnection attempt.
+	maxWatchBackoff     = 30 * time.Second // maxWatchBackoff caps the exponentially growing reconnection delay.
+)
+
+// Options holds the optional parameters of an informer.
+type Options struct {
+	Project       string        // Project restricts the cache to the announcements of the project; empty caches all projects.
+	LabelSelector string        // LabelSelector restricts the cache to the announcements whose labels meet it, e.g., "env=staging".
+	ResyncPeriod  time.Duration // ResyncPeriod is the interval at which OnUpdate is called for every cached announcement; zero disables it.
+	Logger        *slog.Logger  // Logger receives the watch diagnostics; nil uses the default logger.
+}
+
+// AnnouncementInformer caches the announcements selected by its options. It lists them once, then applies the watch
+// events, resuming the watch from the last seen or bookmarked revision after connection drops and listing again when
+// the server signals a gap in the watch history. The changes found by a new list are passed to the handlers like
+// watch events.
+type AnnouncementInformer struct {
+	client  v1.AnnouncementsInterface
+	options Options
+
+	mu       sync.RWMutex
+	items    map[string]*model.Announcement // items maps the "project/name" IDs to the cached announcements.
+	revision int64
+	synced   chan struct{} // synced is closed once the first list is cached.
+
+	// dispatchMu serializes the changes of the cache with the calls of the handlers, so that a handler added
+	// concurrently receives every announcement exactly once
+	dispatchMu sync.Mutex
+	handlers   []ResourceEventHandler
+}
+
+// NewAnnouncementInformer creates an informer of the announcements selected by the options. Run starts it.
+func NewAnnouncementInformer(client v1.AnnouncementsInterface, options Options) *AnnouncementInformer {
+	return &AnnouncementInformer{
+		client:  client,
+		options: options,
+		items:   make(map[string]*model.Announcement),
+		synced:  make(chan struct{}),
+	}
+}
+
+// AddEventHandler registers a handler of the cache changes. When the cache is already synced, the handler is
+// first notified of every cached announcement as added.
+func (i *AnnouncementInformer) AddEventHandler(handler ResourceEventHandler) {
+	i.dispatchMu.Lock()
+	defer i.dispatchMu.Unlock()
+
+	i.handlers = append(i.handlers, handler)
+	if i.HasSynced() {
+		for _, announcement := range i.Lister().List() {
+			handler.OnAdd(announcement)
+		}
+	}
+}
+
+// Lister returns the lister reading from the cache.
+func (i *AnnouncementInformer) Lister() AnnouncementLister {
+	return AnnouncementLister{informer: i}
+}
+
+// HasSynced reports whether the first list is cached.
+func (i *AnnouncementInformer) HasSynced() bool {
+	select {
+	case <-i.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForCacheSync blocks until the first list is cached or the context is canceled, and reports whether the
+// cache is synced.
+func (i *AnnouncementInformer) WaitForCacheSync(ctx context.Context) bool {
+	select {
+	case <-i.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Revision returns the last revision applied to the cache.
+func (i *AnnouncementInformer) Revision() int64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.revision
+}
+
+// Run lists the announcements and watches for changes until the context is canceled. It returns an error only
+// when the first list fails or the context is canceled.
+func (i *AnnouncementInformer) Run(ctx context.Context) error {
+	if err := i.relist(ctx, 0); err != nil {
+		return err
+	}
+	close(i.synced)
+
+	if i.options.ResyncPeriod > 0 {
+		go i.runResync(ctx)
+	}
+
+	backoff := initialWatchBackoff
+	for {
+		var resyncRevision int64
+		var received bool
+		opts := []v1.WatchOption{
+			v1.WithRevision(i.Revision()),
+			v1.WithResyncRevisionCallback(func(revision int64) { resyncRevision = revision }),
+			v1.WithBookmarkCallback(func(revision int64) {
+				received = true
+				i.advanceRevision(revision)
+			}),
+		}
+		if i.options.Project != "" {
+			opts = append(opts, v1.WithProject(i.options.Project))
+		}
+		if i.options.LabelSelector != "" {
+			opts = append(opts, v1.WithLabelSelector(i.options.LabelSelector))
+		}
+		err := i.client.V1WatchAnnouncements(ctx, func(event model.Event) {
+			received = true
+			i.handleEvent(event)
+		}, opts...)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if resyncRevision > 0 {
+			// The history has a gap, list again and pass the missed changes to the handlers
+			if err := i.relist(ctx, resyncRevision); err != nil {
+				i.log().Warn("failed to list announcements after a watch gap, retrying", "error", err, "backoff", backoff)
+			} else {
+				backoff = initialWatchBackoff
+				continue
+			}
+		} else if err != nil {
+			i.log().Warn("watch connection lost, reconnecting", "error", err, "backoff", backoff)
+		}
+
+		// The connection was healthy, so the next drop is treated as a new failure series
+		if received {
+			backoff = initialWatchBackoff
+		}
+
+		// Wait before reconnecting to avoid hammering an unavailable server
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWatchBackoff)
+	}
+}
+
+// advanceRevision moves the revision to resume the watch from forward to the bookmarked revision, so that a resumed
+// watch does not replay the changes filtered out on the server.
+func (i *AnnouncementInformer) advanceRevision(revision int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.revision = max(i.revision, revision)
+}
+
+// handleEvent applies the watch event to the cache and notifies the handlers. Soft-deleted announcements leave the
+// cache like deleted ones, as they leave the lists. Events not newer than the cached announcement are replays of
+// changes already listed and are skipped.
+func (i *AnnouncementInformer) handleEvent(event model.Event) {
+	i.dispatchMu.Lock()
+	defer i.dispatchMu.Unlock()
+
+	announcement := event.Announcement
+	id := key(announcement.Meta.Project, announcement.Meta.Name)
+	deleted := event.Type == model.EventDeleted || announcement.Status.Status == model.StatusWithdrawn
+
+	i.mu.Lock()
+	i.revision = max(i.revision, event.Revision)
+	old, exists := i.items[id]
+	if exists && event.Revision <= resourceVersion(old) {
+		i.mu.Unlock()
+		return
+	}
+	if deleted {
+		delete(i.items, id)
+	} else {
+		i.items[id] = &announcement
+	}
+	i.mu.Unlock()
+
+	for _, handler := range i.handlers {
+		switch {
+		case deleted && exists:
+			handler.OnDelete(old)
+		case deleted:
+		case exists:
+			handler.OnUpdate(old, &announcement)
+		default:
+			handler.OnAdd(&announcement)
+		}
+	}
+}
+
+// relist replaces the cache with a new list and notifies the handlers of the differences. The watch resumes from
+// the revision, or from the highest resource version of the cache if the revision is zero.
+func (i *AnnouncementInformer) relist(ctx context.Context, revision int64) error {
+	announcements, err := i.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	i.dispatchMu.Lock()
+	defer i.dispatchMu.Unlock()
+
+	items := make(map[string]*model.Announcement, len(announcements))
+	for _, announcement := range announcements {
+		items[key(announcement.Meta.Project, announcement.Meta.Name)] = announcement
+		revision = max(revision, resourceVersion(announcement))
+	}
+
+	i.mu.Lock()
+	previous := i.items
+	i.items = items
+	i.revision = max(i.revision, revision)
+	i.mu.Unlock()
+
+	for id, announcement := range items {
+		old, exists := previous[id]
+		for _, handler := range i.handlers {
+			switch {
+			case !exists:
+				handler.OnAdd(announcement)
+			case resourceVersion(old) != resourceVersion(announcement):
+				handler.OnUpdate(old, announcement)
+			}
+		}
+	}
+	for id, old := range previous {
+		if _, exists := items[id]; exists {
+			continue
+		}
+		for _, handler := range i.handlers {
+			handler.OnDelete(old)
+		}
+	}
+	return nil
+}
+
+// list requests the announcements selected by the options from the API server.
+func (i *AnnouncementInformer) list(ctx context.Context) ([]*model.Announcement, error) {
+	var announcements []*model.Announcement
+	switch {
+	case i.options.Project != "":
+		opts := v1.ListOptions{LabelSelector: i.options.LabelSelector}
+		for {
+			page, err := i.client.V1ListAnnouncementsPage(ctx, i.options.Project, opts)
+			if err != nil {
+				return nil, err
+			}
+			for j := range page.Items {
+				announcements = append(announcements, &page.Items[j])
+			}
+			if page.Continue == "" {
+				break
+			}
+			opts.Continue = page.Continue
+		}
+	case i.options.LabelSelector != "":
+		list, err := i.client.V1ListAnnouncementsBySelector(ctx, i.options.LabelSelector)
+		if err != nil {
+			return nil, err
+		}
+		for j := range list {
+			announcements = append(announcements, &list[j])
+		}
+	default:
+		list, err := i.client.V1ListAllAnnouncements(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for j := range list {
+			announcements = append(announcements, &list[j])
+		}
+	}
+	return announcements, nil
+}
+
+// runResync calls OnUpdate for every cached announcement at the resync period until the context is canceled, so
+// that the controllers correct the drift of the state they manage.
+func (i *AnnouncementInformer) runResync(ctx context.Context) {
+	ticker := time.NewTicker(i.options.ResyncPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			i.dispatchMu.Lock()
+			for _, announcement := range i.Lister().List() {
+				for _, handler := range i.handlers {
+					handler.OnUpdate(announcement, announcement)
+				}
+			}
+			i.dispatchMu.Unlock()
+		}
+	}
+}
+
+// log returns the logger of the watch diagnostics.
+func (i *AnnouncementInformer) log() *slog.Logger {
+	if i.options.Logger != nil {
+		return i.options.Logger
+	}
+	return slog.Default()
+}
+
+// key returns the "project/name" ID of an announcement.
+func key(project, name string) string {
+	return project + "/" + name
+}
+
+// resourceVersion returns the resource version of the announcement as a revision; zero if it is not set.
+func resourceVersion(announcement *model.Announcement) int64 {
+	version, _ := strconv.ParseInt(announcement.Meta.ResourceVersion, 10, 64)
+	return version
+}
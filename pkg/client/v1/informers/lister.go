@@ -0,0 +1,50 @@
+package informers
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// AnnouncementLister reads the announcements from the cache of an informer. It is safe for concurrent use. The
+// returned announcements are shared with the cache and must not be modified.
+type AnnouncementLister struct {
+	informer *AnnouncementInformer
+}
+
+// Get returns the cached announcement of the project with the name, and whether it exists.
+func (l AnnouncementLister) Get(project, name string) (*model.Announcement, bool) {
+	l.informer.mu.RLock()
+	defer l.informer.mu.RUnlock()
+
+	announcement, ok := l.informer.items[key(project, name)]
+	return announcement, ok
+}
+
+// List returns the cached announcements of all projects ordered by project and name.
+func (l AnnouncementLister) List() []*model.Announcement {
+	return l.ListFunc(func(*model.Announcement) bool { return true })
+}
+
+// ListProject returns the cached announcements of the project ordered by name.
+func (l AnnouncementLister) ListProject(project string) []*model.Announcement {
+	return l.ListFunc(func(announcement *model.Announcement) bool { return announcement.Meta.Project == project })
+}
+
+// ListFunc returns the cached announcements for which match returns true, ordered by project and name.
+func (l AnnouncementLister) ListFunc(match func(announcement *model.Announcement) bool) []*model.Announcement {
+	l.informer.mu.RLock()
+	announcements := make([]*model.Announcement, 0, len(l.informer.items))
+	for _, announcement := range l.informer.items {
+		if match(announcement) {
+			announcements = append(announcements, announcement)
+		}
+	}
+	l.informer.mu.RUnlock()
+
+	slices.SortFunc(announcements, func(a, b *model.Announcement) int {
+		return cmp.Or(cmp.Compare(a.Meta.Project, b.Meta.Project), cmp.Compare(a.Meta.Name, b.Meta.Name))
+	})
+	return announcements
+}
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// AnnouncementsInterface is the part of the client that reads, writes and watches announcements. Controllers depend
+// on it rather than on *APIClient, so that their unit tests run against the in-memory client of the fake package.
+type AnnouncementsInterface interface {
+	V1GetAnnouncement(ctx context.Context, project, name string) (*model.Announcement, error)
+	V1ListAllAnnouncements(ctx context.Context) ([]model.Announcement, error)
+	V1ListAllProjectAnnouncements(ctx context.Context, project string) ([]model.Announcement, error)
+	V1ListAnnouncementsPage(ctx context.Context, project string, opts ListOptions) (*model.AnnouncementList, error)
+	V1ListAnnouncementsBySelector(ctx context.Context, selector string) ([]model.Announcement, error)
+	V1CreateAnnouncement(ctx context.Context, announcement *model.Announcement, opts ...WriteOption) error
+	V1UpdateAnnouncement(ctx context.Context, announcement *model.Announcement, opts ...WriteOption) error
+	V1ApplyAnnouncement(ctx context.Context, manager string, announcement *model.Announcement) (*model.Announcement, error)
+	V1DeleteAnnouncement(ctx context.Context, project, name string, opts ...WriteOption) error
+	V1GetAnnouncementStatus(ctx context.Context, project, name string) (*model.Status, error)
+	V1UpdateAnnouncementStatus(ctx context.Context, project, name string, patch *model.Status) (*model.Status, error)
+	V1WatchAnnouncements(ctx context.Context, onEvent func(event model.Event), opts ...WatchOption) error
+}
+
+var _ AnnouncementsInterface = (*APIClient)(nil)
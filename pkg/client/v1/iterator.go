@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"context"
+	"iter"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// iteratorPageSize is the number of announcements fetched per request by V1IterateAnnouncements.
+const iteratorPageSize = 100
+
+// V1IterateAnnouncements iterates over the announcements of the project, fetching pages lazily so that only one
+// page is held in memory at a time. Iteration stops at the first error, which is yielded together with a nil announcement.
+func (c *APIClient) V1IterateAnnouncements(ctx context.Context, project string) iter.Seq2[*model.Announcement, error] {
+	return func(yield func(*model.Announcement, error) bool) {
+		var continueToken string
+		for {
+			page, err := c.V1ListAnnouncementsPage(ctx, project, ListOptions{Limit: iteratorPageSize, Continue: continueToken})
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for i := range page.Items {
+				if !yield(&page.Items[i], nil) {
+					return
+				}
+			}
+
+			if page.Continue == "" {
+				return
+			}
+			continueToken = page.Continue
+		}
+	}
+}
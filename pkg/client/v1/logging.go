@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sensitiveHeaders lists the headers whose values are never written to logs.
+var sensitiveHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"Cookie":              {},
+	"Set-Cookie":          {},
+	"X-Api-Key":           {},
+}
+
+// redactedValue replaces the values of sensitive headers.
+const redactedValue = "REDACTED"
+
+// WithSlogLogger logs every request and response at the debug level using the provided logger. The logger also
+// receives the watch diagnostics. When the debug level is disabled, the middleware only performs a level check and
+// adds no allocations.
+func WithSlogLogger(logger *slog.Logger) ClientOption {
+	return func(c *APIClient) {
+		c.logger = logger
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &loggingTransport{next: next, logger: logger}
+		})
+	}
+}
+
+// loggingTransport is an http.RoundTripper that emits structured debug records for requests and responses.
+type loggingTransport struct {
+	next   http.RoundTripper
+	logger *slog.Logger
+}
+
+// RoundTrip logs the request, performs it and logs the response together with the request duration.
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if !t.logger.Enabled(ctx, slog.LevelDebug) {
+		return t.next.RoundTrip(req)
+	}
+
+	t.logger.LogAttrs(ctx, slog.LevelDebug, "sending request",
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Int64("request_content_length", req.ContentLength),
+		slog.Any("headers", redactHeaders(req.Header)),
+	)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	duration := float64(time.Since(start).Microseconds()) / 1000
+
+	if err != nil {
+		t.logger.LogAttrs(ctx, slog.LevelDebug, "request failed",
+			slog.String("method", req.Method),
+			slog.String("url", req.URL.String()),
+			slog.Float64("duration_ms", duration),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	t.logger.LogAttrs(ctx, slog.LevelDebug, "received response",
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Int("status_code", resp.StatusCode),
+		slog.Int64("response_content_length", resp.ContentLength),
+		slog.Float64("duration_ms", duration),
+	)
+	return resp, nil
+}
+
+// redactHeaders returns a copy of the headers with the values of sensitive headers replaced.
+func redactHeaders(headers http.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if _, ok := sensitiveHeaders[http.CanonicalHeaderKey(name)]; ok {
+			result[name] = redactedValue
+			continue
+		}
+		result[name] = strings.Join(values, ", ")
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+func TestSlogLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	announcement := &model.Announcement{Meta: model.Meta{Project: "project", Name: "name"}}
+
+	t.Run("debug", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		// The logging transport is added first, so it runs inside the auth transport and sees the Authorization header
+		client := NewAPIClient(&server.URL, 5*time.Second, WithSlogLogger(logger), WithBearerToken("s3cr3t"))
+		if err := client.V1CreateAnnouncement(context.Background(), announcement); err != nil {
+			t.Fatalf("V1CreateAnnouncement: %v", err)
+		}
+		if strings.Contains(buf.String(), "s3cr3t") {
+			t.Fatalf("log contains the bearer token: %s", buf.String())
+		}
+
+		records := make(map[string]map[string]any)
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var record map[string]any
+			if err := json.Unmarshal(line, &record); err != nil {
+				t.Fatalf("malformed log record %q: %v", line, err)
+			}
+			records[record["msg"].(string)] = record
+		}
+
+		request, ok := records["sending request"]
+		if !ok {
+			t.Fatalf("no request record in %s", buf.String())
+		}
+		if request["method"] != http.MethodPost || request["url"] != server.URL+"/v1/announcements/" {
+			t.Errorf("unexpected request record: %v", request)
+		}
+		if _, ok := request["request_content_length"].(float64); !ok {
+			t.Errorf("request record has no request_content_length: %v", request)
+		}
+		if headers, _ := request["headers"].(map[string]any); headers["Authorization"] != redactedValue {
+			t.Errorf("Authorization header is not redacted: %v", request["headers"])
+		}
+
+		response, ok := records["received response"]
+		if !ok {
+			t.Fatalf("no response record in %s", buf.String())
+		}
+		if response["status_code"] != float64(http.StatusCreated) {
+			t.Errorf("status_code = %v, want %d", response["status_code"], http.StatusCreated)
+		}
+		if _, ok := response["response_content_length"].(float64); !ok {
+			t.Errorf("response record has no response_content_length: %v", response)
+		}
+		if duration, ok := response["duration_ms"].(float64); !ok || duration <= 0 {
+			t.Errorf("duration_ms = %v, want a positive number", response["duration_ms"])
+		}
+	})
+
+	t.Run("info", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		client := NewAPIClient(&server.URL, 5*time.Second, WithSlogLogger(logger))
+		if err := client.V1CreateAnnouncement(context.Background(), announcement); err != nil {
+			t.Fatalf("V1CreateAnnouncement: %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("requests are logged above the debug level: %s", buf.String())
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http2"
+)
+
+// ClientOption configures optional behaviour of the APIClient.
+type ClientOption func(*APIClient)
+
+// WithHTTP2 forces the client to use HTTP/2 for all requests, regardless of ALPN negotiation.
+// Plain http:// endpoints are served over h2c (HTTP/2 with prior knowledge), which the API server accepts on its plain
+// HTTP listener; https:// endpoints use TLS.
+// All requests, including retried ones, are multiplexed over the same HTTP/2 connection.
+func WithHTTP2() ClientOption {
+	return func(c *APIClient) {
+		c.enableHTTP2 = true
+	}
+}
+
+// buildTransport constructs the base transport according to the configured options and wraps it with the middlewares.
+// The trace context propagation is always the outermost layer, so that the middlewares run within the client span.
+func (c *APIClient) buildTransport() http.RoundTripper {
+	var transport http.RoundTripper
+	if c.enableHTTP2 {
+		transport = c.newHTTP2Transport()
+	} else {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.TLSClientConfig = c.tlsConfig
+		transport = base
+	}
+	// Both transports request gzip with Accept-Encoding and decompress the responses transparently, as long as the
+	// header is not set by the caller
+
+	for _, middleware := range c.middlewares {
+		transport = middleware(transport)
+	}
+	return &propagationTransport{next: transport}
+}
+
+// newHTTP2Transport creates an HTTP/2 transport that uses TLS or prior-knowledge h2c depending on the base URL scheme.
+func (c *APIClient) newHTTP2Transport() *http2.Transport {
+	transport := &http2.Transport{
+		TLSClientConfig: c.tlsConfig,
+	}
+
+	parsedURL, err := url.Parse(c.endpoint())
+	if err == nil && parsedURL.Scheme == "http" {
+		// Use a plain TCP connection for h2c, since http2.Transport always calls DialTLSContext
+		transport.AllowHTTP = true
+		transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+
+	return transport
+}
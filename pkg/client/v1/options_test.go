@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// concurrentGets is the number of V1GetAnnouncement calls issued at once by every iteration of the HTTP/2 benchmark.
+const concurrentGets = 64
+
+// BenchmarkConcurrentGet compares concurrent V1GetAnnouncement calls multiplexed over one HTTP/2 connection with the
+// same calls over HTTP/1.1, where the calls exceeding the idle connection pool open new TLS connections.
+func BenchmarkConcurrentGet(b *testing.B) {
+	b.Run("HTTP/1.1", func(b *testing.B) {
+		benchmarkConcurrentGet(b, false)
+	})
+	b.Run("HTTP/2", func(b *testing.B) {
+		benchmarkConcurrentGet(b, true)
+	})
+}
+
+func benchmarkConcurrentGet(b *testing.B, http2 bool) {
+	body, _ := json.Marshal(model.APIResponse{
+		Status: "success",
+		Data:   model.Announcement{Meta: model.Meta{Project: "project", Name: "name"}},
+	})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	// The HTTP/1.1 server does not offer h2 in ALPN, so the client cannot upgrade the connections
+	server.EnableHTTP2 = http2
+	server.StartTLS()
+	defer server.Close()
+
+	opts := []ClientOption{WithTLSConfig(server.Client().Transport.(*http.Transport).TLSClientConfig.Clone())}
+	if http2 {
+		opts = append(opts, WithHTTP2())
+	}
+	client := NewAPIClient(&server.URL, 10*time.Second, opts...)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < concurrentGets; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := client.V1GetAnnouncement(ctx, "project", "name"); err != nil {
+					b.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
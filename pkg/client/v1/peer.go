@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1CreatePeer creates the resource of a BGP neighbor, which the updater then configures on the GoBGP routers. It
+// fails with ErrConflict if a peer with the address already exists.
+func (c *APIClient) V1CreatePeer(ctx context.Context, peer *model.Peer) (*model.Peer, error) {
+	return c.writePeer(ctx, "POST", c.endpoint()+"/v1/peers", peer, http.StatusCreated, "failed to create peer")
+}
+
+// V1UpdatePeer replaces the configuration of an existing BGP neighbor. The status of the peer is kept.
+func (c *APIClient) V1UpdatePeer(ctx context.Context, peer *model.Peer) (*model.Peer, error) {
+	baseURL := fmt.Sprintf("%s/v1/peers/%s", c.endpoint(), url.PathEscape(peer.Address))
+	return c.writePeer(ctx, "PATCH", baseURL, peer, http.StatusOK, "failed to update peer")
+}
+
+// V1UpdatePeerStatus replaces the status of the peer with the session states reported by the routers.
+func (c *APIClient) V1UpdatePeerStatus(ctx context.Context, address string, status *model.PeerStatus) (*model.Peer, error) {
+	baseURL := fmt.Sprintf("%s/v1/peers/%s/status", c.endpoint(), url.PathEscape(address))
+	return c.writePeer(ctx, "PUT", baseURL, status, http.StatusOK, "failed to update peer status")
+}
+
+// writePeer sends the body with the method and decodes the stored peer from the response.
+func (c *APIClient) writePeer(ctx context.Context, method, baseURL string, body interface{}, successStatus int, message string) (*model.Peer, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != successStatus {
+		return nil, responseError(message, resp)
+	}
+
+	var peer model.Peer
+	if err := decodeResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	return &peer, nil
+}
+
+// V1ListPeers returns all peer resources together with their session states.
+func (c *APIClient) V1ListPeers(ctx context.Context) ([]model.Peer, error) {
+	baseURL := c.endpoint() + "/v1/peers"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list peers", resp)
+	}
+
+	var peers []model.Peer
+	if err := decodeResponse(resp, &peers); err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+// V1GetPeer returns the resource of the peer with the address together with its session states.
+func (c *APIClient) V1GetPeer(ctx context.Context, address string) (*model.Peer, error) {
+	baseURL := fmt.Sprintf("%s/v1/peers/%s", c.endpoint(), url.PathEscape(address))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get peer", resp)
+	}
+
+	var peer model.Peer
+	if err := decodeResponse(resp, &peer); err != nil {
+		return nil, err
+	}
+
+	return &peer, nil
+}
+
+// V1DeletePeer deletes the resource of the peer with the address. The updater then removes the neighbor from the
+// GoBGP routers.
+func (c *APIClient) V1DeletePeer(ctx context.Context, address string) error {
+	baseURL := fmt.Sprintf("%s/v1/peers/%s", c.endpoint(), url.PathEscape(address))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to delete peer", resp)
+	}
+
+	return nil
+}
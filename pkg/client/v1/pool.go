@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1CreatePool creates the VIP pool in its project. It fails with ErrConflict if the pool already exists.
+func (c *APIClient) V1CreatePool(ctx context.Context, pool *model.Pool) (*model.Pool, error) {
+	baseURL := fmt.Sprintf("%s/v1/pools/%s", c.endpoint(), url.PathEscape(pool.Project))
+	return c.writePool(ctx, "POST", baseURL, pool, http.StatusCreated, "failed to create pool")
+}
+
+// V1UpdatePool replaces the description and the prefixes of an existing VIP pool. It fails with ErrConflict if an
+// allocated address would leave the pool.
+func (c *APIClient) V1UpdatePool(ctx context.Context, pool *model.Pool) (*model.Pool, error) {
+	baseURL := fmt.Sprintf("%s/v1/pools/%s/%s", c.endpoint(), url.PathEscape(pool.Project), url.PathEscape(pool.Name))
+	return c.writePool(ctx, "PATCH", baseURL, pool, http.StatusOK, "failed to update pool")
+}
+
+// writePool sends the pool with the method, checks the response status and returns the pool with its allocations.
+func (c *APIClient) writePool(ctx context.Context, method, baseURL string, pool *model.Pool, successStatus int, message string) (*model.Pool, error) {
+	data, err := json.Marshal(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != successStatus {
+		return nil, responseError(message, resp)
+	}
+
+	var written model.Pool
+	if err := decodeResponse(resp, &written); err != nil {
+		return nil, err
+	}
+
+	return &written, nil
+}
+
+// V1ListPools returns the VIP pools of the project with their allocations.
+func (c *APIClient) V1ListPools(ctx context.Context, project string) ([]model.Pool, error) {
+	baseURL := fmt.Sprintf("%s/v1/pools/%s", c.endpoint(), url.PathEscape(project))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list pools", resp)
+	}
+
+	var pools []model.Pool
+	if err := decodeResponse(resp, &pools); err != nil {
+		return nil, err
+	}
+
+	return pools, nil
+}
+
+// V1GetPool returns the VIP pool of the project with its allocations.
+func (c *APIClient) V1GetPool(ctx context.Context, project, name string) (*model.Pool, error) {
+	baseURL := fmt.Sprintf("%s/v1/pools/%s/%s", c.endpoint(), url.PathEscape(project), url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get pool", resp)
+	}
+
+	var pool model.Pool
+	if err := decodeResponse(resp, &pool); err != nil {
+		return nil, err
+	}
+
+	return &pool, nil
+}
+
+// V1DeletePool deletes the VIP pool of the project. It fails with ErrConflict while addresses of the pool are
+// allocated.
+func (c *APIClient) V1DeletePool(ctx context.Context, project, name string) error {
+	baseURL := fmt.Sprintf("%s/v1/pools/%s/%s", c.endpoint(), url.PathEscape(project), url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to delete pool", resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1CreateProject creates the project resource holding the metadata and the quota of the project. It fails with
+// ErrConflict if the project already exists.
+func (c *APIClient) V1CreateProject(ctx context.Context, project *model.Project) error {
+	return c.writeProject(ctx, "POST", c.endpoint()+"/v1/projects", project, http.StatusCreated, "failed to create project")
+}
+
+// V1UpdateProject replaces the metadata and the quota of an existing project.
+func (c *APIClient) V1UpdateProject(ctx context.Context, project *model.Project) error {
+	baseURL := fmt.Sprintf("%s/v1/projects/%s", c.endpoint(), url.PathEscape(project.Name))
+	return c.writeProject(ctx, "PATCH", baseURL, project, http.StatusOK, "failed to update project")
+}
+
+// writeProject sends the project with the method and checks the response status.
+func (c *APIClient) writeProject(ctx context.Context, method, baseURL string, project *model.Project, successStatus int, message string) error {
+	data, err := json.Marshal(project)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != successStatus {
+		return responseError(message, resp)
+	}
+
+	return nil
+}
+
+// V1ListProjects returns all project resources.
+func (c *APIClient) V1ListProjects(ctx context.Context) ([]model.Project, error) {
+	baseURL := c.endpoint() + "/v1/projects"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list projects", resp)
+	}
+
+	var projects []model.Project
+	if err := decodeResponse(resp, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// V1GetProject returns the resource of the project.
+func (c *APIClient) V1GetProject(ctx context.Context, name string) (*model.Project, error) {
+	baseURL := fmt.Sprintf("%s/v1/projects/%s", c.endpoint(), url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get project", resp)
+	}
+
+	var project model.Project
+	if err := decodeResponse(resp, &project); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// V1DeleteProject deletes the resource of the project. It fails with ErrConflict while the project has
+// announcements.
+func (c *APIClient) V1DeleteProject(ctx context.Context, name string) error {
+	baseURL := fmt.Sprintf("%s/v1/projects/%s", c.endpoint(), url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to delete project", resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer of the client spans.
+const instrumentationName = "github.com/nikitamishagin/corebgp/pkg/client/v1"
+
+// propagationTransport is an http.RoundTripper that wraps each request in a client span and propagates the trace
+// context to the API server in the request headers. It uses the global OpenTelemetry tracer provider and propagator,
+// so it does nothing unless the application has configured them.
+type propagationTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip starts the client span, injects the trace context into a copy of the request and performs it.
+func (t *propagationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.Redacted()),
+		),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,186 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	"golang.org/x/time/rate"
+)
+
+// WithRateLimit throttles outgoing requests with a token-bucket limiter allowing rps requests per second
+// with the given burst size. A 429 response carrying a Retry-After header additionally delays subsequent requests.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *APIClient) {
+		limiter := rate.NewLimiter(rate.Limit(rps), burst)
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &rateLimitTransport{next: next, limiter: limiter, clock: clock.RealClock{}}
+		})
+	}
+}
+
+// rateLimitTransport is an http.RoundTripper that waits for a limiter token before sending each request.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+	clock   clock.Clock // clock times the waits for the limiter and the Retry-After delays.
+
+	mu        sync.Mutex
+	notBefore time.Time // notBefore is the time before which no request is sent, set from the server's Retry-After.
+}
+
+// RoundTrip waits for the rate limiter, respecting the request context, and then performs the request.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	// Honor the back-off requested by the server with a previous 429 response
+	if wait := t.retryAfterWait(); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.clock.After(wait):
+		}
+	}
+
+	if err := t.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			t.deferUntil(t.clock.Now().Add(delay))
+		}
+	}
+
+	return resp, nil
+}
+
+// wait takes a token from the limiter, waiting until it is available. The token is given back when the context is done
+// first.
+func (t *rateLimitTransport) wait(ctx context.Context) error {
+	now := t.clock.Now()
+	reservation := t.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return fmt.Errorf("rate limit with a burst of %d allows no request", t.limiter.Burst())
+	}
+	delay := reservation.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		reservation.CancelAt(t.clock.Now())
+		return ctx.Err()
+	case <-t.clock.After(delay):
+		return nil
+	}
+}
+
+// defaultThrottleDelay is the delay before retrying a throttled request without a Retry-After header.
+const defaultThrottleDelay = time.Second
+
+// WithThrottleRetries retries requests rejected with 429 Too Many Requests up to maxRetries times, after the delay
+// of the Retry-After header. All methods are retried, since the API server rejects throttled requests before
+// handling them; requests whose body cannot be replayed are not. The delay counts against the client timeout.
+func WithThrottleRetries(maxRetries int) ClientOption {
+	return func(c *APIClient) {
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &throttleRetryTransport{next: next, maxRetries: maxRetries}
+		})
+	}
+}
+
+// throttleRetryTransport is an http.RoundTripper that retries throttled requests after the delay requested by the
+// server.
+type throttleRetryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+}
+
+// RoundTrip performs the request and retries it while the server responds with 429 Too Many Requests.
+func (t *throttleRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= t.maxRetries {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, nil
+		}
+
+		delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if !ok {
+			delay = defaultThrottleDelay
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		retry := req.Clone(ctx)
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		req = retry
+	}
+}
+
+// retryAfterWait returns the remaining time to wait according to the last Retry-After header.
+func (t *rateLimitTransport) retryAfterWait() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.notBefore.Sub(t.clock.Now())
+}
+
+// deferUntil postpones all subsequent requests until the specified time.
+func (t *rateLimitTransport) deferUntil(until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.notBefore) {
+		t.notBefore = until
+	}
+}
+
+// parseRetryAfter parses the Retry-After header value given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
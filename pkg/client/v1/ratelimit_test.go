@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+	"golang.org/x/time/rate"
+)
+
+// waitingClock is a fake clock that reports every wait started with After, so that a test advances the time only
+// once the transport waits for it.
+type waitingClock struct {
+	*clock.FakeClock
+	waits chan time.Duration
+}
+
+func (c *waitingClock) After(d time.Duration) <-chan time.Time {
+	ch := c.FakeClock.After(d)
+	c.waits <- d
+	return ch
+}
+
+// okTransport answers every request with an empty 200 response.
+type okTransport struct{}
+
+func (okTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func newTestRateLimitTransport(rps float64, burst int) (*rateLimitTransport, *waitingClock) {
+	clk := &waitingClock{
+		FakeClock: clock.NewFakeClock(time.Now()),
+		waits:     make(chan time.Duration),
+	}
+	// Start with an empty bucket so that every request waits for its token
+	limiter := rate.NewLimiter(rate.Limit(rps), burst)
+	limiter.AllowN(clk.Now(), burst)
+	return &rateLimitTransport{next: okTransport{}, limiter: limiter, clock: clk}, clk
+}
+
+func TestRateLimitTransportPacesRequests(t *testing.T) {
+	transport, clk := newTestRateLimitTransport(10, 1)
+	start := clk.Now()
+
+	const requests = 100
+	errs := make(chan error, 1)
+	go func() {
+		for i := 0; i < requests; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "http://corebgp.test/", nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				errs <- err
+				return
+			}
+			resp.Body.Close()
+		}
+		errs <- nil
+	}()
+
+	for {
+		select {
+		case d := <-clk.waits:
+			clk.Advance(d)
+			continue
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("requests did not finish")
+		}
+		break
+	}
+
+	if elapsed := clk.Now().Sub(start); elapsed < 9*time.Second {
+		t.Fatalf("%d requests at 10 rps took %v, want at least 9s", requests, elapsed)
+	}
+}
+
+func TestRateLimitTransportCancelledWait(t *testing.T) {
+	transport, clk := newTestRateLimitTransport(10, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://corebgp.test/", nil)
+		_, err := transport.RoundTrip(req)
+		errs <- err
+	}()
+
+	// Cancel while the request waits for a token, without advancing the clock
+	<-clk.waits
+	cancel()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("RoundTrip error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RoundTrip did not return after the cancellation")
+	}
+
+	// The cancelled reservation gives its token back, so the next request waits only for the one token it needs
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "http://corebgp.test/", nil)
+		_, err := transport.RoundTrip(req)
+		errs <- err
+	}()
+	d := <-clk.waits
+	if d > 100*time.Millisecond {
+		t.Fatalf("request after the cancellation waits %v, want at most 100ms", d)
+	}
+	clk.Advance(d)
+	if err := <-errs; err != nil {
+		t.Fatalf("RoundTrip after the cancellation: %v", err)
+	}
+}
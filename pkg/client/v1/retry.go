@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy configures the retries of the requests failing with a connection error or a server error.
+type RetryPolicy struct {
+	MaxAttempts    int           // MaxAttempts is the number of attempts of a request including the first one; values below 2 disable retries.
+	InitialBackoff time.Duration // InitialBackoff is the upper bound of the delay before the first retry.
+	MaxBackoff     time.Duration // MaxBackoff caps the exponential growth of the upper bound of the delay.
+}
+
+// DefaultRetryPolicy makes up to three attempts with delays growing from 100ms to at most 5s.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+
+// WithRetryPolicy retries the requests failing with a connection error or a 5xx status code. The delays grow
+// exponentially with full jitter and count against the client timeout. Only idempotent requests are retried: GET,
+// HEAD, PUT and DELETE requests, and POST requests, which are sent with a random Idempotency-Key header, so that the
+// API server answers a retried create with the response of the first attempt rather than a conflict. PATCH requests
+// are never retried.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *APIClient) {
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &retryTransport{next: next, policy: policy}
+		})
+	}
+}
+
+// retryTransport is an http.RoundTripper that retries idempotent requests according to the retry policy.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+// RoundTrip performs the request and retries it while it fails with a connection error or a server error.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost && req.Header.Get("Idempotency-Key") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Idempotency-Key", newIdempotencyKey())
+	}
+	if !isIdempotent(req) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	backoff := t.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.policy.MaxAttempts || ctx.Err() != nil || (err == nil && resp.StatusCode < http.StatusInternalServerError) {
+			return resp, err
+		}
+		if err == nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		var delay time.Duration
+		if backoff > 0 {
+			delay = mathrand.N(backoff)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, t.policy.MaxBackoff)
+
+		retry := req.Clone(ctx)
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		req = retry
+	}
+}
+
+// isIdempotent reports whether the request can be sent several times with the effect of sending it once.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return req.Header.Get("Idempotency-Key") != ""
+	default:
+		return false
+	}
+}
+
+// newIdempotencyKey returns a random Idempotency-Key header value.
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	return hex.EncodeToString(key)
+}
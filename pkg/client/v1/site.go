@@ -0,0 +1,117 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// V1RegisterUpdater registers the updater with the site, or renews its registration.
+func (c *APIClient) V1RegisterUpdater(ctx context.Context, site string, updater *model.SiteUpdater) error {
+	baseURL := fmt.Sprintf("%s/v1/sites/%s/updaters/%s", c.endpoint(), url.PathEscape(site), url.PathEscape(updater.Identity))
+
+	data, err := json.Marshal(updater)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", baseURL, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to register updater", resp)
+	}
+
+	return nil
+}
+
+// V1DeregisterUpdater removes the registration of the updater with the identity from the site.
+func (c *APIClient) V1DeregisterUpdater(ctx context.Context, site, identity string) error {
+	baseURL := fmt.Sprintf("%s/v1/sites/%s/updaters/%s", c.endpoint(), url.PathEscape(site), url.PathEscape(identity))
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("failed to deregister updater", resp)
+	}
+
+	return nil
+}
+
+// V1ListSites returns the sites with the updaters registered with them.
+func (c *APIClient) V1ListSites(ctx context.Context) ([]model.Site, error) {
+	baseURL := c.endpoint() + "/v1/sites"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to list sites", resp)
+	}
+
+	var sites []model.Site
+	if err := decodeResponse(resp, &sites); err != nil {
+		return nil, err
+	}
+
+	return sites, nil
+}
+
+// V1GetSite returns the site with the updaters registered with it.
+func (c *APIClient) V1GetSite(ctx context.Context, name string) (*model.Site, error) {
+	baseURL := fmt.Sprintf("%s/v1/sites/%s", c.endpoint(), url.PathEscape(name))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("failed to get site", resp)
+	}
+
+	var site model.Site
+	if err := decodeResponse(resp, &site); err != nil {
+		return nil, err
+	}
+
+	return &site, nil
+}
@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// ClientConfig holds the TLS parameters of the connection to the API server.
+type ClientConfig struct {
+	CACert             string // CACert is the path to the CA certificate used to verify the server; empty uses the system pool.
+	ClientCert         string // ClientCert is the path to the client certificate presented for mutual TLS; empty disables it.
+	ClientKey          string // ClientKey is the path to the private key of the client certificate.
+	InsecureSkipVerify bool   // InsecureSkipVerify disables the verification of the server certificate. Use for testing only.
+}
+
+// NewTLSConfig builds the TLS configuration for the API client from the certificate files of the config.
+func NewTLSConfig(config ClientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CACert != "" {
+		caCert, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA certificate: %w", err)
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to append CA certificate")
+		}
+		tlsConfig.RootCAs = caPool
+	}
+
+	if config.ClientCert != "" || config.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate and key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// WithTLSConfig sets the TLS configuration used for https:// requests and wss:// watch connections.
+// Use NewTLSConfig to build it from certificate files.
+func WithTLSConfig(tlsConfig *tls.Config) ClientOption {
+	return func(c *APIClient) {
+		c.tlsConfig = tlsConfig
+	}
+}
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxTraceBodySize is the number of response body bytes written to the trace; the rest is truncated.
+const maxTraceBodySize = 4096
+
+// WithTraceHTTP writes every request (method, URL, headers, body) and response (status, headers, truncated body)
+// to w, similar to curl --verbose. Values of sensitive headers, e.g. Authorization, are redacted.
+// It is intended for development only, since request bodies are buffered in memory.
+func WithTraceHTTP(w io.Writer) ClientOption {
+	return func(c *APIClient) {
+		c.middlewares = append(c.middlewares, func(next http.RoundTripper) http.RoundTripper {
+			return &tracingTransport{next: next, w: w}
+		})
+	}
+}
+
+// tracingTransport is an http.RoundTripper that dumps the raw HTTP traffic to a writer.
+type tracingTransport struct {
+	next http.RoundTripper
+	mu   sync.Mutex // mu prevents the traces of concurrent requests from interleaving.
+	w    io.Writer
+}
+
+// RoundTrip writes the request trace, performs the request and writes the response trace.
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body for tracing: %w", err)
+		}
+		requestBody = body
+
+		// Restore the body on a copy of the request, since a round tripper must not modify the original one
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	var trace strings.Builder
+	fmt.Fprintf(&trace, "> %s %s %s\n", req.Method, req.URL.String(), req.Proto)
+	writeTraceHeaders(&trace, "> ", req.Header)
+	writeTraceBody(&trace, "> ", requestBody, false)
+	t.write(trace.String())
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.write(fmt.Sprintf("* %s %s failed: %v\n", req.Method, req.URL.String(), err))
+		return nil, err
+	}
+
+	// Read only the beginning of the response body and keep the rest for the caller. Event streams are not read, since
+	// waiting for their beginning would hold back the first events.
+	var prefix []byte
+	var readErr error
+	eventStream := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	if !eventStream {
+		prefix, readErr = io.ReadAll(io.LimitReader(resp.Body, maxTraceBodySize+1))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(prefix), resp.Body), resp.Body}
+	}
+
+	trace.Reset()
+	fmt.Fprintf(&trace, "< %s %s\n", resp.Proto, resp.Status)
+	writeTraceHeaders(&trace, "< ", resp.Header)
+	truncated := len(prefix) > maxTraceBodySize
+	if truncated {
+		prefix = prefix[:maxTraceBodySize]
+	}
+	writeTraceBody(&trace, "< ", prefix, truncated)
+	if readErr != nil {
+		fmt.Fprintf(&trace, "* failed to read response body: %v\n", readErr)
+	}
+	if eventStream {
+		trace.WriteString("* event stream body not traced\n")
+	}
+	t.write(trace.String())
+
+	return resp, nil
+}
+
+// write writes the trace to the underlying writer.
+func (t *tracingTransport) write(trace string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = io.WriteString(t.w, trace)
+}
+
+// writeTraceHeaders writes the headers sorted by name with sensitive values redacted.
+func writeTraceHeaders(trace *strings.Builder, prefix string, headers http.Header) {
+	redacted := redactHeaders(headers)
+	names := make([]string, 0, len(redacted))
+	for name := range redacted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(trace, "%s%s: %s\n", prefix, name, redacted[name])
+	}
+	fmt.Fprintf(trace, "%s\n", prefix)
+}
+
+// writeTraceBody writes the body, if any, followed by a truncation marker when only a part of it is shown.
+func writeTraceBody(trace *strings.Builder, prefix string, body []byte, truncated bool) {
+	if len(body) == 0 {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		fmt.Fprintf(trace, "%s%s\n", prefix, line)
+	}
+	if truncated {
+		fmt.Fprintf(trace, "* response body truncated to %d bytes\n", maxTraceBodySize)
+	}
+}
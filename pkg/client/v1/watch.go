@@ -0,0 +1,357 @@
+package v1
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+// WatchOptions holds the optional parameters of a watch request.
+type WatchOptions struct {
+	Revision          int64          // Revision is the last revision seen by the client; the watch resumes right after it.
+	OnResync          func(int64)    // OnResync is called with the current server revision when the watch history has a gap.
+	OnBookmark        func(int64)    // OnBookmark is called with the revision of every bookmark, the revision to resume the watch from.
+	PingInterval      time.Duration  // PingInterval is the interval of the WebSocket pings; the watch times out after three intervals without anything received. Zero disables both.
+	EnableCompression bool           // EnableCompression negotiates permessage-deflate compression of WebSocket frames.
+	Transport         WatchTransport // Transport selects the protocol of the watch.
+
+	Project       string            // Project selects the events of the announcements of the project; empty selects all projects.
+	NamePrefix    string            // NamePrefix selects the events of the announcements whose name starts with the prefix.
+	LabelSelector string            // LabelSelector selects the events of the announcements whose labels meet it.
+	EventTypes    []model.EventType // EventTypes selects the events of the types; empty selects all types.
+
+	EventBuffer  int                  // EventBuffer is the capacity of the buffer between the reader and the consumer; zero dispatches synchronously.
+	Backpressure BackpressureStrategy // Backpressure defines what happens when the event buffer is full.
+}
+
+// BackpressureStrategy defines how a watch reacts when the consumer does not keep up with the incoming events.
+type BackpressureStrategy int
+
+const (
+	BlockSender BackpressureStrategy = iota // BlockSender stops reading from the connection until the consumer frees space in the buffer.
+	DropOldest                              // DropOldest discards the oldest buffered event to make room for the new one.
+	ErrorOnFull                             // ErrorOnFull aborts the watch with ErrEventBufferFull.
+)
+
+// WatchTransport selects the protocol used to stream the watch events.
+type WatchTransport int
+
+const (
+	TransportAuto      WatchTransport = iota // TransportAuto uses a WebSocket and falls back to Server-Sent Events when the upgrade fails, e.g., behind a proxy stripping it.
+	TransportWebSocket                       // TransportWebSocket uses a WebSocket only.
+	TransportSSE                             // TransportSSE uses Server-Sent Events only.
+)
+
+// WatchOption configures a watch request.
+type WatchOption func(*WatchOptions)
+
+// WithRevision resumes the watch right after the specified revision.
+func WithRevision(revision int64) WatchOption {
+	return func(o *WatchOptions) {
+		o.Revision = revision
+	}
+}
+
+// WithCompression enables permessage-deflate compression of the watch WebSocket frames.
+func WithCompression() WatchOption {
+	return func(o *WatchOptions) {
+		o.EnableCompression = true
+	}
+}
+
+// WithTransport selects the protocol of the watch; TransportAuto is the default.
+func WithTransport(transport WatchTransport) WatchOption {
+	return func(o *WatchOptions) {
+		o.Transport = transport
+	}
+}
+
+// WithProject filters the watch on the server to the announcements of the project.
+func WithProject(project string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Project = project
+	}
+}
+
+// WithNamePrefix filters the watch on the server to the announcements whose name starts with the prefix.
+func WithNamePrefix(prefix string) WatchOption {
+	return func(o *WatchOptions) {
+		o.NamePrefix = prefix
+	}
+}
+
+// WithLabelSelector filters the watch on the server to the announcements whose labels meet the selector, e.g.,
+// "env=staging". An update moving an announcement out of the selection is delivered as a deleted event, and one
+// moving it in as an added event.
+func WithLabelSelector(selector string) WatchOption {
+	return func(o *WatchOptions) {
+		o.LabelSelector = selector
+	}
+}
+
+// WithEventTypes filters the watch on the server to the events of the types, e.g., model.EventDeleted. Resync
+// signals are always delivered.
+func WithEventTypes(types ...model.EventType) WatchOption {
+	return func(o *WatchOptions) {
+		o.EventTypes = types
+	}
+}
+
+// WithResyncCallback registers a callback invoked when the server cannot resume the watch from the requested
+// revision (e.g., the history was compacted) and the client has to re-list all announcements.
+func WithResyncCallback(onResync func()) WatchOption {
+	return func(o *WatchOptions) {
+		o.OnResync = func(int64) { onResync() }
+	}
+}
+
+// WithEventBuffer dispatches events to the consumer through a buffer of the specified capacity.
+func WithEventBuffer(capacity int) WatchOption {
+	return func(o *WatchOptions) {
+		o.EventBuffer = capacity
+	}
+}
+
+// WithBackpressureStrategy sets the strategy applied when the event buffer is full. It has effect only
+// together with WithEventBuffer.
+func WithBackpressureStrategy(strategy BackpressureStrategy) WatchOption {
+	return func(o *WatchOptions) {
+		o.Backpressure = strategy
+	}
+}
+
+// WithResyncRevisionCallback registers a resync callback that receives the current server revision, from which the
+// watch resumes after the client has re-listed all announcements.
+func WithResyncRevisionCallback(onResync func(revision int64)) WatchOption {
+	return func(o *WatchOptions) {
+		o.OnResync = onResync
+	}
+}
+
+// WithBookmarkCallback registers a callback invoked with the revision of every bookmark the server sends on the idle
+// watch, up to which all selected changes have been delivered. A watch resumed from it after a reconnection misses
+// no changes, without replaying the changes filtered out on the server.
+func WithBookmarkCallback(onBookmark func(revision int64)) WatchOption {
+	return func(o *WatchOptions) {
+		o.OnBookmark = onBookmark
+	}
+}
+
+// WithPingInterval sets the interval of the pings sent on a WebSocket watch, 15 seconds by default. A watch that
+// receives nothing, neither events nor bookmarks nor answers to the pings, for three intervals is considered dead and
+// ends with ErrWatchTimeout, so that it is re-established. An event stream is not pinged, it times out the same way
+// without the bookmarks of the server. Zero disables the pings and the timeout.
+func WithPingInterval(interval time.Duration) WatchOption {
+	return func(o *WatchOptions) {
+		o.PingInterval = interval
+	}
+}
+
+// filterQuery returns the query parameters of the server-side filters of the watch.
+func (o *WatchOptions) filterQuery() url.Values {
+	query := url.Values{}
+	if o.Project != "" {
+		query.Set("project", o.Project)
+	}
+	if o.NamePrefix != "" {
+		query.Set("namePrefix", o.NamePrefix)
+	}
+	if o.LabelSelector != "" {
+		query.Set("labelSelector", o.LabelSelector)
+	}
+	if len(o.EventTypes) > 0 {
+		types := make([]string, len(o.EventTypes))
+		for i, eventType := range o.EventTypes {
+			types[i] = string(eventType)
+		}
+		query.Set("types", strings.Join(types, ","))
+	}
+	return query
+}
+
+// newWatchOptions applies the watch options to the default values.
+func newWatchOptions(opts []WatchOption) *WatchOptions {
+	options := &WatchOptions{PingInterval: defaultWatchPingInterval}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+const (
+	initialWatchBackoff = time.Second      // initialWatchBackoff is the delay before the first reconnection attempt.
+	maxWatchBackoff     = 30 * time.Second // maxWatchBackoff caps the exponentially growing reconnection delay.
+
+	defaultWatchPingInterval = 15 * time.Second // defaultWatchPingInterval is the interval of the pings of a watch unless set with WithPingInterval.
+	deadWatchPings           = 3                // deadWatchPings is the number of ping intervals without anything received after which a watch times out.
+)
+
+// ResumableWatcher keeps a local copy of all announcements in sync with the API server. It resumes the watch
+// from the last seen or bookmarked revision after connection drops, dead connections included, and on resync signals
+// closes the watch, rebuilds its state from a full list and resumes from the revision of the list.
+// Reconnection attempts are delayed with an exponential backoff that is reset once events flow again.
+type ResumableWatcher struct {
+	client   *APIClient
+	onEvent  func(model.Event)
+	onResync func()
+
+	mu            sync.RWMutex
+	revision      int64
+	announcements map[string]model.Announcement
+}
+
+// NewResumableWatcher creates a watcher that passes every event to onEvent. The optional onResync callback
+// is called before the local state is rebuilt from a full list.
+func (c *APIClient) NewResumableWatcher(onEvent func(model.Event), onResync func()) *ResumableWatcher {
+	return &ResumableWatcher{
+		client:        c,
+		onEvent:       onEvent,
+		onResync:      onResync,
+		announcements: make(map[string]model.Announcement),
+	}
+}
+
+// Run lists all announcements and watches for changes until the context is canceled.
+func (w *ResumableWatcher) Run(ctx context.Context) error {
+	if err := w.relist(ctx, 0); err != nil {
+		return err
+	}
+
+	backoff := initialWatchBackoff
+	for {
+		var resyncRevision int64
+		var received bool
+		// The watch is closed on a resync signal, since the events following it can not be applied to the stale state
+		watchCtx, cancel := context.WithCancel(ctx)
+		err := w.client.V1WatchAnnouncements(watchCtx, func(event model.Event) {
+			if resyncRevision > 0 {
+				return
+			}
+			received = true
+			w.handleEvent(event)
+		},
+			WithRevision(w.Revision()),
+			WithResyncRevisionCallback(func(revision int64) {
+				resyncRevision = revision
+				cancel()
+			}),
+			WithBookmarkCallback(func(revision int64) {
+				if resyncRevision > 0 {
+					return
+				}
+				received = true
+				w.advanceRevision(revision)
+			}),
+		)
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if resyncRevision > 0 {
+			// The history has a gap, notify the consumer and rebuild the state from scratch
+			if w.onResync != nil {
+				w.onResync()
+			}
+			if err := w.relist(ctx, resyncRevision); err != nil {
+				return err
+			}
+			backoff = initialWatchBackoff
+			continue
+		}
+
+		// The connection was healthy, so the next drop is treated as a new failure series
+		if received {
+			backoff = initialWatchBackoff
+		}
+
+		// Wait before reconnecting to avoid hammering an unavailable server
+		if err != nil {
+			w.client.log().Warn("watch connection lost, reconnecting", "error", err, "backoff", backoff)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWatchBackoff)
+	}
+}
+
+// Revision returns the last revision observed by the watcher.
+func (w *ResumableWatcher) Revision() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.revision
+}
+
+// Announcements returns a snapshot of the local state keyed by "project/name".
+func (w *ResumableWatcher) Announcements() map[string]model.Announcement {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	snapshot := make(map[string]model.Announcement, len(w.announcements))
+	for key, announcement := range w.announcements {
+		snapshot[key] = announcement
+	}
+	return snapshot
+}
+
+// handleEvent applies the event to the local state and passes it to the consumer.
+func (w *ResumableWatcher) handleEvent(event model.Event) {
+	key := event.Announcement.Meta.Project + "/" + event.Announcement.Meta.Name
+
+	w.mu.Lock()
+	switch event.Type {
+	case model.EventAdded, model.EventUpdated:
+		w.announcements[key] = event.Announcement
+	case model.EventDeleted:
+		delete(w.announcements, key)
+	}
+	if event.Revision > w.revision {
+		w.revision = event.Revision
+	}
+	w.mu.Unlock()
+
+	if w.onEvent != nil {
+		w.onEvent(event)
+	}
+}
+
+// relist replaces the local state with a full list of announcements from the API server. The watch resumes from the
+// revision of the list: the highest resource version of the listed announcements, or the revision if it is higher.
+// The changes between the revision and the list are replayed, which leaves the state as it is.
+func (w *ResumableWatcher) relist(ctx context.Context, revision int64) error {
+	announcementsByProject, err := w.client.V1GetAllAnnouncements(ctx)
+	if err != nil {
+		return err
+	}
+
+	announcements := make(map[string]model.Announcement)
+	for project, list := range announcementsByProject {
+		for _, announcement := range list {
+			announcements[project+"/"+announcement.Meta.Name] = *announcement
+			if version, err := strconv.ParseInt(announcement.Meta.ResourceVersion, 10, 64); err == nil {
+				revision = max(revision, version)
+			}
+		}
+	}
+
+	w.mu.Lock()
+	w.announcements = announcements
+	w.revision = revision
+	w.mu.Unlock()
+	return nil
+}
+
+// advanceRevision moves the revision to resume the watch from forward to the bookmarked revision.
+func (w *ResumableWatcher) advanceRevision(revision int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.revision = max(w.revision, revision)
+}
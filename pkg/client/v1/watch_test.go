@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nikitamishagin/corebgp/internal/model"
+)
+
+const (
+	eventsPerWatch     = 50               // eventsPerWatch is the number of events the mock server sends on every watch.
+	linkBytesPerSecond = 100 << 20 / 8    // linkBytesPerSecond is the simulated bandwidth of the server's link, 100 Mbit/s.
+	largeEventNextHops = 200              // largeEventNextHops is the number of next hops of every event, a few kilobytes of JSON.
+	watchBenchTimeout  = 10 * time.Second // watchBenchTimeout bounds a single watch of the benchmark.
+)
+
+// BenchmarkWatchCompression compares watches receiving large events with and without permessage-deflate over a link
+// of limited bandwidth, where the smaller frames pay off. The wire-B/op metric is the number of bytes sent per watch.
+func BenchmarkWatchCompression(b *testing.B) {
+	b.Run("uncompressed", func(b *testing.B) {
+		benchmarkWatchCompression(b)
+	})
+	b.Run("compressed", func(b *testing.B) {
+		benchmarkWatchCompression(b, WithCompression())
+	})
+}
+
+func benchmarkWatchCompression(b *testing.B, opts ...WatchOption) {
+	announcement := model.Announcement{Meta: model.Meta{Project: "project", Name: "name"}}
+	for i := 0; i < largeEventNextHops; i++ {
+		announcement.NextHops = append(announcement.NextHops, model.Subnet{IP: fmt.Sprintf("10.0.%d.%d", i/256, i%256), Mask: 32})
+	}
+	message, _ := json.Marshal(model.Event{Type: model.EventUpdated, Announcement: announcement})
+
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.EnableWriteCompression(true)
+		for i := 0; i < eventsPerWatch; i++ {
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		}
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	}))
+	var written atomic.Int64
+	server.Listener = &throttledListener{Listener: server.Listener, written: &written}
+	server.Start()
+	defer server.Close()
+
+	client := NewAPIClient(&server.URL, 10*time.Second)
+	opts = append(opts, WithTransport(TransportWebSocket))
+
+	b.SetBytes(int64(len(message) * eventsPerWatch))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), watchBenchTimeout)
+		var received int
+		err := client.V1WatchAnnouncements(ctx, func(model.Event) {
+			received++
+		}, opts...)
+		cancel()
+		if err != nil {
+			b.Fatalf("V1WatchAnnouncements: %v", err)
+		}
+		if received != eventsPerWatch {
+			b.Fatalf("received %d events, want %d", received, eventsPerWatch)
+		}
+	}
+	b.ReportMetric(float64(written.Load())/float64(b.N), "wire-B/op")
+}
+
+// throttledListener accepts connections whose writes take as long as on a link of linkBytesPerSecond and counts the
+// bytes written.
+type throttledListener struct {
+	net.Listener
+	written *atomic.Int64
+}
+
+func (l *throttledListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &throttledConn{Conn: conn, written: l.written}, nil
+}
+
+// throttledConn is a connection of throttledListener.
+type throttledConn struct {
+	net.Conn
+	written *atomic.Int64
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	time.Sleep(time.Duration(len(p)) * time.Second / linkBytesPerSecond)
+	c.written.Add(int64(len(p)))
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,80 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nikitamishagin/corebgp/internal/model"
+	"github.com/nikitamishagin/corebgp/pkg/apiservertest"
+	v1 "github.com/nikitamishagin/corebgp/pkg/client/v1"
+	"github.com/nikitamishagin/corebgp/pkg/clock"
+)
+
+// TestSoftDeleteLifecycle follows a soft-deleted announcement from the withdrawal through the lists, where it is
+// visible only on request, to its collection after the retention period.
+func TestSoftDeleteLifecycle(t *testing.T) {
+	clk := clock.NewFakeClock(time.Now())
+	server := apiservertest.NewTestServer(t, apiservertest.WithClock(clk), apiservertest.WithConfig(func(config *model.APIConfig) {
+		config.WithdrawnRetention = 10 * time.Second
+	}))
+	client := server.Client()
+	ctx := context.Background()
+
+	for name, address := range map[string]string{"web": "192.0.2.10", "db": "192.0.2.11"} {
+		if err := client.V1CreateAnnouncement(ctx, &model.Announcement{
+			Meta:      model.Meta{Name: name, Project: "alpha"},
+			Addresses: model.Addresses{AnnouncedIP: address},
+			NextHops:  []model.Subnet{{IP: "10.0.0.1", Mask: 32}},
+		}); err != nil {
+			t.Fatalf("V1CreateAnnouncement(%s): %v", name, err)
+		}
+	}
+	if err := client.V1SoftDeleteAnnouncement(ctx, "alpha", "web"); err != nil {
+		t.Fatalf("V1SoftDeleteAnnouncement: %v", err)
+	}
+
+	withdrawn, err := client.V1GetAnnouncement(ctx, "alpha", "web")
+	if err != nil {
+		t.Fatalf("V1GetAnnouncement: %v", err)
+	}
+	if withdrawn.Status.Status != model.StatusWithdrawn {
+		t.Fatalf("status after the soft delete = %q, want %q", withdrawn.Status.Status, model.StatusWithdrawn)
+	}
+
+	ids, err := client.V1ListAnnouncements(ctx, v1.ListOptions{})
+	if err != nil {
+		t.Fatalf("V1ListAnnouncements: %v", err)
+	}
+	if !slices.Equal(ids, []string{"alpha/db"}) {
+		t.Fatalf("default list = %v, want only alpha/db", ids)
+	}
+	ids, err = client.V1ListAnnouncements(ctx, v1.ListOptions{IncludeWithdrawn: true})
+	if err != nil {
+		t.Fatalf("V1ListAnnouncements: %v", err)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"alpha/db", "alpha/web"}) {
+		t.Fatalf("list with withdrawn = %v, want alpha/db and alpha/web", ids)
+	}
+
+	// The collector runs every second of real time, a tenth of the retention, and compares the withdrawal time with the
+	// fake clock
+	clk.Advance(time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := client.V1GetAnnouncement(ctx, "alpha", "web")
+		if errors.Is(err, v1.ErrNotFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("withdrawn announcement was not collected after the retention period, last error: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if _, err := client.V1GetAnnouncement(ctx, "alpha", "db"); err != nil {
+		t.Fatalf("active announcement was collected: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Package clock provides an abstraction over the system time, so that time-dependent logic such as
+// timestamps and retention periods can be controlled in tests.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and the ability to wait.
+type Clock interface {
+	Now() time.Time                         // Now returns the current time.
+	Sleep(d time.Duration)                  // Sleep pauses the calling goroutine for at least the duration d.
+	After(d time.Duration) <-chan time.Time // After returns a channel receiving the current time once at least the duration d has passed.
+}
+
+// RealClock is a Clock backed by the system time.
+type RealClock struct{}
+
+// Now returns the current system time.
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep pauses the calling goroutine for the duration d.
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// After returns a channel receiving the current time after the duration d.
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// FakeClock is a Clock whose time only moves when it is changed with Advance or Set.
+type FakeClock struct {
+	mu       sync.Mutex
+	now      time.Time
+	sleepers []*sleeper
+}
+
+// sleeper is a goroutine blocked in Sleep or a channel returned by After waiting for the fake time to reach the
+// deadline.
+type sleeper struct {
+	deadline time.Time
+	wake     chan time.Time // wake receives the fake time once the deadline has passed; it is buffered, so that nobody has to receive.
+}
+
+// NewFakeClock creates a fake clock set to the specified time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current fake time.
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Sleep blocks until the fake time is advanced by at least d. A non-positive duration returns immediately.
+func (f *FakeClock) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-f.After(d)
+}
+
+// After returns a channel receiving the fake time once it is advanced by at least d. The channel of a non-positive
+// duration receives the current fake time right away.
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := &sleeper{deadline: f.now.Add(d), wake: make(chan time.Time, 1)}
+	if d <= 0 {
+		s.wake <- f.now
+		return s.wake
+	}
+	f.sleepers = append(f.sleepers, s)
+	return s.wake
+}
+
+// Advance moves the fake time forward by d and wakes up the sleepers whose deadline has passed.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(f.now.Add(d))
+}
+
+// Set changes the fake time to t and wakes up the sleepers whose deadline has passed.
+func (f *FakeClock) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(t)
+}
+
+// setLocked updates the time and releases the expired sleepers. The caller must hold f.mu.
+func (f *FakeClock) setLocked(t time.Time) {
+	f.now = t
+
+	pending := f.sleepers[:0]
+	for _, s := range f.sleepers {
+		if !s.deadline.After(t) {
+			s.wake <- t
+			continue
+		}
+		pending = append(pending, s)
+	}
+	f.sleepers = pending
+}
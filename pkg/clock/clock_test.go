@@ -0,0 +1,115 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClockAdvance(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	clk.Advance(time.Minute)
+	if got := clk.Now(); !got.Equal(epoch.Add(time.Minute)) {
+		t.Fatalf("Now() = %v after Advance, want %v", got, epoch.Add(time.Minute))
+	}
+
+	clk.Set(epoch)
+	if got := clk.Now(); !got.Equal(epoch) {
+		t.Fatalf("Now() = %v after Set, want %v", got, epoch)
+	}
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	ch := clk.After(time.Second)
+
+	clk.Advance(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("After fired before the deadline")
+	default:
+	}
+
+	clk.Advance(time.Millisecond)
+	select {
+	case now := <-ch:
+		if !now.Equal(epoch.Add(time.Second)) {
+			t.Fatalf("After received %v, want %v", now, epoch.Add(time.Second))
+		}
+	default:
+		t.Fatal("After did not fire at the deadline")
+	}
+}
+
+func TestFakeClockAfterNonPositive(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	for _, d := range []time.Duration{0, -time.Second} {
+		select {
+		case now := <-clk.After(d):
+			if !now.Equal(epoch) {
+				t.Fatalf("After(%v) received %v, want %v", d, now, epoch)
+			}
+		default:
+			t.Fatalf("After(%v) did not fire right away", d)
+		}
+	}
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	done := make(chan struct{})
+	go func() {
+		clk.Sleep(time.Minute)
+		close(done)
+	}()
+
+	// Advance only once the sleeper is registered, so that the deadline is relative to the epoch
+	waitForSleepers(t, clk, 1)
+	clk.Advance(30 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("Sleep returned before the deadline")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clk.Advance(30 * time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep did not return after the deadline")
+	}
+}
+
+func TestFakeClockSetWakesExpiredSleepers(t *testing.T) {
+	clk := NewFakeClock(epoch)
+	early, late := clk.After(time.Second), clk.After(time.Hour)
+
+	clk.Set(epoch.Add(time.Minute))
+	select {
+	case <-early:
+	default:
+		t.Fatal("Set did not wake the sleeper whose deadline passed")
+	}
+	select {
+	case <-late:
+		t.Fatal("Set woke the sleeper whose deadline did not pass")
+	default:
+	}
+}
+
+// waitForSleepers waits until n goroutines or channels wait for the fake clock.
+func waitForSleepers(t *testing.T, clk *FakeClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		clk.mu.Lock()
+		waiting := len(clk.sleepers)
+		clk.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d sleepers did not register", n)
+}